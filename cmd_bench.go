@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/bench"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+const benchUsage = `Usage:
+  autotunnel bench <route> [flags]
+
+Drives concurrent load through a running autotunnel and reports latency
+percentiles and throughput. Routes are hostnames (HTTP GET through http.listen)
+or tcp:<port> (one connection per request, timed until the first byte back).`
+
+// runBench measures a route through the local proxy, the way clients reach it
+func runBench(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("missing route\n%s", benchUsage)
+	}
+	route, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	concurrency := fs.Int("c", 10, "Concurrent workers")
+	requests := fs.Int("n", 0, "Total requests (0: run for -d)")
+	duration := fs.Duration("d", 10*time.Second, "How long to run when -n is 0")
+	warmup := fs.Int("warmup", 1, "Requests sent first and left out of the results (the first one starts the tunnel)")
+	path := fs.String("path", "/", "HTTP request path")
+	send := fs.String("send", "", "TCP: data written on each connection before waiting for the reply")
+	timeout := fs.Duration("timeout", 10*time.Second, "Per-request timeout")
+	jsonOut := fs.Bool("json", false, "Print the result as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), benchUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	var op bench.Op
+	var target string
+	if portStr, ok := strings.CutPrefix(route, "tcp:"); ok {
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return fmt.Errorf("invalid route %q: expected tcp:<port>", route)
+		}
+		target = net.JoinHostPort(dialHost(cfg.TCP.GetListenHost()), strconv.Itoa(boundTCPPort(cfg, port)))
+		op = bench.TCPOp(target, []byte(*send), *timeout)
+	} else {
+		host, port, err := net.SplitHostPort(cfg.HTTP.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("invalid http.listen %q: %w", cfg.HTTP.ListenAddr, err)
+		}
+		target = "http://" + net.JoinHostPort(dialHost(host), port) + *path
+		client := &http.Client{
+			Timeout: *timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: *concurrency,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		op = bench.HTTPOp(client, target, route)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !*jsonOut {
+		fmt.Fprintf(os.Stderr, "Benchmarking %s via %s (%d workers)...\n", route, target, *concurrency)
+	}
+	res, err := bench.Run(ctx, op, bench.Options{
+		Concurrency: *concurrency,
+		Requests:    *requests,
+		Duration:    *duration,
+		Warmup:      *warmup,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		data, err := json.Marshal(struct {
+			Route string `json:"route"`
+			bench.Result
+			RequestsPerSecond float64 `json:"requests_per_second"`
+			BytesPerSecond    float64 `json:"bytes_per_second"`
+		}{route, res, res.RequestsPerSecond(), res.BytesPerSecond()})
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	printBenchResult(res)
+	return nil
+}
+
+func printBenchResult(res bench.Result) {
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond)) }
+
+	fmt.Printf("Requests:   %d in %v (%d errors)\n", res.Requests, res.Elapsed.Round(time.Millisecond), res.Errors)
+	fmt.Printf("Throughput: %.1f req/s, %.1f KiB/s\n", res.RequestsPerSecond(), res.BytesPerSecond()/1024)
+	if res.Requests > res.Errors {
+		fmt.Printf("Latency:    min %s  p50 %s  p90 %s  p99 %s  max %s\n",
+			ms(res.Min), ms(res.P50), ms(res.P90), ms(res.P99), ms(res.Max))
+	}
+	if len(res.ErrorSamples) > 0 {
+		msgs := make([]string, 0, len(res.ErrorSamples))
+		for msg := range res.ErrorSamples {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		fmt.Println("Errors:")
+		for _, msg := range msgs {
+			fmt.Printf("  %5d  %s\n", res.ErrorSamples[msg], msg)
+		}
+	}
+}
+
+// dialHost turns a listen host into one a local client can connect to
+func dialHost(host string) string {
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		return "127.0.0.1"
+	}
+	return host
+}
+
+// boundTCPPort asks the running daemon where port ended up with auto_remap_ports
+func boundTCPPort(cfg *config.Config, port int) int {
+	if cfg.HTTP.StatusHost == "" {
+		return port
+	}
+	var status struct {
+		TCPPortRemaps map[int]int `json:"tcp_port_remaps"`
+	}
+	if err := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost).Get("/status", &status); err == nil {
+		if bound, ok := status.TCPPortRemaps[port]; ok {
+			return bound
+		}
+	}
+	return port
+}
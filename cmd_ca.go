@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/atas/autotunnel/internal/devca"
+)
+
+// runCA creates (if needed) the local dev CA and prints how to trust it
+func runCA(args []string) error {
+	fs := flag.NewFlagSet("ca", flag.ExitOnError)
+	dir := fs.String("dir", devca.DefaultDir(), "Directory holding the dev CA")
+	_ = fs.Parse(args)
+
+	ca, err := devca.LoadOrCreate(*dir)
+	if err != nil {
+		return err
+	}
+	path := ca.CertPath()
+
+	fmt.Printf("autotunnel dev CA: %s\n\n", path)
+	fmt.Println("Listeners that terminate TLS locally use certificates signed by this CA. Trust it with:")
+	fmt.Printf("  macOS:         sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %s\n", path)
+	fmt.Printf("  Debian/Ubuntu: sudo cp %s /usr/local/share/ca-certificates/autotunnel.crt && sudo update-ca-certificates\n", path)
+	fmt.Printf("  Fedora/RHEL:   sudo cp %s /etc/pki/ca-trust/source/anchors/autotunnel.pem && sudo update-ca-trust\n", path)
+	fmt.Printf("  curl only:     curl --cacert %s ...\n", path)
+	return nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+const completionUsage = `Usage:
+  autotunnel completion bash|zsh|fish
+
+Prints a shell completion script for subcommands, their actions and route
+arguments. Routes come from the running autotunnel, including the services of
+dynamic_host contexts, or from the config file when it isn't running:
+
+  source <(autotunnel completion bash)                     # ~/.bashrc
+  autotunnel completion zsh > "${fpath[1]}/_autotunnel"    # or: source <(autotunnel completion zsh)
+  autotunnel completion fish > ~/.config/fish/completions/autotunnel.fish`
+
+// completeCommand is the hidden action the scripts call with the words typed so
+// far, the last one being the word to complete
+const completeCommand = "__complete"
+
+// globalFlags are the flags of the proxy itself, completed before a subcommand
+var globalFlags = []string{"-config", "-locked", "-no-banner", "-output", "-pid-file", "-quiet", "-ready-file", "-run-mode", "-verbose", "-version"}
+
+// globalValues are the values completed after a global flag; flags listed with
+// nil take a file name
+var globalValues = map[string][]string{
+	"config":     nil,
+	"output":     {"text", "json"},
+	"pid-file":   nil,
+	"ready-file": nil,
+	"run-mode":   {"foreground", "supervised"},
+}
+
+// subcommandActions are the words a subcommand takes first
+var subcommandActions = map[string][]string{
+	"completion": {"bash", "zsh", "fish"},
+	"context":    {"add-eks", "add-gke", "add-aks"},
+	"debug":      {"bundle"},
+	"import":     {"kubefwd", "kube-forwarder", "ssh"},
+	"reload":     {"show", "apply", "discard"},
+}
+
+// routeSubcommands take a route first, limited to the listed kinds (nil = any).
+// Subcommands that take hostnames also get services of dynamic_host contexts.
+var routeSubcommands = map[string]struct {
+	kinds     []string
+	hostnames bool
+}{
+	"bench":     {kinds: []string{config.RouteKindHTTP, config.RouteKindTCP}, hostnames: true},
+	"keep-warm": {hostnames: true},
+	"restart":   {kinds: []string{config.RouteKindHTTP, config.RouteKindTCP, config.RouteKindJump, config.RouteKindGroup}, hostnames: true},
+	"ssh":       {kinds: []string{config.RouteKindTCP, config.RouteKindJump}},
+	"stdio":     {kinds: []string{config.RouteKindTCP, config.RouteKindJump}},
+}
+
+// The completion subcommand is added here rather than in the subcommands map,
+// which would refer to itself through completeWords
+func init() {
+	subcommands["completion"] = runCompletion
+}
+
+// runCompletion prints a completion script, or with __complete the candidates
+// for the last of the given words
+func runCompletion(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing shell\n%s", completionUsage)
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	case completeCommand:
+		// Config warnings would end up in the shell's prompt
+		log.SetOutput(io.Discard)
+		for _, word := range completeWords(args[1:]) {
+			fmt.Println(word)
+		}
+	default:
+		return fmt.Errorf("unknown shell %q\n%s", args[0], completionUsage)
+	}
+	return nil
+}
+
+// completeWords returns the candidates for the last of words, the arguments
+// typed after "autotunnel". Nothing is returned where the shell should fall back
+// to file names.
+func completeWords(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, args := words[len(words)-1], words[:len(words)-1]
+	if len(args) > 0 && isConfigFlag(args[len(args)-1]) {
+		return nil
+	}
+
+	sub, rest := "", args
+	for i := 0; i < len(args); i++ {
+		if _, ok := globalValues[strings.TrimLeft(args[i], "-")]; ok && strings.HasPrefix(args[i], "-") {
+			i++ // skip the flag's value
+			continue
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			sub, rest = args[i], args[i+1:]
+			break
+		}
+	}
+
+	if sub == "" {
+		if len(args) > 0 && strings.HasPrefix(args[len(args)-1], "-") {
+			if values, ok := globalValues[strings.TrimLeft(args[len(args)-1], "-")]; ok {
+				return withPrefix(values, cur)
+			}
+		}
+		if strings.HasPrefix(cur, "-") {
+			return withPrefix(globalFlags, cur)
+		}
+		return withPrefix(subcommandNames(), cur)
+	}
+
+	if len(rest) > 0 || strings.HasPrefix(cur, "-") {
+		if sub == "keep-warm" && len(rest) == 1 {
+			return withPrefix([]string{"off", "1h", "4h", "8h"}, cur)
+		}
+		return nil
+	}
+	if actions, ok := subcommandActions[sub]; ok {
+		return withPrefix(actions, cur)
+	}
+	if r, ok := routeSubcommands[sub]; ok {
+		return completeRoutes(configFlag(args), cur, r.kinds, r.hostnames)
+	}
+	return nil
+}
+
+// completeRoutes asks the running autotunnel for routes starting with prefix, and
+// with hostnames for services of the contexts dynamic_host may use. Without a
+// running autotunnel, the config file's routes are used.
+func completeRoutes(configPath, prefix string, kinds []string, hostnames bool) []string {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil
+	}
+	allowed := func(kind string) bool {
+		return kinds == nil || slices.Contains(kinds, kind) || (hostnames && kind == tunnelmgr.CompletionKindService)
+	}
+
+	if cfg.HTTP.StatusHost != "" {
+		query := url.Values{"prefix": {prefix}}
+		// Listing services of every context on an empty word would be slow and long
+		if hostnames && prefix != "" && cfg.GetDynamicHost() != "" {
+			for _, name := range completionContexts(cfg) {
+				query.Add("context", name)
+			}
+		}
+		var completions []tunnelmgr.Completion
+		client := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost)
+		if err := client.Get("/complete?"+query.Encode(), &completions); err == nil {
+			var values []string
+			for _, c := range completions {
+				if allowed(c.Kind) {
+					values = append(values, c.Value)
+				}
+			}
+			return values
+		}
+	}
+
+	var values []string
+	for _, route := range cfg.RouteList() {
+		if allowed(route.Kind) && strings.HasPrefix(route.ID, prefix) {
+			values = append(values, route.ID)
+		}
+	}
+	return values
+}
+
+// completionContexts returns dynamic_host_allow.contexts, or else the contexts
+// the config names: aliases and those of HTTP routes
+func completionContexts(cfg *config.Config) []string {
+	if allowed := cfg.HTTP.K8s.DynamicHostAllow.Contexts; len(allowed) > 0 {
+		return allowed
+	}
+	var names []string
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	for _, r := range cfg.HTTP.K8s.Routes {
+		if r.Context != "" && !slices.Contains(names, r.Context) {
+			names = append(names, r.Context)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// configFlag returns the -config value among args, or the default path
+func configFlag(args []string) string {
+	for i, arg := range args {
+		if isConfigFlag(arg) && i+1 < len(args) {
+			return args[i+1]
+		}
+		for _, name := range []string{"-config=", "--config="} {
+			if value, ok := strings.CutPrefix(arg, name); ok {
+				return value
+			}
+		}
+	}
+	return defaultConfigPath()
+}
+
+func isConfigFlag(arg string) bool {
+	return arg == "-config" || arg == "--config"
+}
+
+func withPrefix(words []string, prefix string) []string {
+	var matches []string
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			matches = append(matches, word)
+		}
+	}
+	return matches
+}
+
+const bashCompletion = `# bash completion for autotunnel
+_autotunnel() {
+	local cur words cword
+	if declare -F _get_comp_words_by_ref >/dev/null; then
+		_get_comp_words_by_ref -n : cur words cword
+	else
+		cur="${COMP_WORDS[COMP_CWORD]}" words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+	fi
+	local IFS=$'\n'
+	COMPREPLY=($(command autotunnel completion __complete "${words[@]:1:cword}" 2>/dev/null))
+	if declare -F __ltrim_colon_completions >/dev/null; then
+		__ltrim_colon_completions "$cur"
+	fi
+}
+complete -o default -F _autotunnel autotunnel
+`
+
+const zshCompletion = `#compdef autotunnel
+# zsh completion for autotunnel
+_autotunnel() {
+	local -a completions
+	completions=("${(@f)$(command autotunnel completion __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n ${completions[1]} ]]; then
+		compadd -a completions
+	else
+		_files
+	fi
+}
+if [[ $funcstack[1] == _autotunnel ]]; then
+	_autotunnel "$@"
+else
+	compdef _autotunnel autotunnel
+fi
+`
+
+const fishCompletion = `# fish completion for autotunnel
+function __autotunnel_complete
+	set -l words (commandline -opc)[2..-1] (commandline -ct)
+	command autotunnel completion __complete $words 2>/dev/null
+end
+complete -c autotunnel -f -a '(__autotunnel_complete)'
+`
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/atas/autotunnel/internal/cloudctx"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+const contextUsage = `Usage:
+  autotunnel context add-eks -name cluster [-region r] [-profile p] [options]
+  autotunnel context add-gke -name cluster [-location l] [-project p] [options]
+  autotunnel context add-aks -name cluster -resource-group rg [-subscription s] [options]
+
+Looks the cluster up with the provider's CLI (aws, gcloud or az) and writes a
+kubeconfig context for it that gets tokens from the provider's exec credential
+plugin (aws eks get-token, gke-gcloud-auth-plugin, kubelogin). Options:
+
+  -context name     Context name to write (default: the cluster name)
+  -alias name       Also add contexts: <name> to the autotunnel config
+  -kubeconfig path  Kubeconfig to write to (default: the first one autotunnel reads)
+  -force            Overwrite a different cluster, user or context of the same name
+  -config path      autotunnel config file`
+
+// runContext bootstraps kubeconfig contexts for managed clusters
+func runContext(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", contextUsage)
+	}
+
+	fs := flag.NewFlagSet("context "+args[0], flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), contextUsage) }
+	name := fs.String("name", "", "Cluster name")
+	contextName := fs.String("context", "", "Context name to write (default: the cluster name)")
+	alias := fs.String("alias", "", "Also add contexts: <alias> to the autotunnel config")
+	kubeconfig := fs.String("kubeconfig", "", "Kubeconfig to write to (default: the first one autotunnel reads)")
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	force := fs.Bool("force", false, "Overwrite a different cluster, user or context of the same name")
+
+	var lookup func(ctx context.Context) (*cloudctx.Cluster, error)
+	switch args[0] {
+	case "add-eks":
+		region := fs.String("region", "", "AWS region (default: the aws CLI's)")
+		profile := fs.String("profile", "", "AWS profile, also set for the token plugin")
+		lookup = func(ctx context.Context) (*cloudctx.Cluster, error) {
+			return cloudctx.EKS(ctx, cloudctx.RunCommand, *name, *region, *profile)
+		}
+	case "add-gke":
+		location := fs.String("location", "", "Cluster region or zone (default: gcloud's)")
+		project := fs.String("project", "", "Google Cloud project (default: gcloud's)")
+		lookup = func(ctx context.Context) (*cloudctx.Cluster, error) {
+			return cloudctx.GKE(ctx, cloudctx.RunCommand, *name, *location, *project)
+		}
+	case "add-aks":
+		resourceGroup := fs.String("resource-group", "", "Resource group of the cluster")
+		subscription := fs.String("subscription", "", "Azure subscription (default: az's)")
+		lookup = func(ctx context.Context) (*cloudctx.Cluster, error) {
+			if *resourceGroup == "" {
+				return nil, fmt.Errorf("-resource-group is required")
+			}
+			return cloudctx.AKS(ctx, cloudctx.RunCommand, *name, *resourceGroup, *subscription)
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], contextUsage)
+	}
+	_ = fs.Parse(args[1:])
+
+	if *name == "" {
+		return fmt.Errorf("-name is required\n%s", contextUsage)
+	}
+	if *contextName == "" {
+		*contextName = *name
+	}
+
+	// the config is only needed for -alias and the default -kubeconfig
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil && (*alias != "" || *kubeconfig == "") {
+		return err
+	}
+	if *kubeconfig == "" {
+		if len(cfg.HTTP.K8s.ResolvedKubeconfigs) == 0 {
+			return fmt.Errorf("no kubeconfig to write to, pass -kubeconfig")
+		}
+		*kubeconfig = cfg.HTTP.K8s.ResolvedKubeconfigs[0]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cluster, err := lookup(ctx)
+	if err != nil {
+		return err
+	}
+
+	replaced, err := cloudctx.AddToKubeconfig(*kubeconfig, *contextName, cluster, *force)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", *kubeconfig, err)
+	}
+	verb := "Added"
+	if replaced {
+		verb = "Updated"
+	}
+	fmt.Printf("%s context %s in %s (server %s, tokens from %s)\n", verb, *contextName, *kubeconfig, cluster.Server, cluster.Exec.Command)
+
+	// a kubeconfig autotunnel doesn't read is addressed by path
+	routeContext := *contextName
+	if cfg == nil || !slices.Contains(cfg.HTTP.K8s.ResolvedKubeconfigs, *kubeconfig) {
+		routeContext = *kubeconfig + "#" + *contextName
+	}
+	if *alias != "" {
+		if err := config.AddContextAlias(*configPath, *alias, routeContext); err != nil {
+			return err
+		}
+		fmt.Printf("Added contexts: %s -> %s to %s\n", *alias, routeContext, *configPath)
+		routeContext = *alias
+	}
+	fmt.Printf("Use it in routes with: context: %s\n", routeContext)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/diag"
+)
+
+const debugUsage = `Usage:
+  autotunnel debug bundle [-config path] [-o file]
+
+Writes a tar.gz for bug reports with the version, the config with tokens and
+passwords redacted, and, from the running autotunnel, tunnel states, recent
+logs, recovered panics and a goroutine dump.`
+
+// runDebug dispatches `autotunnel debug <action>`; bundle is the only action
+func runDebug(args []string) error {
+	if len(args) == 0 || args[0] != "bundle" {
+		return fmt.Errorf("unknown or missing action\n%s", debugUsage)
+	}
+
+	fs := flag.NewFlagSet("debug bundle", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	out := fs.String("o", "", "Output file (default autotunnel-debug-<time>.tar.gz)")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), debugUsage) }
+	_ = fs.Parse(args[1:])
+
+	if *out == "" {
+		*out = fmt.Sprintf("autotunnel-debug-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+
+	files := map[string][]byte{
+		"version.txt": fmt.Appendf(nil, "autotunnel %s (commit: %s, built: %s)\n%s %s/%s\n",
+			version, commit, date, runtime.Version(), runtime.GOOS, runtime.GOARCH),
+	}
+	var problems []string
+
+	raw, err := os.ReadFile(*configPath)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("config: %v", err))
+	} else if redacted, err := diag.Redacted(raw); err != nil {
+		// never ship a config we couldn't redact
+		problems = append(problems, fmt.Sprintf("config: not included, %v", err))
+	} else {
+		files["config.yaml"] = redacted
+	}
+
+	// the rest comes from the running daemon, if there is one
+	cfg, err := config.LoadConfig(*configPath)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("daemon: not queried, %v", err))
+	case cfg.HTTP.StatusHost == "":
+		problems = append(problems, "daemon: not queried, the status API is disabled (http.status_host is empty)")
+	default:
+		problems = append(problems, collectDaemonState(admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost), files)...)
+	}
+	if len(problems) > 0 {
+		files["errors.txt"] = []byte(strings.Join(problems, "\n") + "\n")
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := diag.WriteBundle(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", *out)
+	for _, p := range problems {
+		fmt.Printf("  skipped %s\n", p)
+	}
+	fmt.Println("Secrets in the config are redacted, but look through the bundle before sharing it.")
+	return nil
+}
+
+// collectDaemonState adds the daemon's status, logs, panics and goroutines to
+// files and returns what it couldn't fetch
+func collectDaemonState(client *admin.Client, files map[string][]byte) []string {
+	var problems []string
+
+	var status json.RawMessage
+	if err := client.Get("/status", &status); err != nil {
+		// everything below would fail the same way
+		return append(problems, fmt.Sprintf("daemon: %v", err))
+	}
+	files["status.json"] = indentJSON(status)
+
+	var logs diag.LogLines
+	if err := client.Get("/debug/logs", &logs); err != nil {
+		problems = append(problems, fmt.Sprintf("logs: %v", err))
+	} else {
+		files["logs.txt"] = []byte(strings.Join(logs.Lines, "\n") + "\n")
+	}
+
+	var panics []diag.PanicRecord
+	if err := client.Get("/debug/panics", &panics); err != nil {
+		problems = append(problems, fmt.Sprintf("panics: %v", err))
+	} else {
+		files["panics.json"], _ = json.MarshalIndent(panics, "", "  ")
+	}
+
+	var dump diag.GoroutineDump
+	if err := client.Get("/debug/goroutines", &dump); err != nil {
+		problems = append(problems, fmt.Sprintf("goroutines: %v", err))
+	} else {
+		files["goroutines.txt"] = []byte(dump.Dump)
+	}
+	return problems
+}
+
+func indentJSON(raw json.RawMessage) []byte {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return raw
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/shellenv"
+	"github.com/atas/autotunnel/internal/tunnel"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// runEnv prints export lines for the configured routes, e.g. `eval "$(autotunnel env)"`
+func runEnv(args []string) error {
+	fs := flag.NewFlagSet("env", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	// Ask the running daemon for remapped TCP ports and running HTTP tunnels;
+	// without one, configured ports are used
+	var remaps map[int]int
+	tunnelPorts := make(map[string]int)
+	if cfg.HTTP.StatusHost != "" {
+		var status struct {
+			TCPPortRemaps map[int]int            `json:"tcp_port_remaps"`
+			Tunnels       []tunnelmgr.TunnelInfo `json:"tunnels"`
+		}
+		if err := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost).Get("/status", &status); err == nil {
+			remaps = status.TCPPortRemaps
+			for _, tun := range status.Tunnels {
+				if tun.State == tunnel.StateRunning.String() {
+					tunnelPorts[tun.Hostname] = tun.LocalPort
+				}
+			}
+		}
+	}
+
+	vars := shellenv.Build(cfg, remaps, tunnelPorts)
+	if len(vars) == 0 {
+		fmt.Fprintln(os.Stderr, "no routes configured")
+		return nil
+	}
+	fmt.Print(shellenv.Export(vars))
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/importer"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"k8s.io/client-go/kubernetes"
+)
+
+const importUsage = `Usage:
+  autotunnel import kubefwd [-config path] "<kubefwd command line>" | -file script.sh
+  autotunnel import kube-forwarder [-context name] export.json
+  autotunnel import ssh -context name -namespace ns (-via-pod pod | -via-service svc) [~/.ssh/config]
+
+Prints the equivalent autotunnel routes as YAML to paste into your config.`
+
+// runImport generates autotunnel routes from kubefwd, kube-forwarder or ssh_config
+func runImport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing source\n%s", importUsage)
+	}
+
+	routes := importer.NewRoutes()
+	var err error
+	switch args[0] {
+	case "kubefwd":
+		err = importKubefwd(args[1:], routes)
+	case "kube-forwarder":
+		err = importKubeForwarder(args[1:], routes)
+	case "ssh":
+		err = importSSH(args[1:], routes)
+	default:
+		err = fmt.Errorf("unknown source %q\n%s", args[0], importUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range routes.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	if routes.Len() == 0 {
+		return fmt.Errorf("no routes found to import")
+	}
+
+	out, err := routes.YAML()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# Generated by `autotunnel import %s` - merge into your autotunnel config\n", args[0])
+	fmt.Print(string(out))
+	return nil
+}
+
+func importKubefwd(args []string, routes *importer.Routes) error {
+	fs := flag.NewFlagSet("import kubefwd", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file (for kubeconfig paths)")
+	file := fs.String("file", "", "Read kubefwd invocations from a script instead of the argument")
+	_ = fs.Parse(args)
+
+	var cmdlines []string
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(strings.ReplaceAll(string(data), "\\\n", " "), "\n") {
+			if strings.Contains(line, "kubefwd") && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				cmdlines = append(cmdlines, line)
+			}
+		}
+	} else {
+		cmdlines = []string{strings.Join(fs.Args(), " ")}
+	}
+
+	var kubeconfigs []string // nil = $KUBECONFIG or ~/.kube/config
+	if cfg, err := config.LoadConfig(*configPath); err == nil {
+		kubeconfigs = cfg.HTTP.K8s.ResolvedKubeconfigs
+	}
+	clients := k8sutil.NewClientFactory(false)
+	clientFor := func(contextName string) (kubernetes.Interface, error) {
+		clientset, _, err := clients.GetClientForContext(kubeconfigs, contextName)
+		return clientset, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, cmdline := range cmdlines {
+		spec, err := importer.ParseKubefwd(cmdline)
+		if err != nil {
+			return err
+		}
+		if len(spec.Contexts) == 0 {
+			current, err := k8sutil.CurrentContext(kubeconfigs)
+			if err != nil {
+				return err
+			}
+			spec.Contexts = []string{current}
+		}
+		if err := spec.Resolve(ctx, clientFor, routes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importKubeForwarder(args []string, routes *importer.Routes) error {
+	fs := flag.NewFlagSet("import kube-forwarder", flag.ExitOnError)
+	contextName := fs.String("context", "", "Kube context to use instead of the exported cluster names")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one export file\n%s", importUsage)
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return importer.ParseKubeForwarder(data, *contextName, routes)
+}
+
+func importSSH(args []string, routes *importer.Routes) error {
+	fs := flag.NewFlagSet("import ssh", flag.ExitOnError)
+	contextName := fs.String("context", "", "Kube context of the jump pod (required)")
+	namespace := fs.String("namespace", "default", "Namespace of the jump pod")
+	viaPod := fs.String("via-pod", "", "Jump pod name")
+	viaService := fs.String("via-service", "", "Service to discover the jump pod from")
+	_ = fs.Parse(args)
+
+	if *contextName == "" || (*viaPod == "") == (*viaService == "") {
+		return fmt.Errorf("-context and exactly one of -via-pod/-via-service are required\n%s", importUsage)
+	}
+
+	path := filepath.Join(os.Getenv("HOME"), ".ssh", "config")
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	return importer.ParseSSHConfig(r, importer.JumpVia{
+		Context:   *contextName,
+		Namespace: *namespace,
+		Via:       config.ViaConfig{Pod: *viaPod, Service: *viaService},
+	}, routes)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+const keepWarmUsage = `Usage:
+  autotunnel keep-warm <route> <duration> [-config path]
+  autotunnel keep-warm <route> off [-config path]
+
+Keeps a route's tunnels from idling out for the given time (e.g. 4h), or lets it
+idle out normally again. Routes are hostnames, tcp:<port>, group:<name> or pg:<port>.`
+
+// runKeepWarm extends a route's idle window through the status API
+func runKeepWarm(args []string) error {
+	if len(args) < 2 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("missing route or duration\n%s", keepWarmUsage)
+	}
+	route, duration, args := args[0], args[1], args[2:]
+
+	fs := flag.NewFlagSet("keep-warm", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	_ = fs.Parse(args)
+
+	var d time.Duration
+	if duration != "off" {
+		var err error
+		if d, err = time.ParseDuration(duration); err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q\n%s", duration, keepWarmUsage)
+		}
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.HTTP.StatusHost == "" {
+		return fmt.Errorf("the status API is disabled (http.status_host is empty)")
+	}
+	client := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost)
+	path := "/keep-warm/" + url.PathEscape(route)
+
+	if d == 0 {
+		if err := client.Do(http.MethodDelete, path, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s idles out normally again\n", route)
+		return nil
+	}
+
+	var info tunnelmgr.KeepWarmInfo
+	if err := client.Do(http.MethodPut, path+"?for="+url.QueryEscape(d.String()), &info); err != nil {
+		return err
+	}
+	fmt.Printf("Keeping %s warm until %s\n", info.Route, info.Until.Local().Format(time.DateTime))
+	return nil
+}
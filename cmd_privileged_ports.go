@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// runPrivilegedPorts helps listening on ports below 1024 (e.g. 80/443).
+// Linux: grants CAP_NET_BIND_SERVICE to the binary via setcap.
+// macOS: prints pf redirect rules from the low ports to the high listen port.
+func runPrivilegedPorts(args []string) error {
+	fs := flag.NewFlagSet("privileged-ports", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	dryRun := fs.Bool("dry-run", false, "Only print what would be done")
+	portsFlag := fs.String("ports", "80,443", "Privileged ports to redirect (pf/iptables rules)")
+	_ = fs.Parse(args)
+
+	ports, err := parsePortList(*portsFlag)
+	if err != nil {
+		return err
+	}
+	target := redirectTargetPort(*configPath)
+
+	switch runtime.GOOS {
+	case "linux":
+		return setcapBinary(*dryRun, ports, target)
+	case "darwin":
+		printPFRules(ports, target)
+		return nil
+	default:
+		return fmt.Errorf("not supported on %s", runtime.GOOS)
+	}
+}
+
+func setcapBinary(dryRun bool, ports []int, target int) error {
+	for _, port := range ports {
+		if netutil.CanBindPrivileged(port) {
+			fmt.Printf("Port %d can already be bound by this user\n", port)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate autotunnel binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	cmdArgs := []string{"setcap", "cap_net_bind_service=+ep", exe}
+	if os.Geteuid() != 0 {
+		cmdArgs = append([]string{"sudo"}, cmdArgs...)
+	}
+
+	fmt.Printf("Granting CAP_NET_BIND_SERVICE:\n  %s\n", strings.Join(cmdArgs, " "))
+	fmt.Println("Note: the capability is lost when the binary is replaced (e.g. on upgrade); re-run this command afterwards.")
+	fmt.Println("Alternatively, keep a high http.listen port and redirect with iptables:")
+	for _, port := range ports {
+		fmt.Printf("  sudo iptables -t nat -A OUTPUT -o lo -p tcp --dport %d -j REDIRECT --to-ports %d\n", port, target)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("setcap failed: %w", err)
+	}
+	fmt.Println("Done. Restart autotunnel to pick up the capability.")
+	return nil
+}
+
+func printPFRules(ports []int, target int) {
+	fmt.Println("# pf redirect rules (see contrib/macos for a launchd job that loads them at boot)")
+	fmt.Println("# Load now with:")
+	fmt.Println("#   sudo pfctl -a com.apple/autotunnel -f /usr/local/etc/autotunnel.pf.conf && sudo pfctl -E")
+	for _, port := range ports {
+		fmt.Printf("rdr pass on lo0 inet proto tcp from any to 127.0.0.1 port %d -> 127.0.0.1 port %d\n", port, target)
+	}
+}
+
+// redirectTargetPort is the high port autotunnel actually listens on:
+// privileged_fallback_listen if set, else http.listen, else the default 8989.
+func redirectTargetPort(configPath string) int {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return 8989
+	}
+	for _, addr := range []string{cfg.HTTP.PrivilegedFallbackListen, cfg.HTTP.ListenAddr} {
+		if addr == "" {
+			continue
+		}
+		if _, portStr, err := net.SplitHostPort(addr); err == nil {
+			if port, err := strconv.Atoi(portStr); err == nil && !netutil.IsPrivilegedPort(port) {
+				return port
+			}
+		}
+	}
+	return 8989
+}
+
+func parsePortList(s string) ([]int, error) {
+	var ports []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
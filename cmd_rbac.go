@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/rbac"
+)
+
+const rbacUsage = `Usage:
+  autotunnel rbac [-config path]
+
+Asks each route's cluster (SelfSubjectAccessReview) whether the kubeconfig's user
+may do what the route needs - get services/pods, list pods, create
+pods/portforward or pods/exec, create pods for via.create - and lists what is
+missing per route. dynamic_host, host_patterns and postgres routes pick their
+namespace per request and are not checked.`
+
+// runRBAC checks the permissions of every configured route
+func runRBAC(args []string) error {
+	fs := flag.NewFlagSet("rbac", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	timeout := fs.Duration("timeout", 30*time.Second, "Time limit for all checks")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), rbacUsage) }
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	routes := rbac.Routes(cfg)
+	if len(routes) == 0 {
+		fmt.Println("No routes to check")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	factory := k8sutil.NewClientFactory(false)
+	factory.SetContexts(cfg.Contexts)
+	checkers := make(map[string]*rbac.Checker) // by context
+	failed := 0
+	for _, route := range routes {
+		checker, ok := checkers[route.Context]
+		if !ok {
+			clientset, _, err := factory.GetClientForContext(route.Kubeconfigs, route.Context)
+			if err != nil {
+				fmt.Printf("✗ %s (%s): %v\n", route.Name, route.Context, err)
+				failed++
+				continue
+			}
+			checker = rbac.NewChecker(clientset)
+			checkers[route.Context] = checker
+		}
+
+		missing, err := checker.Missing(ctx, route)
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s (%s): %v\n", route.Name, route.Context, err)
+			failed++
+		case len(missing) > 0:
+			fmt.Printf("✗ %s (%s)\n", route.Name, route.Context)
+			for _, need := range missing {
+				fmt.Printf("    missing: %s\n", need)
+			}
+			failed++
+		default:
+			fmt.Printf("✓ %s (%s)\n", route.Name, route.Context)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d routes lack permissions or could not be checked", failed, len(routes))
+	}
+	return nil
+}
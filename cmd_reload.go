@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/watcher"
+)
+
+const reloadUsage = `Usage:
+  autotunnel reload [show] [-config path]   show the config change waiting to be applied
+  autotunnel reload apply [-config path]    apply it (restarts listeners and tunnels)
+  autotunnel reload discard [-config path]  drop it
+
+Used with reload_mode: confirm, where saved config changes are validated but held back.`
+
+// runReload shows, applies or discards a config change held back by reload_mode: confirm
+func runReload(args []string) error {
+	action := "show"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("reload", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.HTTP.StatusHost == "" {
+		return fmt.Errorf("the status API is disabled (http.status_host is empty)")
+	}
+	client := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost)
+
+	var info watcher.PendingInfo
+	switch action {
+	case "show":
+		if err := client.Get("/reload", &info); err != nil {
+			return err
+		}
+		if !info.Pending {
+			fmt.Println("No config change is waiting to be applied")
+			return nil
+		}
+		fmt.Printf("Pending config change (%d):\n", len(info.Changes))
+	case "apply":
+		if err := client.Do(http.MethodPost, "/reload/apply", &info); err != nil {
+			return err
+		}
+		fmt.Printf("Applied config change (%d):\n", len(info.Changes))
+	case "discard":
+		if err := client.Do(http.MethodDelete, "/reload", nil); err != nil {
+			return err
+		}
+		fmt.Println("Discarded pending config change")
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q\n%s", action, reloadUsage)
+	}
+
+	for _, change := range info.Changes {
+		fmt.Printf("  %s\n", change)
+	}
+	return nil
+}
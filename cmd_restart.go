@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+const restartUsage = `Usage:
+  autotunnel restart <route> [-config path]
+
+Stops a route's running tunnels so the next connection starts new ones, with the
+kubeconfig read again. Routes are hostnames, tcp:<port> or group:<name>.`
+
+// runRestart bounces a running route's tunnels through the status API
+func runRestart(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("missing route\n%s", restartUsage)
+	}
+	route, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("restart", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.HTTP.StatusHost == "" {
+		return fmt.Errorf("the status API is disabled (http.status_host is empty)")
+	}
+	client := admin.NewClient(cfg.HTTP.ListenAddr, cfg.HTTP.StatusHost)
+
+	var res tunnelmgr.RestartResult
+	if err := client.Do(http.MethodPost, "/tunnels/"+url.PathEscape(route)+"/restart", &res); err != nil {
+		return err
+	}
+	fmt.Printf("Restarted %s (%d tunnel(s) stopped)\n", res.Route, res.Stopped)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/atas/autotunnel/internal/selfupdate"
+)
+
+const selfUpdateUsage = `Usage:
+  autotunnel self-update [-check] [-force]
+
+Installs the latest GitHub release over this binary after checking the archive
+against the release's checksums.txt, and that file's signature against the
+release key built into this binary. Restart the service afterwards.`
+
+// runSelfUpdate replaces the running binary with the latest release
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release exists")
+	force := fs.Bool("force", false, "Install the latest release even if it isn't newer (e.g. to repair the binary)")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), selfUpdateUsage) }
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client := &http.Client{}
+
+	rel, err := selfupdate.Latest(ctx, client, selfupdate.LatestReleaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	newer := selfupdate.IsNewer(version, rel.Version)
+
+	if *check {
+		if newer {
+			fmt.Printf("autotunnel %s is available (running %s)\n", rel.Version, version)
+		} else {
+			fmt.Printf("autotunnel is up to date (running %s, latest %s)\n", version, rel.Version)
+		}
+		return nil
+	}
+	if !newer && !*force {
+		fmt.Printf("autotunnel is up to date (running %s, latest %s)\n", version, rel.Version)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if manager := selfupdate.ManagedBy(exe); manager == "homebrew" {
+		return fmt.Errorf("%s was installed with Homebrew, run `brew upgrade autotunnel` instead", exe)
+	}
+
+	fmt.Printf("Installing autotunnel %s over %s...\n", rel.Version, exe)
+	if err := selfupdate.Install(ctx, client, rel, runtime.GOOS, runtime.GOARCH, exe, releaseKey); err != nil {
+		return err
+	}
+	fmt.Printf("Updated to %s. Restart autotunnel (or its service) to run it.\n", rel.Version)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const sshUsage = `Usage:
+  autotunnel ssh <route> [-config path] [-cmd ssh|scp|sftp] -- <args>
+
+Runs ssh (or scp/sftp with -cmd) with its ProxyCommand set to
+'autotunnel stdio <route>', so the connection goes over the TCP or jump route:
+
+  autotunnel ssh tcp:2222 -- user@bastion.internal
+  autotunnel ssh tcp:2222 -cmd scp -- ./dump.sql user@bastion.internal:/tmp/`
+
+// runSSH spawns ssh, scp or sftp connecting through `autotunnel stdio <route>`.
+// The exit code of the spawned command is passed on.
+func runSSH(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("missing route\n%s", sshUsage)
+	}
+	route, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("ssh", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	command := fs.String("cmd", "ssh", "Command to run: ssh, scp or sftp")
+	_ = fs.Parse(args)
+
+	switch *command {
+	case "ssh", "scp", "sftp":
+	default:
+		return fmt.Errorf("invalid -cmd %q: expected ssh, scp or sftp", *command)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("missing %s arguments after --\n%s", *command, sshUsage)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the autotunnel binary: %w", err)
+	}
+	proxy := strings.Join([]string{shellQuote(self), "stdio", shellQuote(route), "-config", shellQuote(*configPath)}, " ")
+
+	cmd := exec.Command(*command, append([]string{"-o", "ProxyCommand=" + proxy}, fs.Args()...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode()) // ssh already reported why
+	}
+	return err
+}
+
+// shellQuote quotes s for the shell ssh runs ProxyCommand with
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tcpserver"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+const stdioUsage = `Usage:
+  autotunnel stdio <route> [-config path] [-verbose]
+
+Forwards stdin/stdout to a TCP or jump route, given as tcp:<port> or <port>,
+without listening on the port. Meant for SSH's ProxyCommand:
+
+  ssh -o ProxyCommand='autotunnel stdio tcp:2222' user@bastion`
+
+// runStdio forwards stdin/stdout through a route in this process, so it works
+// without the daemon running. Logs go to stderr, which ssh shows as-is.
+func runStdio(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return fmt.Errorf("missing route\n%s", stdioUsage)
+	}
+	route, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("stdio", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath(), "Path to configuration file")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	_ = fs.Parse(args)
+
+	port, err := strconv.Atoi(strings.TrimPrefix(route, "tcp:"))
+	if err != nil {
+		return fmt.Errorf("invalid route %q: expected tcp:<port>\n%s", route, stdioUsage)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	cfg.Verbose = cfg.Verbose || *verbose
+	cfg.TCP.PrometheusFileSD = "" // the daemon's file, not ours to empty on exit
+	cfg.ExecPath.Expand()
+
+	log.SetFlags(log.Ltime)
+	log.SetPrefix("[autotunnel] ")
+
+	manager := tunnelmgr.NewManager(cfg)
+	defer manager.Shutdown()
+	server := tcpserver.NewServer(cfg, manager)
+	defer server.Shutdown()
+
+	local, remote := net.Pipe()
+	go func() {
+		_, _ = io.Copy(local, os.Stdin)
+		local.Close() // ssh closed its end
+	}()
+	written := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(os.Stdout, local)
+		close(written)
+	}()
+
+	if err := server.Forward(port, remote); err != nil {
+		return err
+	}
+	local.Close()
+	<-written
+	return nil
+}
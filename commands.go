@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// subcommands are one-shot helpers run as `autotunnel <name> [flags]`.
+// Without a known subcommand, autotunnel runs the proxy as before.
+var subcommands = map[string]func(args []string) error{
+	"bench":            runBench,
+	"ca":               runCA,
+	"context":          runContext,
+	"debug":            runDebug,
+	"env":              runEnv,
+	"import":           runImport,
+	"keep-warm":        runKeepWarm,
+	"privileged-ports": runPrivilegedPorts,
+	"rbac":             runRBAC,
+	"reload":           runReload,
+	"restart":          runRestart,
+	"self-update":      runSelfUpdate,
+	"ssh":              runSSH,
+	"stdio":            runStdio,
+}
+
+// runSubcommand runs os.Args[1] if it names a subcommand and reports whether it did
+func runSubcommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		return false
+	}
+	if err := run(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "autotunnel %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// subcommandNames lists subcommands for usage output
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func defaultConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".autotunnel.yaml")
+}
@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Handler serves the status/admin API on the reserved status hostname (http.status_host).
+// Components register read-only status sections; GET /status returns all of them
+// as a single JSON object keyed by section name.
+type Handler struct {
+	mu       sync.RWMutex
+	sections map[string]func() any
+
+	mux *http.ServeMux
+}
+
+func NewHandler() *Handler {
+	h := &Handler{
+		sections: make(map[string]func() any),
+		mux:      http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /status", h.handleStatus)
+	return h
+}
+
+// AddSection registers a status section. fn is called on every GET /status,
+// so it must be safe for concurrent use.
+func (h *Handler) AddSection(name string, fn func() any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sections[name] = fn
+}
+
+// HandleFunc registers an additional admin endpoint (net/http pattern syntax)
+func (h *Handler) HandleFunc(pattern string, fn http.HandlerFunc) {
+	h.mux.HandleFunc(pattern, fn)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	status := make(map[string]any, len(h.sections))
+	for name, fn := range h.sections {
+		status[name] = fn()
+	}
+	h.mu.RUnlock()
+
+	WriteJSON(w, http.StatusOK, status)
+}
+
+// WriteJSON writes v as an indented JSON response
+func WriteJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// WriteError writes a JSON error response like {"error": "..."}
+func WriteError(w http.ResponseWriter, code int, msg string) {
+	WriteJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Status(t *testing.T) {
+	h := NewHandler()
+	h.AddSection("tunnels", func() any { return []string{"a.localhost"} })
+	h.AddSection("count", func() any { return 2 })
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if got["count"] != float64(2) {
+		t.Errorf("Expected count=2, got %v", got["count"])
+	}
+	if tunnels, ok := got["tunnels"].([]any); !ok || len(tunnels) != 1 {
+		t.Errorf("Expected one tunnel, got %v", got["tunnels"])
+	}
+}
+
+func TestHandler_StatusRejectsPost(t *testing.T) {
+	h := NewHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
+
+func TestHandler_HandleFunc(t *testing.T) {
+	h := NewHandler()
+	h.HandleFunc("POST /tunnels/{host}/restart", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"host": r.PathValue("host")})
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tunnels/app.localhost/restart", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); body != "{\n  \"host\": \"app.localhost\"\n}\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestClient_Get(t *testing.T) {
+	h := NewHandler()
+	h.AddSection("count", func() any { return 2 })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "autotunnel.localhost" {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	client := NewClient(strings.TrimPrefix(srv.URL, "http://"), "autotunnel.localhost")
+
+	var status struct {
+		Count int `json:"count"`
+	}
+	if err := client.Get("/status", &status); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if status.Count != 2 {
+		t.Errorf("Expected count=2, got %d", status.Count)
+	}
+
+	if err := client.Get("/missing", &status); err == nil {
+		t.Error("Expected error for unknown endpoint")
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	h := NewHandler()
+	h.HandleFunc("POST /things", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"name": "created"})
+	})
+	h.HandleFunc("DELETE /things", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h.HandleFunc("PUT /things", func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, http.StatusConflict, "nothing to update")
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	client := NewClient(strings.TrimPrefix(srv.URL, "http://"), "autotunnel.localhost")
+
+	var created struct {
+		Name string `json:"name"`
+	}
+	if err := client.Do(http.MethodPost, "/things", &created); err != nil || created.Name != "created" {
+		t.Errorf("POST: got %+v, %v", created, err)
+	}
+	if err := client.Do(http.MethodDelete, "/things", nil); err != nil {
+		t.Errorf("DELETE: unexpected error %v", err)
+	}
+	if err := client.Do(http.MethodPut, "/things", nil); err == nil || !strings.Contains(err.Error(), "nothing to update") {
+		t.Errorf("PUT: expected the API error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Client calls the admin API of a running autotunnel. It connects to http.listen
+// directly and sets the Host header to the status host, so it doesn't depend on
+// *.localhost resolving on this machine.
+type Client struct {
+	baseURL    string
+	statusHost string
+	http       *http.Client
+}
+
+// NewClient creates a client for the daemon listening on listenAddr (e.g. ":8989")
+func NewClient(listenAddr, statusHost string) *Client {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		host, port = listenAddr, "80"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return &Client{
+		baseURL:    "http://" + net.JoinHostPort(host, port),
+		statusHost: statusHost,
+		http:       &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// Get fetches path (e.g. "/status") and decodes the JSON response into v
+func (c *Client) Get(path string, v any) error {
+	return c.Do(http.MethodGet, path, v)
+}
+
+// Do sends a request without a body and decodes the JSON response into v,
+// unless v is nil or the response has no content
+func (c *Client) Do(method, path string, v any) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = c.statusHost
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("autotunnel is not reachable at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", path, apiErr.Error)
+		}
+		return fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	}
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,212 @@
+// Package authz decides whether a connection may use a route, using the
+// authorizers configured at the top level or on the route itself.
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// Request kinds
+const (
+	KindHTTP = "http" // an HTTP request, plain or with TLS terminated here
+	KindTLS  = "tls"  // a TLS passthrough connection
+	KindTCP  = "tcp"  // a connection to a TCP, jump, group or postgres port, or a mode: tcp route
+)
+
+// Request describes a connection asking to use a route
+type Request struct {
+	Route      string // hostname, or "tcp:<port>" for TCP listeners
+	Kind       string
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+
+	Token            string              // credential sent with an HTTP request
+	PeerCertificates []*x509.Certificate // client certificates of a TLS connection terminated here
+}
+
+// Authorizer decides whether a request may go through. It returns the name the
+// client was identified as, or an error saying why it was turned away.
+type Authorizer interface {
+	Authorize(ctx context.Context, req Request) (user string, err error)
+}
+
+// Any allows a request when one of its authorizers does
+type Any []Authorizer
+
+func (a Any) Authorize(ctx context.Context, req Request) (string, error) {
+	reasons := make([]string, 0, len(a))
+	for _, authorizer := range a {
+		user, err := authorizer.Authorize(ctx, req)
+		if err == nil {
+			return user, nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return "", fmt.Errorf("not authorized: %s", strings.Join(reasons, "; "))
+}
+
+// New builds an authorizer from its config
+func New(cfg config.AuthorizerConfig) (Authorizer, error) {
+	switch cfg.Type {
+	case config.AuthorizerToken:
+		return newTokenAuthorizer(cfg), nil
+	case config.AuthorizerOSUser:
+		return newOSUserAuthorizer(cfg), nil
+	case config.AuthorizerClientCert:
+		return newClientCertAuthorizer(cfg)
+	case config.AuthorizerCommand:
+		return newCommandAuthorizer(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown authorizer type %q", cfg.Type)
+}
+
+func newAny(cfgs []config.AuthorizerConfig) (Any, error) {
+	authorizers := make(Any, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		a, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		authorizers = append(authorizers, a)
+	}
+	return authorizers, nil
+}
+
+// Policy holds the authorizers of every route. A route's own authorizers replace
+// the top-level ones.
+type Policy struct {
+	global      Any
+	routes      map[string]Any
+	patterns    []hostPattern // http.k8s.host_patterns, in order
+	clientCerts bool          // some authorizer checks client certificates
+}
+
+// hostPattern is a host pattern and its authorizers (nil = the top-level ones)
+type hostPattern struct {
+	config.HostPatternConfig
+	authorizers Any
+}
+
+// NewPolicy builds the authorizers configured in cfg. It returns nil when there
+// are none, and a nil policy allows everything.
+func NewPolicy(cfg *config.Config) (*Policy, error) {
+	p := &Policy{routes: make(map[string]Any)}
+	all := append([]config.AuthorizerConfig(nil), cfg.Authorizers...)
+
+	var err error
+	if p.global, err = newAny(cfg.Authorizers); err != nil {
+		return nil, fmt.Errorf("authorizers: %w", err)
+	}
+	add := func(route string, cfgs []config.AuthorizerConfig) error {
+		if len(cfgs) == 0 {
+			return nil
+		}
+		authorizers, err := newAny(cfgs)
+		if err != nil {
+			return fmt.Errorf("authorizers of %s: %w", route, err)
+		}
+		p.routes[route] = authorizers
+		all = append(all, cfgs...)
+		return nil
+	}
+	for hostname, route := range cfg.HTTP.K8s.Routes {
+		if err := add(hostname, route.Authorizers); err != nil {
+			return nil, err
+		}
+		// a route without authorizers of its own uses the top-level ones, even
+		// when a host pattern with authorizers matches its hostname
+		if _, ok := p.routes[hostname]; !ok && len(cfg.HTTP.K8s.HostPatterns) > 0 {
+			p.routes[hostname] = p.global
+		}
+	}
+	for i, pattern := range cfg.HTTP.K8s.HostPatterns {
+		authorizers, err := newAny(pattern.Authorizers)
+		if err != nil {
+			return nil, fmt.Errorf("authorizers of http.k8s.host_patterns[%d]: %w", i, err)
+		}
+		p.patterns = append(p.patterns, hostPattern{HostPatternConfig: pattern, authorizers: authorizers})
+		all = append(all, pattern.Authorizers...)
+	}
+	for port, route := range cfg.TCP.K8s.Routes {
+		if err := add(TCPRoute(port), route.Authorizers); err != nil {
+			return nil, err
+		}
+	}
+	for port, route := range cfg.TCP.K8s.Jump {
+		if err := add(TCPRoute(port), route.Authorizers); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(all) == 0 {
+		return nil, nil
+	}
+	for _, a := range all {
+		if a.Type == config.AuthorizerClientCert {
+			p.clientCerts = true
+		}
+	}
+	return p, nil
+}
+
+// TCPRoute is the route name of a TCP listener
+func TCPRoute(port int) string {
+	return fmt.Sprintf("tcp:%d", port)
+}
+
+// Check authorizes req against its route's authorizers. Routes without any are
+// open, and so is everything when p is nil.
+func (p *Policy) Check(ctx context.Context, req Request) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+	authorizers := p.authorizers(req.Route)
+	if len(authorizers) == 0 {
+		return "", nil
+	}
+	return authorizers.Authorize(ctx, req)
+}
+
+// TakesToken reports whether route's authorizers read the request's token, which
+// then isn't meant for the backend
+func (p *Policy) TakesToken(route string) bool {
+	if p == nil {
+		return false
+	}
+	for _, a := range p.authorizers(route) {
+		switch a.(type) {
+		case *tokenAuthorizer, *commandAuthorizer:
+			return true
+		}
+	}
+	return false
+}
+
+// authorizers returns the route's own authorizers, those of the first host
+// pattern matching it, or else the top-level ones. Like tunnel lookups, routes
+// win over patterns.
+func (p *Policy) authorizers(route string) Any {
+	if authorizers, ok := p.routes[route]; ok {
+		return authorizers
+	}
+	for _, pattern := range p.patterns {
+		if _, ok := pattern.Match(route); ok {
+			if len(pattern.authorizers) > 0 {
+				return pattern.authorizers
+			}
+			break
+		}
+	}
+	return p.global
+}
+
+// WantsClientCerts reports whether TLS terminated here should ask clients for a certificate
+func (p *Policy) WantsClientCerts() bool {
+	return p != nil && p.clientCerts
+}
@@ -0,0 +1,278 @@
+package authz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+const testToken = "0123456789abcdef"
+
+func tokenConfig() config.AuthorizerConfig {
+	return config.AuthorizerConfig{Type: config.AuthorizerToken, Tokens: map[string]string{"alice": testToken}}
+}
+
+func TestPolicy_RouteAuthorizersReplaceGlobal(t *testing.T) {
+	cfg := &config.Config{Authorizers: []config.AuthorizerConfig{tokenConfig()}}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"open.localhost": {},
+		"cmd.localhost":  {Authorizers: []config.AuthorizerConfig{{Type: config.AuthorizerCommand, Command: []string{"true"}}}},
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := p.Check(ctx, Request{Route: "open.localhost", Kind: KindHTTP}); err == nil {
+		t.Error("Expected the global token authorizer to deny a request without a token")
+	}
+	if user, err := p.Check(ctx, Request{Route: "open.localhost", Kind: KindHTTP, Token: testToken}); err != nil || user != "alice" {
+		t.Errorf("Check with token = %q, %v; want alice", user, err)
+	}
+	if _, err := p.Check(ctx, Request{Route: "cmd.localhost", Kind: KindHTTP}); err != nil {
+		t.Errorf("Expected the route's command authorizer to allow, got %v", err)
+	}
+}
+
+func TestPolicy_HostPatternAuthorizers(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{"open.dev.localhost": {}}
+	cfg.HTTP.K8s.HostPatterns = []config.HostPatternConfig{{
+		Suffix:         ".dev.localhost",
+		K8sRouteConfig: config.K8sRouteConfig{Context: "dev", Namespace: "default", Service: "$prefix", Port: 80, Authorizers: []config.AuthorizerConfig{tokenConfig()}},
+	}}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := p.Check(ctx, Request{Route: "api.dev.localhost", Kind: KindHTTP}); err == nil {
+		t.Error("Expected the pattern's token authorizer to deny a request without a token")
+	}
+	if user, err := p.Check(ctx, Request{Route: "api.dev.localhost", Kind: KindHTTP, Token: testToken}); err != nil || user != "alice" {
+		t.Errorf("Check with token = %q, %v; want alice", user, err)
+	}
+	if _, err := p.Check(ctx, Request{Route: "open.dev.localhost", Kind: KindHTTP}); err != nil {
+		t.Errorf("Expected the route to win over the pattern, got %v", err)
+	}
+	if _, err := p.Check(ctx, Request{Route: "api.prod.localhost", Kind: KindHTTP}); err != nil {
+		t.Errorf("Expected a hostname outside the pattern to stay open, got %v", err)
+	}
+}
+
+func TestPolicy_TakesToken(t *testing.T) {
+	cfg := &config.Config{Authorizers: []config.AuthorizerConfig{tokenConfig()}}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"local.localhost": {Authorizers: []config.AuthorizerConfig{{Type: config.AuthorizerOSUser, Users: []string{"alice"}}}},
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	if !p.TakesToken("api.localhost") {
+		t.Error("Expected the global token authorizer to take the token")
+	}
+	if p.TakesToken("local.localhost") {
+		t.Error("Expected an os_user route to leave the token to the backend")
+	}
+	if (*Policy)(nil).TakesToken("api.localhost") {
+		t.Error("Expected a nil policy to take no token")
+	}
+}
+
+func TestPolicy_NilAllowsEverything(t *testing.T) {
+	p, err := NewPolicy(&config.Config{})
+	if err != nil || p != nil {
+		t.Fatalf("NewPolicy without authorizers = %v, %v; want nil", p, err)
+	}
+	if _, err := p.Check(context.Background(), Request{Route: "tcp:5432", Kind: KindTCP}); err != nil {
+		t.Errorf("nil policy denied: %v", err)
+	}
+	if p.WantsClientCerts() {
+		t.Error("nil policy should not ask for client certificates")
+	}
+}
+
+func TestAny_CollectsReasons(t *testing.T) {
+	a := Any{newTokenAuthorizer(tokenConfig()), newCommandAuthorizer(config.AuthorizerConfig{Command: []string{"false"}})}
+	_, err := a.Authorize(context.Background(), Request{Route: "tcp:1", Kind: KindTCP})
+	if err == nil || !strings.Contains(err.Error(), "no token") || !strings.Contains(err.Error(), "false") {
+		t.Errorf("Expected both reasons in the error, got %v", err)
+	}
+}
+
+func TestTokenAuthorizer(t *testing.T) {
+	a := newTokenAuthorizer(tokenConfig())
+	if _, err := a.Authorize(context.Background(), Request{Token: "wrong-token-wrong"}); err == nil {
+		t.Error("Expected an unknown token to be denied")
+	}
+}
+
+func TestOSUserAuthorizer(t *testing.T) {
+	me, err := user.Current()
+	if err != nil {
+		t.Skipf("current user unknown: %v", err)
+	}
+	uid := os.Getuid()
+	self := func(net.Addr, net.Addr) (int, error) { return uid, nil }
+	req := Request{LocalAddr: &net.TCPAddr{}, RemoteAddr: &net.TCPAddr{}}
+
+	a := &osUserAuthorizer{users: map[string]bool{me.Username: true}, peerUID: self}
+	if name, err := a.Authorize(context.Background(), req); err != nil || name != me.Username {
+		t.Errorf("Authorize = %q, %v; want %s", name, err, me.Username)
+	}
+
+	a = &osUserAuthorizer{users: map[string]bool{"someone-else": true}, peerUID: self}
+	if _, err := a.Authorize(context.Background(), req); err == nil {
+		t.Error("Expected a user not in the list to be denied")
+	}
+}
+
+func TestCommandAuthorizer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	ctx := context.Background()
+	req := Request{Route: "db.localhost", Kind: KindHTTP}
+
+	a := newCommandAuthorizer(config.AuthorizerConfig{Command: []string{"sh", "-c", `[ "$AUTOTUNNEL_ROUTE" = db.localhost ] && echo bob`}})
+	if user, err := a.Authorize(ctx, req); err != nil || user != "bob" {
+		t.Errorf("Authorize = %q, %v; want bob", user, err)
+	}
+
+	a = newCommandAuthorizer(config.AuthorizerConfig{Command: []string{"sh", "-c", "echo go away >&2; exit 1"}})
+	if _, err := a.Authorize(ctx, req); err == nil || !strings.Contains(err.Error(), "go away") {
+		t.Errorf("Expected stderr in the error, got %v", err)
+	}
+
+	a = newCommandAuthorizer(config.AuthorizerConfig{Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond})
+	if _, err := a.Authorize(ctx, req); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+func TestCommandAuthorizer_TokenOnStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	req := Request{Route: "db.localhost", Kind: KindHTTP, Token: "secret-token-0123456789"}
+	a := newCommandAuthorizer(config.AuthorizerConfig{Command: []string{"sh", "-c",
+		`read -r token; [ "$token" = secret-token-0123456789 ] && [ -z "$AUTOTUNNEL_TOKEN" ] && echo carol`}})
+	if user, err := a.Authorize(context.Background(), req); err != nil || user != "carol" {
+		t.Errorf("Authorize = %q, %v; want carol", user, err)
+	}
+}
+
+func TestCommandAuthorizer_CacheTTL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	// the command counts its runs in a file and allows tokens starting with "ok"
+	runs := filepath.Join(t.TempDir(), "runs")
+	a := newCommandAuthorizer(config.AuthorizerConfig{
+		Command:  []string{"sh", "-c", `echo run >> "$0"; read -r token; case "$token" in ok*) echo dave;; *) exit 1;; esac`, runs},
+		CacheTTL: time.Minute,
+	})
+	countRuns := func() int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+	ctx := context.Background()
+	client := func(port int) net.Addr { return &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: port} }
+
+	for port := 40000; port < 40003; port++ {
+		req := Request{Route: "db.localhost", Kind: KindHTTP, RemoteAddr: client(port), Token: "ok-token"}
+		if user, err := a.Authorize(ctx, req); err != nil || user != "dave" {
+			t.Fatalf("Authorize = %q, %v; want dave", user, err)
+		}
+	}
+	if n := countRuns(); n != 1 {
+		t.Errorf("Expected one run for the same route, client IP and token, got %d", n)
+	}
+
+	denied := Request{Route: "db.localhost", Kind: KindHTTP, RemoteAddr: client(40000), Token: "bad-token"}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Authorize(ctx, denied); err == nil {
+			t.Fatal("Expected another token to be denied")
+		}
+	}
+	other := Request{Route: "api.localhost", Kind: KindHTTP, RemoteAddr: client(40000), Token: "ok-token"}
+	if _, err := a.Authorize(ctx, other); err != nil {
+		t.Fatalf("Authorize(other route) = %v", err)
+	}
+	if n := countRuns(); n != 3 {
+		t.Errorf("Expected the denial to be cached and another route to run the command, got %d runs", n)
+	}
+}
+
+func TestClientCertAuthorizer(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	ca, _ := x509.ParseCertificate(caDER)
+
+	issue := func(cn string, signer *x509.Certificate, key *ecdsa.PrivateKey) *x509.Certificate {
+		leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, _ := x509.CreateCertificate(rand.Reader, tmpl, signer, &leafKey.PublicKey, key)
+		cert, _ := x509.ParseCertificate(der)
+		return cert
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := newClientCertAuthorizer(config.AuthorizerConfig{CAFile: caFile, Names: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("newClientCertAuthorizer failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if name, err := a.Authorize(ctx, Request{PeerCertificates: []*x509.Certificate{issue("alice", ca, caKey)}}); err != nil || name != "alice" {
+		t.Errorf("Authorize = %q, %v; want alice", name, err)
+	}
+	if _, err := a.Authorize(ctx, Request{PeerCertificates: []*x509.Certificate{issue("mallory", ca, caKey)}}); err == nil {
+		t.Error("Expected a name not in the list to be denied")
+	}
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	selfSigned := issue("alice", caTmpl, otherKey) // right name, wrong issuer key
+	if _, err := a.Authorize(ctx, Request{PeerCertificates: []*x509.Certificate{selfSigned}}); err == nil {
+		t.Error("Expected a certificate not signed by the CA to be denied")
+	}
+	if _, err := a.Authorize(ctx, Request{}); err == nil {
+		t.Error("Expected a request without certificate to be denied")
+	}
+}
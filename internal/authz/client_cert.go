@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// clientCertAuthorizer lets through TLS connections terminated here whose client
+// certificate chains to its CA and, if names is set, carries one of them
+type clientCertAuthorizer struct {
+	roots *x509.CertPool
+	names map[string]bool
+}
+
+func newClientCertAuthorizer(cfg config.AuthorizerConfig) (*clientCertAuthorizer, error) {
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca_file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+	}
+	names := make(map[string]bool, len(cfg.Names))
+	for _, name := range cfg.Names {
+		names[name] = true
+	}
+	return &clientCertAuthorizer{roots: roots, names: names}, nil
+}
+
+func (a *clientCertAuthorizer) Authorize(_ context.Context, req Request) (string, error) {
+	if len(req.PeerCertificates) == 0 {
+		return "", errors.New("no client certificate")
+	}
+	leaf := req.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range req.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         a.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", fmt.Errorf("client certificate: %w", err)
+	}
+
+	if len(a.names) == 0 {
+		return leaf.Subject.CommonName, nil
+	}
+	for _, name := range append([]string{leaf.Subject.CommonName}, leaf.DNSNames...) {
+		if a.names[name] {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("client certificate %q is not allowed", leaf.Subject.CommonName)
+}
@@ -0,0 +1,157 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// maxCachedDecisions bounds the decision cache; expired entries are dropped
+// when it is reached
+const maxCachedDecisions = 4096
+
+// commandAuthorizer runs a program for every request. Exit status 0 lets the
+// request through, and the first line of stdout names the user. The request is
+// described in AUTOTUNNEL_* environment variables; the token, if any, is written
+// to stdin so it doesn't show up in the process list. With cacheTTL, decisions
+// are reused per route, client IP, token and client certificate.
+type commandAuthorizer struct {
+	argv     []string
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	decisions map[[sha256.Size]byte]decision
+}
+
+// decision is a cached outcome of the command
+type decision struct {
+	user    string
+	err     error
+	expires time.Time
+}
+
+// deniedError is a non-zero exit of the command: a decision, so unlike timeouts
+// and failures to run it, it may be cached
+type deniedError struct{ msg string }
+
+func (e *deniedError) Error() string { return e.msg }
+
+func newCommandAuthorizer(cfg config.AuthorizerConfig) *commandAuthorizer {
+	return &commandAuthorizer{
+		argv:      cfg.Command,
+		timeout:   cfg.GetTimeout(),
+		cacheTTL:  cfg.CacheTTL,
+		decisions: make(map[[sha256.Size]byte]decision),
+	}
+}
+
+func (a *commandAuthorizer) Authorize(ctx context.Context, req Request) (string, error) {
+	if a.cacheTTL <= 0 {
+		return a.run(ctx, req)
+	}
+
+	key := decisionKey(req)
+	a.mu.Lock()
+	d, ok := a.decisions[key]
+	a.mu.Unlock()
+	if ok && time.Now().Before(d.expires) {
+		return d.user, d.err
+	}
+
+	user, err := a.run(ctx, req)
+	var denied *deniedError
+	if err == nil || errors.As(err, &denied) {
+		a.remember(key, decision{user: user, err: err, expires: time.Now().Add(a.cacheTTL)})
+	}
+	return user, err
+}
+
+func (a *commandAuthorizer) run(ctx context.Context, req Request) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.argv[0], a.argv[1:]...)
+	cmd.Env = append(os.Environ(), commandEnv(req)...)
+	if req.Token != "" {
+		cmd.Stdin = strings.NewReader(req.Token + "\n")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s timed out after %v", a.argv[0], a.timeout)
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s: %w", a.argv[0], err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", &deniedError{fmt.Sprintf("%s: %s", a.argv[0], msg)}
+		}
+		return "", &deniedError{fmt.Sprintf("%s: %v", a.argv[0], err)}
+	}
+	user, _, _ := strings.Cut(stdout.String(), "\n")
+	return strings.TrimSpace(user), nil
+}
+
+// remember caches d, first dropping expired decisions when the cache is full
+func (a *commandAuthorizer) remember(key [sha256.Size]byte, d decision) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.decisions) >= maxCachedDecisions {
+		now := time.Now()
+		for k, old := range a.decisions {
+			if now.After(old.expires) {
+				delete(a.decisions, k)
+			}
+		}
+		if len(a.decisions) >= maxCachedDecisions {
+			return
+		}
+	}
+	a.decisions[key] = d
+}
+
+// decisionKey identifies what the command decides on; the client's port is left
+// out so new connections from the same client hit the cache
+func decisionKey(req Request) [sha256.Size]byte {
+	var ip string
+	if req.RemoteAddr != nil {
+		ip = req.RemoteAddr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	var subject string
+	if len(req.PeerCertificates) > 0 {
+		subject = string(req.PeerCertificates[0].Raw)
+	}
+	return sha256.Sum256([]byte(strings.Join([]string{req.Route, req.Kind, ip, req.Token, subject}, "\x00")))
+}
+
+func commandEnv(req Request) []string {
+	env := []string{
+		"AUTOTUNNEL_ROUTE=" + req.Route,
+		"AUTOTUNNEL_KIND=" + req.Kind,
+	}
+	if req.RemoteAddr != nil {
+		env = append(env, "AUTOTUNNEL_REMOTE_ADDR="+req.RemoteAddr.String())
+	}
+	if len(req.PeerCertificates) > 0 {
+		env = append(env, "AUTOTUNNEL_CLIENT_CERT_SUBJECT="+req.PeerCertificates[0].Subject.String())
+	}
+	return env
+}
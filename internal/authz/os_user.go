@@ -0,0 +1,45 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/user"
+	"strconv"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// osUserAuthorizer lets through local connections opened by one of its users,
+// looking up who owns the client's socket
+type osUserAuthorizer struct {
+	users   map[string]bool
+	peerUID func(local, remote net.Addr) (int, error)
+}
+
+func newOSUserAuthorizer(cfg config.AuthorizerConfig) *osUserAuthorizer {
+	users := make(map[string]bool, len(cfg.Users))
+	for _, name := range cfg.Users {
+		users[name] = true
+	}
+	return &osUserAuthorizer{users: users, peerUID: netutil.PeerUID}
+}
+
+func (a *osUserAuthorizer) Authorize(_ context.Context, req Request) (string, error) {
+	if req.LocalAddr == nil || req.RemoteAddr == nil {
+		return "", fmt.Errorf("connection addresses unknown")
+	}
+	uid, err := a.peerUID(req.LocalAddr, req.RemoteAddr)
+	if err != nil {
+		return "", fmt.Errorf("cannot find local user: %w", err)
+	}
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return "", fmt.Errorf("cannot find local user: %w", err)
+	}
+	if !a.users[u.Username] {
+		return "", fmt.Errorf("local user %s is not allowed", u.Username)
+	}
+	return u.Username, nil
+}
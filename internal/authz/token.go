@@ -0,0 +1,31 @@
+package authz
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// tokenAuthorizer lets through HTTP requests carrying one of its tokens. Raw TCP
+// has nowhere to put a token, so it never allows those.
+type tokenAuthorizer struct {
+	tokens map[string]string // user -> token
+}
+
+func newTokenAuthorizer(cfg config.AuthorizerConfig) *tokenAuthorizer {
+	return &tokenAuthorizer{tokens: cfg.Tokens}
+}
+
+func (a *tokenAuthorizer) Authorize(_ context.Context, req Request) (string, error) {
+	if req.Token == "" {
+		return "", errors.New("no token")
+	}
+	for user, token := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(req.Token), []byte(token)) == 1 {
+			return user, nil
+		}
+	}
+	return "", errors.New("invalid token")
+}
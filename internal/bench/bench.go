@@ -0,0 +1,205 @@
+// Package bench drives concurrent load through a route for `autotunnel bench`
+// and summarizes latency and throughput.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is one request or connection; it returns the bytes it received
+type Op func(ctx context.Context) (int64, error)
+
+// Options control a run. It stops after Requests operations, or after Duration
+// when Requests is 0.
+type Options struct {
+	Concurrency int
+	Requests    int
+	Duration    time.Duration
+	Warmup      int // operations run first and left out of the results, e.g. to start the tunnel
+}
+
+// Result summarizes a run. Latencies cover successful operations only.
+type Result struct {
+	Requests int           `json:"requests"`
+	Errors   int           `json:"errors"`
+	Bytes    int64         `json:"bytes"`
+	Elapsed  time.Duration `json:"elapsed"`
+	Min      time.Duration `json:"min"`
+	P50      time.Duration `json:"p50"`
+	P90      time.Duration `json:"p90"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+	// ErrorSamples counts errors by message, so one failure mode doesn't hide another
+	ErrorSamples map[string]int `json:"error_samples,omitempty"`
+}
+
+// RequestsPerSecond is the rate of all operations, failed ones included
+func (r Result) RequestsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// BytesPerSecond is the rate of received bytes
+func (r Result) BytesPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+// maxErrorSamples caps the distinct error messages kept in a Result
+const maxErrorSamples = 10
+
+// Run runs op with opts.Concurrency workers until the request count or duration
+// is reached or ctx is done
+func Run(ctx context.Context, op Op, opts Options) (Result, error) {
+	if opts.Concurrency < 1 {
+		return Result{}, fmt.Errorf("concurrency must be at least 1")
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		return Result{}, fmt.Errorf("either a request count or a duration is required")
+	}
+
+	for i := 0; i < opts.Warmup; i++ {
+		if _, err := op(ctx); err != nil {
+			return Result{}, fmt.Errorf("warmup failed: %w", err)
+		}
+	}
+
+	if opts.Requests <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		issued    atomic.Int64
+		mu        sync.Mutex
+		latencies []time.Duration
+		res       = Result{ErrorSamples: make(map[string]int)}
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if opts.Requests > 0 && issued.Add(1) > int64(opts.Requests) {
+					return
+				}
+				opStart := time.Now()
+				n, err := op(ctx)
+				latency := time.Since(opStart)
+				if err != nil && ctx.Err() != nil && opts.Requests <= 0 {
+					return // cut off by the end of the run, not a failure
+				}
+
+				mu.Lock()
+				res.Requests++
+				res.Bytes += n
+				if err != nil {
+					res.Errors++
+					if _, ok := res.ErrorSamples[err.Error()]; ok || len(res.ErrorSamples) < maxErrorSamples {
+						res.ErrorSamples[err.Error()]++
+					}
+				} else {
+					latencies = append(latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if len(latencies) > 0 {
+		res.Min = latencies[0]
+		res.P50 = percentile(latencies, 50)
+		res.P90 = percentile(latencies, 90)
+		res.P99 = percentile(latencies, 99)
+		res.Max = latencies[len(latencies)-1]
+	}
+	if len(res.ErrorSamples) == 0 {
+		res.ErrorSamples = nil
+	}
+	return res, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// HTTPOp sends GET url with the Host header set to host and reads the whole
+// body. Statuses of 500 and above count as errors, since the proxy answers 502
+// when the tunnel or backend fails.
+func HTTPOp(client *http.Client, url, host string) Op {
+	return func(ctx context.Context) (int64, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Host = host
+		req.Header.Set("User-Agent", "autotunnel-bench")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		n, err := io.Copy(io.Discard, resp.Body)
+		if err != nil {
+			return n, err
+		}
+		if resp.StatusCode >= 500 {
+			return n, fmt.Errorf("status %s", resp.Status)
+		}
+		return n, nil
+	}
+}
+
+// TCPOp opens a connection to addr per operation, writes send (if any) and
+// waits up to timeout for the first byte back, which ends the measurement.
+// Without send, it waits for a server greeting (SSH, MySQL, SMTP, ...).
+func TCPOp(addr string, send []byte, timeout time.Duration) Op {
+	return func(ctx context.Context) (int64, error) {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+
+		if len(send) > 0 {
+			if _, err := conn.Write(send); err != nil {
+				return 0, err
+			}
+		}
+		buf := make([]byte, 32*1024)
+		n, err := conn.Read(buf)
+		if n > 0 {
+			return int64(n), nil
+		}
+		if err == io.EOF {
+			return 0, fmt.Errorf("connection closed before any data")
+		}
+		return 0, err
+	}
+}
@@ -0,0 +1,138 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_RequestCount(t *testing.T) {
+	var calls atomic.Int64
+	op := func(ctx context.Context) (int64, error) {
+		if calls.Add(1)%10 == 0 {
+			return 0, errors.New("boom")
+		}
+		return 100, nil
+	}
+
+	res, err := Run(context.Background(), op, Options{Concurrency: 4, Requests: 50, Warmup: 2})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if calls.Load() != 52 {
+		t.Errorf("op ran %d times, want 50 + 2 warmup", calls.Load())
+	}
+	if res.Requests != 50 {
+		t.Errorf("Requests = %d, want 50 (warmup excluded)", res.Requests)
+	}
+	if res.Errors != 5 || res.ErrorSamples["boom"] != 5 {
+		t.Errorf("Errors = %d, samples %v, want 5 boom", res.Errors, res.ErrorSamples)
+	}
+	if res.Bytes != 4500 {
+		t.Errorf("Bytes = %d, want 4500", res.Bytes)
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	op := func(ctx context.Context) (int64, error) {
+		select {
+		case <-time.After(5 * time.Millisecond):
+			return 1, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	res, err := Run(context.Background(), op, Options{Concurrency: 2, Duration: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Requests == 0 || res.Errors != 0 {
+		t.Errorf("Requests = %d, Errors = %d; operations cut off at the end must not count as errors", res.Requests, res.Errors)
+	}
+	if res.P50 < 5*time.Millisecond || res.Min > res.P50 || res.P50 > res.P99 || res.P99 > res.Max {
+		t.Errorf("percentiles out of order: min %v p50 %v p99 %v max %v", res.Min, res.P50, res.P99, res.Max)
+	}
+}
+
+func TestRun_WarmupFailure(t *testing.T) {
+	op := func(ctx context.Context) (int64, error) { return 0, errors.New("no route") }
+	if _, err := Run(context.Background(), op, Options{Concurrency: 1, Requests: 1, Warmup: 1}); err == nil {
+		t.Error("Expected a failed warmup to abort the run")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{{50, 50}, {90, 90}, {99, 99}, {100, 100}}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%d) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := percentile(sorted[:1], 99); got != 1 {
+		t.Errorf("percentile of one sample = %v, want 1", got)
+	}
+}
+
+func TestHTTPOp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.localhost" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	n, err := HTTPOp(srv.Client(), srv.URL+"/", "api.localhost")(context.Background())
+	if err != nil || n != 5 {
+		t.Errorf("HTTPOp = %d, %v; want 5 bytes", n, err)
+	}
+	if _, err := HTTPOp(srv.Client(), srv.URL+"/", "other.localhost")(context.Background()); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected a 502 to count as an error, got %v", err)
+	}
+}
+
+func TestTCPOp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 16)
+				n, _ := conn.Read(buf)
+				_, _ = conn.Write(buf[:n])
+			}()
+		}
+	}()
+
+	n, err := TCPOp(ln.Addr().String(), []byte("ping"), time.Second)(context.Background())
+	if err != nil || n != 4 {
+		t.Errorf("TCPOp = %d, %v; want the 4 echoed bytes", n, err)
+	}
+	// nothing sent and no greeting: the server closes after its read times out
+	if _, err := TCPOp(ln.Addr().String(), nil, 100*time.Millisecond)(context.Background()); err == nil {
+		t.Error("Expected an error when the server sends nothing")
+	}
+}
@@ -0,0 +1,227 @@
+// Package cloudctx writes kubeconfig entries for managed clusters (EKS, GKE, AKS)
+// that authenticate through the provider's exec credential plugin, for
+// `autotunnel context add-eks|add-gke|add-aks`.
+package cloudctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// execAPIVersion is the credential plugin protocol the generated entries use
+const execAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// aksServerID is the Entra ID application every AAD-enabled AKS API server accepts tokens for
+const aksServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+// Runner runs a provider CLI and returns its standard output
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// RunCommand is the Runner using the installed CLIs
+func RunCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+// Cluster is what a kubeconfig entry needs: where the API server is, the CA it
+// presents, and the plugin fetching tokens for it
+type Cluster struct {
+	Server string
+	CAData []byte
+	Exec   *clientcmdapi.ExecConfig
+}
+
+// EKS describes an EKS cluster with the aws CLI. Tokens come from `aws eks get-token`.
+func EKS(ctx context.Context, run Runner, name, region, profile string) (*Cluster, error) {
+	args := []string{"eks", "describe-cluster", "--name", name, "--output", "json"}
+	tokenArgs := []string{"eks", "get-token", "--cluster-name", name, "--output", "json"}
+	if region != "" {
+		args = append(args, "--region", region)
+		tokenArgs = append(tokenArgs, "--region", region)
+	}
+	out, err := run(ctx, "aws", args...)
+	if err != nil {
+		return nil, err
+	}
+	var described struct {
+		Cluster struct {
+			Endpoint             string `json:"endpoint"`
+			CertificateAuthority struct {
+				Data string `json:"data"`
+			} `json:"certificateAuthority"`
+		} `json:"cluster"`
+	}
+	if err := json.Unmarshal(out, &described); err != nil {
+		return nil, fmt.Errorf("unexpected aws eks describe-cluster output: %w", err)
+	}
+
+	execConfig := &clientcmdapi.ExecConfig{
+		APIVersion:      execAPIVersion,
+		Command:         "aws",
+		Args:            tokenArgs,
+		InstallHint:     "Install the AWS CLI: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	}
+	if profile != "" {
+		execConfig.Env = []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: profile}}
+	}
+	return newCluster(described.Cluster.Endpoint, described.Cluster.CertificateAuthority.Data, execConfig)
+}
+
+// GKE describes a GKE cluster with gcloud. Tokens come from gke-gcloud-auth-plugin.
+func GKE(ctx context.Context, run Runner, name, location, project string) (*Cluster, error) {
+	args := []string{"container", "clusters", "describe", name, "--format", "json"}
+	if location != "" {
+		args = append(args, "--location", location)
+	}
+	if project != "" {
+		args = append(args, "--project", project)
+	}
+	out, err := run(ctx, "gcloud", args...)
+	if err != nil {
+		return nil, err
+	}
+	var described struct {
+		Endpoint   string `json:"endpoint"`
+		MasterAuth struct {
+			ClusterCaCertificate string `json:"clusterCaCertificate"`
+		} `json:"masterAuth"`
+	}
+	if err := json.Unmarshal(out, &described); err != nil {
+		return nil, fmt.Errorf("unexpected gcloud container clusters describe output: %w", err)
+	}
+
+	return newCluster("https://"+described.Endpoint, described.MasterAuth.ClusterCaCertificate, &clientcmdapi.ExecConfig{
+		APIVersion:         execAPIVersion,
+		Command:            "gke-gcloud-auth-plugin",
+		InstallHint:        "Install gke-gcloud-auth-plugin: gcloud components install gke-gcloud-auth-plugin",
+		ProvideClusterInfo: true,
+		InteractiveMode:    clientcmdapi.IfAvailableExecInteractiveMode,
+	})
+}
+
+// AKS reads an AKS cluster's endpoint and CA from `az aks get-credentials`. Tokens
+// come from kubelogin with the az CLI login, which needs Entra ID (AAD) enabled
+// on the cluster.
+func AKS(ctx context.Context, run Runner, name, resourceGroup, subscription string) (*Cluster, error) {
+	args := []string{"aks", "get-credentials", "--name", name, "--resource-group", resourceGroup, "--file", "-"}
+	if subscription != "" {
+		args = append(args, "--subscription", subscription)
+	}
+	out, err := run(ctx, "az", args...)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, err := clientcmd.Load(out)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected az aks get-credentials output: %w", err)
+	}
+	var server string
+	var caData []byte
+	for _, c := range kubeconfig.Clusters {
+		server, caData = c.Server, c.CertificateAuthorityData
+		break
+	}
+	if server == "" {
+		return nil, fmt.Errorf("az aks get-credentials returned no cluster for %s", name)
+	}
+
+	return &Cluster{Server: server, CAData: caData, Exec: &clientcmdapi.ExecConfig{
+		APIVersion:      execAPIVersion,
+		Command:         "kubelogin",
+		Args:            []string{"get-token", "--login", "azurecli", "--server-id", aksServerID},
+		InstallHint:     "Install kubelogin: https://azure.github.io/kubelogin/install.html",
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	}}, nil
+}
+
+func newCluster(server, caBase64 string, execConfig *clientcmdapi.ExecConfig) (*Cluster, error) {
+	if server == "" || server == "https://" {
+		return nil, fmt.Errorf("cluster has no endpoint yet (still being created?)")
+	}
+	caData, err := base64.StdEncoding.DecodeString(caBase64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster CA certificate: %w", err)
+	}
+	return &Cluster{Server: server, CAData: caData, Exec: execConfig}, nil
+}
+
+// AddToKubeconfig writes c into the kubeconfig at path as a cluster, user and
+// context all named contextName. A cluster, user or context of that name that
+// differs from what would be written is only replaced with force; it reports
+// whether a context was replaced. A missing file is created.
+func AddToKubeconfig(path, contextName string, c *Cluster, force bool) (replaced bool, err error) {
+	kubeconfig := clientcmdapi.NewConfig()
+	if _, err := os.Stat(path); err == nil {
+		if kubeconfig, err = clientcmd.LoadFromFile(path); err != nil {
+			return false, err
+		}
+	}
+
+	if !force {
+		if err := checkExisting(kubeconfig, contextName, c); err != nil {
+			return false, fmt.Errorf("%w; pass -force to overwrite it, or pick another -context", err)
+		}
+	}
+
+	_, replaced = kubeconfig.Contexts[contextName]
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = c.Server
+	cluster.CertificateAuthorityData = c.CAData
+	user := clientcmdapi.NewAuthInfo()
+	user.Exec = c.Exec
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = contextName
+	kubeContext.AuthInfo = contextName
+
+	kubeconfig.Clusters[contextName] = cluster
+	kubeconfig.AuthInfos[contextName] = user
+	kubeconfig.Contexts[contextName] = kubeContext
+	return replaced, clientcmd.WriteToFile(*kubeconfig, path)
+}
+
+// checkExisting returns an error naming the first cluster, user or context
+// called name that differs from what AddToKubeconfig would write for c
+func checkExisting(kubeconfig *clientcmdapi.Config, name string, c *Cluster) error {
+	if cluster, ok := kubeconfig.Clusters[name]; ok {
+		if cluster.Server != c.Server || !bytes.Equal(cluster.CertificateAuthorityData, c.CAData) {
+			return fmt.Errorf("cluster %q already exists with server %s", name, cluster.Server)
+		}
+	}
+	if user, ok := kubeconfig.AuthInfos[name]; ok && !sameExec(user, c.Exec) {
+		return fmt.Errorf("user %q already exists with other credentials", name)
+	}
+	if kubeContext, ok := kubeconfig.Contexts[name]; ok {
+		if kubeContext.Cluster != name || kubeContext.AuthInfo != name {
+			return fmt.Errorf("context %q already exists for cluster %q and user %q", name, kubeContext.Cluster, kubeContext.AuthInfo)
+		}
+	}
+	return nil
+}
+
+// sameExec reports whether user only authenticates with an exec plugin run like e
+func sameExec(user *clientcmdapi.AuthInfo, e *clientcmdapi.ExecConfig) bool {
+	if user.Exec == nil || user.Token != "" || user.TokenFile != "" || len(user.ClientCertificateData) > 0 || user.ClientCertificate != "" || user.Username != "" || user.AuthProvider != nil {
+		return false
+	}
+	return user.Exec.Command == e.Command && user.Exec.APIVersion == e.APIVersion &&
+		slices.Equal(user.Exec.Args, e.Args) && slices.Equal(user.Exec.Env, e.Env)
+}
@@ -0,0 +1,155 @@
+package cloudctx
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// fakeCLI answers with out and records the command line
+func fakeCLI(out string, got *string) Runner {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		*got = name + " " + strings.Join(args, " ")
+		return []byte(out), nil
+	}
+}
+
+var caPEM = base64.StdEncoding.EncodeToString([]byte("-----BEGIN CERTIFICATE-----"))
+
+func TestEKS(t *testing.T) {
+	var cmdline string
+	out := `{"cluster": {"endpoint": "https://ABC.gr7.us-east-1.eks.amazonaws.com", "certificateAuthority": {"data": "` + caPEM + `"}}}`
+	c, err := EKS(context.Background(), fakeCLI(out, &cmdline), "main", "us-east-1", "work")
+	if err != nil {
+		t.Fatalf("EKS: %v", err)
+	}
+	if cmdline != "aws eks describe-cluster --name main --output json --region us-east-1" {
+		t.Errorf("ran %q", cmdline)
+	}
+	if c.Server != "https://ABC.gr7.us-east-1.eks.amazonaws.com" || string(c.CAData) != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("got server %s, CA %q", c.Server, c.CAData)
+	}
+	wantArgs := []string{"eks", "get-token", "--cluster-name", "main", "--output", "json", "--region", "us-east-1"}
+	if c.Exec.Command != "aws" || !reflect.DeepEqual(c.Exec.Args, wantArgs) {
+		t.Errorf("exec = %s %v, want aws %v", c.Exec.Command, c.Exec.Args, wantArgs)
+	}
+	if len(c.Exec.Env) != 1 || c.Exec.Env[0].Name != "AWS_PROFILE" || c.Exec.Env[0].Value != "work" {
+		t.Errorf("exec env = %v, want AWS_PROFILE=work", c.Exec.Env)
+	}
+}
+
+func TestGKE(t *testing.T) {
+	var cmdline string
+	out := `{"endpoint": "34.1.2.3", "masterAuth": {"clusterCaCertificate": "` + caPEM + `"}}`
+	c, err := GKE(context.Background(), fakeCLI(out, &cmdline), "main", "europe-west1", "")
+	if err != nil {
+		t.Fatalf("GKE: %v", err)
+	}
+	if cmdline != "gcloud container clusters describe main --format json --location europe-west1" {
+		t.Errorf("ran %q", cmdline)
+	}
+	if c.Server != "https://34.1.2.3" || c.Exec.Command != "gke-gcloud-auth-plugin" || !c.Exec.ProvideClusterInfo {
+		t.Errorf("got server %s, exec %+v", c.Server, c.Exec)
+	}
+}
+
+func TestGKE_NoEndpoint(t *testing.T) {
+	var cmdline string
+	if _, err := GKE(context.Background(), fakeCLI(`{}`, &cmdline), "main", "", ""); err == nil {
+		t.Error("expected an error for a cluster without endpoint")
+	}
+}
+
+func TestAKS(t *testing.T) {
+	var cmdline string
+	out := `apiVersion: v1
+kind: Config
+clusters:
+- name: main
+  cluster:
+    server: https://main-dns.hcp.westeurope.azmk8s.io:443
+    certificate-authority-data: ` + caPEM + `
+`
+	c, err := AKS(context.Background(), fakeCLI(out, &cmdline), "main", "rg", "")
+	if err != nil {
+		t.Fatalf("AKS: %v", err)
+	}
+	if cmdline != "az aks get-credentials --name main --resource-group rg --file -" {
+		t.Errorf("ran %q", cmdline)
+	}
+	if c.Server != "https://main-dns.hcp.westeurope.azmk8s.io:443" || c.Exec.Command != "kubelogin" {
+		t.Errorf("got server %s, exec %s", c.Server, c.Exec.Command)
+	}
+}
+
+func TestAddToKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kube", "config")
+	var cmdline string
+	out := `{"cluster": {"endpoint": "https://eks", "certificateAuthority": {"data": "` + caPEM + `"}}}`
+	c, err := EKS(context.Background(), fakeCLI(out, &cmdline), "main", "", "")
+	if err != nil {
+		t.Fatalf("EKS: %v", err)
+	}
+
+	if replaced, err := AddToKubeconfig(path, "prod", c, false); err != nil || replaced {
+		t.Fatalf("first AddToKubeconfig = %v, %v", replaced, err)
+	}
+	if replaced, err := AddToKubeconfig(path, "prod", c, false); err != nil || !replaced {
+		t.Fatalf("second AddToKubeconfig = %v, %v, want replaced", replaced, err)
+	}
+
+	// the written context loads like any other
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+		&clientcmd.ConfigOverrides{CurrentContext: "prod"},
+	).ClientConfig()
+	if err != nil {
+		t.Fatalf("loading the written context: %v", err)
+	}
+	if restConfig.Host != "https://eks" || restConfig.ExecProvider == nil || restConfig.ExecProvider.Command != "aws" {
+		t.Errorf("got host %s, exec %+v", restConfig.Host, restConfig.ExecProvider)
+	}
+}
+
+func TestAddToKubeconfig_RefusesDifferentEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	existing := clientcmdapi.NewConfig()
+	existing.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://other-prod"}
+	existing.AuthInfos["prod"] = &clientcmdapi.AuthInfo{Token: "static-token"}
+	existing.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod"}
+	if err := clientcmd.WriteToFile(*existing, path); err != nil {
+		t.Fatal(err)
+	}
+
+	var cmdline string
+	out := `{"cluster": {"endpoint": "https://eks", "certificateAuthority": {"data": "` + caPEM + `"}}}`
+	c, err := EKS(context.Background(), fakeCLI(out, &cmdline), "main", "", "")
+	if err != nil {
+		t.Fatalf("EKS: %v", err)
+	}
+
+	_, err = AddToKubeconfig(path, "prod", c, false)
+	if err == nil || !strings.Contains(err.Error(), `cluster "prod" already exists`) || !strings.Contains(err.Error(), "-force") {
+		t.Fatalf("Expected a refusal naming the cluster, got %v", err)
+	}
+	if kubeconfig, _ := clientcmd.LoadFromFile(path); kubeconfig.Clusters["prod"].Server != "https://other-prod" || kubeconfig.AuthInfos["prod"].Token != "static-token" {
+		t.Fatalf("kubeconfig was changed despite the refusal")
+	}
+
+	if replaced, err := AddToKubeconfig(path, "prod", c, true); err != nil || !replaced {
+		t.Fatalf("forced AddToKubeconfig = %v, %v, want replaced", replaced, err)
+	}
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeconfig.Clusters["prod"].Server != "https://eks" || kubeconfig.AuthInfos["prod"].Token != "" || kubeconfig.AuthInfos["prod"].Exec == nil {
+		t.Errorf("forced write left %+v, %+v", kubeconfig.Clusters["prod"], kubeconfig.AuthInfos["prod"])
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,12 +13,36 @@ import (
 const CurrentApiVersion = "autotunnel/v1"
 
 type Config struct {
-	ApiVersion       string     `yaml:"apiVersion"`
-	Verbose          bool       `yaml:"verbose"`
-	AutoReloadConfig *bool      `yaml:"auto_reload_config"` // nil = true (default)
-	ExecPath         []string   `yaml:"exec_path"`          // Additional PATH entries for exec credential plugins
-	HTTP             HTTPConfig `yaml:"http"`
-	TCP              TCPConfig  `yaml:"tcp"`
+	ApiVersion       string            `yaml:"apiVersion"`
+	Verbose          bool              `yaml:"verbose"`
+	AutoReloadConfig *bool             `yaml:"auto_reload_config"` // nil = true (default)
+	ReloadMode       string            `yaml:"reload_mode"`        // "auto" (default) or "confirm": hold validated changes until `autotunnel reload apply`
+	ExecPath         ExecPathConfig    `yaml:"exec_path"`          // Additional PATH entries for exec credential plugins
+	Contexts         ContextsConfig    `yaml:"contexts"`           // Short names and exec plugin settings for kubeconfig contexts
+	Keepalive        time.Duration     `yaml:"keepalive"`          // Port-forward ping and TCP keepalive interval (0 = disabled)
+	ReadyTimeout     time.Duration     `yaml:"ready_timeout"`      // How long a tunnel start may take (0 = 30s); routes can override it
+	CleanupInterval  time.Duration     `yaml:"cleanup_interval"`   // How often idle tunnels are looked for (0 = derived from the idle timeouts)
+	ServiceCacheTTL  time.Duration     `yaml:"service_cache_ttl"`  // How long a route's service (selector, target ports) is reused by new tunnels (0 = 5m, negative = never)
+	ConcurrentStarts int               `yaml:"concurrent_starts"`  // Tunnels establishing at once across all routes; others queue (0 = 8, negative = unlimited)
+	ServerRetry      ServerRetryConfig `yaml:"server_retry"`       // Restart listeners after errors instead of exiting
+	Prewarm          PrewarmConfig     `yaml:"prewarm"`            // Resolve the routes' contexts in parallel at startup
+	IdleNotify       IdleNotifyConfig  `yaml:"idle_notify"`        // Warn before watched routes' tunnels idle out
+	Log              LogConfig         `yaml:"log"`                // Send logs to syslog/journald instead of stderr (applied at startup)
+	UpdateCheck      *bool             `yaml:"update_check"`       // nil = true: look for new releases daily and log them (never installs)
+	PersistPorts     bool              `yaml:"persist_ports"`      // Reuse each tunnel's local port across restarts
+	PortStateFile    string            `yaml:"port_state_file"`    // Where persist_ports keeps them ("" = ~/.autotunnel/ports.json)
+	TLS              TLSConfig         `yaml:"tls"`                // Minimum version and cipher suites for TLS autotunnel terminates or originates
+	HTTP             HTTPConfig        `yaml:"http"`
+	TCP              TCPConfig         `yaml:"tcp"`
+	Team             TeamConfig        `yaml:"team"` // Shared-host mode: clients authenticate with per-user tokens
+
+	// Authorizers are consulted before a connection may use a route; routes can set
+	// their own. Empty = connections are not checked.
+	Authorizers []AuthorizerConfig `yaml:"authorizers"`
+
+	// Locked is set by the --locked flag, never by the file: no dynamic_host, no pins
+	// through the admin API and no via.create pods, only what the config names.
+	Locked bool `yaml:"-"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -40,6 +65,23 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.TCP.K8s.ResolvedKubeconfigs = cfg.HTTP.K8s.ResolvedKubeconfigs
 	}
 
+	if cfg.TCP.PrometheusFileSD != "" {
+		cfg.TCP.PrometheusFileSD = expandTilde(cfg.TCP.PrometheusFileSD)
+	}
+
+	for hostname, static := range cfg.HTTP.Static {
+		static.Dir = expandTilde(static.Dir)
+		cfg.HTTP.Static[hostname] = static
+	}
+	for hostname, local := range cfg.HTTP.Local {
+		local.Socket = expandTilde(local.Socket)
+		cfg.HTTP.Local[hostname] = local
+	}
+
+	if err := cfg.normalizeHostnames(); err != nil {
+		return nil, err
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -586,6 +588,10 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.HTTP.K8s.Routes == nil {
 		t.Error("expected Routes map to be initialized, got nil")
 	}
+
+	if cfg.Keepalive != DefaultKeepalive {
+		t.Errorf("expected keepalive %v, got %v", DefaultKeepalive, cfg.Keepalive)
+	}
 }
 
 func TestResolveKubeconfigs_MultiplePaths(t *testing.T) {
@@ -1092,6 +1098,99 @@ func TestValidate_JumpRouteWithDirectPod(t *testing.T) {
 	}
 }
 
+func TestValidate_JumpRouteInvalidServiceAccount(t *testing.T) {
+	cfg := &Config{
+		ApiVersion: CurrentApiVersion,
+		HTTP: HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+		},
+		TCP: TCPConfig{
+			K8s: TCPK8sConfig{
+				Jump: map[int]JumpRouteConfig{
+					5432: {
+						Context:   "test-context",
+						Namespace: "default",
+						Via: ViaConfig{
+							Pod:    "autotunnel-jump",
+							Create: &CreateConfig{Image: "alpine:3.19", ServiceAccountName: "Jump_SA"},
+						},
+						Target: TargetConfig{Host: "db.internal", Port: 5432},
+					},
+				},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "service_account_name") {
+		t.Errorf("expected service_account_name error, got %v", err)
+	}
+}
+
+func TestValidate_JumpRouteInvalidPullSecret(t *testing.T) {
+	cfg := &Config{
+		ApiVersion: CurrentApiVersion,
+		HTTP: HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+		},
+		TCP: TCPConfig{
+			K8s: TCPK8sConfig{
+				Jump: map[int]JumpRouteConfig{
+					5432: {
+						Context:   "test-context",
+						Namespace: "default",
+						Via: ViaConfig{
+							Pod:    "autotunnel-jump",
+							Create: &CreateConfig{Image: "alpine:3.19", ImagePullSecrets: []string{"regcred", ""}},
+						},
+						Target: TargetConfig{Host: "db.internal", Port: 5432},
+					},
+				},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "image_pull_secrets") {
+		t.Errorf("expected image_pull_secrets error, got %v", err)
+	}
+}
+
+func TestValidate_JumpRoutePool(t *testing.T) {
+	for _, tt := range []struct {
+		pool    int
+		maxAge  time.Duration
+		wantErr string
+	}{
+		{pool: 4},
+		{pool: MaxJumpPool + 1, wantErr: "pool must be between"},
+		{pool: -1, wantErr: "pool must be between"},
+		{pool: 2, maxAge: -time.Second, wantErr: "pool_max_age"},
+	} {
+		cfg := &Config{
+			ApiVersion: CurrentApiVersion,
+			HTTP:       HTTPConfig{ListenAddr: ":8989", IdleTimeout: 60 * time.Minute},
+			TCP: TCPConfig{K8s: TCPK8sConfig{Jump: map[int]JumpRouteConfig{
+				3306: {
+					Context: "test-context", Namespace: "default",
+					Via:    ViaConfig{Pod: "bastion-pod"},
+					Target: TargetConfig{Host: "mydb.rds.amazonaws.com", Port: 3306},
+					Pool:   tt.pool, PoolMaxAge: tt.maxAge,
+				},
+			}}},
+		}
+		err := cfg.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("pool %d: unexpected error %v", tt.pool, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("pool %d, max age %v: error = %v, want %q", tt.pool, tt.maxAge, err, tt.wantErr)
+		}
+	}
+}
+
 // TestLoadConfig_WithJumpRoutes tests loading config with jump routes
 func TestLoadConfig_WithJumpRoutes(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -1652,3 +1751,1773 @@ tcp:
 		}
 	}
 }
+
+func TestValidate_StatusHostReserved(t *testing.T) {
+	cfg := &Config{
+		HTTP: HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+			StatusHost:  DefaultStatusHost,
+			K8s: K8sConfig{
+				Routes: map[string]K8sRouteConfig{
+					"Autotunnel.localhost": {Context: "ctx", Namespace: "default", Service: "svc", Port: 80},
+				},
+			},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "reserved for the status API") {
+		t.Errorf("expected reserved hostname error, got %v", err)
+	}
+
+	cfg.HTTP.StatusHost = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error with status API disabled, got %v", err)
+	}
+}
+
+func TestValidate_PrivilegedFallbackListen(t *testing.T) {
+	tests := []struct {
+		name       string
+		listen     string
+		fallback   string
+		errContain string
+	}{
+		{name: "unset is valid", listen: "127.0.0.1:80", fallback: ""},
+		{name: "high port is valid", listen: "127.0.0.1:80", fallback: "127.0.0.1:8989"},
+		{name: "privileged port rejected", listen: "127.0.0.1:80", fallback: "127.0.0.1:443", errContain: "port >= 1024"},
+		{name: "missing port rejected", listen: "127.0.0.1:80", fallback: "127.0.0.1", errContain: "invalid http.privileged_fallback_listen"},
+		{name: "same as listen rejected", listen: "127.0.0.1:8080", fallback: "127.0.0.1:8080", errContain: "must differ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{
+					ListenAddr:               tt.listen,
+					PrivilegedFallbackListen: tt.fallback,
+					IdleTimeout:              time.Minute,
+				},
+			}
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_TCPAllowedPortRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		portRange  string
+		routePort  int
+		jumpPort   int
+		errContain string
+	}{
+		{name: "unset allows any port", portRange: "", routePort: 22, jumpPort: 631},
+		{name: "ports inside range", portRange: "10000-19999", routePort: 15432, jumpPort: 13306},
+		{name: "route outside range", portRange: "10000-19999", routePort: 22, jumpPort: 13306, errContain: "tcp.k8s.routes[22]: local port 22 is outside tcp.allowed_port_range 10000-19999"},
+		{name: "jump outside range", portRange: "10000-19999", routePort: 15432, jumpPort: 631, errContain: "tcp.k8s.jump[631]: local port 631 is outside"},
+		{name: "range bounds are inclusive", portRange: "15432-15433", routePort: 15432, jumpPort: 15433},
+		{name: "malformed range", portRange: "10000", routePort: 15432, jumpPort: 13306, errContain: "invalid tcp.allowed_port_range"},
+		{name: "inverted range", portRange: "20000-10000", routePort: 15432, jumpPort: 13306, errContain: "min <= max"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP: TCPConfig{
+					AllowedPortRange: tt.portRange,
+					K8s: TCPK8sConfig{
+						Routes: map[int]TCPRouteConfig{
+							tt.routePort: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432},
+						},
+						Jump: map[int]JumpRouteConfig{
+							tt.jumpPort: {
+								Context:   "ctx",
+								Namespace: "default",
+								Via:       ViaConfig{Pod: "bastion"},
+								Target:    TargetConfig{Host: "mysql.internal", Port: 3306},
+							},
+						},
+					},
+				},
+			}
+
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Team(t *testing.T) {
+	t.Setenv("AUTOTUNNEL_TEST_TEAM_TOKEN", "env-token-0123456789")
+
+	tests := []struct {
+		name       string
+		users      map[string]TeamUserConfig
+		errContain string
+	}{
+		{name: "disabled", users: nil},
+		{name: "inline token", users: map[string]TeamUserConfig{"alice": {Token: "alice-token-0123456789", Routes: []string{"*.staging.localhost"}}}},
+		{name: "token from env", users: map[string]TeamUserConfig{"alice": {TokenEnv: "AUTOTUNNEL_TEST_TEAM_TOKEN"}}},
+		{name: "missing token", users: map[string]TeamUserConfig{"alice": {}}, errContain: "team.users[alice]: token or token_env is required"},
+		{name: "both token sources", users: map[string]TeamUserConfig{"alice": {Token: "alice-token-0123456789", TokenEnv: "AUTOTUNNEL_TEST_TEAM_TOKEN"}}, errContain: "cannot specify both"},
+		{name: "empty env", users: map[string]TeamUserConfig{"alice": {TokenEnv: "AUTOTUNNEL_TEST_UNSET_TOKEN"}}, errContain: "AUTOTUNNEL_TEST_UNSET_TOKEN is empty"},
+		{name: "short token", users: map[string]TeamUserConfig{"alice": {Token: "short"}}, errContain: "at least 16 characters"},
+		{name: "shared token", users: map[string]TeamUserConfig{"alice": {Token: "same-token-0123456789"}, "bob": {Token: "same-token-0123456789"}}, errContain: "token is already used"},
+		{name: "bad pattern", users: map[string]TeamUserConfig{"alice": {Token: "alice-token-0123456789", Routes: []string{"[api"}}}, errContain: "invalid route pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				Team: TeamConfig{Users: tt.users},
+			}
+
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestTeamUserConfig_AllowsHost(t *testing.T) {
+	user := TeamUserConfig{Routes: []string{"*.staging.localhost", "grafana.localhost"}}
+
+	for host, want := range map[string]bool{
+		"api.staging.localhost": true,
+		"Grafana.localhost":     true,
+		"api.prod.localhost":    false,
+		"staging.localhost":     false,
+	} {
+		if got := user.AllowsHost(host); got != want {
+			t.Errorf("AllowsHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+	if !(TeamUserConfig{}).AllowsHost("anything.localhost") {
+		t.Error("expected a user without routes to be allowed everywhere")
+	}
+}
+
+func TestValidate_RouteTLSMode(t *testing.T) {
+	for mode, wantErr := range map[string]bool{"": false, "passthrough": false, "terminate": false, "edge": true} {
+		cfg := &Config{
+			HTTP: HTTPConfig{
+				ListenAddr:  ":8989",
+				IdleTimeout: time.Minute,
+				K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+					"app.localhost": {Context: "ctx", Namespace: "default", Service: "app", Port: 443, TLS: mode},
+				}},
+			},
+		}
+		err := cfg.Validate()
+		if wantErr && (err == nil || !strings.Contains(err.Error(), "tls must be")) {
+			t.Errorf("tls %q: expected error, got %v", mode, err)
+		}
+		if !wantErr && err != nil {
+			t.Errorf("tls %q: unexpected error: %v", mode, err)
+		}
+	}
+}
+
+func TestValidate_RouteALPNPorts(t *testing.T) {
+	tests := []struct {
+		name       string
+		tls        string
+		alpnPorts  map[string]int
+		errContain string
+	}{
+		{name: "h2 port", alpnPorts: map[string]int{"h2": 8443}},
+		{name: "invalid port", alpnPorts: map[string]int{"h2": 70000}, errContain: "alpn_ports[h2] must be between 1 and 65535"},
+		{name: "empty protocol", alpnPorts: map[string]int{"": 8443}, errContain: "empty protocol name"},
+		{name: "with terminate", tls: TLSModeTerminate, alpnPorts: map[string]int{"h2": 8443}, errContain: "only applies to tls: passthrough"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{
+					ListenAddr:  ":8989",
+					IdleTimeout: time.Minute,
+					K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+						"grpc.localhost": {Context: "ctx", Namespace: "default", Service: "api", Port: 443, TLS: tt.tls, ALPNPorts: tt.alpnPorts},
+					}},
+				},
+			}
+
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_TCPExtraPorts(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraPorts map[int]int
+		errContain string
+	}{
+		{name: "debugger port", extraPorts: map[int]int{15005: 5005}},
+		{name: "conflicts with route", extraPorts: map[int]int{15432: 5005}, errContain: "port already used"},
+		{name: "conflicts with http", extraPorts: map[int]int{8989: 5005}, errContain: "conflicts with http.listen port"},
+		{name: "invalid target", extraPorts: map[int]int{15005: 0}, errContain: "target port must be between 1 and 65535"},
+		{name: "duplicate target", extraPorts: map[int]int{15005: 8080}, errContain: "target port 8080 is already forwarded"},
+		{name: "outside range", extraPorts: map[int]int{25005: 5005}, errContain: "outside tcp.allowed_port_range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP: TCPConfig{
+					AllowedPortRange: "8000-19999",
+					K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+						18080: {Context: "ctx", Namespace: "default", Service: "api", Port: 8080, ExtraPorts: tt.extraPorts},
+						15432: {Context: "ctx", Namespace: "default", Service: "db", Port: 5432},
+					}},
+				},
+			}
+
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestTCPRouteConfig_ExtraPorts(t *testing.T) {
+	route := TCPRouteConfig{Context: "ctx", Namespace: "default", Service: "api", Port: 8080,
+		ExtraPorts: map[int]int{19090: 9090, 15005: 5005}}
+
+	got := route.ToK8sRouteConfig().TargetPorts()
+	want := []int{8080, 5005, 9090} // extra ports ordered by local port
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TargetPorts() = %v, want %v", got, want)
+	}
+
+	k8s := TCPK8sConfig{Routes: map[int]TCPRouteConfig{18080: route}}
+	shared := k8s.SharedPorts()
+	if len(shared) != 2 {
+		t.Fatalf("SharedPorts() = %v, want 2 entries", shared)
+	}
+	if shared[15005] != (SharedPort{RoutePort: 18080, TargetPort: 5005}) {
+		t.Errorf("SharedPorts()[15005] = %+v", shared[15005])
+	}
+}
+
+func TestValidate_TCPGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		group      GroupRouteConfig
+		errContain string
+	}{
+		{name: "auto ports", group: GroupRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", PortOffset: 10000}},
+		{name: "chosen port", group: GroupRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", Ports: map[int]int{80: 18081}}},
+		{name: "missing service", group: GroupRouteConfig{Context: "ctx", Namespace: "apps"}, errContain: "service is required"},
+		{name: "missing context", group: GroupRouteConfig{Namespace: "apps", Service: "api"}, errContain: "context is required"},
+		{name: "negative offset", group: GroupRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", PortOffset: -1}, errContain: "port_offset must be between"},
+		{name: "invalid service port", group: GroupRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", Ports: map[int]int{0: 18081}}, errContain: "service port must be between"},
+		{name: "conflicts with route", group: GroupRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", Ports: map[int]int{80: 18080}}, errContain: "port already used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP: TCPConfig{K8s: TCPK8sConfig{
+					Routes: map[int]TCPRouteConfig{
+						18080: {Context: "ctx", Namespace: "default", Service: "web", Port: 8080},
+					},
+					Groups: map[string]GroupRouteConfig{"api": tt.group},
+				}},
+			}
+
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Discover(t *testing.T) {
+	tests := []struct {
+		name       string
+		discover   TCPDiscoverConfig
+		errContain string
+	}{
+		{name: "all namespaces", discover: TCPDiscoverConfig{Context: "ctx"}},
+		{name: "namespace and method", discover: TCPDiscoverConfig{Context: "ctx", Namespace: "db", Method: TCPMethodExec}},
+		{name: "no context", discover: TCPDiscoverConfig{Namespace: "db"}, errContain: "tcp.k8s.discover[0]: context is required"},
+		{name: "bad method", discover: TCPDiscoverConfig{Context: "ctx", Method: "ssh"}, errContain: "tcp.k8s.discover[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ApiVersion: CurrentApiVersion,
+				HTTP:       HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP:        TCPConfig{K8s: TCPK8sConfig{Discover: []TCPDiscoverConfig{tt.discover}}},
+			}
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestPostgresRouteConfig_Resolve(t *testing.T) {
+	route := PostgresRouteConfig{Context: "dev", Service: "postgres", Port: 5432, NamespacePrefix: "preview-"}
+	tests := []struct {
+		requested     string
+		database      string
+		wantNamespace string
+		wantDatabase  string
+		wantErr       bool
+	}{
+		{requested: "42", wantNamespace: "preview-42", wantDatabase: "42"},
+		{requested: "42", database: "app", wantNamespace: "preview-42", wantDatabase: "app"},
+		{requested: "42/orders", database: "app", wantNamespace: "preview-42", wantDatabase: "orders"},
+		{requested: "42/", wantErr: true},
+		{requested: "/orders", wantErr: true},
+		{requested: "Feature_X", wantErr: true},
+		{requested: "a.b", wantErr: true},
+		{requested: strings.Repeat("x", 56), wantErr: true}, // 64 characters with the prefix
+	}
+	for _, tt := range tests {
+		r := route
+		r.Database = tt.database
+		got, db, err := r.Resolve(tt.requested)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Resolve(%q) expected an error, got %+v", tt.requested, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.requested, err)
+			continue
+		}
+		if got.Namespace != tt.wantNamespace || db != tt.wantDatabase || got.Service != "postgres" || got.Port != 5432 {
+			t.Errorf("Resolve(%q) = %+v, %q; want namespace %s, database %s", tt.requested, got, db, tt.wantNamespace, tt.wantDatabase)
+		}
+	}
+}
+
+func TestValidate_Postgres(t *testing.T) {
+	valid := PostgresRouteConfig{Context: "dev", Service: "postgres", Port: 5432, NamespacePrefix: "preview-"}
+	tests := []struct {
+		name       string
+		modify     func(r *PostgresRouteConfig)
+		errContain string
+	}{
+		{name: "valid", modify: func(r *PostgresRouteConfig) {}},
+		{name: "no service", modify: func(r *PostgresRouteConfig) { r.Service = "" }, errContain: "service is required"},
+		{name: "bad port", modify: func(r *PostgresRouteConfig) { r.Port = 0 }, errContain: "port must be between"},
+		{name: "bad prefix", modify: func(r *PostgresRouteConfig) { r.NamespacePrefix = "Preview_" }, errContain: "invalid namespace_prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := valid
+			tt.modify(&route)
+			cfg := &Config{
+				ApiVersion: CurrentApiVersion,
+				HTTP:       HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP:        TCPConfig{K8s: TCPK8sConfig{Postgres: map[int]PostgresRouteConfig{15432: route}}},
+			}
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Log(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        LogConfig
+		errContain string
+	}{
+		{name: "default", log: LogConfig{}},
+		{name: "journald", log: LogConfig{Output: LogOutputJournald, Tag: "at"}},
+		{name: "remote syslog", log: LogConfig{Output: LogOutputSyslog, SyslogAddr: "udp://logs.example.com:514"}},
+		{name: "bad output", log: LogConfig{Output: "file"}, errContain: "log.output must be"},
+		{name: "bad syslog scheme", log: LogConfig{Output: LogOutputSyslog, SyslogAddr: "http://logs:514"}, errContain: "log.syslog_addr must look like"},
+		{name: "syslog_addr without syslog", log: LogConfig{SyslogAddr: "udp://logs:514"}, errContain: "requires log.output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ApiVersion: CurrentApiVersion,
+				HTTP:       HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				Log:        tt.log,
+			}
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Authorizers(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer AuthorizerConfig
+		errContain string
+	}{
+		{name: "token", authorizer: AuthorizerConfig{Type: AuthorizerToken, Tokens: map[string]string{"ci": "0123456789abcdef"}}},
+		{name: "short token", authorizer: AuthorizerConfig{Type: AuthorizerToken, Tokens: map[string]string{"ci": "short"}}, errContain: "at least 16 characters"},
+		{name: "os_user without users", authorizer: AuthorizerConfig{Type: AuthorizerOSUser}, errContain: "users is required"},
+		{name: "client_cert without ca_file", authorizer: AuthorizerConfig{Type: AuthorizerClientCert}, errContain: "ca_file is required"},
+		{name: "command without argv", authorizer: AuthorizerConfig{Type: AuthorizerCommand}, errContain: "command is required"},
+		{name: "unknown type", authorizer: AuthorizerConfig{Type: "ldap"}, errContain: "type must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				ApiVersion: CurrentApiVersion,
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+					"api.localhost": {Context: "dev", Namespace: "default", Service: "api", Port: 80, Authorizers: []AuthorizerConfig{tt.authorizer}},
+				}}},
+			}
+			err := cfg.Validate()
+			if tt.errContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContain) {
+				t.Errorf("expected error containing %q, got %v", tt.errContain, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "http.k8s.routes[api.localhost].authorizers[0]") {
+				t.Errorf("expected the error to name the route, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate_HostPatternAuthorizers(t *testing.T) {
+	cfg := &Config{
+		ApiVersion: CurrentApiVersion,
+		HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{HostPatterns: []HostPatternConfig{{
+			Suffix:         ".dev.localhost",
+			K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "default", Service: "$prefix", Port: 80, Authorizers: []AuthorizerConfig{{Type: AuthorizerOSUser}}},
+		}}}},
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "http.k8s.host_patterns[0].authorizers[0]: users is required") {
+		t.Errorf("expected the pattern's authorizer to be validated, got %v", err)
+	}
+}
+
+func TestTCPRouteConfig_ExecRoute(t *testing.T) {
+	podRoute := TCPRouteConfig{Context: "ctx", Namespace: "apps", Pod: "api-0", Port: 8080}
+	got := podRoute.ExecRoute(8080)
+	if got.Via.Pod != "api-0" || got.Target != (TargetConfig{Host: "127.0.0.1", Port: 8080}) {
+		t.Errorf("pod route: got %+v", got)
+	}
+
+	svcRoute := TCPRouteConfig{Context: "ctx", Namespace: "apps", Service: "api", Port: 80}
+	got = svcRoute.ExecRoute(80)
+	if got.Via.Service != "api" || got.Target != (TargetConfig{Host: "api.apps.svc", Port: 80}) {
+		t.Errorf("service route: got %+v", got)
+	}
+	if got.Context != "ctx" || got.Namespace != "apps" {
+		t.Errorf("expected context and namespace to carry over, got %+v", got)
+	}
+	if !IsValidTargetHost(got.Target.Host) {
+		t.Errorf("exec target host %q must pass target host validation", got.Target.Host)
+	}
+}
+
+func TestValidate_TCPMethod(t *testing.T) {
+	for _, method := range []string{"", TCPMethodPortForward, TCPMethodExec, "ssh"} {
+		cfg := &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+			TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+				15432: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432, Method: method},
+			}}},
+		}
+		err := cfg.Validate()
+		if method == "ssh" {
+			if err == nil || !strings.Contains(err.Error(), "method must be") {
+				t.Errorf("method %q: expected error, got %v", method, err)
+			}
+		} else if err != nil {
+			t.Errorf("method %q: unexpected error: %v", method, err)
+		}
+	}
+}
+
+func TestValidate_PinPod(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   TCPRouteConfig
+		wantErr string
+	}{
+		{"auto", TCPRouteConfig{Service: "postgres", PinPod: "auto"}, ""},
+		{"pod name", TCPRouteConfig{Service: "postgres", PinPod: "postgres-0"}, ""},
+		{"invalid name", TCPRouteConfig{Service: "postgres", PinPod: "Postgres_0"}, "pin_pod must be a pod name"},
+		{"pod route", TCPRouteConfig{Pod: "postgres-0", PinPod: "auto"}, "only applies to service routes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := tt.route
+			route.Context, route.Namespace, route.Port = "ctx", "db", 5432
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP:  TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{15432: route}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Keepalive(t *testing.T) {
+	tests := []struct {
+		keepalive time.Duration
+		wantErr   bool
+	}{
+		{0, false},
+		{time.Second, false},
+		{30 * time.Second, false},
+		{500 * time.Millisecond, true},
+		{-time.Second, true},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{
+			Keepalive: tt.keepalive,
+			HTTP:      HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+		}
+		err := cfg.Validate()
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "keepalive must be") {
+				t.Errorf("keepalive %v: expected error, got %v", tt.keepalive, err)
+			}
+		} else if err != nil {
+			t.Errorf("keepalive %v: unexpected error: %v", tt.keepalive, err)
+		}
+	}
+}
+
+func TestValidate_ReadyTimeout(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+			TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+				15432: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432, ReadyTimeout: 2 * time.Minute},
+			}}},
+		}
+	}
+
+	if err := base().Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := base()
+	cfg.ReadyTimeout = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ready_timeout must not be negative") {
+		t.Errorf("expected global ready_timeout error, got %v", err)
+	}
+
+	cfg = base()
+	route := cfg.TCP.K8s.Routes[15432]
+	route.ReadyTimeout = -time.Second
+	cfg.TCP.K8s.Routes[15432] = route
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "15432") {
+		t.Errorf("expected route ready_timeout error, got %v", err)
+	}
+}
+
+func TestValidate_TCPConnLimits(t *testing.T) {
+	for _, route := range []TCPRouteConfig{
+		{ConnectTimeout: -time.Second},
+		{ConnIdleTimeout: -time.Second},
+		{ConnMaxDuration: -time.Second},
+		{ConnectTimeout: 3 * time.Second, ConnIdleTimeout: time.Minute, ConnMaxDuration: time.Hour},
+	} {
+		valid := route.ConnectTimeout > 0
+		route.Context, route.Namespace, route.Service, route.Port = "ctx", "db", "postgres", 5432
+		cfg := &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+			TCP:  TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{15432: route}}},
+		}
+		err := cfg.Validate()
+		if valid && err != nil {
+			t.Errorf("%+v: unexpected error: %v", route, err)
+		} else if !valid && (err == nil || !strings.Contains(err.Error(), "must not be negative")) {
+			t.Errorf("%+v: expected error, got %v", route, err)
+		}
+	}
+
+	if got := (TCPRouteConfig{}).GetConnectTimeout(); got != DefaultTCPConnectTimeout {
+		t.Errorf("GetConnectTimeout() = %v, want %v", got, DefaultTCPConnectTimeout)
+	}
+}
+
+func TestValidate_EchoEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		echoHost string
+		echoPort int
+		route    string
+		wantErr  string
+	}{
+		{name: "both", echoHost: "echo.localhost", echoPort: 19999},
+		{name: "ip host", echoHost: "127.0.0.1", wantErr: "must be a hostname"},
+		{name: "status host", echoHost: "autotunnel.localhost", wantErr: "must differ from http.status_host"},
+		{name: "route on echo host", echoHost: "echo.localhost", route: "echo.localhost", wantErr: "reserved for the echo endpoint"},
+		{name: "port of a route", echoPort: 15432, wantErr: "tcp.echo_port: port already used"},
+		{name: "http port", echoPort: 8989, wantErr: "conflicts with http.listen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, StatusHost: DefaultStatusHost, EchoHost: tt.echoHost},
+				TCP: TCPConfig{EchoPort: tt.echoPort, K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+					15432: {Context: "ctx", Namespace: "ns", Service: "db", Port: 5432},
+				}}},
+			}
+			if tt.route != "" {
+				cfg.HTTP.K8s.Routes = map[string]K8sRouteConfig{tt.route: {Context: "ctx", Namespace: "ns", Service: "svc", Port: 80}}
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_PathQualifiedContext(t *testing.T) {
+	tests := []struct {
+		context string
+		wantErr string
+	}{
+		{context: "prod"},
+		{context: "~/.kube/work#prod"},
+		{context: "#prod", wantErr: "must be a name or path#name"},
+		{context: "~/.kube/work#", wantErr: "must be a name or path#name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+					"api.localhost": {Context: tt.context, Namespace: "ns", Service: "svc", Port: 80},
+				}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ContextAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		contexts ContextsConfig
+		wantErr  string
+	}{
+		{name: "alias", contexts: ContextsConfig{"prod": {Context: "arn:aws:eks:us-east-1:123456789012:cluster/main"}}},
+		{name: "path qualified", contexts: ContextsConfig{"prod": {Context: "~/.kube/work#main"}}},
+		{name: "empty target", contexts: ContextsConfig{"prod": {Context: ""}}, wantErr: "contexts[prod]: context is required"},
+		{name: "chained", contexts: ContextsConfig{"prod": {Context: "main"}, "main": {Context: "arn:main"}}, wantErr: "is an alias itself"},
+		{name: "exec settings only", contexts: ContextsConfig{"prod": {Env: map[string]string{"AWS_PROFILE": "prod"}}}},
+		{name: "bad env name", contexts: ContextsConfig{"prod": {Context: "main", Env: map[string]string{"A=B": "x"}}}, wantErr: "invalid env variable name"},
+		{name: "hash in alias", contexts: ContextsConfig{"a#b": {Context: "main"}}, wantErr: "invalid alias name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Contexts: tt.contexts,
+				HTTP:     HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ExecEnv(t *testing.T) {
+	cfg := &Config{
+		HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+		TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+			15432: {Context: "ctx", ExecEnv: map[string]string{"AWS_PROFILE": "prod"}, Namespace: "ns", Service: "db", Port: 5432},
+		}}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg.TCP.K8s.Routes[15432] = TCPRouteConfig{Context: "ctx", ExecEnv: map[string]string{"AWS PROFILE": "prod"}, Namespace: "ns", Service: "db", Port: 5432}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "exec_env: invalid env variable name") {
+		t.Fatalf("error = %v, want invalid env variable name", err)
+	}
+}
+
+func TestValidate_Socket(t *testing.T) {
+	linger := func(d time.Duration) *time.Duration { return &d }
+	tests := []struct {
+		name    string
+		socket  SocketConfig
+		wantErr string
+	}{
+		{"defaults", SocketConfig{}, ""},
+		{"keepalive and reset on close", SocketConfig{ConnKeepalive: 30 * time.Second, ConnLinger: linger(0)}, ""},
+		{"keepalive disabled", SocketConfig{ConnKeepalive: -1}, ""},
+		{"short keepalive", SocketConfig{ConnKeepalive: 100 * time.Millisecond}, "conn_keepalive must be at least 1s"},
+		{"negative linger", SocketConfig{ConnLinger: linger(-time.Second)}, "conn_linger must be a whole number of seconds"},
+		{"fractional linger", SocketConfig{ConnLinger: linger(1500 * time.Millisecond)}, "conn_linger must be a whole number of seconds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+				TCP: TCPConfig{K8s: TCPK8sConfig{
+					Routes: map[int]TCPRouteConfig{15432: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432, SocketConfig: tt.socket}},
+					Jump: map[int]JumpRouteConfig{13306: {Context: "ctx", Namespace: "db", Via: ViaConfig{Pod: "jump"},
+						Target: TargetConfig{Host: "mysql.example.com", Port: 3306}, SocketConfig: tt.socket}},
+				}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Standby(t *testing.T) {
+	for _, standby := range []int{-1, 0, 2, MaxStandby, MaxStandby + 1} {
+		cfg := &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+				"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80, Standby: standby},
+			}}},
+		}
+		err := cfg.Validate()
+		if standby < 0 || standby > MaxStandby {
+			if err == nil || !strings.Contains(err.Error(), "standby must be between") {
+				t.Errorf("standby %d: expected error, got %v", standby, err)
+			}
+		} else if err != nil {
+			t.Errorf("standby %d: unexpected error: %v", standby, err)
+		}
+	}
+}
+
+func TestValidate_Redirects(t *testing.T) {
+	tests := []struct {
+		name      string
+		redirects map[string]string
+		wantErr   string
+	}{
+		{"valid", map[string]string{"ci.localhost": "https://jenkins.example.com/"}, ""},
+		{"relative url", map[string]string{"ci.localhost": "/jenkins"}, "absolute http"},
+		{"other scheme", map[string]string{"ci.localhost": "ftp://files.example.com"}, "absolute http"},
+		{"route hostname", map[string]string{"app.localhost": "https://example.com"}, "http.k8s.routes"},
+		{"status host", map[string]string{"autotunnel.localhost": "https://example.com"}, "reserved"},
+		{"invalid hostname", map[string]string{"not a host": "https://example.com"}, "must be a hostname"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, StatusHost: "autotunnel.localhost", Redirects: tt.redirects,
+					K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+						"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80},
+					}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Static(t *testing.T) {
+	tests := []struct {
+		name    string
+		static  StaticRouteConfig
+		host    string
+		wantErr string
+	}{
+		{"valid", StaticRouteConfig{Dir: "/srv/web/dist", SPA: true}, "web.localhost", ""},
+		{"missing dir", StaticRouteConfig{}, "web.localhost", "dir is required"},
+		{"relative dir", StaticRouteConfig{Dir: "dist"}, "web.localhost", "absolute path"},
+		{"route hostname", StaticRouteConfig{Dir: "/srv/web"}, "app.localhost", "http.k8s.routes"},
+		{"redirect hostname", StaticRouteConfig{Dir: "/srv/web"}, "ci.localhost", "http.redirects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute,
+					Redirects: map[string]string{"ci.localhost": "https://jenkins.example.com"},
+					Static:    map[string]StaticRouteConfig{tt.host: tt.static},
+					K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+						"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80},
+					}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Local(t *testing.T) {
+	tests := []struct {
+		name    string
+		local   LocalRouteConfig
+		host    string
+		wantErr string
+	}{
+		{"port", LocalRouteConfig{Port: 3000}, "web.localhost", ""},
+		{"socket", LocalRouteConfig{Socket: "/tmp/web.sock", Scheme: "https"}, "web.localhost", ""},
+		{"neither", LocalRouteConfig{}, "web.localhost", "exactly one of port or socket"},
+		{"both", LocalRouteConfig{Port: 3000, Socket: "/tmp/web.sock"}, "web.localhost", "exactly one of port or socket"},
+		{"bad port", LocalRouteConfig{Port: 70000}, "web.localhost", "port must be between"},
+		{"relative socket", LocalRouteConfig{Socket: "web.sock"}, "web.localhost", "absolute path"},
+		{"bad scheme", LocalRouteConfig{Port: 3000, Scheme: "ws"}, "web.localhost", "scheme must be"},
+		{"cluster fallback", LocalRouteConfig{Port: 3000}, "app.localhost", ""},
+		{"raw tcp fallback", LocalRouteConfig{Port: 3000}, "raw.localhost", "mode: tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute,
+					Local: map[string]LocalRouteConfig{tt.host: tt.local},
+					K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+						"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80},
+						"raw.localhost": {Context: "ctx", Namespace: "apps", Service: "rtsp", Port: 554, Mode: RouteModeTCP},
+					}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_TLSPassthroughDisabled(t *testing.T) {
+	disabled := false
+	for name, route := range map[string]K8sRouteConfig{
+		"tls: terminate": {TLS: TLSModeTerminate},
+		"alpn_ports":     {ALPNPorts: map[string]int{"h2": 8443}},
+		"upstream_sni":   {UpstreamSNI: "api.internal"},
+	} {
+		route.Context, route.Namespace, route.Service, route.Port = "ctx", "apps", "app", 443
+		cfg := &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, TLSPassthrough: &disabled,
+				K8s: K8sConfig{Routes: map[string]K8sRouteConfig{"app.localhost": route}}},
+		}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tls_passthrough") {
+			t.Errorf("%s: expected tls_passthrough error, got %v", name, err)
+		}
+		cfg.HTTP.TLSPassthrough = nil
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s: unexpected error with passthrough on: %v", name, err)
+		}
+	}
+}
+
+func TestValidate_Signatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		sig     ProtocolSignature
+		wantErr string
+	}{
+		{"ssh", ProtocolSignature{Prefix: "SSH-", Route: "ssh.localhost"}, ""},
+		{"no prefix", ProtocolSignature{Route: "ssh.localhost"}, "prefix is required"},
+		{"long prefix", ProtocolSignature{Prefix: strings.Repeat("x", MaxSignaturePrefix+1), Route: "ssh.localhost"}, "at most"},
+		{"tls", ProtocolSignature{Prefix: "\x16\x03", Route: "ssh.localhost"}, "TLS handshake"},
+		{"unknown route", ProtocolSignature{Prefix: "SSH-", Route: "nope.localhost"}, "not an http.k8s.routes entry"},
+		{"http route", ProtocolSignature{Prefix: "SSH-", Route: "app.localhost"}, "mode: tcp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute,
+					Signatures: []ProtocolSignature{tt.sig},
+					K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+						"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80},
+						"ssh.localhost": {Context: "ctx", Namespace: "apps", Service: "sshd", Port: 22, Mode: RouteModeTCP},
+					}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_WarmupSchedule(t *testing.T) {
+	for schedule, valid := range map[string]bool{
+		"":                true,
+		"0 9 * * MON-FRI": true,
+		"*/15 8-18 * * *": true,
+		"0 9 * *":         false,
+		"0 25 * * *":      false,
+	} {
+		cfg := &Config{
+			HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+				"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80, WarmupSchedule: schedule},
+			}}},
+		}
+		err := cfg.Validate()
+		if valid && err != nil {
+			t.Errorf("warmup_schedule %q: unexpected error: %v", schedule, err)
+		} else if !valid && (err == nil || !strings.Contains(err.Error(), "warmup_schedule")) {
+			t.Errorf("warmup_schedule %q: expected error, got %v", schedule, err)
+		}
+	}
+}
+
+func TestValidate_TrafficThresholds(t *testing.T) {
+	cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, SlowRequestThreshold: -time.Second}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "slow_request_threshold") {
+		t.Errorf("expected slow_request_threshold error, got %v", err)
+	}
+
+	cfg = &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, LargeResponseThresholdMB: -1}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "large_response_threshold_mb") {
+		t.Errorf("expected large_response_threshold_mb error, got %v", err)
+	}
+}
+
+func TestValidate_HealthCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		hc       *HealthCheckConfig
+		wantErr  string
+		protocol string
+	}{
+		{"defaults", &HealthCheckConfig{Path: "/healthz"}, "", ""},
+		{"all set", &HealthCheckConfig{Path: "/ready", ExpectedStatus: 204, Interval: 10 * time.Second, Timeout: time.Second}, "", ""},
+		{"missing path", &HealthCheckConfig{}, "health_check.path must start with /", ""},
+		{"relative path", &HealthCheckConfig{Path: "healthz"}, "health_check.path must start with /", ""},
+		{"bad status", &HealthCheckConfig{Path: "/", ExpectedStatus: 42}, "expected_status", ""},
+		{"short interval", &HealthCheckConfig{Path: "/", Interval: 100 * time.Millisecond}, "interval must be at least 1s", ""},
+		{"negative timeout", &HealthCheckConfig{Path: "/", Timeout: -time.Second}, "timeout must not be negative", ""},
+		{"service without grpc", &HealthCheckConfig{Path: "/", Service: "payments"}, "service only applies to protocol: grpc", ""},
+		{"grpc defaults", &HealthCheckConfig{}, "", ProtocolGRPC},
+		{"grpc service", &HealthCheckConfig{Service: "payments", Interval: 10 * time.Second}, "", ProtocolGRPC},
+		{"grpc with path", &HealthCheckConfig{Path: "/healthz"}, "don't apply to protocol: grpc", ProtocolGRPC},
+		{"unknown protocol", nil, `protocol must be "grpc"`, "thrift"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+					"app.localhost": {Context: "ctx", Namespace: "apps", Service: "app", Port: 80, Protocol: tt.protocol, HealthCheck: tt.hc},
+				}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_UpstreamTLSAndSNI(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   K8sRouteConfig
+		wantErr string
+	}{
+		{"service default name", K8sRouteConfig{Service: "api", Scheme: "https", UpstreamTLS: &UpstreamTLSConfig{}}, ""},
+		{"pod with server_name", K8sRouteConfig{Pod: "api-0", Scheme: "https", UpstreamTLS: &UpstreamTLSConfig{ServerName: "api.internal"}}, ""},
+		{"https via scheme_routes", K8sRouteConfig{Service: "api", SchemeRoutes: map[string]SchemeRouteConfig{"http": {Scheme: "https", Port: 443}}, UpstreamTLS: &UpstreamTLSConfig{}}, ""},
+		{"plain http", K8sRouteConfig{Service: "api", UpstreamTLS: &UpstreamTLSConfig{}}, "upstream_tls needs scheme: https"},
+		{"pod without server_name", K8sRouteConfig{Pod: "api-0", Scheme: "https", UpstreamTLS: &UpstreamTLSConfig{}}, "server_name is required for pod routes"},
+		{"ip server_name", K8sRouteConfig{Service: "api", Scheme: "https", UpstreamTLS: &UpstreamTLSConfig{ServerName: "10.0.0.1"}}, "must be a hostname"},
+		{"upstream_sni", K8sRouteConfig{Service: "api", UpstreamSNI: "api.apps.svc.cluster.local"}, ""},
+		{"upstream_sni ip", K8sRouteConfig{Service: "api", UpstreamSNI: "10.0.0.1"}, `upstream_sni "10.0.0.1" must be a hostname`},
+		{"upstream_sni with terminate", K8sRouteConfig{Service: "api", TLS: TLSModeTerminate, UpstreamSNI: "api.internal"}, "upstream_sni only applies to tls: passthrough"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := tt.route
+			route.Context, route.Namespace, route.Port = "ctx", "apps", 443
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+					"api.localhost": route,
+				}}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if got := (K8sRouteConfig{Service: "api", Namespace: "apps"}).GetServerName(); got != "api.apps.svc" {
+		t.Errorf("GetServerName() = %q, want api.apps.svc", got)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	current := &Config{
+		HTTP: HTTPConfig{ListenAddr: ":8989", K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+			"keep.localhost":   {Context: "ctx", Namespace: "apps", Service: "keep", Port: 80},
+			"change.localhost": {Context: "ctx", Namespace: "apps", Service: "change", Port: 80},
+			"drop.localhost":   {Context: "ctx", Namespace: "apps", Service: "drop", Port: 80},
+		}}},
+		TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+			5432: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432},
+		}}},
+	}
+
+	if changes := DiffRoutes(current, current); len(changes) != 0 {
+		t.Errorf("Expected no changes against itself, got %v", changes)
+	}
+
+	next := &Config{
+		HTTP: HTTPConfig{ListenAddr: ":8989", K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+			"keep.localhost":   {Context: "ctx", Namespace: "apps", Service: "keep", Port: 80},
+			"change.localhost": {Context: "ctx", Namespace: "apps", Service: "change", Port: 8080},
+			"new.localhost":    {Context: "ctx", Namespace: "apps", Pod: "new-0", Port: 80},
+		}}},
+		TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+			5432: {Context: "ctx", Namespace: "db", Service: "postgres", Port: 5432},
+		}}},
+	}
+	want := []string{
+		"+ http new.localhost -> pod/new-0:80 (ctx/apps)",
+		"- http drop.localhost -> drop:80 (ctx/apps)",
+		"~ http change.localhost -> change:8080 (ctx/apps)",
+	}
+	if got := DiffRoutes(current, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffRoutes() =\n%v\nwant\n%v", got, want)
+	}
+
+	next.HTTP.ListenAddr = ":9090"
+	next.TCP.K8s.Routes = nil
+	got := DiffRoutes(current, next)
+	if got[len(got)-1] != "~ settings (outside the route tables)" {
+		t.Errorf("Expected a settings change last, got %v", got)
+	}
+	if !slices.Contains(got, "- tcp :5432 -> postgres:5432 (ctx/db)") {
+		t.Errorf("Expected the removed TCP route, got %v", got)
+	}
+}
+
+func TestServerRetry(t *testing.T) {
+	retry := ServerRetryConfig{MaxAttempts: 5, Backoff: time.Second}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 6: 30 * time.Second, 50: 30 * time.Second} {
+		if got := retry.Delay(n); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", n, got, want)
+		}
+	}
+
+	if cfg := DefaultConfig(); cfg.ServerRetry.MaxAttempts != DefaultServerRetryAttempts || cfg.ServerRetry.Backoff != DefaultServerRetryBackoff {
+		t.Errorf("unexpected default server_retry %+v", cfg.ServerRetry)
+	}
+
+	tests := []struct {
+		retry   ServerRetryConfig
+		wantErr string
+	}{
+		{ServerRetryConfig{}, ""}, // exit on the first error
+		{ServerRetryConfig{MaxAttempts: 3, Backoff: time.Second}, ""},
+		{ServerRetryConfig{MaxAttempts: -1}, "max_attempts must not be negative"},
+		{ServerRetryConfig{MaxAttempts: 3}, "backoff must be positive"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute}, ServerRetry: tt.retry}
+		err := cfg.Validate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tt.retry, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.retry, tt.wantErr, err)
+		}
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	if p := (PrewarmConfig{}); p.GetTimeout() != DefaultPrewarmTimeout || p.GetParallelism() != DefaultPrewarmParallelism {
+		t.Errorf("unexpected defaults %v, %d", p.GetTimeout(), p.GetParallelism())
+	}
+	if p := (PrewarmConfig{Timeout: time.Minute, Parallelism: 8}); p.GetTimeout() != time.Minute || p.GetParallelism() != 8 {
+		t.Errorf("unexpected values %v, %d", p.GetTimeout(), p.GetParallelism())
+	}
+
+	tests := []struct {
+		prewarm PrewarmConfig
+		wantErr string
+	}{
+		{PrewarmConfig{Enabled: true}, ""},
+		{PrewarmConfig{Enabled: true, Timeout: -time.Second}, "prewarm.timeout must not be negative"},
+		{PrewarmConfig{Enabled: true, Parallelism: -1}, "prewarm.parallelism must not be negative"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute}, Prewarm: tt.prewarm}
+		err := cfg.Validate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tt.prewarm, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.prewarm, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_IdleNotify(t *testing.T) {
+	tests := []struct {
+		notify  IdleNotifyConfig
+		wantErr string
+	}{
+		{IdleNotifyConfig{}, ""},
+		{IdleNotifyConfig{Before: 5 * time.Minute, Routes: []string{"tcp:5432"}, Webhook: "https://hooks.example.com/x"}, ""},
+		{IdleNotifyConfig{Before: -time.Minute}, "idle_notify.before must not be negative"},
+		{IdleNotifyConfig{Before: time.Minute, Webhook: "hooks.example.com"}, "idle_notify.webhook must be an http or https URL"},
+		{IdleNotifyConfig{Before: time.Minute, Routes: []string{""}}, "empty route name"},
+		{IdleNotifyConfig{Routes: []string{"tcp:5432"}}, "idle_notify.before is required"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute}, IdleNotify: tt.notify}
+		err := cfg.Validate()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %v", tt.notify, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.notify, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidate_ListenAddresses(t *testing.T) {
+	tests := []struct {
+		name       string
+		listen     string
+		listenHost string
+		wantErr    string
+	}{
+		{name: "port only (dual-stack)", listen: ":8989"},
+		{name: "ipv4 loopback", listen: "127.0.0.1:8989"},
+		{name: "ipv6 loopback", listen: "[::1]:8989"},
+		{name: "ipv6 any", listen: "[::]:8989"},
+		{name: "hostname", listen: "localhost:8989"},
+		{name: "tcp on ipv6 loopback", listen: ":8989", listenHost: "::1"},
+		{name: "unbracketed ipv6", listen: "::1:8989", wantErr: "invalid http.listen"},
+		{name: "no port", listen: "127.0.0.1", wantErr: "invalid http.listen"},
+		{name: "bad port", listen: "[::1]:http", wantErr: "invalid port"},
+		{name: "tcp on all interfaces", listen: ":8989", listenHost: "0.0.0.0", wantErr: "tcp.listen_host must be a loopback IP"},
+		{name: "tcp on hostname", listen: ":8989", listenHost: "localhost", wantErr: "tcp.listen_host must be a loopback IP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: tt.listen, IdleTimeout: time.Minute},
+				TCP:  TCPConfig{ListenHost: tt.listenHost},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeHostname(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"myapp.localhost", "myapp.localhost"},
+		{"MyApp.Localhost", "myapp.localhost"},
+		{"myapp.localhost.", "myapp.localhost"},
+		{"MyApp.Localhost.", "myapp.localhost"},
+		{"bücher.localhost", "xn--bcher-kva.localhost"},
+		{"BÜCHER.localhost", "xn--bcher-kva.localhost"},
+		{"xn--bcher-kva.localhost", "xn--bcher-kva.localhost"},
+		{"my_app.localhost", "my_app.localhost"},
+		{"::1", "::1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeHostname(tt.in); got != tt.want {
+			t.Errorf("NormalizeHostname(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_NormalizesHostnames(t *testing.T) {
+	route := `
+        context: test
+        namespace: default
+        service: test-svc
+        port: 80
+`
+	tests := []struct {
+		name      string
+		hostnames []string
+		want      string
+		wantErr   string
+	}{
+		{name: "mixed case and trailing dot", hostnames: []string{"MyApp.Localhost."}, want: "myapp.localhost"},
+		{name: "unicode", hostnames: []string{"bücher.localhost"}, want: "xn--bcher-kva.localhost"},
+		{name: "same host spelled twice", hostnames: []string{"myapp.localhost", "MyApp.localhost"}, wantErr: "are the same hostname"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "apiVersion: autotunnel/v1\nhttp:\n  listen: \":9999\"\n  idle_timeout: 30m\n  k8s:\n    routes:\n"
+			for _, hostname := range tt.hostnames {
+				content += "      \"" + hostname + "\":" + route
+			}
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write test config: %v", err)
+			}
+
+			cfg, err := LoadConfig(configPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			if _, ok := cfg.HTTP.K8s.Routes[tt.want]; !ok || len(cfg.HTTP.K8s.Routes) != 1 {
+				t.Errorf("expected only route %q, got %v", tt.want, cfg.HTTP.K8s.Routes)
+			}
+		})
+	}
+}
+
+func TestHostPatternConfig_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  HostPatternConfig
+		hostname string
+		want     K8sRouteConfig
+		wantOK   bool
+	}{
+		{
+			name:     "suffix with prefix capture",
+			pattern:  HostPatternConfig{Suffix: ".dev.mycorp.internal", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "${prefix}", Service: "web", Port: 80}},
+			hostname: "team-a.dev.mycorp.internal",
+			want:     K8sRouteConfig{Context: "dev", Namespace: "team-a", Service: "web", Port: 80},
+			wantOK:   true,
+		},
+		{
+			name:     "suffix without leading dot",
+			pattern:  HostPatternConfig{Suffix: "Dev.MyCorp.Internal", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "apps", Service: "web", Port: 80}},
+			hostname: "x.dev.mycorp.internal",
+			want:     K8sRouteConfig{Context: "dev", Namespace: "apps", Service: "web", Port: 80},
+			wantOK:   true,
+		},
+		{
+			name:     "suffix alone is no match",
+			pattern:  HostPatternConfig{Suffix: ".dev.mycorp.internal", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "apps", Service: "web", Port: 80}},
+			hostname: "dev.mycorp.internal",
+		},
+		{
+			name:     "regex with named and numbered groups",
+			pattern:  HostPatternConfig{Regex: `^(?P<svc>[a-z0-9-]+)\.([a-z0-9-]+)\.k\.localhost$`, K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "$2", Service: "${svc}", Port: 8080}},
+			hostname: "api.tenant-7.k.localhost",
+			want:     K8sRouteConfig{Context: "dev", Namespace: "tenant-7", Service: "api", Port: 8080},
+			wantOK:   true,
+		},
+		{
+			name:     "regex pod target",
+			pattern:  HostPatternConfig{Regex: `^(\w+)-pod\.localhost$`, K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "apps", Pod: "$1-0", Port: 80}},
+			hostname: "db-pod.localhost",
+			want:     K8sRouteConfig{Context: "dev", Namespace: "apps", Pod: "db-0", Port: 80},
+			wantOK:   true,
+		},
+		{
+			name:     "capture is not a valid namespace",
+			pattern:  HostPatternConfig{Regex: `^(.+)\.k\.localhost$`, K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "$1", Service: "web", Port: 80}},
+			hostname: "a_b.k.localhost",
+		},
+		{
+			name:     "no match",
+			pattern:  HostPatternConfig{Suffix: ".dev.localhost", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "apps", Service: "web", Port: 80}},
+			hostname: "app.prod.localhost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.pattern.Match(tt.hostname)
+			if ok != tt.wantOK {
+				t.Fatalf("Match(%q) ok = %v, want %v", tt.hostname, ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %+v, want %+v", tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_HostPatterns(t *testing.T) {
+	route := K8sRouteConfig{Context: "dev", Namespace: "${prefix}", Service: "web", Port: 80}
+	tests := []struct {
+		name    string
+		pattern HostPatternConfig
+		wantErr string
+	}{
+		{name: "valid suffix", pattern: HostPatternConfig{Suffix: ".dev.localhost", K8sRouteConfig: route}},
+		{name: "valid regex", pattern: HostPatternConfig{Regex: `^(?P<prefix>.+)\.localhost$`, K8sRouteConfig: route}},
+		{name: "neither", pattern: HostPatternConfig{K8sRouteConfig: route}, wantErr: "exactly one of suffix or regex"},
+		{name: "both", pattern: HostPatternConfig{Suffix: ".a", Regex: "b", K8sRouteConfig: route}, wantErr: "exactly one of suffix or regex"},
+		{name: "bad regex", pattern: HostPatternConfig{Regex: `^(`, K8sRouteConfig: route}, wantErr: "invalid regex"},
+		{name: "unknown group", pattern: HostPatternConfig{Regex: `^(.+)\.localhost$`, K8sRouteConfig: route}, wantErr: `unknown group "prefix"`},
+		{name: "group out of range", pattern: HostPatternConfig{Suffix: ".x", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "$2", Service: "web", Port: 80}}, wantErr: "refers to group 2"},
+		{name: "missing port", pattern: HostPatternConfig{Suffix: ".x", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "ns", Service: "web"}}, wantErr: "port must be between"},
+		{name: "pin_pod", pattern: HostPatternConfig{Suffix: ".x", K8sRouteConfig: K8sRouteConfig{Context: "dev", Namespace: "ns", Service: "web", Port: 80, PinPod: "auto"}}, wantErr: "only supported on routes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{
+				HostPatterns: []HostPatternConfig{tt.pattern},
+			}}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDynamicHostAllowConfig_Allows(t *testing.T) {
+	allow := DynamicHostAllowConfig{
+		Contexts:   []string{"dev", "staging"},
+		Namespaces: []string{"default", "team-*"},
+		Ports:      []string{"80", "8000-8999"},
+	}
+	tests := []struct {
+		name    string
+		route   K8sRouteConfig
+		wantErr string
+	}{
+		{name: "allowed", route: K8sRouteConfig{Context: "dev", Namespace: "team-a", Service: "web", Port: 8080}},
+		{name: "single port", route: K8sRouteConfig{Context: "staging", Namespace: "default", Service: "web", Port: 80}},
+		{name: "context", route: K8sRouteConfig{Context: "prod", Namespace: "default", Service: "web", Port: 80}, wantErr: "dynamic_host_allow.contexts"},
+		{name: "namespace", route: K8sRouteConfig{Context: "dev", Namespace: "kube-system", Service: "web", Port: 80}, wantErr: "dynamic_host_allow.namespaces"},
+		{name: "port", route: K8sRouteConfig{Context: "dev", Namespace: "default", Service: "web", Port: 443}, wantErr: "dynamic_host_allow.ports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := allow.Allows(tt.route)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := (DynamicHostAllowConfig{}).Allows(K8sRouteConfig{Context: "any", Namespace: "any", Port: 1}); err != nil {
+		t.Errorf("empty allowlist: unexpected error: %v", err)
+	}
+}
+
+func TestValidate_DynamicHostAllow(t *testing.T) {
+	tests := []struct {
+		name        string
+		dynamicHost string
+		allow       DynamicHostAllowConfig
+		wantErr     string
+	}{
+		{name: "valid", dynamicHost: "k8s.localhost", allow: DynamicHostAllowConfig{Contexts: []string{"dev"}, Namespaces: []string{"team-*"}, Ports: []string{"443", "8000-8999"}}},
+		{name: "without dynamic_host", allow: DynamicHostAllowConfig{Contexts: []string{"dev"}}, wantErr: "dynamic_host is not"},
+		{name: "bad glob", dynamicHost: "k8s.localhost", allow: DynamicHostAllowConfig{Namespaces: []string{"team-["}}, wantErr: "invalid pattern"},
+		{name: "bad port", dynamicHost: "k8s.localhost", allow: DynamicHostAllowConfig{Ports: []string{"70000"}}, wantErr: "dynamic_host_allow.ports"},
+		{name: "bad range", dynamicHost: "k8s.localhost", allow: DynamicHostAllowConfig{Ports: []string{"9000-8000"}}, wantErr: "dynamic_host_allow.ports"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{
+				DynamicHost:      tt.dynamicHost,
+				DynamicHostAllow: tt.allow,
+			}}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DynamicHostTLS(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name    string
+		k8s     K8sConfig
+		noTLS   bool
+		wantErr string
+	}{
+		{name: "terminate https", k8s: K8sConfig{DynamicHost: "k8s.localhost", DynamicHostTLS: TLSModeTerminate, DynamicHostScheme: "https"}},
+		{name: "passthrough", k8s: K8sConfig{DynamicHost: "k8s.localhost", DynamicHostTLS: TLSModePassthrough}},
+		{name: "bad tls", k8s: K8sConfig{DynamicHost: "k8s.localhost", DynamicHostTLS: "reencrypt"}, wantErr: "dynamic_host_tls must be"},
+		{name: "bad scheme", k8s: K8sConfig{DynamicHost: "k8s.localhost", DynamicHostScheme: "ftp"}, wantErr: "dynamic_host_scheme must be"},
+		{name: "terminate without tls", k8s: K8sConfig{DynamicHost: "k8s.localhost", DynamicHostTLS: TLSModeTerminate}, noTLS: true, wantErr: "http.tls_passthrough: false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: tt.k8s}}
+			if tt.noTLS {
+				cfg.HTTP.TLSPassthrough = &disabled
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_HostPatterns(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `apiVersion: autotunnel/v1
+http:
+  listen: ":9999"
+  idle_timeout: 30m
+  k8s:
+    host_patterns:
+      - suffix: .dev.localhost
+        context: dev
+        namespace: "${prefix}"
+        service: web
+        port: 80
+        scheme: https
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	route, ok := cfg.HTTP.K8s.MatchHostPattern("alice.dev.localhost")
+	if !ok {
+		t.Fatal("expected alice.dev.localhost to match the suffix pattern")
+	}
+	if route.Namespace != "alice" || route.Service != "web" || route.Scheme != "https" {
+		t.Errorf("unexpected route %+v", route)
+	}
+}
+
+func TestK8sRouteConfig_ForScheme(t *testing.T) {
+	route := K8sRouteConfig{
+		Context: "test", Namespace: "default", Service: "web", Port: 80, Scheme: "http", LocalPort: 18080,
+		SchemeRoutes: map[string]SchemeRouteConfig{
+			"http":  {Port: 8080, Scheme: "https"},
+			"https": {Pod: "web-tls-0", Port: 8443},
+		},
+	}
+
+	got, ok := route.ForScheme("http")
+	if !ok || got.Service != "web" || got.Port != 8080 || got.Scheme != "https" || got.SchemeRoutes != nil || got.LocalPort != 0 {
+		t.Errorf("ForScheme(http) = %+v, %v", got, ok)
+	}
+	got, ok = route.ForScheme("https")
+	if !ok || got.Service != "" || got.Pod != "web-tls-0" || got.Port != 8443 {
+		t.Errorf("ForScheme(https) = %+v, %v", got, ok)
+	}
+
+	plain := K8sRouteConfig{Context: "test", Namespace: "default", Service: "web", Port: 80}
+	if got, ok := plain.ForScheme("https"); ok || !reflect.DeepEqual(got, plain) {
+		t.Errorf("ForScheme without scheme_routes = %+v, %v", got, ok)
+	}
+}
+
+func TestValidate_SchemeRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     string
+		routes  map[string]SchemeRouteConfig
+		wantErr string
+	}{
+		{name: "http and https", routes: map[string]SchemeRouteConfig{"http": {Port: 8080}, "https": {Service: "web-tls", Port: 443}}},
+		{name: "unknown scheme", routes: map[string]SchemeRouteConfig{"h2": {Port: 8443}}, wantErr: `key must be "http" or "https"`},
+		{name: "service and pod", routes: map[string]SchemeRouteConfig{"https": {Service: "a", Pod: "b"}}, wantErr: "cannot specify both service and pod"},
+		{name: "bad port", routes: map[string]SchemeRouteConfig{"http": {Port: 70000}}, wantErr: "port must be between"},
+		{name: "scheme on https", routes: map[string]SchemeRouteConfig{"https": {Scheme: "https"}}, wantErr: "no effect on passthrough"},
+		{name: "https with tls terminate", tls: TLSModeTerminate, routes: map[string]SchemeRouteConfig{"https": {Port: 443}}, wantErr: "use scheme_routes[http]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{
+				Routes: map[string]K8sRouteConfig{
+					"app.localhost": {Context: "test", Namespace: "default", Service: "web", Port: 80, TLS: tt.tls, SchemeRoutes: tt.routes},
+				},
+			}}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_HTTPLocalPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   map[string]int // hostname -> local_port
+		wantErr string
+	}{
+		{name: "distinct ports", ports: map[string]int{"a.localhost": 18080, "b.localhost": 18081}},
+		{name: "out of range", ports: map[string]int{"a.localhost": 70000}, wantErr: "local_port must be between"},
+		{name: "http listen port", ports: map[string]int{"a.localhost": 8989}, wantErr: "already used by http.listen"},
+		{name: "tcp route port", ports: map[string]int{"a.localhost": 15432}, wantErr: "already used by tcp.k8s.routes[15432]"},
+		{name: "tcp extra port", ports: map[string]int{"a.localhost": 15433}, wantErr: "tcp.k8s.routes[15432].extra_ports[15433]"},
+		{name: "jump port", ports: map[string]int{"a.localhost": 13306}, wantErr: "already used by tcp.k8s.jump[13306]"},
+		{name: "another route", ports: map[string]int{"a.localhost": 18080, "b.localhost": 18080}, wantErr: `route "b.localhost": local_port 18080 is already used by route "a.localhost"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := make(map[string]K8sRouteConfig)
+			for hostname, port := range tt.ports {
+				routes[hostname] = K8sRouteConfig{Context: "test", Namespace: "default", Service: "web", Port: 80, LocalPort: port}
+			}
+			cfg := &Config{
+				HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{Routes: routes}},
+				TCP: TCPConfig{K8s: TCPK8sConfig{
+					Routes: map[int]TCPRouteConfig{15432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432, ExtraPorts: map[int]int{15433: 9187}}},
+					Jump:   map[int]JumpRouteConfig{13306: {Context: "test", Namespace: "db", Via: ViaConfig{Pod: "jump"}, Target: TargetConfig{Host: "mysql.example.com", Port: 3306}}},
+				}},
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_RouteMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   K8sRouteConfig
+		wantErr string
+	}{
+		{name: "http", route: K8sRouteConfig{Mode: RouteModeHTTP}},
+		{name: "tcp", route: K8sRouteConfig{Mode: RouteModeTCP}},
+		{name: "tcp with passthrough", route: K8sRouteConfig{Mode: RouteModeTCP, TLS: TLSModePassthrough}},
+		{name: "unknown", route: K8sRouteConfig{Mode: "udp"}, wantErr: `mode must be "http" or "tcp"`},
+		{name: "tcp with terminate", route: K8sRouteConfig{Mode: RouteModeTCP, TLS: TLSModeTerminate}, wantErr: "tls: terminate and scheme_routes don't apply"},
+		{name: "tcp with scheme_routes", route: K8sRouteConfig{Mode: RouteModeTCP, SchemeRoutes: map[string]SchemeRouteConfig{"http": {Port: 8080}}}, wantErr: "tls: terminate and scheme_routes don't apply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := tt.route
+			route.Context, route.Namespace, route.Service, route.Port = "test", "default", "cam", 554
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute, K8s: K8sConfig{
+				Routes: map[string]K8sRouteConfig{"cam.localhost": route},
+			}}}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetCleanupInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		httpIdle time.Duration
+		tcpIdle  time.Duration
+		want     time.Duration
+	}{
+		{name: "default", httpIdle: time.Hour, want: DefaultCleanupInterval},
+		{name: "explicit", interval: 5 * time.Minute, httpIdle: time.Hour, want: 5 * time.Minute},
+		{name: "short http idle timeout", httpIdle: 10 * time.Second, want: 5 * time.Second},
+		{name: "short tcp idle timeout", httpIdle: time.Hour, tcpIdle: 20 * time.Second, want: 10 * time.Second},
+		{name: "at least 1s", httpIdle: time.Second, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				CleanupInterval: tt.interval,
+				HTTP:            HTTPConfig{IdleTimeout: tt.httpIdle},
+				TCP:             TCPConfig{IdleTimeout: tt.tcpIdle},
+			}
+			if got := cfg.GetCleanupInterval(); got != tt.want {
+				t.Errorf("GetCleanupInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_CleanupInterval(t *testing.T) {
+	cfg := &Config{CleanupInterval: 500 * time.Millisecond, HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cleanup_interval must be at least 1s") {
+		t.Errorf("expected cleanup_interval error, got %v", err)
+	}
+	cfg.CleanupInterval = 2 * time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ContextsConfig maps names usable as a route's context to what they stand for
+type ContextsConfig map[string]ContextConfig
+
+// ContextConfig is one contexts: entry. A plain string is an alias for that
+// kubeconfig context; a mapping can also set up its exec credential plugin and
+// the proxy its API server is reached through.
+type ContextConfig struct {
+	Context  string            `yaml:"context"`   // kubeconfig context or path#context ("" = the entry's own name)
+	Env      map[string]string `yaml:"env"`       // Added to the exec plugin's environment, e.g. AWS_PROFILE
+	ExecPath []string          `yaml:"exec_path"` // Searched first for the exec plugin, and put on its PATH
+	Proxy    string            `yaml:"proxy"`     // http(s):// or socks5:// proxy URL, or "direct" ("" = HTTPS_PROXY/NO_PROXY, or the kubeconfig's proxy-url)
+}
+
+// ProxyDirect as a context's proxy connects to its API server without a proxy,
+// whatever HTTPS_PROXY says
+const ProxyDirect = "direct"
+
+func (c *ContextConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Context = node.Value
+		return nil
+	}
+	type plain ContextConfig
+	return node.Decode((*plain)(c))
+}
+
+// Target returns the kubeconfig context the entry named name stands for
+func (c ContextConfig) Target(name string) string {
+	if c.Context == "" {
+		return name
+	}
+	return c.Context
+}
+
+// HasExecSettings reports whether the entry changes how its exec plugin runs
+func (c ContextConfig) HasExecSettings() bool {
+	return len(c.Env) > 0 || len(c.ExecPath) > 0
+}
+
+// HasClientSettings reports whether the entry's client differs from the plain
+// kubeconfig context's: exec plugin settings or a proxy
+func (c ContextConfig) HasClientSettings() bool {
+	return c.HasExecSettings() || c.Proxy != ""
+}
+
+// contextsKey matches the top-level contexts: line of a config file
+var contextsKey = regexp.MustCompile(`(?m)^contexts:[ \t]*(#.*)?$`)
+
+// AddContextAlias adds alias -> contextName to the contexts: map of the config
+// file at path. The file is edited as text, so its comments and layout stay;
+// an alias already pointing at contextName is left alone.
+func AddContextAlias(path, alias, contextName string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	if current, ok := cfg.Contexts[alias]; ok {
+		if current.Target(alias) == contextName {
+			return nil
+		}
+		return fmt.Errorf("contexts: %s already points at %s", alias, current.Target(alias))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	text := string(data)
+	entry := fmt.Sprintf("%s: %q", alias, contextName)
+	if loc := contextsKey.FindStringIndex(text); loc != nil {
+		// first entry of the map, indented like the existing ones
+		rest := text[loc[1]:]
+		indent := "  "
+		if lines := strings.SplitN(strings.TrimPrefix(rest, "\n"), "\n", 2); len(lines) > 0 {
+			if trimmed := strings.TrimLeft(lines[0], " "); trimmed != "" && len(trimmed) < len(lines[0]) {
+				indent = lines[0][:len(lines[0])-len(trimmed)]
+			}
+		}
+		text = text[:loc[1]] + "\n" + indent + entry + rest
+	} else {
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		text += "\ncontexts:\n  " + entry + "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(text), info.Mode().Perm()); err != nil {
+		return err
+	}
+	// make sure the edit parses the way it should
+	if cfg, err := LoadConfig(path); err != nil || cfg.Contexts[alias].Context != contextName {
+		_ = os.WriteFile(path, data, info.Mode().Perm())
+		return fmt.Errorf("could not add contexts: %s to %s, add it by hand", alias, path)
+	}
+	return nil
+}
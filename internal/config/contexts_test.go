@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAddContextAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "autotunnel.yaml")
+	original := "apiVersion: autotunnel/v1\n# my routes\nhttp:\n  listen: \":8989\"\n  idle_timeout: 60m\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := AddContextAlias(path, "prod", "arn:aws:eks:us-east-1:123456789012:cluster/main"); err != nil {
+		t.Fatalf("first alias: %v", err)
+	}
+	if err := AddContextAlias(path, "staging", "~/.kube/work#staging"); err != nil {
+		t.Fatalf("second alias: %v", err)
+	}
+	if err := AddContextAlias(path, "prod", "arn:aws:eks:us-east-1:123456789012:cluster/main"); err != nil {
+		t.Errorf("adding the same alias again: %v", err)
+	}
+	if err := AddContextAlias(path, "prod", "other"); err == nil || !strings.Contains(err.Error(), "already points at") {
+		t.Errorf("repointing an alias: error = %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Contexts) != 2 || cfg.Contexts["staging"].Context != "~/.kube/work#staging" {
+		t.Errorf("contexts = %v", cfg.Contexts)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), original) {
+		t.Errorf("the existing config changed:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestContextConfig_UnmarshalYAML(t *testing.T) {
+	data := `
+contexts:
+  prod: arn:aws:eks:us-east-1:123456789012:cluster/main
+  staging:
+    context: gke_project_europe-west1_staging
+    env:
+      CLOUDSDK_CORE_PROJECT: project
+    exec_path: [~/google-cloud-sdk/bin]
+`
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := cfg.Contexts["prod"]; got.Context != "arn:aws:eks:us-east-1:123456789012:cluster/main" || got.HasExecSettings() {
+		t.Errorf("prod = %+v", got)
+	}
+	staging := cfg.Contexts["staging"]
+	if staging.Target("staging") != "gke_project_europe-west1_staging" || staging.Env["CLOUDSDK_CORE_PROJECT"] != "project" || len(staging.ExecPath) != 1 {
+		t.Errorf("staging = %+v", staging)
+	}
+}
+
+func TestValidate_ContextProxy(t *testing.T) {
+	for proxy, valid := range map[string]bool{
+		"":                        true,
+		"direct":                  true,
+		"http://proxy:3128":       true,
+		"socks5://127.0.0.1:1080": true,
+		"proxy:3128":              false,
+		"ftp://proxy:21":          false,
+		"http://":                 false,
+	} {
+		cfg := &Config{
+			HTTP:     HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+			Contexts: ContextsConfig{"corp": {Context: "corp-cluster", Proxy: proxy}},
+		}
+		err := cfg.Validate()
+		if valid && err != nil {
+			t.Errorf("proxy %q: unexpected error: %v", proxy, err)
+		} else if !valid && (err == nil || !strings.Contains(err.Error(), "proxy")) {
+			t.Errorf("proxy %q: expected proxy error, got %v", proxy, err)
+		}
+	}
+}
@@ -3,14 +3,28 @@ package config
 import (
 	_ "embed"
 	"os"
+	"time"
 )
 
+// DefaultStatusHost is the reserved hostname serving the status/admin API
+const DefaultStatusHost = "autotunnel.localhost"
+
+// DefaultKeepalive keeps port-forward connections active well within common
+// NAT and load balancer idle timeouts (AWS ELB: 60s)
+const DefaultKeepalive = 15 * time.Second
+
 //go:embed default_config.yaml
 var defaultConfigTemplate string
 
 func DefaultConfig() *Config {
 	return &Config{
+		Keepalive: DefaultKeepalive,
+		ServerRetry: ServerRetryConfig{
+			MaxAttempts: DefaultServerRetryAttempts,
+			Backoff:     DefaultServerRetryBackoff,
+		},
 		HTTP: HTTPConfig{
+			StatusHost: DefaultStatusHost,
 			K8s: K8sConfig{
 				Kubeconfig: "", // Empty = try $KUBECONFIG env var, then ~/.kube/config
 				Routes:     make(map[string]K8sRouteConfig),
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DiffRoutes describes how next differs from current, one line per added (+),
+// removed (-) or changed (~) route, sorted. Changes outside the route tables are
+// reported as a single "~ settings" line. Empty when nothing changed.
+func DiffRoutes(current, next *Config) []string {
+	var changes []string
+
+	changes = append(changes, diffMap(current.HTTP.K8s.Routes, next.HTTP.K8s.Routes, func(hostname string, r K8sRouteConfig) string {
+		return fmt.Sprintf("http %s -> %s:%d (%s/%s)", hostname, r.TargetDisplay(), r.Port, r.Context, r.Namespace)
+	})...)
+	changes = append(changes, diffMap(current.TCP.K8s.Routes, next.TCP.K8s.Routes, func(port int, r TCPRouteConfig) string {
+		return fmt.Sprintf("tcp :%d -> %s:%d (%s/%s)", port, r.TargetDisplay(), r.Port, r.Context, r.Namespace)
+	})...)
+	changes = append(changes, diffMap(current.TCP.K8s.Jump, next.TCP.K8s.Jump, func(port int, r JumpRouteConfig) string {
+		return fmt.Sprintf("jump :%d via %s -> %s (%s/%s)", port, r.Via.TargetDisplay(), r.TargetDisplay(), r.Context, r.Namespace)
+	})...)
+	changes = append(changes, diffMap(current.TCP.K8s.Groups, next.TCP.K8s.Groups, func(name string, g GroupRouteConfig) string {
+		return fmt.Sprintf("group %s -> all ports of %s (%s/%s)", name, g.Service, g.Context, g.Namespace)
+	})...)
+	changes = append(changes, diffMap(current.TCP.K8s.Postgres, next.TCP.K8s.Postgres, func(port int, r PostgresRouteConfig) string {
+		return fmt.Sprintf("postgres :%d -> %s<database>/%s:%d (%s)", port, r.NamespacePrefix, r.Service, r.Port, r.Context)
+	})...)
+	changes = append(changes, diffMap(hostPatternsByString(current), hostPatternsByString(next), func(pattern string, p HostPatternConfig) string {
+		return fmt.Sprintf("pattern %s -> %s:%d (%s/%s)", pattern, p.TargetDisplay(), p.Port, p.Context, p.Namespace)
+	})...)
+	sort.Strings(changes)
+
+	if !reflect.DeepEqual(withoutRoutes(current), withoutRoutes(next)) {
+		changes = append(changes, "~ settings (outside the route tables)")
+	}
+	return changes
+}
+
+func diffMap[K comparable, V any](current, next map[K]V, describe func(K, V) string) []string {
+	var changes []string
+	for key, route := range next {
+		old, ok := current[key]
+		switch {
+		case !ok:
+			changes = append(changes, "+ "+describe(key, route))
+		case !reflect.DeepEqual(old, route):
+			changes = append(changes, "~ "+describe(key, route))
+		}
+	}
+	for key, route := range current {
+		if _, ok := next[key]; !ok {
+			changes = append(changes, "- "+describe(key, route))
+		}
+	}
+	return changes
+}
+
+// withoutRoutes returns a copy of c with the route tables cleared
+func withoutRoutes(c *Config) Config {
+	stripped := *c
+	stripped.HTTP.K8s.Routes = nil
+	stripped.TCP.K8s.Routes = nil
+	stripped.TCP.K8s.Jump = nil
+	stripped.TCP.K8s.Groups = nil
+	stripped.TCP.K8s.Postgres = nil
+	stripped.HTTP.K8s.HostPatterns = nil
+	return stripped
+}
+
+func hostPatternsByString(c *Config) map[string]HostPatternConfig {
+	patterns := make(map[string]HostPatternConfig, len(c.HTTP.K8s.HostPatterns))
+	for _, p := range c.HTTP.K8s.HostPatterns {
+		patterns[p.String()] = p
+	}
+	return patterns
+}
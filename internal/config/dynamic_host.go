@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DynamicHostAllowConfig limits what dynamic_host hostnames may reach. Each empty
+// list allows anything; a hostname must pass every list that is set.
+type DynamicHostAllowConfig struct {
+	Contexts   []string `yaml:"contexts,omitempty"`   // Context names as written in the hostname (aliases included)
+	Namespaces []string `yaml:"namespaces,omitempty"` // Names or globs like "team-*"
+	Ports      []string `yaml:"ports,omitempty"`      // Target ports: "443" or ranges like "8000-8999"
+}
+
+// IsEmpty reports whether no allowlist is set
+func (a DynamicHostAllowConfig) IsEmpty() bool {
+	return len(a.Contexts) == 0 && len(a.Namespaces) == 0 && len(a.Ports) == 0
+}
+
+// Allows returns why route, parsed from a dynamic_host hostname, is not allowed,
+// or nil if it is
+func (a DynamicHostAllowConfig) Allows(route K8sRouteConfig) error {
+	if len(a.Contexts) > 0 && !slices.Contains(a.Contexts, route.Context) {
+		return fmt.Errorf("context %q is not in dynamic_host_allow.contexts", route.Context)
+	}
+	if len(a.Namespaces) > 0 && !matchesAnyGlob(a.Namespaces, route.Namespace) {
+		return fmt.Errorf("namespace %q is not in dynamic_host_allow.namespaces", route.Namespace)
+	}
+	if len(a.Ports) > 0 && !inAnyPortRange(a.Ports, route.Port) {
+		return fmt.Errorf("port %d is not in dynamic_host_allow.ports", route.Port)
+	}
+	return nil
+}
+
+func matchesAnyGlob(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func inAnyPortRange(ranges []string, port int) bool {
+	for _, s := range ranges {
+		if r, err := parsePortOrRange(s); err == nil && r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortOrRange parses "443" or "8000-8999"
+func parsePortOrRange(s string) (PortRange, error) {
+	if strings.Contains(s, "-") {
+		return ParsePortRange(s)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return PortRange{}, fmt.Errorf("port %q must be a number within 1-65535 or a range like \"8000-8999\"", s)
+	}
+	return PortRange{Min: port, Max: port}, nil
+}
+
+func (c *Config) validateDynamicHost() error {
+	k := c.HTTP.K8s
+	if k.DynamicHostTLS != "" && k.DynamicHostTLS != TLSModePassthrough && k.DynamicHostTLS != TLSModeTerminate {
+		return fmt.Errorf("http.k8s.dynamic_host_tls must be %q or %q, got %q", TLSModePassthrough, TLSModeTerminate, k.DynamicHostTLS)
+	}
+	if k.DynamicHostTLS == TLSModeTerminate && !c.TLSPassthroughEnabled() {
+		return fmt.Errorf("http.k8s.dynamic_host_tls: terminate needs TLS on http.listen, which http.tls_passthrough: false turns off")
+	}
+	if k.DynamicHostScheme != "" && k.DynamicHostScheme != "http" && k.DynamicHostScheme != "https" {
+		return fmt.Errorf("http.k8s.dynamic_host_scheme must be \"http\" or \"https\", got %q", k.DynamicHostScheme)
+	}
+
+	allow := k.DynamicHostAllow
+	if allow.IsEmpty() {
+		return nil
+	}
+	if c.HTTP.K8s.DynamicHost == "" {
+		return fmt.Errorf("http.k8s.dynamic_host_allow is set but dynamic_host is not")
+	}
+	for _, name := range allow.Contexts {
+		if name == "" {
+			return fmt.Errorf("http.k8s.dynamic_host_allow.contexts: empty context name")
+		}
+	}
+	for _, pattern := range allow.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("http.k8s.dynamic_host_allow.namespaces: invalid pattern %q", pattern)
+		}
+	}
+	for _, s := range allow.Ports {
+		if _, err := parsePortOrRange(s); err != nil {
+			return fmt.Errorf("http.k8s.dynamic_host_allow.ports: %w", err)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // DefaultExecPaths returns the default paths to add for exec credential plugins
@@ -45,10 +47,37 @@ func DefaultExecPaths() []string {
 	return paths
 }
 
+// ExecPathConfig is where exec credential plugins are looked for. A plain list
+// in the config is the same as prepend.
+type ExecPathConfig struct {
+	Prepend  []string `yaml:"prepend"`  // Searched before PATH
+	Append   []string `yaml:"append"`   // Searched after PATH, for tools that must not shadow others
+	Defaults *bool    `yaml:"defaults"` // nil = true: also prepend DefaultExecPaths()
+}
+
+func (e *ExecPathConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&e.Prepend)
+	}
+	type plain ExecPathConfig
+	return node.Decode((*plain)(e))
+}
+
+// UseDefaults reports whether DefaultExecPaths() are added
+func (e ExecPathConfig) UseDefaults() bool {
+	return e.Defaults == nil || *e.Defaults
+}
+
 // ExpandExecPath prepends the given paths and default paths to the current PATH.
 // It filters out paths that don't exist and avoids duplicates.
 // This is useful when running as a service where PATH is minimal.
 func ExpandExecPath(additionalPaths []string) {
+	ExecPathConfig{Prepend: additionalPaths}.Expand()
+}
+
+// Expand adds e's paths to PATH: Prepend and the default paths before it, Append
+// after it. Paths that don't exist or are already there are skipped.
+func (e ExecPathConfig) Expand() {
 	currentPath := os.Getenv("PATH")
 	existingPaths := make(map[string]bool)
 
@@ -57,37 +86,37 @@ func ExpandExecPath(additionalPaths []string) {
 		existingPaths[p] = true
 	}
 
-	var newPaths []string
-
-	// Process additional paths from config (user-specified first)
-	for _, p := range additionalPaths {
-		p = expandTilde(p)
-		if _, err := os.Stat(p); err == nil {
-			if !existingPaths[p] {
-				newPaths = append(newPaths, p)
-				existingPaths[p] = true
-			}
-		}
+	// user-specified first, then the defaults
+	prepend := newPaths(e.Prepend, existingPaths)
+	if e.UseDefaults() {
+		prepend = append(prepend, newPaths(DefaultExecPaths(), existingPaths)...)
 	}
+	appended := newPaths(e.Append, existingPaths)
 
-	// Process default paths
-	for _, p := range DefaultExecPaths() {
-		if _, err := os.Stat(p); err == nil {
-			if !existingPaths[p] {
-				newPaths = append(newPaths, p)
-				existingPaths[p] = true
-			}
-		}
+	if len(prepend) == 0 && len(appended) == 0 {
+		return
+	}
+	var parts []string
+	parts = append(parts, prepend...)
+	if currentPath != "" {
+		parts = append(parts, currentPath)
 	}
+	parts = append(parts, appended...)
+	os.Setenv("PATH", strings.Join(parts, string(os.PathListSeparator)))
+}
 
-	// Prepend new paths to existing PATH
-	if len(newPaths) > 0 {
-		expandedPath := strings.Join(newPaths, string(os.PathListSeparator))
-		if currentPath != "" {
-			expandedPath = expandedPath + string(os.PathListSeparator) + currentPath
+// newPaths returns the paths that exist and aren't in seen yet, adding them to seen
+func newPaths(paths []string, seen map[string]bool) []string {
+	var result []string
+	for _, p := range paths {
+		p = expandTilde(p)
+		if _, err := os.Stat(p); err != nil || seen[p] {
+			continue
 		}
-		os.Setenv("PATH", expandedPath)
+		seen[p] = true
+		result = append(result, p)
 	}
+	return result
 }
 
 // expandTilde expands ~ to the user's home directory
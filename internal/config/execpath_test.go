@@ -2,10 +2,13 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"runtime"
 	"slices"
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestDefaultExecPaths(t *testing.T) {
@@ -131,3 +134,40 @@ func TestExpandExecPath_EmptyInput(t *testing.T) {
 		t.Error("PATH should not be empty after ExpandExecPath")
 	}
 }
+
+func TestExecPathConfig_Expand(t *testing.T) {
+	originalPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", originalPath)
+	os.Setenv("PATH", "/usr/bin:/bin")
+
+	first, last := t.TempDir(), t.TempDir()
+	noDefaults := false
+	ExecPathConfig{Prepend: []string{first}, Append: []string{last}, Defaults: &noDefaults}.Expand()
+
+	want := first + ":/usr/bin:/bin:" + last
+	if got := os.Getenv("PATH"); got != want {
+		t.Errorf("PATH = %s, want %s", got, want)
+	}
+}
+
+func TestExecPathConfig_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want ExecPathConfig
+	}{
+		{name: "list", yaml: "exec_path: [/opt/a]", want: ExecPathConfig{Prepend: []string{"/opt/a"}}},
+		{name: "mapping", yaml: "exec_path: {prepend: [/opt/a], append: [/opt/b]}", want: ExecPathConfig{Prepend: []string{"/opt/a"}, Append: []string{"/opt/b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if err := yaml.Unmarshal([]byte(tt.yaml), &cfg); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.ExecPath, tt.want) {
+				t.Errorf("exec_path = %+v, want %+v", cfg.ExecPath, tt.want)
+			}
+		})
+	}
+}
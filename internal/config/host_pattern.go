@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// patternCache holds compiled host pattern expressions, shared across reloads
+var patternCache sync.Map // expression -> *regexp.Regexp
+
+// templateRefRegex finds capture group references in route fields: $1, $name, ${name}
+var templateRefRegex = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// dnsLabelRegex matches a Kubernetes namespace, service or pod name
+var dnsLabelRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// String describes the pattern for logs and errors, e.g. `suffix ".dev.localhost"`
+func (p HostPatternConfig) String() string {
+	if p.Suffix != "" {
+		return fmt.Sprintf("suffix %q", p.Suffix)
+	}
+	return fmt.Sprintf("regex %q", p.Regex)
+}
+
+// expression returns the regular expression the pattern matches hostnames with.
+// Hostnames are matched in normalized (lowercase) form.
+func (p HostPatternConfig) expression() string {
+	if p.Suffix != "" {
+		suffix := NormalizeHostname(p.Suffix)
+		if !strings.HasPrefix(suffix, ".") {
+			suffix = "." + suffix
+		}
+		return `^(?P<prefix>.+)` + regexp.QuoteMeta(suffix) + `$`
+	}
+	return p.Regex
+}
+
+func (p HostPatternConfig) compile() (*regexp.Regexp, error) {
+	expr := p.expression()
+	if re, ok := patternCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(expr, re)
+	return re, nil
+}
+
+// Match returns the pattern's route for hostname with capture groups filled in.
+// A match whose namespace, service or pod doesn't expand to a valid name is no match.
+func (p HostPatternConfig) Match(hostname string) (K8sRouteConfig, bool) {
+	re, err := p.compile()
+	if err != nil {
+		return K8sRouteConfig{}, false
+	}
+	match := re.FindStringSubmatchIndex(hostname)
+	if match == nil {
+		return K8sRouteConfig{}, false
+	}
+
+	expand := func(template string) string {
+		if !strings.Contains(template, "$") {
+			return template
+		}
+		return string(re.ExpandString(nil, template, hostname, match))
+	}
+
+	route := p.K8sRouteConfig
+	route.Context = expand(route.Context)
+	route.Namespace = expand(route.Namespace)
+	route.Service = expand(route.Service)
+	route.Pod = expand(route.Pod)
+
+	if route.Context == "" || !dnsLabelRegex.MatchString(route.Namespace) {
+		return K8sRouteConfig{}, false
+	}
+	if name := route.TargetName(); !dnsLabelRegex.MatchString(name) {
+		return K8sRouteConfig{}, false
+	}
+	return route, true
+}
+
+// MatchHostPattern returns the route of the first host pattern matching hostname
+func (k K8sConfig) MatchHostPattern(hostname string) (K8sRouteConfig, bool) {
+	for _, p := range k.HostPatterns {
+		if route, ok := p.Match(hostname); ok {
+			return route, true
+		}
+	}
+	return K8sRouteConfig{}, false
+}
+
+func (c *Config) validateHostPatterns() error {
+	for i, p := range c.HTTP.K8s.HostPatterns {
+		patternID := fmt.Sprintf("http.k8s.host_patterns[%d]", i)
+
+		if (p.Suffix == "") == (p.Regex == "") {
+			return fmt.Errorf("%s: exactly one of suffix or regex is required", patternID)
+		}
+		re, err := p.compile()
+		if err != nil {
+			return fmt.Errorf("%s: invalid regex: %w", patternID, err)
+		}
+		if err := validateRouteBase(patternID, p.Context, p.Namespace, p.Service, p.Pod, p.Port); err != nil {
+			return err
+		}
+		for _, field := range []string{p.Context, p.Namespace, p.Service, p.Pod} {
+			if err := checkTemplateRefs(re, field); err != nil {
+				return fmt.Errorf("%s: %w", patternID, err)
+			}
+		}
+		if p.TLS != "" && p.TLS != TLSModePassthrough && p.TLS != TLSModeTerminate {
+			return fmt.Errorf("%s: tls must be %q or %q, got %q", patternID, TLSModePassthrough, TLSModeTerminate, p.TLS)
+		}
+		if len(p.ALPNPorts) > 0 || p.PinPod != "" || p.HealthCheck != nil || p.LocalPort != 0 || p.Mode != "" || p.Protocol != "" || p.UpstreamTLS != nil || p.UpstreamSNI != "" || p.WarmupSchedule != "" {
+			return fmt.Errorf("%s: alpn_ports, pin_pod, health_check, local_port, mode, protocol, upstream_tls, upstream_sni and warmup_schedule are only supported on routes", patternID)
+		}
+		if p.ReadyTimeout < 0 {
+			return fmt.Errorf("%s: ready_timeout must not be negative", patternID)
+		}
+		if p.Standby < 0 || p.Standby > MaxStandby {
+			return fmt.Errorf("%s: standby must be between 0 and %d", patternID, MaxStandby)
+		}
+	}
+	return nil
+}
+
+// checkTemplateRefs rejects references to capture groups re doesn't have, which
+// would otherwise silently expand to ""
+func checkTemplateRefs(re *regexp.Regexp, template string) error {
+	for _, ref := range templateRefRegex.FindAllStringSubmatch(template, -1) {
+		name := ref[1] + ref[2]
+		if n, err := strconv.Atoi(name); err == nil {
+			if n > re.NumSubexp() {
+				return fmt.Errorf("%q refers to group %d, but the pattern has %d", template, n, re.NumSubexp())
+			}
+			continue
+		}
+		if re.SubexpIndex(name) < 0 {
+			return fmt.Errorf("%q refers to unknown group %q", template, name)
+		}
+	}
+	return nil
+}
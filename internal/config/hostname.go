@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeHostname returns the form hostnames are matched in: lowercase, without
+// a trailing dot, and with internationalized labels in punycode, so "MyApp.Localhost."
+// and "myapp.localhost" are the same route. Hosts idna rejects (IPs, underscores)
+// are only lowercased.
+func NormalizeHostname(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// normalizeHostnames rewrites the HTTP route, redirect, static and local keys, signature routes,
+// status_host and dynamic_host in normalized form. Two routes that differ only in spelling are an error.
+func (c *Config) normalizeHostnames() error {
+	c.HTTP.StatusHost = NormalizeHostname(c.HTTP.StatusHost)
+	c.HTTP.K8s.DynamicHost = NormalizeHostname(c.HTTP.K8s.DynamicHost)
+
+	if len(c.HTTP.Redirects) > 0 {
+		redirects := make(map[string]string, len(c.HTTP.Redirects))
+		spelled := make(map[string]string, len(c.HTTP.Redirects))
+		for hostname, target := range c.HTTP.Redirects {
+			key := NormalizeHostname(hostname)
+			if other, ok := spelled[key]; ok {
+				return fmt.Errorf("redirects %q and %q are the same hostname", other, hostname)
+			}
+			spelled[key] = hostname
+			redirects[key] = target
+		}
+		c.HTTP.Redirects = redirects
+	}
+	if len(c.HTTP.Static) > 0 {
+		static := make(map[string]StaticRouteConfig, len(c.HTTP.Static))
+		spelled := make(map[string]string, len(c.HTTP.Static))
+		for hostname, route := range c.HTTP.Static {
+			key := NormalizeHostname(hostname)
+			if other, ok := spelled[key]; ok {
+				return fmt.Errorf("static routes %q and %q are the same hostname", other, hostname)
+			}
+			spelled[key] = hostname
+			static[key] = route
+		}
+		c.HTTP.Static = static
+	}
+	if len(c.HTTP.Local) > 0 {
+		local := make(map[string]LocalRouteConfig, len(c.HTTP.Local))
+		spelled := make(map[string]string, len(c.HTTP.Local))
+		for hostname, route := range c.HTTP.Local {
+			key := NormalizeHostname(hostname)
+			if other, ok := spelled[key]; ok {
+				return fmt.Errorf("local routes %q and %q are the same hostname", other, hostname)
+			}
+			spelled[key] = hostname
+			local[key] = route
+		}
+		c.HTTP.Local = local
+	}
+
+	for i := range c.HTTP.Signatures {
+		c.HTTP.Signatures[i].Route = NormalizeHostname(c.HTTP.Signatures[i].Route)
+	}
+
+	if len(c.HTTP.K8s.Routes) == 0 {
+		return nil
+	}
+	routes := make(map[string]K8sRouteConfig, len(c.HTTP.K8s.Routes))
+	spelled := make(map[string]string, len(c.HTTP.K8s.Routes))
+	for hostname, route := range c.HTTP.K8s.Routes {
+		key := NormalizeHostname(hostname)
+		if other, ok := spelled[key]; ok {
+			return fmt.Errorf("routes %q and %q are the same hostname", other, hostname)
+		}
+		spelled[key] = hostname
+		routes[key] = route
+	}
+	c.HTTP.K8s.Routes = routes
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KubeObject holds a Kubernetes API value written in the config the way it is in a
+// manifest (camelCase field names), e.g. a pod's affinity. Unknown fields are
+// rejected, so a typo fails the config load instead of being dropped silently.
+type KubeObject[T any] struct {
+	Value T
+}
+
+func (o *KubeObject[T]) UnmarshalYAML(node *yaml.Node) error {
+	var raw any
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&o.Value); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCreateConfig_KubeObjects(t *testing.T) {
+	data := `
+image: alpine/socat:latest
+node_selector:
+  topology.kubernetes.io/zone: eu-west-1a
+tolerations:
+  - key: dedicated
+    operator: Equal
+    value: egress
+    effect: NoSchedule
+affinity:
+  nodeAffinity:
+    requiredDuringSchedulingIgnoredDuringExecution:
+      nodeSelectorTerms:
+        - matchExpressions:
+            - {key: network/vpc-routes, operator: Exists}
+service_account_name: jump
+security_context:
+  runAsNonRoot: true
+  runAsUser: 65534
+  seccompProfile: {type: RuntimeDefault}
+container_security_context:
+  allowPrivilegeEscalation: false
+  capabilities: {drop: [ALL]}
+`
+	var create CreateConfig
+	if err := yaml.Unmarshal([]byte(data), &create); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if create.NodeSelector["topology.kubernetes.io/zone"] != "eu-west-1a" {
+		t.Errorf("NodeSelector = %v", create.NodeSelector)
+	}
+	if tol := create.Tolerations.Value; len(tol) != 1 || tol[0].Effect != corev1.TaintEffectNoSchedule || tol[0].Value != "egress" {
+		t.Errorf("Tolerations = %+v", tol)
+	}
+	terms := create.Affinity.Value.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || terms[0].MatchExpressions[0].Operator != corev1.NodeSelectorOpExists {
+		t.Errorf("Affinity = %+v", create.Affinity.Value)
+	}
+	if sc := create.SecurityContext.Value; sc.RunAsUser == nil || *sc.RunAsUser != 65534 || sc.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("SecurityContext = %+v", sc)
+	}
+	if sc := create.ContainerSecurityContext.Value; sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation || sc.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("ContainerSecurityContext = %+v", sc)
+	}
+}
+
+func TestKubeObject_RejectsUnknownFields(t *testing.T) {
+	data := `
+image: alpine
+affinity:
+  nodeAfinity: {}
+`
+	var create CreateConfig
+	err := yaml.Unmarshal([]byte(data), &create)
+	if err == nil || !strings.Contains(err.Error(), "nodeAfinity") {
+		t.Errorf("Unmarshal() error = %v, want unknown field nodeAfinity", err)
+	}
+}
@@ -1,9 +1,9 @@
 package config
 
 import (
-	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"time"
 )
 
 func FileExists(path string) bool {
@@ -19,35 +19,114 @@ func (c *Config) ShouldAutoReload() bool {
 	return *c.AutoReloadConfig
 }
 
-func (c *Config) PrintRoutes() {
-	fmt.Printf("Routes (%d):\n", len(c.HTTP.K8s.Routes))
-	parts := strings.Split(c.HTTP.ListenAddr, ":")
-	port := parts[len(parts)-1]
-	for hostname, route := range c.HTTP.K8s.Routes {
-		scheme := route.Scheme
-		if scheme == "" {
-			scheme = "http"
-		}
-		fmt.Printf("  %s://%s:%s -> %s:%d (%s/%s)\n", scheme, hostname, port, route.TargetDisplay(), route.Port, route.Context, route.Namespace)
+// ShouldCheckUpdates returns whether a running autotunnel looks for new releases (default true)
+func (c *Config) ShouldCheckUpdates() bool {
+	if c.UpdateCheck == nil {
+		return true
+	}
+	return *c.UpdateCheck
+}
+
+// TLSPassthroughEnabled returns whether http.listen accepts TLS connections (default
+// true): passed through by SNI, or terminated for tls: terminate routes
+func (c *Config) TLSPassthroughEnabled() bool {
+	if c.HTTP.TLSPassthrough == nil {
+		return true
+	}
+	return *c.HTTP.TLSPassthrough
+}
+
+// GetDynamicHost returns the dynamic_host suffix, "" when it is unset or --locked
+func (c *Config) GetDynamicHost() string {
+	if c.Locked {
+		return ""
 	}
+	return c.HTTP.K8s.DynamicHost
 }
 
-func (c *Config) PrintTCPRoutes() {
-	if len(c.TCP.K8s.Routes) == 0 {
-		return
+// GetPortStateFile returns where tunnel ports are persisted, "" when persist_ports is off
+func (c *Config) GetPortStateFile() string {
+	if !c.PersistPorts {
+		return ""
 	}
-	fmt.Printf("TCP Routes (%d):\n", len(c.TCP.K8s.Routes))
-	for localPort, route := range c.TCP.K8s.Routes {
-		fmt.Printf("  :%d -> %s:%d (%s/%s)\n", localPort, route.TargetDisplay(), route.Port, route.Context, route.Namespace)
+	if c.PortStateFile != "" {
+		return expandTilde(c.PortStateFile)
 	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".autotunnel", "ports.json")
 }
 
-func (c *Config) PrintJumpRoutes() {
-	if len(c.TCP.K8s.Jump) == 0 {
-		return
+// GetServerName returns the name an https backend's certificate is verified for
+func (r K8sRouteConfig) GetServerName() string {
+	if r.UpstreamTLS != nil && r.UpstreamTLS.ServerName != "" {
+		return r.UpstreamTLS.ServerName
+	}
+	if r.Service != "" {
+		return r.Service + "." + r.Namespace + ".svc"
 	}
-	fmt.Printf("Jump Routes (%d):\n", len(c.TCP.K8s.Jump))
-	for localPort, route := range c.TCP.K8s.Jump {
-		fmt.Printf("  :%d via %s -> %s:%d (%s/%s) [%s]\n", localPort, route.Via.TargetDisplay(), route.Target.Host, route.Target.Port, route.Context, route.Namespace, route.GetMethod())
+	return ""
+}
+
+// GetCAFile returns the CA bundle path with ~ expanded, "" for the system roots
+func (u *UpstreamTLSConfig) GetCAFile() string {
+	return expandTilde(u.CAFile)
+}
+
+const (
+	ReloadModeAuto    = "auto"
+	ReloadModeConfirm = "confirm"
+)
+
+// ConfirmReloads reports whether config changes wait for `autotunnel reload apply`
+func (c *Config) ConfirmReloads() bool {
+	return c.ReloadMode == ReloadModeConfirm
+}
+
+// DefaultCleanupInterval is how often idle tunnels are looked for when neither
+// cleanup_interval nor a short idle timeout says otherwise
+const DefaultCleanupInterval = 30 * time.Second
+
+// GetCleanupInterval returns cleanup_interval, or DefaultCleanupInterval lowered to
+// half the shortest idle timeout (at least 1s) so short timeouts are honored promptly
+func (c *Config) GetCleanupInterval() time.Duration {
+	if c.CleanupInterval > 0 {
+		return c.CleanupInterval
+	}
+	shortest := c.HTTP.IdleTimeout
+	if c.TCP.IdleTimeout > 0 && c.TCP.IdleTimeout < shortest {
+		shortest = c.TCP.IdleTimeout
+	}
+	return max(min(DefaultCleanupInterval, shortest/2), time.Second)
+}
+
+// DefaultServiceCacheTTL is how long new tunnels reuse a route's service when
+// service_cache_ttl isn't set
+const DefaultServiceCacheTTL = 5 * time.Minute
+
+// GetServiceCacheTTL returns service_cache_ttl, DefaultServiceCacheTTL when unset,
+// or 0 when it is negative (caching off)
+func (c *Config) GetServiceCacheTTL() time.Duration {
+	switch {
+	case c.ServiceCacheTTL < 0:
+		return 0
+	case c.ServiceCacheTTL == 0:
+		return DefaultServiceCacheTTL
+	}
+	return c.ServiceCacheTTL
+}
+
+// DefaultConcurrentStarts is how many tunnels may establish at once when
+// concurrent_starts isn't set
+const DefaultConcurrentStarts = 8
+
+// GetConcurrentStarts returns concurrent_starts, DefaultConcurrentStarts when
+// unset, or 0 when it is negative (no limit)
+func (c *Config) GetConcurrentStarts() int {
+	switch {
+	case c.ConcurrentStarts < 0:
+		return 0
+	case c.ConcurrentStarts == 0:
+		return DefaultConcurrentStarts
 	}
+	return c.ConcurrentStarts
 }
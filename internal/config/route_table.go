@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// RouteInfo describes one configured route for the startup table and summary
+type RouteInfo struct {
+	ID         string `json:"id"`   // hostname, "tcp:<port>" or "group:<name>"
+	Kind       string `json:"kind"` // "http", "tcp", "jump", "group" or "postgres"
+	Local      string `json:"local"`
+	LocalPort  int    `json:"local_port,omitempty"` // TCP ports; the configured one until the server remaps it
+	Target     string `json:"target"`
+	Context    string `json:"context"`
+	Namespace  string `json:"namespace,omitempty"`
+	SharesWith string `json:"shares,omitempty"` // extra_ports: the route whose port-forward it uses
+}
+
+// Route kinds, in the order RouteList returns them
+const (
+	RouteKindHTTP     = "http"
+	RouteKindTCP      = "tcp"
+	RouteKindJump     = "jump"
+	RouteKindGroup    = "group"
+	RouteKindPostgres = "postgres"
+)
+
+var routeKindTitles = []struct{ kind, title string }{
+	{RouteKindHTTP, "HTTP routes"},
+	{RouteKindTCP, "TCP routes"},
+	{RouteKindJump, "Jump routes"},
+	{RouteKindGroup, "Group routes"},
+	{RouteKindPostgres, "Postgres routes"},
+}
+
+// RouteTableOptions controls how WriteRouteTable renders
+type RouteTableOptions struct {
+	Color    bool // ANSI colors, for terminals
+	MaxWidth int  // cells longer than this are cut with "…" (0 = 48)
+}
+
+const defaultRouteTableCellWidth = 48
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiDim   = "\033[2m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+)
+
+// RouteCount is the number of configured routes across all route tables
+func (c *Config) RouteCount() int {
+	return len(c.HTTP.K8s.Routes) + len(c.TCP.K8s.Routes) + len(c.TCP.K8s.Jump) + len(c.TCP.K8s.Groups) + len(c.TCP.K8s.Postgres)
+}
+
+// WriteRouteTable prints every route table as one aligned table, sorted by
+// hostname or port, with a count per section and a total
+func (c *Config) WriteRouteTable(w io.Writer, opts RouteTableOptions) {
+	routes := c.RouteList()
+	maxWidth := opts.MaxWidth
+	if maxWidth == 0 {
+		maxWidth = defaultRouteTableCellWidth
+	}
+
+	header := [4]string{"LOCAL", "TARGET", "CONTEXT", "NAMESPACE"}
+	widths := [4]int{}
+	measure := func(cells [4]string) {
+		for i, cell := range cells {
+			widths[i] = max(widths[i], utf8.RuneCountInString(truncateCell(cell, maxWidth)))
+		}
+	}
+	measure(header)
+	for _, r := range routes {
+		measure(r.cells())
+	}
+
+	paint := func(s, code string) string {
+		if !opts.Color || code == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	writeRow := func(cells [4]string, codes [4]string) {
+		var b strings.Builder
+		b.WriteString("  ")
+		for i, cell := range cells {
+			cell = truncateCell(cell, maxWidth)
+			if i < 3 {
+				cell += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2)
+			}
+			b.WriteString(paint(cell, codes[i]))
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+
+	total := 0
+	writeRow(header, [4]string{ansiDim, ansiDim, ansiDim, ansiDim})
+	for _, section := range routeKindTitles {
+		var rows []RouteInfo
+		count := 0
+		for _, r := range routes {
+			if r.Kind == section.kind {
+				rows = append(rows, r)
+				if r.SharesWith == "" {
+					count++
+				}
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, paint(fmt.Sprintf("%s (%d)", section.title, count), ansiBold+ansiCyan))
+		for _, r := range rows {
+			writeRow(r.cells(), [4]string{ansiGreen, "", ansiDim, ansiDim})
+		}
+		total += count
+	}
+	fmt.Fprintf(w, "%d routes\n", total)
+}
+
+func (r RouteInfo) cells() [4]string {
+	target := r.Target
+	if r.SharesWith != "" {
+		target = fmt.Sprintf("%s (shares %s)", r.Target, strings.TrimPrefix(r.SharesWith, "tcp"))
+	}
+	return [4]string{r.Local, target, r.Context, r.Namespace}
+}
+
+// truncateCell cuts s to max runes, ending in "…"
+func truncateCell(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+// RouteList returns every configured route, HTTP first, each kind sorted by
+// hostname, port or name. extra_ports follow the route they share.
+func (c *Config) RouteList() []RouteInfo {
+	var routes []RouteInfo
+	httpPort := c.HTTP.ListenAddr[strings.LastIndex(c.HTTP.ListenAddr, ":")+1:]
+
+	for _, hostname := range sortedKeys(c.HTTP.K8s.Routes) {
+		route := c.HTTP.K8s.Routes[hostname]
+		scheme := route.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		routes = append(routes, RouteInfo{
+			ID:        hostname,
+			Kind:      RouteKindHTTP,
+			Local:     fmt.Sprintf("%s://%s:%s", scheme, hostname, httpPort),
+			Target:    fmt.Sprintf("%s:%d", route.TargetDisplay(), route.Port),
+			Context:   route.Context,
+			Namespace: route.Namespace,
+		})
+	}
+
+	for _, port := range sortedKeys(c.TCP.K8s.Routes) {
+		route := c.TCP.K8s.Routes[port]
+		id := fmt.Sprintf("tcp:%d", port)
+		routes = append(routes, RouteInfo{
+			ID:        id,
+			Kind:      RouteKindTCP,
+			Local:     fmt.Sprintf(":%d", port),
+			LocalPort: port,
+			Target:    fmt.Sprintf("%s:%d", route.TargetDisplay(), route.Port),
+			Context:   route.Context,
+			Namespace: route.Namespace,
+		})
+		for _, extra := range sortedKeys(route.ExtraPorts) {
+			routes = append(routes, RouteInfo{
+				ID:         fmt.Sprintf("tcp:%d", extra),
+				Kind:       RouteKindTCP,
+				Local:      fmt.Sprintf(":%d", extra),
+				LocalPort:  extra,
+				Target:     fmt.Sprintf("%s:%d", route.TargetDisplay(), route.ExtraPorts[extra]),
+				SharesWith: id,
+			})
+		}
+	}
+
+	for _, port := range sortedKeys(c.TCP.K8s.Jump) {
+		route := c.TCP.K8s.Jump[port]
+		routes = append(routes, RouteInfo{
+			ID:        fmt.Sprintf("tcp:%d", port),
+			Kind:      RouteKindJump,
+			Local:     fmt.Sprintf(":%d", port),
+			LocalPort: port,
+			Target:    fmt.Sprintf("%s via %s [%s]", route.TargetDisplay(), route.Via.TargetDisplay(), route.GetMethod()),
+			Context:   route.Context,
+			Namespace: route.Namespace,
+		})
+	}
+
+	for _, name := range sortedKeys(c.TCP.K8s.Groups) {
+		group := c.TCP.K8s.Groups[name]
+		routes = append(routes, RouteInfo{
+			ID:        "group:" + name,
+			Kind:      RouteKindGroup,
+			Local:     name,
+			Target:    "all ports of " + group.Service,
+			Context:   group.Context,
+			Namespace: group.Namespace,
+		})
+	}
+
+	for _, port := range sortedKeys(c.TCP.K8s.Postgres) {
+		route := c.TCP.K8s.Postgres[port]
+		routes = append(routes, RouteInfo{
+			ID:        fmt.Sprintf("tcp:%d", port),
+			Kind:      RouteKindPostgres,
+			Local:     fmt.Sprintf(":%d", port),
+			LocalPort: port,
+			Target:    fmt.Sprintf("%s:%d", route.Service, route.Port),
+			Context:   route.Context,
+			Namespace: route.NamespacePrefix + "<database>",
+		})
+	}
+	return routes
+}
+
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
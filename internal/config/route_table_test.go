@@ -0,0 +1,85 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteRouteTable(t *testing.T) {
+	cfg := &Config{
+		HTTP: HTTPConfig{ListenAddr: ":8989", K8s: K8sConfig{Routes: map[string]K8sRouteConfig{
+			"web.localhost": {Context: "dev", Namespace: "default", Service: "web", Port: 80},
+			"api.localhost": {Context: "dev", Namespace: "default", Service: "api", Port: 8080, Scheme: "https"},
+		}}},
+		TCP: TCPConfig{K8s: TCPK8sConfig{Routes: map[int]TCPRouteConfig{
+			15432: {Context: "dev", Namespace: "db", Service: "postgres", Port: 5432, ExtraPorts: map[int]int{15433: 9187}},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	cfg.WriteRouteTable(&buf, RouteTableOptions{})
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+
+	want := []string{
+		"  LOCAL                       TARGET                         CONTEXT  NAMESPACE",
+		"HTTP routes (2)",
+		"  https://api.localhost:8989  api:8080                       dev      default",
+		"  http://web.localhost:8989   web:80                         dev      default",
+		"TCP routes (1)",
+		"  :15432                      postgres:5432                  dev      db",
+		"  :15433                      postgres:9187 (shares :15432)",
+		"3 routes",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected table:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if strings.Contains(buf.String(), "\033[") {
+		t.Error("expected no ANSI codes without Color")
+	}
+
+	buf.Reset()
+	cfg.WriteRouteTable(&buf, RouteTableOptions{Color: true})
+	if !strings.Contains(buf.String(), ansiBold+ansiCyan+"HTTP routes (2)"+ansiReset) {
+		t.Errorf("expected colored section titles, got %q", buf.String())
+	}
+}
+
+func TestTruncateCell(t *testing.T) {
+	if got := truncateCell("short", 10); got != "short" {
+		t.Errorf("truncateCell(short) = %q", got)
+	}
+	if got := truncateCell("abcdefghij-klm", 10); got != "abcdefghi…" {
+		t.Errorf("truncateCell = %q, want abcdefghi…", got)
+	}
+}
+
+func TestRouteList(t *testing.T) {
+	cfg := &Config{
+		HTTP: HTTPConfig{ListenAddr: "127.0.0.1:8989"},
+		TCP: TCPConfig{K8s: TCPK8sConfig{
+			Routes: map[int]TCPRouteConfig{15432: {Context: "dev", Namespace: "db", Service: "postgres", Port: 5432, ExtraPorts: map[int]int{15433: 9187}}},
+			Jump:   map[int]JumpRouteConfig{13306: {Context: "dev", Namespace: "default", Via: ViaConfig{Pod: "jump"}, Target: TargetConfig{Host: "db.example.com", Port: 3306}}},
+			Groups: map[string]GroupRouteConfig{"api": {Context: "dev", Namespace: "default", Service: "api"}},
+		}},
+	}
+
+	routes := cfg.RouteList()
+	want := []struct{ id, kind, shares string }{
+		{"tcp:15432", RouteKindTCP, ""},
+		{"tcp:15433", RouteKindTCP, "tcp:15432"},
+		{"tcp:13306", RouteKindJump, ""},
+		{"group:api", RouteKindGroup, ""},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("expected %d routes, got %+v", len(want), routes)
+	}
+	for i, w := range want {
+		if routes[i].ID != w.id || routes[i].Kind != w.kind || routes[i].SharesWith != w.shares {
+			t.Errorf("route %d = %+v, want id %s kind %s shares %q", i, routes[i], w.id, w.kind, w.shares)
+		}
+	}
+	if routes[1].LocalPort != 15433 {
+		t.Errorf("extra port local_port = %d, want 15433", routes[1].LocalPort)
+	}
+}
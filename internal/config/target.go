@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML lets a jump route's target be a mapping (host/port), a "host:port"
+// string, or a list of either tried in order. The first entry of a list becomes
+// Target and the others Fallbacks.
+func (r *JumpRouteConfig) UnmarshalYAML(node *yaml.Node) error {
+	// decode everything but target as usual
+	rest := *node
+	rest.Content = nil
+	var targetNode *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "target" {
+			targetNode = node.Content[i+1]
+			continue
+		}
+		rest.Content = append(rest.Content, node.Content[i], node.Content[i+1])
+	}
+
+	type plain JumpRouteConfig
+	if err := rest.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	if targetNode == nil {
+		return nil
+	}
+
+	if targetNode.Kind != yaml.SequenceNode {
+		return decodeTarget(targetNode, &r.Target)
+	}
+	if len(targetNode.Content) == 0 {
+		return fmt.Errorf("line %d: target list is empty", targetNode.Line)
+	}
+	targets := make([]TargetConfig, len(targetNode.Content))
+	for i, item := range targetNode.Content {
+		if item.Kind == yaml.SequenceNode {
+			return fmt.Errorf("line %d: target list entries must be host:port or host/port mappings", item.Line)
+		}
+		if err := decodeTarget(item, &targets[i]); err != nil {
+			return err
+		}
+	}
+	r.Target = targets[0]
+	r.Fallbacks = targets[1:]
+	return nil
+}
+
+func decodeTarget(node *yaml.Node, target *TargetConfig) error {
+	if node.Kind != yaml.ScalarNode {
+		return node.Decode(target)
+	}
+	host, port, err := net.SplitHostPort(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: target %q: expected host:port", node.Line, node.Value)
+	}
+	target.Host = host
+	if target.Port, err = strconv.Atoi(port); err != nil {
+		return fmt.Errorf("line %d: target %q: invalid port", node.Line, node.Value)
+	}
+	return nil
+}
+
+// UsesTLS reports whether any of the route's targets is reached over TLS
+func (r JumpRouteConfig) UsesTLS() bool {
+	for _, target := range r.Targets() {
+		if target.TLS {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets returns Target followed by Fallbacks, in the order they are tried
+func (r JumpRouteConfig) Targets() []TargetConfig {
+	return append([]TargetConfig{r.Target}, r.Fallbacks...)
+}
+
+// TargetDisplay formats the targets as "host:port", comma separated
+func (r JumpRouteConfig) TargetDisplay() string {
+	targets := r.Targets()
+	parts := make([]string, len(targets))
+	for i, target := range targets {
+		parts[i] = fmt.Sprintf("%s:%d", target.Host, target.Port)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestJumpRouteConfig_TargetForms(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{name: "mapping", yaml: "target: {host: db.internal, port: 5432}", want: "db.internal:5432"},
+		{name: "string", yaml: "target: db.internal:5432", want: "db.internal:5432"},
+		{
+			name: "list",
+			yaml: "target:\n  - writer-a.rds.internal:5432\n  - {host: writer-b.rds.internal, port: 5432}\n  - \"[2001:db8::1]:5432\"",
+			want: "writer-a.rds.internal:5432, writer-b.rds.internal:5432, 2001:db8::1:5432",
+		},
+		{name: "empty list", yaml: "target: []", wantErr: "target list is empty"},
+		{name: "no port", yaml: "target: db.internal", wantErr: "expected host:port"},
+		{name: "bad port", yaml: "target: db.internal:pg", wantErr: "invalid port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var route JumpRouteConfig
+			err := yaml.Unmarshal([]byte("context: prod\nvia: {pod: jump}\n"+tt.yaml), &route)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got := route.TargetDisplay(); got != tt.want {
+				t.Errorf("TargetDisplay() = %q, want %q", got, tt.want)
+			}
+			// the rest of the route decodes as before
+			if route.Context != "prod" || route.Via.Pod != "jump" {
+				t.Errorf("route = %+v", route)
+			}
+		})
+	}
+}
+
+func TestValidate_JumpRouteFallbackTargets(t *testing.T) {
+	route := JumpRouteConfig{
+		Context: "ctx", Namespace: "default",
+		Via:       ViaConfig{Pod: "jump"},
+		Target:    TargetConfig{Host: "writer-a.internal", Port: 5432},
+		Fallbacks: []TargetConfig{{Host: "writer-b.internal; rm -rf /", Port: 5432}},
+	}
+	cfg := &Config{
+		ApiVersion: CurrentApiVersion,
+		HTTP:       HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute},
+		TCP:        TCPConfig{K8s: TCPK8sConfig{Jump: map[int]JumpRouteConfig{5432: route}}},
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid characters") {
+		t.Errorf("Validate() error = %v, want the fallback's host rejected", err)
+	}
+}
+
+func TestValidateTargetTLS(t *testing.T) {
+	tests := []struct {
+		target  TargetConfig
+		wantErr string
+	}{
+		{target: TargetConfig{Host: "api.internal", Port: 443, TLS: true, SNI: "api.internal", CAFile: "/etc/ssl/ca.pem"}},
+		{target: TargetConfig{Host: "api.internal", Port: 443, SNI: "api.internal"}, wantErr: "need target.tls"},
+		{target: TargetConfig{Host: "api.internal", Port: 443, TLS: true, SNI: "10.0.0.1"}, wantErr: "must be a hostname"},
+		{target: TargetConfig{Host: "api.internal", Port: 443, TLS: true, CAFile: "ca.pem;id"}, wantErr: "must be an absolute path"},
+		{target: TargetConfig{Host: "api.internal", Port: 443, TLS: true, CAFile: "/ca.pem", InsecureSkipVerify: true}, wantErr: "mutually exclusive"},
+	}
+	for _, tt := range tests {
+		err := validateTargetTLS(tt.target)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("validateTargetTLS(%+v) error = %v", tt.target, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validateTargetTLS(%+v) error = %v, want %q", tt.target, err, tt.wantErr)
+		}
+	}
+}
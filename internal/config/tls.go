@@ -0,0 +1,80 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+)
+
+// TLSConfig restricts the TLS autotunnel speaks itself: when terminating TLS with
+// the dev CA (tls: terminate, upstream_sni, error pages, HTTP/3, https to static,
+// local and echo hosts) and towards https backends. Passthrough connections are
+// between the client and the backend and aren't affected.
+type TLSConfig struct {
+	MinVersion   string   `yaml:"min_version"`   // "1.2" (default) or "1.3"
+	CipherSuites []string `yaml:"cipher_suites"` // Go names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; apply to TLS 1.2 (empty = Go's defaults)
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// GetMinVersion returns min_version as a tls.VersionTLS* constant
+func (t TLSConfig) GetMinVersion() uint16 {
+	if v, ok := tlsVersions[t.MinVersion]; ok {
+		return v
+	}
+	return tls.VersionTLS12
+}
+
+// GetCipherSuites returns the cipher_suites IDs, nil for Go's defaults
+func (t TLSConfig) GetCipherSuites() []uint16 {
+	if len(t.CipherSuites) == 0 {
+		return nil
+	}
+	ids := make([]uint16, 0, len(t.CipherSuites))
+	for _, name := range t.CipherSuites {
+		if id, ok := cipherSuiteID(name); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Apply sets the minimum version and cipher suites on c and returns it. A higher
+// minimum c already has (HTTP/3 needs TLS 1.3) is kept.
+func (t TLSConfig) Apply(c *tls.Config) *tls.Config {
+	c.MinVersion = max(c.MinVersion, t.GetMinVersion())
+	if suites := t.GetCipherSuites(); suites != nil {
+		c.CipherSuites = suites
+	}
+	return c
+}
+
+// cipherSuiteID looks up a TLS 1.2 cipher suite Go considers secure by name
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name && slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+			return suite.ID, true
+		}
+	}
+	return 0, false
+}
+
+// validateTLS checks tls: a known version and cipher suites Go offers for TLS 1.2
+func (c *Config) validateTLS() error {
+	if _, ok := tlsVersions[c.TLS.MinVersion]; c.TLS.MinVersion != "" && !ok {
+		return fmt.Errorf("tls.min_version must be \"1.2\" or \"1.3\", got %q", c.TLS.MinVersion)
+	}
+	for _, name := range c.TLS.CipherSuites {
+		if _, ok := cipherSuiteID(name); !ok {
+			return fmt.Errorf("tls.cipher_suites: %q is not a secure TLS 1.2 cipher suite known to Go", name)
+		}
+	}
+	// Go doesn't let TLS 1.3 suites be chosen; they are all AEADs
+	if len(c.TLS.CipherSuites) > 0 && c.TLS.GetMinVersion() == tls.VersionTLS13 {
+		return fmt.Errorf("tls.cipher_suites only applies to TLS 1.2, which min_version 1.3 turns off")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTLSConfig_Apply(t *testing.T) {
+	settings := TLSConfig{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+	c := settings.Apply(&tls.Config{})
+	if c.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want TLS 1.2 by default", c.MinVersion)
+	}
+	if len(c.CipherSuites) != 1 || c.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("CipherSuites = %v", c.CipherSuites)
+	}
+
+	// a higher minimum (HTTP/3) is kept
+	if c := (TLSConfig{}).Apply(&tls.Config{MinVersion: tls.VersionTLS13}); c.MinVersion != tls.VersionTLS13 {
+		t.Errorf("MinVersion = %x, want TLS 1.3 kept", c.MinVersion)
+	}
+	if c := (TLSConfig{MinVersion: "1.3"}).Apply(&tls.Config{}); c.MinVersion != tls.VersionTLS13 || c.CipherSuites != nil {
+		t.Errorf("min_version 1.3: got %x, %v", c.MinVersion, c.CipherSuites)
+	}
+}
+
+func TestValidate_TLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr string
+	}{
+		{"defaults", TLSConfig{}, ""},
+		{"tls 1.3", TLSConfig{MinVersion: "1.3"}, ""},
+		{"suites", TLSConfig{MinVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}, ""},
+		{"old version", TLSConfig{MinVersion: "1.0"}, "min_version"},
+		{"unknown suite", TLSConfig{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}, "not a secure TLS 1.2 cipher suite"},
+		{"tls 1.3 suite", TLSConfig{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}, "not a secure TLS 1.2 cipher suite"},
+		{"suites with 1.3", TLSConfig{MinVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}}, "only applies to TLS 1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{HTTP: HTTPConfig{ListenAddr: ":8989", IdleTimeout: time.Minute}, TLS: tt.tls}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,11 +1,84 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
 
 type HTTPConfig struct {
-	ListenAddr  string        `yaml:"listen"`
-	IdleTimeout time.Duration `yaml:"idle_timeout"`
-	K8s         K8sConfig     `yaml:"k8s"`
+	ListenAddr               string        `yaml:"listen"`
+	PrivilegedFallbackListen string        `yaml:"privileged_fallback_listen"` // Used if listen is a port < 1024 we may not bind
+	IdleTimeout              time.Duration `yaml:"idle_timeout"`
+	StatusHost               string        `yaml:"status_host"`                 // Reserved hostname serving the status/admin API ("" = disabled)
+	EchoHost                 string        `yaml:"echo_host"`                   // Hostname answering every request with what reached autotunnel ("" = disabled)
+	DebugErrors              bool          `yaml:"debug_errors"`                // Add a diagnostics block (failed step, k8s error, target) to 502 responses
+	SlowRequestThreshold     time.Duration `yaml:"slow_request_threshold"`      // Warn about requests slower than this (0 = off)
+	LargeResponseThresholdMB int           `yaml:"large_response_threshold_mb"` // Warn about responses larger than this many MB (0 = off)
+	H2C                      bool          `yaml:"h2c"`                         // Serve HTTP/2 clients with prior knowledge on the plaintext port (false = close their connections)
+	TLSPassthrough           *bool         `yaml:"tls_passthrough"`             // nil = true; false closes TLS connections on listen right away (HTTP/3 is unaffected)
+	HTTP3                    HTTP3Config   `yaml:"http3"`
+	K8s                      K8sConfig     `yaml:"k8s"`
+
+	// Redirects answers requests for a hostname with a redirect to a URL instead of
+	// a tunnel, e.g. team short links: {ci.localhost: https://jenkins.example.com}
+	Redirects map[string]string `yaml:"redirects,omitempty"`
+
+	// Static serves a local directory at a hostname, e.g. a frontend build next to
+	// the cluster APIs it calls
+	Static map[string]StaticRouteConfig `yaml:"static,omitempty"`
+
+	// Local proxies a hostname to a process running on this machine, so it shares
+	// the listener, cookies and scheme with the routes into the cluster
+	Local map[string]LocalRouteConfig `yaml:"local,omitempty"`
+
+	// Signatures send plaintext connections that start with known bytes to a
+	// mode: tcp route, for protocols that name no host (SSH, Redis). Tried in order.
+	Signatures []ProtocolSignature `yaml:"signatures,omitempty"`
+}
+
+// ProtocolSignature routes plaintext connections by the first bytes the client sends
+type ProtocolSignature struct {
+	Prefix string `yaml:"prefix"` // e.g. "SSH-"; YAML escapes like "\r\n" work in double quotes
+	Route  string `yaml:"route"`  // hostname of an http.k8s.routes entry with mode: tcp
+}
+
+// MaxSignaturePrefix bounds http.signatures prefixes: connections are held until
+// they either match one or can't anymore
+const MaxSignaturePrefix = 64
+
+// LocalRouteConfig is a process on this machine that http.local proxies a hostname
+// to, listening on Port or Socket
+type LocalRouteConfig struct {
+	Port   int    `yaml:"port,omitempty"`   // on 127.0.0.1
+	Socket string `yaml:"socket,omitempty"` // unix socket path, absolute or starting with ~
+	Scheme string `yaml:"scheme,omitempty"` // "http" (default) or "https"; the certificate isn't verified
+}
+
+// Address is where the process listens: "127.0.0.1:<port>" or the socket path
+func (r LocalRouteConfig) Address() string {
+	if r.Socket != "" {
+		return r.Socket
+	}
+	return fmt.Sprintf("127.0.0.1:%d", r.Port)
+}
+
+// StaticRouteConfig is a local directory served by http.static
+type StaticRouteConfig struct {
+	Dir string `yaml:"dir"`           // absolute, or starting with ~
+	SPA bool   `yaml:"spa,omitempty"` // answer paths without a file with index.html, for client-side routing
+}
+
+// HTTP3Config enables an HTTP/3 (QUIC) listener. TLS is terminated locally with
+// the dev CA (see `autotunnel ca`); requests are proxied like plain HTTP ones.
+type HTTP3Config struct {
+	Listen string `yaml:"listen"` // UDP address, e.g. "127.0.0.1:8443" ("" = disabled)
 }
 
 type K8sConfig struct {
@@ -13,15 +86,206 @@ type K8sConfig struct {
 	ResolvedKubeconfigs []string                  `yaml:"-"` // computed at load time
 	Routes              map[string]K8sRouteConfig `yaml:"routes"`
 	DynamicHost         string                    `yaml:"dynamic_host"`
+	HostPatterns        []HostPatternConfig       `yaml:"host_patterns"` // Tried in order after routes and dynamic_host
+
+	// DynamicHostAllow limits the contexts, namespaces and ports dynamic_host
+	// hostnames may reach (empty lists = any)
+	DynamicHostAllow DynamicHostAllowConfig `yaml:"dynamic_host_allow"`
+
+	// DynamicHostTLS and DynamicHostScheme are the tls and scheme of every
+	// dynamic_host route: "terminate" serves a dev CA certificate per hostname, and
+	// "https" proxies requests to the service over TLS
+	DynamicHostTLS    string `yaml:"dynamic_host_tls"`
+	DynamicHostScheme string `yaml:"dynamic_host_scheme"`
+}
+
+// HostPatternConfig routes every hostname ending in Suffix or matching Regex to the
+// inline route. Its context, namespace, service and pod may reference the regex's
+// capture groups ($1, ${name}); a suffix pattern captures the rest of the hostname
+// as ${prefix}.
+type HostPatternConfig struct {
+	Suffix         string `yaml:"suffix,omitempty"`
+	Regex          string `yaml:"regex,omitempty"`
+	K8sRouteConfig `yaml:",inline"`
 }
 
 type K8sRouteConfig struct {
-	Context   string `yaml:"context"`
-	Namespace string `yaml:"namespace"`
-	Service   string `yaml:"service"` // Target service name (mutually exclusive with Pod)
-	Pod       string `yaml:"pod"`     // Target pod name directly (mutually exclusive with Service)
-	Port      int    `yaml:"port"`
-	Scheme    string `yaml:"scheme"` // "http" or "https" - controls X-Forwarded-Proto header (default: http)
+	Context   string            `yaml:"context"`
+	ExecEnv   map[string]string `yaml:"exec_env,omitempty"` // Added to the environment of the context's exec credential plugin, e.g. AWS_PROFILE
+	Namespace string            `yaml:"namespace"`
+	Service   string            `yaml:"service,omitempty"` // Target service name (mutually exclusive with Pod)
+	Pod       string            `yaml:"pod,omitempty"`     // Target pod name directly (mutually exclusive with Service)
+	Port      int               `yaml:"port"`
+	Scheme    string            `yaml:"scheme,omitempty"`   // "http" or "https" - controls X-Forwarded-Proto header (default: http)
+	TLS       string            `yaml:"tls,omitempty"`      // "passthrough" (default) or "terminate" - how TLS clients are handled
+	Mode      string            `yaml:"mode,omitempty"`     // "http" (default) or "tcp": splice plaintext connections too, without HTTP handling
+	Protocol  string            `yaml:"protocol,omitempty"` // "grpc": health checked with the gRPC health protocol
+
+	// ALPNPorts sends TLS passthrough connections to another backend port based on the
+	// client's ALPN protocols, e.g. {"h2": 8443} for gRPC. Unlisted protocols use Port.
+	ALPNPorts map[string]int `yaml:"alpn_ports,omitempty"`
+
+	// SchemeRoutes sends plain HTTP requests ("http") or TLS passthrough connections
+	// ("https") for the hostname to another backend than the route's own. Each gets
+	// its own tunnel, tracked as "hostname:scheme".
+	SchemeRoutes map[string]SchemeRouteConfig `yaml:"scheme_routes,omitempty"`
+
+	// LocalPort makes the route's tunnel forward from this local port instead of
+	// one the OS picks, for tools that connect to the backend directly
+	LocalPort int `yaml:"local_port,omitempty"`
+
+	// ExtraPorts are forwarded over the same port-forward session as Port.
+	// Set from tcp.k8s.routes[].extra_ports; not configurable on HTTP routes.
+	ExtraPorts []int `yaml:"-"`
+
+	PinPod string `yaml:"pin_pod,omitempty"` // Keep service routes on this pod, or "auto" for the first one selected
+
+	// ReadyTimeout bounds how long a tunnel start may take (0 = top-level ready_timeout)
+	ReadyTimeout time.Duration `yaml:"ready_timeout,omitempty"`
+
+	// Standby keeps this many extra port-forwards open next to the route's tunnel;
+	// connections rotate through them so one broken stream doesn't stop traffic
+	Standby int `yaml:"standby,omitempty"`
+
+	// HealthCheck periodically requests a path through the route's tunnel while it
+	// is up, so a broken service shows up even though the tunnel itself is fine
+	HealthCheck *HealthCheckConfig `yaml:"health_check,omitempty"`
+
+	// WarmupSchedule starts the route's tunnel ahead of use on a cron schedule in
+	// local time, e.g. "0 9 * * MON-FRI"; it still closes once idle
+	WarmupSchedule string `yaml:"warmup_schedule,omitempty"`
+
+	// UpstreamSNI is the server name sent to the backend for TLS passthrough
+	// connections instead of the client's, for backends that only answer to their
+	// cluster-internal name. TLS is then terminated and re-originated.
+	UpstreamSNI string `yaml:"upstream_sni,omitempty"`
+
+	// UpstreamTLS verifies the certificate of a scheme: https backend, which is
+	// otherwise accepted as is
+	UpstreamTLS *UpstreamTLSConfig `yaml:"upstream_tls,omitempty"`
+
+	// Authorizers replace the top-level authorizers for this route
+	Authorizers []AuthorizerConfig `yaml:"authorizers,omitempty"`
+
+	// Keepalive is the top-level keepalive setting, copied in by the tunnel manager
+	Keepalive time.Duration `yaml:"-"`
+}
+
+// HealthCheckConfig is a synthetic HTTP check of an HTTP route's backend
+type HealthCheckConfig struct {
+	Path           string        `yaml:"path"`                      // e.g. "/healthz"
+	ExpectedStatus int           `yaml:"expected_status,omitempty"` // default 200
+	Interval       time.Duration `yaml:"interval,omitempty"`        // default 30s
+	Timeout        time.Duration `yaml:"timeout,omitempty"`         // default 5s
+	Service        string        `yaml:"service,omitempty"`         // protocol: grpc only, service to ask about (default: the whole server)
+}
+
+// UpstreamTLSConfig is how an https backend's certificate is verified. The tunnel
+// connects to 127.0.0.1, so requests are addressed to ServerName and that name is
+// resolved to the tunnel internally: SNI and the CN/SAN check both use it.
+type UpstreamTLSConfig struct {
+	ServerName string `yaml:"server_name,omitempty"` // default: <service>.<namespace>.svc
+	CAFile     string `yaml:"ca_file,omitempty"`     // PEM bundle to verify against (default: system roots)
+}
+
+const (
+	DefaultHealthCheckInterval = 30 * time.Second
+	DefaultHealthCheckTimeout  = 5 * time.Second
+)
+
+// GetExpectedStatus returns the status a healthy backend answers with, defaulting to 200
+func (h *HealthCheckConfig) GetExpectedStatus() int {
+	if h.ExpectedStatus == 0 {
+		return 200
+	}
+	return h.ExpectedStatus
+}
+
+// GetInterval returns the time between checks, defaulting to 30s
+func (h *HealthCheckConfig) GetInterval() time.Duration {
+	if h.Interval == 0 {
+		return DefaultHealthCheckInterval
+	}
+	return h.Interval
+}
+
+// GetTimeout returns how long one check may take, defaulting to 5s
+func (h *HealthCheckConfig) GetTimeout() time.Duration {
+	if h.Timeout == 0 {
+		return DefaultHealthCheckTimeout
+	}
+	return h.Timeout
+}
+
+// MaxStandby caps standby port-forwards per route
+const MaxStandby = 4
+
+// PinPodAuto pins a service route to the first pod a tunnel selects
+const PinPodAuto = "auto"
+
+const (
+	TLSModePassthrough = "passthrough"
+	TLSModeTerminate   = "terminate"
+)
+
+const (
+	RouteModeHTTP = "http"
+	RouteModeTCP  = "tcp"
+)
+
+// ProtocolGRPC marks a route whose backend serves gRPC
+const ProtocolGRPC = "grpc"
+
+// GRPC reports whether the route's backend is checked as a gRPC server
+func (r K8sRouteConfig) GRPC() bool {
+	return r.Protocol == ProtocolGRPC
+}
+
+// RawTCP reports whether connections for the route are spliced to the backend
+// as bytes, plaintext included, instead of going through the reverse proxy
+func (r K8sRouteConfig) RawTCP() bool {
+	return r.Mode == RouteModeTCP
+}
+
+// SchemeRouteConfig overrides a route's backend for one client scheme. Empty fields
+// keep the route's values; setting service or pod replaces both.
+type SchemeRouteConfig struct {
+	Service string `yaml:"service,omitempty"`
+	Pod     string `yaml:"pod,omitempty"`
+	Port    int    `yaml:"port,omitempty"`
+	Scheme  string `yaml:"scheme,omitempty"` // Backend scheme for "http" requests (default: the route's)
+}
+
+// ForScheme returns the route with scheme_routes[scheme] applied, and whether the
+// route has an entry for scheme ("http" or "https", the way the client connected)
+func (r K8sRouteConfig) ForScheme(scheme string) (K8sRouteConfig, bool) {
+	override, ok := r.SchemeRoutes[scheme]
+	if !ok {
+		return r, false
+	}
+	if override.Service != "" || override.Pod != "" {
+		r.Service, r.Pod = override.Service, override.Pod
+	}
+	if override.Port != 0 {
+		r.Port = override.Port
+	}
+	if override.Scheme != "" {
+		r.Scheme = override.Scheme
+	}
+	r.SchemeRoutes = nil
+	r.LocalPort = 0 // belongs to the route's own tunnel
+	return r, true
+}
+
+// TargetPorts returns Port followed by ExtraPorts, in forwarding order
+func (r K8sRouteConfig) TargetPorts() []int {
+	return append([]int{r.Port}, r.ExtraPorts...)
+}
+
+// TerminatesTLS reports whether TLS clients are terminated locally with the dev CA
+// (and re-encrypted when Scheme is https) instead of passed through to the backend
+func (r K8sRouteConfig) TerminatesTLS() bool {
+	return r.TLS == TLSModeTerminate
 }
 
 // TargetName returns a display name for the target (pod or service)
@@ -40,9 +304,160 @@ func (r K8sRouteConfig) TargetDisplay() string {
 	return r.Service
 }
 
+// DefaultTCPListenHost is where TCP routes listen unless tcp.listen_host says otherwise
+const DefaultTCPListenHost = "127.0.0.1"
+
+// GetListenHost returns the loopback IP TCP routes listen on
+func (c TCPConfig) GetListenHost() string {
+	if c.ListenHost == "" {
+		return DefaultTCPListenHost
+	}
+	return c.ListenHost
+}
+
+// ServerRetryConfig is how often and how fast autotunnel restarts its listeners after
+// a server error (bind failure after sleep/wake, EADDRINUSE during a fast reload)
+type ServerRetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"` // restarts in a row before exiting (0 = exit on the first error)
+	Backoff     time.Duration `yaml:"backoff"`      // delay before the first restart, doubled per attempt up to 30s
+}
+
+const (
+	DefaultServerRetryAttempts = 5
+	DefaultServerRetryBackoff  = time.Second
+	maxServerRetryBackoff      = 30 * time.Second
+)
+
+// Delay returns how long to wait before restart attempt n (1-based)
+func (r ServerRetryConfig) Delay(n int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < n && delay < maxServerRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxServerRetryBackoff)
+}
+
+// PrewarmConfig resolves the clients of the routes' contexts in the background at
+// startup, so slow exec credential plugins run before the first connection instead
+// of during it. A context that fails or times out is logged and left to the first
+// tunnel that needs it.
+type PrewarmConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Timeout     time.Duration `yaml:"timeout"`     // per context (0 = 15s)
+	Parallelism int           `yaml:"parallelism"` // contexts resolved at once (0 = 4)
+}
+
+const (
+	DefaultPrewarmTimeout     = 15 * time.Second
+	DefaultPrewarmParallelism = 4
+)
+
+// GetTimeout returns how long one context may take, defaulting to 15s
+func (p PrewarmConfig) GetTimeout() time.Duration {
+	if p.Timeout == 0 {
+		return DefaultPrewarmTimeout
+	}
+	return p.Timeout
+}
+
+// GetParallelism returns how many contexts are resolved at once, defaulting to 4
+func (p PrewarmConfig) GetParallelism() int {
+	if p.Parallelism == 0 {
+		return DefaultPrewarmParallelism
+	}
+	return p.Parallelism
+}
+
+// IdleNotifyConfig warns shortly before a watched route's tunnel is stopped for
+// being idle, so it can be kept warm (see `autotunnel keep-warm`). Routes kept
+// warm are always watched, for when their extra time runs out.
+type IdleNotifyConfig struct {
+	Before  time.Duration `yaml:"before"`  // how long ahead to warn (0 = off)
+	Webhook string        `yaml:"webhook"` // also POST the warning here as JSON ("" = log only)
+	Routes  []string      `yaml:"routes"`  // hostnames, "tcp:<port>", "group:<name>" or "pg:<port>"
+}
+
+// Enabled reports whether idle warnings are sent
+func (n IdleNotifyConfig) Enabled() bool {
+	return n.Before > 0
+}
+
+// LogConfig selects where log lines go. Service-managed installs can send them to
+// the system log instead of stderr.
+type LogConfig struct {
+	Output     string `yaml:"output"`      // "stderr" (default), "syslog" or "journald"
+	SyslogAddr string `yaml:"syslog_addr"` // syslog only: "udp://host:514" or "tcp://host:514" (default: local syslog)
+	Tag        string `yaml:"tag"`         // syslog tag / journald SYSLOG_IDENTIFIER (default: "autotunnel")
+}
+
+// Log outputs
+const (
+	LogOutputStderr   = "stderr"
+	LogOutputSyslog   = "syslog"
+	LogOutputJournald = "journald"
+)
+
+// DefaultLogTag identifies autotunnel's lines in the system log
+const DefaultLogTag = "autotunnel"
+
+// GetTag returns tag, or DefaultLogTag when unset
+func (l LogConfig) GetTag() string {
+	if l.Tag == "" {
+		return DefaultLogTag
+	}
+	return l.Tag
+}
+
 type TCPConfig struct {
-	IdleTimeout time.Duration `yaml:"idle_timeout"`
-	K8s         TCPK8sConfig  `yaml:"k8s"`
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`
+	AutoRemapPorts   bool          `yaml:"auto_remap_ports"`   // Move a busy local port to the next free one instead of failing
+	AllowedPortRange string        `yaml:"allowed_port_range"` // e.g. "10000-19999"; routes may only bind local ports inside it
+	PrometheusFileSD string        `yaml:"prometheus_file_sd"` // Write bound route/group ports as a Prometheus file_sd JSON file here
+	ListenHost       string        `yaml:"listen_host"`        // Loopback IP TCP routes listen on: "127.0.0.1" (default) or "::1"
+	EchoPort         int           `yaml:"echo_port"`          // Local port echoing back whatever it receives (0 = disabled)
+	K8s              TCPK8sConfig  `yaml:"k8s"`
+}
+
+// PortRange is an inclusive range of local ports
+type PortRange struct {
+	Min int
+	Max int
+}
+
+// ParsePortRange parses "min-max" (inclusive), e.g. "10000-19999"
+func ParsePortRange(s string) (PortRange, error) {
+	lo, hi, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return PortRange{}, fmt.Errorf("port range %q must look like \"min-max\"", s)
+	}
+	minPort, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	maxPort, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil {
+		return PortRange{}, fmt.Errorf("port range %q must look like \"min-max\"", s)
+	}
+	if minPort < 1 || maxPort > 65535 || minPort > maxPort {
+		return PortRange{}, fmt.Errorf("port range %q must be within 1-65535 with min <= max", s)
+	}
+	return PortRange{Min: minPort, Max: maxPort}, nil
+}
+
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Min && port <= r.Max
+}
+
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// PortRange returns the allowed local port range, or 1-65535 when unrestricted.
+// Invalid values are rejected by Validate, so they're treated as unrestricted here.
+func (c TCPConfig) PortRange() PortRange {
+	if c.AllowedPortRange != "" {
+		if r, err := ParsePortRange(c.AllowedPortRange); err == nil {
+			return r
+		}
+	}
+	return PortRange{Min: 1, Max: 65535}
 }
 
 type TCPK8sConfig struct {
@@ -50,15 +465,179 @@ type TCPK8sConfig struct {
 	ResolvedKubeconfigs []string                 `yaml:"-"` // Computed: resolved paths (not from YAML)
 	Routes              map[int]TCPRouteConfig   `yaml:"routes"` // local port -> direct port-forward route
 	Jump                map[int]JumpRouteConfig  `yaml:"jump"`   // local port -> jump-host route via exec+socat/nc
+
+	// Groups forward every port a service declares, keyed by group name
+	Groups map[string]GroupRouteConfig `yaml:"groups"`
+
+	// Discover watches clusters for labeled Services and adds a TCP route for each
+	Discover []TCPDiscoverConfig `yaml:"discover"`
+
+	// Postgres routes connections on a local port by the database they ask for
+	Postgres map[int]PostgresRouteConfig `yaml:"postgres"`
+}
+
+// Labels and annotations read from Services by tcp.k8s.discover
+const (
+	DiscoverPortLabel           = "autotunnel.atas.dev/tcp-port"   // service port to forward
+	DiscoverLocalPortAnnotation = "autotunnel.atas.dev/local-port" // local port (default: the service port)
+)
+
+// TCPDiscoverConfig watches one context for Services labeled autotunnel.atas.dev/tcp-port
+// and forwards each on a local port taken from its annotations. The routes come and
+// go with the Services; the tunnels are still on demand.
+type TCPDiscoverConfig struct {
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace,omitempty"` // empty watches every namespace
+	Method    string `yaml:"method,omitempty"`    // same as tcp.k8s.routes[].method
+}
+
+// GroupRouteConfig forwards all of a service's declared ports over one port-forward
+// session, like `kubectl port-forward svc/name` with every port listed. The service's
+// ports are looked up when the TCP server starts; the tunnel is still on demand.
+type GroupRouteConfig struct {
+	Context    string            `yaml:"context"`
+	ExecEnv    map[string]string `yaml:"exec_env,omitempty"` // Added to the environment of the context's exec credential plugin, e.g. AWS_PROFILE
+	Namespace  string            `yaml:"namespace"`
+	Service    string            `yaml:"service"`
+	Ports      map[int]int       `yaml:"ports,omitempty"`       // service port -> local port; unlisted ports are auto-assigned
+	PortOffset int               `yaml:"port_offset,omitempty"` // auto-assigned local port = service port + offset (next free port if taken)
+	Method     string            `yaml:"method,omitempty"`      // same as tcp.k8s.routes[].method
+	PinPod     string            `yaml:"pin_pod,omitempty"`     // same as http.k8s.routes[].pin_pod
+
+	ReadyTimeout time.Duration `yaml:"ready_timeout,omitempty"` // same as http.k8s.routes[].ready_timeout
+}
+
+// TCPRoute returns the group as a TCP route to its service (without ports)
+func (g GroupRouteConfig) TCPRoute() TCPRouteConfig {
+	return TCPRouteConfig{Context: g.Context, Namespace: g.Namespace, Service: g.Service, Method: g.Method}
+}
+
+// ToK8sRouteConfig converts to K8sRouteConfig for shared tunnel code. ports are the
+// service ports to forward; the first is the route's Port, the rest ExtraPorts.
+func (g GroupRouteConfig) ToK8sRouteConfig(ports []int) K8sRouteConfig {
+	route := K8sRouteConfig{
+		Context:      g.Context,
+		Namespace:    g.Namespace,
+		Service:      g.Service,
+		Scheme:       "tcp",
+		PinPod:       g.PinPod,
+		ReadyTimeout: g.ReadyTimeout,
+	}
+	if len(ports) > 0 {
+		route.Port = ports[0]
+		route.ExtraPorts = ports[1:]
+	}
+	return route
+}
+
+// PostgresRouteConfig sends PostgreSQL connections on one local port to the service
+// of the namespace named by the database the client asks for, so `psql -d preview-42`
+// reaches preview-42's database. "<namespace>/<database>" also picks the database.
+type PostgresRouteConfig struct {
+	Context         string            `yaml:"context"`
+	ExecEnv         map[string]string `yaml:"exec_env,omitempty"`         // Added to the environment of the context's exec credential plugin, e.g. AWS_PROFILE
+	Service         string            `yaml:"service"`                    // same service name in every namespace
+	Port            int               `yaml:"port"`                       // target port on the service
+	NamespacePrefix string            `yaml:"namespace_prefix,omitempty"` // namespace = prefix + requested name
+	Database        string            `yaml:"database,omitempty"`         // database to open when only a namespace is given (default: the same name)
+
+	ReadyTimeout time.Duration `yaml:"ready_timeout,omitempty"` // same as http.k8s.routes[].ready_timeout
+}
+
+// Resolve returns the TCP route for a client asking for database, and the database
+// to ask the server for instead
+func (r PostgresRouteConfig) Resolve(database string) (TCPRouteConfig, string, error) {
+	name, db, hasDB := strings.Cut(database, "/")
+	namespace := r.NamespacePrefix + name
+	if name == "" || !IsValidNamespace(namespace) {
+		return TCPRouteConfig{}, "", fmt.Errorf("database %q doesn't name a namespace (%q)", database, namespace)
+	}
+	switch {
+	case hasDB && db == "":
+		return TCPRouteConfig{}, "", fmt.Errorf("database %q has no database name after /", database)
+	case !hasDB && r.Database != "":
+		db = r.Database
+	case !hasDB:
+		db = name
+	}
+	route := TCPRouteConfig{
+		Context:      r.Context,
+		Namespace:    namespace,
+		Service:      r.Service,
+		Port:         r.Port,
+		ReadyTimeout: r.ReadyTimeout,
+	}
+	return route, db, nil
 }
 
 // TCPRouteConfig defines a single TCP route (simpler than K8sRouteConfig - no Scheme field)
 type TCPRouteConfig struct {
-	Context   string `yaml:"context"`
-	Namespace string `yaml:"namespace"`
-	Service   string `yaml:"service"` // Target service name (mutually exclusive with Pod)
-	Pod       string `yaml:"pod"`     // Target pod name directly (mutually exclusive with Service)
-	Port      int    `yaml:"port"`    // Target port on the service/pod
+	Context   string            `yaml:"context"`
+	ExecEnv   map[string]string `yaml:"exec_env,omitempty"` // Added to the environment of the context's exec credential plugin, e.g. AWS_PROFILE
+	Namespace string            `yaml:"namespace"`
+	Service   string            `yaml:"service,omitempty"` // Target service name (mutually exclusive with Pod)
+	Pod       string            `yaml:"pod,omitempty"`     // Target pod name directly (mutually exclusive with Service)
+	Port      int               `yaml:"port"`              // Target port on the service/pod
+
+	// ExtraPorts maps additional local ports to target ports on the same service/pod,
+	// e.g. {5005: 5005} for a debugger next to the app port. They share one port-forward
+	// session with Port instead of opening their own.
+	ExtraPorts map[int]int `yaml:"extra_ports,omitempty"`
+
+	Method string `yaml:"method,omitempty"`  // "" (port-forward, exec when forbidden), "portforward" or "exec"
+	PinPod string `yaml:"pin_pod,omitempty"` // same as http.k8s.routes[].pin_pod
+
+	ReadyTimeout time.Duration `yaml:"ready_timeout,omitempty"` // same as http.k8s.routes[].ready_timeout
+	Standby      int           `yaml:"standby,omitempty"`       // same as http.k8s.routes[].standby
+
+	WarmupSchedule string `yaml:"warmup_schedule,omitempty"` // same as http.k8s.routes[].warmup_schedule
+
+	// Per-connection limits, so a hung backend can't hold local sockets forever
+	ConnectTimeout  time.Duration `yaml:"connect_timeout,omitempty"`   // reaching the tunnel (default 10s)
+	ConnIdleTimeout time.Duration `yaml:"conn_idle_timeout,omitempty"` // close after no data either way for this long (default: never)
+	ConnMaxDuration time.Duration `yaml:"conn_max_duration,omitempty"` // close connections open this long (default: never)
+
+	SocketConfig `yaml:",inline"`
+
+	Authorizers []AuthorizerConfig `yaml:"authorizers,omitempty"` // same as http.k8s.routes[].authorizers
+}
+
+// SocketConfig tunes the client connections accepted on a TCP or jump route's port
+type SocketConfig struct {
+	ConnKeepalive time.Duration  `yaml:"conn_keepalive,omitempty"` // TCP keepalive period (default: Go's 15s, negative disables)
+	ConnLinger    *time.Duration `yaml:"conn_linger,omitempty"`    // SO_LINGER in whole seconds, 0 resets on close (default: the OS's)
+}
+
+// DefaultTCPConnectTimeout bounds reaching a TCP route's tunnel when connect_timeout is unset
+const DefaultTCPConnectTimeout = 10 * time.Second
+
+// GetConnectTimeout returns how long connecting to the tunnel may take, defaulting to 10s
+func (r TCPRouteConfig) GetConnectTimeout() time.Duration {
+	if r.ConnectTimeout == 0 {
+		return DefaultTCPConnectTimeout
+	}
+	return r.ConnectTimeout
+}
+
+const (
+	TCPMethodPortForward = "portforward" // port-forward only, never fall back
+	TCPMethodExec        = "exec"        // always kubectl exec + socat/nc into the target pod
+)
+
+// ExecRoute returns the jump route that reaches targetPort through kubectl exec +
+// socat/nc in the route's own pod. It is used when pods/portforward is forbidden
+// but pods/exec is allowed. Pod routes connect to 127.0.0.1 inside the pod; service
+// routes exec into one of the service's pods and connect to the service's DNS name.
+func (r TCPRouteConfig) ExecRoute(targetPort int) JumpRouteConfig {
+	route := JumpRouteConfig{Context: r.Context, Namespace: r.Namespace}
+	if r.Pod != "" {
+		route.Via.Pod = r.Pod
+		route.Target = TargetConfig{Host: "127.0.0.1", Port: targetPort}
+	} else {
+		route.Via.Service = r.Service
+		route.Target = TargetConfig{Host: fmt.Sprintf("%s.%s.svc", r.Service, r.Namespace), Port: targetPort}
+	}
+	return route
 }
 
 // TargetName returns a display name for the target (service preferred over pod)
@@ -80,24 +659,97 @@ func (r TCPRouteConfig) TargetDisplay() string {
 // ToK8sRouteConfig converts to K8sRouteConfig for shared tunnel code
 func (r TCPRouteConfig) ToK8sRouteConfig() K8sRouteConfig {
 	return K8sRouteConfig{
-		Context:   r.Context,
-		Namespace: r.Namespace,
-		Service:   r.Service,
-		Pod:       r.Pod,
-		Port:      r.Port,
-		Scheme:    "tcp",
+		Context:      r.Context,
+		Namespace:    r.Namespace,
+		Service:      r.Service,
+		Pod:          r.Pod,
+		Port:         r.Port,
+		Scheme:       "tcp",
+		ExtraPorts:   r.extraTargetPorts(),
+		PinPod:       r.PinPod,
+		ReadyTimeout: r.ReadyTimeout,
+		Standby:      r.Standby,
 	}
 }
 
+// extraTargetPorts returns the ExtraPorts target ports sorted by local port
+func (r TCPRouteConfig) extraTargetPorts() []int {
+	if len(r.ExtraPorts) == 0 {
+		return nil
+	}
+	localPorts := make([]int, 0, len(r.ExtraPorts))
+	for local := range r.ExtraPorts {
+		localPorts = append(localPorts, local)
+	}
+	sort.Ints(localPorts)
+	targets := make([]int, len(localPorts))
+	for i, local := range localPorts {
+		targets[i] = r.ExtraPorts[local]
+	}
+	return targets
+}
+
+// SharedPort describes a local port served by another route's port-forward session
+type SharedPort struct {
+	RoutePort  int // local port of the route that owns the session
+	TargetPort int // target port on the service/pod
+}
+
+// SharedPorts returns every tcp extra_ports local port keyed to the route that owns it
+func (k TCPK8sConfig) SharedPorts() map[int]SharedPort {
+	shared := make(map[int]SharedPort)
+	for routePort, route := range k.Routes {
+		for local, target := range route.ExtraPorts {
+			shared[local] = SharedPort{RoutePort: routePort, TargetPort: target}
+		}
+	}
+	return shared
+}
+
 
 // JumpRouteConfig defines a jump-host route via kubectl exec + socat/nc
 // This allows connecting to VPC-internal services (RDS, Cloud SQL, etc.) through a jump pod
 type JumpRouteConfig struct {
-	Context   string       `yaml:"context"`         // K8s context name
-	Namespace string       `yaml:"namespace"`       // K8s namespace
-	Via       ViaConfig    `yaml:"via"`             // Jump pod configuration
-	Target    TargetConfig `yaml:"target"`          // External target (e.g., RDS hostname)
-	Method    string       `yaml:"method,omitempty"` // "socat" (default) or future alternatives
+	Context   string            `yaml:"context"`            // K8s context name
+	ExecEnv   map[string]string `yaml:"exec_env,omitempty"` // Added to the environment of the context's exec credential plugin, e.g. AWS_PROFILE
+	Namespace string            `yaml:"namespace"`          // K8s namespace
+	Via       ViaConfig         `yaml:"via"`                // Jump pod configuration
+	Target    TargetConfig      `yaml:"target"`             // External target (e.g., RDS hostname)
+	Method    string            `yaml:"method,omitempty"`   // "socat" (default) or future alternatives
+
+	// Pool keeps this many exec streams open to the target ahead of connections, so
+	// clients opening a connection per query skip the exec setup. 0 (default) opens
+	// one per connection.
+	Pool       int           `yaml:"pool,omitempty"`
+	PoolMaxAge time.Duration `yaml:"pool_max_age,omitempty"` // unclaimed streams are closed after this (default: 5s)
+
+	// DNSCacheTTL resolves target hostnames from inside the jump pod and reuses
+	// the address for this long, instead of a lookup on every connection. 0 (default)
+	// leaves resolving to socat/nc.
+	DNSCacheTTL time.Duration `yaml:"dns_cache_ttl,omitempty"`
+
+	SocketConfig `yaml:",inline"`
+
+	Authorizers []AuthorizerConfig `yaml:"authorizers,omitempty"` // same as http.k8s.routes[].authorizers
+
+	// Fallbacks are tried in order when Target can't be reached. Set by writing
+	// target: as a list; see target.go.
+	Fallbacks []TargetConfig `yaml:"-"`
+}
+
+// DefaultJumpPoolMaxAge stays under the time databases give a new connection to
+// authenticate (MySQL's connect_timeout is 10s)
+const DefaultJumpPoolMaxAge = 5 * time.Second
+
+// MaxJumpPool caps pool, each stream being an exec session and a target connection
+const MaxJumpPool = 16
+
+// GetPoolMaxAge returns pool_max_age, or DefaultJumpPoolMaxAge when unset
+func (r *JumpRouteConfig) GetPoolMaxAge() time.Duration {
+	if r.PoolMaxAge == 0 {
+		return DefaultJumpPoolMaxAge
+	}
+	return r.PoolMaxAge
 }
 
 // GetMethod returns the forwarding method, defaulting to "socat" if not specified
@@ -128,9 +780,111 @@ type CreateConfig struct {
 	Image   string        `yaml:"image"`             // Required: container image (e.g., "alpine:3.19")
 	Command []string      `yaml:"command,omitempty"` // Optional: pod command (default: ["sleep", "infinity"])
 	Timeout time.Duration `yaml:"timeout,omitempty"` // Optional: pod readiness timeout (default: 60s)
+
+	// Scheduling and security, e.g. to land on nodes with a route to the target or to
+	// pass PodSecurity admission. All optional; the structured ones take the same
+	// fields as in a pod manifest.
+	NodeSelector             map[string]string                      `yaml:"node_selector,omitempty"`
+	Tolerations              *KubeObject[[]corev1.Toleration]       `yaml:"tolerations,omitempty"`
+	Affinity                 *KubeObject[corev1.Affinity]           `yaml:"affinity,omitempty"`
+	ServiceAccountName       string                                 `yaml:"service_account_name,omitempty"`
+	SecurityContext          *KubeObject[corev1.PodSecurityContext] `yaml:"security_context,omitempty"`           // pod level
+	ContainerSecurityContext *KubeObject[corev1.SecurityContext]    `yaml:"container_security_context,omitempty"` // the jump container
+
+	// Private registries and quotas. Resources entries replace the default
+	// request/limit of the same resource (cpu 10m/100m, memory 16Mi/64Mi).
+	ImagePullSecrets []string                                 `yaml:"image_pull_secrets,omitempty"` // secret names in the route's namespace
+	Resources        *KubeObject[corev1.ResourceRequirements] `yaml:"resources,omitempty"`
+	Env              *KubeObject[[]corev1.EnvVar]             `yaml:"env,omitempty"`
+	Annotations      map[string]string                        `yaml:"annotations,omitempty"`
 }
 
 type TargetConfig struct {
 	Host string `yaml:"host"` // Target hostname (e.g., mydb.cluster-xyz.us-east-1.rds.amazonaws.com)
 	Port int    `yaml:"port"` // Target port
+
+	// TLS makes socat in the jump pod open a TLS connection to the target, so the
+	// certificate is checked against the pod's CA bundle (or CAFile) instead of the
+	// laptop's. The client then speaks plaintext to the local port.
+	TLS                bool   `yaml:"tls,omitempty"`
+	SNI                string `yaml:"sni,omitempty"`                  // server name to send and verify (default: host)
+	CAFile             string `yaml:"ca_file,omitempty"`              // CA bundle path inside the jump pod
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"` // don't verify the certificate
+}
+
+// TeamConfig turns autotunnel into a shared team server: it runs on a host that holds
+// the cluster credentials, and every HTTP request must carry a user's token.
+// Team mode is enabled when at least one user is configured.
+type TeamConfig struct {
+	Users map[string]TeamUserConfig `yaml:"users"` // user name -> credentials and allowed routes
+}
+
+// Enabled reports whether clients must authenticate
+func (t TeamConfig) Enabled() bool {
+	return len(t.Users) > 0
+}
+
+type TeamUserConfig struct {
+	Token    string   `yaml:"token,omitempty"`     // Shared secret (mutually exclusive with TokenEnv)
+	TokenEnv string   `yaml:"token_env,omitempty"` // Environment variable holding the token
+	Routes   []string `yaml:"routes,omitempty"`    // Hostname patterns this user may reach, e.g. "*.staging.localhost" (empty = all)
+	Admin    bool     `yaml:"admin,omitempty"`     // May change things through the status API (restart, pin, reload...) and read /debug
+}
+
+// ResolvedToken returns the token, reading TokenEnv if set
+func (u TeamUserConfig) ResolvedToken() string {
+	if u.TokenEnv != "" {
+		return os.Getenv(u.TokenEnv)
+	}
+	return u.Token
+}
+
+// AllowsHost reports whether hostname matches one of the user's route patterns
+func (u TeamUserConfig) AllowsHost(hostname string) bool {
+	if len(u.Routes) == 0 {
+		return true
+	}
+	hostname = strings.ToLower(hostname)
+	for _, pattern := range u.Routes {
+		if ok, _ := path.Match(strings.ToLower(pattern), hostname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorizer types
+const (
+	AuthorizerToken      = "token"       // a secret sent with HTTP requests, like team tokens
+	AuthorizerOSUser     = "os_user"     // the local user owning the client socket
+	AuthorizerClientCert = "client_cert" // a client certificate on tls: terminate routes
+	AuthorizerCommand    = "command"     // an external program decides
+)
+
+// DefaultAuthorizerTimeout bounds how long a command authorizer may run
+const DefaultAuthorizerTimeout = 5 * time.Second
+
+// AuthorizerConfig is one way a connection can prove it may use a route. A
+// connection is let through when any of the route's authorizers allows it.
+type AuthorizerConfig struct {
+	Type string `yaml:"type"`
+
+	Tokens  map[string]string `yaml:"tokens,omitempty"`  // token: user name -> token
+	Users   []string          `yaml:"users,omitempty"`   // os_user: allowed local user names
+	CAFile  string            `yaml:"ca_file,omitempty"` // client_cert: CA bundle client certificates must chain to
+	Names   []string          `yaml:"names,omitempty"`   // client_cert: allowed common names / DNS names (empty = any)
+	Command []string          `yaml:"command,omitempty"` // command: argv, exit status 0 allows
+	Timeout time.Duration     `yaml:"timeout,omitempty"` // command: default 5s
+
+	// command: how long a decision is reused for the same route, client IP, token
+	// and client certificate (0 = run the command for every request)
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+}
+
+// GetTimeout returns timeout, or DefaultAuthorizerTimeout when unset
+func (a AuthorizerConfig) GetTimeout() time.Duration {
+	if a.Timeout == 0 {
+		return DefaultAuthorizerTimeout
+	}
+	return a.Timeout
 }
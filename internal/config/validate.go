@@ -3,9 +3,16 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/cron"
 )
 
 // hostnameRegex matches valid DNS hostnames (RFC 1123)
@@ -35,6 +42,22 @@ func IsValidTargetHost(host string) bool {
 	return hostnameRegex.MatchString(host)
 }
 
+// podNameRegex matches Kubernetes object names (RFC 1123 subdomain)
+var podNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-\.]*[a-z0-9])?$`)
+
+// IsValidPodName checks if name is a valid Kubernetes pod name
+func IsValidPodName(name string) bool {
+	return len(name) <= 253 && podNameRegex.MatchString(name)
+}
+
+// namespaceRegex matches Kubernetes namespace names (RFC 1123 label: no dots)
+var namespaceRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-]*[a-z0-9])?$`)
+
+// IsValidNamespace checks if name is a valid Kubernetes namespace name
+func IsValidNamespace(name string) bool {
+	return len(name) <= 63 && namespaceRegex.MatchString(name)
+}
+
 // IsValidImageName checks if an image name is safe for use.
 // Rejects shell metacharacters to prevent command injection.
 func IsValidImageName(image string) bool {
@@ -68,10 +91,104 @@ func validateLocalPort(routeID string, localPort, httpPort int, seenPorts map[in
 }
 
 // validateRouteBase validates the common fields shared by HTTP and TCP routes
-func validateRouteBase(routeID, context, namespace, service, pod string, port int) error {
+// validateContext checks a route's context: a name from the kubeconfig, or
+// path#context for the context in that kubeconfig file only
+func validateContext(routeID, context string) error {
 	if context == "" {
 		return fmt.Errorf("%s: context is required", routeID)
 	}
+	if i := strings.LastIndex(context, "#"); i >= 0 && (i == 0 || i == len(context)-1) {
+		return fmt.Errorf("%s: context %q must be a name or path#name", routeID, context)
+	}
+	return nil
+}
+
+// validateContextAliases checks the contexts: map. An alias names a kubeconfig
+// context directly; aliases of aliases would make renames harder to follow.
+func (c *Config) validateContextAliases() error {
+	for alias, entry := range c.Contexts {
+		aliasID := fmt.Sprintf("contexts[%s]", alias)
+		if alias == "" || strings.Contains(alias, "#") {
+			return fmt.Errorf("%s: invalid alias name", aliasID)
+		}
+		if entry.Context == "" && !entry.HasClientSettings() {
+			return fmt.Errorf("%s: context is required", aliasID)
+		}
+		target := entry.Target(alias)
+		if err := validateContext(aliasID, target); err != nil {
+			return err
+		}
+		if _, ok := c.Contexts[target]; ok && target != alias {
+			return fmt.Errorf("%s: %q is an alias itself, point it at a kubeconfig context", aliasID, target)
+		}
+		if err := validateEnvNames(aliasID, entry.Env); err != nil {
+			return err
+		}
+		if err := validateProxy(aliasID, entry.Proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateProxy checks a context's proxy: "", "direct" or a proxy URL
+func validateProxy(id, proxy string) error {
+	if proxy == "" || proxy == ProxyDirect {
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%s: proxy %q must be a URL like http://proxy:3128, or %q", id, proxy, ProxyDirect)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	}
+	return fmt.Errorf("%s: proxy scheme must be http, https or socks5, got %q", id, u.Scheme)
+}
+
+// validateIdleNotify checks idle_notify
+func (c *Config) validateIdleNotify() error {
+	n := c.IdleNotify
+	if n.Before < 0 {
+		return fmt.Errorf("idle_notify.before must not be negative")
+	}
+	if n.Webhook != "" {
+		u, err := url.Parse(n.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("idle_notify.webhook must be an http or https URL, got %q", n.Webhook)
+		}
+	}
+	for _, route := range n.Routes {
+		if route == "" {
+			return fmt.Errorf("idle_notify.routes: empty route name")
+		}
+	}
+	if (n.Webhook != "" || len(n.Routes) > 0) && !n.Enabled() {
+		return fmt.Errorf("idle_notify.before is required to send idle warnings")
+	}
+	return nil
+}
+
+// validateEnvNames checks the variable names of an env map
+func validateEnvNames(id string, env map[string]string) error {
+	for name := range env {
+		if name == "" || strings.ContainsAny(name, "= ") {
+			return fmt.Errorf("%s: invalid env variable name %q", id, name)
+		}
+	}
+	return nil
+}
+
+// validateExecEnv checks a route's exec_env
+func validateExecEnv(routeID string, env map[string]string) error {
+	return validateEnvNames(routeID+": exec_env", env)
+}
+
+func validateRouteBase(routeID, context, namespace, service, pod string, port int) error {
+	if err := validateContext(routeID, context); err != nil {
+		return err
+	}
 	if namespace == "" {
 		return fmt.Errorf("%s: namespace is required", routeID)
 	}
@@ -87,6 +204,34 @@ func validateRouteBase(routeID, context, namespace, service, pod string, port in
 	return nil
 }
 
+func validateSchemeRoutes(routeID string, route K8sRouteConfig) error {
+	for scheme, override := range route.SchemeRoutes {
+		entryID := fmt.Sprintf("%s: scheme_routes[%s]", routeID, scheme)
+		switch scheme {
+		case "http":
+		case "https":
+			if route.TerminatesTLS() {
+				return fmt.Errorf("%s: tls: terminate serves TLS clients as http requests, use scheme_routes[http]", entryID)
+			}
+			if override.Scheme != "" {
+				return fmt.Errorf("%s: scheme has no effect on passthrough connections", entryID)
+			}
+		default:
+			return fmt.Errorf("%s: key must be \"http\" or \"https\"", entryID)
+		}
+		if override.Service != "" && override.Pod != "" {
+			return fmt.Errorf("%s: cannot specify both service and pod", entryID)
+		}
+		if override.Port < 0 || override.Port > 65535 {
+			return fmt.Errorf("%s: port must be between 1 and 65535", entryID)
+		}
+		if override.Scheme != "" && override.Scheme != "http" && override.Scheme != "https" {
+			return fmt.Errorf("%s: scheme must be \"http\" or \"https\"", entryID)
+		}
+	}
+	return nil
+}
+
 func (c *Config) Validate() error {
 	// Allow empty apiVersion (defaults to current), but reject wrong versions
 	if c.ApiVersion != "" && c.ApiVersion != CurrentApiVersion {
@@ -101,11 +246,188 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("http.idle_timeout must be positive")
 	}
 
+	if c.Keepalive != 0 && c.Keepalive < time.Second {
+		return fmt.Errorf("keepalive must be at least 1s, or 0 to disable, got %v", c.Keepalive)
+	}
+
+	if c.ReadyTimeout < 0 {
+		return fmt.Errorf("ready_timeout must not be negative")
+	}
+
+	if err := c.validateContextAliases(); err != nil {
+		return err
+	}
+
+	if c.CleanupInterval != 0 && c.CleanupInterval < time.Second {
+		return fmt.Errorf("cleanup_interval must be at least 1s, or 0 for the default, got %v", c.CleanupInterval)
+	}
+
+	if c.ServerRetry.MaxAttempts < 0 {
+		return fmt.Errorf("server_retry.max_attempts must not be negative")
+	}
+	if c.ServerRetry.MaxAttempts > 0 && c.ServerRetry.Backoff <= 0 {
+		return fmt.Errorf("server_retry.backoff must be positive")
+	}
+
+	if c.Prewarm.Timeout < 0 {
+		return fmt.Errorf("prewarm.timeout must not be negative")
+	}
+	if c.Prewarm.Parallelism < 0 {
+		return fmt.Errorf("prewarm.parallelism must not be negative")
+	}
+
+	if err := c.validateIdleNotify(); err != nil {
+		return err
+	}
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+
+	if c.ReloadMode != "" && c.ReloadMode != ReloadModeAuto && c.ReloadMode != ReloadModeConfirm {
+		return fmt.Errorf("reload_mode must be %q or %q, got %q", ReloadModeAuto, ReloadModeConfirm, c.ReloadMode)
+	}
+
+	if err := c.validateLog(); err != nil {
+		return err
+	}
+
+	if c.HTTP.SlowRequestThreshold < 0 {
+		return fmt.Errorf("http.slow_request_threshold must not be negative")
+	}
+	if c.HTTP.LargeResponseThresholdMB < 0 {
+		return fmt.Errorf("http.large_response_threshold_mb must not be negative")
+	}
+
+	if _, err := extractPort(c.HTTP.ListenAddr); err != nil {
+		return fmt.Errorf("invalid http.listen: %w", err)
+	}
+
+	if c.TCP.ListenHost != "" {
+		if ip := net.ParseIP(c.TCP.ListenHost); ip == nil || !ip.IsLoopback() {
+			return fmt.Errorf("tcp.listen_host must be a loopback IP like 127.0.0.1 or ::1, got %q", c.TCP.ListenHost)
+		}
+	}
+
+	if c.HTTP.PrivilegedFallbackListen != "" {
+		fallbackPort, err := extractPort(c.HTTP.PrivilegedFallbackListen)
+		if err != nil {
+			return fmt.Errorf("invalid http.privileged_fallback_listen: %w", err)
+		}
+		if fallbackPort < 1024 {
+			return fmt.Errorf("http.privileged_fallback_listen must use a port >= 1024, got %d", fallbackPort)
+		}
+		if c.HTTP.PrivilegedFallbackListen == c.HTTP.ListenAddr {
+			return fmt.Errorf("http.privileged_fallback_listen must differ from http.listen")
+		}
+	}
+
+	if c.HTTP.HTTP3.Listen != "" {
+		if _, err := extractPort(c.HTTP.HTTP3.Listen); err != nil {
+			return fmt.Errorf("invalid http.http3.listen: %w", err)
+		}
+	}
+
+	if c.HTTP.EchoHost != "" {
+		if !IsValidTargetHost(c.HTTP.EchoHost) || net.ParseIP(c.HTTP.EchoHost) != nil {
+			return fmt.Errorf("http.echo_host %q must be a hostname", c.HTTP.EchoHost)
+		}
+		if strings.EqualFold(c.HTTP.EchoHost, c.HTTP.StatusHost) {
+			return fmt.Errorf("http.echo_host must differ from http.status_host")
+		}
+	}
+
+	if err := c.validateRedirects(); err != nil {
+		return err
+	}
+	if err := c.validateStatic(); err != nil {
+		return err
+	}
+	if err := c.validateLocal(); err != nil {
+		return err
+	}
+	if err := c.validateSignatures(); err != nil {
+		return err
+	}
+
 	for hostname, route := range c.HTTP.K8s.Routes {
 		routeID := fmt.Sprintf("route %q", hostname)
+		if c.HTTP.StatusHost != "" && strings.EqualFold(hostname, c.HTTP.StatusHost) {
+			return fmt.Errorf("%s: hostname is reserved for the status API (change http.status_host)", routeID)
+		}
+		if c.HTTP.EchoHost != "" && strings.EqualFold(hostname, c.HTTP.EchoHost) {
+			return fmt.Errorf("%s: hostname is reserved for the echo endpoint (change http.echo_host)", routeID)
+		}
+		if err := validateExecEnv(routeID, route.ExecEnv); err != nil {
+			return err
+		}
 		if err := validateRouteBase(routeID, route.Context, route.Namespace, route.Service, route.Pod, route.Port); err != nil {
 			return err
 		}
+		if route.TLS != "" && route.TLS != TLSModePassthrough && route.TLS != TLSModeTerminate {
+			return fmt.Errorf("%s: tls must be %q or %q, got %q", routeID, TLSModePassthrough, TLSModeTerminate, route.TLS)
+		}
+		if !c.TLSPassthroughEnabled() && (route.TerminatesTLS() || len(route.ALPNPorts) > 0 || route.UpstreamSNI != "") {
+			return fmt.Errorf("%s: tls, alpn_ports and upstream_sni need TLS on http.listen, which http.tls_passthrough: false turns off", routeID)
+		}
+		if len(route.ALPNPorts) > 0 && route.TerminatesTLS() {
+			return fmt.Errorf("%s: alpn_ports only applies to tls: passthrough", routeID)
+		}
+		if route.Mode != "" && route.Mode != RouteModeHTTP && route.Mode != RouteModeTCP {
+			return fmt.Errorf("%s: mode must be %q or %q, got %q", routeID, RouteModeHTTP, RouteModeTCP, route.Mode)
+		}
+		if route.RawTCP() && (route.TerminatesTLS() || len(route.SchemeRoutes) > 0) {
+			return fmt.Errorf("%s: mode: tcp splices connections as they are, so tls: terminate and scheme_routes don't apply", routeID)
+		}
+		for proto, port := range route.ALPNPorts {
+			if proto == "" {
+				return fmt.Errorf("%s: alpn_ports has an empty protocol name", routeID)
+			}
+			if port <= 0 || port > 65535 {
+				return fmt.Errorf("%s: alpn_ports[%s] must be between 1 and 65535", routeID, proto)
+			}
+		}
+		if err := validatePinPod(routeID, route.PinPod, route.Pod); err != nil {
+			return err
+		}
+		if err := validateSchemeRoutes(routeID, route); err != nil {
+			return err
+		}
+		if route.ReadyTimeout < 0 {
+			return fmt.Errorf("%s: ready_timeout must not be negative", routeID)
+		}
+		if route.Standby < 0 || route.Standby > MaxStandby {
+			return fmt.Errorf("%s: standby must be between 0 and %d", routeID, MaxStandby)
+		}
+		if route.LocalPort < 0 || route.LocalPort > 65535 {
+			return fmt.Errorf("%s: local_port must be between 1 and 65535", routeID)
+		}
+		if route.Protocol != "" && !route.GRPC() {
+			return fmt.Errorf("%s: protocol must be %q, got %q", routeID, ProtocolGRPC, route.Protocol)
+		}
+		if err := validateHealthCheck(routeID, route.HealthCheck, route.GRPC()); err != nil {
+			return err
+		}
+		if err := validateWarmupSchedule(routeID, route.WarmupSchedule); err != nil {
+			return err
+		}
+		if err := validateUpstreamTLS(routeID, route); err != nil {
+			return err
+		}
+		if route.UpstreamSNI != "" {
+			if !IsValidTargetHost(route.UpstreamSNI) || net.ParseIP(route.UpstreamSNI) != nil {
+				return fmt.Errorf("%s: upstream_sni %q must be a hostname", routeID, route.UpstreamSNI)
+			}
+			if route.TerminatesTLS() || route.RawTCP() {
+				return fmt.Errorf("%s: upstream_sni only applies to tls: passthrough (with tls: terminate, use upstream_tls.server_name)", routeID)
+			}
+		}
+	}
+
+	if err := c.validateHostPatterns(); err != nil {
+		return err
+	}
+	if err := c.validateDynamicHost(); err != nil {
+		return err
 	}
 
 	// Validate TCP config (optional - skip if no routes configured)
@@ -113,14 +435,206 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.validateHTTPLocalPorts(); err != nil {
+		return err
+	}
+
+	if err := c.validateTeam(); err != nil {
+		return err
+	}
+
+	if err := c.validateAuthorizers(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateHTTPLocalPorts checks that HTTP routes' local_port values don't collide
+// with each other, the HTTP listeners or the ports TCP routes bind
+func (c *Config) validateHTTPLocalPorts() error {
+	taken := c.tcpLocalPorts()
+	if port, err := extractPort(c.HTTP.ListenAddr); err == nil {
+		taken[port] = "http.listen"
+	}
+	if port, err := extractPort(c.HTTP.PrivilegedFallbackListen); err == nil {
+		taken[port] = "http.privileged_fallback_listen"
+	}
+
+	hostnames := make([]string, 0, len(c.HTTP.K8s.Routes))
+	for hostname := range c.HTTP.K8s.Routes {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames) // report the same conflict on every load
+
+	for _, hostname := range hostnames {
+		port := c.HTTP.K8s.Routes[hostname].LocalPort
+		if port == 0 {
+			continue
+		}
+		if owner, ok := taken[port]; ok {
+			return fmt.Errorf("route %q: local_port %d is already used by %s", hostname, port, owner)
+		}
+		taken[port] = fmt.Sprintf("route %q", hostname)
+	}
+	return nil
+}
+
+// tcpLocalPorts returns the local ports TCP routes listen on, named like their
+// validation errors. Discovered routes are only known at runtime.
+func (c *Config) tcpLocalPorts() map[int]string {
+	ports := make(map[int]string)
+	for localPort, route := range c.TCP.K8s.Routes {
+		ports[localPort] = fmt.Sprintf("tcp.k8s.routes[%d]", localPort)
+		for extraPort := range route.ExtraPorts {
+			ports[extraPort] = fmt.Sprintf("tcp.k8s.routes[%d].extra_ports[%d]", localPort, extraPort)
+		}
+	}
+	for name, group := range c.TCP.K8s.Groups {
+		for servicePort, localPort := range group.Ports {
+			ports[localPort] = fmt.Sprintf("tcp.k8s.groups[%s].ports[%d]", name, servicePort)
+		}
+	}
+	for localPort := range c.TCP.K8s.Postgres {
+		ports[localPort] = fmt.Sprintf("tcp.k8s.postgres[%d]", localPort)
+	}
+	for localPort := range c.TCP.K8s.Jump {
+		ports[localPort] = fmt.Sprintf("tcp.k8s.jump[%d]", localPort)
+	}
+	return ports
+}
+
+func (c *Config) validateLog() error {
+	switch c.Log.Output {
+	case "", LogOutputStderr, LogOutputJournald:
+		if c.Log.SyslogAddr != "" {
+			return fmt.Errorf("log.syslog_addr requires log.output: %s", LogOutputSyslog)
+		}
+	case LogOutputSyslog:
+		if c.Log.SyslogAddr == "" {
+			return nil
+		}
+		u, err := url.Parse(c.Log.SyslogAddr)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+			return fmt.Errorf("log.syslog_addr must look like udp://host:514 or tcp://host:514, got %q", c.Log.SyslogAddr)
+		}
+	default:
+		return fmt.Errorf("log.output must be %q, %q or %q, got %q", LogOutputStderr, LogOutputSyslog, LogOutputJournald, c.Log.Output)
+	}
+	return nil
+}
+
+// minTeamTokenLength keeps team tokens from being guessable
+const minTeamTokenLength = 16
+
+func (c *Config) validateTeam() error {
+	tokens := make(map[string]string) // token -> user
+	for name, user := range c.Team.Users {
+		userID := fmt.Sprintf("team.users[%s]", name)
+
+		if user.Token != "" && user.TokenEnv != "" {
+			return fmt.Errorf("%s: cannot specify both token and token_env", userID)
+		}
+		if user.Token == "" && user.TokenEnv == "" {
+			return fmt.Errorf("%s: token or token_env is required", userID)
+		}
+
+		token := user.ResolvedToken()
+		if token == "" {
+			return fmt.Errorf("%s: environment variable %s is empty", userID, user.TokenEnv)
+		}
+		if len(token) < minTeamTokenLength {
+			return fmt.Errorf("%s: token must be at least %d characters", userID, minTeamTokenLength)
+		}
+		if other, exists := tokens[token]; exists {
+			return fmt.Errorf("%s: token is already used by team.users[%s]", userID, other)
+		}
+		tokens[token] = name
+
+		for _, pattern := range user.Routes {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid route pattern %q", userID, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateAuthorizers() error {
+	if err := validateAuthorizerList("authorizers", c.Authorizers); err != nil {
+		return err
+	}
+	for hostname, route := range c.HTTP.K8s.Routes {
+		if err := validateAuthorizerList(fmt.Sprintf("http.k8s.routes[%s].authorizers", hostname), route.Authorizers); err != nil {
+			return err
+		}
+	}
+	for i, pattern := range c.HTTP.K8s.HostPatterns {
+		if err := validateAuthorizerList(fmt.Sprintf("http.k8s.host_patterns[%d].authorizers", i), pattern.Authorizers); err != nil {
+			return err
+		}
+	}
+	for port, route := range c.TCP.K8s.Routes {
+		if err := validateAuthorizerList(fmt.Sprintf("tcp.k8s.routes[%d].authorizers", port), route.Authorizers); err != nil {
+			return err
+		}
+	}
+	for port, route := range c.TCP.K8s.Jump {
+		if err := validateAuthorizerList(fmt.Sprintf("tcp.k8s.jump[%d].authorizers", port), route.Authorizers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAuthorizerList(field string, authorizers []AuthorizerConfig) error {
+	for i, a := range authorizers {
+		id := fmt.Sprintf("%s[%d]", field, i)
+		switch a.Type {
+		case AuthorizerToken:
+			if len(a.Tokens) == 0 {
+				return fmt.Errorf("%s: tokens is required", id)
+			}
+			for name, token := range a.Tokens {
+				if len(token) < minTeamTokenLength {
+					return fmt.Errorf("%s: token of %s must be at least %d characters", id, name, minTeamTokenLength)
+				}
+			}
+		case AuthorizerOSUser:
+			if len(a.Users) == 0 {
+				return fmt.Errorf("%s: users is required", id)
+			}
+		case AuthorizerClientCert:
+			if a.CAFile == "" {
+				return fmt.Errorf("%s: ca_file is required", id)
+			}
+		case AuthorizerCommand:
+			if len(a.Command) == 0 {
+				return fmt.Errorf("%s: command is required", id)
+			}
+			if a.Timeout < 0 {
+				return fmt.Errorf("%s: timeout cannot be negative", id)
+			}
+			if a.CacheTTL < 0 {
+				return fmt.Errorf("%s: cache_ttl cannot be negative", id)
+			}
+		default:
+			return fmt.Errorf("%s: type must be %q, %q, %q or %q, got %q", id,
+				AuthorizerToken, AuthorizerOSUser, AuthorizerClientCert, AuthorizerCommand, a.Type)
+		}
+	}
 	return nil
 }
 
 func (c *Config) validateTCP() error {
 	hasRoutes := len(c.TCP.K8s.Routes) > 0
 	hasJump := len(c.TCP.K8s.Jump) > 0
+	hasGroups := len(c.TCP.K8s.Groups) > 0
+	hasDiscover := len(c.TCP.K8s.Discover) > 0
+	hasPostgres := len(c.TCP.K8s.Postgres) > 0
+	hasEcho := c.TCP.EchoPort != 0
 
-	if !hasRoutes && !hasJump {
+	if !hasRoutes && !hasJump && !hasGroups && !hasDiscover && !hasPostgres && !hasEcho {
 		return nil
 	}
 
@@ -135,6 +649,13 @@ func (c *Config) validateTCP() error {
 		return fmt.Errorf("invalid http.listen address: %w", err)
 	}
 
+	if c.TCP.AllowedPortRange != "" {
+		if _, err := ParsePortRange(c.TCP.AllowedPortRange); err != nil {
+			return fmt.Errorf("invalid tcp.allowed_port_range: %w", err)
+		}
+	}
+	allowed := c.TCP.PortRange()
+
 	// Track all seen TCP ports (both routes and jump)
 	seenPorts := make(map[int]string) // port -> source ("routes" or "jump")
 
@@ -145,9 +666,140 @@ func (c *Config) validateTCP() error {
 		if err := validateLocalPort(routeID, localPort, httpPort, seenPorts, "routes"); err != nil {
 			return err
 		}
+		if !allowed.Contains(localPort) {
+			return fmt.Errorf("%s: local port %d is outside tcp.allowed_port_range %s", routeID, localPort, allowed)
+		}
+		if err := validateExecEnv(routeID, route.ExecEnv); err != nil {
+			return err
+		}
 		if err := validateRouteBase(routeID, route.Context, route.Namespace, route.Service, route.Pod, route.Port); err != nil {
 			return err
 		}
+
+		if err := validateTCPMethod(routeID, route.Method); err != nil {
+			return err
+		}
+		if err := validatePinPod(routeID, route.PinPod, route.Pod); err != nil {
+			return err
+		}
+		if route.ReadyTimeout < 0 {
+			return fmt.Errorf("%s: ready_timeout must not be negative", routeID)
+		}
+		if route.Standby < 0 || route.Standby > MaxStandby {
+			return fmt.Errorf("%s: standby must be between 0 and %d", routeID, MaxStandby)
+		}
+		if route.ConnectTimeout < 0 || route.ConnIdleTimeout < 0 || route.ConnMaxDuration < 0 {
+			return fmt.Errorf("%s: connect_timeout, conn_idle_timeout and conn_max_duration must not be negative", routeID)
+		}
+		if err := validateWarmupSchedule(routeID, route.WarmupSchedule); err != nil {
+			return err
+		}
+		if err := validateSocket(routeID, route.SocketConfig); err != nil {
+			return err
+		}
+
+		// Extra ports share the route's port-forward, so each target port may appear once
+		targets := map[int]bool{route.Port: true}
+		for extraPort, targetPort := range route.ExtraPorts {
+			extraID := fmt.Sprintf("%s.extra_ports[%d]", routeID, extraPort)
+			if err := validateLocalPort(extraID, extraPort, httpPort, seenPorts, "routes"); err != nil {
+				return err
+			}
+			if !allowed.Contains(extraPort) {
+				return fmt.Errorf("%s: local port %d is outside tcp.allowed_port_range %s", extraID, extraPort, allowed)
+			}
+			if targetPort <= 0 || targetPort > 65535 {
+				return fmt.Errorf("%s: target port must be between 1 and 65535", extraID)
+			}
+			if targets[targetPort] {
+				return fmt.Errorf("%s: target port %d is already forwarded by this route", extraID, targetPort)
+			}
+			targets[targetPort] = true
+		}
+	}
+
+	// Validate group routes (their service ports are only known at runtime)
+	for name, group := range c.TCP.K8s.Groups {
+		groupID := fmt.Sprintf("tcp.k8s.groups[%s]", name)
+
+		if err := validateExecEnv(groupID, group.ExecEnv); err != nil {
+			return err
+		}
+		if err := validateContext(groupID, group.Context); err != nil {
+			return err
+		}
+		if group.Namespace == "" {
+			return fmt.Errorf("%s: namespace is required", groupID)
+		}
+		if group.Service == "" {
+			return fmt.Errorf("%s: service is required", groupID)
+		}
+		if err := validateTCPMethod(groupID, group.Method); err != nil {
+			return err
+		}
+		if err := validatePinPod(groupID, group.PinPod, ""); err != nil {
+			return err
+		}
+		if group.ReadyTimeout < 0 {
+			return fmt.Errorf("%s: ready_timeout must not be negative", groupID)
+		}
+		if group.PortOffset < 0 || group.PortOffset >= 65535 {
+			return fmt.Errorf("%s: port_offset must be between 0 and 65534", groupID)
+		}
+		for servicePort, localPort := range group.Ports {
+			portID := fmt.Sprintf("%s.ports[%d]", groupID, servicePort)
+			if servicePort <= 0 || servicePort > 65535 {
+				return fmt.Errorf("%s: service port must be between 1 and 65535", portID)
+			}
+			if err := validateLocalPort(portID, localPort, httpPort, seenPorts, "groups"); err != nil {
+				return err
+			}
+			if !allowed.Contains(localPort) {
+				return fmt.Errorf("%s: local port %d is outside tcp.allowed_port_range %s", portID, localPort, allowed)
+			}
+		}
+	}
+
+	// Validate postgres routes (the namespace comes from each connection)
+	for localPort, route := range c.TCP.K8s.Postgres {
+		routeID := fmt.Sprintf("tcp.k8s.postgres[%d]", localPort)
+
+		if err := validateLocalPort(routeID, localPort, httpPort, seenPorts, "postgres"); err != nil {
+			return err
+		}
+		if !allowed.Contains(localPort) {
+			return fmt.Errorf("%s: local port %d is outside tcp.allowed_port_range %s", routeID, localPort, allowed)
+		}
+		if err := validateExecEnv(routeID, route.ExecEnv); err != nil {
+			return err
+		}
+		if err := validateContext(routeID, route.Context); err != nil {
+			return err
+		}
+		if route.Service == "" {
+			return fmt.Errorf("%s: service is required", routeID)
+		}
+		if route.Port <= 0 || route.Port > 65535 {
+			return fmt.Errorf("%s: port must be between 1 and 65535", routeID)
+		}
+		if route.NamespacePrefix != "" && !IsValidNamespace(route.NamespacePrefix+"x") {
+			return fmt.Errorf("%s: invalid namespace_prefix %q", routeID, route.NamespacePrefix)
+		}
+		if route.ReadyTimeout < 0 {
+			return fmt.Errorf("%s: ready_timeout must not be negative", routeID)
+		}
+	}
+
+	// Validate discovery (the routes themselves are only known at runtime)
+	for i, d := range c.TCP.K8s.Discover {
+		discoverID := fmt.Sprintf("tcp.k8s.discover[%d]", i)
+
+		if err := validateContext(discoverID, d.Context); err != nil {
+			return err
+		}
+		if err := validateTCPMethod(discoverID, d.Method); err != nil {
+			return err
+		}
 	}
 
 	// Validate jump (jump-host) routes
@@ -157,9 +809,15 @@ func (c *Config) validateTCP() error {
 		if err := validateLocalPort(routeID, localPort, httpPort, seenPorts, "jump"); err != nil {
 			return err
 		}
+		if !allowed.Contains(localPort) {
+			return fmt.Errorf("%s: local port %d is outside tcp.allowed_port_range %s", routeID, localPort, allowed)
+		}
 
-		if route.Context == "" {
-			return fmt.Errorf("%s: context is required", routeID)
+		if err := validateExecEnv(routeID, route.ExecEnv); err != nil {
+			return err
+		}
+		if err := validateContext(routeID, route.Context); err != nil {
+			return err
 		}
 		if route.Namespace == "" {
 			return fmt.Errorf("%s: namespace is required", routeID)
@@ -187,19 +845,46 @@ func (c *Config) validateTCP() error {
 			if !IsValidImageName(route.Via.Create.Image) {
 				return fmt.Errorf("%s: via.create.image %q is invalid", routeID, route.Via.Create.Image)
 			}
+			if sa := route.Via.Create.ServiceAccountName; sa != "" && !IsValidPodName(sa) {
+				return fmt.Errorf("%s: via.create.service_account_name %q is invalid", routeID, sa)
+			}
+			for _, secret := range route.Via.Create.ImagePullSecrets {
+				if !IsValidPodName(secret) {
+					return fmt.Errorf("%s: via.create.image_pull_secrets: %q is not a valid secret name", routeID, secret)
+				}
+			}
 		}
 
-		// Validate target host - must be valid hostname/IP to prevent command injection
-		if route.Target.Host == "" {
-			return fmt.Errorf("%s: target.host is required", routeID)
+		if route.Pool < 0 || route.Pool > MaxJumpPool {
+			return fmt.Errorf("%s: pool must be between 0 and %d", routeID, MaxJumpPool)
 		}
-		if !IsValidTargetHost(route.Target.Host) {
-			return fmt.Errorf("%s: target.host %q contains invalid characters (must be valid hostname or IP)", routeID, route.Target.Host)
+		if route.PoolMaxAge < 0 {
+			return fmt.Errorf("%s: pool_max_age cannot be negative", routeID)
 		}
+		if route.DNSCacheTTL < 0 {
+			return fmt.Errorf("%s: dns_cache_ttl cannot be negative", routeID)
+		}
+		if err := validateSocket(routeID, route.SocketConfig); err != nil {
+			return err
+		}
+
+		for _, target := range route.Targets() {
+			// Validate target host - must be valid hostname/IP to prevent command injection
+			if target.Host == "" {
+				return fmt.Errorf("%s: target.host is required", routeID)
+			}
+			if !IsValidTargetHost(target.Host) {
+				return fmt.Errorf("%s: target.host %q contains invalid characters (must be valid hostname or IP)", routeID, target.Host)
+			}
 
-		// Validate target port
-		if route.Target.Port <= 0 || route.Target.Port > 65535 {
-			return fmt.Errorf("%s: target.port must be between 1 and 65535", routeID)
+			// Validate target port
+			if target.Port <= 0 || target.Port > 65535 {
+				return fmt.Errorf("%s: target.port must be between 1 and 65535", routeID)
+			}
+
+			if err := validateTargetTLS(target); err != nil {
+				return fmt.Errorf("%s: %w", routeID, err)
+			}
 		}
 
 		// Validate method (allow empty or "socat" for now)
@@ -208,19 +893,257 @@ func (c *Config) validateTCP() error {
 		}
 	}
 
+	if c.TCP.EchoPort != 0 {
+		if err := validateLocalPort("tcp.echo_port", c.TCP.EchoPort, httpPort, seenPorts, "echo_port"); err != nil {
+			return err
+		}
+		if !allowed.Contains(c.TCP.EchoPort) {
+			return fmt.Errorf("tcp.echo_port: local port %d is outside tcp.allowed_port_range %s", c.TCP.EchoPort, allowed)
+		}
+	}
+
+	return nil
+}
+
+// validateTCPMethod validates the method of a port-forward route or group
+func validateTCPMethod(routeID, method string) error {
+	if method != "" && method != TCPMethodPortForward && method != TCPMethodExec {
+		return fmt.Errorf("%s: method must be %q or %q, got %q", routeID, TCPMethodPortForward, TCPMethodExec, method)
+	}
+	return nil
+}
+
+// validatePinPod validates pin_pod: a pod name or "auto", on service routes only
+func validatePinPod(routeID, pinPod, pod string) error {
+	if pinPod == "" {
+		return nil
+	}
+	if pod != "" {
+		return fmt.Errorf("%s: pin_pod only applies to service routes (pod routes are already pinned)", routeID)
+	}
+	if pinPod != PinPodAuto && !IsValidPodName(pinPod) {
+		return fmt.Errorf("%s: pin_pod must be a pod name or %q, got %q", routeID, PinPodAuto, pinPod)
+	}
 	return nil
 }
 
-// extractPort extracts the port number from an address string like ":8989" or "127.0.0.1:8989"
+// validateRedirects checks http.redirects: hostnames that no route or reserved
+// host uses, pointing at absolute http(s) URLs
+func (c *Config) validateRedirects() error {
+	for hostname, target := range c.HTTP.Redirects {
+		id := fmt.Sprintf("http.redirects[%s]", hostname)
+		if !IsValidTargetHost(hostname) || net.ParseIP(hostname) != nil {
+			return fmt.Errorf("%s: %q must be a hostname", id, hostname)
+		}
+		if strings.EqualFold(hostname, c.HTTP.StatusHost) || strings.EqualFold(hostname, c.HTTP.EchoHost) {
+			return fmt.Errorf("%s: hostname is reserved for the status API or echo endpoint", id)
+		}
+		if _, ok := c.HTTP.K8s.Routes[hostname]; ok {
+			return fmt.Errorf("%s: hostname is also an http.k8s.routes entry", id)
+		}
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s: %q must be an absolute http:// or https:// URL", id, target)
+		}
+	}
+	return nil
+}
+
+// validateStatic checks http.static: hostnames that no route, redirect or reserved
+// host uses, serving absolute directories. The directory may not exist yet (a
+// build that hasn't run); requests then get 404s.
+func (c *Config) validateStatic() error {
+	for hostname, static := range c.HTTP.Static {
+		id := fmt.Sprintf("http.static[%s]", hostname)
+		if !IsValidTargetHost(hostname) || net.ParseIP(hostname) != nil {
+			return fmt.Errorf("%s: %q must be a hostname", id, hostname)
+		}
+		if strings.EqualFold(hostname, c.HTTP.StatusHost) || strings.EqualFold(hostname, c.HTTP.EchoHost) {
+			return fmt.Errorf("%s: hostname is reserved for the status API or echo endpoint", id)
+		}
+		if _, ok := c.HTTP.K8s.Routes[hostname]; ok {
+			return fmt.Errorf("%s: hostname is also an http.k8s.routes entry", id)
+		}
+		if _, ok := c.HTTP.Redirects[hostname]; ok {
+			return fmt.Errorf("%s: hostname is also an http.redirects entry", id)
+		}
+		if static.Dir == "" {
+			return fmt.Errorf("%s: dir is required", id)
+		}
+		if !filepath.IsAbs(static.Dir) {
+			return fmt.Errorf("%s: dir %q must be an absolute path", id, static.Dir)
+		}
+	}
+	return nil
+}
+
+// validateLocal checks http.local: hostnames that no redirect, static route or
+// reserved host uses, each with either a port or an absolute socket path. A
+// hostname may also be an http.k8s.routes entry, which then serves requests while
+// the local process isn't listening.
+func (c *Config) validateLocal() error {
+	for hostname, local := range c.HTTP.Local {
+		id := fmt.Sprintf("http.local[%s]", hostname)
+		if !IsValidTargetHost(hostname) || net.ParseIP(hostname) != nil {
+			return fmt.Errorf("%s: %q must be a hostname", id, hostname)
+		}
+		if strings.EqualFold(hostname, c.HTTP.StatusHost) || strings.EqualFold(hostname, c.HTTP.EchoHost) {
+			return fmt.Errorf("%s: hostname is reserved for the status API or echo endpoint", id)
+		}
+		if route, ok := c.HTTP.K8s.Routes[hostname]; ok && route.RawTCP() {
+			return fmt.Errorf("%s: the hostname's http.k8s.routes entry has mode: tcp, which can't fall back from a local process", id)
+		}
+		if _, ok := c.HTTP.Redirects[hostname]; ok {
+			return fmt.Errorf("%s: hostname is also an http.redirects entry", id)
+		}
+		if _, ok := c.HTTP.Static[hostname]; ok {
+			return fmt.Errorf("%s: hostname is also an http.static entry", id)
+		}
+		if (local.Port == 0) == (local.Socket == "") {
+			return fmt.Errorf("%s: exactly one of port or socket is required", id)
+		}
+		if local.Port < 0 || local.Port > 65535 {
+			return fmt.Errorf("%s: port must be between 1 and 65535", id)
+		}
+		if local.Socket != "" && !filepath.IsAbs(local.Socket) {
+			return fmt.Errorf("%s: socket %q must be an absolute path", id, local.Socket)
+		}
+		if local.Scheme != "" && local.Scheme != "http" && local.Scheme != "https" {
+			return fmt.Errorf("%s: scheme must be \"http\" or \"https\", got %q", id, local.Scheme)
+		}
+	}
+	return nil
+}
+
+// validateSignatures checks http.signatures: non-empty prefixes that can't be taken
+// for TLS, each sending connections to a mode: tcp route
+func (c *Config) validateSignatures() error {
+	for i, sig := range c.HTTP.Signatures {
+		id := fmt.Sprintf("http.signatures[%d]", i)
+		if sig.Prefix == "" {
+			return fmt.Errorf("%s: prefix is required", id)
+		}
+		if len(sig.Prefix) > MaxSignaturePrefix {
+			return fmt.Errorf("%s: prefix must be at most %d bytes", id, MaxSignaturePrefix)
+		}
+		// 0x16 = TLS handshake record type, which is always passed through by SNI
+		if sig.Prefix[0] == 0x16 {
+			return fmt.Errorf("%s: prefix starts like a TLS handshake", id)
+		}
+		route, ok := c.HTTP.K8s.Routes[sig.Route]
+		if !ok {
+			return fmt.Errorf("%s: route %q is not an http.k8s.routes entry", id, sig.Route)
+		}
+		if !route.RawTCP() {
+			return fmt.Errorf("%s: route %q must have mode: tcp", id, sig.Route)
+		}
+	}
+	return nil
+}
+
+// validateWarmupSchedule checks a route's warmup_schedule cron expression
+func validateWarmupSchedule(routeID, schedule string) error {
+	if schedule == "" {
+		return nil
+	}
+	if _, err := cron.Parse(schedule); err != nil {
+		return fmt.Errorf("%s: warmup_schedule: %w", routeID, err)
+	}
+	return nil
+}
+
+// validateHealthCheck validates an HTTP route's health_check block. gRPC routes
+// are checked by service name instead of path and status.
+func validateHealthCheck(routeID string, hc *HealthCheckConfig, grpc bool) error {
+	if hc == nil {
+		return nil
+	}
+	if grpc {
+		if hc.Path != "" || hc.ExpectedStatus != 0 {
+			return fmt.Errorf("%s: health_check.path and expected_status don't apply to protocol: grpc, use health_check.service", routeID)
+		}
+	} else if hc.Service != "" {
+		return fmt.Errorf("%s: health_check.service only applies to protocol: grpc", routeID)
+	} else if !strings.HasPrefix(hc.Path, "/") {
+		return fmt.Errorf("%s: health_check.path must start with /, got %q", routeID, hc.Path)
+	}
+	if hc.ExpectedStatus != 0 && (hc.ExpectedStatus < 100 || hc.ExpectedStatus > 599) {
+		return fmt.Errorf("%s: health_check.expected_status must be between 100 and 599", routeID)
+	}
+	if hc.Interval != 0 && hc.Interval < time.Second {
+		return fmt.Errorf("%s: health_check.interval must be at least 1s", routeID)
+	}
+	if hc.Timeout < 0 {
+		return fmt.Errorf("%s: health_check.timeout must not be negative", routeID)
+	}
+	return nil
+}
+
+// validateSocket validates a TCP or jump route's conn_keepalive and conn_linger
+func validateSocket(routeID string, sc SocketConfig) error {
+	if sc.ConnKeepalive > 0 && sc.ConnKeepalive < time.Second {
+		return fmt.Errorf("%s: conn_keepalive must be at least 1s (or negative to disable)", routeID)
+	}
+	if sc.ConnLinger != nil && (*sc.ConnLinger < 0 || *sc.ConnLinger%time.Second != 0) {
+		return fmt.Errorf("%s: conn_linger must be a whole number of seconds, 0 or more", routeID)
+	}
+	return nil
+}
+
+// validateUpstreamTLS validates an HTTP route's upstream_tls block
+func validateUpstreamTLS(routeID string, route K8sRouteConfig) error {
+	u := route.UpstreamTLS
+	if u == nil {
+		return nil
+	}
+	route, _ = route.ForScheme("http") // the backend plain HTTP requests are proxied to
+	if route.Scheme != "https" {
+		return fmt.Errorf("%s: upstream_tls needs scheme: https", routeID)
+	}
+	if u.ServerName != "" && (!IsValidTargetHost(u.ServerName) || net.ParseIP(u.ServerName) != nil) {
+		return fmt.Errorf("%s: upstream_tls.server_name %q must be a hostname", routeID, u.ServerName)
+	}
+	if route.GetServerName() == "" {
+		return fmt.Errorf("%s: upstream_tls.server_name is required for pod routes", routeID)
+	}
+	return nil
+}
+
+// extractPort extracts the port number from an address string like ":8989",
+// "127.0.0.1:8989" or "[::1]:8989". The host, if any, must be an IP or hostname.
 func extractPort(addr string) (int, error) {
-	idx := strings.LastIndex(addr, ":")
-	if idx == -1 {
-		return 0, fmt.Errorf("no port in address %q", addr)
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0, fmt.Errorf("address %q must look like \":8989\", \"127.0.0.1:8989\" or \"[::1]:8989\"", addr)
+	}
+	if host != "" && !IsValidTargetHost(host) {
+		return 0, fmt.Errorf("invalid host %q in address %q", host, addr)
 	}
-	portStr := addr[idx+1:]
 	port, err := strconv.Atoi(portStr)
-	if err != nil {
+	if err != nil || port < 0 || port > 65535 {
 		return 0, fmt.Errorf("invalid port %q in address %q", portStr, addr)
 	}
 	return port, nil
 }
+
+// podPathRegex limits ca_file to plain absolute paths, as it ends up in a shell command
+var podPathRegex = regexp.MustCompile(`^/[a-zA-Z0-9._/\-]+$`)
+
+func validateTargetTLS(target TargetConfig) error {
+	if !target.TLS {
+		if target.SNI != "" || target.CAFile != "" || target.InsecureSkipVerify {
+			return fmt.Errorf("target.sni, target.ca_file and target.insecure_skip_verify need target.tls: true")
+		}
+		return nil
+	}
+	if target.SNI != "" && (!IsValidTargetHost(target.SNI) || net.ParseIP(target.SNI) != nil) {
+		return fmt.Errorf("target.sni %q must be a hostname", target.SNI)
+	}
+	if target.CAFile != "" && !podPathRegex.MatchString(target.CAFile) {
+		return fmt.Errorf("target.ca_file %q must be an absolute path of letters, digits, '.', '_', '-' and '/'", target.CAFile)
+	}
+	if target.CAFile != "" && target.InsecureSkipVerify {
+		return fmt.Errorf("target.ca_file and target.insecure_skip_verify are mutually exclusive")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, expr string) *Schedule {
+	t.Helper()
+	s, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", expr, err)
+	}
+	return s
+}
+
+func TestNext(t *testing.T) {
+	// Wednesday 2025-01-15 10:30
+	base := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 9 * * MON-FRI", base, time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2025, 1, 17, 9, 0, 0, 0, time.UTC), time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", base, time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", base, time.Date(2025, 1, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 8-18/2 * * *", base, time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN,JUL *", base, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", base, time.Date(2025, 1, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 0", base, time.Date(2025, 1, 19, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 20th or a Friday)
+		{"0 0 20 * FRI", base, time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := mustParse(t, tt.expr).Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNext_Never(t *testing.T) {
+	s := mustParse(t, "0 0 30 2 *")
+	if got := s.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %v, want zero time", got)
+	}
+}
+
+func TestNext_KeepsLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	got := mustParse(t, "0 9 * * *").Next(time.Date(2025, 1, 15, 8, 0, 0, 0, loc))
+	if want := time.Date(2025, 1, 15, 9, 0, 0, 0, loc); !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 9 * *",
+		"0 9 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * * FOO",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
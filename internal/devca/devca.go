@@ -0,0 +1,210 @@
+// Package devca manages autotunnel's local development CA. It is created once
+// (by default under ~/.autotunnel/ca) and signs per-hostname certificates for
+// listeners that terminate TLS locally. Trust ca.pem to avoid browser warnings.
+package devca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	CertFileName = "ca.pem"
+	KeyFileName  = "ca-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 7 * 24 * time.Hour
+	// leafRenewBefore re-mints cached leaves this long before they expire
+	leafRenewBefore = time.Hour
+	// maxLeafCacheSize bounds the leaf cache; it's simply reset when full
+	maxLeafCacheSize = 1000
+)
+
+// DefaultDir returns ~/.autotunnel/ca
+func DefaultDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".autotunnel", "ca")
+}
+
+// CA signs leaf certificates for local TLS termination
+type CA struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certPath string
+
+	mu     sync.Mutex
+	leaves map[string]*tls.Certificate
+}
+
+// LoadOrCreate loads the CA from dir, creating a new one on first use
+func LoadOrCreate(dir string) (*CA, error) {
+	certPath := filepath.Join(dir, CertFileName)
+	keyPath := filepath.Join(dir, KeyFileName)
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if errors.Is(certErr, os.ErrNotExist) && errors.Is(keyErr, os.ErrNotExist) {
+		if err := create(dir, certPath, keyPath); err != nil {
+			return nil, fmt.Errorf("failed to create dev CA in %s: %w", dir, err)
+		}
+		certPEM, certErr = os.ReadFile(certPath)
+		keyPEM, keyErr = os.ReadFile(keyPath)
+	}
+	if certErr != nil {
+		return nil, fmt.Errorf("failed to read dev CA certificate: %w", certErr)
+	}
+	if keyErr != nil {
+		return nil, fmt.Errorf("failed to read dev CA key: %w", keyErr)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dev CA in %s: %w", dir, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dev CA certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("dev CA key must be ECDSA")
+	}
+
+	return &CA{
+		cert:     cert,
+		key:      key,
+		certPath: certPath,
+		leaves:   make(map[string]*tls.Certificate),
+	}, nil
+}
+
+func create(dir, certPath, keyPath string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject: pkix.Name{
+			Organization: []string{"autotunnel"},
+			CommonName:   "autotunnel Development CA",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644)
+}
+
+// CertPath is the CA certificate file to add to trust stores
+func (c *CA) CertPath() string {
+	return c.certPath
+}
+
+// Certificate returns the CA certificate
+func (c *CA) Certificate() *x509.Certificate {
+	return c.cert
+}
+
+// GetCertificate implements tls.Config.GetCertificate, minting a leaf for the SNI hostname
+func (c *CA) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	hostname := hello.ServerName
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	return c.LeafFor(hostname)
+}
+
+// LeafFor returns a (cached) certificate for hostname signed by the CA
+func (c *CA) LeafFor(hostname string) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if leaf, ok := c.leaves[hostname]; ok && time.Until(leaf.Leaf.NotAfter) > leafRenewBefore {
+		return leaf, nil
+	}
+
+	leaf, err := c.mintLeaf(hostname)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.leaves) >= maxLeafCacheSize {
+		c.leaves = make(map[string]*tls.Certificate)
+	}
+	c.leaves[hostname] = leaf
+	return leaf, nil
+}
+
+func (c *CA) mintLeaf(hostname string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject: pkix.Name{
+			Organization: []string{"autotunnel"},
+			CommonName:   hostname,
+		},
+		DNSNames:    []string{hostname},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der, c.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
@@ -0,0 +1,77 @@
+package devca
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrCreate_PersistsCA(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+
+	ca, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+	if !ca.Certificate().IsCA {
+		t.Error("Expected a CA certificate")
+	}
+
+	info, err := os.Stat(filepath.Join(dir, KeyFileName))
+	if err != nil {
+		t.Fatalf("Expected key file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected key file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	reloaded, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if !reloaded.Certificate().Equal(ca.Certificate()) {
+		t.Error("Expected the same CA after reload")
+	}
+}
+
+func TestLoadOrCreate_MissingKey(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadOrCreate(dir); err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+	_ = os.Remove(filepath.Join(dir, KeyFileName))
+
+	if _, err := LoadOrCreate(dir); err == nil {
+		t.Error("Expected error when only the certificate exists")
+	}
+}
+
+func TestCA_GetCertificate(t *testing.T) {
+	ca, err := LoadOrCreate(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadOrCreate failed: %v", err)
+	}
+
+	cert, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "api.localhost"})
+	if err != nil {
+		t.Fatalf("GetCertificate failed: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "api.localhost", Roots: roots}); err != nil {
+		t.Errorf("Leaf does not verify against the CA: %v", err)
+	}
+
+	again, _ := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "api.localhost"})
+	if again != cert {
+		t.Error("Expected cached leaf to be reused")
+	}
+
+	fallback, err := ca.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil || fallback.Leaf.DNSNames[0] != "localhost" {
+		t.Errorf("Expected localhost certificate without SNI, got %v (err %v)", fallback, err)
+	}
+}
@@ -0,0 +1,94 @@
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKey matches config keys whose values must not leave the machine: secret
+// names as a whole word of the key (so a toleration's "key" or "monkey" are
+// kept), webhook URLs, which are credentials themselves, and environment maps
+// and lists, which are redacted wholesale
+var secretKey = regexp.MustCompile(`(?i)(^|_)(token|tokens|password|secret|secrets|api_key|private_key|secret_key|access_key|webhook)$|^(exec_)?env$`)
+
+// Redacted replaces secret values in a YAML config (tokens, passwords, keys,
+// webhooks, exec_env and env values) with "REDACTED", and so the user and password of URLs
+// like a context's proxy. Keys ending in _env or _file only name where a secret
+// lives and are kept. Comments and layout are preserved.
+func Redacted(config []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, err
+	}
+	redactNode(&doc, false)
+	return yaml.Marshal(&doc)
+}
+
+func redactNode(n *yaml.Node, secret bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if secret && n.Value != "" {
+			n.Value = "REDACTED"
+			n.Style = 0
+			n.Tag = "!!str"
+		} else if u, ok := urlWithUserinfo(n.Value); ok {
+			u.User = url.User("REDACTED")
+			n.Value = u.String()
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			redactNode(n.Content[i+1], secret || secretKey.MatchString(n.Content[i].Value))
+		}
+	default:
+		for _, c := range n.Content {
+			redactNode(c, secret)
+		}
+	}
+}
+
+// urlWithUserinfo parses s if it is a URL carrying a user or password
+func urlWithUserinfo(s string) (*url.URL, bool) {
+	if !strings.Contains(s, "://") || !strings.Contains(s, "@") {
+		return nil, false
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return nil, false
+	}
+	return u, true
+}
+
+// WriteBundle writes files as a tar.gz archive, sorted by name
+func WriteBundle(w io.Writer, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,93 @@
+// Package diag keeps what a bug report needs: recent log lines, panics recovered
+// from connection goroutines, and goroutine dumps, served on the status API and
+// collected by `autotunnel debug bundle`.
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// maxPanics is how many recovered panics are kept for the status API
+const maxPanics = 20
+
+// PanicRecord is one panic recovered from a connection goroutine
+type PanicRecord struct {
+	Component string    `json:"component"`
+	Value     string    `json:"value"`
+	Stack     string    `json:"stack"`
+	Time      time.Time `json:"time"`
+}
+
+var (
+	panicsMu sync.Mutex
+	panics   []PanicRecord
+)
+
+// Recover stops a panic in the calling goroutine from taking the process down.
+// Use it as `defer diag.Recover("tcp:5432")` at the top of a connection goroutine;
+// the panic is logged with its stack and kept for the status API.
+func Recover(component string) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	log.Printf("[%s] Error: recovered from panic: %v\n%s", component, v, stack)
+
+	panicsMu.Lock()
+	defer panicsMu.Unlock()
+	panics = append(panics, PanicRecord{Component: component, Value: fmt.Sprint(v), Stack: stack, Time: time.Now()})
+	if len(panics) > maxPanics {
+		panics = panics[len(panics)-maxPanics:]
+	}
+}
+
+// Panics returns the recovered panics, oldest first
+func Panics() []PanicRecord {
+	panicsMu.Lock()
+	defer panicsMu.Unlock()
+	return append([]PanicRecord{}, panics...)
+}
+
+// Goroutines returns a dump of every goroutine's stack, as printed on a crash
+func Goroutines() string {
+	var buf bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&buf, 2)
+	return buf.String()
+}
+
+// RegisterHandlers adds the "panics" status section and the debug endpoints
+// GET /debug/panics, GET /debug/logs and GET /debug/goroutines used by
+// `autotunnel debug bundle`. The HTTP server only lets team admins reach them,
+// or local clients outside team mode.
+func RegisterHandlers(h *admin.Handler) {
+	h.AddSection("panics", func() any { return len(Panics()) })
+	h.HandleFunc("GET /debug/panics", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, Panics())
+	})
+	h.HandleFunc("GET /debug/logs", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, LogLines{Lines: logs.Lines()})
+	})
+	h.HandleFunc("GET /debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, GoroutineDump{Dump: Goroutines()})
+	})
+}
+
+// LogLines is the response of GET /debug/logs
+type LogLines struct {
+	Lines []string `json:"lines"`
+}
+
+// GoroutineDump is the response of GET /debug/goroutines
+type GoroutineDump struct {
+	Dump string `json:"dump"`
+}
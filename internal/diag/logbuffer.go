@@ -0,0 +1,61 @@
+package diag
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLogLines is how many recent log lines CaptureLogs keeps
+const DefaultLogLines = 2000
+
+// LogBuffer keeps the last lines written to it, each with the time it was written
+type LogBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func NewLogBuffer(size int) *LogBuffer {
+	return &LogBuffer{lines: make([]string, size)}
+}
+
+// Write takes one log line per call, as the log package writes them
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	line := time.Now().Format(time.RFC3339) + " " + strings.TrimRight(string(p), "\n")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, oldest first
+func (b *LogBuffer) Lines() []string {
+	if b == nil {
+		return []string{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]string{}, b.lines[:b.next]...)
+	}
+	return append(append([]string{}, b.lines[b.next:]...), b.lines[:b.next]...)
+}
+
+// logs is the buffer CaptureLogs installed, served on GET /debug/logs
+var logs *LogBuffer
+
+// CaptureLogs keeps a copy of everything logged from now on, next to the
+// current log output. Call it once, after the log output is set up.
+func CaptureLogs(size int) {
+	logs = NewLogBuffer(size)
+	log.SetOutput(io.MultiWriter(log.Writer(), logs))
+}
@@ -0,0 +1,146 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// grpcHealthMethod is the standard health service's Check method
+// (grpc/health/v1/health.proto)
+const grpcHealthMethod = "/grpc.health.v1.Health/Check"
+
+// HealthCheckResponse.ServingStatus values
+var servingStatuses = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// newGRPCClient returns a client speaking HTTP/2 only: prior knowledge (h2c) to
+// http tunnels and ALPN h2 to https ones
+func newGRPCClient(tlsSettings config.TLSConfig) *http.Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{
+		Transport: &http.Transport{
+			Protocols:         &protocols,
+			TLSClientConfig:   tlsSettings.Apply(&tls.Config{InsecureSkipVerify: true}),
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+// probeGRPC calls Health/Check through the tunnel and returns the HTTP status and
+// the serving status. Anything but SERVING is an error.
+func (c *Checker) probeGRPC(tun tunnelmgr.TunnelHandle, hostname string, hc config.HealthCheckConfig) (int, string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, hc.GetTimeout())
+	defer cancel()
+
+	url := fmt.Sprintf("%s://127.0.0.1:%d%s", tun.Scheme(), tun.LocalPort(), grpcHealthMethod)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(grpcFrame(healthCheckRequest(hc.Service))))
+	if err != nil {
+		return 0, "", 0, err
+	}
+	req.Host = hostname
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set("User-Agent", "autotunnel-health-check")
+
+	start := time.Now()
+	resp, err := c.grpcClient.Do(req)
+	if err != nil {
+		return 0, "", time.Since(start), err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	latency := time.Since(start)
+	if err != nil {
+		return resp.StatusCode, "", latency, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, "", latency, fmt.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	// trailers, or headers for a trailers-only response
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return resp.StatusCode, "", latency, fmt.Errorf("grpc-status %s: %s", status, message)
+	}
+
+	serving, err := parseHealthCheckResponse(body)
+	if err != nil {
+		return resp.StatusCode, "", latency, err
+	}
+	if serving != "SERVING" {
+		return resp.StatusCode, serving, latency, fmt.Errorf("serving status %s", serving)
+	}
+	return resp.StatusCode, serving, latency, nil
+}
+
+// healthCheckRequest encodes HealthCheckRequest{service}
+func healthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	msg := []byte{0x0a} // field 1, length-delimited
+	msg = binary.AppendUvarint(msg, uint64(len(service)))
+	return append(msg, service...)
+}
+
+// grpcFrame prefixes msg with the uncompressed length-prefixed message header
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+// parseHealthCheckResponse returns the serving status in a framed HealthCheckResponse
+func parseHealthCheckResponse(body []byte) (string, error) {
+	if len(body) < 5 || body[0] != 0 {
+		return "", fmt.Errorf("malformed gRPC response")
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < size {
+		return "", fmt.Errorf("truncated gRPC response")
+	}
+	msg := body[5 : 5+size]
+
+	// the only field is status (1, varint); proto3 leaves it out when UNKNOWN
+	var status uint64
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", fmt.Errorf("malformed HealthCheckResponse")
+		}
+		msg = msg[n:]
+		if tag&7 != 0 {
+			return "", fmt.Errorf("unexpected field %d in HealthCheckResponse", tag>>3)
+		}
+		value, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return "", fmt.Errorf("malformed HealthCheckResponse")
+		}
+		msg = msg[n:]
+		if tag>>3 == 1 {
+			status = value
+		}
+	}
+	if name, ok := servingStatuses[status]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("%d", status), nil
+}
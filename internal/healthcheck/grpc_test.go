@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// grpcBackend answers Health/Check over h2c with status for the requested service
+func grpcBackend(t *testing.T, statuses map[string]uint64) *mockTunnel {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != grpcHealthMethod || r.Header.Get("Content-Type") != "application/grpc" {
+			http.Error(w, "not a gRPC health check", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		service := ""
+		if len(body) > 7 {
+			service = string(body[7:]) // frame header, tag, length
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		status, ok := statuses[service]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5") // NOT_FOUND, trailers-only
+			return
+		}
+		_, _ = w.Write(grpcFrame(binary.AppendUvarint([]byte{0x08}, status)))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return &mockTunnel{localPort: srv.Listener.Addr().(*net.TCPAddr).Port}
+}
+
+func TestChecker_GRPC(t *testing.T) {
+	tun := grpcBackend(t, map[string]uint64{"": 1, "payments": 2})
+	tests := []struct {
+		name        string
+		service     string
+		wantStatus  string
+		wantServing string
+		wantErr     string
+	}{
+		{"server serving", "", StatusHealthy, "SERVING", ""},
+		{"service not serving", "payments", StatusUnhealthy, "NOT_SERVING", "serving status NOT_SERVING"},
+		{"unknown service", "ledger", StatusUnhealthy, "", "grpc-status 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HTTP: config.HTTPConfig{K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+				"api.localhost": {Context: "test", Namespace: "ns", Service: "api", Port: 50051, Protocol: config.ProtocolGRPC,
+					HealthCheck: &config.HealthCheckConfig{Service: tt.service}},
+			}}}}
+			c := NewChecker(cfg, mockTunnels{"api.localhost": tun})
+
+			c.check("api.localhost", *cfg.HTTP.K8s.Routes["api.localhost"].HealthCheck)
+
+			res := c.Results()[0]
+			if res.Status != tt.wantStatus || res.ServingStatus != tt.wantServing {
+				t.Errorf("got status %s / %q, want %s / %q", res.Status, res.ServingStatus, tt.wantStatus, tt.wantServing)
+			}
+			if res.Path != grpcHealthMethod {
+				t.Errorf("Path = %s, want %s", res.Path, grpcHealthMethod)
+			}
+			if tt.wantErr != "" && !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("Error = %q, want it to contain %q", res.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewChecker_GRPCWithoutHealthCheck(t *testing.T) {
+	cfg := &config.Config{HTTP: config.HTTPConfig{K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "test", Namespace: "ns", Service: "api", Port: 50051, Protocol: config.ProtocolGRPC},
+	}}}}
+	c := NewChecker(cfg, mockTunnels{})
+	if c == nil {
+		t.Fatal("Expected gRPC routes to be checked without a health_check block")
+	}
+	if hc := c.checks["api.localhost"]; hc.GetInterval() != config.DefaultHealthCheckInterval {
+		t.Errorf("Expected the default interval, got %v", hc.GetInterval())
+	}
+}
@@ -0,0 +1,227 @@
+// Package healthcheck runs the synthetic HTTP checks configured with
+// http.k8s.routes[].health_check against routes whose tunnel is up. Routes with
+// protocol: grpc are asked over the gRPC health protocol instead.
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// Tunnels looks up a route's tunnel without creating or touching it, so checks
+// never start a tunnel or keep one from idling out
+type Tunnels interface {
+	RunningTunnel(hostname string) (tunnelmgr.TunnelHandle, bool)
+}
+
+// Check results
+const (
+	StatusUnknown   = "unknown"   // not checked yet
+	StatusIdle      = "idle"      // tunnel not running, nothing to check
+	StatusHealthy   = "healthy"   // got the expected status
+	StatusUnhealthy = "unhealthy" // wrong status or request failed
+)
+
+// Result is the latest outcome of a route's check
+type Result struct {
+	Route               string        `json:"route"`
+	Path                string        `json:"path"`
+	Status              string        `json:"status"`
+	HTTPStatus          int           `json:"http_status,omitempty"`
+	ServingStatus       string        `json:"serving_status,omitempty"` // gRPC routes: SERVING, NOT_SERVING, ...
+	Latency             time.Duration `json:"latency,omitempty"`
+	Error               string        `json:"error,omitempty"`
+	CheckedAt           time.Time     `json:"checked_at,omitzero"`
+	ConsecutiveFailures int           `json:"consecutive_failures,omitempty"`
+}
+
+// Checker runs one check loop per route with a health_check block or protocol: grpc
+type Checker struct {
+	tunnels    Tunnels
+	checks     map[string]config.HealthCheckConfig
+	grpc       map[string]bool // routes checked with the gRPC health protocol
+	client     *http.Client
+	grpcClient *http.Client
+
+	mu      sync.RWMutex
+	results map[string]*Result
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewChecker returns a checker for cfg's HTTP routes, or nil if none has a
+// health_check. gRPC routes are checked with the defaults even without one.
+func NewChecker(cfg *config.Config, tunnels Tunnels) *Checker {
+	checks := make(map[string]config.HealthCheckConfig)
+	grpc := make(map[string]bool)
+	for hostname, route := range cfg.HTTP.K8s.Routes {
+		if route.HealthCheck != nil {
+			checks[hostname] = *route.HealthCheck
+		} else if route.GRPC() {
+			checks[hostname] = config.HealthCheckConfig{}
+		}
+		if route.GRPC() {
+			grpc[hostname] = true
+		}
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Checker{
+		tunnels:    tunnels,
+		checks:     checks,
+		grpc:       grpc,
+		grpcClient: newGRPCClient(cfg.TLS),
+		client: &http.Client{
+			// backends serve cluster-internal certificates
+			Transport: &http.Transport{
+				TLSClientConfig:   cfg.TLS.Apply(&tls.Config{InsecureSkipVerify: true}),
+				DisableKeepAlives: true, // don't hold port-forward streams open between checks
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		results: make(map[string]*Result, len(checks)),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for hostname, hc := range checks {
+		path := hc.Path
+		if grpc[hostname] {
+			path = grpcHealthMethod
+		}
+		c.results[hostname] = &Result{Route: hostname, Path: path, Status: StatusUnknown}
+	}
+	return c
+}
+
+func (c *Checker) Start() {
+	for hostname, hc := range c.checks {
+		c.wg.Add(1)
+		go c.loop(hostname, hc)
+	}
+}
+
+func (c *Checker) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+func (c *Checker) loop(hostname string, hc config.HealthCheckConfig) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(hc.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		c.check(hostname, hc)
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs one check and logs when a route turns unhealthy or recovers
+func (c *Checker) check(hostname string, hc config.HealthCheckConfig) {
+	tun, ok := c.tunnels.RunningTunnel(hostname)
+	if !ok {
+		c.mu.Lock()
+		c.results[hostname].Status = StatusIdle
+		c.mu.Unlock()
+		return
+	}
+
+	var code int
+	var serving string
+	var latency time.Duration
+	var err error
+	if c.grpc[hostname] {
+		code, serving, latency, err = c.probeGRPC(tun, hostname, hc)
+	} else {
+		code, latency, err = c.probe(tun, hostname, hc)
+		if err == nil && code != hc.GetExpectedStatus() {
+			err = fmt.Errorf("expected status %d, got %d", hc.GetExpectedStatus(), code)
+		}
+	}
+	if c.ctx.Err() != nil {
+		return // shutting down, not the backend's fault
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := c.results[hostname]
+	prev := res.Status
+	res.HTTPStatus = code
+	res.ServingStatus = serving
+	res.Latency = latency
+	res.CheckedAt = time.Now()
+	if err != nil {
+		res.Status = StatusUnhealthy
+		res.Error = err.Error()
+		res.ConsecutiveFailures++
+	} else {
+		res.Status = StatusHealthy
+		res.Error = ""
+		res.ConsecutiveFailures = 0
+	}
+
+	switch {
+	case res.Status == StatusUnhealthy && prev != StatusUnhealthy:
+		log.Printf("[health] [%s] Warning: %s is unhealthy: %v", hostname, res.Path, err)
+	case res.Status == StatusHealthy && prev == StatusUnhealthy:
+		log.Printf("[health] [%s] %s is healthy again (status %d, %v)", hostname, res.Path, code, latency.Round(time.Millisecond))
+	}
+}
+
+// probe requests hc.Path through the tunnel with the route's hostname as Host
+func (c *Checker) probe(tun tunnelmgr.TunnelHandle, hostname string, hc config.HealthCheckConfig) (int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, hc.GetTimeout())
+	defer cancel()
+
+	url := fmt.Sprintf("%s://127.0.0.1:%d%s", tun.Scheme(), tun.LocalPort(), hc.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Host = hostname
+	req.Header.Set("User-Agent", "autotunnel-health-check")
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return 0, latency, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, latency, nil
+}
+
+// Results returns the latest result of every check, sorted by route
+func (c *Checker) Results() []Result {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	results := make([]Result, 0, len(c.results))
+	for _, res := range c.results {
+		results = append(results, *res)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Route < results[j].Route })
+	return results
+}
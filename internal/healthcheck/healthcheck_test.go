@@ -0,0 +1,145 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnel"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+type mockTunnel struct {
+	localPort int
+}
+
+func (m *mockTunnel) IsRunning() bool                 { return true }
+func (m *mockTunnel) Start(ctx context.Context) error { return nil }
+func (m *mockTunnel) Stop()                           {}
+func (m *mockTunnel) LocalPort() int                  { return m.localPort }
+func (m *mockTunnel) LocalPortFor(port int) int       { return m.localPort }
+func (m *mockTunnel) Scheme() string                  { return "http" }
+func (m *mockTunnel) Touch()                          {}
+func (m *mockTunnel) IdleDuration() time.Duration     { return 0 }
+func (m *mockTunnel) State() tunnel.State             { return tunnel.StateRunning }
+func (m *mockTunnel) LastError() error                { return nil }
+
+type mockTunnels map[string]tunnelmgr.TunnelHandle
+
+func (m mockTunnels) RunningTunnel(hostname string) (tunnelmgr.TunnelHandle, bool) {
+	tun, ok := m[hostname]
+	return tun, ok
+}
+
+func checkConfig(checks map[string]*config.HealthCheckConfig) *config.Config {
+	routes := make(map[string]config.K8sRouteConfig, len(checks))
+	for hostname, hc := range checks {
+		routes[hostname] = config.K8sRouteConfig{Context: "test", Namespace: "ns", Service: "svc", Port: 80, HealthCheck: hc}
+	}
+	return &config.Config{HTTP: config.HTTPConfig{K8s: config.K8sConfig{Routes: routes}}}
+}
+
+// backend serves status for every request and records the Host header
+func backend(t *testing.T, status int, hosts chan<- string) *mockTunnel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hosts != nil {
+			hosts <- r.Host + r.URL.Path
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return &mockTunnel{localPort: srv.Listener.Addr().(*net.TCPAddr).Port}
+}
+
+func TestNewChecker_NoChecks(t *testing.T) {
+	cfg := checkConfig(map[string]*config.HealthCheckConfig{"app.localhost": nil})
+	if c := NewChecker(cfg, mockTunnels{}); c != nil {
+		t.Error("Expected nil checker when no route has a health_check")
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	hosts := make(chan string, 1)
+	tests := []struct {
+		name       string
+		hc         config.HealthCheckConfig
+		status     int
+		tunnel     bool
+		wantStatus string
+		wantErr    string
+	}{
+		{"healthy with default status", config.HealthCheckConfig{Path: "/healthz"}, 200, true, StatusHealthy, ""},
+		{"expected status", config.HealthCheckConfig{Path: "/ready", ExpectedStatus: 204}, 204, true, StatusHealthy, ""},
+		{"wrong status", config.HealthCheckConfig{Path: "/healthz"}, 503, true, StatusUnhealthy, "expected status 200, got 503"},
+		{"tunnel idle", config.HealthCheckConfig{Path: "/healthz"}, 200, false, StatusIdle, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnels := mockTunnels{}
+			if tt.tunnel {
+				tunnels["app.localhost"] = backend(t, tt.status, hosts)
+			}
+			c := NewChecker(checkConfig(map[string]*config.HealthCheckConfig{"app.localhost": &tt.hc}), tunnels)
+
+			c.check("app.localhost", tt.hc)
+
+			res := c.Results()[0]
+			if res.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", res.Status, tt.wantStatus)
+			}
+			if tt.wantErr != "" && !strings.Contains(res.Error, tt.wantErr) {
+				t.Errorf("Error = %q, want it to contain %q", res.Error, tt.wantErr)
+			}
+			if tt.tunnel {
+				if got := <-hosts; got != "app.localhost"+tt.hc.Path {
+					t.Errorf("Backend got %s, want the route hostname as Host", got)
+				}
+			}
+		})
+	}
+}
+
+func TestChecker_ConsecutiveFailuresAndRecovery(t *testing.T) {
+	hc := config.HealthCheckConfig{Path: "/healthz"}
+	failing := backend(t, 500, nil)
+	tunnels := mockTunnels{"app.localhost": failing}
+	c := NewChecker(checkConfig(map[string]*config.HealthCheckConfig{"app.localhost": &hc}), tunnels)
+
+	c.check("app.localhost", hc)
+	c.check("app.localhost", hc)
+	if res := c.Results()[0]; res.ConsecutiveFailures != 2 || res.HTTPStatus != 500 {
+		t.Errorf("After 2 failures got %+v", res)
+	}
+
+	tunnels["app.localhost"] = backend(t, 200, nil)
+	c.check("app.localhost", hc)
+	res := c.Results()[0]
+	if res.Status != StatusHealthy || res.ConsecutiveFailures != 0 || res.Error != "" {
+		t.Errorf("After recovery got %+v", res)
+	}
+	if res.CheckedAt.IsZero() {
+		t.Error("Expected CheckedAt to be set")
+	}
+}
+
+func TestChecker_StartStop(t *testing.T) {
+	hosts := make(chan string, 1)
+	hc := &config.HealthCheckConfig{Path: "/healthz", Interval: time.Second}
+	c := NewChecker(checkConfig(map[string]*config.HealthCheckConfig{"app.localhost": hc}),
+		mockTunnels{"app.localhost": backend(t, 200, hosts)})
+
+	c.Start()
+	select {
+	case <-hosts:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a check right after Start")
+	}
+	c.Stop()
+}
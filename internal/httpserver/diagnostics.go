@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/tunnel"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// diagnosticsDocsURL explains each failed step and what to check
+const diagnosticsDocsURL = "https://github.com/atas/autotunnel#troubleshooting"
+
+// diagnostic is the structured block added to 502 responses with http.debug_errors
+type diagnostic struct {
+	Host      string `json:"host"`
+	RequestID string `json:"request_id"`
+	Step      string `json:"step"`
+	Error     string `json:"error"`
+	K8sReason string `json:"k8s_reason,omitempty"`
+	Context   string `json:"context,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Docs      string `json:"docs"`
+}
+
+// writeTunnelError answers with a 502 that carries the request ID. With
+// http.debug_errors it adds which step failed and what the route points at:
+// as JSON when the client asks for it, otherwise as a text block after the message.
+func (s *Server) writeTunnelError(w http.ResponseWriter, r *http.Request, host, msg string, err error) {
+	requestID := r.Header.Get(RequestIDHeader)
+	w.Header().Set(RequestIDHeader, requestID)
+	if !s.config.HTTP.DebugErrors {
+		http.Error(w, msg+"\nRequest ID: "+requestID, http.StatusBadGateway)
+		return
+	}
+
+	d := s.diagnose(host, err)
+	d.RequestID = requestID
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{"error": msg, "diagnostics": d})
+		return
+	}
+	http.Error(w, msg+"\n\n"+d.String(), http.StatusBadGateway)
+}
+
+func (s *Server) diagnose(host string, err error) diagnostic {
+	d := diagnostic{
+		Host:      host,
+		Step:      tunnel.FailedStep(err),
+		Error:     err.Error(),
+		K8sReason: k8sutil.Reason(err),
+		Docs:      diagnosticsDocsURL,
+	}
+	if d.Step == "" {
+		d.Step = "proxy"
+	}
+
+	route, ok := s.routeFor(host)
+	if !ok {
+		return d
+	}
+	d.Context = route.Context
+	d.Namespace = route.Namespace
+	d.Target = targetDisplay(route)
+	return d
+}
+
+// routeFor returns host's static, dynamic or host pattern route
+func (s *Server) routeFor(host string) (config.K8sRouteConfig, bool) {
+	if route, ok := s.config.HTTP.K8s.Routes[host]; ok {
+		return route, true
+	}
+	if parsed, valid := tunnelmgr.DynamicRoute(s.config, host, "http"); valid {
+		return *parsed, s.config.HTTP.K8s.DynamicHostAllow.Allows(*parsed) == nil
+	}
+	return s.config.HTTP.K8s.MatchHostPattern(host)
+}
+
+// targetDisplay renders a route's target like "svc/api:80" or "pod/api-0:8080"
+func targetDisplay(route config.K8sRouteConfig) string {
+	if route.Pod != "" {
+		return fmt.Sprintf("pod/%s:%d", route.Pod, route.Port)
+	}
+	return fmt.Sprintf("svc/%s:%d", route.Service, route.Port)
+}
+
+// String renders the diagnostics as aligned "key: value" lines
+func (d diagnostic) String() string {
+	var b strings.Builder
+	b.WriteString("--- autotunnel diagnostics ---\n")
+	for _, field := range [][2]string{
+		{"host", d.Host},
+		{"request_id", d.RequestID},
+		{"step", d.Step},
+		{"error", d.Error},
+		{"k8s_reason", d.K8sReason},
+		{"context", d.Context},
+		{"namespace", d.Namespace},
+		{"target", d.Target},
+		{"docs", d.Docs},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&b, "%-11s %s\n", field[0]+":", field[1])
+		}
+	}
+	return b.String()
+}
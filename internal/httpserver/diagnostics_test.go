@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnel"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func debugErrorsServer(startErr error) *Server {
+	cfg := testHTTPConfig()
+	cfg.HTTP.DebugErrors = true
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "prod", Namespace: "apps", Service: "api", Port: 80},
+	}
+	return NewServer(cfg, &mockManager{tunnel: &mockTunnel{startErr: startErr}})
+}
+
+func TestServeHTTP_DebugErrors_Text(t *testing.T) {
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "services"}, "api")
+	startErr := &tunnel.StepError{Step: tunnel.StepServiceGet, Err: fmt.Errorf("failed to get service: %w", notFound)}
+	server := debugErrorsServer(startErr)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "api.localhost"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("Expected status 502, got %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"Failed to start tunnel",
+		"--- autotunnel diagnostics ---",
+		"step:       service get",
+		"k8s_reason: NotFound",
+		"context:    prod",
+		"namespace:  apps",
+		"target:     svc/api:80",
+		"docs:       " + diagnosticsDocsURL,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestServeHTTP_DebugErrors_JSON(t *testing.T) {
+	server := debugErrorsServer(&tunnel.StepError{Step: tunnel.StepSPDYDial, Err: errors.New("port forward failed: EOF")})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "api.localhost"
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	var resp struct {
+		Error       string     `json:"error"`
+		Diagnostics diagnostic `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected a JSON body: %v\n%s", err, w.Body.String())
+	}
+	if resp.Diagnostics.Step != tunnel.StepSPDYDial || resp.Diagnostics.Target != "svc/api:80" {
+		t.Errorf("Unexpected diagnostics: %+v", resp.Diagnostics)
+	}
+}
+
+func TestServeHTTP_DebugErrors_Disabled(t *testing.T) {
+	server := debugErrorsServer(&tunnel.StepError{Step: tunnel.StepPodList, Err: errors.New("no running pods")})
+	server.config.HTTP.DebugErrors = false
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "api.localhost"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if strings.Contains(w.Body.String(), "diagnostics") {
+		t.Errorf("Expected no diagnostics without debug_errors, got:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// echoResponse is what http.echo_host answers: the request as autotunnel received it
+type echoResponse struct {
+	Host       string              `json:"host"`
+	Method     string              `json:"method"`
+	URI        string              `json:"uri"`
+	Proto      string              `json:"proto"`
+	TLS        bool                `json:"tls"`
+	RemoteAddr string              `json:"remote_addr"`
+	Headers    map[string][]string `json:"headers"`
+	BodyBytes  int64               `json:"body_bytes"`
+}
+
+// isEchoHost reports whether host is http.echo_host
+func (s *Server) isEchoHost(host string) bool {
+	return s.config.HTTP.EchoHost != "" && strings.EqualFold(host, s.config.HTTP.EchoHost)
+}
+
+// serveEcho describes r back to the client, to check proxy, DNS and /etc/hosts
+// setups without involving a cluster
+func serveEcho(w http.ResponseWriter, r *http.Request) {
+	n, _ := io.Copy(io.Discard, r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(echoResponse{
+		Host:       r.Host,
+		Method:     r.Method,
+		URI:        r.RequestURI,
+		Proto:      r.Proto,
+		TLS:        r.TLS != nil,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    r.Header,
+		BodyBytes:  n,
+	})
+}
@@ -0,0 +1,42 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer_ServesEchoHost(t *testing.T) {
+	mockMgr := &mockManager{}
+	cfg := testHTTPConfig()
+	cfg.HTTP.EchoHost = "echo.localhost"
+	server := NewServer(cfg, mockMgr)
+
+	req := httptest.NewRequest("POST", "/some/path?q=1", strings.NewReader("hello"))
+	req.Host = "Echo.localhost:8989"
+	req.Header.Set("X-Test", "yes")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var echo echoResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &echo); err != nil {
+		t.Fatalf("Response is not JSON: %v\n%s", err, w.Body.String())
+	}
+	if echo.Method != "POST" || echo.URI != "/some/path?q=1" || echo.Host != "Echo.localhost:8989" {
+		t.Errorf("unexpected echo %+v", echo)
+	}
+	if echo.BodyBytes != 5 || echo.Headers["X-Test"][0] != "yes" {
+		t.Errorf("unexpected body size or headers in %+v", echo)
+	}
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mockMgr.getCalls)
+	}
+	if !server.terminatesTLS("echo.localhost") {
+		t.Error("Expected TLS for the echo host to be terminated locally")
+	}
+}
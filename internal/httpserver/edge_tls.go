@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+
+	"github.com/atas/autotunnel/internal/devca"
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+// devCA loads the dev CA on first use. HTTP/3 and edge-terminated routes share it.
+func (s *Server) devCA() (*devca.CA, error) {
+	s.caOnce.Do(func() {
+		s.ca, s.caErr = devca.LoadOrCreate(devca.DefaultDir())
+	})
+	return s.ca, s.caErr
+}
+
+// terminatesTLS reports whether TLS for hostname is terminated here (route tls:
+// terminate, http.echo_host, http.redirects, http.static or http.local)
+func (s *Server) terminatesTLS(hostname string) bool {
+	if s.isEchoHost(hostname) {
+		return true
+	}
+	if _, ok := s.redirectTarget(hostname); ok {
+		return true
+	}
+	if _, ok := s.staticRoute(hostname); ok {
+		return true
+	}
+	if _, ok := s.local[hostname]; ok {
+		return true
+	}
+	route, ok := s.routeFor(hostname)
+	return ok && route.TerminatesTLS()
+}
+
+// handleEdgeTLS terminates TLS with a dev CA certificate and hands the connection
+// to the edge HTTP server. Requests then take the normal ServeHTTP path (team auth,
+// X-Forwarded-* headers, logging); https backends are re-encrypted by the proxy.
+// Returns false if the connection wasn't handed off and should be closed by the caller.
+func (s *Server) handleEdgeTLS(conn *muxconn.Conn, clientHello []byte, sni string) bool {
+	ca, err := s.devCA()
+	if err != nil {
+		log.Printf("[tls] [%s] Cannot terminate TLS: %v", sni, err)
+		s.sendTLSErrorPage(conn.Conn, clientHello, sni, tlsErrorTermination, fmt.Sprintf("Cannot terminate TLS: %v", err))
+		return false
+	}
+
+	tlsConfig := s.config.TLS.Apply(&tls.Config{
+		GetCertificate: ca.GetCertificate,
+		NextProtos:     []string{"http/1.1"},
+	})
+	// client_cert authorizers verify the certificate themselves, against their own CA
+	if s.authz.WantsClientCerts() {
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+	tlsConn := tls.Server(&replayConn{Conn: conn.Conn, initial: clientHello}, tlsConfig)
+
+	if s.config.Verbose {
+		log.Printf("[tls] [%s] Terminating TLS locally", sni)
+	}
+
+	return s.listener.edge.Dispatch(tlsConn)
+}
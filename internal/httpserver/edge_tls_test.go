@@ -0,0 +1,219 @@
+package httpserver
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+func TestHandleTLSConnection_EdgeTerminate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var backendTLS bool
+	var forwardedProto string
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendTLS = r.TLS != nil
+		forwardedProto = r.Header.Get("X-Forwarded-Proto")
+		_, _ = w.Write([]byte("edge ok"))
+	}))
+	defer backend.Close()
+
+	cfg := testHTTPConfig()
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"secure.localhost": {Context: "ctx", Namespace: "default", Service: "api", Port: 443, Scheme: "https", TLS: config.TLSModeTerminate},
+	}
+	mockMgr := &mockManager{tunnel: &mockTunnel{
+		running:   true,
+		localPort: backend.Listener.Addr().(*net.TCPAddr).Port,
+		scheme:    "https",
+	}}
+	server := NewServer(cfg, mockMgr)
+
+	// Wire up the edge server the way Start does, without binding http.listen
+	mux, err := newMuxListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("newMuxListener failed: %v", err)
+	}
+	server.listener = mux
+	edge := &http.Server{Handler: server}
+	go func() { _ = edge.Serve(mux.edgeListener()) }()
+	defer func() {
+		// same order as Shutdown: the listener unblocks Serve before Close waits for it
+		_ = mux.Close()
+		_ = edge.Close()
+	}()
+
+	client, serverConn := net.Pipe()
+	defer client.Close()
+	go server.handleTLSConnection(muxconn.NewConn(serverConn))
+
+	tlsClient := tls.Client(client, &tls.Config{
+		ServerName: "secure.localhost",
+		// The CA doesn't exist until the handshake starts, so verify against it afterwards
+		InsecureSkipVerify: true,
+	})
+	if err := tlsClient.Handshake(); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+
+	caPEM, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".autotunnel", "ca", "ca.pem"))
+	if err != nil {
+		t.Fatalf("Expected dev CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	peer := tlsClient.ConnectionState().PeerCertificates[0]
+	if _, err := peer.Verify(x509.VerifyOptions{DNSName: "secure.localhost", Roots: roots}); err != nil {
+		t.Errorf("Edge certificate not signed by the dev CA: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://secure.localhost/", nil)
+	if err := req.Write(tlsClient); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(tlsClient), req)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "edge ok" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, body)
+	}
+	if !backendTLS {
+		t.Error("Expected the request to be re-encrypted to the https backend")
+	}
+	if forwardedProto != "https" {
+		t.Errorf("Expected X-Forwarded-Proto https, got %q", forwardedProto)
+	}
+}
+
+func TestHandleTLSConnection_EdgeTerminateDynamicHost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var backendTLS bool
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendTLS = r.TLS != nil
+		_, _ = w.Write([]byte("dynamic ok"))
+	}))
+	defer backend.Close()
+
+	cfg := testHTTPConfig()
+	cfg.HTTP.K8s.DynamicHost = "k8s.localhost"
+	cfg.HTTP.K8s.DynamicHostTLS = config.TLSModeTerminate
+	cfg.HTTP.K8s.DynamicHostScheme = "https"
+	mockMgr := &mockManager{tunnel: &mockTunnel{
+		running:   true,
+		localPort: backend.Listener.Addr().(*net.TCPAddr).Port,
+		scheme:    "https",
+	}}
+	server := NewServer(cfg, mockMgr)
+	mux, err := newMuxListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("newMuxListener failed: %v", err)
+	}
+	server.listener = mux
+	edge := &http.Server{Handler: server}
+	go func() { _ = edge.Serve(mux.edgeListener()) }()
+	defer func() {
+		_ = mux.Close()
+		_ = edge.Close()
+	}()
+
+	host := "argocd-server-443.svc.argocd.ns.microk8s.cx.k8s.localhost"
+	client, serverConn := net.Pipe()
+	defer client.Close()
+	go server.handleTLSConnection(muxconn.NewConn(serverConn))
+
+	tlsClient := tls.Client(client, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err := tlsClient.Handshake(); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if peer := tlsClient.ConnectionState().PeerCertificates[0]; peer.VerifyHostname(host) != nil {
+		t.Errorf("Expected a certificate for %s, got %v", host, peer.DNSNames)
+	}
+
+	req, _ := http.NewRequest("GET", "https://"+host+"/", nil)
+	if err := req.Write(tlsClient); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(tlsClient), req)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "dynamic ok" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, body)
+	}
+	if !backendTLS {
+		t.Error("Expected the request to be re-encrypted to the https service")
+	}
+	if len(mockMgr.getCalls) != 1 || mockMgr.getCalls[0] != host {
+		t.Errorf("Expected one lookup for %s, got %v", host, mockMgr.getCalls)
+	}
+}
+
+func TestHandleTLSConnection_EdgeTerminateMinVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := testHTTPConfig()
+	cfg.TLS.MinVersion = "1.3"
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"secure.localhost": {Context: "ctx", Namespace: "default", Service: "api", Port: 443, TLS: config.TLSModeTerminate},
+	}
+	server := NewServer(cfg, &mockManager{tunnel: &mockTunnel{running: true}})
+	mux, err := newMuxListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("newMuxListener failed: %v", err)
+	}
+	server.listener = mux
+	edge := &http.Server{Handler: server}
+	go func() { _ = edge.Serve(mux.edgeListener()) }()
+	defer func() {
+		_ = mux.Close()
+		_ = edge.Close()
+	}()
+
+	for version, wantOK := range map[uint16]bool{tls.VersionTLS12: false, tls.VersionTLS13: true} {
+		client, serverConn := net.Pipe()
+		go server.handleTLSConnection(muxconn.NewConn(serverConn))
+		tlsClient := tls.Client(client, &tls.Config{
+			ServerName:         "secure.localhost",
+			InsecureSkipVerify: true,
+			MaxVersion:         version,
+		})
+		if err := tlsClient.Handshake(); (err == nil) != wantOK {
+			t.Errorf("Handshake with max version %x: err = %v, want success %v", version, err, wantOK)
+		}
+		client.Close()
+	}
+}
+
+func TestServer_TerminatesTLS(t *testing.T) {
+	cfg := testHTTPConfig()
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"edge.localhost":        {TLS: config.TLSModeTerminate},
+		"passthrough.localhost": {},
+	}
+	server := NewServer(cfg, &mockManager{})
+
+	if !server.terminatesTLS("edge.localhost") {
+		t.Error("Expected edge.localhost to terminate TLS")
+	}
+	if server.terminatesTLS("passthrough.localhost") || server.terminatesTLS("unknown.localhost") {
+		t.Error("Expected passthrough for other hosts")
+	}
+}
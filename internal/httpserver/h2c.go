@@ -0,0 +1,46 @@
+package httpserver
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+// isHTTP2Preface reports whether a plaintext connection opens with the HTTP/2
+// prior-knowledge preface (curl --http2-prior-knowledge, gRPC without TLS). Fed
+// to the HTTP/1 server, it would only get a 400 back.
+func (s *Server) isHTTP2Preface(conn *muxconn.Conn) bool {
+	_ = conn.Conn.SetReadDeadline(time.Now().Add(PlaintextHostDeadline))
+	defer func() { _ = conn.Conn.SetReadDeadline(time.Time{}) }()
+	return conn.IsHTTP2Preface()
+}
+
+// handleH2C hands an HTTP/2 prior-knowledge connection to the h2c server when
+// http.h2c is enabled, and closes it otherwise
+func (s *Server) handleH2C(conn *muxconn.Conn) {
+	if !s.config.HTTP.H2C {
+		log.Printf("[h2c] Rejected an HTTP/2 prior-knowledge connection from %s: set http.h2c: true to serve it", conn.RemoteAddr())
+		_ = conn.Close()
+		return
+	}
+	if !s.listener.h2c.Dispatch(conn) {
+		_ = conn.Close()
+	}
+}
+
+// newH2CServer serves cleartext HTTP/2 with the same handler and timeouts as
+// the HTTP/1 server. Connections reach it only after the preface was seen, so
+// it speaks nothing else.
+func newH2CServer(handler http.Handler) *http.Server {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Handler:      handler,
+		Protocols:    &protocols,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+}
@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+// startH2CTestServer serves s on a random port, returning its address
+func startH2CTestServer(t *testing.T, h2c bool) string {
+	t.Helper()
+	cfg := testHTTPConfig()
+	cfg.HTTP.ListenAddr = "127.0.0.1:0"
+	cfg.HTTP.EchoHost = "echo.localhost"
+	cfg.HTTP.H2C = h2c
+	s := NewServer(cfg, &mockManager{})
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go func() { _ = s.Serve() }()
+	t.Cleanup(func() { _ = s.Shutdown(t.Context()) })
+	return s.listener.Addr().String()
+}
+
+func TestServer_ServesH2CPriorKnowledge(t *testing.T) {
+	addr := startH2CTestServer(t, true)
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			Protocols: &protocols,
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://echo.localhost/h2c")
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Fatalf("Expected 200 over HTTP/2, got %d over %s", resp.StatusCode, resp.Proto)
+	}
+	var echo echoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil || echo.URI != "/h2c" {
+		t.Errorf("Unexpected echo %+v (err %v)", echo, err)
+	}
+}
+
+func TestServer_RejectsH2CWhenDisabled(t *testing.T) {
+	addr := startH2CTestServer(t, false)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte(muxconn.HTTP2Preface))
+
+	// closed without an HTTP/1 "400 Bad Request" in reply
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64)
+	if n, err := conn.Read(buf); err == nil {
+		t.Errorf("Expected the connection to be closed, got %q", buf[:n])
+	}
+}
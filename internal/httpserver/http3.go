@@ -0,0 +1,54 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// startHTTP3 serves http.http3.listen over QUIC. TLS is terminated here with
+// dev CA certificates, then requests go through ServeHTTP like plain HTTP.
+func (s *Server) startHTTP3() error {
+	addr := s.config.HTTP.HTTP3.Listen
+
+	ca, err := s.devCA()
+	if err != nil {
+		return fmt.Errorf("HTTP/3 needs the dev CA: %w", err)
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s (udp): %w", addr, err)
+	}
+
+	s.http3Conn = conn
+	s.altSvc = fmt.Sprintf(`%s=":%d"; ma=86400`, http3.NextProtoH3, conn.LocalAddr().(*net.UDPAddr).Port)
+	s.http3Server = &http3.Server{
+		Handler: s,
+		TLSConfig: http3.ConfigureTLSConfig(s.config.TLS.Apply(&tls.Config{
+			MinVersion:     tls.VersionTLS13,
+			GetCertificate: ca.GetCertificate,
+		})),
+	}
+
+	go func() {
+		if err := s.http3Server.Serve(conn); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP/3 server error: %v", err)
+		}
+	}()
+
+	log.Printf("HTTP/3 listening on %s (udp, dev CA: %s)", conn.LocalAddr(), ca.CertPath())
+	return nil
+}
+
+// advertiseHTTP3 tells HTTP/1.1 and HTTP/2 clients, browsers in particular, that
+// they can switch to the HTTP/3 port
+func (s *Server) advertiseHTTP3(w http.ResponseWriter, r *http.Request) {
+	if s.altSvc != "" && r.ProtoMajor < 3 {
+		w.Header().Set("Alt-Svc", s.altSvc)
+	}
+}
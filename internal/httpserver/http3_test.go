@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestServer_HTTP3(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "host=%s", r.Host)
+	}))
+	defer backend.Close()
+
+	mockMgr := &mockManager{tunnel: &mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port}}
+	cfg := testHTTPConfig()
+	cfg.HTTP.HTTP3.Listen = "127.0.0.1:0"
+
+	server := NewServer(cfg, mockMgr)
+	if err := server.startHTTP3(); err != nil {
+		t.Fatalf("startHTTP3 failed: %v", err)
+	}
+	defer func() { _ = server.Shutdown(context.Background()) }()
+
+	caPEM, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".autotunnel", "ca", "ca.pem"))
+	if err != nil {
+		t.Fatalf("Expected dev CA to be created: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "app.localhost"}}
+	defer transport.Close()
+
+	port := server.http3Conn.LocalAddr().(*net.UDPAddr).Port
+	req, _ := http.NewRequest("GET", fmt.Sprintf("https://127.0.0.1:%d/", port), nil)
+	req.Host = "app.localhost"
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("HTTP/3 request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 3 {
+		t.Errorf("Expected HTTP/3 200, got %s %d", resp.Proto, resp.StatusCode)
+	}
+	if alt := resp.Header.Get("Alt-Svc"); alt != "" {
+		t.Errorf("Expected no Alt-Svc on HTTP/3 responses, got %q", alt)
+	}
+	if string(body) != "host=app.localhost" {
+		t.Errorf("Unexpected backend response: %q", body)
+	}
+	if len(mockMgr.getCalls) != 1 || mockMgr.getCalls[0] != "app.localhost" {
+		t.Errorf("Expected tunnel lookup for app.localhost, got %v", mockMgr.getCalls)
+	}
+}
+
+func TestServer_HTTP3_AltSvc(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	mockMgr := &mockManager{tunnel: &mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port}}
+	cfg := testHTTPConfig()
+	cfg.HTTP.HTTP3.Listen = "127.0.0.1:0"
+
+	server := NewServer(cfg, mockMgr)
+	if err := server.startHTTP3(); err != nil {
+		t.Fatalf("startHTTP3 failed: %v", err)
+	}
+	defer func() { _ = server.Shutdown(context.Background()) }()
+
+	port := server.http3Conn.LocalAddr().(*net.UDPAddr).Port
+	want := fmt.Sprintf(`h3=":%d"; ma=86400`, port)
+	for _, proto := range []int{1, 2} {
+		req := httptest.NewRequest("GET", "http://app.localhost/", nil)
+		req.ProtoMajor = proto
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Alt-Svc"); got != want {
+			t.Errorf("HTTP/%d: Alt-Svc = %q, want %q", proto, got, want)
+		}
+	}
+}
+
+func TestServer_NoAltSvcWithoutHTTP3(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	mockMgr := &mockManager{tunnel: &mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port}}
+	server := NewServer(testHTTPConfig(), mockMgr)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "http://app.localhost/", nil))
+
+	if got := rec.Header().Get("Alt-Svc"); got != "" {
+		t.Errorf("Alt-Svc = %q without http3, want none", got)
+	}
+}
@@ -2,36 +2,70 @@ package httpserver
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"golang.org/x/net/http/httpguts"
 )
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	host := r.Host
-	if idx := strings.LastIndex(host, ":"); idx != -1 {
-		host = host[:idx]
+	host := config.NormalizeHostname(stripPort(r.Host))
+	s.advertiseHTTP3(w, r)
+
+	if !s.authorizeRoute(w, r, host) || !s.authorizeTeam(w, r, host) || !s.authorizeDebug(w, r, host) {
+		return
+	}
+
+	if s.admin != nil && s.config.HTTP.StatusHost != "" && strings.EqualFold(host, s.config.HTTP.StatusHost) {
+		s.admin.ServeHTTP(w, r)
+		return
+	}
+	if s.isEchoHost(host) {
+		serveEcho(w, r)
+		return
+	}
+	if target, ok := s.redirectTarget(host); ok {
+		serveRedirect(w, r, target)
+		return
+	}
+	if route, ok := s.staticRoute(host); ok {
+		serveStatic(w, r, route)
+		return
 	}
 
+	s.traffic.record(host, requestProtocol(r))
+	requestID := ensureRequestID(r)
+
 	if s.config.Verbose {
-		log.Printf("[http] [%s] %s %s", host, r.Method, r.URL.Path)
+		log.Printf("[http] [%s] [%s] %s %s", host, requestID, r.Method, r.URL.Path)
+	}
+
+	if backend, ok := s.localFor(host); ok {
+		s.serveLocal(w, r, host, requestID, backend)
+		return
 	}
 
 	tunnel, err := s.manager.GetOrCreateTunnel(host, "http")
 	if err != nil {
-		log.Printf("[http] [%s] Error: %v", host, err)
-		http.Error(w, fmt.Sprintf("No service configured for host: %s", host), http.StatusBadGateway)
+		log.Printf("[http] [%s] [%s] Error: %v", host, requestID, err)
+		if isUnrouted(err) {
+			s.traffic.recordUnrouted(host)
+		}
+		s.writeTunnelError(w, r, host, fmt.Sprintf("No service configured for host: %s", host), err)
 		return
 	}
 
 	if !tunnel.IsRunning() {
 		if err := tunnel.Start(r.Context()); err != nil {
-			log.Printf("[http] [%s] Failed to start tunnel: %v", host, err)
-			http.Error(w, fmt.Sprintf("Failed to start tunnel: %v", err), http.StatusBadGateway)
+			log.Printf("[http] [%s] [%s] Failed to start tunnel: %v", host, requestID, err)
+			s.writeTunnelError(w, r, host, fmt.Sprintf("Failed to start tunnel: %v", err), err)
 			return
 		}
 	}
@@ -44,23 +78,37 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Host:   fmt.Sprintf("127.0.0.1:%d", tunnel.LocalPort()),
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-
+	var transport *http.Transport
 	if scheme == "https" {
-		proxy.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		transport, targetURL, err = s.upstreamTransport(host, tunnel.LocalPort())
+		if err != nil {
+			log.Printf("[http] [%s] [%s] Error: %v", host, requestID, err)
+			s.writeTunnelError(w, r, host, fmt.Sprintf("Upstream TLS error for host '%s': %v", host, err), err)
+			return
 		}
 	}
 
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	if transport != nil {
+		proxy.Transport = transport
+	}
+
+	// TLS we terminated ourselves (edge routes, HTTP/3) was https for the client
+	forwardedProto := scheme
+	if r.TLS != nil {
+		forwardedProto = "https"
+	}
+
 	originalDirector := proxy.Director
 	proxy.Director = func(req *http.Request) {
 		originalDirector(req)
-		req.Host = r.Host
-		req.Header.Set("X-Forwarded-Proto", scheme)
-		req.Header.Set("X-Forwarded-Host", r.Host)
-		if r.RemoteAddr != "" {
-			req.Header.Set("X-Forwarded-For", strings.Split(r.RemoteAddr, ":")[0])
-		}
+		setForwardedHeaders(req, r, forwardedProto)
+	}
+
+	// the backend may set its own ID; clients should see the one we logged
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		resp.Header.Set(RequestIDHeader, requestID)
+		return nil
 	}
 
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
@@ -68,9 +116,55 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err == context.Canceled || strings.Contains(err.Error(), "context canceled") {
 			return
 		}
-		log.Printf("[http] [%s] Proxy error: %v", host, err)
-		http.Error(w, fmt.Sprintf("Proxy error for host '%s': %v", host, err), http.StatusBadGateway)
+		log.Printf("[http] [%s] [%s] Proxy error: %v", host, requestID, err)
+		s.writeTunnelError(w, r, host, fmt.Sprintf("Proxy error for host '%s': %v", host, err), err)
+	}
+
+	// upgraded connections (WebSockets) outlive the request; keep the tunnel from
+	// idling out until they close
+	if isUpgrade(r) {
+		if tracker, ok := tunnel.(connTracker); ok {
+			defer tracker.TrackConn()()
+		}
+	}
+
+	if !s.trafficThresholds() {
+		proxy.ServeHTTP(w, r)
+		return
+	}
+
+	cw := &countingWriter{ResponseWriter: w}
+	start := time.Now()
+	proxy.ServeHTTP(cw, r)
+	s.logTraffic(r, host, requestID, tunnel, cw, time.Since(start))
+}
+
+// setForwardedHeaders addresses the proxied req like the client's r and tells the
+// backend who asked and how
+func setForwardedHeaders(req, r *http.Request, proto string) {
+	req.Host = r.Host
+	req.Header.Set("X-Forwarded-Proto", proto)
+	req.Header.Set("X-Forwarded-Host", r.Host)
+	if r.RemoteAddr != "" {
+		req.Header.Set("X-Forwarded-For", stripPort(r.RemoteAddr))
 	}
+}
+
+// stripPort returns the host of a Host header or remote address without port and
+// IPv6 brackets: "app.localhost:8989" -> "app.localhost", "[::1]:8989" -> "::1"
+func stripPort(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+}
+
+// connTracker is implemented by tunnels that stay open while tracked connections are
+type connTracker interface {
+	TrackConn() (release func())
+}
 
-	proxy.ServeHTTP(w, r)
+// isUpgrade reports whether r asks to switch protocols (WebSocket, h2c)
+func isUpgrade(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" && httpguts.HeaderValuesContainsToken(r.Header["Connection"], "Upgrade")
 }
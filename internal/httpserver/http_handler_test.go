@@ -1,12 +1,15 @@
 package httpserver
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -36,6 +39,7 @@ func (m *mockTunnel) Start(ctx context.Context) error {
 }
 func (m *mockTunnel) Stop()                        {}
 func (m *mockTunnel) LocalPort() int               { return m.localPort }
+func (m *mockTunnel) LocalPortFor(port int) int     { return m.localPort }
 func (m *mockTunnel) Scheme() string {
 	if m.scheme == "" {
 		return "http"
@@ -53,6 +57,7 @@ type mockManager struct {
 	err         error
 	getCalls    []string
 	getSchemes  []string
+	portCalls   []int
 }
 
 func (m *mockManager) GetOrCreateTunnel(hostname string, scheme string) (tunnelmgr.TunnelHandle, error) {
@@ -61,6 +66,12 @@ func (m *mockManager) GetOrCreateTunnel(hostname string, scheme string) (tunnelm
 	return m.tunnel, m.err
 }
 
+func (m *mockManager) GetOrCreatePortTunnel(hostname string, port int) (tunnelmgr.TunnelHandle, error) {
+	m.getCalls = append(m.getCalls, hostname)
+	m.portCalls = append(m.portCalls, port)
+	return m.tunnel, m.err
+}
+
 func testHTTPConfig() *config.Config {
 	return &config.Config{
 		Verbose: false,
@@ -173,6 +184,30 @@ func TestServer_ServeHTTP_HostWithPort(t *testing.T) {
 	}
 }
 
+func TestServer_ServeHTTP_NormalizesHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"MyApp.Localhost:8989", "myapp.localhost"},
+		{"myapp.localhost.:8989", "myapp.localhost"},
+		{"bücher.localhost", "xn--bcher-kva.localhost"},
+	}
+
+	for _, tt := range tests {
+		mockMgr := &mockManager{err: errors.New("no route configured")}
+		server := NewServer(testHTTPConfig(), mockMgr)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = tt.host
+		server.ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(mockMgr.getCalls) != 1 || mockMgr.getCalls[0] != tt.want {
+			t.Errorf("Host %q: expected lookup for %q, got %v", tt.host, tt.want, mockMgr.getCalls)
+		}
+	}
+}
+
 func TestServer_ServeHTTP_EmptyHost(t *testing.T) {
 	mockMgr := &mockManager{
 		err: errors.New("no route configured"),
@@ -344,3 +379,128 @@ func TestServer_ServeHTTP_HTTPSchemeDefault(t *testing.T) {
 		t.Errorf("Expected default X-Forwarded-Proto 'http', got %q", receivedProto)
 	}
 }
+
+func TestServer_ServeHTTP_StatusHost(t *testing.T) {
+	mockMgr := &mockManager{}
+	cfg := testHTTPConfig()
+	cfg.HTTP.StatusHost = "autotunnel.localhost"
+
+	server := NewServer(cfg, mockMgr)
+	server.SetAdminHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("admin"))
+	}))
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	req.Host = "AutoTunnel.localhost:8989"
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Body.String() != "admin" {
+		t.Errorf("Expected admin handler response, got %q", w.Body.String())
+	}
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup for status host, got %v", mockMgr.getCalls)
+	}
+}
+
+func TestStripPort(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"app.localhost:8989", "app.localhost"},
+		{"app.localhost", "app.localhost"},
+		{"127.0.0.1:8989", "127.0.0.1"},
+		{"[::1]:8989", "::1"},
+		{"[::1]", "::1"},
+	}
+	for _, tt := range tests {
+		if got := stripPort(tt.in); got != tt.want {
+			t.Errorf("stripPort(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// trackingTunnel is a mockTunnel that counts tracked connections
+type trackingTunnel struct {
+	mockTunnel
+	open atomic.Int32
+}
+
+func (m *trackingTunnel) TrackConn() func() {
+	m.open.Add(1)
+	return func() { m.open.Add(-1) }
+}
+
+func TestServer_ServeHTTP_TracksUpgradedConnections(t *testing.T) {
+	// backend accepts the upgrade and echoes until the client hangs up
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = rw.Flush()
+		_, _ = io.Copy(conn, rw)
+	}))
+	defer backend.Close()
+
+	tun := &trackingTunnel{mockTunnel: mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port}}
+	frontend := httptest.NewServer(NewServer(testHTTPConfig(), &mockManager{tunnel: tun}))
+	defer frontend.Close()
+
+	conn, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_, _ = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: app.localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+
+	if n := tun.open.Load(); n != 1 {
+		t.Errorf("Expected 1 tracked connection while the WebSocket is open, got %d", n)
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for tun.open.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := tun.open.Load(); n != 0 {
+		t.Errorf("Expected the connection to be released after close, got %d open", n)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	tests := []struct {
+		connection string
+		upgrade    string
+		want       bool
+	}{
+		{"Upgrade", "websocket", true},
+		{"keep-alive, Upgrade", "websocket", true},
+		{"keep-alive", "websocket", false},
+		{"Upgrade", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.connection != "" {
+			r.Header.Set("Connection", tt.connection)
+		}
+		if tt.upgrade != "" {
+			r.Header.Set("Upgrade", tt.upgrade)
+		}
+		if got := isUpgrade(r); got != tt.want {
+			t.Errorf("isUpgrade(Connection=%q, Upgrade=%q) = %v, want %v", tt.connection, tt.upgrade, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// localProbeInterval is how long a check whether a local process with a cluster
+// fallback is listening stays valid; localProbeTimeout bounds the check
+var (
+	localProbeInterval = 2 * time.Second
+	localProbeTimeout  = 200 * time.Millisecond
+	localProbeDial     = net.DialTimeout
+)
+
+// localBackend is a process on this machine that an http.local hostname is
+// proxied to. Its transport is shared by all requests to keep connections alive.
+type localBackend struct {
+	addr      string // 127.0.0.1:<port> or the socket path, for error messages
+	network   string // "tcp" or "unix"
+	target    *url.URL
+	transport *http.Transport
+
+	// fallback is set when the hostname is also an http.k8s.routes entry: requests
+	// go to the cluster while the process isn't listening
+	fallback bool
+
+	mu        sync.Mutex
+	checked   time.Time
+	listening bool
+	probing   bool // a check is dialing; others use the last result meanwhile
+}
+
+func newLocalBackends(cfg *config.Config) map[string]*localBackend {
+	backends := make(map[string]*localBackend, len(cfg.HTTP.Local))
+	for hostname, route := range cfg.HTTP.Local {
+		backend := newLocalBackend(route, cfg.TLS)
+		_, backend.fallback = cfg.HTTP.K8s.Routes[hostname]
+		backends[hostname] = backend
+	}
+	return backends
+}
+
+func newLocalBackend(route config.LocalRouteConfig, tlsSettings config.TLSConfig) *localBackend {
+	scheme := route.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	transport := &http.Transport{
+		// local dev servers use self-signed certificates
+		TLSClientConfig: tlsSettings.Apply(&tls.Config{InsecureSkipVerify: true}),
+	}
+	target := &url.URL{Scheme: scheme, Host: net.JoinHostPort("127.0.0.1", strconv.Itoa(route.Port))}
+	if route.Socket != "" {
+		var dialer net.Dialer
+		socket := route.Socket
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		target.Host = "localhost"
+	}
+	network := "tcp"
+	if route.Socket != "" {
+		network = "unix"
+	}
+	return &localBackend{addr: route.Address(), network: network, target: target, transport: transport}
+}
+
+// localFor returns the local process to serve host's requests: its http.local
+// backend, unless that has a cluster fallback and isn't listening
+func (s *Server) localFor(host string) (*localBackend, bool) {
+	backend, ok := s.local[host]
+	if !ok || (backend.fallback && !backend.isListening(host)) {
+		return nil, false
+	}
+	return backend, true
+}
+
+// isListening reports whether something accepts connections on the backend's
+// address, checking at most every localProbeInterval. One request at a time
+// dials, without holding the lock; the others get the last result meanwhile.
+// Switches between the local process and the cluster are logged.
+func (b *localBackend) isListening(host string) bool {
+	b.mu.Lock()
+	if b.probing || time.Since(b.checked) < localProbeInterval {
+		listening := b.listening
+		b.mu.Unlock()
+		return listening
+	}
+	b.probing = true
+	b.mu.Unlock()
+
+	listening := false
+	if conn, err := localProbeDial(b.network, b.addr, localProbeTimeout); err == nil {
+		conn.Close()
+		listening = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	switch {
+	case listening && !b.listening:
+		log.Printf("[http] [%s] Local process on %s is listening, serving it instead of the cluster", host, b.addr)
+	case !listening && b.listening:
+		log.Printf("[http] [%s] Local process on %s stopped listening, back to the cluster", host, b.addr)
+	}
+	b.listening = listening
+	b.checked = time.Now()
+	return listening
+}
+
+// serveLocal proxies r to backend with the same forwarding headers as tunneled
+// requests, so the process sees the hostname and scheme the browser used
+func (s *Server) serveLocal(w http.ResponseWriter, r *http.Request, host, requestID string, backend *localBackend) {
+	forwardedProto := "http"
+	if r.TLS != nil {
+		forwardedProto = "https"
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(backend.target)
+	proxy.Transport = backend.transport
+	originalDirector := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		originalDirector(req)
+		setForwardedHeaders(req, r, forwardedProto)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		resp.Header.Set(RequestIDHeader, requestID)
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if r.Context().Err() != nil {
+			return
+		}
+		log.Printf("[http] [%s] [%s] Local backend error: %v", host, requestID, err)
+		w.Header().Set(RequestIDHeader, requestID)
+		http.Error(w, fmt.Sprintf("Local backend for host '%s' (%s) is not reachable: %v\nRequest ID: %s",
+			host, backend.addr, err, requestID), http.StatusBadGateway)
+	}
+	proxy.ServeHTTP(w, r)
+}
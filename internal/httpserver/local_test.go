@@ -0,0 +1,207 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_ProxiesLocalRoutes(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Host", r.Host)
+		w.Header().Set("X-Seen-Proto", r.Header.Get("X-Forwarded-Proto"))
+		_, _ = io.WriteString(w, "local "+r.URL.Path)
+	})
+	tcpBackend := httptest.NewServer(backend)
+	defer tcpBackend.Close()
+	_, portStr, _ := net.SplitHostPort(tcpBackend.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	unixBackend := &httptest.Server{Listener: ln, Config: &http.Server{Handler: backend}}
+	unixBackend.Start()
+	defer unixBackend.Close()
+
+	mockMgr := &mockManager{}
+	cfg := testHTTPConfig()
+	cfg.HTTP.Local = map[string]config.LocalRouteConfig{
+		"web.localhost":  {Port: port},
+		"sock.localhost": {Socket: socket},
+		"down.localhost": {Port: 1},
+	}
+	server := NewServer(cfg, mockMgr)
+
+	for _, host := range []string{"web.localhost", "sock.localhost"} {
+		req := httptest.NewRequest("GET", "/api/me", nil)
+		req.Host = host + ":8989"
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Body.String() != "local /api/me" {
+			t.Errorf("%s: got %d %q", host, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("X-Seen-Host"); got != host+":8989" {
+			t.Errorf("%s: backend saw Host %q", host, got)
+		}
+		if got := w.Header().Get("X-Seen-Proto"); got != "http" {
+			t.Errorf("%s: backend saw X-Forwarded-Proto %q", host, got)
+		}
+		if w.Header().Get(RequestIDHeader) == "" {
+			t.Errorf("%s: response has no request ID", host)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "down.localhost"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("unreachable local backend: expected 502, got %d", w.Code)
+	}
+
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mockMgr.getCalls)
+	}
+	if !server.terminatesTLS("web.localhost") {
+		t.Error("Expected TLS for a local host to be terminated locally")
+	}
+}
+
+func TestServer_ShutdownClosesLocalConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	local := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "local")
+	}))
+	local.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			select {
+			case closed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	local.Start()
+	defer local.Close()
+	port := local.Listener.Addr().(*net.TCPAddr).Port
+
+	cfg := testHTTPConfig()
+	cfg.HTTP.Local = map[string]config.LocalRouteConfig{"web.localhost": {Port: port}}
+	server := NewServer(cfg, &mockManager{})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "web.localhost"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Body.String() != "local" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+
+	_ = server.Shutdown(context.Background())
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the idle connection to the local process to be closed on shutdown")
+	}
+}
+
+func TestLocalBackend_ProbeDoesNotBlockOtherRequests(t *testing.T) {
+	defer func(d time.Duration, dial func(string, string, time.Duration) (net.Conn, error)) {
+		localProbeInterval, localProbeDial = d, dial
+	}(localProbeInterval, localProbeDial)
+	localProbeInterval = time.Hour // only the first call is due for a check
+
+	dialing, release := make(chan struct{}), make(chan struct{})
+	localProbeDial = func(network, addr string, timeout time.Duration) (net.Conn, error) {
+		close(dialing)
+		<-release
+		return nil, errors.New("connection refused")
+	}
+	backend := newLocalBackend(config.LocalRouteConfig{Port: 1}, config.TLSConfig{})
+	backend.listening = true
+
+	done := make(chan bool)
+	go func() { done <- backend.isListening("api.localhost") }()
+	<-dialing
+
+	answered := make(chan bool)
+	go func() { answered <- backend.isListening("api.localhost") }()
+	select {
+	case listening := <-answered:
+		if !listening {
+			t.Error("Expected the last result while another request probes")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("isListening blocked while another request was dialing")
+	}
+
+	close(release)
+	if <-done {
+		t.Error("Expected the probe's result once it finished")
+	}
+	if backend.isListening("api.localhost") {
+		t.Error("Expected the probe's result to be kept for the next requests")
+	}
+}
+
+func TestServer_LocalFallsBackToCluster(t *testing.T) {
+	defer func(d time.Duration) { localProbeInterval = d }(localProbeInterval)
+	localProbeInterval = 0
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close() // not listening yet
+
+	mockMgr := &mockManager{err: errors.New("cluster unreachable")}
+	cfg := testHTTPConfig()
+	cfg.HTTP.Local = map[string]config.LocalRouteConfig{"api.localhost": {Port: port}}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "test", Namespace: "default", Service: "api", Port: 80},
+	}
+	server := NewServer(cfg, mockMgr)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = "api.localhost"
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	get()
+	if len(mockMgr.getCalls) != 1 {
+		t.Fatalf("Expected the cluster route while nothing listens locally, got tunnel lookups %v", mockMgr.getCalls)
+	}
+
+	ln, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("port %d was taken in the meantime: %v", port, err)
+	}
+	local := &httptest.Server{Listener: ln, Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "local")
+	})}}
+	local.Start()
+	defer local.Close()
+
+	if w := get(); w.Body.String() != "local" {
+		t.Errorf("Expected the local process once it listens, got %d %q", w.Code, w.Body.String())
+	}
+	if len(mockMgr.getCalls) != 1 {
+		t.Errorf("Expected no more tunnel lookups, got %v", mockMgr.getCalls)
+	}
+}
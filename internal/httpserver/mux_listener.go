@@ -1,84 +1,43 @@
 package httpserver
 
 import (
-	"bufio"
 	"net"
-)
-
-type peekConn struct {
-	net.Conn
-	reader *bufio.Reader
-}
-
-func newPeekConn(conn net.Conn) *peekConn {
-	return &peekConn{
-		Conn:   conn,
-		reader: bufio.NewReader(conn),
-	}
-}
 
-func (c *peekConn) Read(b []byte) (int, error) {
-	return c.reader.Read(b)
-}
-
-func (c *peekConn) Peek(n int) ([]byte, error) {
-	return c.reader.Peek(n)
-}
-
-func (c *peekConn) isTLS() bool {
-	// 0x16 = TLS handshake record type
-	b, err := c.reader.Peek(1)
-	if err != nil {
-		return false
-	}
-	return b[0] == 0x16
-}
+	"github.com/atas/autotunnel/internal/muxconn"
+)
 
-// muxListener routes connections to either TLS passthrough or HTTP based on first byte
+// muxListener splits http.listen by protocol. Plain HTTP/1 connections, TLS
+// connections for edge-terminated routes (handed back as *tls.Conn) and HTTP/2
+// with prior knowledge each go to their own server; TLS passthrough and mode: tcp
+// connections are spliced by handleConnection itself.
 type muxListener struct {
-	net.Listener
-	httpConns chan net.Conn
-	done      chan struct{}
+	*muxconn.Listener
+	http *muxconn.Route
+	edge *muxconn.Route
+	h2c  *muxconn.Route
 }
 
 func newMuxListener(addr string) (*muxListener, error) {
-	l, err := net.Listen("tcp", addr)
+	l, err := muxconn.Listen(addr)
 	if err != nil {
 		return nil, err
 	}
 	return &muxListener{
-		Listener:  l,
-		httpConns: make(chan net.Conn, 256),
-		done:      make(chan struct{}),
+		Listener: l,
+		http:     l.NewRoute(),
+		edge:     l.NewRoute(),
+		h2c:      l.NewRoute(),
 	}, nil
 }
 
 func (m *muxListener) httpListener() net.Listener {
-	return &httpOnlyListener{mux: m}
-}
-
-func (m *muxListener) Close() error {
-	close(m.done)
-	return m.Listener.Close()
-}
-
-type httpOnlyListener struct {
-	mux *muxListener
-}
-
-func (h *httpOnlyListener) Accept() (net.Conn, error) {
-	select {
-	case conn := <-h.mux.httpConns:
-		return conn, nil
-	case <-h.mux.done:
-		return nil, net.ErrClosed
-	}
+	return m.http
 }
 
-func (h *httpOnlyListener) Close() error {
-	return nil // muxListener handles this
+func (m *muxListener) edgeListener() net.Listener {
+	return m.edge
 }
 
-func (h *httpOnlyListener) Addr() net.Addr {
-	return h.mux.Listener.Addr()
+func (m *muxListener) h2cListener() net.Listener {
+	return m.h2c
 }
@@ -0,0 +1,150 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/authz"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// maxPlaintextPeek bounds how much of a plaintext connection is read looking for
+// its host: the peekConn buffer size
+const maxPlaintextPeek = 4096
+
+// hasRawTCPRoutes reports whether any route has mode: tcp. Without one, plaintext
+// connections go straight to the HTTP server.
+func (s *Server) hasRawTCPRoutes() bool {
+	for _, route := range s.config.HTTP.K8s.Routes {
+		if route.RawTCP() {
+			return true
+		}
+	}
+	return false
+}
+
+// rawTCPRoute reports whether host is a route with mode: tcp
+func (s *Server) rawTCPRoute(host string) bool {
+	route, ok := s.config.HTTP.K8s.Routes[host]
+	return ok && route.RawTCP()
+}
+
+// rawTCPHost returns the mode: tcp route a plaintext connection is for, "" when it
+// belongs to the HTTP server
+func (s *Server) rawTCPHost(conn *muxconn.Conn) string {
+	if !s.rawTCP {
+		return ""
+	}
+	if host := peekPlaintextHost(conn, PlaintextHostDeadline); s.rawTCPRoute(host) {
+		return host
+	}
+	return ""
+}
+
+// peekPlaintextHost waits for the start of a plaintext connection and returns
+// the host it is for, "" if it names none. Text protocols shaped like HTTP
+// (RTSP, ICAP, SIP over TCP, ...) name it in a Host header or an absolute URL on
+// the request line. Nothing is consumed: the HTTP server still sees every byte.
+func peekPlaintextHost(conn *muxconn.Conn, deadline time.Duration) string {
+	_ = conn.Conn.SetReadDeadline(time.Now().Add(deadline))
+	defer func() { _ = conn.Conn.SetReadDeadline(time.Time{}) }()
+
+	var head []byte
+	for n := 1; n <= maxPlaintextPeek; n = len(head) + 1 {
+		b, err := conn.Peek(max(n, conn.Buffered()))
+		head = b
+		if bytes.Contains(head, []byte("\r\n\r\n")) || bytes.Contains(head, []byte("\n\n")) || err != nil {
+			break
+		}
+	}
+	return plaintextHost(head)
+}
+
+// plaintextHost extracts the host from a request line and header block
+func plaintextHost(head []byte) string {
+	lines := strings.Split(strings.ReplaceAll(string(head), "\r\n", "\n"), "\n")
+	if len(lines) < 2 {
+		return "" // not even a complete request line
+	}
+
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "host") {
+			return normalizeHostPort(strings.TrimSpace(value))
+		}
+	}
+
+	// METHOD scheme://host[:port]/path PROTO/x.y
+	fields := strings.Fields(lines[0])
+	if len(fields) == 3 {
+		if u, err := url.Parse(fields[1]); err == nil && u.Host != "" {
+			return normalizeHostPort(u.Host)
+		}
+	}
+	return ""
+}
+
+func normalizeHostPort(hostport string) string {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	return config.NormalizeHostname(host)
+}
+
+// handleRawTCP splices a plaintext connection for a mode: tcp route to its
+// tunnel, replaying what was peeked. Errors just close the connection: the
+// client isn't speaking HTTP, so there is no error page to show.
+func (s *Server) handleRawTCP(conn *muxconn.Conn, host string) {
+	defer conn.Close()
+
+	// like passthrough, there is no token to check
+	if s.config.Team.Enabled() {
+		log.Printf("[tcp] [%s] Rejected: mode: tcp routes are disabled in team mode", host)
+		return
+	}
+	if !s.authorizeConn(conn.Conn, host, authz.KindTCP) {
+		return
+	}
+
+	s.traffic.record(host, protoRawTCP)
+	tunnel, err := s.manager.GetOrCreateTunnel(host, "http")
+	if err != nil {
+		log.Printf("[tcp] [%s] Error: %v", host, err)
+		return
+	}
+	if !tunnel.IsRunning() {
+		if err := tunnel.Start(context.Background()); err != nil {
+			log.Printf("[tcp] [%s] Failed to start tunnel: %v", host, err)
+			return
+		}
+	}
+	tunnel.Touch()
+
+	backendConn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", tunnel.LocalPort()), TLSBackendDialTimeout)
+	if err != nil {
+		log.Printf("[tcp] [%s] Failed to connect to backend: %v", host, err)
+		return
+	}
+	defer backendConn.Close()
+
+	if s.config.Verbose {
+		log.Printf("[tcp] [%s] New connection from %s", host, conn.RemoteAddr())
+	}
+	if tracker, ok := tunnel.(connTracker); ok {
+		defer tracker.TrackConn()()
+	}
+	// conn reads the peeked bytes first
+	netutil.BidirectionalCopy(backendConn, conn)
+}
@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestPlaintextHost(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"host header", "GET / HTTP/1.1\r\nHost: App.localhost:8989\r\n\r\n", "app.localhost"},
+		{"lowercase header", "OPTIONS * RTSP/1.0\r\nCSeq: 1\r\nhost: cam.localhost\r\n\r\n", "cam.localhost"},
+		{"absolute url", "DESCRIBE rtsp://cam.localhost:8989/stream RTSP/1.0\r\nCSeq: 2\r\n\r\n", "cam.localhost"},
+		{"bare newlines", "REQMOD icap://av.localhost/scan ICAP/1.0\n\n", "av.localhost"},
+		{"no host", "PING\r\n\r\n", ""},
+		{"incomplete line", "GET / HT", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plaintextHost([]byte(tt.head)); got != tt.want {
+				t.Errorf("plaintextHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_SplicesRawTCPRoute(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	mockTun := &tlsMockTunnel{localPort: backend.Addr().(*net.TCPAddr).Port}
+	mockMgr := &tlsMockManager{tunnel: mockTun}
+	cfg := &config.Config{HTTP: config.HTTPConfig{
+		ListenAddr: ":8989",
+		K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+			"cam.localhost": {Context: "test", Namespace: "ns", Service: "cam", Port: 554, Mode: config.RouteModeTCP},
+		}},
+	}}
+	server := NewServer(cfg, mockMgr)
+
+	client, serverConn := net.Pipe()
+	defer client.Close()
+	go server.handleConnection(serverConn)
+
+	request := "DESCRIBE rtsp://cam.localhost/stream RTSP/1.0\r\nCSeq: 1\r\n\r\n"
+	if _, err := client.Write([]byte(request)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	echoed := make([]byte, len(request))
+	if _, err := io.ReadFull(bufio.NewReader(client), echoed); err != nil {
+		t.Fatalf("Failed to read echo: %v", err)
+	}
+	if string(echoed) != request {
+		t.Errorf("Expected the request echoed unchanged, got %q", echoed)
+	}
+	if !mockTun.startCalled || !mockTun.touchCalled {
+		t.Error("Expected the tunnel to be started and touched")
+	}
+	if hosts := server.Traffic(); len(hosts) != 1 || hosts[0].RawTCP != 1 {
+		t.Errorf("Expected 1 tcp connection counted, got %+v", hosts)
+	}
+}
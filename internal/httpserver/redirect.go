@@ -0,0 +1,39 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redirectTarget returns where http.redirects sends requests for host, if anywhere
+func (s *Server) redirectTarget(host string) (string, bool) {
+	target, ok := s.config.HTTP.Redirects[host]
+	return target, ok
+}
+
+// serveRedirect answers r with a redirect to target. The request's path and query
+// are appended, so ci.localhost/job/deploy reaches the same page on the target.
+// 302 rather than 301, so browsers pick up changes to the config.
+func serveRedirect(w http.ResponseWriter, r *http.Request, target string) {
+	http.Redirect(w, r, redirectURL(target, r.URL), http.StatusFound)
+}
+
+func redirectURL(target string, req *url.URL) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	if req.Path != "" && req.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + req.Path
+		u.RawPath = ""
+	}
+	if req.RawQuery != "" {
+		if u.RawQuery != "" {
+			u.RawQuery += "&" + req.RawQuery
+		} else {
+			u.RawQuery = req.RawQuery
+		}
+	}
+	return u.String()
+}
@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_ServesRedirects(t *testing.T) {
+	mockMgr := &mockManager{}
+	cfg := testHTTPConfig()
+	cfg.HTTP.Redirects = map[string]string{
+		"ci.localhost":   "https://jenkins.example.com",
+		"docs.localhost": "https://wiki.example.com/team/?lang=en",
+	}
+	server := NewServer(cfg, mockMgr)
+
+	tests := []struct {
+		host, uri, want string
+	}{
+		{"ci.localhost:8989", "/", "https://jenkins.example.com"},
+		{"ci.localhost", "/job/deploy?delay=0", "https://jenkins.example.com/job/deploy?delay=0"},
+		{"docs.localhost", "/", "https://wiki.example.com/team/?lang=en"},
+		{"docs.localhost", "/oncall?x=1", "https://wiki.example.com/team/oncall?lang=en&x=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.uri, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		if w.Code != http.StatusFound {
+			t.Errorf("%s%s: expected status 302, got %d", tt.host, tt.uri, w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s%s: Location = %q, want %q", tt.host, tt.uri, got, tt.want)
+		}
+	}
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mockMgr.getCalls)
+	}
+	if !server.terminatesTLS("ci.localhost") {
+		t.Error("Expected TLS for a redirect host to be terminated locally")
+	}
+}
@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader correlates a request across the client, autotunnel and the backend
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-provided IDs, which end up in our logs
+const maxRequestIDLen = 128
+
+// ensureRequestID keeps the client's X-Request-ID when it looks sane, otherwise
+// sets a new one on r, so it is forwarded to the backend with the request
+func ensureRequestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+		r.Header.Set(RequestIDHeader, id)
+	}
+	return id
+}
+
+// validRequestID accepts printable ASCII without spaces, so IDs can't break log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnsureRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"client id kept", "abc-123", true},
+		{"missing", "", false},
+		{"spaces", "abc 123", false},
+		{"newline", "abc\nforged log line", false},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+
+			id := ensureRequestID(req)
+			if tt.keep && id != tt.incoming {
+				t.Errorf("ensureRequestID() = %q, want the client's %q", id, tt.incoming)
+			}
+			if !tt.keep && (id == tt.incoming || len(id) != 32) {
+				t.Errorf("ensureRequestID() = %q, want a new 32-char id", id)
+			}
+			if req.Header.Get(RequestIDHeader) != id {
+				t.Errorf("Expected the id set on the request for forwarding, got %q", req.Header.Get(RequestIDHeader))
+			}
+		})
+	}
+}
+
+func TestServer_ServeHTTP_PropagatesRequestID(t *testing.T) {
+	var receivedID string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedID = r.Header.Get(RequestIDHeader)
+		w.Header().Set(RequestIDHeader, "backend-id")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	mockTun := &mockTunnel{
+		running:   true,
+		localPort: backend.Listener.Addr().(*net.TCPAddr).Port,
+		scheme:    "http",
+	}
+	server := NewServer(testHTTPConfig(), &mockManager{tunnel: mockTun})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "test.localhost"
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	respID := w.Header().Get(RequestIDHeader)
+	if receivedID == "" || receivedID != respID {
+		t.Errorf("Expected the backend and the client to see the same id, got %q and %q", receivedID, respID)
+	}
+	if values := w.Header().Values(RequestIDHeader); len(values) != 1 {
+		t.Errorf("Expected one %s response header, got %v", RequestIDHeader, values)
+	}
+}
+
+func TestServer_ServeHTTP_RequestIDOnErrorPage(t *testing.T) {
+	mockTun := &mockTunnel{startErr: errors.New("failed to connect to k8s")}
+	server := NewServer(testHTTPConfig(), &mockManager{tunnel: mockTun})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "test.localhost"
+	req.Header.Set(RequestIDHeader, "trace-42")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "Request ID: trace-42") {
+		t.Errorf("Expected the request id on the error page, got:\n%s", w.Body.String())
+	}
+	if w.Header().Get(RequestIDHeader) != "trace-42" {
+		t.Errorf("Expected the request id response header, got %q", w.Header().Get(RequestIDHeader))
+	}
+}
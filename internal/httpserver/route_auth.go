@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/authz"
+)
+
+// SetAuthorizer checks every request and passthrough connection against p.
+// Must be called before Start.
+func (s *Server) SetAuthorizer(p *authz.Policy) {
+	s.authz = p
+}
+
+// authorizeRoute runs the route's authorizers for an HTTP request. It writes a
+// 401/403 and returns false when the request may not reach host.
+func (s *Server) authorizeRoute(w http.ResponseWriter, r *http.Request, host string) bool {
+	if s.authz == nil {
+		return true
+	}
+
+	token, fromAuthorization := requestToken(r)
+	req := authz.Request{
+		Route:      host,
+		Kind:       authz.KindHTTP,
+		RemoteAddr: tcpAddr(r.RemoteAddr),
+		Token:      token,
+	}
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		req.LocalAddr = local
+	}
+	if r.TLS != nil {
+		req.PeerCertificates = r.TLS.PeerCertificates
+	}
+	// team mode removes the header itself once it has read it
+	if !s.config.Team.Enabled() {
+		r.Header.Del(TeamTokenHeader)
+	}
+
+	user, err := s.authz.Check(r.Context(), req)
+	if err != nil {
+		log.Printf("[authz] [%s] Rejected %s: %v", host, r.RemoteAddr, err)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="autotunnel"`)
+			http.Error(w, "not authorized for this route", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "not authorized for this route", http.StatusForbidden)
+		}
+		return false
+	}
+	// Like the header, Basic or Bearer credentials were meant for us, not the
+	// backend. Team mode removes them itself once it has read them.
+	if fromAuthorization && !s.config.Team.Enabled() && s.authz.TakesToken(host) {
+		r.Header.Del("Authorization")
+	}
+	if s.config.Verbose && user != "" {
+		log.Printf("[authz] [%s] user %s", host, user)
+	}
+	return true
+}
+
+// authorizeConn runs the route's authorizers for a TLS passthrough (KindTLS) or
+// mode: tcp (KindTCP) connection, which carries no token or client certificate
+// we can see
+func (s *Server) authorizeConn(conn net.Conn, host, kind string) bool {
+	if s.authz == nil {
+		return true
+	}
+	_, err := s.authz.Check(context.Background(), authz.Request{
+		Route:      host,
+		Kind:       kind,
+		LocalAddr:  conn.LocalAddr(),
+		RemoteAddr: conn.RemoteAddr(),
+	})
+	if err != nil {
+		log.Printf("[authz] [%s] Rejected %s: %v", host, conn.RemoteAddr(), err)
+		return false
+	}
+	return true
+}
+
+// tcpAddr parses an http.Request's RemoteAddr, or returns nil
+func tcpAddr(addr string) net.Addr {
+	a, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil
+	}
+	return a
+}
@@ -0,0 +1,103 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/authz"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_ServeHTTP_Authorizers(t *testing.T) {
+	var backendToken string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendToken = r.Header.Get(TeamTokenHeader)
+	}))
+	defer backend.Close()
+	backendPort := backend.Listener.Addr().(*net.TCPAddr).Port
+
+	cfg := testHTTPConfig()
+	cfg.Authorizers = []config.AuthorizerConfig{{Type: config.AuthorizerToken, Tokens: map[string]string{"ci": "ci-token-0123456789"}}}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"public.localhost": {Authorizers: []config.AuthorizerConfig{{Type: config.AuthorizerCommand, Command: []string{"true"}}}},
+	}
+	policy, err := authz.NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		token      string
+		wantStatus int
+	}{
+		{"no token", "api.localhost", "", http.StatusUnauthorized},
+		{"wrong token", "api.localhost", "nope-nope-nope-nope", http.StatusForbidden},
+		{"token", "api.localhost", "ci-token-0123456789", http.StatusOK},
+		{"route's own authorizer", "public.localhost", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendToken = ""
+			mockMgr := &mockManager{tunnel: &mockTunnel{running: true, localPort: backendPort}}
+			server := NewServer(cfg, mockMgr)
+			server.SetAuthorizer(policy)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = tt.host
+			if tt.token != "" {
+				req.Header.Set(TeamTokenHeader, tt.token)
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus != http.StatusOK && len(mockMgr.getCalls) != 0 {
+				t.Errorf("Expected no tunnel lookup for rejected request, got %v", mockMgr.getCalls)
+			}
+			if backendToken != "" {
+				t.Errorf("Token leaked to backend: %q", backendToken)
+			}
+		})
+	}
+}
+
+func TestServer_ServeHTTP_AuthorizersStripAuthorization(t *testing.T) {
+	var backendAuth []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendAuth = append(backendAuth, r.Header.Get("Authorization"))
+	}))
+	defer backend.Close()
+
+	cfg := testHTTPConfig()
+	cfg.Authorizers = []config.AuthorizerConfig{{Type: config.AuthorizerToken, Tokens: map[string]string{"ci": "ci-token-0123456789"}}}
+	policy, err := authz.NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	mockMgr := &mockManager{tunnel: &mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port}}
+	server := NewServer(cfg, mockMgr)
+	server.SetAuthorizer(policy)
+
+	bearer := httptest.NewRequest("GET", "/", nil)
+	bearer.Header.Set("Authorization", "Bearer ci-token-0123456789")
+	basic := httptest.NewRequest("GET", "/", nil)
+	basic.SetBasicAuth("ci", "ci-token-0123456789")
+
+	for _, req := range []*http.Request{bearer, basic} {
+		req.Host = "api.localhost"
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+	if len(backendAuth) != 2 || backendAuth[0] != "" || backendAuth[1] != "" {
+		t.Errorf("Authorization leaked to backend: %q", backendAuth)
+	}
+}
@@ -7,14 +7,22 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/atas/autotunnel/internal/authz"
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/devca"
+	"github.com/atas/autotunnel/internal/diag"
+	"github.com/atas/autotunnel/internal/muxconn"
+	"github.com/atas/autotunnel/internal/netutil"
 	"github.com/atas/autotunnel/internal/tunnelmgr"
+	"github.com/quic-go/quic-go/http3"
 )
 
 type Manager interface {
 	GetOrCreateTunnel(hostname string, scheme string) (tunnelmgr.TunnelHandle, error)
+	GetOrCreatePortTunnel(hostname string, port int) (tunnelmgr.TunnelHandle, error)
 }
 
 type Server struct {
@@ -24,26 +32,74 @@ type Server struct {
 	server               *http.Server
 	done                 chan struct{}
 	tlsErrorCertProvider *tlsErrorCertProvider
+	admin                http.Handler
+	http3Server          *http3.Server
+	http3Conn            net.PacketConn
+	altSvc               string       // Alt-Svc advertising the HTTP/3 port ("" = no HTTP/3)
+	edgeServer           *http.Server // serves edge-terminated TLS routes
+	h2cServer            *http.Server // serves HTTP/2 with prior knowledge, see h2c.go
+	traffic              trafficStats // per-hostname counts by protocol
+	authz                *authz.Policy
+	rawTCP               bool                     // some route has mode: tcp, see raw_tcp.go
+	local                map[string]*localBackend // http.local, by hostname
+	signatures           []string                 // http.signatures prefixes, see signatures.go
+
+	caOnce sync.Once
+	ca     *devca.CA
+	caErr  error
 }
 
 func NewServer(cfg *config.Config, mgr Manager) *Server {
 	// cert generation can fail (rare) - we just won't show TLS error pages then
 	certProvider, _ := newTLSErrorCertProvider()
 
-	return &Server{
+	s := &Server{
 		config:               cfg,
 		manager:              mgr,
 		done:                 make(chan struct{}),
 		tlsErrorCertProvider: certProvider,
 	}
+	s.rawTCP = s.hasRawTCPRoutes()
+	s.local = newLocalBackends(cfg)
+	s.signatures = signaturePrefixes(cfg)
+	return s
+}
+
+// SetAdminHandler serves h for requests addressed to http.status_host.
+// Must be called before Start.
+func (s *Server) SetAdminHandler(h http.Handler) {
+	s.admin = h
 }
 
+// Start binds the listeners and serves until Shutdown
 func (s *Server) Start() error {
-	mux, err := newMuxListener(s.config.HTTP.ListenAddr)
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen binds http.listen (and the HTTP/3 port) without serving yet, so bind
+// errors are returned right away
+func (s *Server) Listen() error {
+	mux, err := s.listen()
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", s.config.HTTP.ListenAddr, err)
+		return err
+	}
+
+	if s.config.HTTP.HTTP3.Listen != "" {
+		if err := s.startHTTP3(); err != nil {
+			_ = mux.Close()
+			return err
+		}
 	}
 	s.listener = mux
+	return nil
+}
+
+// Serve accepts connections on the listener bound by Listen until Shutdown
+func (s *Server) Serve() error {
+	mux := s.listener
 
 	s.server = &http.Server{
 		Handler:      s,
@@ -52,6 +108,13 @@ func (s *Server) Start() error {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	s.edgeServer = &http.Server{
+		Handler:      s,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
 	go func() {
 		err := s.server.Serve(mux.httpListener())
 		// these errors are expected during shutdown, don't spam the logs
@@ -60,8 +123,28 @@ func (s *Server) Start() error {
 		}
 	}()
 
-	log.Printf("Server listening on %s (HTTP + TLS passthrough)", s.config.HTTP.ListenAddr)
+	go func() {
+		if err := s.edgeServer.Serve(mux.edgeListener()); err != nil && err != http.ErrServerClosed && err != net.ErrClosed {
+			log.Printf("Edge TLS server error: %v", err)
+		}
+	}()
+
+	if s.config.HTTP.H2C {
+		s.h2cServer = newH2CServer(s)
+		go func() {
+			if err := s.h2cServer.Serve(mux.h2cListener()); err != nil && err != http.ErrServerClosed && err != net.ErrClosed {
+				log.Printf("h2c server error: %v", err)
+			}
+		}()
+	}
+
+	if s.config.TLSPassthroughEnabled() {
+		log.Printf("Server listening on %s (HTTP + TLS passthrough)", mux.Addr())
+	} else {
+		log.Printf("Server listening on %s (HTTP only, http.tls_passthrough is false)", mux.Addr())
+	}
 
+	acceptErrors := 0
 	for {
 		conn, err := mux.Listener.Accept()
 		if err != nil {
@@ -69,26 +152,75 @@ func (s *Server) Start() error {
 			case <-s.done:
 				return nil
 			default:
-				log.Printf("Accept error: %v", err)
-				continue
 			}
+			// e.g. after sleep/wake: back off, and give up so main can restart the listener
+			acceptErrors++
+			if acceptErrors >= maxAcceptErrors {
+				return fmt.Errorf("accepting on %s keeps failing: %w", mux.Addr(), err)
+			}
+			log.Printf("Accept error: %v", err)
+			time.Sleep(acceptRetryDelay(acceptErrors))
+			continue
 		}
+		acceptErrors = 0
 
 		go s.handleConnection(conn)
 	}
 }
 
+// maxAcceptErrors is how many accept errors in a row Serve tolerates before returning
+const maxAcceptErrors = 10
+
+// acceptRetryDelay backs off from 5ms up to 1s, like net/http
+func acceptRetryDelay(n int) time.Duration {
+	return min(5*time.Millisecond<<(n-1), time.Second)
+}
+
+// listen binds http.listen. If that's a privileged port we aren't allowed to bind,
+// it falls back to http.privileged_fallback_listen (if set) so an OS-level
+// redirect (pf, iptables) can forward the low port to us.
+func (s *Server) listen() (*muxListener, error) {
+	addr := s.config.HTTP.ListenAddr
+	mux, err := newMuxListener(addr)
+	if err == nil {
+		return mux, nil
+	}
+
+	port, _ := netutil.ListenPort(addr)
+	if !netutil.IsPermissionDenied(err) || !netutil.IsPrivilegedPort(port) {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	fallback := s.config.HTTP.PrivilegedFallbackListen
+	if fallback == "" {
+		return nil, fmt.Errorf("failed to listen on %s: %w (%s)", addr, err, netutil.PrivilegedPortHint(port))
+	}
+
+	mux, fallbackErr := newMuxListener(fallback)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w (fallback %s also failed: %v)", addr, err, fallback, fallbackErr)
+	}
+	log.Printf("Warning: cannot bind privileged port %d, listening on fallback %s instead. Redirect %d to it (see `autotunnel privileged-ports`)",
+		port, fallback, port)
+	return mux, nil
+}
+
 func (s *Server) handleConnection(conn net.Conn) {
-	peekConn := newPeekConn(conn)
+	defer diag.Recover("http")
+	peekConn := muxconn.NewConn(conn)
 
-	if peekConn.isTLS() {
+	if peekConn.IsTLS() && !s.config.TLSPassthroughEnabled() {
+		s.rejectTLS(peekConn)
+	} else if peekConn.IsTLS() {
 		s.handleTLSConnection(peekConn)
-	} else {
-		select {
-		case s.listener.httpConns <- peekConn:
-		case <-s.done:
-			_ = conn.Close()
-		}
+	} else if s.isHTTP2Preface(peekConn) {
+		s.handleH2C(peekConn)
+	} else if host := s.signatureRoute(peekConn); host != "" {
+		s.handleRawTCP(peekConn, host)
+	} else if host := s.rawTCPHost(peekConn); host != "" {
+		s.handleRawTCP(peekConn, host)
+	} else if !s.listener.http.Dispatch(peekConn) {
+		_ = conn.Close()
 	}
 }
 
@@ -101,5 +233,19 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	if s.server != nil {
 		_ = s.server.Close()
 	}
+	if s.edgeServer != nil {
+		_ = s.edgeServer.Close()
+	}
+	if s.h2cServer != nil {
+		_ = s.h2cServer.Close()
+	}
+	if s.http3Server != nil {
+		_ = s.http3Server.Close()
+		_ = s.http3Conn.Close()
+	}
+	// a reload builds new backends, so drop the kept-alive connections of these
+	for _, backend := range s.local {
+		backend.transport.CloseIdleConnections()
+	}
 	return nil
 }
@@ -4,76 +4,19 @@ import (
 	"bufio"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
-)
-
-func TestPeekConn_IsTLS(t *testing.T) {
-	tests := []struct {
-		name     string
-		data     []byte
-		expected bool
-	}{
-		{
-			name:     "TLS handshake starts with 0x16",
-			data:     []byte{0x16, 0x03, 0x01, 0x00, 0x05}, // TLS 1.0 handshake
-			expected: true,
-		},
-		{
-			name:     "TLS 1.2 handshake",
-			data:     []byte{0x16, 0x03, 0x03, 0x00, 0x05}, // TLS 1.2 handshake
-			expected: true,
-		},
-		{
-			name:     "HTTP GET request",
-			data:     []byte("GET / HTTP/1.1\r\n"),
-			expected: false,
-		},
-		{
-			name:     "HTTP POST request",
-			data:     []byte("POST /api HTTP/1.1\r\n"),
-			expected: false,
-		},
-		{
-			name:     "HTTP PUT request",
-			data:     []byte("PUT /resource HTTP/1.1\r\n"),
-			expected: false,
-		},
-		{
-			name:     "Random data not TLS",
-			data:     []byte{0x00, 0x01, 0x02, 0x03},
-			expected: false,
-		},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create a pipe to simulate a connection
-			client, server := net.Pipe()
-			defer func() { _ = client.Close() }()
-			defer func() { _ = server.Close() }()
-
-			// Write test data from client side
-			go func() {
-				_, _ = client.Write(tt.data)
-			}()
-
-			// Create peekConn and test
-			pc := newPeekConn(server)
-			result := pc.isTLS()
-
-			if result != tt.expected {
-				t.Errorf("isTLS() = %v, expected %v", result, tt.expected)
-			}
-		})
-	}
-}
+	"github.com/atas/autotunnel/internal/muxconn"
+)
 
 func TestExtractSNI(t *testing.T) {
 	tests := []struct {
@@ -185,13 +128,57 @@ func TestMuxListener_ProtocolDetection(t *testing.T) {
 		defer func() { _ = accepted.Close() }()
 
 		// Wrap and check
-		pc := newPeekConn(accepted)
-		if pc.isTLS() {
+		pc := muxconn.NewConn(accepted)
+		if pc.IsTLS() {
 			t.Error("HTTP connection incorrectly detected as TLS")
 		}
 	})
 }
 
+// failingListener wraps a real listener but fails every Accept, like after sleep/wake
+type failingListener struct {
+	net.Listener
+}
+
+func (l failingListener) Accept() (net.Conn, error) {
+	return nil, fmt.Errorf("accept: network is down")
+}
+
+func TestServer_ServeReturnsOnPersistentAcceptErrors(t *testing.T) {
+	cfg := testHTTPConfig()
+	cfg.HTTP.ListenAddr = "127.0.0.1:0"
+	s := NewServer(cfg, &mockManager{})
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer func() { _ = s.Shutdown(t.Context()) }()
+	s.listener.Listener.Listener = failingListener{s.listener.Listener.Listener}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Serve() }()
+
+	select {
+	case err := <-errCh:
+		if err == nil || !strings.Contains(err.Error(), "keeps failing") {
+			t.Errorf("Expected persistent accept error, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Serve did not give up on persistent accept errors")
+	}
+}
+
+func TestAcceptRetryDelay(t *testing.T) {
+	if d := acceptRetryDelay(1); d != 5*time.Millisecond {
+		t.Errorf("acceptRetryDelay(1) = %v, want 5ms", d)
+	}
+	if d := acceptRetryDelay(3); d != 20*time.Millisecond {
+		t.Errorf("acceptRetryDelay(3) = %v, want 20ms", d)
+	}
+	if d := acceptRetryDelay(20); d != time.Second {
+		t.Errorf("acceptRetryDelay(20) = %v, want 1s cap", d)
+	}
+}
+
 // generateClientHello creates a minimal TLS 1.2 ClientHello with SNI extension
 func generateClientHello(serverName string) []byte {
 	// This is a simplified ClientHello - in real tests you might use crypto/tls
@@ -409,41 +396,6 @@ func TestSNIExtraction_RealTLSClientHello(t *testing.T) {
 	}
 }
 
-func TestPeekConn_ReadAfterPeek(t *testing.T) {
-	// Verify that data can still be read after peeking
-	client, server := net.Pipe()
-	defer client.Close()
-	defer server.Close()
-
-	testData := []byte("GET /test HTTP/1.1\r\nHost: example.com\r\n\r\n")
-
-	go func() {
-		_, _ = client.Write(testData)
-		client.Close()
-	}()
-
-	pc := newPeekConn(server)
-
-	// Peek at first byte
-	peeked, err := pc.Peek(1)
-	if err != nil {
-		t.Fatalf("Peek failed: %v", err)
-	}
-	if peeked[0] != 'G' {
-		t.Errorf("Expected 'G', got %c", peeked[0])
-	}
-
-	// Now read all data - should get full content including peeked byte
-	all, err := io.ReadAll(pc)
-	if err != nil {
-		t.Fatalf("ReadAll failed: %v", err)
-	}
-
-	if string(all) != string(testData) {
-		t.Errorf("Expected %q, got %q", testData, all)
-	}
-}
-
 func TestMuxListener_HTTPListener(t *testing.T) {
 	mux, err := newMuxListener("127.0.0.1:0")
 	if err != nil {
@@ -461,3 +413,32 @@ func TestMuxListener_HTTPListener(t *testing.T) {
 		t.Error("httpListener.Addr() returned nil")
 	}
 }
+
+func TestServer_TLSPassthroughDisabled(t *testing.T) {
+	disabled := false
+	cfg := testHTTPConfig()
+	cfg.HTTP.ListenAddr = "127.0.0.1:0"
+	cfg.HTTP.TLSPassthrough = &disabled
+	mockMgr := &mockManager{}
+	s := NewServer(cfg, mockMgr)
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go func() { _ = s.Serve() }()
+	defer func() { _ = s.Shutdown(t.Context()) }()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write(generateClientHello("app.localhost"))
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected the connection to be closed right away, got %v", err)
+	}
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mockMgr.getCalls)
+	}
+}
@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"log"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+// signaturePrefixes lists the http.signatures prefixes, in order
+func signaturePrefixes(cfg *config.Config) []string {
+	prefixes := make([]string, len(cfg.HTTP.Signatures))
+	for i, sig := range cfg.HTTP.Signatures {
+		prefixes[i] = sig.Prefix
+	}
+	return prefixes
+}
+
+// signatureRoute returns the mode: tcp route http.signatures sends a plaintext
+// connection to, "" when its first bytes match no signature. Only protocols whose
+// client speaks first can match; a client waiting for a server banner is held
+// until PlaintextHostDeadline, then goes to the HTTP server.
+func (s *Server) signatureRoute(conn *muxconn.Conn) string {
+	if len(s.signatures) == 0 {
+		return ""
+	}
+	_ = conn.Conn.SetReadDeadline(time.Now().Add(PlaintextHostDeadline))
+	defer func() { _ = conn.Conn.SetReadDeadline(time.Time{}) }()
+
+	i := conn.MatchPrefix(s.signatures)
+	if i < 0 {
+		return ""
+	}
+	route := s.config.HTTP.Signatures[i].Route
+	if s.config.Verbose {
+		log.Printf("[tcp] [%s] Connection from %s matched signature %q", route, conn.RemoteAddr(), s.signatures[i])
+	}
+	return route
+}
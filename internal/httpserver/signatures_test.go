@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_RoutesBySignature(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	mockTun := &tlsMockTunnel{localPort: backend.Addr().(*net.TCPAddr).Port}
+	mockMgr := &tlsMockManager{tunnel: mockTun}
+	cfg := &config.Config{HTTP: config.HTTPConfig{
+		ListenAddr: ":8989",
+		Signatures: []config.ProtocolSignature{
+			{Prefix: "*1\r\n$4\r\nPING", Route: "redis.localhost"},
+			{Prefix: "SSH-", Route: "ssh.localhost"},
+		},
+		K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+			"ssh.localhost":   {Context: "test", Namespace: "ns", Service: "sshd", Port: 22, Mode: config.RouteModeTCP},
+			"redis.localhost": {Context: "test", Namespace: "ns", Service: "redis", Port: 6379, Mode: config.RouteModeTCP},
+		}},
+	}}
+	server := NewServer(cfg, mockMgr)
+
+	client, serverConn := net.Pipe()
+	defer client.Close()
+	go server.handleConnection(serverConn)
+
+	// an SSH client sends its banner without a header block, then waits
+	banner := "SSH-2.0-OpenSSH_9.6\r\n"
+	if _, err := client.Write([]byte(banner)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	echoed := make([]byte, len(banner))
+	if _, err := io.ReadFull(client, echoed); err != nil {
+		t.Fatalf("Failed to read echo: %v", err)
+	}
+	if string(echoed) != banner {
+		t.Errorf("Expected the banner echoed unchanged, got %q", echoed)
+	}
+	if calls := mockMgr.GetCalls(); len(calls) != 1 || calls[0] != "ssh.localhost" {
+		t.Errorf("Expected a tunnel for ssh.localhost, got %v", calls)
+	}
+}
+
+func TestServer_SignatureMismatchGoesToHTTP(t *testing.T) {
+	cfg := testHTTPConfig()
+	cfg.HTTP.ListenAddr = "127.0.0.1:0"
+	cfg.HTTP.EchoHost = "echo.localhost"
+	cfg.HTTP.Signatures = []config.ProtocolSignature{{Prefix: "SSH-", Route: "ssh.localhost"}}
+	server := NewServer(cfg, &mockManager{})
+	if err := server.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go func() { _ = server.Serve() }()
+	defer func() { _ = server.Shutdown(t.Context()) }()
+
+	// "S" is shared with "SSH-", the rest isn't
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte("SEARCH / HTTP/1.1\r\nHost: echo.localhost\r\n\r\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	status := make([]byte, len("HTTP/1.1 200"))
+	if _, err := io.ReadFull(conn, status); err != nil || string(status) != "HTTP/1.1 200" {
+		t.Errorf("Expected the HTTP server to answer, got %q (err %v)", status, err)
+	}
+}
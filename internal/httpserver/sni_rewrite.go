@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/atas/autotunnel/internal/muxconn"
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// upstreamSNI returns the server name to send the backend instead of the client's,
+// "" to pass the connection through untouched
+func (s *Server) upstreamSNI(sni string) string {
+	route, ok := s.routeFor(sni)
+	if !ok {
+		return ""
+	}
+	return route.UpstreamSNI
+}
+
+// rewriteSNI serves a passthrough connection whose route sets upstream_sni. Editing
+// server_name in the ClientHello we forward would break the handshake, since the
+// client's Finished message covers the ClientHello it sent, so TLS is terminated
+// here with the dev CA and re-originated to the backend under upstream_sni. The
+// backend is asked first, with the client's ALPN protocols, and the client is
+// then offered only the protocol the backend picked. It returns the decrypted bytes
+// the client sent and received.
+func (s *Server) rewriteSNI(conn *muxconn.Conn, clientHello []byte, sni, upstreamSNI string, backendConn net.Conn) (bytesIn, bytesOut int64) {
+	ca, err := s.devCA()
+	if err != nil {
+		log.Printf("[tls] [%s] Cannot terminate TLS for upstream_sni: %v", sni, err)
+		s.sendTLSErrorPage(conn.Conn, clientHello, sni, tlsErrorTermination, fmt.Sprintf("Cannot terminate TLS: %v", err))
+		return 0, 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TLSBackendDialTimeout)
+	defer cancel()
+	// like scheme: https backends, the certificate is not verified
+	backendTLS := tls.Client(backendConn, s.config.TLS.Apply(&tls.Config{
+		ServerName:         upstreamSNI,
+		NextProtos:         extractALPN(clientHello),
+		InsecureSkipVerify: true,
+	}))
+	if err := backendTLS.HandshakeContext(ctx); err != nil {
+		log.Printf("[tls] [%s] TLS handshake with backend as %s failed: %v", sni, upstreamSNI, err)
+		s.sendTLSErrorPage(conn.Conn, clientHello, sni, tlsErrorForwarding, fmt.Sprintf("TLS handshake with backend as %s failed: %v", upstreamSNI, err))
+		return 0, 0
+	}
+	defer backendTLS.Close()
+
+	var nextProtos []string
+	if proto := backendTLS.ConnectionState().NegotiatedProtocol; proto != "" {
+		nextProtos = []string{proto}
+	}
+	clientTLS := tls.Server(&replayConn{Conn: conn.Conn, initial: clientHello}, s.config.TLS.Apply(&tls.Config{
+		GetCertificate: ca.GetCertificate,
+		NextProtos:     nextProtos,
+	}))
+	_ = conn.Conn.SetDeadline(time.Now().Add(TLSClientHelloDeadline))
+	if err := clientTLS.Handshake(); err != nil {
+		if s.config.Verbose {
+			log.Printf("[tls] [%s] Client TLS handshake failed: %v", sni, err)
+		}
+		return 0, 0
+	}
+	_ = conn.Conn.SetDeadline(time.Time{})
+
+	if s.config.Verbose {
+		log.Printf("[tls] [%s] Re-originating TLS to the backend as %s", sni, upstreamSNI)
+	}
+	return netutil.BidirectionalCopyCounted(backendTLS, clientTLS)
+}
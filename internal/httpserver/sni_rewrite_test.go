@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
+)
+
+func TestHandleTLSConnection_UpstreamSNI(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var backendSNI string
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("rewritten"))
+	}))
+	backend.TLS = &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			backendSNI = hello.ServerName
+			return nil, nil
+		},
+	}
+	backend.StartTLS()
+	defer backend.Close()
+
+	cfg := testHTTPConfig()
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "ctx", Namespace: "apps", Service: "api", Port: 443, UpstreamSNI: "api.apps.svc.cluster.local"},
+	}
+	server := NewServer(cfg, &tlsMockManager{tunnel: &tlsMockTunnel{
+		running:   true,
+		localPort: backend.Listener.Addr().(*net.TCPAddr).Port,
+	}})
+
+	client, serverConn := net.Pipe()
+	defer client.Close()
+	go server.handleTLSConnection(muxconn.NewConn(serverConn))
+
+	tlsClient := tls.Client(client, &tls.Config{
+		ServerName:         "api.localhost",
+		NextProtos:         []string{"h2", "http/1.1"},
+		InsecureSkipVerify: true, // signed by the dev CA
+	})
+	if err := tlsClient.Handshake(); err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if proto := tlsClient.ConnectionState().NegotiatedProtocol; proto != "http/1.1" {
+		t.Errorf("Expected the backend's ALPN choice http/1.1, got %q", proto)
+	}
+	if backendSNI != "api.apps.svc.cluster.local" {
+		t.Errorf("Backend saw SNI %q, want upstream_sni", backendSNI)
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.localhost/", nil)
+	if err := req.Write(tlsClient); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(tlsClient), req)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "rewritten" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,36 @@
+package httpserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// staticRoute returns the http.static directory served for host, if any
+func (s *Server) staticRoute(host string) (config.StaticRouteConfig, bool) {
+	route, ok := s.config.HTTP.Static[host]
+	return route, ok
+}
+
+// serveStatic serves r from route's directory. Responses are revalidated on every
+// request so a rebuilt frontend shows up on reload. With spa, paths that aren't
+// files get index.html, leaving the route to the app's client-side router.
+func serveStatic(w http.ResponseWriter, r *http.Request, route config.StaticRouteConfig) {
+	w.Header().Set("Cache-Control", "no-cache")
+	if route.SPA && !staticFileExists(route.Dir, r.URL.Path) {
+		http.ServeFile(w, r, filepath.Join(route.Dir, "index.html"))
+		return
+	}
+	http.FileServer(http.Dir(route.Dir)).ServeHTTP(w, r)
+}
+
+// staticFileExists reports whether urlPath names a file or directory under dir
+func staticFileExists(dir, urlPath string) bool {
+	_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path.Clean("/"+urlPath))))
+	return !errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_ServesStatic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>app</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockMgr := &mockManager{}
+	cfg := testHTTPConfig()
+	cfg.HTTP.Static = map[string]config.StaticRouteConfig{
+		"web.localhost": {Dir: dir},
+		"spa.localhost": {Dir: dir, SPA: true},
+	}
+	server := NewServer(cfg, mockMgr)
+
+	tests := []struct {
+		host, path string
+		wantStatus int
+		wantBody   string
+	}{
+		{"web.localhost:8989", "/", http.StatusOK, "<h1>app</h1>"},
+		{"web.localhost", "/app.js", http.StatusOK, "console.log(1)"},
+		{"web.localhost", "/users/42", http.StatusNotFound, ""},
+		{"web.localhost", "/../../etc/passwd", http.StatusNotFound, ""},
+		{"spa.localhost", "/users/42", http.StatusOK, "<h1>app</h1>"},
+		{"spa.localhost", "/app.js", http.StatusOK, "console.log(1)"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = tt.path
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s%s: expected status %d, got %d", tt.host, tt.path, tt.wantStatus, w.Code)
+		}
+		if tt.wantBody != "" && !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%s%s: body %q doesn't contain %q", tt.host, tt.path, w.Body.String(), tt.wantBody)
+		}
+	}
+	if len(mockMgr.getCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mockMgr.getCalls)
+	}
+	if !server.terminatesTLS("web.localhost") {
+		t.Error("Expected TLS for a static host to be terminated locally")
+	}
+}
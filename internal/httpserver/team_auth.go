@@ -0,0 +1,133 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TeamTokenHeader carries a team token for clients that can't set Authorization
+const TeamTokenHeader = "X-Autotunnel-Token"
+
+// authorizeTeam enforces team mode for an HTTP request. It writes a 401/403 and
+// returns false when the request may not reach host. Credentials are removed from
+// the request so they are never forwarded to cluster services.
+func (s *Server) authorizeTeam(w http.ResponseWriter, r *http.Request, host string) bool {
+	team := s.config.Team
+	if !team.Enabled() {
+		return true
+	}
+
+	token, fromAuthorization := requestToken(r)
+	r.Header.Del(TeamTokenHeader)
+
+	name, ok := s.teamUserForToken(token)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="autotunnel"`)
+		http.Error(w, "autotunnel team token required", http.StatusUnauthorized)
+		return false
+	}
+	// Authorization may belong to the backend app when the token came from our header
+	if fromAuthorization {
+		r.Header.Del("Authorization")
+	}
+
+	// The status API and echo endpoint are available to every team member, but
+	// only admins may change things through it or read /debug
+	if strings.EqualFold(host, s.config.HTTP.StatusHost) {
+		if isAdminOnly(r) && !team.Users[name].Admin {
+			log.Printf("[team] [%s] user %s is not an admin: %s %s", host, name, r.Method, r.URL.Path)
+			http.Error(w, "admin access required", http.StatusForbidden)
+			return false
+		}
+	} else if !s.isEchoHost(host) && !team.Users[name].AllowsHost(host) {
+		log.Printf("[team] [%s] user %s is not allowed to use this route", host, name)
+		http.Error(w, "route not allowed for this user", http.StatusForbidden)
+		return false
+	}
+
+	if s.config.Verbose {
+		log.Printf("[team] [%s] user %s", host, name)
+	}
+	return true
+}
+
+// isAdminOnly reports whether a status API request needs a team admin: anything
+// but reading, and the /debug endpoints (logs, goroutine dumps, panics)
+func isAdminOnly(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return true
+	}
+	return isDebugPath(r.URL.Path)
+}
+
+func isDebugPath(p string) bool {
+	return p == "/debug" || strings.HasPrefix(p, "/debug/")
+}
+
+// authorizeDebug serves the status API's /debug endpoints only to clients on
+// this machine when there is no team mode to require an admin for them
+func (s *Server) authorizeDebug(w http.ResponseWriter, r *http.Request, host string) bool {
+	if s.config.Team.Enabled() || !strings.EqualFold(host, s.config.HTTP.StatusHost) || !isDebugPath(r.URL.Path) {
+		return true
+	}
+	if isLocalClient(r) {
+		return true
+	}
+	log.Printf("[admin] Refused %s %s from %s: only local clients may use /debug", r.Method, r.URL.Path, r.RemoteAddr)
+	http.Error(w, "the debug endpoints are only served to local clients", http.StatusForbidden)
+	return false
+}
+
+// isLocalClient reports whether r comes from loopback or from one of this
+// machine's addresses (a client dialing http.listen's own IP)
+func isLocalClient(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	localHost, _, err := net.SplitHostPort(local.String())
+	return err == nil && net.ParseIP(localHost).Equal(ip)
+}
+
+// teamUserForToken finds the user owning token, comparing in constant time
+func (s *Server) teamUserForToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	for name, user := range s.config.Team.Users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(user.ResolvedToken())) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// requestToken reads the token from X-Autotunnel-Token, a Bearer token, or the
+// password of Basic auth (so browsers can log in with any username).
+// fromAuthorization reports whether it came from the Authorization header.
+func requestToken(r *http.Request) (token string, fromAuthorization bool) {
+	if token := r.Header.Get(TeamTokenHeader); token != "" {
+		return token, false
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password, true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token), true
+	}
+	return "", false
+}
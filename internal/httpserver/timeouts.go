@@ -10,9 +10,10 @@ const (
 	// TLSClientHelloDeadline is the deadline for reading TLS ClientHello
 	TLSClientHelloDeadline = 10 * time.Second
 
-	// TLSTunnelStartTimeout is the timeout for starting a tunnel for TLS connections
-	TLSTunnelStartTimeout = 30 * time.Second
-
 	// TLSBackendDialTimeout is the timeout for dialing the backend service
 	TLSBackendDialTimeout = 10 * time.Second
+
+	// PlaintextHostDeadline is how long a plaintext connection may take to name its
+	// host when mode: tcp routes are configured
+	PlaintextHostDeadline = 10 * time.Second
 )
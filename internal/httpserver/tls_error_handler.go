@@ -17,6 +17,7 @@ const (
 	tlsErrorTunnelStartup
 	tlsErrorBackendConnection
 	tlsErrorForwarding
+	tlsErrorTermination
 )
 
 func (e tlsErrorType) statusCode() int {
@@ -33,7 +34,8 @@ func (e tlsErrorType) statusCode() int {
 // sendTLSErrorPage generates a self-signed cert, completes TLS handshake,
 // and returns an HTTP error. Without this, browsers just show "connection reset".
 func (s *Server) sendTLSErrorPage(conn net.Conn, clientHello []byte, hostname string, errType tlsErrorType, errMsg string) {
-	if s.tlsErrorCertProvider == nil {
+	// mode: tcp routes get their connection closed, whatever the protocol inside is
+	if s.tlsErrorCertProvider == nil || s.rawTCPRoute(hostname) {
 		return
 	}
 
@@ -52,10 +54,9 @@ func (s *Server) sendTLSErrorPage(conn net.Conn, clientHello []byte, hostname st
 		return
 	}
 
-	tlsConfig := &tls.Config{
+	tlsConfig := s.config.TLS.Apply(&tls.Config{
 		Certificates: []tls.Certificate{*cert},
-		MinVersion:   tls.VersionTLS12,
-	}
+	})
 
 	// we already consumed the ClientHello, need to replay it for TLS handshake
 	replayConn := &replayConn{
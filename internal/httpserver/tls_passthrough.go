@@ -7,17 +7,42 @@ import (
 	"net"
 	"time"
 
+	"github.com/atas/autotunnel/internal/authz"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
 	"github.com/atas/autotunnel/internal/netutil"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
 )
 
-func (s *Server) handleTLSConnection(conn *peekConn) {
-	defer conn.Close()
+// rejectTLS resets a TLS connection when http.tls_passthrough is false, without
+// reading its ClientHello
+func (s *Server) rejectTLS(conn *muxconn.Conn) {
+	log.Printf("[tls] Rejected a TLS connection from %s: http.tls_passthrough is false", conn.RemoteAddr())
+	// a reset rather than a FIN, so the client fails at once instead of on a short read
+	if tcp, ok := conn.Conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+func (s *Server) handleTLSConnection(conn *muxconn.Conn) {
+	// Read enough for ClientHello into a pooled buffer
+	helloBuf := netutil.GetBuffer()
+	buf := (*helloBuf)[:16384]
+
+	handedOff := false
+	defer func() {
+		// edge-terminated connections are closed by the edge HTTP server,
+		// which also keeps replaying the ClientHello from buf
+		if !handedOff {
+			_ = conn.Close()
+			netutil.PutBuffer(helloBuf)
+		}
+	}()
 
 	// give slow clients time to send ClientHello
 	_ = conn.Conn.SetReadDeadline(time.Now().Add(TLSClientHelloDeadline))
 
-	// Read enough for ClientHello
-	buf := make([]byte, 16384)
 	n, err := conn.Read(buf)
 	if err != nil {
 		if s.config.Verbose {
@@ -34,27 +59,45 @@ func (s *Server) handleTLSConnection(conn *peekConn) {
 		s.sendTLSErrorPage(conn.Conn, buf[:n], "", tlsErrorSNIExtraction, fmt.Sprintf("Failed to extract SNI: %v", err))
 		return
 	}
+	sni = config.NormalizeHostname(sni)
 
 	if s.config.Verbose {
 		log.Printf("[tls] [%s] New connection", sni)
 	}
 
-	tunnel, err := s.manager.GetOrCreateTunnel(sni, "https")
+	if s.terminatesTLS(sni) {
+		handedOff = s.handleEdgeTLS(conn, buf[:n], sni)
+		return
+	}
+
+	// Passthrough can't see credentials, so it's unavailable to team clients
+	if s.config.Team.Enabled() {
+		log.Printf("[tls] [%s] Rejected: TLS passthrough is disabled in team mode, use http:// or tls: terminate", sni)
+		return
+	}
+
+	if !s.authorizeConn(conn.Conn, sni, authz.KindTLS) {
+		return
+	}
+
+	s.traffic.record(sni, protoTLSPassthrough)
+	tunnel, err := s.passthroughTunnel(sni, buf[:n])
 	if err != nil {
 		log.Printf("[tls] [%s] Error: %v", sni, err)
+		if isUnrouted(err) {
+			s.traffic.recordUnrouted(sni)
+		}
 		s.sendTLSErrorPage(conn.Conn, buf[:n], sni, tlsErrorRouteNotFound, fmt.Sprintf("No service configured for host: %s", sni))
 		return
 	}
 
 	if !tunnel.IsRunning() {
-		ctx, cancel := context.WithTimeout(context.Background(), TLSTunnelStartTimeout)
-		if err := tunnel.Start(ctx); err != nil {
-			cancel()
+		// bounded by the route's ready_timeout
+		if err := tunnel.Start(context.Background()); err != nil {
 			log.Printf("[tls] [%s] Failed to start tunnel: %v", sni, err)
 			s.sendTLSErrorPage(conn.Conn, buf[:n], sni, tlsErrorTunnelStartup, fmt.Sprintf("Failed to start tunnel: %v", err))
 			return
 		}
-		cancel()
 	}
 
 	tunnel.Touch()
@@ -67,15 +110,44 @@ func (s *Server) handleTLSConnection(conn *peekConn) {
 		return
 	}
 	defer backendConn.Close()
+	closed := s.traffic.passthroughOpened(sni)
+
+	// the connection may carry a WebSocket or other long-lived stream
+	if tracker, ok := tunnel.(connTracker); ok {
+		defer tracker.TrackConn()()
+	}
+
+	if upstreamSNI := s.upstreamSNI(sni); upstreamSNI != "" {
+		closed(s.rewriteSNI(conn, buf[:n], sni, upstreamSNI, backendConn))
+		return
+	}
 
 	// replay the ClientHello we already read - backend hasn't seen it yet
 	if _, err := backendConn.Write(buf[:n]); err != nil {
 		log.Printf("[tls] [%s] Failed to forward ClientHello: %v", sni, err)
 		s.sendTLSErrorPage(conn.Conn, buf[:n], sni, tlsErrorForwarding, fmt.Sprintf("Failed to forward ClientHello: %v", err))
+		closed(0, 0)
 		return
 	}
 
-	netutil.BidirectionalCopy(backendConn, conn.Conn)
+	toBackend, toClient := netutil.BidirectionalCopyCounted(backendConn, conn.Conn)
+	closed(int64(n)+toBackend, toClient)
+}
+
+// passthroughTunnel picks the tunnel for a TLS connection: the route's alpn_ports
+// entry for the first protocol the client offers, otherwise the route's default port
+func (s *Server) passthroughTunnel(sni string, clientHello []byte) (tunnelmgr.TunnelHandle, error) {
+	if route, ok := s.config.HTTP.K8s.Routes[sni]; ok && len(route.ALPNPorts) > 0 {
+		for _, proto := range extractALPN(clientHello) {
+			if port, ok := route.ALPNPorts[proto]; ok {
+				if s.config.Verbose {
+					log.Printf("[tls] [%s] ALPN %s -> port %d", sni, proto, port)
+				}
+				return s.manager.GetOrCreatePortTunnel(sni, port)
+			}
+		}
+	}
+	return s.manager.GetOrCreateTunnel(sni, "https")
 }
 
 // extractSNI parses the TLS ClientHello to find the Server Name Indication.
@@ -186,3 +258,50 @@ func findSNIInExtensions(extensions []byte, extLen int) (string, error) {
 
 	return "", fmt.Errorf("SNI extension not found")
 }
+
+// extractALPN returns the ALPN protocols offered in a ClientHello, in client preference
+// order. Returns nil if the ClientHello can't be parsed or has no ALPN extension.
+func extractALPN(data []byte) []string {
+	handshake, err := validateTLSHandshake(data)
+	if err != nil {
+		return nil
+	}
+	extStart, extLen, err := skipToExtensions(handshake)
+	if err != nil {
+		return nil
+	}
+
+	extensions := handshake[extStart:]
+	pos := 0
+	for pos+4 <= extLen && pos+4 <= len(extensions) {
+		extType := int(extensions[pos])<<8 | int(extensions[pos+1])
+		thisExtLen := int(extensions[pos+2])<<8 | int(extensions[pos+3])
+		pos += 4
+
+		if pos+thisExtLen > len(extensions) {
+			return nil
+		}
+
+		// ALPN is extension type 0x0010: list length(2), then length(1)-prefixed names
+		if extType == 0x0010 {
+			extData := extensions[pos : pos+thisExtLen]
+			if len(extData) < 2 {
+				return nil
+			}
+			var protos []string
+			for i := 2; i < len(extData); {
+				nameLen := int(extData[i])
+				i++
+				if i+nameLen > len(extData) {
+					return nil
+				}
+				protos = append(protos, string(extData[i:i+nameLen]))
+				i += nameLen
+			}
+			return protos
+		}
+
+		pos += thisExtLen
+	}
+	return nil
+}
@@ -2,14 +2,17 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"io"
 	"net"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
 	"github.com/atas/autotunnel/internal/tunnel"
 	"github.com/atas/autotunnel/internal/tunnelmgr"
 )
@@ -34,6 +37,7 @@ func (m *tlsMockTunnel) Start(ctx context.Context) error {
 }
 func (m *tlsMockTunnel) Stop()                       {}
 func (m *tlsMockTunnel) LocalPort() int              { return m.localPort }
+func (m *tlsMockTunnel) LocalPortFor(port int) int   { return m.localPort }
 func (m *tlsMockTunnel) Scheme() string              { return "https" }
 func (m *tlsMockTunnel) Touch()                      { m.touchCalled = true }
 func (m *tlsMockTunnel) IdleDuration() time.Duration { return 0 }
@@ -57,6 +61,13 @@ func (m *tlsMockManager) GetOrCreateTunnel(hostname string, scheme string) (tunn
 	return m.tunnel, m.err
 }
 
+func (m *tlsMockManager) GetOrCreatePortTunnel(hostname string, port int) (tunnelmgr.TunnelHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.getCalls = append(m.getCalls, hostname)
+	return m.tunnel, m.err
+}
+
 func (m *tlsMockManager) GetCalls() []string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -199,7 +210,7 @@ func TestHandleTLSConnection_Success(t *testing.T) {
 	}()
 
 	// Handle the connection
-	pc := newPeekConn(serverConn)
+	pc := muxconn.NewConn(serverConn)
 	handlerDone := make(chan struct{})
 	go func() {
 		defer close(handlerDone)
@@ -259,7 +270,7 @@ func TestHandleTLSConnection_UnknownSNI(t *testing.T) {
 		client.Close()
 	}()
 
-	pc := newPeekConn(serverConn)
+	pc := muxconn.NewConn(serverConn)
 	server.handleTLSConnection(pc)
 
 	// Should have tried to look up the unknown host
@@ -296,7 +307,7 @@ func TestHandleTLSConnection_TunnelStartError(t *testing.T) {
 		client.Close()
 	}()
 
-	pc := newPeekConn(serverConn)
+	pc := muxconn.NewConn(serverConn)
 	server.handleTLSConnection(pc)
 
 	if !mockTun.startCalled {
@@ -376,3 +387,108 @@ func TestForwardClientHello(t *testing.T) {
 	}
 }
 
+// captureClientHello returns the ClientHello a real crypto/tls client sends
+func captureClientHello(t *testing.T, serverName string, nextProtos []string) []byte {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		conn := tls.Client(client, &tls.Config{ServerName: serverName, NextProtos: nextProtos, InsecureSkipVerify: true})
+		_ = conn.Handshake() // fails once the pipe closes
+		_ = client.Close()
+	}()
+
+	buf := make([]byte, 16384)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read ClientHello: %v", err)
+	}
+	return buf[:n]
+}
+
+func TestExtractALPN(t *testing.T) {
+	tests := []struct {
+		name       string
+		nextProtos []string
+	}{
+		{name: "grpc client", nextProtos: []string{"h2"}},
+		{name: "browser", nextProtos: []string{"h2", "http/1.1"}},
+		{name: "no ALPN", nextProtos: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractALPN(captureClientHello(t, "grpc.localhost", tt.nextProtos))
+			if !reflect.DeepEqual(got, tt.nextProtos) {
+				t.Errorf("extractALPN() = %v, want %v", got, tt.nextProtos)
+			}
+		})
+	}
+
+	if got := extractALPN([]byte{0x16, 0x03}); got != nil {
+		t.Errorf("Expected nil for truncated data, got %v", got)
+	}
+}
+
+func TestServer_PassthroughTunnel_ALPNPorts(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{
+			ListenAddr: ":8989",
+			K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+				"grpc.localhost": {Port: 443, ALPNPorts: map[string]int{"h2": 8443}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		nextProtos []string
+		wantPort   int // 0 = route default via GetOrCreateTunnel
+	}{
+		{name: "h2 goes to alpn port", nextProtos: []string{"h2", "http/1.1"}, wantPort: 8443},
+		{name: "http/1.1 uses default port", nextProtos: []string{"http/1.1"}},
+		{name: "no ALPN uses default port", nextProtos: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMgr := &mockManager{tunnel: &mockTunnel{}}
+			server := NewServer(cfg, mockMgr)
+
+			if _, err := server.passthroughTunnel("grpc.localhost", captureClientHello(t, "grpc.localhost", tt.nextProtos)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.wantPort == 0 {
+				if len(mockMgr.portCalls) != 0 || len(mockMgr.getSchemes) != 1 {
+					t.Errorf("Expected default tunnel, got port calls %v", mockMgr.portCalls)
+				}
+				return
+			}
+			if len(mockMgr.portCalls) != 1 || mockMgr.portCalls[0] != tt.wantPort {
+				t.Errorf("Expected port tunnel %d, got %v", tt.wantPort, mockMgr.portCalls)
+			}
+		})
+	}
+}
+
+func TestHandleTLSConnection_NormalizesSNI(t *testing.T) {
+	mockMgr := &tlsMockManager{err: errors.New("no route configured")}
+	server := NewServer(&config.Config{HTTP: config.HTTPConfig{ListenAddr: ":8989"}}, mockMgr)
+
+	client, serverConn := net.Pipe()
+	defer serverConn.Close()
+
+	go func() {
+		_, _ = client.Write(generateClientHello("MyApp.Localhost"))
+		time.Sleep(50 * time.Millisecond)
+		client.Close()
+	}()
+
+	server.handleTLSConnection(muxconn.NewConn(serverConn))
+
+	if len(mockMgr.getCalls) != 1 || mockMgr.getCalls[0] != "myapp.localhost" {
+		t.Errorf("Expected lookup for myapp.localhost, got %v", mockMgr.getCalls)
+	}
+}
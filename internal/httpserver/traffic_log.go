@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// podNamer is implemented by tunnels that know which pod they forward to
+type podNamer interface {
+	PodName() string
+}
+
+// countingWriter records the status and body size of a proxied response
+type countingWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses (SSE, chunked) flowing through the proxy
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (hijacking for upgrades)
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// trafficThresholds reports whether slow or large responses should be logged at all
+func (s *Server) trafficThresholds() bool {
+	return s.config.HTTP.SlowRequestThreshold > 0 || s.config.HTTP.LargeResponseThresholdMB > 0
+}
+
+// logTraffic warns about a request that was slower or larger than the configured
+// thresholds, with the route and pod it went to
+func (s *Server) logTraffic(r *http.Request, host, requestID string, tun tunnelmgr.TunnelHandle, cw *countingWriter, elapsed time.Duration) {
+	if cw.status == http.StatusSwitchingProtocols {
+		return // WebSockets and other upgrades are long-lived by design
+	}
+
+	var problems []string
+	if limit := s.config.HTTP.SlowRequestThreshold; limit > 0 && elapsed > limit {
+		problems = append(problems, fmt.Sprintf("slow request (%v)", elapsed.Round(time.Millisecond)))
+	}
+	if limit := int64(s.config.HTTP.LargeResponseThresholdMB) << 20; limit > 0 && cw.bytes > limit {
+		problems = append(problems, fmt.Sprintf("large response (%.1f MB)", float64(cw.bytes)/(1<<20)))
+	}
+	if len(problems) == 0 {
+		return
+	}
+
+	details := fmt.Sprintf("status %d", cw.status)
+	if route, ok := s.routeFor(host); ok {
+		details += fmt.Sprintf(", route %s/%s %s", route.Context, route.Namespace, targetDisplay(route))
+	}
+	if namer, ok := tun.(podNamer); ok && namer.PodName() != "" {
+		details += ", pod " + namer.PodName()
+	}
+
+	for _, problem := range problems {
+		log.Printf("[http] [%s] [%s] Warning: %s: %s %s (%s)", host, requestID, problem, r.Method, r.URL.Path, details)
+	}
+}
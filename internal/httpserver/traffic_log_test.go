@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// podMockTunnel is a mockTunnel that knows its pod
+type podMockTunnel struct {
+	*mockTunnel
+	pod string
+}
+
+func (m *podMockTunnel) PodName() string { return m.pod }
+
+// captureLog collects log output for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func trafficServer(t *testing.T, handler http.HandlerFunc) (*Server, func() string) {
+	t.Helper()
+	backend := httptest.NewServer(handler)
+	t.Cleanup(backend.Close)
+
+	cfg := testHTTPConfig()
+	cfg.HTTP.SlowRequestThreshold = 50 * time.Millisecond
+	cfg.HTTP.LargeResponseThresholdMB = 1
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "prod", Namespace: "apps", Service: "api", Port: 80},
+	}
+	tun := &podMockTunnel{
+		mockTunnel: &mockTunnel{running: true, scheme: "http", localPort: backend.Listener.Addr().(*net.TCPAddr).Port},
+		pod:        "api-7d9f8-x2k4q",
+	}
+	server := NewServer(cfg, &mockManager{tunnel: tun})
+
+	logs := captureLog(t)
+	serve := func() string {
+		req := httptest.NewRequest("GET", "/report", nil)
+		req.Host = "api.localhost"
+		server.ServeHTTP(httptest.NewRecorder(), req)
+		return logs.String()
+	}
+	return server, serve
+}
+
+func TestServeHTTP_LogsSlowRequest(t *testing.T) {
+	_, serve := trafficServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	out := serve()
+	for _, want := range []string{"slow request", "GET /report", "status 200", "route prod/apps svc/api:80", "pod api-7d9f8-x2k4q"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected log to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "large response") {
+		t.Errorf("Did not expect a large response warning, got:\n%s", out)
+	}
+}
+
+func TestServeHTTP_LogsLargeResponse(t *testing.T) {
+	_, serve := trafficServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 2<<20))
+	})
+
+	out := serve()
+	if !strings.Contains(out, "large response (2.0 MB)") {
+		t.Errorf("Expected a large response warning, got:\n%s", out)
+	}
+	if strings.Contains(out, "slow request") {
+		t.Errorf("Did not expect a slow request warning, got:\n%s", out)
+	}
+}
+
+func TestServeHTTP_NoTrafficWarningUnderThresholds(t *testing.T) {
+	_, serve := trafficServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	if out := serve(); strings.Contains(out, "Warning") {
+		t.Errorf("Expected no warnings, got:\n%s", out)
+	}
+}
@@ -0,0 +1,179 @@
+package httpserver
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// Protocols counted per hostname
+const (
+	protoHTTP           = "http"            // plain HTTP request
+	protoTLSPassthrough = "tls_passthrough" // TLS connection passed through by SNI
+	protoTLSTerminated  = "tls_terminated"  // request over TLS we terminated (tls: terminate)
+	protoHTTP3          = "http3"           // request over QUIC
+	protoRawTCP         = "tcp"             // plaintext connection spliced for a mode: tcp route
+)
+
+// maxTrafficHosts bounds the stats map; further hostnames are counted under otherTrafficHost
+const maxTrafficHosts = 1000
+
+const otherTrafficHost = "(other)"
+
+// HostTraffic counts how clients reached one hostname since the server started.
+// Unrouted counts requests and connections for which no route matched, which is
+// what a mistyped hostname or an SNI mismatch looks like.
+type HostTraffic struct {
+	Hostname       string    `json:"hostname"`
+	HTTP           int64     `json:"http_requests"`
+	TLSPassthrough int64     `json:"tls_passthrough_connections"`
+	TLSTerminated  int64     `json:"tls_terminated_requests"`
+	HTTP3          int64     `json:"http3_requests"`
+	RawTCP         int64     `json:"tcp_connections,omitempty"`
+	Unrouted       int64     `json:"unrouted,omitempty"`
+	LastProtocol   string    `json:"last_protocol"`
+	LastSeen       time.Time `json:"last_seen"`
+
+	// Passthrough follows TLS passthrough connections past the handshake, which
+	// are spliced to the backend without passing through the HTTP handler
+	Passthrough *PassthroughTraffic `json:"tls_passthrough,omitempty"`
+}
+
+// PassthroughTraffic measures the TLS passthrough connections to one hostname that
+// reached a backend. BytesIn is what the client sent, ClientHello included;
+// BytesOut is what the backend sent back.
+type PassthroughTraffic struct {
+	Open         int64   `json:"open"`
+	Closed       int64   `json:"closed"`
+	BytesIn      int64   `json:"bytes_in"`
+	BytesOut     int64   `json:"bytes_out"`
+	TotalSeconds float64 `json:"total_seconds"`
+	MaxSeconds   float64 `json:"max_seconds"`
+}
+
+type trafficStats struct {
+	mu    sync.Mutex
+	hosts map[string]*HostTraffic
+}
+
+// record counts one request or connection for host over proto
+func (t *trafficStats) record(host, proto string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.entry(host)
+	switch proto {
+	case protoHTTP:
+		h.HTTP++
+	case protoTLSPassthrough:
+		h.TLSPassthrough++
+	case protoTLSTerminated:
+		h.TLSTerminated++
+	case protoHTTP3:
+		h.HTTP3++
+	case protoRawTCP:
+		h.RawTCP++
+	}
+	h.LastProtocol = proto
+	h.LastSeen = time.Now()
+}
+
+// recordUnrouted counts a request or connection to host that matched no route
+func (t *trafficStats) recordUnrouted(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entry(host).Unrouted++
+}
+
+// passthroughOpened counts a passthrough connection to host that reached its
+// backend. The returned func records it closed, with the bytes each way.
+func (t *trafficStats) passthroughOpened(host string) func(bytesIn, bytesOut int64) {
+	start := time.Now()
+	t.mu.Lock()
+	p := t.passthrough(host)
+	p.Open++
+	t.mu.Unlock()
+
+	return func(bytesIn, bytesOut int64) {
+		elapsed := time.Since(start).Seconds()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		p.Open--
+		p.Closed++
+		p.BytesIn += bytesIn
+		p.BytesOut += bytesOut
+		p.TotalSeconds += elapsed
+		p.MaxSeconds = max(p.MaxSeconds, elapsed)
+	}
+}
+
+// passthrough returns host's passthrough counters, creating them if needed.
+// Caller must hold t.mu.
+func (t *trafficStats) passthrough(host string) *PassthroughTraffic {
+	h := t.entry(host)
+	if h.Passthrough == nil {
+		h.Passthrough = &PassthroughTraffic{}
+	}
+	return h.Passthrough
+}
+
+// entry returns host's counters, creating them if needed. Caller must hold t.mu.
+func (t *trafficStats) entry(host string) *HostTraffic {
+	if t.hosts == nil {
+		t.hosts = make(map[string]*HostTraffic)
+	}
+	if h, ok := t.hosts[host]; ok {
+		return h
+	}
+	if len(t.hosts) >= maxTrafficHosts {
+		host = otherTrafficHost
+		if h, ok := t.hosts[host]; ok {
+			return h
+		}
+	}
+	h := &HostTraffic{Hostname: host}
+	t.hosts[host] = h
+	return h
+}
+
+func (t *trafficStats) snapshot() []HostTraffic {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hosts := make([]HostTraffic, 0, len(t.hosts))
+	for _, h := range t.hosts {
+		c := *h
+		if h.Passthrough != nil {
+			p := *h.Passthrough
+			c.Passthrough = &p
+		}
+		hosts = append(hosts, c)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+	return hosts
+}
+
+// isUnrouted reports whether a tunnel lookup failed because no route matched
+func isUnrouted(err error) bool {
+	return tunnel.FailedStep(err) == tunnel.StepRouteLookup
+}
+
+// requestProtocol returns how r reached us: plain HTTP, TLS we terminated, or HTTP/3
+func requestProtocol(r *http.Request) string {
+	switch {
+	case r.ProtoMajor == 3:
+		return protoHTTP3
+	case r.TLS != nil:
+		return protoTLSTerminated
+	default:
+		return protoHTTP
+	}
+}
+
+// Traffic returns per-hostname request and connection counts by protocol, sorted by hostname
+func (s *Server) Traffic() []HostTraffic {
+	return s.traffic.snapshot()
+}
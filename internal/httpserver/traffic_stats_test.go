@@ -0,0 +1,163 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/muxconn"
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+func TestTrafficStats_Record(t *testing.T) {
+	var stats trafficStats
+	stats.record("app.localhost", protoHTTP)
+	stats.record("app.localhost", protoHTTP)
+	stats.record("app.localhost", protoTLSPassthrough)
+	stats.record("api.localhost", protoHTTP3)
+	stats.recordUnrouted("api.localhost")
+
+	hosts := stats.snapshot()
+	if len(hosts) != 2 || hosts[0].Hostname != "api.localhost" || hosts[1].Hostname != "app.localhost" {
+		t.Fatalf("Expected api.localhost and app.localhost, got %+v", hosts)
+	}
+	app := hosts[1]
+	if app.HTTP != 2 || app.TLSPassthrough != 1 || app.LastProtocol != protoTLSPassthrough {
+		t.Errorf("Unexpected app.localhost counts %+v", app)
+	}
+	if hosts[0].HTTP3 != 1 || hosts[0].Unrouted != 1 {
+		t.Errorf("Unexpected api.localhost counts %+v", hosts[0])
+	}
+}
+
+func TestTrafficStats_BoundsHostnames(t *testing.T) {
+	var stats trafficStats
+	for i := range maxTrafficHosts + 5 {
+		stats.record(fmt.Sprintf("h%d.localhost", i), protoHTTP)
+	}
+	hosts := stats.snapshot()
+	if len(hosts) != maxTrafficHosts+1 {
+		t.Fatalf("Expected %d entries, got %d", maxTrafficHosts+1, len(hosts))
+	}
+	for _, h := range hosts {
+		if h.Hostname == otherTrafficHost && h.HTTP != 5 {
+			t.Errorf("Expected 5 requests under %s, got %d", otherTrafficHost, h.HTTP)
+		}
+	}
+}
+
+func TestRequestProtocol(t *testing.T) {
+	plain := httptest.NewRequest("GET", "/", nil)
+	terminated := httptest.NewRequest("GET", "/", nil)
+	terminated.TLS = &tls.ConnectionState{}
+	quic := httptest.NewRequest("GET", "/", nil)
+	quic.TLS = &tls.ConnectionState{}
+	quic.ProtoMajor = 3
+
+	for _, tt := range []struct {
+		name string
+		want string
+		got  string
+	}{
+		{"plain", protoHTTP, requestProtocol(plain)},
+		{"terminated", protoTLSTerminated, requestProtocol(terminated)},
+		{"http3", protoHTTP3, requestProtocol(quic)},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s: requestProtocol = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestServer_CountsTrafficByProtocol(t *testing.T) {
+	noRoute := &tunnel.StepError{Step: tunnel.StepRouteLookup, Err: errors.New("no route configured")}
+	server := NewServer(&config.Config{HTTP: config.HTTPConfig{ListenAddr: ":8989"}}, &mockManager{err: noRoute})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "typo.localhost"
+	server.ServeHTTP(httptest.NewRecorder(), req)
+
+	client, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		_, _ = client.Write(generateClientHello("typo.localhost"))
+		time.Sleep(50 * time.Millisecond)
+		client.Close()
+	}()
+	server.handleTLSConnection(muxconn.NewConn(serverConn))
+
+	hosts := server.Traffic()
+	if len(hosts) != 1 {
+		t.Fatalf("Expected 1 hostname, got %+v", hosts)
+	}
+	if h := hosts[0]; h.HTTP != 1 || h.TLSPassthrough != 1 || h.Unrouted != 2 {
+		t.Errorf("Expected 1 http request, 1 passthrough connection and 2 unrouted, got %+v", h)
+	}
+}
+
+func TestTrafficStats_Passthrough(t *testing.T) {
+	var stats trafficStats
+	first := stats.passthroughOpened("app.localhost")
+	second := stats.passthroughOpened("app.localhost")
+
+	if p := stats.snapshot()[0].Passthrough; p == nil || p.Open != 2 || p.Closed != 0 {
+		t.Fatalf("Expected 2 open connections, got %+v", p)
+	}
+
+	first(100, 2000)
+	time.Sleep(10 * time.Millisecond)
+	second(50, 0)
+
+	p := stats.snapshot()[0].Passthrough
+	if p.Open != 0 || p.Closed != 2 || p.BytesIn != 150 || p.BytesOut != 2000 {
+		t.Errorf("Unexpected passthrough counts %+v", p)
+	}
+	if p.MaxSeconds < 0.01 || p.TotalSeconds < p.MaxSeconds {
+		t.Errorf("Unexpected durations: total %v, max %v", p.TotalSeconds, p.MaxSeconds)
+	}
+}
+
+func TestServer_CountsPassthroughBytes(t *testing.T) {
+	clientHello := generateClientHello("app.localhost")
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.ReadFull(conn, make([]byte, len(clientHello)))
+		_, _ = conn.Write([]byte("server hello"))
+	}()
+
+	mockTun := &tlsMockTunnel{running: true, localPort: backend.Addr().(*net.TCPAddr).Port}
+	server := NewServer(&config.Config{HTTP: config.HTTPConfig{ListenAddr: ":8989"}}, &tlsMockManager{tunnel: mockTun})
+
+	client, serverConn := net.Pipe()
+	go func() {
+		_, _ = client.Write(clientHello)
+		// net.Pipe can't half-close, so stop after the backend's reply
+		_, _ = io.ReadFull(client, make([]byte, len("server hello")))
+		client.Close()
+	}()
+	server.handleTLSConnection(muxconn.NewConn(serverConn))
+	serverConn.Close()
+
+	p := server.Traffic()[0].Passthrough
+	if p == nil || p.Open != 0 || p.Closed != 1 {
+		t.Fatalf("Expected 1 closed passthrough connection, got %+v", p)
+	}
+	if p.BytesIn != int64(len(clientHello)) || p.BytesOut != int64(len("server hello")) {
+		t.Errorf("Expected %d bytes in and %d out, got %+v", len(clientHello), len("server hello"), p)
+	}
+}
@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// upstreamTransport returns the transport and target URL for proxying host's
+// requests to an https backend on localPort. Without upstream_tls the backend's
+// certificate is accepted as is. With it, requests are addressed to the route's
+// server_name and every dial is resolved to the tunnel, so the certificate is
+// verified for that name even though the connection goes to 127.0.0.1.
+func (s *Server) upstreamTransport(host string, localPort int) (*http.Transport, *url.URL, error) {
+	tunnelAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort))
+
+	route, ok := s.config.HTTP.K8s.Routes[host]
+	route, _ = route.ForScheme("http")
+	if !ok || route.UpstreamTLS == nil {
+		return &http.Transport{
+			TLSClientConfig: s.config.TLS.Apply(&tls.Config{InsecureSkipVerify: true}),
+		}, &url.URL{Scheme: "https", Host: tunnelAddr}, nil
+	}
+
+	serverName := route.GetServerName()
+	tlsConfig := s.config.TLS.Apply(&tls.Config{ServerName: serverName})
+	if caFile := route.UpstreamTLS.GetCAFile(); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read upstream_tls.ca_file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+
+	var dialer net.Dialer
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		// server_name resolves to the tunnel, whatever DNS says about it
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, tunnelAddr)
+		},
+	}
+	return transport, &url.URL{Scheme: "https", Host: net.JoinHostPort(serverName, strconv.Itoa(localPort))}, nil
+}
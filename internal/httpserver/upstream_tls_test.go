@@ -0,0 +1,65 @@
+package httpserver
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_ServeHTTP_UpstreamTLS(t *testing.T) {
+	var sni string
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sni = r.TLS.ServerName
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.StartTLS()
+	defer backend.Close()
+
+	// httptest's certificate is for example.com
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		upstream   *config.UpstreamTLSConfig
+		wantStatus int
+	}{
+		{"matching server_name", &config.UpstreamTLSConfig{ServerName: "example.com", CAFile: caFile}, http.StatusOK},
+		{"wrong server_name", &config.UpstreamTLSConfig{ServerName: "api.internal", CAFile: caFile}, http.StatusBadGateway},
+		{"untrusted CA", &config.UpstreamTLSConfig{ServerName: "example.com"}, http.StatusBadGateway},
+		{"missing ca_file", &config.UpstreamTLSConfig{ServerName: "example.com", CAFile: filepath.Join(t.TempDir(), "none.pem")}, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sni = ""
+			cfg := testHTTPConfig()
+			cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+				"secure.localhost": {Context: "test", Namespace: "ns", Service: "api", Port: 443, Scheme: "https", UpstreamTLS: tt.upstream},
+			}
+			tun := &mockTunnel{running: true, localPort: backend.Listener.Addr().(*net.TCPAddr).Port, scheme: "https"}
+			server := NewServer(cfg, &mockManager{tunnel: tun})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = "secure.localhost"
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && sni != "example.com" {
+				t.Errorf("Backend saw SNI %q, want example.com", sni)
+			}
+		})
+	}
+}
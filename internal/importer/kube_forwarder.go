@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// kube-forwarder (https://kube-forwarder.pixelpoint.io) export format.
+// Exports are either {"clusters": [...]} or a bare array of clusters.
+type kubeForwarderCluster struct {
+	Name     string                 `json:"name"`
+	Services []kubeForwarderService `json:"services"`
+}
+
+type kubeForwarderService struct {
+	Alias        string `json:"alias"`
+	Namespace    string `json:"namespace"`
+	WorkloadType string `json:"workloadType"` // "service", "pod" or "deployment"
+	WorkloadName string `json:"workloadName"`
+	Forwards     []struct {
+		LocalPort  int `json:"localPort"`
+		RemotePort int `json:"remotePort"`
+	} `json:"forwards"`
+}
+
+// ParseKubeForwarder converts a kube-forwarder JSON export into TCP routes.
+// Cluster names are used as kube contexts unless contextOverride is set.
+func ParseKubeForwarder(data []byte, contextOverride string, routes *Routes) error {
+	var clusters []kubeForwarderCluster
+	var wrapped struct {
+		Clusters []kubeForwarderCluster `json:"clusters"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Clusters != nil {
+		clusters = wrapped.Clusters
+	} else if err := json.Unmarshal(data, &clusters); err != nil {
+		return fmt.Errorf("failed to parse kube-forwarder export: %w", err)
+	}
+
+	for _, cluster := range clusters {
+		contextName := cluster.Name
+		if contextOverride != "" {
+			contextName = contextOverride
+		}
+
+		for _, svc := range cluster.Services {
+			name := svc.Alias
+			if name == "" {
+				name = svc.WorkloadName
+			}
+			if svc.WorkloadType != "service" && svc.WorkloadType != "pod" {
+				routes.warnf("skipping %q: workload type %q is not supported (use a service or pod)", name, svc.WorkloadType)
+				continue
+			}
+			namespace := svc.Namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+
+			for _, fwd := range svc.Forwards {
+				if routes.portTaken(fwd.LocalPort, fmt.Sprintf("%q port %d", name, fwd.RemotePort)) {
+					continue
+				}
+				routes.TCP[fwd.LocalPort] = tcpRoute(contextName, namespace, svc.WorkloadType, svc.WorkloadName, fwd.RemotePort)
+			}
+		}
+	}
+	return nil
+}
+
+func tcpRoute(contextName, namespace, workloadType, name string, port int) config.TCPRouteConfig {
+	route := config.TCPRouteConfig{
+		Context:   contextName,
+		Namespace: namespace,
+		Port:      port,
+	}
+	if workloadType == "pod" {
+		route.Pod = name
+	} else {
+		route.Service = name
+	}
+	return route
+}
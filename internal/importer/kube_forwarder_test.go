@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+const kubeForwarderExport = `{
+  "version": 1,
+  "clusters": [{
+    "name": "minikube",
+    "services": [
+      {"alias": "api", "namespace": "apps", "workloadType": "service", "workloadName": "api",
+       "forwards": [{"localPort": 18080, "remotePort": 80}, {"localPort": 15005, "remotePort": 5005}]},
+      {"namespace": "", "workloadType": "pod", "workloadName": "debug-0",
+       "forwards": [{"localPort": 19000, "remotePort": 9000}]},
+      {"alias": "web", "namespace": "apps", "workloadType": "deployment", "workloadName": "web",
+       "forwards": [{"localPort": 13000, "remotePort": 3000}]}
+    ]
+  }]
+}`
+
+func TestParseKubeForwarder(t *testing.T) {
+	routes := NewRoutes()
+	if err := ParseKubeForwarder([]byte(kubeForwarderExport), "", routes); err != nil {
+		t.Fatalf("ParseKubeForwarder failed: %v", err)
+	}
+
+	want := map[int]config.TCPRouteConfig{
+		18080: {Context: "minikube", Namespace: "apps", Service: "api", Port: 80},
+		15005: {Context: "minikube", Namespace: "apps", Service: "api", Port: 5005},
+		19000: {Context: "minikube", Namespace: "default", Pod: "debug-0", Port: 9000},
+	}
+	if len(routes.TCP) != len(want) {
+		t.Fatalf("expected %d routes, got %+v", len(want), routes.TCP)
+	}
+	for port, route := range want {
+		if !reflect.DeepEqual(routes.TCP[port], route) {
+			t.Errorf("port %d: got %+v, want %+v", port, routes.TCP[port], route)
+		}
+	}
+	if len(routes.Warnings) != 1 {
+		t.Errorf("expected a warning for the deployment workload, got %v", routes.Warnings)
+	}
+}
+
+func TestParseKubeForwarder_BareArrayAndContextOverride(t *testing.T) {
+	data := `[{"name": "ignored", "services": [
+		{"namespace": "ns", "workloadType": "service", "workloadName": "svc", "forwards": [{"localPort": 15432, "remotePort": 5432}]}
+	]}]`
+
+	routes := NewRoutes()
+	if err := ParseKubeForwarder([]byte(data), "prod", routes); err != nil {
+		t.Fatalf("ParseKubeForwarder failed: %v", err)
+	}
+	if got := routes.TCP[15432].Context; got != "prod" {
+		t.Errorf("expected context override 'prod', got %q", got)
+	}
+}
+
+func TestParseKubeForwarder_InvalidJSON(t *testing.T) {
+	if err := ParseKubeForwarder([]byte("not json"), "", NewRoutes()); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// KubefwdSpec is what a `kubefwd svc ...` invocation selects
+type KubefwdSpec struct {
+	Contexts      []string
+	Namespaces    []string
+	LabelSelector string
+	FieldSelector string
+}
+
+// ParseKubefwd parses a kubefwd command line such as
+// `sudo kubefwd svc -n default -n staging -l app=api -x my-cluster`.
+func ParseKubefwd(cmdline string) (*KubefwdSpec, error) {
+	args, err := splitArgs(cmdline)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip "sudo", "-E" etc. up to the kubefwd binary and its "svc" subcommand
+	for len(args) > 0 && !strings.HasSuffix(args[0], "kubefwd") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("not a kubefwd command: %q", cmdline)
+	}
+	args = args[1:]
+	if len(args) > 0 && (args[0] == "svc" || args[0] == "services") {
+		args = args[1:]
+	}
+
+	spec := &KubefwdSpec{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !strings.HasPrefix(name, "-") {
+			continue
+		}
+		if !hasValue {
+			switch name {
+			case "-n", "--namespace", "-x", "--context", "-l", "--selector", "-f", "--field-selector":
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("flag %s needs a value", name)
+				}
+				i++
+				value = args[i]
+			}
+		}
+
+		switch name {
+		case "-n", "--namespace":
+			spec.Namespaces = append(spec.Namespaces, splitList(value)...)
+		case "-x", "--context":
+			spec.Contexts = append(spec.Contexts, splitList(value)...)
+		case "-l", "--selector":
+			spec.LabelSelector = value
+		case "-f", "--field-selector":
+			spec.FieldSelector = value
+		}
+	}
+
+	if len(spec.Namespaces) == 0 {
+		spec.Namespaces = []string{"default"} // kubefwd's default
+	}
+	return spec, nil
+}
+
+// Resolve lists the services kubefwd would forward and adds a TCP route for each
+// service port, keeping the same local port like kubefwd does.
+func (s *KubefwdSpec) Resolve(ctx context.Context, clientFor func(contextName string) (kubernetes.Interface, error), routes *Routes) error {
+	for _, contextName := range s.Contexts {
+		clientset, err := clientFor(contextName)
+		if err != nil {
+			return fmt.Errorf("failed to get client for context %s: %w", contextName, err)
+		}
+
+		for _, namespace := range s.Namespaces {
+			services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: s.LabelSelector,
+				FieldSelector: s.FieldSelector,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list services in %s/%s: %w", contextName, namespace, err)
+			}
+
+			for _, svc := range services.Items {
+				addServiceRoutes(contextName, svc, routes)
+			}
+		}
+	}
+	return nil
+}
+
+func addServiceRoutes(contextName string, svc corev1.Service, routes *Routes) {
+	for _, port := range svc.Spec.Ports {
+		if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+			routes.warnf("skipping %s/%s port %d: only TCP is supported", svc.Namespace, svc.Name, port.Port)
+			continue
+		}
+		localPort := int(port.Port)
+		if routes.portTaken(localPort, fmt.Sprintf("%s/%s port %d", svc.Namespace, svc.Name, port.Port)) {
+			continue
+		}
+		routes.TCP[localPort] = tcpRoute(contextName, svc.Namespace, "service", svc.Name, localPort)
+	}
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// splitArgs splits a shell command line on whitespace, honouring quotes and
+// backslash line continuations. It's not a shell; it's enough for copy-pasted commands.
+func splitArgs(cmdline string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range strings.ReplaceAll(cmdline, "\\\n", " ") {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", cmdline)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseKubefwd(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmdline string
+		want    *KubefwdSpec
+		wantErr bool
+	}{
+		{
+			name:    "namespaces and context",
+			cmdline: "sudo -E kubefwd svc -n default -n staging -x my-cluster",
+			want:    &KubefwdSpec{Contexts: []string{"my-cluster"}, Namespaces: []string{"default", "staging"}},
+		},
+		{
+			name:    "comma lists and equals form",
+			cmdline: "kubefwd services --namespace=a,b --context=c1,c2",
+			want:    &KubefwdSpec{Contexts: []string{"c1", "c2"}, Namespaces: []string{"a", "b"}},
+		},
+		{
+			name:    "selectors with quotes",
+			cmdline: `/usr/local/bin/kubefwd svc -l 'app in (api, web)' -f metadata.name=api`,
+			want: &KubefwdSpec{
+				Namespaces:    []string{"default"},
+				LabelSelector: "app in (api, web)",
+				FieldSelector: "metadata.name=api",
+			},
+		},
+		{
+			name:    "line continuation",
+			cmdline: "kubefwd svc \\\n  -n prod",
+			want:    &KubefwdSpec{Namespaces: []string{"prod"}},
+		},
+		{name: "not kubefwd", cmdline: "kubectl port-forward svc/api 8080", wantErr: true},
+		{name: "missing flag value", cmdline: "kubefwd svc -n", wantErr: true},
+		{name: "unterminated quote", cmdline: "kubefwd svc -l 'app=api", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKubefwd(tt.cmdline)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubefwdSpec_Resolve(t *testing.T) {
+	service := func(name string, ports ...corev1.ServicePort) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "db", Labels: map[string]string{"tier": "data"}},
+			Spec:       corev1.ServiceSpec{Ports: ports},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		service("postgres", corev1.ServicePort{Port: 5432, Protocol: corev1.ProtocolTCP}),
+		service("redis", corev1.ServicePort{Port: 6379}, corev1.ServicePort{Port: 6379, Protocol: corev1.ProtocolUDP}),
+		service("postgres-replica", corev1.ServicePort{Port: 5432}),
+	)
+
+	spec := &KubefwdSpec{Contexts: []string{"prod"}, Namespaces: []string{"db"}, LabelSelector: "tier=data"}
+	routes := NewRoutes()
+	err := spec.Resolve(context.Background(), func(string) (kubernetes.Interface, error) { return clientset, nil }, routes)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if got := routes.TCP[6379]; !reflect.DeepEqual(got, config.TCPRouteConfig{Context: "prod", Namespace: "db", Service: "redis", Port: 6379}) {
+		t.Errorf("unexpected redis route: %+v", got)
+	}
+	if _, ok := routes.TCP[5432]; !ok {
+		t.Error("expected a route for port 5432")
+	}
+	if len(routes.TCP) != 2 {
+		t.Errorf("expected 2 routes, got %d: %+v", len(routes.TCP), routes.TCP)
+	}
+	// UDP port and the duplicate 5432 are both reported
+	if len(routes.Warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", routes.Warnings)
+	}
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/atas/autotunnel/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Routes collects autotunnel routes generated from another tool's configuration
+type Routes struct {
+	TCP      map[int]config.TCPRouteConfig
+	Jump     map[int]config.JumpRouteConfig
+	Warnings []string
+}
+
+func NewRoutes() *Routes {
+	return &Routes{
+		TCP:  make(map[int]config.TCPRouteConfig),
+		Jump: make(map[int]config.JumpRouteConfig),
+	}
+}
+
+func (r *Routes) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// portTaken reports (and warns about) a local port that's already been generated
+func (r *Routes) portTaken(localPort int, what string) bool {
+	_, tcp := r.TCP[localPort]
+	_, jump := r.Jump[localPort]
+	if tcp || jump {
+		r.warnf("skipping %s: local port %d is already used by another imported route", what, localPort)
+		return true
+	}
+	return false
+}
+
+// Len returns the number of generated routes
+func (r *Routes) Len() int {
+	return len(r.TCP) + len(r.Jump)
+}
+
+// YAML renders the routes as a config fragment to paste into ~/.autotunnel.yaml
+func (r *Routes) YAML() ([]byte, error) {
+	type k8s struct {
+		Routes map[int]config.TCPRouteConfig  `yaml:"routes,omitempty"`
+		Jump   map[int]config.JumpRouteConfig `yaml:"jump,omitempty"`
+	}
+	type tcp struct {
+		K8s k8s `yaml:"k8s"`
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(struct {
+		TCP tcp `yaml:"tcp"`
+	}{TCP: tcp{K8s: k8s{Routes: r.TCP, Jump: r.Jump}}}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// JumpVia says which cluster/pod should replace the SSH bastion for imported LocalForwards
+type JumpVia struct {
+	Context   string
+	Namespace string
+	Via       config.ViaConfig
+}
+
+// ParseSSHConfig turns LocalForward entries from an ssh_config file into jump routes
+// through the given pod, since the pod plays the role the SSH bastion used to.
+func ParseSSHConfig(r io.Reader, via JumpVia, routes *Routes) error {
+	scanner := bufio.NewScanner(r)
+	host := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, rest := splitSSHKeyword(line)
+		switch strings.ToLower(keyword) {
+		case "host":
+			host = rest
+		case "include":
+			routes.warnf("Include %s is not followed; import the included files separately", rest)
+		case "localforward":
+			localPort, targetHost, targetPort, err := parseLocalForward(rest)
+			if err != nil {
+				routes.warnf("Host %s: skipping LocalForward %q: %v", host, rest, err)
+				continue
+			}
+			if routes.portTaken(localPort, fmt.Sprintf("Host %s LocalForward %s", host, rest)) {
+				continue
+			}
+			routes.Jump[localPort] = config.JumpRouteConfig{
+				Context:   via.Context,
+				Namespace: via.Namespace,
+				Via:       via.Via,
+				Target:    config.TargetConfig{Host: targetHost, Port: targetPort},
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// splitSSHKeyword splits "Keyword value" and "Keyword=value" forms
+func splitSSHKeyword(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx == -1 {
+		return line, ""
+	}
+	rest := strings.TrimLeft(line[idx:], " \t=")
+	return line[:idx], strings.TrimSpace(rest)
+}
+
+// parseLocalForward parses "[bind_address:]port host:hostport"
+func parseLocalForward(value string) (localPort int, host string, port int, err error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0, "", 0, fmt.Errorf("expected \"[bind:]port host:port\"")
+	}
+
+	local := fields[0]
+	if idx := strings.LastIndex(local, ":"); idx != -1 {
+		local = local[idx+1:]
+	}
+	localPort, err = strconv.Atoi(local)
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("local side is not a port (unix sockets aren't supported)")
+	}
+
+	host, portStr, err := net.SplitHostPort(fields[1])
+	if err != nil {
+		return 0, "", 0, err
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("invalid target port %q", portStr)
+	}
+	if !config.IsValidTargetHost(host) {
+		return 0, "", 0, fmt.Errorf("invalid target host %q", host)
+	}
+	return localPort, host, port, nil
+}
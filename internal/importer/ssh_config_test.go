@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestParseSSHConfig(t *testing.T) {
+	sshConfig := `
+# bastion for the data VPC
+Host bastion
+  HostName 10.0.0.1
+  LocalForward 5432 mydb.cluster-xyz.rds.amazonaws.com:5432
+  LocalForward=127.0.0.1:6380 redis.internal:6379
+
+Host other
+  LocalForward 13306 [2001:db8::5]:3306
+  LocalForward 5432 duplicate.internal:5432
+  LocalForward /tmp/socket db.internal:5432
+  Include ~/.ssh/config.d/*
+`
+	via := JumpVia{Context: "eks-prod", Namespace: "default", Via: config.ViaConfig{Service: "backend-api"}}
+
+	routes := NewRoutes()
+	if err := ParseSSHConfig(strings.NewReader(sshConfig), via, routes); err != nil {
+		t.Fatalf("ParseSSHConfig failed: %v", err)
+	}
+
+	wantTargets := map[int]config.TargetConfig{
+		5432:  {Host: "mydb.cluster-xyz.rds.amazonaws.com", Port: 5432},
+		6380:  {Host: "redis.internal", Port: 6379},
+		13306: {Host: "2001:db8::5", Port: 3306},
+	}
+	if len(routes.Jump) != len(wantTargets) {
+		t.Fatalf("expected %d jump routes, got %+v", len(wantTargets), routes.Jump)
+	}
+	for port, target := range wantTargets {
+		route := routes.Jump[port]
+		if route.Target != target {
+			t.Errorf("port %d: target %+v, want %+v", port, route.Target, target)
+		}
+		if route.Context != "eks-prod" || route.Via.Service != "backend-api" {
+			t.Errorf("port %d: via not applied: %+v", port, route)
+		}
+	}
+
+	// duplicate port, unix socket, Include
+	if len(routes.Warnings) != 3 {
+		t.Errorf("expected 3 warnings, got %v", routes.Warnings)
+	}
+}
+
+func TestRoutes_YAMLLoadsAsConfig(t *testing.T) {
+	routes := NewRoutes()
+	routes.TCP[15432] = config.TCPRouteConfig{Context: "prod", Namespace: "db", Service: "postgres", Port: 5432}
+
+	out, err := routes.YAML()
+	if err != nil {
+		t.Fatalf("YAML failed: %v", err)
+	}
+	if strings.Contains(string(out), "pod:") {
+		t.Errorf("expected empty pod field to be omitted:\n%s", out)
+	}
+	if !strings.Contains(string(out), "tcp:\n  k8s:\n    routes:\n      15432:\n        context: prod\n") {
+		t.Errorf("unexpected YAML:\n%s", out)
+	}
+}
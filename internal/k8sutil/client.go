@@ -3,8 +3,12 @@ package k8sutil
 import (
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 
+	"github.com/atas/autotunnel/internal/config"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -14,6 +18,7 @@ import (
 type ClientFactory struct {
 	clients   map[string]*cachedClient
 	clientsMu sync.RWMutex
+	contexts  config.ContextsConfig
 	verbose   bool
 }
 
@@ -30,12 +35,60 @@ func NewClientFactory(verbose bool) *ClientFactory {
 	}
 }
 
+// SetContexts applies the contexts: config: aliases, and env and PATH for exec
+// credential plugins. Call it before the factory is used.
+func (f *ClientFactory) SetContexts(contexts config.ContextsConfig) {
+	f.contexts = contexts
+}
+
+// resolve returns the key the client for contextName is cached under and the
+// contexts: entry for it, with a route's execEnv on top. Aliases share their
+// context's client unless they set up the exec plugin differently.
+func (f *ClientFactory) resolve(contextName string, execEnv map[string]string) (string, config.ContextConfig) {
+	entry, ok := f.contexts[contextName]
+	key := contextName
+	if ok {
+		entry.Context = entry.Target(contextName)
+		if !entry.HasClientSettings() {
+			key = entry.Context
+		}
+	} else {
+		entry.Context = contextName
+	}
+	if len(execEnv) == 0 {
+		return key, entry
+	}
+
+	env := make(map[string]string, len(entry.Env)+len(execEnv))
+	maps.Copy(env, entry.Env)
+	maps.Copy(env, execEnv)
+	entry.Env = env
+	// one client per distinct route env
+	names := slices.Sorted(maps.Keys(execEnv))
+	for _, name := range names {
+		key += routeEnvSeparator + name + "=" + execEnv[name]
+	}
+	return key, entry
+}
+
+// routeEnvSeparator separates a route's exec_env from the context in client keys
+const routeEnvSeparator = "\x00"
+
 // GetClientForContext returns a cached or new clientset for the given context.
-// kubeconfigPaths can specify multiple kubeconfig files to merge (like KUBECONFIG=a:b:c).
+// kubeconfigPaths can specify multiple kubeconfig files to merge (like KUBECONFIG=a:b:c);
+// a contextName of the form path#context reads the context from that file only.
 func (f *ClientFactory) GetClientForContext(kubeconfigPaths []string, contextName string) (*kubernetes.Clientset, *rest.Config, error) {
+	return f.GetClientForRoute(kubeconfigPaths, contextName, nil)
+}
+
+// GetClientForRoute is GetClientForContext for a route setting exec_env: the
+// context's exec credential plugin runs with it added to its environment
+func (f *ClientFactory) GetClientForRoute(kubeconfigPaths []string, contextName string, execEnv map[string]string) (*kubernetes.Clientset, *rest.Config, error) {
+	key, entry := f.resolve(contextName, execEnv)
+
 	// Fast path: check cache
 	f.clientsMu.RLock()
-	if client, ok := f.clients[contextName]; ok {
+	if client, ok := f.clients[key]; ok {
 		f.clientsMu.RUnlock()
 		return client.clientset, client.restConfig, nil
 	}
@@ -46,28 +99,51 @@ func (f *ClientFactory) GetClientForContext(kubeconfigPaths []string, contextNam
 	defer f.clientsMu.Unlock()
 
 	// Double-check after acquiring write lock
-	if client, ok := f.clients[contextName]; ok {
+	if client, ok := f.clients[key]; ok {
 		return client.clientset, client.restConfig, nil
 	}
+	return f.newClient(kubeconfigPaths, key, entry)
+}
 
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	if len(kubeconfigPaths) > 0 {
-		loadingRules.Precedence = kubeconfigPaths
+// RenewClientForContext rebuilds the client of contextName from the kubeconfig, for
+// when the API server rejected stale's credentials (401): a token written there
+// since, or one the exec plugin fetches anew. If the cached client isn't stale
+// anymore, another caller renewed it already and it is returned as is.
+func (f *ClientFactory) RenewClientForContext(kubeconfigPaths []string, contextName string, execEnv map[string]string, stale *rest.Config) (*kubernetes.Clientset, *rest.Config, error) {
+	key, entry := f.resolve(contextName, execEnv)
+	f.clientsMu.Lock()
+	defer f.clientsMu.Unlock()
+
+	if client, ok := f.clients[key]; ok && client.restConfig != stale {
+		return client.clientset, client.restConfig, nil
 	}
-	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+	delete(f.clients, key)
+	return f.newClient(kubeconfigPaths, key, entry)
+}
+
+// newClient builds the client for entry and caches it under key. Caller must hold f.clientsMu.
+func (f *ClientFactory) newClient(kubeconfigPaths []string, key string, entry config.ContextConfig) (*kubernetes.Clientset, *rest.Config, error) {
+	contextName := entry.Context
+	rules, name := loadingRules(kubeconfigPaths, contextName)
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: name}
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, configOverrides)
 
 	restConfig, err := kubeConfig.ClientConfig()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to build REST config for context %s: %w", contextName, err)
 	}
 
+	applyExecSettings(restConfig, entry)
+	if err := applyProxy(restConfig, entry); err != nil {
+		return nil, nil, fmt.Errorf("context %s: %w", contextName, err)
+	}
+
 	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create clientset for context %s: %w", contextName, err)
 	}
 
-	f.clients[contextName] = &cachedClient{
+	f.clients[key] = &cachedClient{
 		clientset:  clientset,
 		restConfig: restConfig,
 	}
@@ -79,6 +155,22 @@ func (f *ClientFactory) GetClientForContext(kubeconfigPaths []string, contextNam
 	return clientset, restConfig, nil
 }
 
+// CurrentContext returns the current-context from the merged kubeconfig files
+func CurrentContext(kubeconfigPaths []string) (string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if len(kubeconfigPaths) > 0 {
+		loadingRules.Precedence = kubeconfigPaths
+	}
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if rawConfig.CurrentContext == "" {
+		return "", fmt.Errorf("kubeconfig has no current-context")
+	}
+	return rawConfig.CurrentContext, nil
+}
+
 // Clear clears all cached clients (for shutdown)
 func (f *ClientFactory) Clear() {
 	f.clientsMu.Lock()
@@ -86,6 +178,19 @@ func (f *ClientFactory) Clear() {
 	f.clientsMu.Unlock()
 }
 
+// Forget drops the cached client for contextName, so the next tunnel on that
+// context re-reads the kubeconfig (and picks up a refreshed token)
+func (f *ClientFactory) Forget(contextName string) {
+	f.clientsMu.Lock()
+	key, _ := f.resolve(contextName, nil)
+	for cached := range f.clients {
+		if cached == key || strings.HasPrefix(cached, key+routeEnvSeparator) {
+			delete(f.clients, cached)
+		}
+	}
+	f.clientsMu.Unlock()
+}
+
 // InjectClient adds a pre-configured client for a context (for testing)
 func (f *ClientFactory) InjectClient(contextName string, clientset *kubernetes.Clientset, restConfig *rest.Config) {
 	f.clientsMu.Lock()
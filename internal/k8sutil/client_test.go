@@ -0,0 +1,34 @@
+package k8sutil
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestRenewClientForContext_KeepsClientRenewedByAnother(t *testing.T) {
+	f := NewClientFactory(false)
+	renewed := &rest.Config{Host: "https://renewed"}
+	f.InjectClient("prod", nil, renewed)
+
+	// a caller still holding the config replaced since gets the renewed one
+	_, restConfig, err := f.RenewClientForContext(nil, "prod", nil, &rest.Config{Host: "https://stale"})
+	if err != nil {
+		t.Fatalf("RenewClientForContext: %v", err)
+	}
+	if restConfig != renewed {
+		t.Errorf("got %v, want the already renewed config", restConfig)
+	}
+}
+
+func TestRenewClientForContext_RebuildsStaleClient(t *testing.T) {
+	f := NewClientFactory(false)
+	stale := &rest.Config{Host: "https://stale"}
+	f.InjectClient("prod", nil, stale)
+
+	// the context isn't in any kubeconfig: the rebuild fails rather than
+	// handing back the rejected client
+	if _, _, err := f.RenewClientForContext([]string{t.TempDir() + "/missing"}, "prod", nil, stale); err == nil {
+		t.Error("RenewClientForContext returned the stale client")
+	}
+}
@@ -0,0 +1,31 @@
+package k8sutil
+
+import (
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// IsForbidden reports whether err is an RBAC denial. Port-forward and exec stream
+// upgrade failures only carry the API server's message text, so that is checked too.
+func IsForbidden(err error) bool {
+	if err == nil {
+		return false
+	}
+	return apierrors.IsForbidden(err) || strings.Contains(strings.ToLower(err.Error()), "forbidden")
+}
+
+// IsUnauthorized reports whether the API server rejected the credentials (401),
+// e.g. an expired token. Stream upgrade failures are matched by message, like IsForbidden.
+func IsUnauthorized(err error) bool {
+	if err == nil {
+		return false
+	}
+	return apierrors.IsUnauthorized(err) || strings.Contains(strings.ToLower(err.Error()), "unauthorized")
+}
+
+// Reason returns the Kubernetes API status reason of err (e.g. "NotFound",
+// "Forbidden"), or "" when err didn't come from the API server
+func Reason(err error) string {
+	return string(apierrors.ReasonForError(err))
+}
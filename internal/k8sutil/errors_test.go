@@ -0,0 +1,52 @@
+package k8sutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsForbidden(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "status error", err: apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "api-0", errors.New("denied")), want: true},
+		{name: "upgrade failure", err: fmt.Errorf("port forward failed: %w", errors.New(`error upgrading connection: pods "api-0" is forbidden: User "dev" cannot create resource "pods/portforward"`)), want: true},
+		{name: "other error", err: errors.New("connection refused"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsForbidden(tt.err); got != tt.want {
+				t.Errorf("IsForbidden(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnauthorized(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "status error", err: fmt.Errorf("failed to get service: %w", apierrors.NewUnauthorized("token expired")), want: true},
+		{name: "upgrade failure", err: errors.New("error upgrading connection: Unauthorized"), want: true},
+		{name: "forbidden", err: apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "api-0", errors.New("denied")), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUnauthorized(tt.err); got != tt.want {
+				t.Errorf("IsUnauthorized(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
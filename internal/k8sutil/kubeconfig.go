@@ -0,0 +1,160 @@
+package k8sutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// SplitContext splits a route context of the form path#context into the kubeconfig
+// file it must come from and the context name in it. Plain names have no path.
+func SplitContext(name string) (path, contextName string) {
+	i := strings.LastIndex(name, "#")
+	if i < 0 {
+		return "", name
+	}
+	return expandHome(name[:i]), name[i+1:]
+}
+
+// loadingRules returns the rules loading contextName (path#context reads only that
+// file) and the context name to select in what they load
+func loadingRules(kubeconfigPaths []string, contextName string) (*clientcmd.ClientConfigLoadingRules, string) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	path, name := SplitContext(contextName)
+	switch {
+	case path != "":
+		rules.Precedence = []string{path}
+	case len(kubeconfigPaths) > 0:
+		rules.Precedence = kubeconfigPaths
+	}
+	return rules, name
+}
+
+// ContextConflict is a context name defined differently by several kubeconfig files.
+// Merged, the first file's definition wins.
+type ContextConflict struct {
+	Context string
+	Paths   []string // in merge order
+}
+
+func (c ContextConflict) String() string {
+	return fmt.Sprintf("context %q is defined in %s; the one in %s is used (use %s#%s in routes to pick another)",
+		c.Context, strings.Join(c.Paths, ", "), c.Paths[0], c.Paths[len(c.Paths)-1], c.Context)
+}
+
+// ContextConflicts lists the contexts that more than one of kubeconfigPaths defines,
+// pointing at different clusters, users or namespaces. Unreadable files are skipped;
+// loading the merged kubeconfig reports them.
+func ContextConflicts(kubeconfigPaths []string) []ContextConflict {
+	type definition struct {
+		server, user, namespace string
+	}
+	defined := make(map[string]map[definition][]string) // context -> definition -> paths
+	for _, path := range kubeconfigPaths {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		for name, ctx := range kubeconfig.Contexts {
+			def := definition{server: clusterServer(kubeconfig, ctx.Cluster), user: ctx.AuthInfo, namespace: ctx.Namespace}
+			if defined[name] == nil {
+				defined[name] = make(map[definition][]string)
+			}
+			defined[name][def] = append(defined[name][def], path)
+		}
+	}
+
+	var conflicts []ContextConflict
+	for name, definitions := range defined {
+		if len(definitions) < 2 {
+			continue
+		}
+		var paths []string
+		for _, p := range definitions {
+			paths = append(paths, p...)
+		}
+		sort.Slice(paths, func(i, j int) bool { return indexOf(kubeconfigPaths, paths[i]) < indexOf(kubeconfigPaths, paths[j]) })
+		conflicts = append(conflicts, ContextConflict{Context: name, Paths: paths})
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Context < conflicts[j].Context })
+	return conflicts
+}
+
+func clusterServer(kubeconfig *clientcmdapi.Config, cluster string) string {
+	if c, ok := kubeconfig.Clusters[cluster]; ok {
+		return c.Server
+	}
+	return ""
+}
+
+func indexOf(paths []string, path string) int {
+	for i, p := range paths {
+		if p == path {
+			return i
+		}
+	}
+	return len(paths)
+}
+
+// applyExecSettings gives restConfig's exec credential plugin the env and PATH of
+// its contexts: entry. The plugin is looked up in the entry's exec_path first,
+// as the process PATH decides where it is found otherwise.
+func applyExecSettings(restConfig *rest.Config, entry config.ContextConfig) {
+	plugin := restConfig.ExecProvider
+	if plugin == nil || !entry.HasExecSettings() {
+		return
+	}
+
+	names := make([]string, 0, len(entry.Env))
+	for name := range entry.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		plugin.Env = append(plugin.Env, clientcmdapi.ExecEnvVar{Name: name, Value: entry.Env[name]})
+	}
+
+	if len(entry.ExecPath) == 0 {
+		return
+	}
+	dirs := make([]string, len(entry.ExecPath))
+	for i, dir := range entry.ExecPath {
+		dirs[i] = expandHome(dir)
+	}
+	if !strings.ContainsRune(plugin.Command, filepath.Separator) {
+		for _, dir := range dirs {
+			candidate := filepath.Join(dir, plugin.Command)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				plugin.Command = candidate
+				break
+			}
+		}
+	}
+	// tools the plugin runs itself (aws-iam-authenticator calling aws) are found there too
+	path := strings.Join(dirs, string(os.PathListSeparator))
+	if current := os.Getenv("PATH"); current != "" {
+		path += string(os.PathListSeparator) + current
+	}
+	plugin.Env = append(plugin.Env, clientcmdapi.ExecEnvVar{Name: "PATH", Value: path})
+}
+
+// expandHome expands a leading ~ to the home directory
+func expandHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	} else if path == "~" {
+		return home
+	}
+	return path
+}
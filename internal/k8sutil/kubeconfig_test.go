@@ -0,0 +1,205 @@
+package k8sutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// writeKubeconfig writes a kubeconfig whose contexts point at the given servers
+func writeKubeconfig(t *testing.T, name string, servers map[string]string) string {
+	t.Helper()
+	var clusters, contexts string
+	for ctx, server := range servers {
+		clusters += fmt.Sprintf("- name: %s\n  cluster:\n    server: %s\n", ctx, server)
+		contexts += fmt.Sprintf("- name: %s\n  context:\n    cluster: %s\n    user: dev\n", ctx, ctx)
+	}
+	data := "apiVersion: v1\nkind: Config\nclusters:\n" + clusters + "contexts:\n" + contexts + "users:\n- name: dev\n  user:\n    token: abc\n"
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestSplitContext(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		name, wantPath, wantContext string
+	}{
+		{"prod", "", "prod"},
+		{"/etc/kube/work.yaml#prod", "/etc/kube/work.yaml", "prod"},
+		{"~/.kube/work#prod", filepath.Join(home, ".kube/work"), "prod"},
+		{"arn:aws:eks:eu-west-1:123:cluster/prod", "", "arn:aws:eks:eu-west-1:123:cluster/prod"},
+	}
+	for _, tt := range tests {
+		path, ctx := SplitContext(tt.name)
+		if path != tt.wantPath || ctx != tt.wantContext {
+			t.Errorf("SplitContext(%q) = %q, %q, want %q, %q", tt.name, path, ctx, tt.wantPath, tt.wantContext)
+		}
+	}
+}
+
+func TestContextConflicts(t *testing.T) {
+	personal := writeKubeconfig(t, "personal", map[string]string{"prod": "https://personal", "kind": "https://kind"})
+	work := writeKubeconfig(t, "work", map[string]string{"prod": "https://work", "kind": "https://kind"})
+
+	conflicts := ContextConflicts([]string{personal, work, filepath.Join(t.TempDir(), "missing")})
+	if len(conflicts) != 1 {
+		t.Fatalf("got %v, want only prod (kind is the same in both)", conflicts)
+	}
+	if c := conflicts[0]; c.Context != "prod" || len(c.Paths) != 2 || c.Paths[0] != personal || c.Paths[1] != work {
+		t.Errorf("got %+v, want prod in personal, then work", c)
+	}
+}
+
+func TestGetClientForContext_PathQualified(t *testing.T) {
+	personal := writeKubeconfig(t, "personal", map[string]string{"prod": "https://personal"})
+	work := writeKubeconfig(t, "work", map[string]string{"prod": "https://work"})
+	paths := []string{personal, work}
+	f := NewClientFactory(false)
+
+	tests := []struct {
+		context, wantHost string
+	}{
+		{"prod", "https://personal"}, // merged: the first file wins
+		{work + "#prod", "https://work"},
+		{personal + "#prod", "https://personal"},
+	}
+	for _, tt := range tests {
+		_, restConfig, err := f.GetClientForContext(paths, tt.context)
+		if err != nil {
+			t.Fatalf("GetClientForContext(%q): %v", tt.context, err)
+		}
+		if restConfig.Host != tt.wantHost {
+			t.Errorf("GetClientForContext(%q) host = %s, want %s", tt.context, restConfig.Host, tt.wantHost)
+		}
+	}
+}
+
+func TestGetClientForContext_Alias(t *testing.T) {
+	long := "arn:aws:eks:us-east-1:123456789012:cluster/main"
+	paths := []string{writeKubeconfig(t, "config", map[string]string{long: "https://eks"})}
+	f := NewClientFactory(false)
+	f.SetContexts(config.ContextsConfig{"prod": {Context: long}})
+
+	aliased, restConfig, err := f.GetClientForContext(paths, "prod")
+	if err != nil {
+		t.Fatalf("GetClientForContext(prod): %v", err)
+	}
+	if restConfig.Host != "https://eks" {
+		t.Errorf("host = %s, want the aliased context's", restConfig.Host)
+	}
+	direct, _, err := f.GetClientForContext(paths, long)
+	if err != nil {
+		t.Fatalf("GetClientForContext(%s): %v", long, err)
+	}
+	if aliased != direct {
+		t.Error("the alias and the context name got different clients")
+	}
+}
+
+func TestGetClientForContext_ExecSettings(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: main
+  cluster:
+    server: https://eks
+contexts:
+- name: main
+  context:
+    cluster: main
+    user: eks
+users:
+- name: eks
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: [eks, get-token, --cluster-name, main]
+`
+	if err := os.WriteFile(kubeconfig, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	tools := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tools, "aws"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+
+	f := NewClientFactory(false)
+	f.SetContexts(config.ContextsConfig{
+		"prod": {Context: "main", Env: map[string]string{"AWS_PROFILE": "prod"}, ExecPath: []string{tools}},
+	})
+	_, restConfig, err := f.GetClientForContext([]string{kubeconfig}, "prod")
+	if err != nil {
+		t.Fatalf("GetClientForContext: %v", err)
+	}
+	plugin := restConfig.ExecProvider
+	if plugin.Command != filepath.Join(tools, "aws") {
+		t.Errorf("command = %s, want the one in exec_path", plugin.Command)
+	}
+	env := map[string]string{}
+	for _, v := range plugin.Env {
+		env[v.Name] = v.Value
+	}
+	if env["AWS_PROFILE"] != "prod" || !strings.HasPrefix(env["PATH"], tools) {
+		t.Errorf("env = %v, want AWS_PROFILE=prod and PATH starting with %s", env, tools)
+	}
+
+	// the context itself keeps the kubeconfig's plugin settings
+	_, plain, err := f.GetClientForContext([]string{kubeconfig}, "main")
+	if err != nil {
+		t.Fatalf("GetClientForContext(main): %v", err)
+	}
+	if plain.ExecProvider.Command != "aws" || len(plain.ExecProvider.Env) != 0 {
+		t.Errorf("main got the alias's settings: %+v", plain.ExecProvider)
+	}
+}
+
+func TestGetClientForRoute_ExecEnv(t *testing.T) {
+	paths := []string{writeKubeconfig(t, "config", map[string]string{"main": "https://eks"})}
+	f := NewClientFactory(false)
+	f.SetContexts(config.ContextsConfig{"main": {Env: map[string]string{"AWS_REGION": "us-east-1"}}})
+
+	ctxClient, _, err := f.GetClientForContext(paths, "main")
+	if err != nil {
+		t.Fatalf("GetClientForContext: %v", err)
+	}
+	prodClient, _, err := f.GetClientForRoute(paths, "main", map[string]string{"AWS_PROFILE": "prod"})
+	if err != nil {
+		t.Fatalf("GetClientForRoute(prod): %v", err)
+	}
+	again, _, _ := f.GetClientForRoute(paths, "main", map[string]string{"AWS_PROFILE": "prod"})
+	devClient, _, _ := f.GetClientForRoute(paths, "main", map[string]string{"AWS_PROFILE": "dev"})
+
+	if prodClient == ctxClient || prodClient == devClient {
+		t.Error("routes with different exec_env share a client")
+	}
+	if again != prodClient {
+		t.Error("routes with the same exec_env got different clients")
+	}
+
+	f.Forget("main")
+	if n := len(f.clients); n != 0 {
+		t.Errorf("%d clients left after Forget, want the route variants dropped too", n)
+	}
+}
+
+func TestResolve_MergesRouteEnv(t *testing.T) {
+	f := NewClientFactory(false)
+	f.SetContexts(config.ContextsConfig{"prod": {Context: "main", Env: map[string]string{"AWS_PROFILE": "prod", "AWS_REGION": "us-east-1"}}})
+
+	_, entry := f.resolve("prod", map[string]string{"AWS_PROFILE": "prod-readonly"})
+	if entry.Context != "main" || entry.Env["AWS_PROFILE"] != "prod-readonly" || entry.Env["AWS_REGION"] != "us-east-1" {
+		t.Errorf("entry = %+v, want the route's AWS_PROFILE over the context's", entry)
+	}
+	if _, plain := f.resolve("prod", nil); plain.Env["AWS_PROFILE"] != "prod" {
+		t.Errorf("the contexts: entry was changed: %+v", plain)
+	}
+}
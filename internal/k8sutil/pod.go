@@ -22,6 +22,17 @@ import (
 //   - selectorLabels: label selector to match pods
 //   - serviceName: service name for error messages (optional, can be empty)
 func FindReadyPod(ctx context.Context, clientset kubernetes.Interface, namespace string, selectorLabels map[string]string, serviceName string) (*corev1.Pod, error) {
+	pods, err := RunningPods(ctx, clientset, namespace, selectorLabels, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return &pods[0], nil
+}
+
+// RunningPods lists the running pods matching the selector labels, ready pods first,
+// so callers can move on to the next candidate when one fails. Takes the same
+// parameters as FindReadyPod and never returns an empty list without an error.
+func RunningPods(ctx context.Context, clientset kubernetes.Interface, namespace string, selectorLabels map[string]string, serviceName string) ([]corev1.Pod, error) {
 	selector := metav1.FormatLabelSelector(&metav1.LabelSelector{
 		MatchLabels: selectorLabels,
 	})
@@ -41,18 +52,27 @@ func FindReadyPod(ctx context.Context, clientset kubernetes.Interface, namespace
 		return nil, fmt.Errorf("no running pods found matching selector %s", selector)
 	}
 
-	// Select the first ready pod
-	for i := range pods.Items {
-		pod := &pods.Items[i]
-		for _, cond := range pod.Status.Conditions {
-			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
-				return pod, nil
-			}
+	// Ready pods first; not-ready ones are kept as a last resort - they might still work
+	ordered := make([]corev1.Pod, 0, len(pods.Items))
+	var notReady []corev1.Pod
+	for _, pod := range pods.Items {
+		if IsPodReady(&pod) {
+			ordered = append(ordered, pod)
+		} else {
+			notReady = append(notReady, pod)
 		}
 	}
+	return append(ordered, notReady...), nil
+}
 
-	// Not ideal but better than failing - pod might still work
-	return &pods.Items[0], nil
+// IsPodReady reports whether the pod's Ready condition is true
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
 }
 
 // WaitForPodReady polls until a pod is ready or timeout/context cancellation
@@ -72,10 +92,8 @@ func WaitForPodReady(ctx context.Context, clientset kubernetes.Interface, namesp
 			}
 			// Transient error, continue polling
 		} else {
-			for _, cond := range pod.Status.Conditions {
-				if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
-					return nil
-				}
+			if IsPodReady(pod) {
+				return nil
 			}
 			if pod.Status.Phase == corev1.PodFailed {
 				return fmt.Errorf("pod %s failed", name)
@@ -232,3 +232,49 @@ func TestFindReadyPod_MultipleLabels(t *testing.T) {
 		t.Errorf("FindReadyPod() selected %q, want %q", pod.Name, "pod-multi-label")
 	}
 }
+
+func TestRunningPods_ReadyFirst(t *testing.T) {
+	ctx := context.Background()
+
+	newPod := func(name string, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-ns",
+				Labels:    map[string]string{"app": "test"},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: ready},
+				},
+			},
+		}
+	}
+
+	fakeClient := fake.NewSimpleClientset(
+		newPod("a-not-ready", corev1.ConditionFalse),
+		newPod("b-ready", corev1.ConditionTrue),
+		newPod("c-ready", corev1.ConditionTrue),
+	)
+
+	pods, err := RunningPods(ctx, fakeClient, "test-ns", map[string]string{"app": "test"}, "test-svc")
+	if err != nil {
+		t.Fatalf("RunningPods() error = %v", err)
+	}
+
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	want := []string{"b-ready", "c-ready", "a-not-ready"}
+	if len(names) != len(want) {
+		t.Fatalf("RunningPods() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("RunningPods() = %v, want %v", names, want)
+			break
+		}
+	}
+}
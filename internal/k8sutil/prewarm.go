@@ -0,0 +1,81 @@
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrewarmTarget is a context whose client is resolved ahead of its first tunnel
+type PrewarmTarget struct {
+	Kubeconfigs []string
+	Context     string
+	ExecEnv     map[string]string
+}
+
+// String names the target in logs and status: the context, and the exec_env
+// variables of routes that set their own
+func (t PrewarmTarget) String() string {
+	if len(t.ExecEnv) == 0 {
+		return t.Context
+	}
+	vars := make([]string, 0, len(t.ExecEnv))
+	for _, name := range slices.Sorted(maps.Keys(t.ExecEnv)) {
+		vars = append(vars, name+"="+t.ExecEnv[name])
+	}
+	return fmt.Sprintf("%s (%s)", t.Context, strings.Join(vars, " "))
+}
+
+// Prewarm resolves the clients of targets, parallel at a time, and sends one request
+// with each so exec credential plugins run now rather than on the first connection.
+// Each target gets timeout; one failing or hanging doesn't hold up the others. The
+// returned errors are in the order of targets, nil for the ones that succeeded.
+func (f *ClientFactory) Prewarm(ctx context.Context, targets []PrewarmTarget, parallel int, timeout time.Duration) []error {
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		sem <- struct{}{}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			errs[i] = err
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = f.prewarm(ctx, target, timeout)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// prewarm resolves one target. A plugin that outlives timeout keeps running in the
+// background; its client is cached once it is done.
+func (f *ClientFactory) prewarm(ctx context.Context, target PrewarmTarget, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		clientset, _, err := f.GetClientForRoute(target.Kubeconfigs, target.Context, target.ExecEnv)
+		if err == nil {
+			// credentials are only fetched with the first request
+			err = clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("no answer within %v", timeout)
+	}
+}
@@ -0,0 +1,74 @@
+package k8sutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func injectServerClient(t *testing.T, f *ClientFactory, contextName string, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	restConfig := &rest.Config{Host: srv.URL}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("NewForConfig: %v", err)
+	}
+	f.InjectClient(contextName, clientset, restConfig)
+}
+
+func TestPrewarm_PartialFailure(t *testing.T) {
+	f := NewClientFactory(false)
+	injectServerClient(t, f, "fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"29"}`))
+	})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	injectServerClient(t, f, "slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	targets := []PrewarmTarget{
+		{Context: "fast"},
+		{Context: "slow"},
+		{Kubeconfigs: []string{t.TempDir() + "/missing"}, Context: "missing"},
+	}
+	start := time.Now()
+	errs := f.Prewarm(context.Background(), targets, 2, 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Prewarm took %v, the slow context should have timed out", elapsed)
+	}
+
+	if errs[0] != nil {
+		t.Errorf("fast: unexpected error %v", errs[0])
+	}
+	if errs[1] == nil {
+		t.Error("slow: expected a timeout")
+	}
+	if errs[2] == nil {
+		t.Error("missing: expected an error")
+	}
+}
+
+func TestPrewarm_Canceled(t *testing.T) {
+	f := NewClientFactory(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := f.Prewarm(ctx, []PrewarmTarget{{Context: "a"}, {Context: "b"}}, 1, time.Second)
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("target %d: expected an error after cancel", i)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package k8sutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/rest"
+)
+
+// applyProxy sends restConfig's API server connections through its contexts:
+// entry's proxy. Without one, client-go uses the kubeconfig cluster's proxy-url,
+// else HTTPS_PROXY/NO_PROXY; port-forwards follow restConfig.Proxy the same way.
+func applyProxy(restConfig *rest.Config, entry config.ContextConfig) error {
+	switch entry.Proxy {
+	case "":
+		return nil
+	case config.ProxyDirect:
+		restConfig.Proxy = func(*http.Request) (*url.URL, error) { return nil, nil }
+		return nil
+	}
+	u, err := url.Parse(entry.Proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy %q: %w", entry.Proxy, err)
+	}
+	restConfig.Proxy = http.ProxyURL(u)
+	return nil
+}
@@ -0,0 +1,43 @@
+package k8sutil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestGetClientForContext_Proxy(t *testing.T) {
+	paths := []string{writeKubeconfig(t, "config", map[string]string{"corp": "https://api.corp.example"})}
+	f := NewClientFactory(false)
+	f.SetContexts(config.ContextsConfig{
+		"corp-proxied": {Context: "corp", Proxy: "http://proxy.corp.example:3128"},
+		"corp-direct":  {Context: "corp", Proxy: config.ProxyDirect},
+	})
+	req, _ := http.NewRequest("GET", "https://api.corp.example/api", nil)
+
+	proxied, restConfig, err := f.GetClientForContext(paths, "corp-proxied")
+	if err != nil {
+		t.Fatalf("GetClientForContext(corp-proxied): %v", err)
+	}
+	if u, err := restConfig.Proxy(req); err != nil || u == nil || u.Host != "proxy.corp.example:3128" {
+		t.Errorf("proxy = %v, %v; want proxy.corp.example:3128", u, err)
+	}
+
+	_, restConfig, err = f.GetClientForContext(paths, "corp-direct")
+	if err != nil {
+		t.Fatalf("GetClientForContext(corp-direct): %v", err)
+	}
+	if u, err := restConfig.Proxy(req); err != nil || u != nil {
+		t.Errorf("proxy = %v, %v; want a direct connection", u, err)
+	}
+
+	// the plain context keeps client-go's environment-based default
+	plain, restConfig, err := f.GetClientForContext(paths, "corp")
+	if err != nil {
+		t.Fatalf("GetClientForContext(corp): %v", err)
+	}
+	if restConfig.Proxy != nil || plain == proxied {
+		t.Error("the plain context shares the proxied alias's client")
+	}
+}
@@ -3,6 +3,7 @@ package k8sutil
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,6 +15,21 @@ func GetService(ctx context.Context, clientset kubernetes.Interface, namespace,
 	return clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
+// ServicePorts returns the ports a service declares, sorted and de-duplicated
+// (a service may declare the same port for TCP and UDP)
+func ServicePorts(svc *corev1.Service) []int {
+	seen := make(map[int]bool, len(svc.Spec.Ports))
+	ports := make([]int, 0, len(svc.Spec.Ports))
+	for _, p := range svc.Spec.Ports {
+		if !seen[int(p.Port)] {
+			seen[int(p.Port)] = true
+			ports = append(ports, int(p.Port))
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
 // ResolveServicePort finds the target port for a given service port.
 // Returns the container port number and port name (if named port).
 // If port name is returned, caller must resolve it against pod spec.
@@ -0,0 +1,90 @@
+package k8sutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceCache remembers services fetched by GetService for a while, so the
+// selector and target ports of a route's service aren't fetched again every time
+// its tunnel is recreated (after idling out, a restart or a failed start). Entries
+// are keyed by context, namespace and name. A nil cache fetches every time.
+type ServiceCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[serviceKey]cachedService
+}
+
+type serviceKey struct {
+	context, namespace, name string
+}
+
+type cachedService struct {
+	svc     *corev1.Service
+	fetched time.Time
+}
+
+// NewServiceCache returns a cache keeping services for ttl, or nil if ttl isn't
+// positive
+func NewServiceCache(ttl time.Duration) *ServiceCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &ServiceCache{ttl: ttl, now: time.Now, entries: make(map[serviceKey]cachedService)}
+}
+
+// Get returns the service from the cache, or fetches and caches it. Errors aren't
+// cached. The service is shared with other callers and must not be modified.
+func (c *ServiceCache) Get(ctx context.Context, clientset kubernetes.Interface, contextName, namespace, name string) (*corev1.Service, error) {
+	if c == nil {
+		return GetService(ctx, clientset, namespace, name)
+	}
+	key := serviceKey{contextName, namespace, name}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.fetched) < c.ttl {
+		return entry.svc, nil
+	}
+
+	svc, err := GetService(ctx, clientset, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = cachedService{svc: svc, fetched: c.now()}
+	c.mu.Unlock()
+	return svc, nil
+}
+
+// Forget drops a service, for when a start using it failed: the service may have
+// changed since it was cached
+func (c *ServiceCache) Forget(contextName, namespace, name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, serviceKey{contextName, namespace, name})
+	c.mu.Unlock()
+}
+
+// ForgetContext drops every service of a context
+func (c *ServiceCache) ForgetContext(contextName string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.context == contextName {
+			delete(c.entries, key)
+		}
+	}
+}
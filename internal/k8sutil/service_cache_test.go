@@ -0,0 +1,99 @@
+package k8sutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func countServiceGets(clientset *fake.Clientset) *int {
+	gets := 0
+	clientset.PrependReactor("get", "services", func(k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+	return &gets
+}
+
+func TestServiceCache(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	})
+	gets := countServiceGets(clientset)
+
+	now := time.Now()
+	cache := NewServiceCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 3 {
+		svc, err := cache.Get(ctx, clientset, "dev", "apps", "web")
+		if err != nil || svc.Spec.Selector["app"] != "web" {
+			t.Fatalf("Get = %v, %v", svc, err)
+		}
+	}
+	if *gets != 1 {
+		t.Errorf("expected 1 API call for 3 lookups, got %d", *gets)
+	}
+
+	// same service name in another context is another entry
+	if _, err := cache.Get(ctx, clientset, "prod", "apps", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if *gets != 2 {
+		t.Errorf("expected a lookup in another context to hit the API, got %d calls", *gets)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.Get(ctx, clientset, "dev", "apps", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if *gets != 3 {
+		t.Errorf("expected an expired entry to be fetched again, got %d calls", *gets)
+	}
+
+	cache.Forget("dev", "apps", "web")
+	if _, err := cache.Get(ctx, clientset, "dev", "apps", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if *gets != 4 {
+		t.Errorf("expected a forgotten entry to be fetched again, got %d calls", *gets)
+	}
+
+	cache.ForgetContext("prod")
+	if _, err := cache.Get(ctx, clientset, "prod", "apps", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if *gets != 5 {
+		t.Errorf("expected ForgetContext to drop the context's entries, got %d calls", *gets)
+	}
+
+	if _, err := cache.Get(ctx, clientset, "dev", "apps", "missing"); err == nil {
+		t.Error("expected an error for a missing service")
+	}
+}
+
+func TestServiceCache_Disabled(t *testing.T) {
+	if cache := NewServiceCache(0); cache != nil {
+		t.Fatal("expected no cache for a zero TTL")
+	}
+	var cache *ServiceCache
+	clientset := fake.NewSimpleClientset(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"}})
+	gets := countServiceGets(clientset)
+	for range 2 {
+		if _, err := cache.Get(context.Background(), clientset, "dev", "apps", "web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.Forget("dev", "apps", "web")
+	if *gets != 2 {
+		t.Errorf("expected a nil cache to fetch every time, got %d calls", *gets)
+	}
+}
@@ -0,0 +1,27 @@
+package k8sutil
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestServicePorts(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 8080},
+				{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP},
+				{Name: "dns-udp", Port: 53, Protocol: corev1.ProtocolUDP},
+				{Name: "debug", Port: 5005},
+			},
+		},
+	}
+
+	got := ServicePorts(svc)
+	want := []int{53, 5005, 8080}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ServicePorts() = %v, want %v", got, want)
+	}
+}
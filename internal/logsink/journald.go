@@ -0,0 +1,67 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// journalSocket is where systemd-journald takes native protocol datagrams
+var journalSocket = "/run/systemd/journal/socket"
+
+// journaldWriter sends each line as a journal entry with PRIORITY and the line's
+// tags as AUTOTUNNEL_COMPONENT / AUTOTUNNEL_ROUTE
+type journaldWriter struct {
+	conn *net.UnixConn
+	tag  string
+}
+
+func openJournald(tag string) (io.WriteCloser, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald is not available: %w", err)
+	}
+	return &journaldWriter{conn: conn, tag: tag}, nil
+}
+
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	l := parseLine(p)
+
+	var entry bytes.Buffer
+	writeJournalField(&entry, "MESSAGE", l.text)
+	writeJournalField(&entry, "PRIORITY", strconv.Itoa(l.severity()))
+	writeJournalField(&entry, "SYSLOG_IDENTIFIER", w.tag)
+	if c := l.component(); c != "" {
+		writeJournalField(&entry, "AUTOTUNNEL_COMPONENT", c)
+	}
+	if r := l.route(); r != "" {
+		writeJournalField(&entry, "AUTOTUNNEL_ROUTE", r)
+	}
+
+	if _, err := w.conn.Write(entry.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *journaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// writeJournalField appends KEY=value, or the length-prefixed form for values
+// containing a newline
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", key, value)
+		return
+	}
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,78 @@
+// Package logsink sends autotunnel's log lines to the system log (log.output),
+// with a severity picked from the line and its [component] [route] tags as fields.
+package logsink
+
+import (
+	"io"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// Severities, as numbered by syslog and journald
+const (
+	severityErr     = 3
+	severityWarning = 4
+	severityInfo    = 6
+)
+
+// Open returns a writer for cfg's output, to be passed to log.SetOutput, or nil
+// when logs stay on stderr. Each Write is expected to be one log line.
+func Open(cfg config.LogConfig) (io.WriteCloser, error) {
+	switch cfg.Output {
+	case config.LogOutputSyslog:
+		return openSyslog(cfg.SyslogAddr, cfg.GetTag())
+	case config.LogOutputJournald:
+		return openJournald(cfg.GetTag())
+	}
+	return nil, nil
+}
+
+// line is a log line split into its leading "[...]" tags and the rest
+type line struct {
+	text string // the whole line without the trailing newline
+	tags []string
+	msg  string
+}
+
+func parseLine(p []byte) line {
+	l := line{text: strings.TrimRight(string(p), "\n")}
+	rest := l.text
+	for strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			break
+		}
+		l.tags = append(l.tags, rest[1:end])
+		rest = strings.TrimPrefix(rest[end+1:], " ")
+	}
+	l.msg = rest
+	return l
+}
+
+// component is the first tag, e.g. "http", "tls" or "tcp:5432"
+func (l line) component() string {
+	if len(l.tags) == 0 {
+		return ""
+	}
+	return l.tags[0]
+}
+
+// route is the second tag, which HTTP and TLS lines use for the hostname
+func (l line) route() string {
+	if len(l.tags) < 2 {
+		return ""
+	}
+	return l.tags[1]
+}
+
+// severity follows the "Error: ..." / "Warning: ..." wording used across the code
+func (l line) severity() int {
+	switch {
+	case strings.HasPrefix(l.msg, "Error"), strings.HasPrefix(l.msg, "Failed"), strings.HasPrefix(l.msg, "Fatal"):
+		return severityErr
+	case strings.HasPrefix(l.msg, "Warning"):
+		return severityWarning
+	}
+	return severityInfo
+}
@@ -0,0 +1,111 @@
+package logsink
+
+import (
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		in        string
+		component string
+		route     string
+		severity  int
+	}{
+		{"[http] [api.localhost] [abc123] GET /\n", "http", "api.localhost", severityInfo},
+		{"[tls] [db.localhost] Error: no route\n", "tls", "db.localhost", severityErr},
+		{"[tcp:5432] Failed to start tunnel: boom\n", "tcp:5432", "", severityErr},
+		{"Warning: Failed to start config watcher: x\n", "", "", severityWarning},
+	}
+	for _, tt := range tests {
+		l := parseLine([]byte(tt.in))
+		if l.component() != tt.component || l.route() != tt.route || l.severity() != tt.severity {
+			t.Errorf("parseLine(%q) = component %q, route %q, severity %d; want %q, %q, %d",
+				tt.in, l.component(), l.route(), l.severity(), tt.component, tt.route, tt.severity)
+		}
+		if strings.HasSuffix(l.text, "\n") {
+			t.Errorf("parseLine(%q) kept the newline", tt.in)
+		}
+	}
+}
+
+func TestOpen_Stderr(t *testing.T) {
+	w, err := Open(config.LogConfig{})
+	if w != nil || err != nil {
+		t.Errorf("Open(stderr) = %v, %v; want nil, nil", w, err)
+	}
+}
+
+func TestJournald(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs unix datagram sockets")
+	}
+	sock := filepath.Join(t.TempDir(), "journal.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+	defer func(orig string) { journalSocket = orig }(journalSocket)
+	journalSocket = sock
+
+	w, err := Open(config.LogConfig{Output: config.LogOutputJournald})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("[tls] [db.localhost] Warning: slow\nsecond line\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	entry := string(buf[:n])
+	for _, want := range []string{"PRIORITY=4\n", "SYSLOG_IDENTIFIER=autotunnel\n", "AUTOTUNNEL_COMPONENT=tls\n", "AUTOTUNNEL_ROUTE=db.localhost\n", "MESSAGE\n"} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("entry is missing %q:\n%q", want, entry)
+		}
+	}
+}
+
+func TestSyslog_Remote(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("syslog is not supported")
+	}
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	w, err := Open(config.LogConfig{Output: config.LogOutputSyslog, SyslogAddr: "udp://" + server.LocalAddr().String(), Tag: "at-test"})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("Error: something broke\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := server.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	// <27> = facility daemon (3) * 8 + severity err (3)
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<27>") || !strings.Contains(msg, "at-test") || !strings.Contains(msg, "Error: something broke") {
+		t.Errorf("unexpected syslog message %q", msg)
+	}
+}
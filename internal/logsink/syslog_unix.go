@@ -0,0 +1,53 @@
+//go:build !windows && !plan9
+
+package logsink
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/url"
+)
+
+// syslogWriter sends each line with the severity picked from its wording
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+// openSyslog connects to addr ("udp://host:514"), or the local syslog when empty
+func openSyslog(addr, tag string) (io.WriteCloser, error) {
+	var network, raddr string
+	if addr != "" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog address: %w", err)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("syslog is not available: %w", err)
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	l := parseLine(p)
+	var err error
+	switch l.severity() {
+	case severityErr:
+		err = s.w.Err(l.text)
+	case severityWarning:
+		err = s.w.Warning(l.text)
+	default:
+		err = s.w.Info(l.text)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
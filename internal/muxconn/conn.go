@@ -0,0 +1,98 @@
+// Package muxconn serves several protocols on one listener: Conn looks at the
+// first bytes of a connection without consuming them, and Listener hands each
+// connection to the server for its protocol through a Route.
+package muxconn
+
+import (
+	"bufio"
+	"net"
+)
+
+// HTTP2Preface is what an HTTP/2 client with prior knowledge (h2c, no Upgrade)
+// sends before its first frame
+const HTTP2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// Conn is a connection whose first bytes can be peeked at. Reads replay them.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// Peek returns the next n bytes without consuming them, waiting for them to arrive
+func (c *Conn) Peek(n int) ([]byte, error) {
+	return c.reader.Peek(n)
+}
+
+// Buffered is how many bytes have been read from the connection but not consumed
+func (c *Conn) Buffered() int {
+	return c.reader.Buffered()
+}
+
+// CloseWrite and CloseRead let netutil.BidirectionalCopy half-close the client connection
+func (c *Conn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func (c *Conn) CloseRead() error {
+	if cr, ok := c.Conn.(interface{ CloseRead() error }); ok {
+		return cr.CloseRead()
+	}
+	return nil
+}
+
+// IsTLS reports whether the connection starts with a TLS handshake record
+func (c *Conn) IsTLS() bool {
+	// 0x16 = TLS handshake record type
+	b, err := c.reader.Peek(1)
+	if err != nil {
+		return false
+	}
+	return b[0] == 0x16
+}
+
+// IsHTTP2Preface reports whether the connection starts with HTTP2Preface
+func (c *Conn) IsHTTP2Preface() bool {
+	return c.MatchPrefix([]string{HTTP2Preface}) == 0
+}
+
+// MatchPrefix returns the index of the first of prefixes the connection starts
+// with, -1 if none. It reads only as far as some prefix still matches, so a
+// client that sent something else isn't left waiting for bytes that never come.
+// Nothing is consumed.
+func (c *Conn) MatchPrefix(prefixes []string) int {
+	n := 1
+	for {
+		// on error, b still holds what arrived, and no more will: prefixes it
+		// completes count, the ones it only starts don't
+		b, err := c.reader.Peek(n)
+		longest := 0 // of the earlier prefixes still matching
+		for i, p := range prefixes {
+			switch {
+			case len(b) >= len(p) && string(b[:len(p)]) == p:
+				if longest == 0 {
+					return i
+				}
+			case err == nil && len(b) < len(p) && string(b) == p[:len(b)]:
+				longest = max(longest, len(p))
+			}
+		}
+		if longest == 0 {
+			return -1
+		}
+		n = min(max(len(b)+1, c.reader.Buffered()), longest)
+	}
+}
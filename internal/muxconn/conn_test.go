@@ -0,0 +1,184 @@
+package muxconn
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConn_IsTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected bool
+	}{
+		{
+			name:     "TLS handshake starts with 0x16",
+			data:     []byte{0x16, 0x03, 0x01, 0x00, 0x05}, // TLS 1.0 handshake
+			expected: true,
+		},
+		{
+			name:     "TLS 1.2 handshake",
+			data:     []byte{0x16, 0x03, 0x03, 0x00, 0x05}, // TLS 1.2 handshake
+			expected: true,
+		},
+		{
+			name:     "HTTP GET request",
+			data:     []byte("GET / HTTP/1.1\r\n"),
+			expected: false,
+		},
+		{
+			name:     "HTTP POST request",
+			data:     []byte("POST /api HTTP/1.1\r\n"),
+			expected: false,
+		},
+		{
+			name:     "HTTP PUT request",
+			data:     []byte("PUT /resource HTTP/1.1\r\n"),
+			expected: false,
+		},
+		{
+			name:     "Random data not TLS",
+			data:     []byte{0x00, 0x01, 0x02, 0x03},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create a pipe to simulate a connection
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+			defer func() { _ = server.Close() }()
+
+			// Write test data from client side
+			go func() {
+				_, _ = client.Write(tt.data)
+			}()
+
+			// Create Conn and test
+			pc := NewConn(server)
+			result := pc.IsTLS()
+
+			if result != tt.expected {
+				t.Errorf("IsTLS() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConn_ReadAfterPeek(t *testing.T) {
+	// Verify that data can still be read after peeking
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	testData := []byte("GET /test HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	go func() {
+		_, _ = client.Write(testData)
+		client.Close()
+	}()
+
+	pc := NewConn(server)
+
+	// Peek at first byte
+	peeked, err := pc.Peek(1)
+	if err != nil {
+		t.Fatalf("Peek failed: %v", err)
+	}
+	if peeked[0] != 'G' {
+		t.Errorf("Expected 'G', got %c", peeked[0])
+	}
+
+	// Now read all data - should get full content including peeked byte
+	all, err := io.ReadAll(pc)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+
+	if string(all) != string(testData) {
+		t.Errorf("Expected %q, got %q", testData, all)
+	}
+}
+
+func TestConn_IsHTTP2Preface(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{"preface", HTTP2Preface, true},
+		{"preface and frames", HTTP2Preface + "\x00\x00\x12\x04", true},
+		{"HTTP/1 request", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", false},
+		{"short HTTP/1 request", "GET / HTTP/1.0\r\n\r\n", false},
+		{"PRI method, not the preface", "PRI / HTTP/1.1\r\n\r\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			// the client keeps the connection open: a mismatch must not wait for more bytes
+			go func() { _, _ = client.Write([]byte(tt.data)) }()
+
+			pc := NewConn(server)
+			_ = server.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if got := pc.IsHTTP2Preface(); got != tt.expected {
+				t.Errorf("IsHTTP2Preface() = %v, expected %v", got, tt.expected)
+			}
+
+			// nothing was consumed
+			head, err := pc.Peek(4)
+			if err != nil || string(head) != tt.data[:4] {
+				t.Errorf("Peek after detection = %q, %v; want %q", head, err, tt.data[:4])
+			}
+		})
+	}
+}
+
+func TestConn_MatchPrefix(t *testing.T) {
+	prefixes := []string{"SSH-", "PING\r\n", "PI"}
+	tests := []struct {
+		name     string
+		data     string
+		expected int
+	}{
+		{"ssh banner", "SSH-2.0-OpenSSH_9.6\r\n", 0},
+		{"redis inline ping", "PING\r\n", 1},
+		// "PING\r\n" comes first, so "PI" waits for it to fail
+		{"shorter later prefix", "PIZZA", 2},
+		{"http", "GET / HTTP/1.1\r\n\r\n", -1},
+		{"shares first byte", "SSL", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			go func() { _, _ = client.Write([]byte(tt.data)) }()
+
+			_ = server.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if got := NewConn(server).MatchPrefix(prefixes); got != tt.expected {
+				t.Errorf("MatchPrefix(%q) = %d, expected %d", tt.data, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConn_MatchPrefix_ClientStopsEarly(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		_, _ = client.Write([]byte("PI"))
+		client.Close()
+	}()
+
+	// "PING" can't complete anymore, but "PI" did
+	if got := NewConn(server).MatchPrefix([]string{"PING", "PI"}); got != 1 {
+		t.Errorf("MatchPrefix = %d, expected 1", got)
+	}
+}
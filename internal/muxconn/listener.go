@@ -0,0 +1,83 @@
+package muxconn
+
+import (
+	"net"
+	"sync"
+)
+
+// routeBacklog is how many dispatched connections a Route holds until accepted
+const routeBacklog = 256
+
+// Listener accepts connections for the caller to look at and Dispatch to one of
+// its Routes, each served by its own server
+type Listener struct {
+	net.Listener
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func Listen(addr string) (*Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewListener(l), nil
+}
+
+// NewListener wraps an already bound listener
+func NewListener(l net.Listener) *Listener {
+	return &Listener{
+		Listener: l,
+		done:     make(chan struct{}),
+	}
+}
+
+// NewRoute returns a net.Listener accepting the connections dispatched to it
+func (l *Listener) NewRoute() *Route {
+	return &Route{mux: l, conns: make(chan net.Conn, routeBacklog)}
+}
+
+// Close stops Accept on the Listener and on all its Routes
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// Route accepts the connections the Listener's owner dispatched to it
+type Route struct {
+	mux   *Listener
+	conns chan net.Conn
+}
+
+// Dispatch hands conn to the server accepting on r. It returns false once the
+// Listener is closed; conn is then left to the caller.
+func (r *Route) Dispatch(conn net.Conn) bool {
+	select {
+	case <-r.mux.done:
+		return false
+	default:
+	}
+	select {
+	case r.conns <- conn:
+		return true
+	case <-r.mux.done:
+		return false
+	}
+}
+
+func (r *Route) Accept() (net.Conn, error) {
+	select {
+	case conn := <-r.conns:
+		return conn, nil
+	case <-r.mux.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (r *Route) Close() error {
+	return nil // Listener handles this
+}
+
+func (r *Route) Addr() net.Addr {
+	return r.mux.Listener.Addr()
+}
@@ -0,0 +1,75 @@
+package muxconn
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListener_Dispatch(t *testing.T) {
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	a, b := l.NewRoute(), l.NewRoute()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	if !b.Dispatch(conn) {
+		t.Fatal("Dispatch failed on an open listener")
+	}
+	if got, err := b.Accept(); err != nil || got != conn {
+		t.Errorf("Route b accepted %v, %v; want the dispatched connection", got, err)
+	}
+	if b.Addr().String() != l.Addr().String() {
+		t.Errorf("Route address %s, want the listener's %s", b.Addr(), l.Addr())
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		_, err := a.Accept()
+		accepted <- err
+	}()
+	select {
+	case err := <-accepted:
+		t.Fatalf("Route a accepted a connection dispatched to b (err %v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = l.Close()
+	select {
+	case err := <-accepted:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Accept after Close = %v, want net.ErrClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not unblock Accept on a route")
+	}
+}
+
+func TestRoute_DispatchAfterClose(t *testing.T) {
+	l, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	r := l.NewRoute()
+	_ = l.Close()
+	_ = l.Close() // closing twice is fine
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	if r.Dispatch(server) {
+		t.Error("Dispatch succeeded on a closed listener")
+	}
+}
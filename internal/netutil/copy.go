@@ -6,22 +6,92 @@ import (
 	"sync"
 )
 
+// copyBufferSize matches io.Copy's default buffer size
+const copyBufferSize = 32 * 1024
+
+var copyBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// GetBuffer returns a pooled 32KB buffer. Return it with PutBuffer once nothing references it.
+func GetBuffer() *[]byte {
+	return copyBufPool.Get().(*[]byte)
+}
+
+// PutBuffer returns a buffer obtained from GetBuffer to the pool
+func PutBuffer(buf *[]byte) {
+	copyBufPool.Put(buf)
+}
+
+// Copy copies src to dst until EOF. Between two TCP connections it uses io.Copy so
+// the kernel can splice (Linux); otherwise it copies through a pooled buffer, since
+// net.TCPConn.ReadFrom and io.Copy would allocate a fresh one per call.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	if _, ok := dst.(*net.TCPConn); ok {
+		if _, ok := src.(*net.TCPConn); ok {
+			return io.Copy(dst, src)
+		}
+	}
+
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	// hide ReadFrom/WriteTo so io.CopyBuffer actually uses buf
+	return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, *buf)
+}
+
+// HalfClose passes on the end of src's data to dst: dst's write side and src's
+// read side are shut down, for every connection type supporting it (TCP, Unix,
+// TLS, and wrappers forwarding CloseWrite/CloseRead)
+func HalfClose(dst, src net.Conn) {
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	}
+	if cr, ok := src.(interface{ CloseRead() error }); ok {
+		_ = cr.CloseRead()
+	}
+}
+
+type writerOnly struct{ io.Writer }
+
+type readerOnly struct{ io.Reader }
+
+// PooledWriter wraps w so that io.Copy into it goes through Copy's pooled buffer.
+// Use it for writers handed to libraries that copy with io.Copy internally.
+func PooledWriter(w io.Writer) io.Writer {
+	return pooledWriter{w}
+}
+
+type pooledWriter struct{ io.Writer }
+
+func (p pooledWriter) ReadFrom(r io.Reader) (int64, error) {
+	return Copy(p.Writer, r)
+}
+
 // BidirectionalCopy copies data between two connections in both directions.
-// It blocks until both directions are complete and handles CloseWrite for TCP connections.
+// It blocks until both directions are complete. When one side stops sending, the
+// other is half-closed (CloseWrite) so it sees EOF but can still answer.
 func BidirectionalCopy(conn1, conn2 net.Conn) {
+	BidirectionalCopyCounted(conn1, conn2)
+}
+
+// BidirectionalCopyCounted is BidirectionalCopy, returning how many bytes were
+// written to conn1 and to conn2
+func BidirectionalCopyCounted(conn1, conn2 net.Conn) (to1, to2 int64) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	copy := func(dst, src net.Conn) {
+	copy := func(dst, src net.Conn, n *int64) {
 		defer wg.Done()
-		_, _ = io.Copy(dst, src)
-		if tc, ok := dst.(*net.TCPConn); ok {
-			_ = tc.CloseWrite()
-		}
+		*n, _ = Copy(dst, src)
+		HalfClose(dst, src)
 	}
 
-	go copy(conn1, conn2)
-	go copy(conn2, conn1)
+	go copy(conn1, conn2, &to1)
+	go copy(conn2, conn1, &to2)
 
 	wg.Wait()
+	return to1, to2
 }
@@ -0,0 +1,106 @@
+package netutil
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CopyLimits bound how long BidirectionalCopyLimited keeps two connections joined.
+// Zero means no limit.
+type CopyLimits struct {
+	Idle        time.Duration // no data in either direction for this long
+	MaxDuration time.Duration // since the copy started, however busy
+}
+
+// Reasons BidirectionalCopyLimited cut the connections
+const (
+	LimitIdle        = "idle timeout"
+	LimitMaxDuration = "max duration"
+)
+
+// BidirectionalCopyLimited is BidirectionalCopy closing both connections once a
+// limit is hit, and returns which one ("" if the connections ended on their own).
+// With limits it copies through a pooled buffer, since watching for idleness
+// rules out the kernel splicing of io.Copy.
+func BidirectionalCopyLimited(conn1, conn2 net.Conn, limits CopyLimits) string {
+	if limits.Idle == 0 && limits.MaxDuration == 0 {
+		BidirectionalCopy(conn1, conn2)
+		return ""
+	}
+
+	start := time.Now()
+	var lastActive atomic.Int64
+	lastActive.Store(start.UnixNano())
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copy := func(dst, src net.Conn) {
+		defer wg.Done()
+		buf := GetBuffer()
+		defer PutBuffer(buf)
+		for {
+			n, err := src.Read(*buf)
+			if n > 0 {
+				lastActive.Store(time.Now().UnixNano())
+				if _, werr := dst.Write((*buf)[:n]); werr != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		HalfClose(dst, src)
+	}
+	go copy(conn1, conn2)
+	go copy(conn2, conn1)
+
+	done := make(chan struct{})
+	reason := make(chan string, 1)
+	go func() {
+		reason <- watchLimits(conn1, conn2, limits, start, &lastActive, done)
+	}()
+
+	wg.Wait()
+	close(done)
+	return <-reason
+}
+
+// watchLimits closes both connections when a limit is hit, or returns "" once done is closed
+func watchLimits(conn1, conn2 net.Conn, limits CopyLimits, start time.Time, lastActive *atomic.Int64, done <-chan struct{}) string {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return ""
+		case now := <-timer.C:
+			var hit string
+			next := time.Duration(-1)
+			if limits.MaxDuration > 0 {
+				left := limits.MaxDuration - now.Sub(start)
+				if left <= 0 {
+					hit = LimitMaxDuration
+				}
+				next = left
+			}
+			if limits.Idle > 0 && hit == "" {
+				left := limits.Idle - now.Sub(time.Unix(0, lastActive.Load()))
+				if left <= 0 {
+					hit = LimitIdle
+				}
+				if next < 0 || left < next {
+					next = left
+				}
+			}
+			if hit != "" {
+				_ = conn1.Close()
+				_ = conn2.Close()
+				return hit
+			}
+			timer.Reset(next)
+		}
+	}
+}
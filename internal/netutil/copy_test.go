@@ -0,0 +1,192 @@
+package netutil
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tcpPair returns both ends of a loopback TCP connection
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := l.Accept()
+		accepted <- conn
+	}()
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	server := <-accepted
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client.(*net.TCPConn), server.(*net.TCPConn)
+}
+
+func TestCopy_NonTCP(t *testing.T) {
+	data := strings.Repeat("autotunnel", 10000)
+	var dst bytes.Buffer
+
+	n, err := Copy(&dst, strings.NewReader(data))
+	if err != nil || n != int64(len(data)) || dst.String() != data {
+		t.Errorf("Copy = %d, %v; copied %d bytes", n, err, dst.Len())
+	}
+}
+
+func TestBidirectionalCopy_TCP(t *testing.T) {
+	clientA, proxyA := tcpPair(t)
+	proxyB, backendB := tcpPair(t)
+
+	done := make(chan struct{})
+	go func() {
+		BidirectionalCopy(proxyA, proxyB)
+		close(done)
+	}()
+
+	request := strings.Repeat("ping", 50000)
+	go func() {
+		_, _ = clientA.Write([]byte(request))
+		_ = clientA.CloseWrite()
+	}()
+
+	got, err := io.ReadAll(backendB)
+	if err != nil || string(got) != request {
+		t.Fatalf("Backend received %d bytes (err %v), want %d", len(got), err, len(request))
+	}
+
+	_, _ = backendB.Write([]byte("pong"))
+	_ = backendB.CloseWrite()
+
+	reply, _ := io.ReadAll(clientA)
+	if string(reply) != "pong" {
+		t.Errorf("Client received %q, want %q", reply, "pong")
+	}
+	<-done
+}
+
+func TestBidirectionalCopyCounted(t *testing.T) {
+	client, proxyIn := tcpPair(t)
+	proxyOut, backend := tcpPair(t)
+	go func() {
+		_, _ = io.ReadAll(backend)
+		_, _ = backend.Write([]byte("pong!"))
+		_ = backend.CloseWrite()
+	}()
+	go func() {
+		_, _ = client.Write([]byte("ping"))
+		_ = client.CloseWrite()
+		_, _ = io.Copy(io.Discard, client)
+	}()
+
+	toBackend, toClient := BidirectionalCopyCounted(proxyOut, proxyIn)
+	if toBackend != 4 || toClient != 5 {
+		t.Errorf("BidirectionalCopyCounted = %d, %d; want 4, 5", toBackend, toClient)
+	}
+}
+
+func TestPooledWriter_ReadFrom(t *testing.T) {
+	var dst bytes.Buffer
+	w := PooledWriter(&dst)
+
+	if _, ok := w.(io.ReaderFrom); !ok {
+		t.Fatal("Expected PooledWriter to implement io.ReaderFrom")
+	}
+	if _, err := io.Copy(w, strings.NewReader("hello")); err != nil || dst.String() != "hello" {
+		t.Errorf("io.Copy via PooledWriter = %q, %v", dst.String(), err)
+	}
+}
+
+func TestBidirectionalCopyLimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits CopyLimits
+		chatty bool // keep sending past the idle timeout
+		want   string
+	}{
+		{"idle", CopyLimits{Idle: 50 * time.Millisecond}, false, LimitIdle},
+		{"busy past idle", CopyLimits{Idle: 100 * time.Millisecond, MaxDuration: 400 * time.Millisecond}, true, LimitMaxDuration},
+		{"max duration", CopyLimits{MaxDuration: 50 * time.Millisecond}, false, LimitMaxDuration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxyIn := tcpPair(t)
+			proxyOut, backend := tcpPair(t)
+			go func() { _, _ = io.Copy(io.Discard, backend) }()
+			if tt.chatty {
+				go func() {
+					for {
+						if _, err := client.Write([]byte("ping")); err != nil {
+							return
+						}
+						time.Sleep(20 * time.Millisecond)
+					}
+				}()
+			}
+
+			start := time.Now()
+			got := BidirectionalCopyLimited(proxyIn, proxyOut, tt.limits)
+			if got != tt.want {
+				t.Errorf("reason = %q, want %q", got, tt.want)
+			}
+			if tt.chatty && time.Since(start) < 300*time.Millisecond {
+				t.Errorf("Busy connection was cut after %v, before max duration", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestBidirectionalCopyLimited_EndsOnItsOwn(t *testing.T) {
+	client, proxyIn := tcpPair(t)
+	proxyOut, backend := tcpPair(t)
+	go func() {
+		_, _ = io.Copy(backend, backend) // echo until the client half-closes
+		_ = backend.CloseWrite()
+	}()
+	go func() {
+		_, _ = client.Write([]byte("hello"))
+		_ = client.CloseWrite()
+		_, _ = io.Copy(io.Discard, client)
+	}()
+
+	if got := BidirectionalCopyLimited(proxyIn, proxyOut, CopyLimits{Idle: time.Minute}); got != "" {
+		t.Errorf("reason = %q, want none", got)
+	}
+}
+
+// wrappedConn hides the *net.TCPConn type the way muxconn.Conn or tls.Conn do,
+// keeping its CloseWrite/CloseRead
+type wrappedConn struct{ *net.TCPConn }
+
+func TestBidirectionalCopy_HalfCloseThroughWrapper(t *testing.T) {
+	client, proxyIn := tcpPair(t)
+	proxyOut, backend := tcpPair(t)
+	go BidirectionalCopy(wrappedConn{proxyOut}, wrappedConn{proxyIn})
+
+	// the backend answers only once the request is complete
+	go func() {
+		req, _ := io.ReadAll(backend)
+		_, _ = backend.Write([]byte("got " + string(req)))
+		_ = backend.Close()
+	}()
+
+	_, _ = client.Write([]byte("request"))
+	_ = client.CloseWrite()
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := io.ReadAll(client)
+	if err != nil || string(resp) != "got request" {
+		t.Errorf("response = %q, %v; want the backend to see EOF and still answer", resp, err)
+	}
+}
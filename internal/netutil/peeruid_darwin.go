@@ -0,0 +1,49 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PeerUID asks lsof which user owns the client end of a loopback connection
+// accepted on local from remote
+func PeerUID(local, remote net.Addr) (int, error) {
+	localTCP, ok1 := local.(*net.TCPAddr)
+	remoteTCP, ok2 := remote.(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		return 0, fmt.Errorf("not a TCP connection")
+	}
+	if !remoteTCP.IP.IsLoopback() {
+		return 0, fmt.Errorf("peer %s is not local", remote)
+	}
+
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", remoteTCP.Port), "-sTCP:ESTABLISHED", "-Fpun").Output()
+	if err != nil {
+		return 0, fmt.Errorf("lsof: %w", err)
+	}
+	// -F output is one field per line: p<pid>, u<uid>, then n<local>-><remote> per socket.
+	// Both ends match the port; the client's is the one whose local side is remote.
+	var pid, uid int
+	wantName := fmt.Sprintf(":%d->", remoteTCP.Port)
+	wantPeer := fmt.Sprintf(":%d", localTCP.Port)
+	for _, line := range strings.Split(string(out), "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			pid, _ = strconv.Atoi(line[1:])
+		case 'u':
+			uid, _ = strconv.Atoi(line[1:])
+		case 'n':
+			if pid != os.Getpid() && strings.Contains(line, wantName) && strings.HasSuffix(line, wantPeer) {
+				return uid, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no socket found for %s", remote)
+}
@@ -0,0 +1,56 @@
+package netutil
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PeerUID finds the uid owning the client end of a loopback connection accepted
+// on local from remote, by looking up the client's socket in /proc/net/tcp{,6}
+func PeerUID(local, remote net.Addr) (int, error) {
+	localTCP, ok1 := local.(*net.TCPAddr)
+	remoteTCP, ok2 := remote.(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		return 0, fmt.Errorf("not a TCP connection")
+	}
+	if !remoteTCP.IP.IsLoopback() {
+		return 0, fmt.Errorf("peer %s is not local", remote)
+	}
+
+	// the client socket is bound to the remote port and connected to ours
+	wantLocal := fmt.Sprintf(":%04X", remoteTCP.Port)
+	wantRemote := fmt.Sprintf(":%04X", localTCP.Port)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		if uid, ok := establishedUID(path, wantLocal, wantRemote); ok {
+			return uid, nil
+		}
+	}
+	return 0, fmt.Errorf("no socket found for %s", remote)
+}
+
+// establishedUID returns the uid of an ESTABLISHED (01) socket with the given port suffixes
+func establishedUID(path, wantLocal, wantRemote string) (int, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if strings.HasSuffix(fields[1], wantLocal) && strings.HasSuffix(fields[2], wantRemote) && fields[3] == "01" {
+			uid, err := strconv.Atoi(fields[7])
+			return uid, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package netutil
+
+import (
+	"fmt"
+	"net"
+)
+
+// PeerUID is not implemented on this platform
+func PeerUID(local, remote net.Addr) (int, error) {
+	return 0, fmt.Errorf("peer user lookup is not supported on this platform")
+}
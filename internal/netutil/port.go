@@ -0,0 +1,55 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// IsAddrInUse reports whether a listen error was caused by the port already being bound
+func IsAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// DescribePortOwner returns a human-readable description of the process listening
+// on the given TCP port, e.g. "postgres (pid 4211)". Returns "" when it can't tell.
+func DescribePortOwner(port int) string {
+	name, pid := PortOwner(port)
+	switch {
+	case pid > 0 && name != "":
+		return fmt.Sprintf("%s (pid %d)", name, pid)
+	case pid > 0:
+		return fmt.Sprintf("pid %d", pid)
+	default:
+		return ""
+	}
+}
+
+// ListenPort extracts the port from a listen address like ":8989" or "127.0.0.1:8989"
+func ListenPort(addr string) (int, error) {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(portStr)
+}
+
+// ListenNextFree binds the first free port on host in (start, limit], skipping ports in skip.
+// Returns the listener and the port it ended up on.
+func ListenNextFree(host string, start, limit int, skip map[int]bool) (net.Listener, int, error) {
+	for port := start + 1; port <= limit; port++ {
+		if skip[port] {
+			continue
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+		if err == nil {
+			return l, port, nil
+		}
+		if !IsAddrInUse(err) {
+			return nil, 0, err
+		}
+	}
+	return nil, 0, fmt.Errorf("no free port found in %d-%d", start+1, limit)
+}
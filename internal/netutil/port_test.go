@@ -0,0 +1,116 @@
+package netutil
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestListenNextFree_SkipsBusyAndReserved(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:19701")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer busy.Close()
+
+	l, port, err := ListenNextFree("127.0.0.1", 19700, 65535, map[int]bool{19702: true})
+	if err != nil {
+		t.Fatalf("ListenNextFree failed: %v", err)
+	}
+	defer l.Close()
+
+	if port != 19703 {
+		t.Errorf("Expected port 19703 (19701 busy, 19702 reserved), got %d", port)
+	}
+}
+
+func TestListenNextFree_RespectsMax(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:19711")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer busy.Close()
+
+	_, _, err = ListenNextFree("127.0.0.1", 19710, 19711, nil)
+	if err == nil {
+		t.Error("Expected error when no port is free below max")
+	}
+}
+
+func TestListenPort(t *testing.T) {
+	tests := map[string]int{
+		":8989":        8989,
+		"127.0.0.1:80": 80,
+		"[::1]:8443":   8443,
+	}
+	for addr, want := range tests {
+		if got, err := ListenPort(addr); err != nil || got != want {
+			t.Errorf("ListenPort(%q) = %d, %v; want %d", addr, got, err, want)
+		}
+	}
+	if _, err := ListenPort("localhost"); err == nil {
+		t.Error("Expected an error for an address without a port")
+	}
+}
+
+func TestIsAddrInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	_, err = net.Listen("tcp", l.Addr().String())
+	if !IsAddrInUse(err) {
+		t.Errorf("Expected EADDRINUSE, got %v", err)
+	}
+}
+
+func TestPortOwner_Self(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("owner lookup via /proc is linux-only")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	_, pid := PortOwner(l.Addr().(*net.TCPAddr).Port)
+	if pid != os.Getpid() {
+		t.Errorf("Expected owner pid %d, got %d", os.Getpid(), pid)
+	}
+}
+
+func TestPeerUID_Self(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer lookup via /proc is linux-only")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer conn.Close()
+
+	uid, err := PeerUID(conn.LocalAddr(), conn.RemoteAddr())
+	if err != nil {
+		t.Fatalf("PeerUID failed: %v", err)
+	}
+	if uid != os.Getuid() {
+		t.Errorf("Expected uid %d, got %d", os.Getuid(), uid)
+	}
+}
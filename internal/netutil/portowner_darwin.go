@@ -0,0 +1,33 @@
+package netutil
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PortOwner asks lsof which process is listening on a TCP port.
+// lsof ships with macOS; if it fails we just report nothing.
+func PortOwner(port int) (name string, pid int) {
+	out, err := exec.Command("lsof", "-nP", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN", "-Fpc").Output()
+	if err != nil {
+		return "", 0
+	}
+	// -F output is one field per line: p<pid>, c<command>
+	for _, line := range strings.Split(string(out), "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			if pid != 0 {
+				return name, pid
+			}
+			pid, _ = strconv.Atoi(line[1:])
+		case 'c':
+			name = line[1:]
+		}
+	}
+	return name, pid
+}
@@ -0,0 +1,69 @@
+package netutil
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PortOwner finds the process listening on a TCP port by matching the socket inode
+// from /proc/net/tcp{,6} against /proc/<pid>/fd. Processes owned by other users
+// aren't visible without privileges, in which case pid is 0.
+func PortOwner(port int) (name string, pid int) {
+	inodes := make(map[string]bool)
+	for _, f := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		for _, inode := range listeningInodes(f, port) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return "", 0
+	}
+
+	procs, _ := filepath.Glob("/proc/[0-9]*")
+	for _, proc := range procs {
+		fds, err := os.ReadDir(filepath.Join(proc, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(proc, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				pid, _ = strconv.Atoi(filepath.Base(proc))
+				comm, _ := os.ReadFile(filepath.Join(proc, "comm"))
+				return strings.TrimSpace(string(comm)), pid
+			}
+		}
+	}
+	return "", 0
+}
+
+// listeningInodes returns socket inodes in LISTEN state (0A) bound to port
+func listeningInodes(path string, port int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	wantPort := fmt.Sprintf(":%04X", port)
+	var inodes []string
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if strings.HasSuffix(fields[1], wantPort) && fields[3] == "0A" {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
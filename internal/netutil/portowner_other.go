@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package netutil
+
+// PortOwner is not implemented on this platform
+func PortOwner(port int) (name string, pid int) {
+	return "", 0
+}
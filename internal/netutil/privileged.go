@@ -0,0 +1,16 @@
+package netutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsPrivilegedPort reports whether binding port traditionally needs root (< 1024)
+func IsPrivilegedPort(port int) bool {
+	return port > 0 && port < 1024
+}
+
+// IsPermissionDenied reports whether a listen error was caused by missing privileges
+func IsPermissionDenied(err error) bool {
+	return errors.Is(err, syscall.EACCES)
+}
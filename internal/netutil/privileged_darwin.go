@@ -0,0 +1,14 @@
+package netutil
+
+import "fmt"
+
+// CanBindPrivileged always returns true: since Mojave, macOS lets any user bind ports below 1024
+func CanBindPrivileged(port int) bool {
+	return true
+}
+
+// PrivilegedPortHint explains how to allow binding a privileged port on this OS
+func PrivilegedPortHint(port int) string {
+	return fmt.Sprintf("binding port %d was denied: listen on a high port via http.privileged_fallback_listen "+
+		"and redirect %d to it with pf (run `autotunnel privileged-ports` for the rules)", port, port)
+}
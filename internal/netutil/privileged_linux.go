@@ -0,0 +1,53 @@
+package netutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// capNetBindService is CAP_NET_BIND_SERVICE from linux/capability.h
+const capNetBindService = 10
+
+// CanBindPrivileged reports whether this process may bind the given port, either
+// because the kernel allows it for everyone (net.ipv4.ip_unprivileged_port_start)
+// or because we have CAP_NET_BIND_SERVICE.
+func CanBindPrivileged(port int) bool {
+	if !IsPrivilegedPort(port) || os.Geteuid() == 0 {
+		return true
+	}
+
+	if data, err := os.ReadFile("/proc/sys/net/ipv4/ip_unprivileged_port_start"); err == nil {
+		if start, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && port >= start {
+			return true
+		}
+	}
+
+	return hasEffectiveCap(capNetBindService)
+}
+
+func hasEffectiveCap(bit uint) bool {
+	data, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<bit) != 0
+	}
+	return false
+}
+
+// PrivilegedPortHint explains how to allow binding a privileged port on this OS
+func PrivilegedPortHint(port int) string {
+	return fmt.Sprintf("binding port %d needs CAP_NET_BIND_SERVICE: run `autotunnel privileged-ports` "+
+		"(sudo setcap cap_net_bind_service=+ep on the binary) or set http.privileged_fallback_listen "+
+		"to a high port and redirect to it", port)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package netutil
+
+import "fmt"
+
+// CanBindPrivileged is unknown on this platform, so we let the bind attempt decide
+func CanBindPrivileged(port int) bool {
+	return true
+}
+
+// PrivilegedPortHint explains how to allow binding a privileged port on this OS
+func PrivilegedPortHint(port int) string {
+	return fmt.Sprintf("binding port %d was denied: run as a privileged user or set http.privileged_fallback_listen", port)
+}
@@ -0,0 +1,84 @@
+// Package portstate remembers the local port each tunnel forwarded from, so a
+// restarted tunnel can ask for the same one (persist_ports).
+package portstate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a JSON file mapping keys like "api.localhost/8080" to local ports
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	ports map[string]int
+}
+
+// Load reads the state file at path. A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, ports: make(map[string]int)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.ports); err != nil {
+		return nil, fmt.Errorf("invalid port state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Key names the port of one target port of a route's tunnel
+func Key(route string, targetPort int) string {
+	return fmt.Sprintf("%s/%d", route, targetPort)
+}
+
+// Port returns the port last recorded for key, 0 if none
+func (s *Store) Port(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ports[key]
+}
+
+// SetPort records port for key, rewriting the file when it changed
+func (s *Store) SetPort(key string, port int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ports[key] == port {
+		return nil
+	}
+	s.ports[key] = port
+	return s.save()
+}
+
+// save writes the file through a temp file and rename, so a crash never leaves
+// it half-written. Caller must hold s.mu.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.ports, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".ports-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
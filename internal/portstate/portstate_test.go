@@ -0,0 +1,52 @@
+package portstate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ports.json")
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of a missing file failed: %v", err)
+	}
+	if got := s.Port(Key("api.localhost", 8080)); got != 0 {
+		t.Errorf("Port on an empty store = %d, want 0", got)
+	}
+	if err := s.SetPort(Key("api.localhost", 8080), 41234); err != nil {
+		t.Fatalf("SetPort failed: %v", err)
+	}
+	if err := s.SetPort(Key("tcp:5432", 5432), 41235); err != nil {
+		t.Fatalf("SetPort failed: %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := reloaded.Port("api.localhost/8080"); got != 41234 {
+		t.Errorf("api.localhost/8080 = %d, want 41234", got)
+	}
+	if got := reloaded.Port("tcp:5432/5432"); got != 41235 {
+		t.Errorf("tcp:5432/5432 = %d, want 41235", got)
+	}
+
+	// no leftover temp files
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only ports.json in the state dir, got %d entries", len(entries))
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ports.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for an invalid state file")
+	}
+}
@@ -0,0 +1,191 @@
+// Package rbac works out which Kubernetes permissions each route needs and asks
+// the API server (SelfSubjectAccessReview) which of them the kubeconfig's user
+// lacks, for `autotunnel rbac`.
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/atas/autotunnel/internal/config"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is one verb on one resource
+type Permission struct {
+	Verb        string
+	Resource    string
+	Subresource string
+	Namespace   string // "" is every namespace
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s in all namespaces", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+}
+
+// Need is a permission a route can't work without. With Alternative set, either
+// one will do (TCP routes fall back to exec when port-forward is forbidden).
+type Need struct {
+	Permission
+	Alternative *Permission
+}
+
+func (n Need) String() string {
+	if n.Alternative == nil {
+		return n.Permission.String()
+	}
+	return fmt.Sprintf("%s (or %s)", n.Permission, *n.Alternative)
+}
+
+// Route is a configured route and the permissions it needs
+type Route struct {
+	Name        string // hostname, tcp:<port>, jump:<port>, group:<name> or discover:<context>
+	Context     string
+	Kubeconfigs []string
+	Needs       []Need
+}
+
+// Routes lists the routes of cfg, sorted by name. dynamic_host, host_patterns and
+// postgres routes pick their namespace per request, so they are left out.
+func Routes(cfg *config.Config) []Route {
+	httpKubeconfigs := cfg.HTTP.K8s.ResolvedKubeconfigs
+	tcpKubeconfigs := cfg.TCP.K8s.ResolvedKubeconfigs
+	if len(tcpKubeconfigs) == 0 {
+		tcpKubeconfigs = httpKubeconfigs
+	}
+
+	var routes []Route
+	for hostname, r := range cfg.HTTP.K8s.Routes {
+		needs := targetNeeds(r.Namespace, r.Service, config.TCPMethodPortForward)
+		for scheme := range r.SchemeRoutes {
+			s, _ := r.ForScheme(scheme)
+			needs = append(needs, targetNeeds(s.Namespace, s.Service, config.TCPMethodPortForward)...)
+		}
+		routes = append(routes, route(hostname, r.Context, httpKubeconfigs, needs))
+	}
+	for port, r := range cfg.TCP.K8s.Routes {
+		routes = append(routes, route(fmt.Sprintf("tcp:%d", port), r.Context, tcpKubeconfigs,
+			targetNeeds(r.Namespace, r.Service, r.Method)))
+	}
+	for name, g := range cfg.TCP.K8s.Groups {
+		routes = append(routes, route("group:"+name, g.Context, tcpKubeconfigs,
+			targetNeeds(g.Namespace, g.Service, g.Method)))
+	}
+	for port, j := range cfg.TCP.K8s.Jump {
+		needs := targetNeeds(j.Namespace, j.Via.Service, config.TCPMethodExec)
+		if j.Via.Create != nil {
+			needs = append(needs, need("create", "pods", "", j.Namespace))
+		}
+		routes = append(routes, route(fmt.Sprintf("jump:%d", port), j.Context, tcpKubeconfigs, needs))
+	}
+	for _, d := range cfg.TCP.K8s.Discover {
+		routes = append(routes, route("discover:"+d.Context, d.Context, tcpKubeconfigs, []Need{
+			need("list", "services", "", d.Namespace),
+			need("watch", "services", "", d.Namespace),
+		}))
+	}
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes
+}
+
+func route(name, contextName string, kubeconfigs []string, needs []Need) Route {
+	seen := make(map[string]bool, len(needs))
+	unique := needs[:0]
+	for _, n := range needs {
+		if !seen[n.String()] {
+			seen[n.String()] = true
+			unique = append(unique, n)
+		}
+	}
+	return Route{Name: name, Context: contextName, Kubeconfigs: kubeconfigs, Needs: unique}
+}
+
+func need(verb, resource, subresource, namespace string) Need {
+	return Need{Permission: Permission{Verb: verb, Resource: resource, Subresource: subresource, Namespace: namespace}}
+}
+
+// targetNeeds is what reaching a service or pod with method takes: finding the pod
+// (service get and pod list, or pod get), then port-forward or exec into it
+func targetNeeds(namespace, service, method string) []Need {
+	var needs []Need
+	if service != "" {
+		needs = append(needs, need("get", "services", "", namespace), need("list", "pods", "", namespace))
+	} else {
+		needs = append(needs, need("get", "pods", "", namespace))
+	}
+
+	portForward := need("create", "pods", "portforward", namespace)
+	exec := need("create", "pods", "exec", namespace)
+	switch method {
+	case config.TCPMethodExec:
+		needs = append(needs, exec)
+	case config.TCPMethodPortForward:
+		needs = append(needs, portForward)
+	default:
+		portForward.Alternative = &exec.Permission
+		needs = append(needs, portForward)
+	}
+	return needs
+}
+
+// Checker asks one cluster about permissions, each only once
+type Checker struct {
+	clientset kubernetes.Interface
+	allowed   map[Permission]bool
+}
+
+func NewChecker(clientset kubernetes.Interface) *Checker {
+	return &Checker{clientset: clientset, allowed: make(map[Permission]bool)}
+}
+
+// Missing returns the needs of r the user doesn't have
+func (c *Checker) Missing(ctx context.Context, r Route) ([]Need, error) {
+	var missing []Need
+	for _, n := range r.Needs {
+		ok, err := c.can(ctx, n.Permission)
+		if err != nil {
+			return nil, err
+		}
+		if !ok && n.Alternative != nil {
+			if ok, err = c.can(ctx, *n.Alternative); err != nil {
+				return nil, err
+			}
+		}
+		if !ok {
+			missing = append(missing, n)
+		}
+	}
+	return missing, nil
+}
+
+func (c *Checker) can(ctx context.Context, p Permission) (bool, error) {
+	if allowed, ok := c.allowed[p]; ok {
+		return allowed, nil
+	}
+	review, err := c.clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   p.Namespace,
+				Verb:        p.Verb,
+				Resource:    p.Resource,
+				Subresource: p.Subresource,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("access review for %s failed: %w", p, err)
+	}
+	c.allowed[p] = review.Status.Allowed
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,112 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeCluster allows everything except the listed permissions and counts reviews
+func fakeCluster(denied ...Permission) (*fake.Clientset, *int) {
+	reviews := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		asked := Permission{Verb: attrs.Verb, Resource: attrs.Resource, Subresource: attrs.Subresource, Namespace: attrs.Namespace}
+		review.Status.Allowed = true
+		for _, p := range denied {
+			if p == asked {
+				review.Status.Allowed = false
+			}
+		}
+		return true, review, nil
+	})
+	return client, &reviews
+}
+
+func TestRoutes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.HTTP.K8s.Routes = map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "prod", Namespace: "apps", Service: "api", Port: 80},
+	}
+	cfg.TCP.K8s.Routes = map[int]config.TCPRouteConfig{
+		5432: {Context: "prod", Namespace: "db", Pod: "pg-0", Port: 5432},
+	}
+	cfg.TCP.K8s.Jump = map[int]config.JumpRouteConfig{
+		3306: {Context: "prod", Namespace: "tools", Via: config.ViaConfig{Pod: "jump", Create: &config.CreateConfig{Image: "alpine"}}},
+	}
+
+	routes := Routes(cfg)
+	want := map[string][]string{
+		"api.localhost": {"get services in namespace apps", "list pods in namespace apps", "create pods/portforward in namespace apps"},
+		"jump:3306":     {"get pods in namespace tools", "create pods/exec in namespace tools", "create pods in namespace tools"},
+		"tcp:5432":      {"get pods in namespace db", "create pods/portforward in namespace db (or create pods/exec in namespace db)"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("got %d routes, want %d: %+v", len(routes), len(want), routes)
+	}
+	for _, r := range routes {
+		needs := want[r.Name]
+		if len(r.Needs) != len(needs) {
+			t.Errorf("%s: needs = %v, want %v", r.Name, r.Needs, needs)
+			continue
+		}
+		for i, n := range r.Needs {
+			if n.String() != needs[i] {
+				t.Errorf("%s: need %d = %q, want %q", r.Name, i, n, needs[i])
+			}
+		}
+	}
+}
+
+func TestChecker_Missing(t *testing.T) {
+	portForward := Permission{Verb: "create", Resource: "pods", Subresource: "portforward", Namespace: "db"}
+	exec := Permission{Verb: "create", Resource: "pods", Subresource: "exec", Namespace: "db"}
+	route := Route{Name: "tcp:5432", Needs: []Need{
+		{Permission: Permission{Verb: "get", Resource: "pods", Namespace: "db"}},
+		{Permission: portForward, Alternative: &exec},
+	}}
+
+	tests := []struct {
+		name    string
+		denied  []Permission
+		missing int
+	}{
+		{"all allowed", nil, 0},
+		{"exec fallback covers port-forward", []Permission{portForward}, 0},
+		{"neither", []Permission{portForward, exec}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := fakeCluster(tt.denied...)
+			missing, err := NewChecker(client).Missing(context.Background(), route)
+			if err != nil {
+				t.Fatalf("Missing: %v", err)
+			}
+			if len(missing) != tt.missing {
+				t.Errorf("missing = %v, want %d", missing, tt.missing)
+			}
+		})
+	}
+}
+
+func TestChecker_AsksOncePerPermission(t *testing.T) {
+	client, reviews := fakeCluster()
+	checker := NewChecker(client)
+	route := Route{Needs: []Need{{Permission: Permission{Verb: "get", Resource: "pods", Namespace: "db"}}}}
+	for range 3 {
+		if _, err := checker.Missing(context.Background(), route); err != nil {
+			t.Fatalf("Missing: %v", err)
+		}
+	}
+	if *reviews != 1 {
+		t.Errorf("sent %d reviews, want 1", *reviews)
+	}
+}
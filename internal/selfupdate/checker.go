@@ -0,0 +1,106 @@
+package selfupdate
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCheckInterval is how often a running autotunnel looks for a new release
+const DefaultCheckInterval = 24 * time.Hour
+
+// Status is the outcome of the latest check, for the status API
+type Status struct {
+	Current   string    `json:"current"`
+	Latest    string    `json:"latest,omitempty"`
+	Available bool      `json:"update_available"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Checker looks for a newer release in the background and logs when one appears.
+// It never installs anything; that is left to `autotunnel self-update`.
+type Checker struct {
+	current  string
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	mu     sync.RWMutex
+	status Status
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewChecker(current, url string, interval time.Duration) *Checker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Checker{
+		current:  current,
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		status:   Status{Current: current},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (c *Checker) Start() {
+	c.wg.Add(1)
+	go c.loop()
+}
+
+func (c *Checker) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+func (c *Checker) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.check()
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check asks for the latest release, logging a newly available version once
+func (c *Checker) check() {
+	rel, err := Latest(c.ctx, c.client, c.url)
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status.CheckedAt = time.Now()
+	if err != nil {
+		c.status.Error = err.Error()
+		return
+	}
+	c.status.Error = ""
+	if rel.Version != c.status.Latest && IsNewer(c.current, rel.Version) {
+		log.Printf("[update] autotunnel %s is available (running %s), run `autotunnel self-update` to install it", rel.Version, c.current)
+	}
+	c.status.Latest = rel.Version
+	c.status.Available = IsNewer(c.current, rel.Version)
+}
+
+// Status returns the outcome of the latest check
+func (c *Checker) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
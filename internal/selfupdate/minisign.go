@@ -0,0 +1,79 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// SignatureFile is the minisign signature of ChecksumsFile published with each release
+const SignatureFile = ChecksumsFile + ".minisig"
+
+// minisignKey is a parsed minisign public key
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// parseMinisignKey reads a minisign public key: the base64 line of a .pub file,
+// with or without its "untrusted comment:" line
+func parseMinisignKey(s string) (*minisignKey, error) {
+	var line string
+	for _, l := range strings.Split(strings.TrimSpace(s), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "untrusted comment:") {
+			line = l
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, errors.New("invalid minisign public key")
+	}
+	k := &minisignKey{key: ed25519.PublicKey(raw[10:])}
+	copy(k.id[:], raw[2:10])
+	return k, nil
+}
+
+// verify checks a minisign signature file over data: the signature of data
+// itself (or of its BLAKE2b-512 hash for prehashed signatures) and the global
+// signature over the trusted comment
+func (k *minisignKey) verify(data, sigFile []byte) error {
+	lines := strings.Split(strings.TrimRight(string(sigFile), "\r\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") {
+		return errors.New("malformed minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+	if !bytes.Equal(sig[2:10], k.id[:]) {
+		return fmt.Errorf("signed with key %X, not the release key %X", sig[2:10], k.id)
+	}
+
+	msg := data
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(k.key, msg, sig[10:]) {
+		return errors.New("invalid signature")
+	}
+
+	trusted, ok := strings.CutPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+	if !ok {
+		return errors.New("malformed minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || !ed25519.Verify(k.key, append(bytes.Clone(sig[10:]), trusted...), global) {
+		return errors.New("invalid signature of the trusted comment")
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package selfupdate finds newer autotunnel releases on GitHub and replaces the
+// running binary with one, after checking it against the release's checksums.txt
+// and that file's minisign signature.
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LatestReleaseURL is the GitHub API endpoint describing the newest release
+const LatestReleaseURL = "https://api.github.com/repos/atas/autotunnel/releases/latest"
+
+// ChecksumsFile is the release asset listing the sha256 of every archive
+const ChecksumsFile = "checksums.txt"
+
+// maxBinarySize bounds what is read out of a release archive
+const maxBinarySize = 256 << 20
+
+// Release is a published version and its downloadable files
+type Release struct {
+	Version string            // without the leading "v"
+	Assets  map[string]string // file name -> download URL
+}
+
+// Latest asks url (normally LatestReleaseURL) for the newest release
+func Latest(ctx context.Context, client *http.Client, url string) (*Release, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var payload struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if payload.TagName == "" {
+		return nil, errors.New("release has no tag")
+	}
+
+	rel := &Release{Version: strings.TrimPrefix(payload.TagName, "v"), Assets: make(map[string]string, len(payload.Assets))}
+	for _, a := range payload.Assets {
+		rel.Assets[a.Name] = a.URL
+	}
+	return rel, nil
+}
+
+// IsNewer reports whether latest is a higher version than current. Development
+// builds ("dev" or anything unparsable) are never considered out of date.
+func IsNewer(current, latest string) bool {
+	cur, ok1 := parseVersion(current)
+	lat, ok2 := parseVersion(latest)
+	if !ok1 || !ok2 {
+		return false
+	}
+	for i := range cur {
+		if lat[i] != cur[i] {
+			return lat[i] > cur[i]
+		}
+	}
+	return false
+}
+
+// parseVersion reads "1.2.3" (with optional "v" and pre-release suffix)
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// ArchiveName is the release archive for a platform, as named by .goreleaser.yaml
+func ArchiveName(version, goos, goarch string) string {
+	return fmt.Sprintf("autotunnel_%s_%s_%s.tar.gz", version, goos, goarch)
+}
+
+// ManagedBy names the package manager that installed exePath, if any. Those
+// installs should be updated with the package manager instead.
+func ManagedBy(exePath string) string {
+	if strings.Contains(exePath, "/Cellar/") || strings.Contains(exePath, "/homebrew/") || strings.Contains(exePath, "/linuxbrew/") {
+		return "homebrew"
+	}
+	return ""
+}
+
+// Install downloads rel's archive for goos/goarch, checks the release's
+// checksums.txt against its signature by publicKey (a minisign public key) and
+// the archive's sha256 against checksums.txt, and replaces exePath with the
+// binary inside
+func Install(ctx context.Context, client *http.Client, rel *Release, goos, goarch, exePath, publicKey string) error {
+	if publicKey == "" {
+		return errors.New("this build has no release signing key (a dev build?), refusing to install an unverified binary")
+	}
+	key, err := parseMinisignKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	archive := ArchiveName(rel.Version, goos, goarch)
+	archiveURL, ok := rel.Assets[archive]
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Version, archive)
+	}
+	checksumsURL, ok := rel.Assets[ChecksumsFile]
+	if !ok {
+		return fmt.Errorf("release %s has no %s, refusing to install an unverified binary", rel.Version, ChecksumsFile)
+	}
+	signatureURL, ok := rel.Assets[SignatureFile]
+	if !ok {
+		return fmt.Errorf("release %s has no %s, refusing to install an unverified binary", rel.Version, SignatureFile)
+	}
+
+	checksums, err := download(ctx, client, checksumsURL)
+	if err != nil {
+		return err
+	}
+	signature, err := download(ctx, client, signatureURL)
+	if err != nil {
+		return err
+	}
+	if err := key.verify(checksums, signature); err != nil {
+		return fmt.Errorf("%s of release %s: %w", SignatureFile, rel.Version, err)
+	}
+	want, err := expectedChecksum(checksums, archive)
+	if err != nil {
+		return err
+	}
+
+	data, err := download(ctx, client, archiveURL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", archive, got, want)
+	}
+
+	binary, err := extractBinary(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", archive, err)
+	}
+	return replaceFile(exePath, binary)
+}
+
+// expectedChecksum finds name's sha256 in the checksums file
+func expectedChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s does not list %s", ChecksumsFile, name)
+}
+
+// extractBinary returns the autotunnel executable from a tar.gz archive
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no autotunnel binary in archive")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "autotunnel" {
+			return io.ReadAll(io.LimitReader(tr, maxBinarySize))
+		}
+	}
+}
+
+// replaceFile swaps path's contents for data by renaming a file written next to
+// it, keeping the old file's permissions
+func replaceFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".autotunnel-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s (run with the permissions that installed it): %w", path, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	body, err := get(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, maxBinarySize))
+}
+
+func get(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "autotunnel-self-update")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
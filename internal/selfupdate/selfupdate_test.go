@@ -0,0 +1,251 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"1.2.3", "1.2.4", true},
+		{"1.2.3", "v1.10.0", true},
+		{"1.2.3", "1.2.3", false},
+		{"2.0.0", "1.9.9", false},
+		{"1.2.3-rc1", "1.2.3", false},
+		{"dev", "9.9.9", false},
+	}
+	for _, tt := range tests {
+		if got := IsNewer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestManagedBy(t *testing.T) {
+	if got := ManagedBy("/opt/homebrew/Cellar/autotunnel/1.2.3/bin/autotunnel"); got != "homebrew" {
+		t.Errorf("ManagedBy(brew path) = %q, want homebrew", got)
+	}
+	if got := ManagedBy("/usr/local/bin/autotunnel"); got != "" {
+		t.Errorf("ManagedBy(/usr/local/bin) = %q, want empty", got)
+	}
+}
+
+func testArchive(t *testing.T, binary []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range map[string][]byte{"README.md": []byte("readme"), "autotunnel": binary} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write(data)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// testSigner is a minisign key pair for signing fake releases
+type testSigner struct {
+	publicKey string // as in a minisign .pub file
+	id        [8]byte
+	private   ed25519.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSigner{private: priv}
+	_, _ = rand.Read(s.id[:])
+	raw := append(append([]byte("Ed"), s.id[:]...), pub...)
+	s.publicKey = "untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(raw) + "\n"
+	return s
+}
+
+// sign returns a prehashed minisign signature file for data, as `minisign -S` writes
+func (s *testSigner) sign(data []byte) []byte {
+	hash := blake2b.Sum512(data)
+	sig := ed25519.Sign(s.private, hash[:])
+	trusted := "timestamp:1700000000\tfile:checksums.txt"
+	global := ed25519.Sign(s.private, append(append([]byte{}, sig...), trusted...))
+	line := append(append([]byte("ED"), s.id[:]...), sig...)
+	return []byte("untrusted comment: signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(line) + "\n" +
+		"trusted comment: " + trusted + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+// releaseServer serves a fake GitHub release of version with one archive, its
+// checksums.txt and, unless signer is nil, that file's signature
+func releaseServer(t *testing.T, version string, archive []byte, checksum string, signer *testSigner) *httptest.Server {
+	t.Helper()
+	name := ArchiveName(version, "linux", "amd64")
+	checksums := []byte(fmt.Sprintf("%s  autotunnel_%s_darwin_arm64.tar.gz\n%s  %s\n", strings.Repeat("0", 64), version, checksum, name))
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		signature := ""
+		if signer != nil {
+			signature = fmt.Sprintf(`,{"name":"checksums.txt.minisig","browser_download_url":"%s/signature"}`, srv.URL)
+		}
+		fmt.Fprintf(w, `{"tag_name":"v%s","assets":[{"name":%q,"browser_download_url":"%s/archive"},{"name":"checksums.txt","browser_download_url":"%s/checksums"}%s]}`,
+			version, name, srv.URL, srv.URL, signature)
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(archive) })
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(checksums) })
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(signer.sign(checksums)) })
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// installLatest runs Install for the release srv serves over an "old binary"
+// and returns what the binary holds afterwards and the error
+func installLatest(t *testing.T, srv *httptest.Server, publicKey string) (string, error) {
+	t.Helper()
+	rel, err := Latest(context.Background(), srv.Client(), srv.URL+"/latest")
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	exe := filepath.Join(t.TempDir(), "autotunnel")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err = Install(context.Background(), srv.Client(), rel, "linux", "amd64", exe, publicKey)
+	data, _ := os.ReadFile(exe)
+	return string(data), err
+}
+
+func TestInstall(t *testing.T) {
+	archive := testArchive(t, []byte("new binary"))
+	sum := sha256.Sum256(archive)
+	signer := newTestSigner(t)
+	srv := releaseServer(t, "1.3.0", archive, hex.EncodeToString(sum[:]), signer)
+
+	rel, err := Latest(context.Background(), srv.Client(), srv.URL+"/latest")
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if rel.Version != "1.3.0" {
+		t.Fatalf("Version = %q, want 1.3.0", rel.Version)
+	}
+
+	exe := filepath.Join(t.TempDir(), "autotunnel")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(context.Background(), srv.Client(), rel, "linux", "amd64", exe, signer.publicKey); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new binary" {
+		t.Errorf("binary = %q, want the new one", data)
+	}
+	if info, _ := os.Stat(exe); info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+}
+
+func TestInstall_ChecksumMismatch(t *testing.T) {
+	archive := testArchive(t, []byte("tampered"))
+	signer := newTestSigner(t)
+	srv := releaseServer(t, "1.3.0", archive, strings.Repeat("ab", 32), signer)
+
+	binary, err := installLatest(t, srv, signer.publicKey)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+	if binary != "old binary" {
+		t.Errorf("binary was replaced despite the mismatch: %q", binary)
+	}
+}
+
+func TestInstall_Signature(t *testing.T) {
+	archive := testArchive(t, []byte("new binary"))
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+	signer := newTestSigner(t)
+
+	tests := []struct {
+		name      string
+		signer    *testSigner // signs the release, nil for no signature
+		publicKey string      // embedded in the running binary
+		wantErr   string
+	}{
+		{"missing signature", nil, signer.publicKey, "has no checksums.txt.minisig"},
+		{"other key", newTestSigner(t), signer.publicKey, "not the release key"},
+		{"no embedded key", signer, "", "no release signing key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := releaseServer(t, "1.3.0", archive, checksum, tt.signer)
+			binary, err := installLatest(t, srv, tt.publicKey)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+			if binary != "old binary" {
+				t.Errorf("binary was replaced: %q", binary)
+			}
+		})
+	}
+}
+
+func TestMinisignVerify_Tampered(t *testing.T) {
+	signer := newTestSigner(t)
+	key, err := parseMinisignKey(signer.publicKey)
+	if err != nil {
+		t.Fatalf("parseMinisignKey failed: %v", err)
+	}
+	data := []byte("abc  autotunnel_1.3.0_linux_amd64.tar.gz\n")
+	sig := signer.sign(data)
+	if err := key.verify(data, sig); err != nil {
+		t.Fatalf("verify failed on a good signature: %v", err)
+	}
+	if err := key.verify([]byte("def  autotunnel_1.3.0_linux_amd64.tar.gz\n"), sig); err == nil {
+		t.Error("verify accepted a signature over other data")
+	}
+	tampered := strings.Replace(string(sig), "timestamp:1700000000", "timestamp:1800000000", 1)
+	if err := key.verify(data, []byte(tampered)); err == nil {
+		t.Error("verify accepted a changed trusted comment")
+	}
+}
+
+func TestChecker(t *testing.T) {
+	srv := releaseServer(t, "1.3.0", nil, "", nil)
+	c := NewChecker("1.2.0", srv.URL+"/latest", time.Hour)
+	c.Start()
+	defer c.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for c.Status().CheckedAt.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	st := c.Status()
+	if !st.Available || st.Latest != "1.3.0" || st.Current != "1.2.0" || st.Error != "" {
+		t.Errorf("unexpected status %+v", st)
+	}
+}
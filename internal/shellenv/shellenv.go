@@ -0,0 +1,197 @@
+// Package shellenv derives environment variables (PGHOST, DATABASE_URL, service URLs, ...)
+// from configured routes for `autotunnel env`.
+package shellenv
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// Var is a single environment variable
+type Var struct {
+	Name  string
+	Value string
+}
+
+// wellKnown describes the conventional client variables for a remote port.
+// Empty names are not emitted.
+type wellKnown struct {
+	scheme  string
+	hostVar string
+	portVar string
+	urlVar  string
+}
+
+var wellKnownPorts = map[int]wellKnown{
+	5432:  {scheme: "postgres", hostVar: "PGHOST", portVar: "PGPORT", urlVar: "DATABASE_URL"},
+	3306:  {scheme: "mysql", hostVar: "MYSQL_HOST", portVar: "MYSQL_TCP_PORT", urlVar: "DATABASE_URL"},
+	6379:  {scheme: "redis", urlVar: "REDIS_URL"},
+	27017: {scheme: "mongodb", urlVar: "MONGODB_URI"},
+	5672:  {scheme: "amqp", urlVar: "AMQP_URL"},
+}
+
+// tcpEndpoint is a local TCP port and what it forwards to
+type tcpEndpoint struct {
+	localPort  int
+	name       string
+	remotePort int
+}
+
+// Build returns variables for every HTTP, TCP and jump route in cfg, running or not.
+// remaps (configured port -> actual port) and tunnelPorts (HTTP route -> local
+// port of its running tunnel) come from a running daemon and may be nil.
+//
+// Each route gets <NAME>_URL (HTTP) or <NAME>_HOST/<NAME>_PORT (TCP), plus <NAME>_URL
+// when the remote port is a well-known protocol. HTTP routes whose tunnel port is
+// known (running, or a fixed local_port) also get <NAME>_TUNNEL_PORT/_TUNNEL_URL
+// for tools that skip the proxy. The conventional client variables (PGHOST,
+// DATABASE_URL, ...) are set from the lowest local port that matches.
+func Build(cfg *config.Config, remaps map[int]int, tunnelPorts map[string]int) []Var {
+	b := &builder{seen: make(map[string]bool)}
+
+	var endpoints []tcpEndpoint
+	for localPort, route := range cfg.TCP.K8s.Routes {
+		endpoints = append(endpoints, tcpEndpoint{localPort: localPort, name: route.TargetName(), remotePort: route.Port})
+		for extraPort, targetPort := range route.ExtraPorts {
+			endpoints = append(endpoints, tcpEndpoint{localPort: extraPort, name: route.TargetName(), remotePort: targetPort})
+		}
+	}
+	for localPort, route := range cfg.TCP.K8s.Jump {
+		name, _, _ := strings.Cut(route.Target.Host, ".")
+		endpoints = append(endpoints, tcpEndpoint{localPort: localPort, name: name, remotePort: route.Target.Port})
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].localPort < endpoints[j].localPort })
+
+	tcpHost := cfg.TCP.GetListenHost() // where tcpserver binds route ports
+	for _, ep := range endpoints {
+		port := ep.localPort
+		if actual, ok := remaps[port]; ok {
+			port = actual
+		}
+		addr := net.JoinHostPort(tcpHost, strconv.Itoa(port))
+		prefix := b.prefix(ep.name, fmt.Sprintf("%s_%d", ep.name, ep.localPort), "_HOST", "_PORT", "_URL")
+
+		b.add(prefix+"_HOST", tcpHost)
+		b.add(prefix+"_PORT", strconv.Itoa(port))
+
+		known, ok := wellKnownPorts[ep.remotePort]
+		if !ok {
+			continue
+		}
+		url := fmt.Sprintf("%s://%s", known.scheme, addr)
+		b.add(prefix+"_URL", url)
+		b.addOnce(known.hostVar, tcpHost)
+		b.addOnce(known.portVar, strconv.Itoa(port))
+		b.addOnce(known.urlVar, url)
+	}
+
+	hostnames := make([]string, 0, len(cfg.HTTP.K8s.Routes))
+	for hostname := range cfg.HTTP.K8s.Routes {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	httpPort := "80"
+	if _, port, err := net.SplitHostPort(cfg.HTTP.ListenAddr); err == nil {
+		httpPort = port
+	}
+	for _, hostname := range hostnames {
+		route := cfg.HTTP.K8s.Routes[hostname]
+		name, _, _ := strings.Cut(hostname, ".")
+		prefix := b.prefix(name, hostname, "_URL", "_TUNNEL_PORT", "_TUNNEL_URL")
+
+		url := "http://" + hostname
+		if httpPort != "80" {
+			url += ":" + httpPort
+		}
+		b.add(prefix+"_URL", url)
+
+		tunnelPort, ok := tunnelPorts[hostname]
+		if !ok || tunnelPort == 0 {
+			tunnelPort = route.LocalPort
+		}
+		if tunnelPort > 0 {
+			scheme := route.Scheme
+			if scheme == "" {
+				scheme = "http"
+			}
+			b.add(prefix+"_TUNNEL_PORT", strconv.Itoa(tunnelPort))
+			b.add(prefix+"_TUNNEL_URL", fmt.Sprintf("%s://127.0.0.1:%d", scheme, tunnelPort))
+		}
+	}
+
+	return b.vars
+}
+
+type builder struct {
+	vars []Var
+	seen map[string]bool
+}
+
+func (b *builder) add(name, value string) {
+	b.vars = append(b.vars, Var{Name: name, Value: value})
+	b.seen[name] = true
+}
+
+// addOnce adds name unless an earlier route already set it
+func (b *builder) addOnce(name, value string) {
+	if name == "" || b.seen[name] {
+		return
+	}
+	b.add(name, value)
+}
+
+// prefix turns a route name into a variable prefix ("my-db" -> "MY_DB"). When one
+// of the resulting variables is already taken, fallback is used instead (the
+// name with the local port, or the full hostname), then fallback with a counter.
+func (b *builder) prefix(name, fallback string, suffixes ...string) string {
+	if prefix := VarName(name); !b.taken(prefix, suffixes) {
+		return prefix
+	}
+	prefix := VarName(fallback)
+	for n := 2; b.taken(prefix, suffixes); n++ {
+		prefix = fmt.Sprintf("%s_%d", VarName(fallback), n)
+	}
+	return prefix
+}
+
+// taken reports whether prefix with any of suffixes is already set
+func (b *builder) taken(prefix string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if b.seen[prefix+suffix] {
+			return true
+		}
+	}
+	return false
+}
+
+// VarName converts s into a valid upper-case shell variable name
+func VarName(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	name := sb.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// Export formats vars as POSIX shell export lines (eval/direnv friendly)
+func Export(vars []Var) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&sb, "export %s='%s'\n", v.Name, strings.ReplaceAll(v.Value, "'", `'\''`))
+	}
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+package shellenv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestBuild(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{
+			ListenAddr: "127.0.0.1:8989",
+			K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+				"grafana.localhost": {Service: "grafana", Port: 3000},
+				"api.localhost":     {Service: "api", Port: 8443, Scheme: "https"},
+				"docs.localhost":    {Service: "docs", Port: 80, LocalPort: 18080},
+			}},
+		},
+		TCP: config.TCPConfig{K8s: config.TCPK8sConfig{
+			Routes: map[int]config.TCPRouteConfig{
+				15432: {Service: "postgres", Port: 5432},
+				16379: {Pod: "redis-0", Port: 6379},
+				15433: {Service: "postgres", Port: 5432},
+			},
+			Jump: map[int]config.JumpRouteConfig{
+				13306: {Target: config.TargetConfig{Host: "orders.cluster-xyz.rds.amazonaws.com", Port: 3306}},
+			},
+		}},
+	}
+
+	got := Build(cfg, map[int]int{15433: 15434}, map[string]int{"api.localhost": 41234})
+	want := []Var{
+		{"ORDERS_HOST", "127.0.0.1"},
+		{"ORDERS_PORT", "13306"},
+		{"ORDERS_URL", "mysql://127.0.0.1:13306"},
+		{"MYSQL_HOST", "127.0.0.1"},
+		{"MYSQL_TCP_PORT", "13306"},
+		{"DATABASE_URL", "mysql://127.0.0.1:13306"},
+		{"POSTGRES_HOST", "127.0.0.1"},
+		{"POSTGRES_PORT", "15432"},
+		{"POSTGRES_URL", "postgres://127.0.0.1:15432"},
+		{"PGHOST", "127.0.0.1"},
+		{"PGPORT", "15432"},
+		{"POSTGRES_15433_HOST", "127.0.0.1"},
+		{"POSTGRES_15433_PORT", "15434"},
+		{"POSTGRES_15433_URL", "postgres://127.0.0.1:15434"},
+		{"REDIS_0_HOST", "127.0.0.1"},
+		{"REDIS_0_PORT", "16379"},
+		{"REDIS_0_URL", "redis://127.0.0.1:16379"},
+		{"REDIS_URL", "redis://127.0.0.1:16379"},
+		{"API_URL", "http://api.localhost:8989"},
+		{"API_TUNNEL_PORT", "41234"},
+		{"API_TUNNEL_URL", "https://127.0.0.1:41234"},
+		{"DOCS_URL", "http://docs.localhost:8989"},
+		{"DOCS_TUNNEL_PORT", "18080"},
+		{"DOCS_TUNNEL_URL", "http://127.0.0.1:18080"},
+		{"GRAFANA_URL", "http://grafana.localhost:8989"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build mismatch\n got: %v\nwant: %v", got, want)
+	}
+}
+
+func TestVarName(t *testing.T) {
+	tests := map[string]string{
+		"postgres":   "POSTGRES",
+		"my-db.prod": "MY_DB_PROD",
+		"1password":  "_1PASSWORD",
+		"":           "_",
+	}
+	for in, want := range tests {
+		if got := VarName(in); got != want {
+			t.Errorf("VarName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	got := Export([]Var{{"A", "x"}, {"B", "it's"}})
+	want := "export A='x'\nexport B='it'\\''s'\n"
+	if got != want {
+		t.Errorf("Export = %q, want %q", got, want)
+	}
+}
+
+func TestBuild_IPv6ListenHost(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{ListenAddr: "[::1]:8989"},
+		TCP: config.TCPConfig{ListenHost: "::1", K8s: config.TCPK8sConfig{
+			Routes: map[int]config.TCPRouteConfig{15432: {Service: "postgres", Port: 5432}},
+		}},
+	}
+
+	vars := make(map[string]string)
+	for _, v := range Build(cfg, nil, nil) {
+		vars[v.Name] = v.Value
+	}
+	if vars["PGHOST"] != "::1" {
+		t.Errorf("PGHOST = %q, want ::1", vars["PGHOST"])
+	}
+	if vars["POSTGRES_URL"] != "postgres://[::1]:15432" {
+		t.Errorf("POSTGRES_URL = %q, want brackets around the IPv6 host", vars["POSTGRES_URL"])
+	}
+}
+
+func TestBuild_SharedFirstLabel(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{
+			ListenAddr: "127.0.0.1:8989",
+			K8s: config.K8sConfig{Routes: map[string]config.K8sRouteConfig{
+				"api.dev.localhost":     {Service: "api", Port: 80},
+				"api.staging.localhost": {Service: "api", Port: 80},
+				"api.prod.localhost":    {Service: "api", Port: 80},
+			}},
+		},
+	}
+
+	got := Build(cfg, nil, nil)
+	want := []Var{
+		{"API_URL", "http://api.dev.localhost:8989"},
+		{"API_PROD_LOCALHOST_URL", "http://api.prod.localhost:8989"},
+		{"API_STAGING_LOCALHOST_URL", "http://api.staging.localhost:8989"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build mismatch\n got: %v\nwant: %v", got, want)
+	}
+}
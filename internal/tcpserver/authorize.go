@@ -0,0 +1,46 @@
+package tcpserver
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/atas/autotunnel/internal/authz"
+)
+
+// SetAuthorizer checks every accepted connection against p. Must be called before Start.
+func (s *Server) SetAuthorizer(p *authz.Policy) {
+	s.authz = p
+}
+
+// authorize runs the authorizers of the route owning pl. extra_ports use their
+// route's authorizers; groups and postgres routes use the top-level ones.
+func (s *Server) authorize(pl *portListener, conn net.Conn) bool {
+	if s.authz == nil {
+		return true
+	}
+
+	route := authz.TCPRoute(pl.port)
+	switch pl.listenerType {
+	case listenerTypeRoute:
+		routePort, _ := s.routeTarget(pl.port)
+		route = authz.TCPRoute(routePort)
+	case listenerTypeGroup:
+		route = "group:" + pl.group
+	}
+
+	user, err := s.authz.Check(context.Background(), authz.Request{
+		Route:      route,
+		Kind:       authz.KindTCP,
+		LocalAddr:  conn.LocalAddr(),
+		RemoteAddr: conn.RemoteAddr(),
+	})
+	if err != nil {
+		log.Printf("[%s] Rejected %s: %v", route, conn.RemoteAddr(), err)
+		return false
+	}
+	if s.verbose && user != "" {
+		log.Printf("[%s] user %s", route, user)
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package tcpserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/authz"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_Authorize(t *testing.T) {
+	deny := []config.AuthorizerConfig{{Type: config.AuthorizerCommand, Command: []string{"false"}}}
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19740: {Context: "test", Namespace: "ns", Service: "svc", Port: 80, ExtraPorts: map[int]int{19741: 81}, Authorizers: deny},
+		19742: {Context: "test", Namespace: "ns", Service: "other", Port: 80},
+	})
+	cfg.Authorizers = []config.AuthorizerConfig{{Type: config.AuthorizerCommand, Command: []string{"true"}}}
+	policy, err := authz.NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	mgr := &mockManager{tunnelToReturn: &sharedMockTunnel{}}
+	s := NewServer(cfg, mgr)
+	s.SetAuthorizer(policy)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	tests := []struct {
+		pl   *portListener
+		want bool
+	}{
+		{&portListener{port: 19740, listenerType: listenerTypeRoute}, false},
+		{&portListener{port: 19741, listenerType: listenerTypeRoute}, false}, // extra_ports follow their route
+		{&portListener{port: 19742, listenerType: listenerTypeRoute}, true},  // top-level authorizers
+		{&portListener{port: 19750, listenerType: listenerTypeGroup, group: "api"}, true},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		if got := s.authorize(tt.pl, server); got != tt.want {
+			t.Errorf("authorize(:%d) = %v, want %v", tt.pl.port, got, tt.want)
+		}
+		client.Close()
+		server.Close()
+	}
+
+	// a rejected connection is closed before any tunnel is looked up
+	conn, err := net.Dial("tcp", "127.0.0.1:19740")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected the connection to be closed")
+	}
+	if len(mgr.getTCPTunnelCalls) != 0 {
+		t.Errorf("Expected no tunnel lookup, got %v", mgr.getTCPTunnelCalls)
+	}
+}
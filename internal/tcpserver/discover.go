@@ -0,0 +1,254 @@
+package tcpserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// discoverResync is how often the informer replays every Service, retrying the
+// ones whose local port was taken when they were first seen
+var discoverResync = 5 * time.Minute
+
+// startDiscovery watches Services for each tcp.k8s.discover entry in the background
+func (s *Server) startDiscovery() {
+	for _, d := range s.config.TCP.K8s.Discover {
+		s.wg.Add(1)
+		go s.discover(d)
+	}
+}
+
+// discover keeps a TCP route for every labeled Service in d's context until shutdown
+func (s *Server) discover(d config.TCPDiscoverConfig) {
+	defer s.wg.Done()
+
+	for {
+		clientset, _, err := s.manager.GetClientForContext(s.config.TCP.K8s.ResolvedKubeconfigs, d.Context)
+		if err == nil {
+			s.watchServices(d, clientset)
+			return
+		}
+		log.Printf("[discover:%s] Failed to get K8s client (retrying in %v): %v", d.Context, groupRetryInterval, err)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(groupRetryInterval):
+		}
+	}
+}
+
+// watchServices runs an informer on the labeled Services until shutdown. It
+// reconnects on its own when the API server goes away.
+func (s *Server) watchServices(d config.TCPDiscoverConfig, clientset kubernetes.Interface) {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, discoverResync,
+		informers.WithNamespace(d.Namespace), // "" is every namespace
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = config.DiscoverPortLabel
+		}))
+	informer := factory.Core().V1().Services().Informer()
+	_, _ = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				s.addDiscovered(d, svc)
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				s.addDiscovered(d, svc)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+				s.removeDiscovered(d.Context + "/" + key)
+			}
+		},
+	})
+
+	log.Printf("[discover:%s] Watching Services labeled %s in %s", d.Context, config.DiscoverPortLabel, namespaceDisplay(d.Namespace))
+	factory.Start(s.ctx.Done())
+	<-s.ctx.Done()
+	factory.Shutdown()
+}
+
+// discoveredRoute returns the local port and TCP route for a labeled Service
+func discoveredRoute(d config.TCPDiscoverConfig, svc *corev1.Service) (int, config.TCPRouteConfig, error) {
+	port, err := strconv.Atoi(svc.Labels[config.DiscoverPortLabel])
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, config.TCPRouteConfig{}, fmt.Errorf("label %s=%q is not a port", config.DiscoverPortLabel, svc.Labels[config.DiscoverPortLabel])
+	}
+	localPort := port
+	if value, ok := svc.Annotations[config.DiscoverLocalPortAnnotation]; ok {
+		localPort, err = strconv.Atoi(value)
+		if err != nil || localPort <= 0 || localPort > 65535 {
+			return 0, config.TCPRouteConfig{}, fmt.Errorf("annotation %s=%q is not a port", config.DiscoverLocalPortAnnotation, value)
+		}
+	}
+	return localPort, config.TCPRouteConfig{
+		Context:   d.Context,
+		Namespace: svc.Namespace,
+		Service:   svc.Name,
+		Port:      port,
+		Method:    d.Method,
+	}, nil
+}
+
+// addDiscovered starts or updates the route of a labeled Service. A Service whose
+// local port is taken is skipped with a warning rather than moved, so discovered
+// ports stay the same from one run to the next.
+func (s *Server) addDiscovered(d config.TCPDiscoverConfig, svc *corev1.Service) {
+	key := d.Context + "/" + svc.Namespace + "/" + svc.Name
+	localPort, route, err := discoveredRoute(d, svc)
+	if err != nil {
+		log.Printf("[discover:%s] Skipping service %s/%s: %v", d.Context, svc.Namespace, svc.Name, err)
+		s.removeDiscovered(key)
+		return
+	}
+
+	s.discoverMu.Lock()
+	defer s.discoverMu.Unlock()
+
+	if current, ok := s.discoveredBy[key]; ok {
+		if current == localPort {
+			s.setDiscoveredRoute(key, localPort, route)
+			return
+		}
+		s.stopDiscovered(key) // the local port annotation changed
+	}
+
+	if reason := s.discoveredPortTaken(localPort); reason != "" {
+		log.Printf("[discover:%s] Skipping service %s/%s: local port %d %s", d.Context, svc.Namespace, svc.Name, localPort, reason)
+		return
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.listenHost, strconv.Itoa(localPort)))
+	if err != nil {
+		log.Printf("[discover:%s] Skipping service %s/%s: %v", d.Context, svc.Namespace, svc.Name, err)
+		return
+	}
+	if !s.setDiscoveredRoute(key, localPort, route) {
+		listener.Close()
+		return
+	}
+
+	s.mu.Lock()
+	s.reserved[localPort] = true
+	s.mu.Unlock()
+
+	s.serve(&portListener{port: localPort, listenerType: listenerTypeRoute, listener: listener},
+		fmt.Sprintf("-> %s/%s:%d (discovered)", route.Namespace, route.Service, route.Port))
+	s.writeFileSD()
+}
+
+// setDiscoveredRoute records route on localPort for the Server and the tunnel
+// manager. Caller must hold s.discoverMu.
+func (s *Server) setDiscoveredRoute(key string, localPort int, route config.TCPRouteConfig) bool {
+	if err := s.manager.AddDiscoveredRoute(localPort, route); err != nil {
+		log.Printf("[discover] Skipping %s: %v", key, err)
+		return false
+	}
+	s.discoveredMu.Lock()
+	s.discovered[localPort] = route
+	s.discoveredMu.Unlock()
+	s.discoveredBy[key] = localPort
+	return true
+}
+
+// discoveredPortTaken says why localPort can't be used for a discovered route, or ""
+func (s *Server) discoveredPortTaken(localPort int) string {
+	if !s.config.TCP.PortRange().Contains(localPort) {
+		return fmt.Sprintf("is outside tcp.allowed_port_range %s", s.config.TCP.PortRange())
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.reserved[localPort] {
+		return "is already used by another route"
+	}
+	return ""
+}
+
+// removeDiscovered stops the route of a Service that was deleted or lost its label
+func (s *Server) removeDiscovered(key string) {
+	s.discoverMu.Lock()
+	defer s.discoverMu.Unlock()
+
+	if _, ok := s.discoveredBy[key]; !ok {
+		return
+	}
+	s.stopDiscovered(key)
+	s.writeFileSD()
+}
+
+// stopDiscovered closes key's listener and forgets its route. Caller must hold s.discoverMu.
+func (s *Server) stopDiscovered(key string) {
+	localPort := s.discoveredBy[key]
+	delete(s.discoveredBy, key)
+
+	s.discoveredMu.Lock()
+	delete(s.discovered, localPort)
+	s.discoveredMu.Unlock()
+	s.manager.RemoveDiscoveredRoute(localPort)
+
+	s.mu.Lock()
+	if pl, ok := s.listeners[localPort]; ok {
+		close(pl.stopChan)
+		pl.listener.Close()
+		delete(s.listeners, localPort)
+		log.Printf("TCP listener stopped on port %d (%s is gone)", localPort, key)
+	}
+	delete(s.reserved, localPort)
+	s.mu.Unlock()
+}
+
+// tcpRoute returns the configured or discovered TCP route on localPort
+func (s *Server) tcpRoute(localPort int) config.TCPRouteConfig {
+	if route, ok := s.config.TCP.K8s.Routes[localPort]; ok {
+		return route
+	}
+	s.discoveredMu.RLock()
+	defer s.discoveredMu.RUnlock()
+	return s.discovered[localPort]
+}
+
+// DiscoveredRoute is a route added by tcp.k8s.discover, for the status API
+type DiscoveredRoute struct {
+	Port       int    `json:"port"`
+	Context    string `json:"context"`
+	Namespace  string `json:"namespace"`
+	Service    string `json:"service"`
+	TargetPort int    `json:"target_port"`
+}
+
+// DiscoveredRoutes returns the routes added by tcp.k8s.discover, sorted by port
+func (s *Server) DiscoveredRoutes() []DiscoveredRoute {
+	s.discoveredMu.RLock()
+	defer s.discoveredMu.RUnlock()
+
+	routes := make([]DiscoveredRoute, 0, len(s.discovered))
+	for port, route := range s.discovered {
+		routes = append(routes, DiscoveredRoute{
+			Port:       port,
+			Context:    route.Context,
+			Namespace:  route.Namespace,
+			Service:    route.Service,
+			TargetPort: route.Port,
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Port < routes[j].Port })
+	return routes
+}
+
+func namespaceDisplay(namespace string) string {
+	if namespace == "" {
+		return "all namespaces"
+	}
+	return "namespace " + namespace
+}
@@ -0,0 +1,113 @@
+package tcpserver
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func labeledService(namespace, name, port string, annotations map[string]string) *corev1.Service {
+	return &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        name,
+		Labels:      map[string]string{config.DiscoverPortLabel: port},
+		Annotations: annotations,
+	}}
+}
+
+func TestDiscoveredRoute(t *testing.T) {
+	d := config.TCPDiscoverConfig{Context: "prod", Method: config.TCPMethodExec}
+	tests := []struct {
+		name      string
+		svc       *corev1.Service
+		wantLocal int
+		wantErr   string
+	}{
+		{name: "service port", svc: labeledService("db", "postgres", "5432", nil), wantLocal: 5432},
+		{name: "annotated local port", svc: labeledService("db", "postgres", "5432", map[string]string{config.DiscoverLocalPortAnnotation: "15432"}), wantLocal: 15432},
+		{name: "bad label", svc: labeledService("db", "postgres", "pg", nil), wantErr: "is not a port"},
+		{name: "bad annotation", svc: labeledService("db", "postgres", "5432", map[string]string{config.DiscoverLocalPortAnnotation: "70000"}), wantErr: "is not a port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localPort, route, err := discoveredRoute(d, tt.svc)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := config.TCPRouteConfig{Context: "prod", Namespace: "db", Service: "postgres", Port: 5432, Method: config.TCPMethodExec}
+			if localPort != tt.wantLocal || !reflect.DeepEqual(route, want) {
+				t.Errorf("discoveredRoute() = %d, %+v; want %d, %+v", localPort, route, tt.wantLocal, want)
+			}
+		})
+	}
+}
+
+// waitForDiscovered polls until n discovered routes are listed
+func waitForDiscovered(t *testing.T, s *Server, n int) []DiscoveredRoute {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if routes := s.DiscoveredRoutes(); len(routes) == n {
+			return routes
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected %d discovered routes, got %+v", n, s.DiscoveredRoutes())
+	return nil
+}
+
+func TestServer_WatchServices(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19790: {Context: "prod", Namespace: "ns", Service: "static", Port: 80},
+	})
+	d := config.TCPDiscoverConfig{Context: "prod"}
+	clientset := fake.NewSimpleClientset(
+		labeledService("db", "postgres", "5432", map[string]string{config.DiscoverLocalPortAnnotation: "19791"}),
+		// the configured route keeps its port
+		labeledService("db", "mysql", "3306", map[string]string{config.DiscoverLocalPortAnnotation: "19790"}),
+	)
+
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{}})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+	go s.watchServices(d, clientset)
+
+	routes := waitForDiscovered(t, s, 1)
+	if want := (DiscoveredRoute{Port: 19791, Context: "prod", Namespace: "db", Service: "postgres", TargetPort: 5432}); routes[0] != want {
+		t.Errorf("discovered %+v, want %+v", routes[0], want)
+	}
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19791", time.Second)
+	if err != nil {
+		t.Fatalf("Expected a listener for the discovered service: %v", err)
+	}
+	conn.Close()
+	if port, target := s.routeTarget(19791); port != 19791 || target != 5432 {
+		t.Errorf("routeTarget(19791) = %d, %d; want 19791, 5432", port, target)
+	}
+
+	// deleting the service closes its listener
+	if err := clientset.CoreV1().Services("db").Delete(context.Background(), "postgres", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForDiscovered(t, s, 0)
+	if conn, err := net.DialTimeout("tcp", "127.0.0.1:19791", 100*time.Millisecond); err == nil {
+		conn.Close()
+		t.Error("Expected the listener to be closed after the service was deleted")
+	}
+}
@@ -0,0 +1,14 @@
+package tcpserver
+
+import (
+	"net"
+
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// handleEchoConnection sends everything conn receives straight back (tcp.echo_port),
+// to check a client's setup without involving a cluster
+func handleEchoConnection(conn net.Conn) {
+	defer conn.Close()
+	_, _ = netutil.Copy(conn, conn)
+}
@@ -0,0 +1,40 @@
+package tcpserver
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestServer_EchoPort(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{})
+	cfg.TCP.EchoPort = 19777
+
+	mgr := &mockManager{}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19777", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("echo = %q, %v", buf, err)
+	}
+	if len(mgr.getTCPTunnelCalls) != 0 {
+		t.Errorf("Expected no tunnel, got calls for %v", mgr.getTCPTunnelCalls)
+	}
+}
@@ -0,0 +1,59 @@
+package tcpserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+)
+
+// execRoute returns the TCP route behind a listener, the target port to reach
+// and a key identifying the route (shared by its extra_ports or group ports)
+func (s *Server) execRoute(pl *portListener) (route config.TCPRouteConfig, targetPort int, key string) {
+	if pl.listenerType == listenerTypeGroup {
+		return s.config.TCP.K8s.Groups[pl.group].TCPRoute(), pl.targetPort, "group " + pl.group
+	}
+	routePort, targetPort := s.routeTarget(pl.port)
+	return s.tcpRoute(routePort), targetPort, fmt.Sprintf("route %d", routePort)
+}
+
+// usesExec reports whether connections on pl go through kubectl exec instead of a
+// port-forward: configured with method: exec, or after port-forward was forbidden
+func (s *Server) usesExec(pl *portListener) bool {
+	route, _, key := s.execRoute(pl)
+	if route.Method == config.TCPMethodExec {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.execRoutes[key]
+}
+
+// fallBackToExec switches a route to exec when its port-forward failed because
+// pods/portforward is forbidden, unless the route pins method: portforward
+func (s *Server) fallBackToExec(pl *portListener, err error) bool {
+	route, _, key := s.execRoute(pl)
+	if route.Method == config.TCPMethodPortForward || !k8sutil.IsForbidden(err) {
+		return false
+	}
+
+	s.mu.Lock()
+	first := !s.execRoutes[key]
+	s.execRoutes[key] = true
+	s.mu.Unlock()
+
+	if first {
+		log.Printf("[tcp:%d] Port-forward is forbidden for %s/%s, falling back to exec + socat/nc (set method: portforward to disable): %v",
+			pl.port, route.Namespace, route.TargetDisplay(), err)
+	}
+	return true
+}
+
+// handleExecConnection forwards conn via kubectl exec + socat/nc in the route's pod,
+// the same mechanism jump routes use
+func (s *Server) handleExecConnection(pl *portListener, conn net.Conn) {
+	route, targetPort, _ := s.execRoute(pl)
+	s.runJump(pl.port, route.ExecRoute(targetPort), conn)
+}
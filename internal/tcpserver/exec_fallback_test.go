@@ -0,0 +1,82 @@
+package tcpserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+var errPortForwardForbidden = errors.New(`port forward failed: error upgrading connection: pods "api-0" is forbidden: User "dev" cannot create resource "pods/portforward"`)
+
+func TestServer_FallsBackToExecWhenPortForwardForbidden(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19720: {Context: "test", Namespace: "ns", Service: "svc", Port: 80, ExtraPorts: map[int]int{19721: 81}},
+	})
+	mgr := &mockManager{tunnelToReturn: &sharedMockTunnel{startErr: errPortForwardForbidden}}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19720", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// the exec attempt fails without a cluster and closes the connection
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _ = conn.Read(make([]byte, 1))
+
+	s.mu.RLock()
+	extra := s.listeners[19721]
+	s.mu.RUnlock()
+	if !s.usesExec(extra) {
+		t.Error("Expected the route (and its extra ports) to switch to exec")
+	}
+}
+
+func TestServer_FallBackToExec(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   bool
+	}{
+		{name: "forbidden", err: errPortForwardForbidden, want: true},
+		{name: "other error", err: errors.New("connection refused"), want: false},
+		{name: "pinned to portforward", method: config.TCPMethodPortForward, err: errPortForwardForbidden, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(map[int]config.TCPRouteConfig{
+				19730: {Context: "test", Namespace: "ns", Pod: "api-0", Port: 80, Method: tt.method},
+			})
+			s := NewServer(cfg, &mockManager{})
+			pl := &portListener{port: 19730, listenerType: listenerTypeRoute}
+
+			if got := s.fallBackToExec(pl, tt.err); got != tt.want {
+				t.Errorf("fallBackToExec() = %v, want %v", got, tt.want)
+			}
+			if got := s.usesExec(pl); got != tt.want {
+				t.Errorf("usesExec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_UsesExec_ConfiguredMethod(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19740: {Context: "test", Namespace: "ns", Pod: "api-0", Port: 80, Method: config.TCPMethodExec},
+	})
+	s := NewServer(cfg, &mockManager{})
+
+	if !s.usesExec(&portListener{port: 19740, listenerType: listenerTypeRoute}) {
+		t.Error("Expected method: exec to always use exec")
+	}
+}
@@ -0,0 +1,120 @@
+package tcpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// fileSDGroup is one entry of a Prometheus file_sd file
+type fileSDGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// writeFileSD rewrites tcp.prometheus_file_sd with every bound route, extra_ports and
+// group port, so a local Prometheus picks up forwarded exporters on its own. Jump
+// routes are left out: they reach hosts outside the cluster.
+func (s *Server) writeFileSD() {
+	path := s.config.TCP.PrometheusFileSD
+	if path == "" {
+		return
+	}
+
+	// serialized so a snapshot taken earlier never overwrites a newer one
+	s.fileSDMu.Lock()
+	defer s.fileSDMu.Unlock()
+
+	data, err := json.MarshalIndent(s.fileSDGroups(), "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode Prometheus file_sd targets: %v", err)
+		return
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		log.Printf("Failed to write Prometheus file_sd %s: %v", path, err)
+	}
+}
+
+// fileSDGroups returns one target group per serving listener, ordered by local port
+func (s *Server) fileSDGroups() []fileSDGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ports := make([]int, 0, len(s.listeners))
+	for port := range s.listeners {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	groups := make([]fileSDGroup, 0, len(ports))
+	for _, port := range ports {
+		pl := s.listeners[port]
+		if pl.listenerType == listenerTypeJump || pl.listenerType == listenerTypePostgres || pl.listenerType == listenerTypeEcho {
+			continue
+		}
+		groups = append(groups, fileSDGroup{
+			Targets: []string{pl.listener.Addr().String()}, // the bound port, even when remapped
+			Labels:  s.fileSDLabels(pl),
+		})
+	}
+	return groups
+}
+
+func (s *Server) fileSDLabels(pl *portListener) map[string]string {
+	if pl.listenerType == listenerTypeGroup {
+		group := s.config.TCP.K8s.Groups[pl.group]
+		return map[string]string{
+			"autotunnel_route": "group:" + pl.group,
+			"k8s_context":      group.Context,
+			"namespace":        group.Namespace,
+			"service":          group.Service,
+			"target_port":      strconv.Itoa(pl.targetPort),
+		}
+	}
+
+	routePort, targetPort := s.routeTarget(pl.port)
+	route := s.tcpRoute(routePort)
+	labels := map[string]string{
+		"autotunnel_route": fmt.Sprintf("tcp:%d", routePort),
+		"k8s_context":      route.Context,
+		"namespace":        route.Namespace,
+		"target_port":      strconv.Itoa(targetPort),
+	}
+	if route.Pod != "" {
+		labels["pod"] = route.Pod
+	} else {
+		labels["service"] = route.Service
+	}
+	return labels
+}
+
+// writeFileAtomic replaces path through a temp file in the same directory, so
+// Prometheus never reads a half-written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
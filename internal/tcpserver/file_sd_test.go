@@ -0,0 +1,105 @@
+package tcpserver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func readFileSD(t *testing.T, path string) []fileSDGroup {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file_sd: %v", err)
+	}
+	var groups []fileSDGroup
+	if err := json.Unmarshal(data, &groups); err != nil {
+		t.Fatalf("file_sd is not valid JSON: %v\n%s", err, data)
+	}
+	return groups
+}
+
+func TestServer_WritesFileSD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sd", "autotunnel.json")
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19720: {Context: "test", Namespace: "monitoring", Service: "node-exporter", Port: 9100, ExtraPorts: map[int]int{19721: 9256}},
+		19722: {Context: "test", Namespace: "ns", Pod: "app-0", Port: 8080},
+	})
+	cfg.TCP.K8s.Jump = map[int]config.JumpRouteConfig{
+		19723: {Context: "test", Namespace: "ns", Target: config.TargetConfig{Host: "db.example.com", Port: 5432}},
+	}
+	cfg.TCP.PrometheusFileSD = path
+
+	s := NewServer(cfg, &mockManager{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	groups := readFileSD(t, path)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 target groups (jump route excluded), got %d: %+v", len(groups), groups)
+	}
+
+	tests := []struct {
+		target string
+		labels map[string]string
+	}{
+		{"127.0.0.1:19720", map[string]string{"autotunnel_route": "tcp:19720", "namespace": "monitoring", "service": "node-exporter", "target_port": "9100"}},
+		{"127.0.0.1:19721", map[string]string{"autotunnel_route": "tcp:19720", "service": "node-exporter", "target_port": "9256"}},
+		{"127.0.0.1:19722", map[string]string{"autotunnel_route": "tcp:19722", "pod": "app-0", "k8s_context": "test"}},
+	}
+	for i, tt := range tests {
+		if len(groups[i].Targets) != 1 || groups[i].Targets[0] != tt.target {
+			t.Errorf("group %d targets = %v, want [%s]", i, groups[i].Targets, tt.target)
+		}
+		for k, v := range tt.labels {
+			if got := groups[i].Labels[k]; got != v {
+				t.Errorf("group %d label %s = %q, want %q", i, k, got, v)
+			}
+		}
+	}
+
+	s.Shutdown()
+	if groups := readFileSD(t, path); len(groups) != 0 {
+		t.Errorf("Expected no targets after shutdown, got %+v", groups)
+	}
+}
+
+func TestServer_FileSDIncludesGroupPorts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "autotunnel.json")
+	cfg := groupConfig(config.GroupRouteConfig{
+		Context: "test", Namespace: "ns", Service: "api",
+		Ports: map[int]int{9090: 19730},
+	})
+	cfg.TCP.PrometheusFileSD = path
+
+	s := NewServer(cfg, &mockManager{groupPorts: map[string][]int{"api": {9090}}})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+	waitForGroup(t, s, "api", 1)
+
+	// the file is rewritten once all of the group's ports are bound
+	deadline := time.Now().Add(2 * time.Second)
+	var groups []fileSDGroup
+	for time.Now().Before(deadline) {
+		if groups = readFileSD(t, path); len(groups) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 target group, got %+v", groups)
+	}
+	if groups[0].Targets[0] != "127.0.0.1:19730" {
+		t.Errorf("target = %s, want 127.0.0.1:19730", groups[0].Targets[0])
+	}
+	if groups[0].Labels["autotunnel_route"] != "group:api" || groups[0].Labels["target_port"] != "9090" {
+		t.Errorf("unexpected labels %v", groups[0].Labels)
+	}
+}
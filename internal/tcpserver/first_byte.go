@@ -0,0 +1,199 @@
+package tcpserver
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/tunnelmgr"
+)
+
+// maxReplay bounds what a connection keeps of the client's data while waiting for
+// the backend's first byte. Past it, the connection is no longer retried.
+const maxReplay = 64 * 1024
+
+// retryConn is the backend side of a port-forwarded connection. Until the backend
+// sends its first byte it keeps what the client wrote, and if the backend fails
+// before then (a port-forward stream to a pod that is gone) it redials once and
+// replays it, so the client never notices.
+type retryConn struct {
+	net.Conn // the first backend connection, for addresses and deadlines
+	redial   func() (net.Conn, error)
+
+	mu          sync.Mutex
+	conn        net.Conn // current backend connection
+	sent        []byte
+	overflow    bool // sent more than maxReplay
+	answered    bool // the backend sent something: no more retries
+	retried     bool
+	closed      bool
+	closedWrite bool
+}
+
+func newRetryConn(backend net.Conn, redial func() (net.Conn, error)) *retryConn {
+	return &retryConn{Conn: backend, conn: backend, redial: redial}
+}
+
+func (r *retryConn) current() net.Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn
+}
+
+func (r *retryConn) Read(p []byte) (int, error) {
+	conn := r.current()
+	n, err := conn.Read(p)
+	if n > 0 || err == nil {
+		r.mu.Lock()
+		r.answered = true
+		r.sent = nil
+		r.mu.Unlock()
+		return n, err
+	}
+	if r.retry(conn) {
+		return r.Read(p)
+	}
+	return n, err
+}
+
+func (r *retryConn) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if r.answered {
+		conn := r.conn
+		r.mu.Unlock()
+		return conn.Write(p)
+	}
+	// held while writing, so a retry replays exactly what reached the backend
+	defer r.mu.Unlock()
+	r.record(p)
+	conn := r.conn
+	n, err := conn.Write(p)
+	if err != nil && r.retryLocked(conn) {
+		return len(p), nil // p was part of the replay
+	}
+	return n, err
+}
+
+func (r *retryConn) record(p []byte) {
+	if r.overflow {
+		return
+	}
+	if len(r.sent)+len(p) > maxReplay {
+		r.overflow = true
+		r.sent = nil
+		return
+	}
+	r.sent = append(r.sent, p...)
+}
+
+// retry replaces failed with a new backend connection, if it's still the current
+// one (the other direction may have retried already) and a retry is allowed
+func (r *retryConn) retry(failed net.Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.retryLocked(failed)
+}
+
+// retryLocked is retry with r.mu held
+func (r *retryConn) retryLocked(failed net.Conn) bool {
+	if r.conn != failed {
+		return true
+	}
+	// after the client's half-close the backend hanging up is an answer too
+	if r.answered || r.retried || r.overflow || r.closed || r.closedWrite {
+		return false
+	}
+	r.retried = true
+
+	conn, err := r.redial()
+	if err != nil {
+		return false
+	}
+	_ = failed.Close()
+	if len(r.sent) > 0 {
+		if _, err := conn.Write(r.sent); err != nil {
+			_ = conn.Close()
+			return false
+		}
+	}
+	r.conn = conn
+	return true
+}
+
+func (r *retryConn) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.conn.Close()
+}
+
+func (r *retryConn) CloseWrite() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closedWrite = true
+	if cw, ok := r.conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func (r *retryConn) CloseRead() error {
+	if cr, ok := r.current().(interface{ CloseRead() error }); ok {
+		return cr.CloseRead()
+	}
+	return nil
+}
+
+// tunnelRestarts remembers when each tunnel was last restarted over a stale
+// stream, so connections failing together restart it only once
+type tunnelRestarts struct {
+	mu sync.Mutex
+	at map[string]time.Time
+}
+
+func newTunnelRestarts() *tunnelRestarts {
+	return &tunnelRestarts{at: make(map[string]time.Time)}
+}
+
+// restart stops tunnel unless it was restarted after since, the time the failed
+// connection was dialed. The caller starts it again.
+func (r *tunnelRestarts) restart(key string, tunnel tunnelmgr.TunnelHandle, since time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.at[key].After(since) {
+		return
+	}
+	tunnel.Stop()
+	r.at[key] = time.Now()
+}
+
+// tunnelKey names the tunnel serving pl, like the manager does
+func (s *Server) tunnelKey(pl *portListener) string {
+	if pl.listenerType == listenerTypeGroup {
+		return "group:" + pl.group
+	}
+	routePort, _ := s.routeTarget(pl.port)
+	return fmt.Sprintf("tcp:%d", routePort)
+}
+
+// redialer returns the retryConn redial of a connection to pl dialed at dialedAt:
+// it restarts the tunnel, for a fresh port-forward to a live pod, and dials again
+func (s *Server) redialer(pl *portListener, tunnel tunnelmgr.TunnelHandle, targetPort int, dialedAt time.Time) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		log.Printf("[tcp:%d] Backend closed before answering, restarting the tunnel and retrying", pl.port)
+		s.restarts.restart(s.tunnelKey(pl), tunnel, dialedAt)
+		if err := tunnel.Start(s.ctx); err != nil {
+			log.Printf("[tcp:%d] Failed to restart tunnel: %v", pl.port, err)
+			return nil, err
+		}
+		backendPort := tunnel.LocalPortFor(targetPort)
+		if backendPort == 0 {
+			return nil, errors.New("tunnel stopped before connecting")
+		}
+		backendAddr := fmt.Sprintf("127.0.0.1:%d", backendPort)
+		return net.DialTimeout("tcp", backendAddr, s.connRoute(pl).GetConnectTimeout())
+	}
+}
@@ -0,0 +1,138 @@
+package tcpserver
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// stoppableMockTunnel counts Stop calls
+type stoppableMockTunnel struct {
+	sharedMockTunnel
+	stops atomic.Int32
+}
+
+func (m *stoppableMockTunnel) Stop() { m.stops.Add(1) }
+
+func TestServer_RetriesStreamClosedBeforeFirstByte(t *testing.T) {
+	// the first connection dies without answering, like a stream to a deleted pod;
+	// later ones echo
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			if i == 0 {
+				_, _ = conn.Read(make([]byte, 16))
+				conn.Close()
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19776: {Context: "test", Namespace: "ns", Service: "svc", Port: 80},
+	})
+	tun := &stoppableMockTunnel{sharedMockTunnel: sharedMockTunnel{
+		localPorts: map[int]int{80: backend.Addr().(*net.TCPAddr).Port},
+	}}
+	s := NewServer(cfg, &mockManager{tunnelToReturn: tun})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19776", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Expected the retried connection to answer, got %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("got %q, want the replayed ping", buf)
+	}
+	if got := tun.stops.Load(); got != 1 {
+		t.Errorf("tunnel stopped %d times, want 1", got)
+	}
+}
+
+func TestRetryConn_NoRetryAfterAnswer(t *testing.T) {
+	client, backend := net.Pipe()
+	var redials int
+	rc := newRetryConn(client, func() (net.Conn, error) {
+		redials++
+		return nil, io.ErrClosedPipe
+	})
+
+	go func() {
+		_, _ = backend.Write([]byte("hi"))
+		backend.Close()
+	}()
+
+	data, err := io.ReadAll(rc)
+	if err != nil || string(data) != "hi" {
+		t.Fatalf("ReadAll = %q, %v", data, err)
+	}
+	if redials != 0 {
+		t.Errorf("redialed %d times after the backend answered", redials)
+	}
+}
+
+func TestRetryConn_NoRetryAfterClientHalfClose(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err == nil {
+			_, _ = io.Copy(io.Discard, conn)
+			conn.Close()
+		}
+	}()
+
+	dialed, err := net.Dial("tcp", backend.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	var redials int
+	rc := newRetryConn(dialed, func() (net.Conn, error) {
+		redials++
+		return nil, io.ErrClosedPipe
+	})
+	defer rc.Close()
+
+	// a client connecting and leaving at once (nc -z) isn't a stale stream
+	if err := rc.CloseWrite(); err != nil {
+		t.Fatalf("CloseWrite: %v", err)
+	}
+	if _, err := rc.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+	if redials != 0 {
+		t.Errorf("redialed %d times after the client's half-close", redials)
+	}
+}
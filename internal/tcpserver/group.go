@@ -0,0 +1,131 @@
+package tcpserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// groupRetryInterval is how long to wait before looking up a group's service again
+// after a failure (cluster unreachable, service missing, ...)
+var groupRetryInterval = 30 * time.Second
+
+// startGroups looks up each group's service ports in the background and binds a
+// listener per port once they are known, so startup never waits on the API server
+func (s *Server) startGroups() {
+	for name := range s.config.TCP.K8s.Groups {
+		s.wg.Add(1)
+		go s.resolveGroup(name)
+	}
+}
+
+func (s *Server) resolveGroup(name string) {
+	defer s.wg.Done()
+
+	for {
+		ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
+		ports, err := s.manager.GroupServicePorts(ctx, name)
+		cancel()
+		if err == nil {
+			s.bindGroup(name, ports)
+			return
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("[group:%s] Failed to look up service ports (retrying in %v): %v", name, groupRetryInterval, err)
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(groupRetryInterval):
+		}
+	}
+}
+
+// bindGroup listens on a local port for every service port: the one set in ports,
+// or service port + port_offset, moving to the next free port when that is taken
+func (s *Server) bindGroup(name string, ports []int) {
+	group := s.config.TCP.K8s.Groups[name]
+
+	s.mu.Lock()
+	s.groupPorts[name] = ports
+	s.groupLocal[name] = make(map[int]int, len(ports))
+	s.mu.Unlock()
+
+	for _, servicePort := range ports {
+		listener, localPort, err := s.listenGroupPort(group, servicePort)
+		if err != nil {
+			log.Printf("[group:%s] Failed to listen for service port %d: %v", name, servicePort, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.groupLocal[name][servicePort] = localPort
+		s.mu.Unlock()
+
+		s.serve(&portListener{
+			port:         localPort,
+			listenerType: listenerTypeGroup,
+			listener:     listener,
+			group:        name,
+			targetPort:   servicePort,
+		}, fmt.Sprintf("-> %s/%s:%d (group %s)", group.Namespace, group.Service, servicePort, name))
+	}
+	s.writeFileSD()
+}
+
+// listenGroupPort binds the local port for one of a group's service ports
+func (s *Server) listenGroupPort(group config.GroupRouteConfig, servicePort int) (net.Listener, int, error) {
+	if localPort, ok := group.Ports[servicePort]; ok {
+		listener, err := net.Listen("tcp", net.JoinHostPort(s.listenHost, strconv.Itoa(localPort)))
+		return listener, localPort, err
+	}
+
+	allowed := s.config.TCP.PortRange()
+	candidate := servicePort + group.PortOffset
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if allowed.Contains(candidate) && !s.reserved[candidate] {
+		listener, err := net.Listen("tcp", net.JoinHostPort(s.listenHost, strconv.Itoa(candidate)))
+		if err == nil {
+			s.reserved[candidate] = true
+			return listener, candidate, nil
+		}
+	}
+
+	// taken, privileged or out of range: take the next free unprivileged port in range
+	start := max(candidate, allowed.Min, 1024)
+	if start > allowed.Max {
+		start = max(allowed.Min, 1024)
+	}
+	listener, localPort, err := netutil.ListenNextFree(s.listenHost, start-1, allowed.Max, s.reserved)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.reserved[localPort] = true
+	return listener, localPort, nil
+}
+
+// GroupPorts returns group name -> service port -> bound local port for resolved groups
+func (s *Server) GroupPorts() map[string]map[int]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	groups := make(map[string]map[int]int, len(s.groupLocal))
+	for name, local := range s.groupLocal {
+		ports := make(map[int]int, len(local))
+		for servicePort, localPort := range local {
+			ports[servicePort] = localPort
+		}
+		groups[name] = ports
+	}
+	return groups
+}
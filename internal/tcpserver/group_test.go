@@ -0,0 +1,106 @@
+package tcpserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// waitForGroup polls until the group has n bound ports
+func waitForGroup(t *testing.T, s *Server, name string, n int) map[int]int {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if ports := s.GroupPorts()[name]; len(ports) == n {
+			return ports
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("group %s did not bind %d ports, got %v", name, n, s.GroupPorts()[name])
+	return nil
+}
+
+func groupConfig(group config.GroupRouteConfig) *config.Config {
+	cfg := testConfig(nil)
+	cfg.TCP.K8s.Groups = map[string]config.GroupRouteConfig{"api": group}
+	return cfg
+}
+
+func TestServer_GroupBindsServicePorts(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	accepted := make(chan struct{})
+	go func() {
+		conn, err := backend.Accept()
+		if err == nil {
+			conn.Close()
+			close(accepted)
+		}
+	}()
+
+	cfg := groupConfig(config.GroupRouteConfig{
+		Context: "test", Namespace: "ns", Service: "api",
+		Ports:      map[int]int{80: 19810},
+		PortOffset: 19000,
+	})
+	mgr := &mockManager{
+		groupPorts: map[string][]int{"api": {80, 443}},
+		tunnelToReturn: &sharedMockTunnel{localPorts: map[int]int{
+			443: backend.Addr().(*net.TCPAddr).Port,
+		}},
+	}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	ports := waitForGroup(t, s, "api", 2)
+	if ports[80] != 19810 {
+		t.Errorf("Expected configured port 19810 for service port 80, got %d", ports[80])
+	}
+	if ports[443] != 19443 {
+		t.Errorf("Expected service port 443 + offset = 19443, got %d", ports[443])
+	}
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19443", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect to group port: %v", err)
+	}
+	defer conn.Close()
+
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Group port connection did not reach the backend for its service port")
+	}
+}
+
+func TestServer_GroupAutoPortTaken(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:19443")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer busy.Close()
+
+	cfg := groupConfig(config.GroupRouteConfig{
+		Context: "test", Namespace: "ns", Service: "api",
+		PortOffset: 19000,
+	})
+	mgr := &mockManager{groupPorts: map[string][]int{"api": {443}}}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	ports := waitForGroup(t, s, "api", 1)
+	if ports[443] != 19444 {
+		t.Errorf("Expected the next free port 19444, got %d", ports[443])
+	}
+}
@@ -0,0 +1,116 @@
+package tcpserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// resolveTimeout bounds a lookup in the jump pod; on timeout the hostname is used
+const resolveTimeout = 5 * time.Second
+
+// targetResolver caches jump target addresses resolved from inside the cluster
+// (dns_cache_ttl), as the jump pod sees them
+type targetResolver struct {
+	mu      sync.Mutex
+	entries map[string]resolvedTarget // context/namespace/host
+}
+
+type resolvedTarget struct {
+	ip      string
+	expires time.Time
+}
+
+func newTargetResolver() *targetResolver {
+	return &targetResolver{entries: make(map[string]resolvedTarget)}
+}
+
+// resolve returns host's cached address, looking it up with lookup when missing
+// or expired. Failed lookups are not cached.
+func (r *targetResolver) resolve(key string, ttl time.Duration, lookup func() (string, error)) (string, error) {
+	r.mu.Lock()
+	entry, ok := r.entries[key]
+	r.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.ip, nil
+	}
+
+	ip, err := lookup()
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	r.entries[key] = resolvedTarget{ip: ip, expires: time.Now().Add(ttl)}
+	r.mu.Unlock()
+	return ip, nil
+}
+
+// resolveTargets replaces the hostnames among the route's targets with addresses
+// resolved in the jump pod, when dns_cache_ttl is set. A failed lookup keeps the
+// hostname, leaving it to socat/nc as without the cache.
+func (h *JumpHandler) resolveTargets(ctx context.Context, podName, containerName string) {
+	if h.route.DNSCacheTTL <= 0 || h.resolver == nil {
+		return
+	}
+
+	h.route.Target = h.resolveTarget(ctx, podName, containerName, h.route.Target)
+	fallbacks := make([]config.TargetConfig, len(h.route.Fallbacks)) // shared with the config
+	for i, target := range h.route.Fallbacks {
+		fallbacks[i] = h.resolveTarget(ctx, podName, containerName, target)
+	}
+	h.route.Fallbacks = fallbacks
+}
+
+func (h *JumpHandler) resolveTarget(ctx context.Context, podName, containerName string, target config.TargetConfig) config.TargetConfig {
+	if net.ParseIP(target.Host) != nil {
+		return target
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", h.route.Context, h.route.Namespace, target.Host)
+	ip, err := h.resolver.resolve(key, h.route.DNSCacheTTL, func() (string, error) {
+		return h.lookupHost(ctx, podName, containerName, target.Host)
+	})
+	if err != nil {
+		if h.verbose {
+			log.Printf("[jump] Failed to resolve %s in pod %s/%s, connecting by name: %v", target.Host, h.route.Namespace, podName, err)
+		}
+		return target
+	}
+	if target.TLS && target.SNI == "" {
+		target.SNI = target.Host // still send and verify the name, not the address
+	}
+	target.Host = ip
+	return target
+}
+
+// lookupHost resolves host with getent in the jump pod
+func (h *JumpHandler) lookupHost(ctx context.Context, podName, containerName, host string) (string, error) {
+	// defense-in-depth: host ends up in a shell command
+	if !config.IsValidTargetHost(host) {
+		return "", fmt.Errorf("invalid target host %q", host)
+	}
+	stdout, stderr, err := h.runCommand(ctx, podName, containerName, "getent hosts "+host, resolveTimeout)
+	if err != nil {
+		if stderr != "" {
+			return "", fmt.Errorf("%w (%s)", err, stderr)
+		}
+		return "", err
+	}
+	return parseGetentHosts(stdout)
+}
+
+// parseGetentHosts returns the first address of `getent hosts` output
+// ("10.0.1.5      db.internal")
+func parseGetentHosts(out string) (string, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 || net.ParseIP(fields[0]) == nil {
+		return "", fmt.Errorf("unexpected getent output %q", out)
+	}
+	return fields[0], nil
+}
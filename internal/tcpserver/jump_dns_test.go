@@ -0,0 +1,103 @@
+package tcpserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestParseGetentHosts(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{out: "10.0.1.5      db.internal", want: "10.0.1.5"},
+		{out: "2001:db8::5  db.internal db\n10.0.1.5 db.internal", want: "2001:db8::5"},
+		{out: "", wantErr: true},
+		{out: "not-an-ip db.internal", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseGetentHosts(tt.out)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseGetentHosts(%q) = %q, %v; want %q, error %v", tt.out, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTargetResolver_CachesUntilTTL(t *testing.T) {
+	r := newTargetResolver()
+	lookups := 0
+	lookup := func() (string, error) { lookups++; return "10.0.1.5", nil }
+
+	for i := 0; i < 3; i++ {
+		if ip, err := r.resolve("ctx/ns/db.internal", time.Minute, lookup); err != nil || ip != "10.0.1.5" {
+			t.Fatalf("resolve() = %q, %v", ip, err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("looked up %d times within the TTL, want 1", lookups)
+	}
+
+	// expired entries and failures are looked up again
+	r.entries["ctx/ns/db.internal"] = resolvedTarget{ip: "10.0.1.5", expires: time.Now().Add(-time.Second)}
+	if _, err := r.resolve("ctx/ns/db.internal", time.Minute, lookup); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 2 {
+		t.Errorf("looked up %d times, want 2 after the entry expired", lookups)
+	}
+	if _, err := r.resolve("ctx/ns/other.internal", time.Minute, func() (string, error) { return "", errors.New("nxdomain") }); err == nil {
+		t.Error("Expected the lookup error")
+	}
+	if _, ok := r.entries["ctx/ns/other.internal"]; ok {
+		t.Error("Expected failed lookups not to be cached")
+	}
+}
+
+func TestJumpHandler_resolveTargets(t *testing.T) {
+	fallbacks := []config.TargetConfig{{Host: "writer-b.internal", Port: 5432}, {Host: "10.0.9.9", Port: 5432}}
+	route := config.JumpRouteConfig{
+		Context: "ctx", Namespace: "ns",
+		Target:      config.TargetConfig{Host: "writer-a.internal", Port: 5432},
+		Fallbacks:   fallbacks,
+		DNSCacheTTL: time.Minute,
+	}
+
+	resolver := newTargetResolver()
+	expires := time.Now().Add(time.Minute)
+	resolver.entries["ctx/ns/writer-a.internal"] = resolvedTarget{ip: "10.0.1.1", expires: expires}
+	resolver.entries["ctx/ns/writer-b.internal"] = resolvedTarget{ip: "10.0.1.2", expires: expires}
+
+	handler := NewJumpHandler(route, nil, nil, nil, false)
+	handler.resolver = resolver
+	handler.resolveTargets(context.Background(), "jump", "")
+
+	if got := handler.route.TargetDisplay(); got != "10.0.1.1:5432, 10.0.1.2:5432, 10.0.9.9:5432" {
+		t.Errorf("targets = %s", got)
+	}
+	if fallbacks[0].Host != "writer-b.internal" {
+		t.Error("Expected the configured fallbacks to be left alone")
+	}
+}
+
+func TestJumpHandler_resolveTargets_KeepsTLSName(t *testing.T) {
+	route := config.JumpRouteConfig{
+		Context: "ctx", Namespace: "ns",
+		Target:      config.TargetConfig{Host: "api.internal", Port: 443, TLS: true},
+		DNSCacheTTL: time.Minute,
+	}
+	resolver := newTargetResolver()
+	resolver.entries["ctx/ns/api.internal"] = resolvedTarget{ip: "10.0.1.1", expires: time.Now().Add(time.Minute)}
+
+	handler := NewJumpHandler(route, nil, nil, nil, false)
+	handler.resolver = resolver
+	handler.resolveTargets(context.Background(), "jump", "")
+
+	if got := handler.route.Target; got.Host != "10.0.1.1" || got.SNI != "api.internal" {
+		t.Errorf("Target = %+v, want the address with the hostname kept as SNI", got)
+	}
+}
@@ -0,0 +1,161 @@
+package tcpserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// targetFailCooldown is how long a target that could not be reached is tried
+// after the others
+const targetFailCooldown = 30 * time.Second
+
+// targetConnectTimeout bounds socat's connect to each target of a list, so a
+// blackholed one doesn't hold up the failover
+const targetConnectTimeout = 5 * time.Second
+
+// halfCloseTimeout is how long socat keeps the target's side open after the
+// client half-closes (socat -t; its default of 0.5s cuts off slow responses to
+// clients that shut down writing once their request is sent)
+const halfCloseTimeout = 30 * time.Second
+
+// targetMarker is written to stderr before each target of a list is tried
+const targetMarker = "autotunnel-target:"
+
+// targetHealth remembers the jump targets that recently failed to connect
+type targetHealth struct {
+	mu     sync.Mutex
+	failed map[string]time.Time // context/namespace/host:port -> when
+}
+
+func newTargetHealth() *targetHealth {
+	return &targetHealth{failed: make(map[string]time.Time)}
+}
+
+func targetKey(route config.JumpRouteConfig, target config.TargetConfig) string {
+	return fmt.Sprintf("%s/%s/%s", route.Context, route.Namespace, net.JoinHostPort(target.Host, strconv.Itoa(target.Port)))
+}
+
+// order returns route's targets with those that failed within the cooldown moved
+// to the end, otherwise keeping the configured order
+func (th *targetHealth) order(route config.JumpRouteConfig) []config.TargetConfig {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	var healthy, failing []config.TargetConfig
+	for _, target := range route.Targets() {
+		if at, ok := th.failed[targetKey(route, target)]; ok && time.Since(at) < targetFailCooldown {
+			failing = append(failing, target)
+		} else {
+			healthy = append(healthy, target)
+		}
+	}
+	return append(healthy, failing...)
+}
+
+func (th *targetHealth) markFailed(route config.JumpRouteConfig, target config.TargetConfig) {
+	th.mu.Lock()
+	th.failed[targetKey(route, target)] = time.Now()
+	th.mu.Unlock()
+}
+
+// forwardCommand is the socat/nc command for one target
+func forwardCommand(target config.TargetConfig, socatOptions string) (string, error) {
+	host := target.Host
+
+	// defense-in-depth: validate host even though config validation should catch this
+	if !config.IsValidTargetHost(host) {
+		return "", fmt.Errorf("invalid target host %q: must be valid hostname or IP", host)
+	}
+
+	// Wrap IPv6 addresses in brackets for proper socat/nc syntax
+	// e.g., 2001:db8::1 becomes [2001:db8::1] to avoid ambiguity with port separator
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+
+	socat := fmt.Sprintf("socat -t%d -", int(halfCloseTimeout.Seconds()))
+
+	// nc can't do TLS, so there is no fallback
+	if target.TLS {
+		return fmt.Sprintf("%s OPENSSL:%s:%d%s%s", socat, host, target.Port, tlsOptions(target), socatOptions), nil
+	}
+
+	// try socat first (handles binary better), fall back to nc
+	// stderr is captured for error logging (connection refused, etc.)
+	return fmt.Sprintf("%s TCP:%s:%d%s || nc %s %d", socat, host, target.Port, socatOptions, host, target.Port), nil
+}
+
+// tlsOptions are the socat OPENSSL options for target's sni, ca_file and
+// insecure_skip_verify (all validated when the config is loaded)
+func tlsOptions(target config.TargetConfig) string {
+	var opts string
+	if target.SNI != "" {
+		opts += ",snihost=" + target.SNI + ",commonname=" + target.SNI
+	}
+	if target.CAFile != "" {
+		opts += ",cafile=" + target.CAFile
+	}
+	if target.InsecureSkipVerify {
+		opts += ",verify=0"
+	} else {
+		opts += ",verify=1"
+	}
+	return opts
+}
+
+// failoverCommand tries each target in turn until one connects, announcing each
+// attempt on stderr so the handler knows which targets failed
+func failoverCommand(targets []config.TargetConfig) (string, error) {
+	timeout := fmt.Sprintf(",connect-timeout=%d", int(targetConnectTimeout.Seconds()))
+	attempts := make([]string, len(targets))
+	for i, target := range targets {
+		cmd, err := forwardCommand(target, timeout)
+		if err != nil {
+			return "", err
+		}
+		attempts[i] = fmt.Sprintf("(echo %s%d >&2; %s)", targetMarker, i, cmd)
+	}
+	return strings.Join(attempts, " || "), nil
+}
+
+// filterTargetAttempts handles the attempt announcements in a chunk of stderr and
+// returns the rest of it, trimmed
+func (h *JumpHandler) filterTargetAttempts(localPort int, chunk string) string {
+	if len(h.attempts) == 0 {
+		return strings.TrimSpace(chunk)
+	}
+	var rest []string
+	for _, line := range strings.Split(chunk, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !h.noteTargetAttempt(localPort, line) {
+			rest = append(rest, line)
+		}
+	}
+	return strings.Join(rest, "\n")
+}
+
+// noteTargetAttempt handles a stderr line announcing the attempt at h.attempts[i]:
+// the target tried before it failed. Reports whether line was such an announcement.
+func (h *JumpHandler) noteTargetAttempt(localPort int, line string) bool {
+	idx, ok := strings.CutPrefix(line, targetMarker)
+	if !ok {
+		return false
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil || i <= 0 || i >= len(h.attempts) {
+		return true
+	}
+
+	failed, next := h.attempts[i-1], h.attempts[i]
+	log.Printf("[jump:%d] Target %s:%d unreachable, trying %s:%d", localPort, failed.Host, failed.Port, next.Host, next.Port)
+	if h.health != nil {
+		h.health.markFailed(h.route, failed)
+	}
+	return true
+}
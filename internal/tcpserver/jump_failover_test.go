@@ -0,0 +1,97 @@
+package tcpserver
+
+import (
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func failoverRoute() config.JumpRouteConfig {
+	return config.JumpRouteConfig{
+		Context: "prod", Namespace: "default",
+		Via:       config.ViaConfig{Pod: "jump"},
+		Target:    config.TargetConfig{Host: "writer-a.internal", Port: 5432},
+		Fallbacks: []config.TargetConfig{{Host: "writer-b.internal", Port: 5432}},
+	}
+}
+
+func TestJumpHandler_buildForwardCommand_TargetList(t *testing.T) {
+	handler := NewJumpHandler(failoverRoute(), nil, nil, nil, false)
+
+	cmd, err := handler.buildForwardCommand()
+	if err != nil {
+		t.Fatalf("buildForwardCommand() error = %v", err)
+	}
+	want := "(echo autotunnel-target:0 >&2; socat -t30 - TCP:writer-a.internal:5432,connect-timeout=5 || nc writer-a.internal 5432)" +
+		" || (echo autotunnel-target:1 >&2; socat -t30 - TCP:writer-b.internal:5432,connect-timeout=5 || nc writer-b.internal 5432)"
+	if cmd != want {
+		t.Errorf("buildForwardCommand() =\n%s\nwant\n%s", cmd, want)
+	}
+}
+
+func TestJumpHandler_FailoverMovesFailedTargetLast(t *testing.T) {
+	route := failoverRoute()
+	health := newTargetHealth()
+
+	handler := NewJumpHandler(route, nil, nil, nil, false)
+	handler.health = health
+	if _, err := handler.buildForwardCommand(); err != nil {
+		t.Fatal(err)
+	}
+
+	// writer-a failed, the command moved on to writer-b
+	rest := handler.filterTargetAttempts(5432, "autotunnel-target:0\n2024/01/01 socat E connect(5, AF=2 10.0.0.1:5432, 16): Connection timed out\nautotunnel-target:1\n")
+	if rest != "2024/01/01 socat E connect(5, AF=2 10.0.0.1:5432, 16): Connection timed out" {
+		t.Errorf("filterTargetAttempts() = %q, want the announcements removed", rest)
+	}
+
+	order := health.order(route)
+	if order[0].Host != "writer-b.internal" || order[1].Host != "writer-a.internal" {
+		t.Errorf("order() = %+v, want writer-a moved last", order)
+	}
+
+	// the next connection tries writer-b first
+	next := NewJumpHandler(route, nil, nil, nil, false)
+	next.health = health
+	if _, err := next.buildForwardCommand(); err != nil {
+		t.Fatal(err)
+	}
+	if next.attempts[0].Host != "writer-b.internal" {
+		t.Errorf("attempts = %+v, want writer-b first", next.attempts)
+	}
+}
+
+func TestForwardCommand_TLS(t *testing.T) {
+	tests := []struct {
+		name   string
+		target config.TargetConfig
+		want   string
+	}{
+		{
+			name:   "verify with the pod's CAs",
+			target: config.TargetConfig{Host: "api.internal", Port: 443, TLS: true},
+			want:   "socat -t30 - OPENSSL:api.internal:443,verify=1",
+		},
+		{
+			name:   "sni and ca_file",
+			target: config.TargetConfig{Host: "10.0.1.5", Port: 6380, TLS: true, SNI: "cache.internal", CAFile: "/etc/ssl/internal-ca.pem"},
+			want:   "socat -t30 - OPENSSL:10.0.1.5:6380,snihost=cache.internal,commonname=cache.internal,cafile=/etc/ssl/internal-ca.pem,verify=1",
+		},
+		{
+			name:   "insecure",
+			target: config.TargetConfig{Host: "api.internal", Port: 443, TLS: true, InsecureSkipVerify: true},
+			want:   "socat -t30 - OPENSSL:api.internal:443,verify=0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := forwardCommand(tt.target, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("forwardCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
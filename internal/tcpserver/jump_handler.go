@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/atas/autotunnel/internal/config"
 	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/netutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -30,6 +33,14 @@ type JumpHandler struct {
 	clientset  kubernetes.Interface
 	restConfig *rest.Config
 	verbose    bool
+	noCreate   bool // --locked: use an existing via pod, never create it
+
+	pods  *jumpPods       // shared with the other jump routes; nil creates the pod unshared
+	tools *jumpToolChecks // containers known to have socat or nc; nil skips the check
+
+	health   *targetHealth         // recently failed targets; nil keeps the configured order
+	resolver *targetResolver       // dns_cache_ttl cache; nil connects by name
+	attempts []config.TargetConfig // order of a target list in the forward command
 }
 
 func NewJumpHandler(route config.JumpRouteConfig, kubeconfig []string, clientset kubernetes.Interface, restConfig *rest.Config, verbose bool) *JumpHandler {
@@ -57,78 +68,44 @@ func (h *JumpHandler) HandleConnection(ctx context.Context, conn net.Conn, local
 
 	if h.verbose {
 		if h.route.Via.Service != "" {
-			log.Printf("[jump:%d] Connecting via service %s (pod %s/%s) to %s:%d",
-				localPort, h.route.Via.Service, h.route.Namespace, podName, h.route.Target.Host, h.route.Target.Port)
+			log.Printf("[jump:%d] Connecting via service %s (pod %s/%s) to %s",
+				localPort, h.route.Via.Service, h.route.Namespace, podName, h.route.TargetDisplay())
 		} else {
-			log.Printf("[jump:%d] Connecting via pod %s/%s to %s:%d",
-				localPort, h.route.Namespace, podName, h.route.Target.Host, h.route.Target.Port)
+			log.Printf("[jump:%d] Connecting via pod %s/%s to %s",
+				localPort, h.route.Namespace, podName, h.route.TargetDisplay())
 		}
 	}
 
+	if err := h.ensureForwardTools(ctx, podName, containerName); err != nil {
+		return err
+	}
+	h.resolveTargets(ctx, podName, containerName)
+
 	cmd, err := h.buildForwardCommand()
 	if err != nil {
 		return fmt.Errorf("failed to build forward command: %w", err)
 	}
 
-	req := h.clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Namespace(h.route.Namespace).
-		Name(podName).
-		SubResource("exec")
-
-	execOpts := &corev1.PodExecOptions{
-		Command: []string{"sh", "-c", cmd},
-		Stdin:   true,
-		Stdout:  true,
-		Stderr:  true,
-		TTY:     false,
-	}
-	if containerName != "" {
-		execOpts.Container = containerName
-	}
-
-	req.VersionedParams(execOpts, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(h.restConfig, "POST", req.URL())
+	exec, err := h.newExecutor(podName, containerName, cmd, true)
 	if err != nil {
 		return fmt.Errorf("failed to create executor: %w", err)
 	}
 
-	stderrReader, stderrWriter := io.Pipe()
+	stderrWriter := h.logStderr(localPort)
 	defer stderrWriter.Close() // ensures cleanup even on panic
 
-	go func() {
-		defer stderrReader.Close()
-		buf := make([]byte, 4096)
-		for {
-			n, err := stderrReader.Read(buf)
-			if n > 0 {
-				stderrMsg := strings.TrimSpace(string(buf[:n]))
-				// Log connection errors non-verbose (these are important)
-				if isConnectionError(stderrMsg) {
-					log.Printf("[jump:%d] Connection error: %s", localPort, stderrMsg)
-				} else if h.verbose {
-					log.Printf("[jump:%d] stderr: %s", localPort, stderrMsg)
-				}
-			}
-			if err != nil {
-				return
-			}
-		}
-	}()
-
 	execCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	connWrapper := &connReadWriter{conn: conn, ctx: execCtx, cancel: cancel}
 
 	// Log successful tunnel start (non-verbose, matches TCP tunnel behavior)
-	log.Printf("Jump tunnel started: :%d via %s/%s -> %s:%d",
-		localPort, h.route.Namespace, podName, h.route.Target.Host, h.route.Target.Port)
+	log.Printf("Jump tunnel started: :%d via %s/%s -> %s",
+		localPort, h.route.Namespace, podName, h.route.TargetDisplay())
 
 	err = exec.StreamWithContext(execCtx, remotecommand.StreamOptions{
 		Stdin:  connWrapper,
-		Stdout: conn,
+		Stdout: netutil.PooledWriter(conn),
 		Stderr: stderrWriter,
 	})
 
@@ -145,6 +122,56 @@ func (h *JumpHandler) HandleConnection(ctx context.Context, conn net.Conn, local
 	return nil
 }
 
+// logStderr returns a writer whose lines are logged: connection errors always,
+// anything else with verbose. Close it when the stream ends.
+func (h *JumpHandler) logStderr(localPort int) *io.PipeWriter {
+	stderrReader, stderrWriter := io.Pipe()
+	go func() {
+		defer stderrReader.Close()
+		buf := make([]byte, 4096)
+		for {
+			n, err := stderrReader.Read(buf)
+			if n > 0 {
+				stderrMsg := h.filterTargetAttempts(localPort, string(buf[:n]))
+				// Log connection errors non-verbose (these are important)
+				if isConnectionError(stderrMsg) {
+					log.Printf("[jump:%d] Connection error: %s", localPort, stderrMsg)
+				} else if h.verbose && stderrMsg != "" {
+					log.Printf("[jump:%d] stderr: %s", localPort, stderrMsg)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return stderrWriter
+}
+
+// newExecutor prepares `sh -c cmd` in the jump pod's container
+func (h *JumpHandler) newExecutor(podName, containerName, cmd string, stdin bool) (remotecommand.Executor, error) {
+	req := h.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(h.route.Namespace).
+		Name(podName).
+		SubResource("exec")
+
+	execOpts := &corev1.PodExecOptions{
+		Command: []string{"sh", "-c", cmd},
+		Stdin:   stdin,
+		Stdout:  true,
+		Stderr:  true,
+		TTY:     false,
+	}
+	if containerName != "" {
+		execOpts.Container = containerName
+	}
+
+	req.VersionedParams(execOpts, scheme.ParameterCodec)
+
+	return remotecommand.NewSPDYExecutor(h.restConfig, "POST", req.URL())
+}
+
 type connReadWriter struct {
 	conn   net.Conn
 	ctx    context.Context
@@ -159,8 +186,10 @@ func (c *connReadWriter) Read(p []byte) (int, error) {
 	default:
 	}
 
+	// EOF is a half-close: the exec stream passes it on as the end of stdin and
+	// keeps delivering the target's output
 	n, err := c.conn.Read(p)
-	if err != nil {
+	if err != nil && err != io.EOF {
 		c.once.Do(c.cancel)
 	}
 	return n, err
@@ -170,6 +199,12 @@ func (c *connReadWriter) Write(p []byte) (int, error) {
 	return c.conn.Write(p)
 }
 
+// WriteTo lets the exec stream's io.Copy use a pooled buffer instead of allocating one.
+// netutil.Copy only sees Read, so this doesn't recurse.
+func (c *connReadWriter) WriteTo(w io.Writer) (int64, error) {
+	return netutil.Copy(w, c)
+}
+
 func (h *JumpHandler) discoverJumpPod(ctx context.Context) (podName string, containerName string, err error) {
 	containerName = h.route.Via.Container
 
@@ -197,32 +232,41 @@ func (h *JumpHandler) discoverJumpPod(ctx context.Context) (podName string, cont
 }
 
 func (h *JumpHandler) buildForwardCommand() (string, error) {
-	host := h.route.Target.Host
-	port := h.route.Target.Port
-
-	// defense-in-depth: validate host even though config validation should catch this
-	if !config.IsValidTargetHost(host) {
-		return "", fmt.Errorf("invalid target host %q: must be valid hostname or IP", host)
+	if len(h.route.Fallbacks) == 0 {
+		return forwardCommand(h.route.Target, "")
 	}
 
-	// Wrap IPv6 addresses in brackets for proper socat/nc syntax
-	// e.g., 2001:db8::1 becomes [2001:db8::1] to avoid ambiguity with port separator
-	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
-		host = "[" + host + "]"
+	// a target list: the ones that failed lately go last
+	h.attempts = h.route.Targets()
+	if h.health != nil {
+		h.attempts = h.health.order(h.route)
 	}
-
-	// try socat first (handles binary better), fall back to nc
-	// stderr is captured for error logging (connection refused, etc.)
-	return fmt.Sprintf("socat - TCP:%s:%d || nc %s %d", host, port, host, port), nil
+	return failoverCommand(h.attempts)
 }
 
-// ensureJumpPodExists checks if the jump pod exists, and creates it if via.create is configured
+// ensureJumpPodExists checks if the jump pod exists, and creates it if via.create is configured.
+// Concurrent calls for the same pod, from this route or another, share one check.
 func (h *JumpHandler) ensureJumpPodExists(ctx context.Context) error {
 	// If no create config, nothing to do
 	if h.route.Via.Create == nil {
 		return nil
 	}
+	if h.noCreate {
+		if _, err := h.clientset.CoreV1().Pods(h.route.Namespace).Get(ctx, h.route.Via.Pod, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("jump pod %s/%s: %w (via.create is disabled by --locked)", h.route.Namespace, h.route.Via.Pod, err)
+		}
+		return nil
+	}
+	if h.pods == nil {
+		_, err := h.createJumpPodIfMissing(ctx)
+		return err
+	}
+	return h.pods.ensure(ctx, h.route, h.createJumpPodIfMissing)
+}
 
+// createJumpPodIfMissing creates the jump pod unless it exists and waits for it to be
+// ready. Reports whether it created the pod.
+func (h *JumpHandler) createJumpPodIfMissing(ctx context.Context) (bool, error) {
 	podName := h.route.Via.Pod
 	namespace := h.route.Namespace
 
@@ -233,11 +277,11 @@ func (h *JumpHandler) ensureJumpPodExists(ctx context.Context) error {
 		if h.verbose {
 			log.Printf("[jump] Pod %s/%s already exists", namespace, podName)
 		}
-		return nil
+		return false, nil
 	}
 
 	if !errors.IsNotFound(err) {
-		return fmt.Errorf("failed to check if pod exists: %w", err)
+		return false, fmt.Errorf("failed to check if pod exists: %w", err)
 	}
 
 	// Pod doesn't exist, create it
@@ -248,9 +292,9 @@ func (h *JumpHandler) ensureJumpPodExists(ctx context.Context) error {
 	if err != nil {
 		// Check if it was created by another request in the meantime
 		if errors.IsAlreadyExists(err) {
-			return nil
+			return false, nil
 		}
-		return fmt.Errorf("failed to create jump pod: %w", err)
+		return false, fmt.Errorf("failed to create jump pod: %w", err)
 	}
 
 	// Wait for pod to be ready
@@ -259,12 +303,12 @@ func (h *JumpHandler) ensureJumpPodExists(ctx context.Context) error {
 		timeout = h.route.Via.Create.Timeout
 	}
 	if err := k8sutil.WaitForPodReady(ctx, h.clientset, h.route.Namespace, podName, timeout); err != nil {
-		return fmt.Errorf("jump pod not ready: %w", err)
+		return true, fmt.Errorf("jump pod not ready: %w", err)
 	}
 
 	log.Printf("[jump] Created jump pod %s/%s", namespace, podName)
 
-	return nil
+	return true, nil
 }
 
 // buildJumpPodSpec builds the Pod manifest for the jump pod
@@ -274,14 +318,22 @@ func (h *JumpHandler) buildJumpPodSpec() *corev1.Pod {
 		command = []string{"sleep", "infinity"}
 	}
 
-	return &corev1.Pod{
+	labels := map[string]string{
+		"app.kubernetes.io/name":       "autotunnel-jump",
+		"app.kubernetes.io/managed-by": "autotunnel",
+	}
+	if h.pods != nil {
+		// every jump route sharing the pod, not just the one that created it
+		for _, port := range h.pods.routes(h.route) {
+			labels[jumpRouteLabelPrefix+strconv.Itoa(port)] = "true"
+		}
+	}
+
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      h.route.Via.Pod,
 			Namespace: h.route.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "autotunnel-jump",
-				"app.kubernetes.io/managed-by": "autotunnel",
-			},
+			Labels:    labels,
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
@@ -304,6 +356,43 @@ func (h *JumpHandler) buildJumpPodSpec() *corev1.Pod {
 			RestartPolicy: corev1.RestartPolicyAlways,
 		},
 	}
+	applyCreateOptions(pod, h.route.Via.Create)
+	return pod
+}
+
+// applyCreateOptions sets via.create's optional settings on pod
+func applyCreateOptions(pod *corev1.Pod, create *config.CreateConfig) {
+	spec := &pod.Spec
+	container := &spec.Containers[0]
+
+	// scheduling and security
+	spec.NodeSelector = create.NodeSelector
+	spec.ServiceAccountName = create.ServiceAccountName
+	if create.Tolerations != nil {
+		spec.Tolerations = create.Tolerations.Value
+	}
+	if create.Affinity != nil {
+		spec.Affinity = &create.Affinity.Value
+	}
+	if create.SecurityContext != nil {
+		spec.SecurityContext = &create.SecurityContext.Value
+	}
+	if create.ContainerSecurityContext != nil {
+		container.SecurityContext = &create.ContainerSecurityContext.Value
+	}
+
+	// registries and quotas
+	for _, secret := range create.ImagePullSecrets {
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	if create.Resources != nil {
+		maps.Copy(container.Resources.Requests, create.Resources.Value.Requests)
+		maps.Copy(container.Resources.Limits, create.Resources.Value.Limits)
+	}
+	if create.Env != nil {
+		container.Env = create.Env.Value
+	}
+	pod.Annotations = create.Annotations
 }
 
 // isConnectionError checks if stderr output indicates a connection error
@@ -12,6 +12,7 @@ import (
 	"github.com/atas/autotunnel/internal/config"
 	"github.com/atas/autotunnel/internal/k8sutil"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
@@ -288,35 +289,35 @@ func TestJumpHandler_buildForwardCommand(t *testing.T) {
 			name:       "standard host and port",
 			targetHost: "database.internal",
 			targetPort: 5432,
-			wantSocat:  "socat - TCP:database.internal:5432",
+			wantSocat:  "socat -t30 - TCP:database.internal:5432",
 			wantNc:     "nc database.internal 5432",
 		},
 		{
 			name:       "localhost target",
 			targetHost: "127.0.0.1",
 			targetPort: 3306,
-			wantSocat:  "socat - TCP:127.0.0.1:3306",
+			wantSocat:  "socat -t30 - TCP:127.0.0.1:3306",
 			wantNc:     "nc 127.0.0.1 3306",
 		},
 		{
 			name:       "RDS endpoint",
 			targetHost: "mydb.cluster-xyz.us-east-1.rds.amazonaws.com",
 			targetPort: 5432,
-			wantSocat:  "socat - TCP:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
+			wantSocat:  "socat -t30 - TCP:mydb.cluster-xyz.us-east-1.rds.amazonaws.com:5432",
 			wantNc:     "nc mydb.cluster-xyz.us-east-1.rds.amazonaws.com 5432",
 		},
 		{
 			name:       "IPv6 address",
 			targetHost: "2001:db8::1",
 			targetPort: 5432,
-			wantSocat:  "socat - TCP:[2001:db8::1]:5432",
+			wantSocat:  "socat -t30 - TCP:[2001:db8::1]:5432",
 			wantNc:     "nc [2001:db8::1] 5432",
 		},
 		{
 			name:       "IPv6 loopback",
 			targetHost: "::1",
 			targetPort: 3306,
-			wantSocat:  "socat - TCP:[::1]:3306",
+			wantSocat:  "socat -t30 - TCP:[::1]:3306",
 			wantNc:     "nc [::1] 3306",
 		},
 	}
@@ -504,8 +505,9 @@ func TestConnReadWriter_ReadErrorCancelsContext(t *testing.T) {
 		cancel: cancel,
 	}
 
-	// Close client side to cause read error
-	client.Close()
+	defer client.Close()
+	// Close our own side to cause a read error other than EOF
+	server.Close()
 
 	buf := make([]byte, 10)
 	_, err := crw.Read(buf)
@@ -523,6 +525,31 @@ func TestConnReadWriter_ReadErrorCancelsContext(t *testing.T) {
 	}
 }
 
+func TestConnReadWriter_EOFKeepsContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	crw := &connReadWriter{
+		conn:   server,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	// the client half-closing must not end the exec stream
+	client.Close()
+
+	buf := make([]byte, 10)
+	if _, err := crw.Read(buf); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("Context should stay open after EOF")
+	}
+}
+
 func TestConnReadWriter_OnlyOneCancelOnMultipleErrors(t *testing.T) {
 	client, server := net.Pipe()
 	defer server.Close()
@@ -711,6 +738,39 @@ func TestJumpHandler_ensureJumpPodExists_CreatesPod(t *testing.T) {
 	}
 }
 
+func TestJumpHandler_ensureJumpPodExists_Locked(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		t.Error("expected no pod to be created when locked")
+		return true, nil, nil
+	})
+
+	route := config.JumpRouteConfig{
+		Context:   "test-context",
+		Namespace: "test-ns",
+		Via: config.ViaConfig{
+			Pod:    "autotunnel-jump",
+			Create: &config.CreateConfig{Image: "alpine:3.19"},
+		},
+		Target: config.TargetConfig{Host: "database.internal", Port: 5432},
+	}
+	handler := NewJumpHandler(route, nil, clientset, nil, false)
+	handler.noCreate = true
+
+	err := handler.ensureJumpPodExists(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "disabled by --locked") {
+		t.Errorf("Expected a --locked error for a missing pod, got %v", err)
+	}
+
+	// a pod created by someone else is used
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "autotunnel-jump", Namespace: "test-ns"}}
+	handler = NewJumpHandler(route, nil, fake.NewSimpleClientset(pod), nil, false)
+	handler.noCreate = true
+	if err := handler.ensureJumpPodExists(context.Background()); err != nil {
+		t.Errorf("ensureJumpPodExists with an existing pod: %v", err)
+	}
+}
+
 func TestJumpHandler_buildJumpPodSpec(t *testing.T) {
 	route := config.JumpRouteConfig{
 		Context:   "test-context",
@@ -815,6 +875,90 @@ func TestJumpHandler_buildJumpPodSpec_CustomCommand(t *testing.T) {
 	}
 }
 
+func TestJumpHandler_buildJumpPodSpec_Placement(t *testing.T) {
+	runAsNonRoot, noEscalation := true, false
+	route := config.JumpRouteConfig{
+		Context:   "test-context",
+		Namespace: "test-ns",
+		Via: config.ViaConfig{
+			Pod: "autotunnel-jump",
+			Create: &config.CreateConfig{
+				Image:              "alpine:3.19",
+				NodeSelector:       map[string]string{"network/vpc-routes": "true"},
+				ServiceAccountName: "jump",
+				Tolerations: &config.KubeObject[[]corev1.Toleration]{Value: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "egress", Effect: corev1.TaintEffectNoSchedule},
+				}},
+				Affinity:                 &config.KubeObject[corev1.Affinity]{Value: corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}},
+				SecurityContext:          &config.KubeObject[corev1.PodSecurityContext]{Value: corev1.PodSecurityContext{RunAsNonRoot: &runAsNonRoot}},
+				ContainerSecurityContext: &config.KubeObject[corev1.SecurityContext]{Value: corev1.SecurityContext{AllowPrivilegeEscalation: &noEscalation}},
+			},
+		},
+		Target: config.TargetConfig{Host: "database.internal", Port: 5432},
+	}
+
+	spec := NewJumpHandler(route, nil, nil, nil, false).buildJumpPodSpec().Spec
+
+	if spec.NodeSelector["network/vpc-routes"] != "true" || spec.ServiceAccountName != "jump" {
+		t.Errorf("NodeSelector = %v, ServiceAccountName = %q", spec.NodeSelector, spec.ServiceAccountName)
+	}
+	if len(spec.Tolerations) != 1 || spec.Tolerations[0].Value != "egress" {
+		t.Errorf("Tolerations = %+v", spec.Tolerations)
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil {
+		t.Error("expected affinity to be set")
+	}
+	if spec.SecurityContext == nil || !*spec.SecurityContext.RunAsNonRoot {
+		t.Error("expected pod security context to be set")
+	}
+	if sc := spec.Containers[0].SecurityContext; sc == nil || *sc.AllowPrivilegeEscalation {
+		t.Error("expected container security context to be set")
+	}
+}
+
+func TestJumpHandler_buildJumpPodSpec_RegistryAndQuota(t *testing.T) {
+	route := config.JumpRouteConfig{
+		Context:   "test-context",
+		Namespace: "test-ns",
+		Via: config.ViaConfig{
+			Pod: "autotunnel-jump",
+			Create: &config.CreateConfig{
+				Image:            "registry.internal/socat:1",
+				ImagePullSecrets: []string{"regcred"},
+				Resources: &config.KubeObject[corev1.ResourceRequirements]{Value: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")},
+				}},
+				Env:         &config.KubeObject[[]corev1.EnvVar]{Value: []corev1.EnvVar{{Name: "HTTP_PROXY", Value: "http://proxy:3128"}}},
+				Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+			},
+		},
+		Target: config.TargetConfig{Host: "database.internal", Port: 5432},
+	}
+
+	pod := NewJumpHandler(route, nil, nil, nil, false).buildJumpPodSpec()
+	container := pod.Spec.Containers[0]
+
+	if len(pod.Spec.ImagePullSecrets) != 1 || pod.Spec.ImagePullSecrets[0].Name != "regcred" {
+		t.Errorf("ImagePullSecrets = %+v", pod.Spec.ImagePullSecrets)
+	}
+	// the memory limit is replaced, the other defaults are kept
+	if got := container.Resources.Limits[corev1.ResourceMemory]; got.String() != "32Mi" {
+		t.Errorf("memory limit = %s, want 32Mi", got.String())
+	}
+	if got := container.Resources.Limits[corev1.ResourceCPU]; got.String() != "100m" {
+		t.Errorf("cpu limit = %s, want the 100m default", got.String())
+	}
+	if got := container.Resources.Requests[corev1.ResourceMemory]; got.String() != "16Mi" {
+		t.Errorf("memory request = %s, want the 16Mi default", got.String())
+	}
+	if len(container.Env) != 1 || container.Env[0].Name != "HTTP_PROXY" {
+		t.Errorf("Env = %+v", container.Env)
+	}
+	if pod.Annotations["sidecar.istio.io/inject"] != "false" {
+		t.Errorf("Annotations = %v", pod.Annotations)
+	}
+}
+
 func TestWaitForPodReady_Timeout(t *testing.T) {
 	// Create a pod that never becomes ready
 	pod := &corev1.Pod{
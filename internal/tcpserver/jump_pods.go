@@ -0,0 +1,158 @@
+package tcpserver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// Jump pod states reported by JumpPods()
+const (
+	jumpPodPending  = "pending"  // not checked yet
+	jumpPodCreating = "creating" // being looked up or created and waited on
+	jumpPodReady    = "ready"
+	jumpPodFailed   = "failed"
+)
+
+// jumpRouteLabelPrefix marks a created jump pod with each jump route using it,
+// e.g. autotunnel/jump-port-5432: "true"
+const jumpRouteLabelPrefix = "autotunnel/jump-port-"
+
+// JumpPodInfo describes a jump pod managed through via.create for the status API
+type JumpPodInfo struct {
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Routes    []int     `json:"routes"` // local ports of the jump routes using it
+	State     string    `json:"state"`
+	Created   bool      `json:"created,omitempty"` // autotunnel created it, rather than finding it
+	LastError string    `json:"last_error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
+// jumpPods lets the jump routes sharing a via.create pod share its creation and
+// ready wait: the first connection does it, the others wait for its result
+// instead of racing to create the same pod.
+type jumpPods struct {
+	mu       sync.Mutex
+	pods     map[string]*JumpPodInfo
+	inflight map[string]*jumpPodCall
+}
+
+type jumpPodCall struct {
+	done chan struct{}
+	err  error
+}
+
+// newJumpPods registers the pods of the jump routes that set via.create
+func newJumpPods(routes map[int]config.JumpRouteConfig) *jumpPods {
+	p := &jumpPods{
+		pods:     make(map[string]*JumpPodInfo),
+		inflight: make(map[string]*jumpPodCall),
+	}
+	for port, route := range routes {
+		if route.Via.Create == nil {
+			continue
+		}
+		key := jumpPodKey(route)
+		info, ok := p.pods[key]
+		if !ok {
+			info = &JumpPodInfo{Context: route.Context, Namespace: route.Namespace, Pod: route.Via.Pod, State: jumpPodPending}
+			p.pods[key] = info
+		}
+		info.Routes = append(info.Routes, port)
+	}
+	for _, info := range p.pods {
+		sort.Ints(info.Routes)
+	}
+	return p
+}
+
+// jumpPodKey identifies a jump pod across routes
+func jumpPodKey(route config.JumpRouteConfig) string {
+	return fmt.Sprintf("%s/%s/%s", route.Context, route.Namespace, route.Via.Pod)
+}
+
+// routes returns the local ports of the jump routes using route's pod
+func (p *jumpPods) routes(route config.JumpRouteConfig) []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if info, ok := p.pods[jumpPodKey(route)]; ok {
+		return info.Routes
+	}
+	return nil
+}
+
+// ensure runs ensureFn for route's pod unless a call for the same pod is already
+// in flight, in which case it waits for that call's result
+func (p *jumpPods) ensure(ctx context.Context, route config.JumpRouteConfig, ensureFn func(context.Context) (created bool, err error)) error {
+	key := jumpPodKey(route)
+
+	p.mu.Lock()
+	if call, ok := p.inflight[key]; ok {
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &jumpPodCall{done: make(chan struct{})}
+	p.inflight[key] = call
+	info, ok := p.pods[key]
+	if !ok {
+		info = &JumpPodInfo{Context: route.Context, Namespace: route.Namespace, Pod: route.Via.Pod}
+		p.pods[key] = info
+	}
+	info.State = jumpPodCreating
+	p.mu.Unlock()
+
+	created, err := ensureFn(ctx)
+
+	p.mu.Lock()
+	delete(p.inflight, key)
+	info.CheckedAt = time.Now()
+	info.Created = info.Created || created
+	if err != nil {
+		info.State = jumpPodFailed
+		info.LastError = err.Error()
+	} else {
+		info.State = jumpPodReady
+		info.LastError = ""
+	}
+	p.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// list returns every managed jump pod, sorted by context, namespace and name
+func (p *jumpPods) list() []JumpPodInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := make([]string, 0, len(p.pods))
+	for key := range p.pods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pods := make([]JumpPodInfo, 0, len(keys))
+	for _, key := range keys {
+		info := *p.pods[key]
+		info.Routes = append([]int(nil), info.Routes...)
+		pods = append(pods, info)
+	}
+	return pods
+}
+
+// JumpPods returns the jump pods managed through via.create
+func (s *Server) JumpPods() []JumpPodInfo {
+	return s.jumpPods.list()
+}
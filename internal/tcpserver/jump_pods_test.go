@@ -0,0 +1,122 @@
+package tcpserver
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func sharedJumpRoutes() map[int]config.JumpRouteConfig {
+	via := config.ViaConfig{Pod: "autotunnel-jump", Create: &config.CreateConfig{Image: "alpine:3.19"}}
+	return map[int]config.JumpRouteConfig{
+		5432: {Context: "test", Namespace: "ns", Via: via, Target: config.TargetConfig{Host: "db.internal", Port: 5432}},
+		6379: {Context: "test", Namespace: "ns", Via: via, Target: config.TargetConfig{Host: "cache.internal", Port: 6379}},
+		8080: {Context: "test", Namespace: "ns", Via: config.ViaConfig{Service: "bastion"}, Target: config.TargetConfig{Host: "api.internal", Port: 80}},
+	}
+}
+
+func TestJumpPods_SharedCreation(t *testing.T) {
+	routes := sharedJumpRoutes()
+	pods := newJumpPods(routes)
+
+	var creates atomic.Int32
+	var mu sync.Mutex
+	var created *corev1.Pod
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		creates.Add(1)
+		time.Sleep(50 * time.Millisecond) // leave the other connections time to pile up
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod).DeepCopy()
+		pod.Status = corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		}
+		mu.Lock()
+		created = pod
+		mu.Unlock()
+		return false, nil, nil
+	})
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if created != nil {
+			return true, created, nil
+		}
+		return false, nil, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		port := []int{5432, 6379}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler := NewJumpHandler(routes[port], nil, clientset, nil, false)
+			handler.pods = pods
+			if err := handler.ensureJumpPodExists(context.Background()); err != nil {
+				t.Errorf("ensureJumpPodExists(%d) error = %v", port, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := creates.Load(); n != 1 {
+		t.Errorf("Expected the shared pod to be created once, got %d creates", n)
+	}
+
+	mu.Lock()
+	labels := created.Labels
+	mu.Unlock()
+	for _, key := range []string{"autotunnel/jump-port-5432", "autotunnel/jump-port-6379"} {
+		if labels[key] != "true" {
+			t.Errorf("Expected label %s on the shared pod, got labels %v", key, labels)
+		}
+	}
+	if _, ok := labels["autotunnel/jump-port-8080"]; ok {
+		t.Error("Expected no label for a route through another pod")
+	}
+
+	list := pods.list()
+	if len(list) != 1 {
+		t.Fatalf("Expected 1 managed jump pod (via.service routes excluded), got %+v", list)
+	}
+	got := list[0]
+	if got.Pod != "autotunnel-jump" || got.State != jumpPodReady || !got.Created {
+		t.Errorf("JumpPods() = %+v", got)
+	}
+	if len(got.Routes) != 2 || got.Routes[0] != 5432 || got.Routes[1] != 6379 {
+		t.Errorf("Routes = %v, want [5432 6379]", got.Routes)
+	}
+}
+
+func TestJumpPods_ReportsFailure(t *testing.T) {
+	routes := sharedJumpRoutes()
+	pods := newJumpPods(routes)
+	if got := pods.list()[0].State; got != jumpPodPending {
+		t.Errorf("State before the first connection = %q, want %q", got, jumpPodPending)
+	}
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, context.DeadlineExceeded
+	})
+	handler := NewJumpHandler(routes[5432], nil, clientset, nil, false)
+	handler.pods = pods
+
+	if err := handler.ensureJumpPodExists(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	got := pods.list()[0]
+	if got.State != jumpPodFailed || got.LastError == "" || got.Created {
+		t.Errorf("JumpPods() after a failed check = %+v", got)
+	}
+}
@@ -0,0 +1,248 @@
+package tcpserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/netutil"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// warmStream is an exec stream to a jump route's target opened before a client
+// connection needs it. Output arriving before the connection is attached (a
+// server greeting, say) is buffered.
+type warmStream struct {
+	podName string
+	stdin   *io.PipeWriter
+	out     *attachableWriter
+	cancel  context.CancelFunc
+	opened  time.Time
+
+	done chan struct{} // closed when the exec stream ends
+	err  error         // set before done is closed
+}
+
+// alive reports whether the exec stream is still running
+func (ws *warmStream) alive() bool {
+	select {
+	case <-ws.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// serve forwards conn over the stream until either side closes
+func (ws *warmStream) serve(conn net.Conn) error {
+	ws.out.attach(conn)
+	go func() {
+		if _, err := netutil.Copy(ws.stdin, conn); err != nil {
+			ws.cancel() // the client is gone, like connReadWriter does
+			return
+		}
+		_ = ws.stdin.Close() // a half-close: the target still gets to answer
+	}()
+	<-ws.done
+	return ws.err
+}
+
+// attachableWriter buffers writes until a destination is attached
+type attachableWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	w   io.Writer
+}
+
+func (a *attachableWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.w == nil {
+		return a.buf.Write(p)
+	}
+	return a.w.Write(p)
+}
+
+func (a *attachableWriter) attach(w io.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, _ = a.buf.WriteTo(w)
+	a.w = w
+}
+
+// openWarmStream starts an exec stream to the target with nothing attached yet
+func (h *JumpHandler) openWarmStream(ctx context.Context, localPort int) (*warmStream, error) {
+	if h.restConfig == nil {
+		return nil, fmt.Errorf("restConfig is nil, cannot create SPDY executor")
+	}
+	podName, containerName, err := h.discoverJumpPod(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover jump pod: %w", err)
+	}
+	if err := h.ensureForwardTools(ctx, podName, containerName); err != nil {
+		return nil, err
+	}
+	h.resolveTargets(ctx, podName, containerName)
+	cmd, err := h.buildForwardCommand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build forward command: %w", err)
+	}
+	exec, err := h.newExecutor(podName, containerName, cmd, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	stdinReader, stdinWriter := io.Pipe()
+	ws := &warmStream{
+		podName: podName,
+		stdin:   stdinWriter,
+		out:     &attachableWriter{},
+		cancel:  cancel,
+		opened:  time.Now(),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		stderrWriter := h.logStderr(localPort)
+		err := exec.StreamWithContext(streamCtx, remotecommand.StreamOptions{
+			Stdin:  stdinReader,
+			Stdout: ws.out,
+			Stderr: stderrWriter,
+		})
+		stderrWriter.Close()
+		stdinReader.Close()
+		if err != nil && streamCtx.Err() == nil {
+			ws.err = fmt.Errorf("exec stream failed: %w", err)
+		}
+		cancel()
+		close(ws.done)
+	}()
+	return ws, nil
+}
+
+// jumpPool keeps up to size warm streams for one jump route. It refills after a
+// connection takes a stream, and streams nobody takes within maxAge are closed
+// without replacement, so an idle route holds nothing open.
+type jumpPool struct {
+	localPort int
+	size      int
+	maxAge    time.Duration
+	open      func(ctx context.Context) (*warmStream, error)
+	verbose   bool
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+
+	mu      sync.Mutex
+	ready   []*warmStream
+	opening int
+}
+
+func newJumpPool(ctx context.Context, wg *sync.WaitGroup, localPort int, route config.JumpRouteConfig, open func(context.Context) (*warmStream, error), verbose bool) *jumpPool {
+	return &jumpPool{
+		localPort: localPort,
+		size:      route.Pool,
+		maxAge:    route.GetPoolMaxAge(),
+		open:      open,
+		verbose:   verbose,
+		ctx:       ctx,
+		wg:        wg,
+	}
+}
+
+// take returns the most recently opened live stream, or nil if there is none
+func (p *jumpPool) take() *warmStream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.ready) > 0 {
+		ws := p.ready[len(p.ready)-1]
+		p.ready = p.ready[:len(p.ready)-1]
+		if ws.alive() && time.Since(ws.opened) < p.maxAge {
+			return ws
+		}
+		ws.cancel()
+	}
+	return nil
+}
+
+// refill opens streams in the background until the pool is full
+func (p *jumpPool) refill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.ready)+p.opening < p.size {
+		p.opening++
+		p.wg.Add(1)
+		go p.openOne()
+	}
+}
+
+func (p *jumpPool) openOne() {
+	defer p.wg.Done()
+
+	ws, err := p.open(p.ctx)
+
+	p.mu.Lock()
+	p.opening--
+	if err != nil || p.ctx.Err() != nil {
+		p.mu.Unlock()
+		if err != nil && p.verbose {
+			log.Printf("[jump:%d] Failed to open pooled stream: %v", p.localPort, err)
+		}
+		if ws != nil {
+			ws.cancel()
+		}
+		return
+	}
+	p.ready = append(p.ready, ws)
+	p.mu.Unlock()
+
+	// close it if no connection takes it in time
+	timer := time.NewTimer(p.maxAge)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		p.expire(ws)
+	case <-ws.done:
+	}
+}
+
+// expire closes ws if it is still waiting in the pool
+func (p *jumpPool) expire(ws *warmStream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, ready := range p.ready {
+		if ready == ws {
+			p.ready = append(p.ready[:i], p.ready[i+1:]...)
+			ws.cancel()
+			return
+		}
+	}
+}
+
+// jumpPoolFor returns the pool of the jump route on localPort, creating it on first use
+func (s *Server) jumpPoolFor(localPort int, route config.JumpRouteConfig) *jumpPool {
+	s.jumpPoolsMu.Lock()
+	defer s.jumpPoolsMu.Unlock()
+
+	if pool, ok := s.jumpPools[localPort]; ok {
+		return pool
+	}
+	open := func(ctx context.Context) (*warmStream, error) {
+		handler, err := s.newJumpHandler(route)
+		if err != nil {
+			return nil, err
+		}
+		return handler.openWarmStream(ctx, localPort)
+	}
+	pool := newJumpPool(s.ctx, &s.wg, localPort, route, open, s.verbose)
+	s.jumpPools[localPort] = pool
+	return pool
+}
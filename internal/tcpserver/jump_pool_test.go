@@ -0,0 +1,130 @@
+package tcpserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// fakeWarmStream is a warm stream whose "target" sends greeting, then echoes
+func fakeWarmStream(ctx context.Context, greeting string) *warmStream {
+	streamCtx, cancel := context.WithCancel(ctx)
+	stdinReader, stdinWriter := io.Pipe()
+	ws := &warmStream{
+		podName: "jump",
+		stdin:   stdinWriter,
+		out:     &attachableWriter{},
+		cancel:  cancel,
+		opened:  time.Now(),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(ws.done)
+		go func() {
+			<-streamCtx.Done()
+			stdinReader.Close()
+		}()
+		_, _ = ws.out.Write([]byte(greeting))
+		_, _ = io.Copy(ws.out, stdinReader)
+	}()
+	return ws
+}
+
+func waitReady(t *testing.T, p *jumpPool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		ready := len(p.ready)
+		p.mu.Unlock()
+		if ready == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("pool never had %d ready streams", n)
+}
+
+func TestJumpPool_TakeAndRefill(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() { cancel(); wg.Wait() }()
+
+	var opened atomic.Int32
+	open := func(ctx context.Context) (*warmStream, error) {
+		opened.Add(1)
+		return fakeWarmStream(ctx, "hello\n"), nil
+	}
+	p := newJumpPool(ctx, &wg, 5432, config.JumpRouteConfig{Pool: 2, PoolMaxAge: time.Minute}, open, false)
+
+	if ws := p.take(); ws != nil {
+		t.Fatal("Expected an empty pool before first use")
+	}
+	p.refill()
+	waitReady(t, p, 2)
+
+	ws := p.take()
+	if ws == nil {
+		t.Fatal("Expected a warm stream")
+	}
+	p.refill()
+	waitReady(t, p, 2)
+	if n := opened.Load(); n != 3 {
+		t.Errorf("opened %d streams, want 3 (2 + 1 refill)", n)
+	}
+
+	// the greeting sent before the client arrived is delivered, then it echoes
+	client, server := net.Pipe()
+	go func() { _ = ws.serve(server) }()
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(client, buf); err != nil || string(buf) != "hello\n" {
+		t.Fatalf("greeting = %q, %v", buf, err)
+	}
+	if _, err := client.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(client, buf[:4]); err != nil || string(buf[:4]) != "ping" {
+		t.Errorf("echo = %q, %v", buf[:4], err)
+	}
+	client.Close()
+
+	select {
+	case <-ws.done:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the stream to end once the client closed")
+	}
+}
+
+func TestJumpPool_ExpiresUnclaimedStreams(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() { cancel(); wg.Wait() }()
+
+	open := func(ctx context.Context) (*warmStream, error) {
+		return fakeWarmStream(ctx, ""), nil
+	}
+	p := newJumpPool(ctx, &wg, 5432, config.JumpRouteConfig{Pool: 1, PoolMaxAge: 50 * time.Millisecond}, open, false)
+
+	p.refill()
+	waitReady(t, p, 1)
+	p.mu.Lock()
+	ws := p.ready[0]
+	p.mu.Unlock()
+
+	waitReady(t, p, 0)
+	select {
+	case <-ws.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the expired stream to be closed")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if p.take() != nil {
+		t.Error("Expected expired streams not to be replaced until a connection uses the pool")
+	}
+}
@@ -0,0 +1,120 @@
+package tcpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+// forwardToolsCommand exits non-zero when the container has neither forwarder
+const forwardToolsCommand = "command -v socat || command -v nc"
+
+// tlsToolsCommand exits non-zero without socat, the only forwarder that does TLS
+const tlsToolsCommand = "command -v socat"
+
+// forwardToolsTimeout bounds the check, so a stuck exec doesn't hold the connection
+const forwardToolsTimeout = 10 * time.Second
+
+// jumpToolChecks remembers the jump pod containers found to have socat or nc, so
+// a route is checked on its first connection rather than on every one
+type jumpToolChecks struct {
+	mu sync.Mutex
+	ok map[string]bool // context/namespace/pod/container
+}
+
+func newJumpToolChecks() *jumpToolChecks {
+	return &jumpToolChecks{ok: make(map[string]bool)}
+}
+
+// ensure runs check unless key already passed. Failures are not remembered: the
+// image may be fixed, or the error may have been transient.
+func (c *jumpToolChecks) ensure(key string, check func() error) error {
+	c.mu.Lock()
+	ok := c.ok[key]
+	c.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	if err := check(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.ok[key] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// ensureForwardTools checks once per container that socat or nc is installed, so a
+// jump pod without them fails with an error saying so instead of a dead stream
+func (h *JumpHandler) ensureForwardTools(ctx context.Context, podName, containerName string) error {
+	if h.tools == nil {
+		return nil
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s", h.route.Context, h.route.Namespace, podName, containerName)
+	if h.route.UsesTLS() {
+		key += "/tls"
+	}
+	return h.tools.ensure(key, func() error {
+		return h.checkForwardTools(ctx, podName, containerName)
+	})
+}
+
+func (h *JumpHandler) checkForwardTools(ctx context.Context, podName, containerName string) error {
+	cmd := forwardToolsCommand
+	if h.route.UsesTLS() {
+		cmd = tlsToolsCommand
+	}
+	_, stderr, err := h.runCommand(ctx, podName, containerName, cmd, forwardToolsTimeout)
+	return forwardToolsError(err, h.route.Namespace, podName, containerName, stderr, h.route.UsesTLS())
+}
+
+// runCommand runs a short `sh -c cmd` in the jump pod and returns its trimmed output
+func (h *JumpHandler) runCommand(ctx context.Context, podName, containerName, cmd string, timeout time.Duration) (stdout, stderr string, err error) {
+	exec, err := h.newExecutor(podName, containerName, cmd, false)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var outBuf, errBuf bytes.Buffer
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &outBuf, Stderr: &errBuf})
+	return strings.TrimSpace(outBuf.String()), strings.TrimSpace(errBuf.String()), err
+}
+
+// forwardToolsError turns the check's result into an actionable error: nil when a
+// forwarder was found, a "not installed" error when the command ran and failed
+func forwardToolsError(err error, namespace, podName, containerName, stderr string, needSocat bool) error {
+	if err == nil {
+		return nil
+	}
+
+	container := "the default container"
+	if containerName != "" {
+		container = fmt.Sprintf("container %q", containerName)
+	}
+
+	var exitErr utilexec.ExitError
+	if !errors.As(err, &exitErr) {
+		return fmt.Errorf("failed to check for socat/nc in %s of pod %s/%s: %w", container, namespace, podName, err)
+	}
+	msg := fmt.Sprintf("socat and nc are both missing from %s of pod %s/%s; jump routes need one of them. "+
+		"Use an image that has socat (e.g. alpine/socat), or set via.container to a container that does", container, namespace, podName)
+	if needSocat {
+		msg = fmt.Sprintf("socat is missing from %s of pod %s/%s; target.tls needs it (nc can't do TLS). "+
+			"Use an image that has socat (e.g. alpine/socat), or set via.container to a container that does", container, namespace, podName)
+	}
+	if stderr != "" {
+		msg += " (" + stderr + ")"
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,50 @@
+package tcpserver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func TestJumpToolChecks_RemembersSuccessOnly(t *testing.T) {
+	c := newJumpToolChecks()
+	calls := 0
+	failing := func() error { calls++; return errors.New("missing") }
+	passing := func() error { calls++; return nil }
+
+	if err := c.ensure("ctx/ns/pod/", failing); err == nil {
+		t.Fatal("Expected the failed check's error")
+	}
+	if err := c.ensure("ctx/ns/pod/", passing); err != nil {
+		t.Fatalf("ensure() error = %v", err)
+	}
+	if err := c.ensure("ctx/ns/pod/", failing); err != nil {
+		t.Errorf("Expected a passed check not to run again, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("check ran %d times, want 2", calls)
+	}
+}
+
+func TestForwardToolsError(t *testing.T) {
+	if err := forwardToolsError(nil, "ns", "jump", "", "", false); err != nil {
+		t.Errorf("Expected nil when a forwarder was found, got %v", err)
+	}
+
+	err := forwardToolsError(utilexec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}, "ns", "jump", "tools", "", false)
+	if err == nil || !strings.Contains(err.Error(), `socat and nc are both missing from container "tools" of pod ns/jump`) {
+		t.Errorf("missing tools error = %v", err)
+	}
+
+	err = forwardToolsError(utilexec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}, "ns", "jump", "", "", true)
+	if err == nil || !strings.Contains(err.Error(), "socat is missing from the default container of pod ns/jump; target.tls needs it") {
+		t.Errorf("missing socat for TLS error = %v", err)
+	}
+
+	err = forwardToolsError(errors.New("connection refused"), "ns", "jump", "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "failed to check for socat/nc in the default container") {
+		t.Errorf("exec failure error = %v", err)
+	}
+}
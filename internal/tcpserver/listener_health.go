@@ -0,0 +1,148 @@
+package tcpserver
+
+import (
+	"log"
+	"net"
+	"sort"
+	"time"
+)
+
+// Listener states reported by Listeners()
+const (
+	listenerListening = "listening"
+	listenerRetrying  = "retrying"  // accept failed, backing off
+	listenerRebinding = "rebinding" // closed after repeated failures, binding the port again
+)
+
+// maxAcceptErrors is how many accept errors in a row a listener tolerates
+// before it is closed and bound again
+const maxAcceptErrors = 10
+
+// rebindMaxDelay caps the wait between attempts to bind a broken listener's port again
+const rebindMaxDelay = 30 * time.Second
+
+// ListenerHealth describes one TCP listener for the status API
+type ListenerHealth struct {
+	Port         int    `json:"port"` // bound local port
+	State        string `json:"state"`
+	AcceptErrors int    `json:"accept_errors,omitempty"` // total since start
+	Restarts     int    `json:"restarts,omitempty"`      // times the port was bound again
+	LastError    string `json:"last_error,omitempty"`
+	OpenConns    int    `json:"open_conns"` // client connections being served
+}
+
+// Listeners returns the health of every TCP listener, sorted by port
+func (s *Server) Listeners() []ListenerHealth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	health := make([]ListenerHealth, 0, len(s.listeners))
+	for _, pl := range s.listeners {
+		health = append(health, ListenerHealth{
+			Port:         boundPort(pl.listener),
+			State:        pl.state,
+			AcceptErrors: pl.acceptErrors,
+			Restarts:     pl.restarts,
+			LastError:    pl.lastError,
+			OpenConns:    int(pl.openConns.Load()),
+		})
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Port < health[j].Port })
+	return health
+}
+
+// acceptRetryDelay backs off from 5ms up to 1s between failed accepts
+func acceptRetryDelay(n int) time.Duration {
+	return min(5*time.Millisecond<<(n-1), time.Second)
+}
+
+// recordAcceptError tracks a failed accept. Only the first of a run is logged
+// (every one with verbose), so a broken listener doesn't flood the log.
+func (s *Server) recordAcceptError(pl *portListener, err error, failures int) {
+	s.mu.Lock()
+	pl.state = listenerRetrying
+	pl.acceptErrors++
+	pl.lastError = err.Error()
+	s.mu.Unlock()
+
+	if failures == 1 || s.verbose {
+		log.Printf("[tcp:%d] Accept error: %v", pl.port, err)
+	}
+}
+
+func (s *Server) setListenerState(pl *portListener, state string) {
+	s.mu.Lock()
+	pl.state = state
+	s.mu.Unlock()
+}
+
+// rebind closes pl's listener and binds its address again, backing off until it
+// succeeds or the listener is stopped. Returns false once stopped.
+func (s *Server) rebind(pl *portListener, cause error) bool {
+	addr := pl.listener.Addr().String()
+	log.Printf("[tcp:%d] Listener on %s keeps failing (%v), binding it again", pl.port, addr, cause)
+
+	s.setListenerState(pl, listenerRebinding)
+	pl.listener.Close()
+
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		listener, err := net.Listen("tcp", addr)
+		if err == nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.listenerStopped(pl) {
+				listener.Close()
+				return false
+			}
+			pl.listener = listener
+			pl.state = listenerListening
+			pl.restarts++
+			log.Printf("[tcp:%d] Listener on %s is back", pl.port, addr)
+			return true
+		}
+
+		s.mu.Lock()
+		pl.lastError = err.Error()
+		s.mu.Unlock()
+		if attempt == 1 || s.verbose {
+			log.Printf("[tcp:%d] Failed to bind %s again (retrying): %v", pl.port, addr, err)
+		}
+
+		if !s.sleepUnlessStopped(pl, delay) {
+			return false
+		}
+		delay = min(delay*2, rebindMaxDelay)
+	}
+}
+
+// listenerStopped reports whether pl or the whole server was shut down
+func (s *Server) listenerStopped(pl *portListener) bool {
+	select {
+	case <-pl.stopChan:
+		return true
+	case <-s.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepUnlessStopped waits d, returning false if pl is stopped first
+func (s *Server) sleepUnlessStopped(pl *portListener, d time.Duration) bool {
+	select {
+	case <-pl.stopChan:
+		return false
+	case <-s.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func boundPort(l net.Listener) int {
+	if addr, ok := l.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
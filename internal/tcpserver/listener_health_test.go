@@ -0,0 +1,76 @@
+package tcpserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// failingListener fails every Accept, like a listener broken by sleep/wake
+type failingListener struct {
+	net.Listener
+}
+
+func (l failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("accept: network is down")
+}
+
+func TestServer_BrokenListenerIsRebound(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19750: {Context: "test", Namespace: "ns", Service: "healthy", Port: 80},
+		19751: {Context: "test", Namespace: "ns", Service: "broken", Port: 80},
+	})
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{}})
+	bound, err := s.preflight()
+	if err != nil {
+		t.Fatalf("preflight failed: %v", err)
+	}
+	defer s.Shutdown()
+
+	s.startListener(19750, listenerTypeRoute, bound[19750])
+	s.startListener(19751, listenerTypeRoute, failingListener{bound[19751]})
+
+	// the other route keeps accepting while 19751 fails
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19750", time.Second)
+	if err != nil {
+		t.Fatalf("Healthy listener stopped accepting: %v", err)
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(10 * time.Second)
+	var health []ListenerHealth
+	for time.Now().Before(deadline) {
+		health = s.Listeners()
+		if len(health) == 2 && health[1].Restarts == 1 && health[1].State == listenerListening {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(health) != 2 || health[1].Port != 19751 || health[1].Restarts != 1 || health[1].State != listenerListening {
+		t.Fatalf("Expected 19751 to be bound again, got %+v", health)
+	}
+	if health[1].AcceptErrors != maxAcceptErrors || health[1].LastError == "" {
+		t.Errorf("Expected %d accept errors with the last error, got %+v", maxAcceptErrors, health[1])
+	}
+	if health[0].State != listenerListening || health[0].AcceptErrors != 0 {
+		t.Errorf("Expected the healthy listener untouched, got %+v", health[0])
+	}
+
+	conn, err = net.DialTimeout("tcp", "127.0.0.1:19751", time.Second)
+	if err != nil {
+		t.Fatalf("Rebound listener not accepting: %v", err)
+	}
+	conn.Close()
+}
+
+func TestAcceptRetryDelay(t *testing.T) {
+	if d := acceptRetryDelay(1); d != 5*time.Millisecond {
+		t.Errorf("acceptRetryDelay(1) = %v, want 5ms", d)
+	}
+	if d := acceptRetryDelay(30); d != time.Second {
+		t.Errorf("acceptRetryDelay(30) = %v, want 1s cap", d)
+	}
+}
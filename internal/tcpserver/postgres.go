@@ -0,0 +1,208 @@
+package tcpserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/atas/autotunnel/internal/netutil"
+)
+
+// PostgreSQL startup codes, see "Message Formats" in the PostgreSQL docs
+const (
+	pgProtocolVersion3 = 196608   // 3.0
+	pgCancelRequest    = 80877102 // sent on a new connection, no database to route by
+	pgSSLRequest       = 80877103
+	pgGSSENCRequest    = 80877104
+
+	pgMaxStartupLength = 10000 // the server's own limit
+	pgStartupTimeout   = 10 * time.Second
+)
+
+// pgStartup is the parameters of a PostgreSQL StartupMessage in the client's order
+type pgStartup struct {
+	params [][2]string
+}
+
+func (m *pgStartup) get(key string) string {
+	for _, p := range m.params {
+		if p[0] == key {
+			return p[1]
+		}
+	}
+	return ""
+}
+
+func (m *pgStartup) set(key, value string) {
+	for i, p := range m.params {
+		if p[0] == key {
+			m.params[i][1] = value
+			return
+		}
+	}
+	m.params = append(m.params, [2]string{key, value})
+}
+
+// database is the database the client asked for, which defaults to the user name
+func (m *pgStartup) database() string {
+	if db := m.get("database"); db != "" {
+		return db
+	}
+	return m.get("user")
+}
+
+func (m *pgStartup) encode() []byte {
+	var body bytes.Buffer
+	_ = binary.Write(&body, binary.BigEndian, uint32(pgProtocolVersion3))
+	for _, p := range m.params {
+		body.WriteString(p[0])
+		body.WriteByte(0)
+		body.WriteString(p[1])
+		body.WriteByte(0)
+	}
+	body.WriteByte(0)
+
+	msg := binary.BigEndian.AppendUint32(nil, uint32(body.Len()+4))
+	return append(msg, body.Bytes()...)
+}
+
+// readPGStartup reads the client's StartupMessage. SSL and GSS encryption requests
+// are declined first, so the client goes on in plaintext: the database name is
+// needed to pick the backend, and the port-forward to it is encrypted anyway.
+func readPGStartup(conn io.ReadWriter) (*pgStartup, error) {
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		code := binary.BigEndian.Uint32(header[4:])
+		if length < 8 || length > pgMaxStartupLength {
+			return nil, fmt.Errorf("invalid startup message length %d", length)
+		}
+
+		body := make([]byte, length-8)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return nil, err
+		}
+
+		switch code {
+		case pgSSLRequest, pgGSSENCRequest:
+			if _, err := conn.Write([]byte{'N'}); err != nil {
+				return nil, err
+			}
+			continue
+		case pgCancelRequest:
+			return nil, errors.New("cancel requests can't be routed by database")
+		case pgProtocolVersion3:
+			return parsePGParams(body)
+		default:
+			return nil, fmt.Errorf("unsupported protocol version %d.%d", code>>16, code&0xffff)
+		}
+	}
+}
+
+// parsePGParams parses the name/value pairs of a StartupMessage
+func parsePGParams(body []byte) (*pgStartup, error) {
+	fields := bytes.Split(body, []byte{0})
+	// pairs, then the terminating empty name and the empty tail after it
+	if len(fields) < 2 || len(fields)%2 != 0 || len(fields[len(fields)-1]) != 0 || len(fields[len(fields)-2]) != 0 {
+		return nil, errors.New("malformed startup parameters")
+	}
+	m := &pgStartup{}
+	for i := 0; i+1 < len(fields)-2; i += 2 {
+		m.params = append(m.params, [2]string{string(fields[i]), string(fields[i+1])})
+	}
+	return m, nil
+}
+
+// writePGError sends a FATAL ErrorResponse, which psql and drivers show to the user
+func writePGError(w io.Writer, sqlState, message string) {
+	var body bytes.Buffer
+	for _, field := range []struct {
+		kind  byte
+		value string
+	}{{'S', "FATAL"}, {'V', "FATAL"}, {'C', sqlState}, {'M', "autotunnel: " + message}} {
+		body.WriteByte(field.kind)
+		body.WriteString(field.value)
+		body.WriteByte(0)
+	}
+	body.WriteByte(0)
+
+	msg := append([]byte{'E'}, binary.BigEndian.AppendUint32(nil, uint32(body.Len()+4))...)
+	_, _ = w.Write(append(msg, body.Bytes()...))
+}
+
+// handlePostgresConnection reads which database the client wants and forwards it
+// to the route's service in the namespace named by it
+func (s *Server) handlePostgresConnection(pl *portListener, conn net.Conn) {
+	defer conn.Close()
+
+	localPort := pl.port
+	route := s.config.TCP.K8s.Postgres[localPort]
+
+	_ = conn.SetDeadline(time.Now().Add(pgStartupTimeout))
+	startup, err := readPGStartup(conn)
+	if err != nil {
+		if s.verbose {
+			log.Printf("[pg:%d] Failed to read startup message: %v", localPort, err)
+		}
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	requested := startup.database()
+	target, database, err := route.Resolve(requested)
+	if err != nil {
+		log.Printf("[pg:%d] %v", localPort, err)
+		writePGError(conn, "3D000", err.Error()) // invalid_catalog_name
+		return
+	}
+	startup.set("database", database)
+
+	tunnel, err := s.manager.GetOrCreatePostgresTunnel(localPort, target)
+	if err == nil && !tunnel.IsRunning() {
+		err = tunnel.Start(s.ctx) // bounded by the route's ready_timeout
+	}
+	if err != nil {
+		log.Printf("[pg:%d] Failed to start tunnel to %s/%s: %v", localPort, target.Namespace, target.Service, err)
+		writePGError(conn, "08001", fmt.Sprintf("no tunnel to %s/%s: %v", target.Namespace, target.Service, err))
+		return
+	}
+
+	backendPort := tunnel.LocalPortFor(target.Port)
+	if backendPort == 0 {
+		log.Printf("[pg:%d] Tunnel to %s/%s stopped before connecting", localPort, target.Namespace, target.Service)
+		writePGError(conn, "08001", "the tunnel stopped before connecting")
+		return
+	}
+	backendAddr := fmt.Sprintf("127.0.0.1:%d", backendPort)
+	backend, err := net.DialTimeout("tcp", backendAddr, 10*time.Second)
+	if err != nil {
+		log.Printf("[pg:%d] Failed to connect to backend %s: %v", localPort, backendAddr, err)
+		writePGError(conn, "08001", "failed to connect through the tunnel")
+		return
+	}
+	defer backend.Close()
+
+	if _, err := backend.Write(startup.encode()); err != nil {
+		log.Printf("[pg:%d] Failed to send startup message: %v", localPort, err)
+		return
+	}
+
+	tunnel.Touch()
+	if tracker, ok := tunnel.(connTracker); ok {
+		defer tracker.TrackConn()()
+	}
+	if s.verbose {
+		log.Printf("[pg:%d] Connection for database %q -> %s/%s:%d (database %q)",
+			localPort, requested, target.Namespace, target.Service, target.Port, database)
+	}
+
+	netutil.BidirectionalCopy(backend, conn)
+}
@@ -0,0 +1,130 @@
+package tcpserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// pgRequest builds an SSLRequest-style message: length and code, no body
+func pgRequest(code uint32) []byte {
+	msg := binary.BigEndian.AppendUint32(nil, 8)
+	return binary.BigEndian.AppendUint32(msg, code)
+}
+
+func TestReadPGStartup(t *testing.T) {
+	want := &pgStartup{params: [][2]string{{"user", "app"}, {"database", "preview-42"}, {"application_name", "psql"}}}
+
+	var client bytes.Buffer
+	client.Write(pgRequest(pgSSLRequest))
+	client.Write(want.encode())
+	var replies bytes.Buffer
+	conn := struct {
+		io.Reader
+		io.Writer
+	}{&client, &replies}
+
+	got, err := readPGStartup(conn)
+	if err != nil {
+		t.Fatalf("readPGStartup() error = %v", err)
+	}
+	if replies.String() != "N" {
+		t.Errorf("Expected the SSL request to be declined with N, got %q", replies.String())
+	}
+	if got.database() != "preview-42" || got.get("application_name") != "psql" {
+		t.Errorf("Parsed %+v", got.params)
+	}
+	if !bytes.Equal(got.encode(), want.encode()) {
+		t.Error("Expected the message to encode back to the same bytes")
+	}
+
+	// no database: postgres uses the user name
+	if db := (&pgStartup{params: [][2]string{{"user", "preview-7"}}}).database(); db != "preview-7" {
+		t.Errorf("database() = %q, want the user name", db)
+	}
+
+	if _, err := readPGStartup(bytes.NewBuffer(pgRequest(pgCancelRequest))); err == nil {
+		t.Error("Expected cancel requests to be refused")
+	}
+}
+
+func TestServer_PostgresRoute(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	received := make(chan *pgStartup, 1)
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		startup, _ := readPGStartup(conn)
+		received <- startup
+		_, _ = conn.Write([]byte("R"))
+	}()
+
+	cfg := testConfig(nil)
+	cfg.TCP.K8s.Postgres = map[int]config.PostgresRouteConfig{
+		19795: {Context: "dev", Service: "postgres", Port: 5432, NamespacePrefix: "pr-"},
+	}
+	mgr := &mockManager{tunnelToReturn: &sharedMockTunnel{
+		localPorts: map[int]int{5432: backend.Addr().(*net.TCPAddr).Port},
+	}}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19795", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	startup := &pgStartup{params: [][2]string{{"user", "app"}, {"database", "42/orders"}}}
+	_, _ = conn.Write(startup.encode())
+
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(conn, buf); err != nil || buf[0] != 'R' {
+		t.Fatalf("Expected the backend's reply, got %q (%v)", buf, err)
+	}
+	if got := <-received; got.get("database") != "orders" || got.get("user") != "app" {
+		t.Errorf("Backend got %+v, want database orders", got.params)
+	}
+	if len(mgr.postgresRoutes) != 1 || mgr.postgresRoutes[0].Namespace != "pr-42" {
+		t.Errorf("Expected a tunnel to namespace pr-42, got %+v", mgr.postgresRoutes)
+	}
+}
+
+func TestServer_PostgresRouteRejectsBadDatabase(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.TCP.K8s.Postgres = map[int]config.PostgresRouteConfig{
+		19796: {Context: "dev", Service: "postgres", Port: 5432},
+	}
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{}})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19796", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	_, _ = conn.Write((&pgStartup{params: [][2]string{{"user", "app"}, {"database", "Not_A_Namespace"}}}).encode())
+
+	reply, _ := io.ReadAll(conn)
+	if len(reply) == 0 || reply[0] != 'E' || !strings.Contains(string(reply), "doesn't name a namespace") {
+		t.Errorf("Expected an ErrorResponse, got %q", reply)
+	}
+}
@@ -5,27 +5,64 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/atas/autotunnel/internal/authz"
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/diag"
+	"github.com/atas/autotunnel/internal/k8sutil"
 	"github.com/atas/autotunnel/internal/netutil"
+	"github.com/atas/autotunnel/internal/tunnelmgr"
 )
 
 type listenerType int
 
 const (
-	listenerTypeRoute listenerType = iota // direct port-forward
-	listenerTypeJump                      // jump-host via exec+socat/nc
+	listenerTypeRoute    listenerType = iota // direct port-forward
+	listenerTypeJump                         // jump-host via exec+socat/nc
+	listenerTypeGroup                        // one port of a group's shared port-forward
+	listenerTypePostgres                     // port-forward picked by the requested database
+	listenerTypeEcho                         // tcp.echo_port, no tunnel at all
 )
 
 type Server struct {
-	config  *config.Config
-	manager Manager
-	verbose bool
+	config     *config.Config
+	manager    Manager
+	verbose    bool
+	listenHost string // TCP routes only ever listen on loopback (tcp.listen_host)
+	authz      *authz.Policy
 
-	mu        sync.RWMutex
-	listeners map[int]*portListener
+	mu         sync.RWMutex
+	listeners  map[int]*portListener     // keyed by configured port, even when remapped
+	remapped   map[int]int               // configured port -> bound port
+	execRoutes map[string]bool           // routes switched to exec after port-forward was forbidden
+	shared     map[int]config.SharedPort // extra_ports local port -> owning route and target port
+	reserved   map[int]bool              // local ports that are configured or already bound
+
+	groupPorts map[string][]int       // group name -> service ports, once resolved
+	groupLocal map[string]map[int]int // group name -> service port -> bound local port
+
+	fileSDMu sync.Mutex // serializes tcp.prometheus_file_sd writes
+
+	jumpPods   *jumpPods       // via.create pods shared by jump routes
+	jumpTools  *jumpToolChecks // jump pod containers checked for socat/nc
+	jumpHealth *targetHealth   // jump targets that recently failed to connect
+	jumpDNS    *targetResolver // jump target addresses cached with dns_cache_ttl
+
+	jumpPoolsMu sync.Mutex
+	jumpPools   map[int]*jumpPool // jump routes with pool set, by local port
+
+	restarts *tunnelRestarts // tunnels restarted after a stream went stale
+
+	discoverMu   sync.Mutex                    // serializes tcp.k8s.discover changes
+	discoveredBy map[string]int                // context/namespace/service -> local port (guarded by discoverMu)
+	discoveredMu sync.RWMutex                  // guards discovered; never held while taking another lock
+	discovered   map[int]config.TCPRouteConfig // discovered routes by local port
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -37,104 +74,324 @@ type portListener struct {
 	listenerType listenerType
 	listener     net.Listener
 	stopChan     chan struct{}
+
+	group      string // listenerTypeGroup only
+	targetPort int    // listenerTypeGroup only: service port to reach
+
+	// health, guarded by Server.mu
+	state        string
+	acceptErrors int
+	restarts     int
+	lastError    string
+
+	openConns atomic.Int32 // client connections being served
+}
+
+// connTracker is implemented by tunnels that stay open while tracked connections are
+type connTracker interface {
+	TrackConn() (release func())
 }
 
 func NewServer(cfg *config.Config, mgr Manager) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		config:    cfg,
-		manager:   mgr,
-		verbose:   cfg.Verbose,
-		listeners: make(map[int]*portListener),
-		ctx:       ctx,
-		cancel:    cancel,
+		config:     cfg,
+		manager:    mgr,
+		verbose:    cfg.Verbose,
+		listenHost: cfg.TCP.GetListenHost(),
+		listeners:  make(map[int]*portListener),
+		remapped:   make(map[int]int),
+		execRoutes: make(map[string]bool),
+		shared:     cfg.TCP.K8s.SharedPorts(),
+		reserved:   make(map[int]bool),
+
+		groupPorts: make(map[string][]int),
+		groupLocal: make(map[string]map[int]int),
+		jumpPods:   newJumpPods(cfg.TCP.K8s.Jump),
+		jumpTools:  newJumpToolChecks(),
+		jumpHealth: newTargetHealth(),
+		jumpDNS:    newTargetResolver(),
+		jumpPools:  make(map[int]*jumpPool),
+		restarts:   newTunnelRestarts(),
+
+		discoveredBy: make(map[string]int),
+		discovered:   make(map[int]config.TCPRouteConfig),
+
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
 func (s *Server) Start() error {
-	for port := range s.config.TCP.K8s.Routes {
-		if err := s.startListener(port, listenerTypeRoute); err != nil {
-			s.Shutdown()
-			return err
-		}
+	bound, err := s.preflight()
+	if err != nil {
+		return err
 	}
 
-	for port := range s.config.TCP.K8s.Jump {
-		if err := s.startListener(port, listenerTypeJump); err != nil {
-			s.Shutdown()
-			return err
+	for port, listener := range bound {
+		lt := listenerTypeRoute
+		if _, isJump := s.config.TCP.K8s.Jump[port]; isJump {
+			lt = listenerTypeJump
+		}
+		if _, isPostgres := s.config.TCP.K8s.Postgres[port]; isPostgres {
+			lt = listenerTypePostgres
 		}
+		if port == s.config.TCP.EchoPort {
+			lt = listenerTypeEcho
+		}
+		s.startListener(port, lt, listener)
 	}
+	s.writeFileSD()
+
+	s.startGroups()
+	s.startDiscovery()
 
 	return nil
 }
 
-func (s *Server) startListener(port int, lt listenerType) error {
-	addr := fmt.Sprintf("127.0.0.1:%d", port)
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on TCP port %d: %w", port, err)
+// preflight binds every configured route and jump port before any of them starts
+// serving, so all conflicts are reported at once together with the process that
+// owns the port. With tcp.auto_remap_ports a busy port moves to the next free one.
+func (s *Server) preflight() (map[int]net.Listener, error) {
+	ports := make([]int, 0, len(s.config.TCP.K8s.Routes)+len(s.shared)+len(s.config.TCP.K8s.Jump))
+	for port := range s.config.TCP.K8s.Routes {
+		ports = append(ports, port)
+	}
+	for port := range s.shared {
+		ports = append(ports, port)
+	}
+	for port := range s.config.TCP.K8s.Jump {
+		ports = append(ports, port)
+	}
+	for port := range s.config.TCP.K8s.Postgres {
+		ports = append(ports, port)
+	}
+	if s.config.TCP.EchoPort != 0 {
+		ports = append(ports, s.config.TCP.EchoPort)
+	}
+	sort.Ints(ports)
+
+	// never remap onto another route's port, a group's configured port or the HTTP listener
+	s.mu.Lock()
+	reserved := s.reserved
+	for _, port := range ports {
+		reserved[port] = true
+	}
+	for _, group := range s.config.TCP.K8s.Groups {
+		for _, port := range group.Ports {
+			reserved[port] = true
+		}
+	}
+	if httpPort, err := netutil.ListenPort(s.config.HTTP.ListenAddr); err == nil {
+		reserved[httpPort] = true
+	}
+	s.mu.Unlock()
+
+	bound := make(map[int]net.Listener, len(ports))
+	var conflicts []string
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", net.JoinHostPort(s.listenHost, strconv.Itoa(port)))
+		if err == nil {
+			bound[port] = listener
+			continue
+		}
+		if !netutil.IsAddrInUse(err) {
+			closeAll(bound)
+			return nil, fmt.Errorf("failed to listen on TCP port %d: %w", port, err)
+		}
+
+		owner := netutil.DescribePortOwner(port)
+		if owner == "" {
+			owner = "another process"
+		}
+
+		if !s.config.TCP.AutoRemapPorts {
+			conflicts = append(conflicts, fmt.Sprintf("port %d is already in use by %s", port, owner))
+			continue
+		}
+
+		listener, newPort, err := netutil.ListenNextFree(s.listenHost, port, s.config.TCP.PortRange().Max, reserved)
+		if err != nil {
+			closeAll(bound)
+			return nil, fmt.Errorf("failed to remap TCP port %d: %w", port, err)
+		}
+		reserved[newPort] = true
+		bound[port] = listener
+
+		s.mu.Lock()
+		s.remapped[port] = newPort
+		s.mu.Unlock()
+		log.Printf("TCP port %d is already in use by %s, remapped to %d", port, owner, newPort)
 	}
 
-	pl := &portListener{
-		port:         port,
-		listenerType: lt,
-		listener:     listener,
-		stopChan:     make(chan struct{}),
+	if len(conflicts) > 0 {
+		closeAll(bound)
+		return nil, fmt.Errorf("TCP port conflict: %s (set tcp.auto_remap_ports: true to pick free ports automatically)",
+			strings.Join(conflicts, "; "))
+	}
+
+	return bound, nil
+}
+
+func (s *Server) startListener(port int, lt listenerType, listener net.Listener) {
+	var destStr string
+	switch lt {
+	case listenerTypeJump:
+		jumpCfg := s.config.TCP.K8s.Jump[port]
+		destStr = fmt.Sprintf("-> %s via %s/%s (jump)",
+			jumpCfg.TargetDisplay(), jumpCfg.Namespace, jumpCfg.Via.TargetDisplay())
+	case listenerTypePostgres:
+		pgCfg := s.config.TCP.K8s.Postgres[port]
+		destStr = fmt.Sprintf("-> %s<database>/%s:%d (postgres)", pgCfg.NamespacePrefix, pgCfg.Service, pgCfg.Port)
+	case listenerTypeEcho:
+		destStr = "(echo)"
+	default:
+		routePort, targetPort := s.routeTarget(port)
+		routeCfg := s.config.TCP.K8s.Routes[routePort]
+		destStr = fmt.Sprintf("-> %s/%s:%d", routeCfg.Namespace, routeCfg.TargetDisplay(), targetPort)
+		if routePort != port {
+			destStr += fmt.Sprintf(" (shares port %d's port-forward)", routePort)
+		}
 	}
 
+	s.serve(&portListener{port: port, listenerType: lt, listener: listener}, destStr)
+}
+
+// serve registers pl and starts accepting on it. Listeners bound after Shutdown
+// (group ports resolve in the background) are closed instead.
+func (s *Server) serve(pl *portListener, destStr string) {
+	pl.stopChan = make(chan struct{})
+	pl.state = listenerListening
+
 	s.mu.Lock()
-	s.listeners[port] = pl
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		pl.listener.Close()
+		return
+	}
+	s.listeners[pl.port] = pl
 	s.mu.Unlock()
 
 	s.wg.Add(1)
 	go s.acceptLoop(pl)
 
-	var destStr string
-	if lt == listenerTypeJump {
-		jumpCfg := s.config.TCP.K8s.Jump[port]
-		destStr = fmt.Sprintf("-> %s:%d via %s/%s (jump)",
-			jumpCfg.Target.Host, jumpCfg.Target.Port, jumpCfg.Namespace, jumpCfg.Via.TargetDisplay())
-	} else {
-		routeCfg := s.config.TCP.K8s.Routes[port]
-		destStr = fmt.Sprintf("-> %s/%s:%d", routeCfg.Namespace, routeCfg.TargetDisplay(), routeCfg.Port)
+	log.Printf("TCP listener started on %s %s", pl.listener.Addr(), destStr)
+}
+
+// RemappedPorts returns configured port -> actually bound port for routes moved by auto_remap_ports
+func (s *Server) RemappedPorts() map[int]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	remapped := make(map[int]int, len(s.remapped))
+	for from, to := range s.remapped {
+		remapped[from] = to
 	}
-	log.Printf("TCP listener started on %s %s", addr, destStr)
-	return nil
+	return remapped
 }
 
 func (s *Server) acceptLoop(pl *portListener) {
 	defer s.wg.Done()
 
+	failures := 0
 	for {
 		conn, err := pl.listener.Accept()
 		if err != nil {
-			select {
-			case <-pl.stopChan:
-				return
-			case <-s.ctx.Done():
+			if s.listenerStopped(pl) {
 				return
-			default:
-				if s.verbose {
-					log.Printf("[tcp:%d] Accept error: %v", pl.port, err)
+			}
+			// back off instead of spinning, and rebind once the listener looks broken;
+			// other listeners keep serving either way
+			failures++
+			s.recordAcceptError(pl, err, failures)
+			if failures < maxAcceptErrors {
+				if !s.sleepUnlessStopped(pl, acceptRetryDelay(failures)) {
+					return
 				}
 				continue
 			}
+			if !s.rebind(pl, err) {
+				return
+			}
+			failures = 0
+			continue
 		}
-
-		if pl.listenerType == listenerTypeJump {
-			go s.handleJumpConnection(pl.port, conn)
-		} else {
-			go s.handleConnection(pl.port, conn)
+		if failures > 0 {
+			failures = 0
+			s.setListenerState(pl, listenerListening)
 		}
+
+		go s.serveConn(pl, conn)
+	}
+}
+
+func (s *Server) serveConn(pl *portListener, conn net.Conn) {
+	defer diag.Recover(fmt.Sprintf("tcp:%d", pl.port))
+	pl.openConns.Add(1)
+	defer pl.openConns.Add(-1)
+
+	if !s.authorize(pl, conn) {
+		conn.Close()
+		return
+	}
+	applySocketConfig(conn, s.socketConfig(pl))
+
+	switch pl.listenerType {
+	case listenerTypeJump:
+		s.handleJumpConnection(pl.port, conn)
+	case listenerTypePostgres:
+		s.handlePostgresConnection(pl, conn)
+	case listenerTypeEcho:
+		handleEchoConnection(conn)
+	default:
+		s.handleConnection(pl, conn)
+	}
+}
+
+// routeTarget maps a listening port to the route owning its port-forward session
+// and the target port to reach through it
+func (s *Server) routeTarget(localPort int) (routePort, targetPort int) {
+	if shared, ok := s.shared[localPort]; ok {
+		return shared.RoutePort, shared.TargetPort
+	}
+	return localPort, s.tcpRoute(localPort).Port
+}
+
+// tunnelFor returns the tunnel serving a listener and the target port to reach
+// through it. extra_ports and group ports share a tunnel with other ports.
+func (s *Server) tunnelFor(pl *portListener) (tunnelmgr.TunnelHandle, int, error) {
+	if pl.listenerType == listenerTypeGroup {
+		s.mu.RLock()
+		ports := s.groupPorts[pl.group]
+		s.mu.RUnlock()
+		tunnel, err := s.manager.GetOrCreateGroupTunnel(pl.group, ports)
+		return tunnel, pl.targetPort, err
+	}
+
+	routePort, targetPort := s.routeTarget(pl.port)
+	tunnel, err := s.manager.GetOrCreateTCPTunnel(routePort)
+	return tunnel, targetPort, err
+}
+
+// connRoute returns the route whose connection limits apply to pl: the TCP route
+// for its own and extra ports, none for group ports
+func (s *Server) connRoute(pl *portListener) config.TCPRouteConfig {
+	if pl.listenerType == listenerTypeGroup {
+		return config.TCPRouteConfig{}
 	}
+	routePort, _ := s.routeTarget(pl.port)
+	return s.tcpRoute(routePort)
 }
 
-func (s *Server) handleConnection(localPort int, conn net.Conn) {
+func (s *Server) handleConnection(pl *portListener, conn net.Conn) {
 	defer conn.Close()
 
-	// Get or create tunnel for this port
-	tunnel, err := s.manager.GetOrCreateTCPTunnel(localPort)
+	localPort := pl.port
+	if s.usesExec(pl) {
+		s.handleExecConnection(pl, conn)
+		return
+	}
+
+	tunnel, targetPort, err := s.tunnelFor(pl)
 	if err != nil {
 		log.Printf("[tcp:%d] Failed to get tunnel: %v", localPort, err)
 		return
@@ -142,43 +399,77 @@ func (s *Server) handleConnection(localPort int, conn net.Conn) {
 
 	// Ensure tunnel is started
 	if !tunnel.IsRunning() {
-		ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
-		if err := tunnel.Start(ctx); err != nil {
-			cancel()
+		// bounded by the route's ready_timeout
+		if err := tunnel.Start(s.ctx); err != nil {
+			if s.fallBackToExec(pl, err) {
+				s.handleExecConnection(pl, conn)
+				return
+			}
 			log.Printf("[tcp:%d] Failed to start tunnel: %v", localPort, err)
 			return
 		}
-		cancel()
 	}
 
-	// Connect to tunnel's local port
-	backendAddr := fmt.Sprintf("127.0.0.1:%d", tunnel.LocalPort())
-	backend, err := net.DialTimeout("tcp", backendAddr, 10*time.Second)
+	// Connect to tunnel's local port for the target
+	backendPort := tunnel.LocalPortFor(targetPort)
+	if backendPort == 0 {
+		log.Printf("[tcp:%d] Tunnel stopped before connecting", localPort)
+		return
+	}
+	backendAddr := fmt.Sprintf("127.0.0.1:%d", backendPort)
+	route := s.connRoute(pl)
+	dialedAt := time.Now()
+	dialed, err := net.DialTimeout("tcp", backendAddr, route.GetConnectTimeout())
 	if err != nil {
 		log.Printf("[tcp:%d] Failed to connect to backend %s: %v", localPort, backendAddr, err)
 		return
 	}
+	// a stream to a restarted pod fails before the first byte: retry it once
+	backend := newRetryConn(dialed, s.redialer(pl, tunnel, targetPort, dialedAt))
 	defer backend.Close()
 
 	tunnel.Touch()
 
-	if s.verbose {
-		log.Printf("[tcp:%d] Connection established -> backend port %d", localPort, tunnel.LocalPort())
+	// a long session (psql, redis-cli) keeps the tunnel from idling out until it ends
+	if tracker, ok := tunnel.(connTracker); ok {
+		defer tracker.TrackConn()()
 	}
 
-	netutil.BidirectionalCopy(backend, conn)
-
 	if s.verbose {
+		log.Printf("[tcp:%d] Connection established -> backend port %d", localPort, backendPort)
+	}
+
+	limits := netutil.CopyLimits{Idle: route.ConnIdleTimeout, MaxDuration: route.ConnMaxDuration}
+	if reason := netutil.BidirectionalCopyLimited(backend, conn, limits); reason != "" {
+		log.Printf("[tcp:%d] Connection closed: %s reached", localPort, reason)
+	} else if s.verbose {
 		log.Printf("[tcp:%d] Connection closed", localPort)
 	}
 }
 
+// Forward serves conn as if it had arrived on the TCP or jump route listening on
+// port, without binding that port (autotunnel stdio). Group ports aren't supported.
+// It returns once the connection is done; failures past the route lookup are logged.
+func (s *Server) Forward(port int, conn net.Conn) error {
+	if _, isJump := s.config.TCP.K8s.Jump[port]; isJump {
+		s.handleJumpConnection(port, conn)
+		return nil
+	}
+	if _, isRoute := s.config.TCP.K8s.Routes[port]; !isRoute {
+		if _, isShared := s.shared[port]; !isShared {
+			conn.Close()
+			return fmt.Errorf("no TCP or jump route on port %d", port)
+		}
+	}
+	s.handleConnection(&portListener{port: port, listenerType: listenerTypeRoute}, conn)
+	return nil
+}
+
 func (s *Server) handleJumpConnection(localPort int, conn net.Conn) {
 	defer conn.Close()
 
 	s.mu.RLock()
 	route, exists := s.config.TCP.K8s.Jump[localPort]
-	kubeconfigs := s.config.TCP.K8s.ResolvedKubeconfigs
 	s.mu.RUnlock()
 
 	if !exists {
@@ -186,18 +477,61 @@ func (s *Server) handleJumpConnection(localPort int, conn net.Conn) {
 		return
 	}
 
-	clientset, restConfig, err := s.manager.GetClientForContext(kubeconfigs, route.Context)
+	s.runJump(localPort, route, conn)
+}
+
+// runJump forwards conn through kubectl exec + socat/nc as described by route
+func (s *Server) runJump(localPort int, route config.JumpRouteConfig, conn net.Conn) {
+	if route.Pool > 0 {
+		pool := s.jumpPoolFor(localPort, route)
+		ws := pool.take()
+		pool.refill()
+		if ws != nil {
+			if s.verbose {
+				log.Printf("[jump:%d] Using pooled stream via %s/%s", localPort, route.Namespace, ws.podName)
+			}
+			if err := ws.serve(conn); err != nil {
+				log.Printf("[jump:%d] Connection error: %v", localPort, err)
+			}
+			return
+		}
+	}
+
+	handler, err := s.newJumpHandler(route)
 	if err != nil {
-		log.Printf("[jump:%d] Failed to get K8s client: %v", localPort, err)
+		log.Printf("[jump:%d] Connection error: %v", localPort, err)
 		return
 	}
-
-	handler := NewJumpHandler(route, kubeconfigs, clientset, restConfig, s.verbose)
 	if err := handler.HandleConnection(s.ctx, conn, localPort); err != nil {
 		log.Printf("[jump:%d] Connection error: %v", localPort, err)
+		if k8sutil.IsUnauthorized(err) {
+			// the next connection gets a client with fresh credentials
+			log.Printf("[jump:%d] Credentials rejected by the API server, reloading the kubeconfig", localPort)
+			if _, _, err := s.manager.RenewClientForContext(s.config.TCP.K8s.ResolvedKubeconfigs, route.Context, route.ExecEnv, handler.restConfig); err != nil {
+				log.Printf("[jump:%d] Failed to renew credentials: %v", localPort, err)
+			}
+		}
 	}
 }
 
+// newJumpHandler returns a handler for route sharing the server's jump pod state
+func (s *Server) newJumpHandler(route config.JumpRouteConfig) (*JumpHandler, error) {
+	kubeconfigs := s.config.TCP.K8s.ResolvedKubeconfigs
+
+	clientset, restConfig, err := s.manager.GetClientForRoute(kubeconfigs, route.Context, route.ExecEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get K8s client: %w", err)
+	}
+
+	handler := NewJumpHandler(route, kubeconfigs, clientset, restConfig, s.verbose)
+	handler.noCreate = s.config.Locked
+	handler.pods = s.jumpPods
+	handler.tools = s.jumpTools
+	handler.health = s.jumpHealth
+	handler.resolver = s.jumpDNS
+	return handler, nil
+}
+
 func (s *Server) Shutdown() {
 	s.cancel()
 
@@ -211,5 +545,13 @@ func (s *Server) Shutdown() {
 	s.mu.Unlock()
 
 	s.wg.Wait()
+
+	// nothing is forwarded anymore: leave Prometheus an empty target list
+	s.writeFileSD()
 }
 
+func closeAll(listeners map[int]net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
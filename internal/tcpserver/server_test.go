@@ -1,11 +1,18 @@
 package tcpserver
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/diag"
+	"github.com/atas/autotunnel/internal/tunnel"
 	"github.com/atas/autotunnel/internal/tunnelmgr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -16,6 +23,8 @@ type mockManager struct {
 	getTCPTunnelCalls []int
 	tunnelToReturn    tunnelmgr.TunnelHandle
 	errorToReturn     error
+	groupPorts        map[string][]int // group name -> service ports
+	postgresRoutes    []config.TCPRouteConfig
 }
 
 func (m *mockManager) GetOrCreateTCPTunnel(port int) (tunnelmgr.TunnelHandle, error) {
@@ -23,11 +32,41 @@ func (m *mockManager) GetOrCreateTCPTunnel(port int) (tunnelmgr.TunnelHandle, er
 	return m.tunnelToReturn, m.errorToReturn
 }
 
+func (m *mockManager) GroupServicePorts(ctx context.Context, name string) ([]int, error) {
+	if ports, ok := m.groupPorts[name]; ok {
+		return ports, nil
+	}
+	return nil, fmt.Errorf("no service for group %s", name)
+}
+
+func (m *mockManager) GetOrCreateGroupTunnel(name string, ports []int) (tunnelmgr.TunnelHandle, error) {
+	return m.tunnelToReturn, m.errorToReturn
+}
+
 func (m *mockManager) GetClientForContext(kubeconfigPaths []string, contextName string) (*kubernetes.Clientset, *rest.Config, error) {
 	// Return nil for testing - jump tests would need more elaborate mocking
 	return nil, nil, nil
 }
 
+func (m *mockManager) GetClientForRoute(kubeconfigPaths []string, contextName string, execEnv map[string]string) (*kubernetes.Clientset, *rest.Config, error) {
+	return nil, nil, nil
+}
+
+func (m *mockManager) RenewClientForContext(kubeconfigPaths []string, contextName string, execEnv map[string]string, stale *rest.Config) (*kubernetes.Clientset, *rest.Config, error) {
+	return nil, nil, nil
+}
+
+func (m *mockManager) AddDiscoveredRoute(localPort int, route config.TCPRouteConfig) error {
+	return nil
+}
+
+func (m *mockManager) RemoveDiscoveredRoute(localPort int) {}
+
+func (m *mockManager) GetOrCreatePostgresTunnel(localPort int, route config.TCPRouteConfig) (tunnelmgr.TunnelHandle, error) {
+	m.postgresRoutes = append(m.postgresRoutes, route)
+	return m.tunnelToReturn, m.errorToReturn
+}
+
 // testConfig creates a config with TCP routes on high ports
 func testConfig(tcpRoutes map[int]config.TCPRouteConfig) *config.Config {
 	return &config.Config{
@@ -224,3 +263,374 @@ func TestServer_Start_MixedRoutesAndJump(t *testing.T) {
 	s.mu.RUnlock()
 }
 
+
+func TestServer_Start_PortConflictReportsAllPorts(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:19410")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer l1.Close()
+	l2, err := net.Listen("tcp", "127.0.0.1:19411")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer l2.Close()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19410: {Context: "test", Namespace: "ns", Service: "svc", Port: 80},
+		19411: {Context: "test", Namespace: "ns", Service: "svc2", Port: 81},
+		19412: {Context: "test", Namespace: "ns", Service: "svc3", Port: 82},
+	})
+	s := NewServer(cfg, &mockManager{})
+
+	err = s.Start()
+	if err == nil {
+		s.Shutdown()
+		t.Fatal("Expected error when starting server on occupied ports")
+	}
+	for _, want := range []string{"port 19410 is already in use", "port 19411 is already in use", "auto_remap_ports"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+
+	// the free port must not stay bound after a failed preflight
+	l3, err := net.Listen("tcp", "127.0.0.1:19412")
+	if err != nil {
+		t.Errorf("Expected port 19412 to be released after failed start: %v", err)
+	} else {
+		l3.Close()
+	}
+}
+
+func TestServer_Start_AutoRemapPorts(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:19420")
+	if err != nil {
+		t.Fatalf("Failed to bind test port: %v", err)
+	}
+	defer busy.Close()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19420: {Context: "test", Namespace: "ns", Service: "svc", Port: 80},
+		19421: {Context: "test", Namespace: "ns", Service: "svc2", Port: 81},
+	})
+	cfg.TCP.AutoRemapPorts = true
+	s := NewServer(cfg, &mockManager{})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Expected remap instead of error, got: %v", err)
+	}
+	defer s.Shutdown()
+
+	remapped := s.RemappedPorts()
+	newPort, ok := remapped[19420]
+	if !ok {
+		t.Fatalf("Expected port 19420 to be remapped, got %v", remapped)
+	}
+	// 19421 belongs to another route, so it must be skipped
+	if newPort == 19420 || newPort == 19421 {
+		t.Errorf("Expected remap to a free non-route port, got %d", newPort)
+	}
+	if _, ok := remapped[19421]; ok {
+		t.Error("Expected free port 19421 not to be remapped")
+	}
+
+	// listener is still tracked under the configured port
+	s.mu.RLock()
+	pl, ok := s.listeners[19420]
+	s.mu.RUnlock()
+	if !ok {
+		t.Fatal("Expected listener to be keyed by configured port")
+	}
+	if got := pl.listener.Addr().(*net.TCPAddr).Port; got != newPort {
+		t.Errorf("Expected listener bound to %d, got %d", newPort, got)
+	}
+}
+
+// sharedMockTunnel exposes one local port per target port. It is running unless
+// startErr is set, in which case Start fails with it.
+type sharedMockTunnel struct {
+	localPorts map[int]int
+	startErr   error
+}
+
+func (m *sharedMockTunnel) IsRunning() bool                 { return m.startErr == nil }
+func (m *sharedMockTunnel) Start(ctx context.Context) error { return m.startErr }
+func (m *sharedMockTunnel) Stop()                           {}
+func (m *sharedMockTunnel) LocalPort() int                  { return 0 }
+func (m *sharedMockTunnel) LocalPortFor(port int) int       { return m.localPorts[port] }
+func (m *sharedMockTunnel) Scheme() string                  { return "tcp" }
+func (m *sharedMockTunnel) Touch()                          {}
+func (m *sharedMockTunnel) IdleDuration() time.Duration     { return 0 }
+func (m *sharedMockTunnel) State() tunnel.State             { return tunnel.StateRunning }
+func (m *sharedMockTunnel) LastError() error                { return nil }
+
+func TestServer_ExtraPortsShareRouteTunnel(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	accepted := make(chan struct{})
+	go func() {
+		conn, err := backend.Accept()
+		if err == nil {
+			conn.Close()
+			close(accepted)
+		}
+	}()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19700: {Context: "test", Namespace: "ns", Service: "svc", Port: 8080, ExtraPorts: map[int]int{19701: 5005}},
+	})
+	mgr := &mockManager{tunnelToReturn: &sharedMockTunnel{localPorts: map[int]int{
+		5005: backend.Addr().(*net.TCPAddr).Port,
+	}}}
+	s := NewServer(cfg, mgr)
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19701", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect to extra port: %v", err)
+	}
+	defer conn.Close()
+
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Extra port connection did not reach the backend for its target port")
+	}
+
+	if routePort, targetPort := s.routeTarget(19701); routePort != 19700 || targetPort != 5005 {
+		t.Errorf("routeTarget(19701) = %d, %d; want 19700, 5005", routePort, targetPort)
+	}
+	if routePort, targetPort := s.routeTarget(19700); routePort != 19700 || targetPort != 8080 {
+		t.Errorf("routeTarget(19700) = %d, %d; want 19700, 8080", routePort, targetPort)
+	}
+}
+
+func TestServer_Start_IPv6ListenHost(t *testing.T) {
+	if ln, err := net.Listen("tcp", "[::1]:0"); err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	} else {
+		ln.Close()
+	}
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19740: {Context: "test", Namespace: "ns", Service: "svc", Port: 80},
+	})
+	cfg.TCP.ListenHost = "::1"
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{}})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "[::1]:19740", time.Second)
+	if err != nil {
+		t.Fatalf("Expected listener on [::1]:19740: %v", err)
+	}
+	conn.Close()
+}
+
+// trackingMockTunnel is a sharedMockTunnel that counts tracked connections
+type trackingMockTunnel struct {
+	sharedMockTunnel
+	open atomic.Int32
+}
+
+func (m *trackingMockTunnel) TrackConn() func() {
+	m.open.Add(1)
+	return func() { m.open.Add(-1) }
+}
+
+func TestServer_TracksOpenConnections(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 64)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+
+	tun := &trackingMockTunnel{sharedMockTunnel: sharedMockTunnel{
+		localPorts: map[int]int{5432: backend.Addr().(*net.TCPAddr).Port},
+	}}
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19750: {Context: "test", Namespace: "ns", Service: "postgres", Port: 5432},
+	})
+	s := NewServer(cfg, &mockManager{tunnelToReturn: tun})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19750", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	// a round trip means the connection is established through the tunnel
+	_, _ = conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := conn.Read(buf); err != nil {
+		t.Fatalf("Failed to read echo: %v", err)
+	}
+
+	if n := tun.open.Load(); n != 1 {
+		t.Errorf("Expected 1 tracked connection on the tunnel, got %d", n)
+	}
+	if health := s.Listeners(); len(health) != 1 || health[0].OpenConns != 1 {
+		t.Errorf("Expected listener to report 1 open connection, got %+v", health)
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for (tun.open.Load() != 0 || s.Listeners()[0].OpenConns != 0) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := tun.open.Load(); n != 0 {
+		t.Errorf("Expected the tunnel connection to be released, got %d open", n)
+	}
+	if n := s.Listeners()[0].OpenConns; n != 0 {
+		t.Errorf("Expected listener to report 0 open connections, got %d", n)
+	}
+}
+
+func TestServer_Forward(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("SSH-2.0-test\r\n"))
+	}()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19760: {Context: "test", Namespace: "ns", Service: "bastion", Port: 22},
+	})
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{
+		localPorts: map[int]int{22: backend.Addr().(*net.TCPAddr).Port},
+	}})
+	defer s.Shutdown()
+
+	// no listener is started: the connection is handed over directly
+	client, server := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- s.Forward(19760, server) }()
+
+	buf := make([]byte, 14)
+	if _, err := io.ReadFull(client, buf); err != nil {
+		t.Fatalf("Failed to read from the backend: %v", err)
+	}
+	if string(buf) != "SSH-2.0-test\r\n" {
+		t.Errorf("Read %q from the backend", buf)
+	}
+	client.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Forward() error = %v", err)
+	}
+
+	other, _ := net.Pipe()
+	if err := s.Forward(19761, other); err == nil {
+		t.Error("Expected an error for a port without a route")
+	}
+}
+
+func TestServer_RecoversConnectionPanic(t *testing.T) {
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19760: {Context: "test", Namespace: "ns", Service: "svc", Port: 80},
+	})
+	// a nil tunnel makes serving a connection panic
+	s := NewServer(cfg, &mockManager{})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	before := len(diag.Panics())
+	for i := 0; i < 2; i++ {
+		conn, err := net.DialTimeout("tcp", "127.0.0.1:19760", time.Second)
+		if err != nil {
+			t.Fatalf("connection %d: listener stopped accepting: %v", i, err)
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _ = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(diag.Panics()) < before+2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(diag.Panics()) - before; got != 2 {
+		t.Errorf("Expected 2 recovered panics, got %d", got)
+	}
+	if p := diag.Panics()[len(diag.Panics())-1]; p.Component != "tcp:19760" {
+		t.Errorf("panic component = %q, want tcp:19760", p.Component)
+	}
+}
+
+func TestServer_ClosesIdleConnections(t *testing.T) {
+	// the backend accepts and then hangs
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cfg := testConfig(map[int]config.TCPRouteConfig{
+		19775: {Context: "test", Namespace: "ns", Service: "hung", Port: 80, ConnIdleTimeout: 100 * time.Millisecond},
+	})
+	s := NewServer(cfg, &mockManager{tunnelToReturn: &sharedMockTunnel{
+		localPorts: map[int]int{80: backend.Addr().(*net.TCPAddr).Port},
+	}})
+	if err := s.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer s.Shutdown()
+
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:19775", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Expected the idle connection to be closed, got %v", err)
+	}
+}
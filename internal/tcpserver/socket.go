@@ -0,0 +1,39 @@
+package tcpserver
+
+import (
+	"net"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// socketConfig returns the conn_keepalive/conn_linger settings of the route on pl.
+// Group and postgres routes have none.
+func (s *Server) socketConfig(pl *portListener) config.SocketConfig {
+	switch pl.listenerType {
+	case listenerTypeJump:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.config.TCP.K8s.Jump[pl.port].SocketConfig
+	case listenerTypeRoute:
+		return s.connRoute(pl).SocketConfig
+	}
+	return config.SocketConfig{}
+}
+
+// applySocketConfig sets sc's options on a client connection
+func applySocketConfig(conn net.Conn, sc config.SocketConfig) {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	switch {
+	case sc.ConnKeepalive < 0:
+		_ = tc.SetKeepAlive(false)
+	case sc.ConnKeepalive > 0:
+		_ = tc.SetKeepAlive(true)
+		_ = tc.SetKeepAlivePeriod(sc.ConnKeepalive)
+	}
+	if sc.ConnLinger != nil {
+		_ = tc.SetLinger(int(sc.ConnLinger.Seconds()))
+	}
+}
@@ -0,0 +1,93 @@
+package tcpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestApplySocketConfig_LingerZeroResets(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+
+	linger := time.Duration(0)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		applySocketConfig(conn, config.SocketConfig{ConnKeepalive: -1, ConnLinger: &linger})
+		// unread data makes a lingering close reset even without SO_LINGER, so wait for none
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := client.Read(make([]byte, 1)); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("Read after close = %v, want a reset from conn_linger: 0", err)
+	}
+}
+
+func TestWarmStream_HalfClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a target that answers once the request is complete
+	streamCtx, streamCancel := context.WithCancel(ctx)
+	stdinReader, stdinWriter := io.Pipe()
+	ws := &warmStream{stdin: stdinWriter, out: &attachableWriter{}, cancel: streamCancel, opened: time.Now(), done: make(chan struct{})}
+	go func() {
+		defer close(ws.done)
+		req, _ := io.ReadAll(stdinReader)
+		if streamCtx.Err() == nil {
+			_, _ = ws.out.Write([]byte("got " + string(req)))
+		}
+	}()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = ws.serve(conn)
+	}()
+
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer client.Close()
+	_, _ = client.Write([]byte("request"))
+	_ = client.(*net.TCPConn).CloseWrite()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	resp, err := io.ReadAll(client)
+	if err != nil || string(resp) != "got request" {
+		t.Errorf("response = %q, %v; want the target to answer after the half-close", resp, err)
+	}
+	wg.Wait()
+}
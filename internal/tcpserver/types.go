@@ -1,6 +1,9 @@
 package tcpserver
 
 import (
+	"context"
+
+	"github.com/atas/autotunnel/internal/config"
 	"github.com/atas/autotunnel/internal/tunnelmgr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -8,5 +11,12 @@ import (
 
 type Manager interface {
 	GetOrCreateTCPTunnel(localPort int) (tunnelmgr.TunnelHandle, error)
+	GroupServicePorts(ctx context.Context, name string) ([]int, error)
+	GetOrCreateGroupTunnel(name string, ports []int) (tunnelmgr.TunnelHandle, error)
 	GetClientForContext(kubeconfigPaths []string, contextName string) (*kubernetes.Clientset, *rest.Config, error)
+	GetClientForRoute(kubeconfigPaths []string, contextName string, execEnv map[string]string) (*kubernetes.Clientset, *rest.Config, error)
+	RenewClientForContext(kubeconfigPaths []string, contextName string, execEnv map[string]string, stale *rest.Config) (*kubernetes.Clientset, *rest.Config, error)
+	AddDiscoveredRoute(localPort int, route config.TCPRouteConfig) error
+	RemoveDiscoveredRoute(localPort int)
+	GetOrCreatePostgresTunnel(localPort int, route config.TCPRouteConfig) (tunnelmgr.TunnelHandle, error)
 }
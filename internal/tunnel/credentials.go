@@ -0,0 +1,46 @@
+package tunnel
+
+import (
+	"log"
+
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClientRenewer returns a client with fresh credentials in place of stale's
+type ClientRenewer func(stale *rest.Config) (kubernetes.Interface, *rest.Config, error)
+
+// SetClientRenewer lets the tunnel swap its client when the API server rejects
+// the credentials (401, e.g. an expired token) and retry the start once
+func (t *Tunnel) SetClientRenewer(renew ClientRenewer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.renewClient = renew
+}
+
+// renewCredentials replaces the client after err if err is a 401 and a renewer is
+// set, and reports whether the start is worth another try
+func (t *Tunnel) renewCredentials(err error) bool {
+	t.mu.RLock()
+	renew, stale := t.renewClient, t.restConfig
+	t.mu.RUnlock()
+	if renew == nil || !k8sutil.IsUnauthorized(err) {
+		return false
+	}
+
+	log.Printf("[%s] Credentials rejected by the API server (%v), reloading the kubeconfig and retrying", t.hostname, err)
+	clientset, restConfig, renewErr := renew(stale)
+	if renewErr != nil {
+		log.Printf("[%s] Failed to renew credentials: %v", t.hostname, renewErr)
+		return false
+	}
+
+	t.mu.Lock()
+	t.clientset, t.restConfig = clientset, restConfig
+	if t.state == StateFailed {
+		t.setState(StateIdle)
+	}
+	t.mu.Unlock()
+	return true
+}
@@ -0,0 +1,69 @@
+package tunnel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// apiServer answers every request with status and counts them
+func apiServer(t *testing.T, status int) (*rest.Config, kubernetes.Interface, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	restConfig := &rest.Config{Host: srv.URL}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("Failed to create clientset: %v", err)
+	}
+	return restConfig, clientset, &requests
+}
+
+func TestTunnel_Start_RenewsRejectedCredentials(t *testing.T) {
+	staleConfig, staleClient, _ := apiServer(t, http.StatusUnauthorized)
+	freshConfig, freshClient, freshRequests := apiServer(t, http.StatusNotFound)
+
+	cfg := config.K8sRouteConfig{Namespace: "ns", Service: "svc", Port: 80}
+	tun := NewTunnel("test.localhost", cfg, staleClient, staleConfig, "", false)
+	var renewals int
+	tun.SetClientRenewer(func(stale *rest.Config) (kubernetes.Interface, *rest.Config, error) {
+		renewals++
+		if stale != staleConfig {
+			t.Errorf("renewer got %v, want the rejected config", stale)
+		}
+		return freshClient, freshConfig, nil
+	})
+
+	if err := tun.Start(context.Background()); err == nil {
+		t.Fatal("Start() succeeded, want the fresh client's not found")
+	}
+	if renewals != 1 {
+		t.Errorf("renewed %d times, want 1", renewals)
+	}
+	if freshRequests.Load() == 0 {
+		t.Error("the retry didn't use the renewed client")
+	}
+}
+
+func TestTunnel_Start_NoRenewalWithoutRenewer(t *testing.T) {
+	staleConfig, staleClient, requests := apiServer(t, http.StatusUnauthorized)
+
+	cfg := config.K8sRouteConfig{Namespace: "ns", Service: "svc", Port: 80}
+	tun := NewTunnel("test.localhost", cfg, staleClient, staleConfig, "", false)
+	if err := tun.Start(context.Background()); err == nil {
+		t.Fatal("Start() succeeded against a 401")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("sent %d requests, want 1 (no retry)", got)
+	}
+}
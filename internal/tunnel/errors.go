@@ -0,0 +1,45 @@
+package tunnel
+
+import "errors"
+
+// Steps of getting a tunnel up, reported by StepError for diagnostics
+const (
+	StepRouteLookup  = "route lookup"
+	StepK8sClient    = "k8s client"
+	StepServiceGet   = "service get"
+	StepPodList      = "pod list"
+	StepPortResolve  = "port resolve"
+	StepSPDYDial     = "spdy dial"
+	StepReadyTimeout = "ready timeout"
+)
+
+// StepError records which step of a tunnel start failed. Its message is the
+// wrapped error's, so logs and plain error responses read the same as before.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// FailedStep returns the step err failed at, or "" if it carries none
+func FailedStep(err error) string {
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return stepErr.Step
+	}
+	return ""
+}
+
+// fail records a failed step as the tunnel's error and returns it
+func (t *Tunnel) fail(step string, err error) error {
+	err = &StepError{Step: step, Err: err}
+	t.setFailed(err)
+	return err
+}
@@ -0,0 +1,70 @@
+package tunnel
+
+// HealthObserver is told how each start of a tunnel went (nil error on success)
+// and when a running port-forward breaks
+type HealthObserver func(err error)
+
+// SetHealthObserver makes the tunnel report its starts and failures to observe
+func (t *Tunnel) SetHealthObserver(observe HealthObserver) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.observeHealth = observe
+}
+
+// reportHealth passes err to the health observer, if one is set
+func (t *Tunnel) reportHealth(err error) {
+	t.mu.RLock()
+	observe := t.observeHealth
+	t.mu.RUnlock()
+	if observe != nil {
+		observe(err)
+	}
+}
+
+// StateObserver is told about every state change of a tunnel; err is the
+// failure when to is StateFailed
+type StateObserver func(to State, err error)
+
+// SetStateObserver makes the tunnel report its state changes to observe
+func (t *Tunnel) SetStateObserver(observe StateObserver) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.observeState = observe
+}
+
+// setState moves the tunnel to state and reports the change (every failure counts
+// as one). Leaving StateRunning forgets the forwarded ports, so LocalPortFor
+// returns 0 until the next start. Caller must hold t.mu; the observer must not
+// call back into the tunnel.
+func (t *Tunnel) setState(state State) {
+	if t.state == state && state != StateFailed {
+		return
+	}
+	if state != StateRunning {
+		t.localPorts = nil
+	}
+	t.state = state
+	if t.observeState == nil {
+		return
+	}
+	var err error
+	if state == StateFailed {
+		err = t.lastError
+	}
+	t.observeState(state, err)
+}
+
+// SetHealthObserver reports the pool's starts as a whole. A standby member
+// breaking isn't a failure of the route while others are running.
+func (p *Pool) SetHealthObserver(observe HealthObserver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.observeHealth = observe
+}
+
+// SetStateObserver reports the state changes of every member, standbys included
+func (p *Pool) SetStateObserver(observe StateObserver) {
+	for _, member := range p.members {
+		member.SetStateObserver(observe)
+	}
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestTunnel_ReportsFailedStart(t *testing.T) {
+	cfg := config.K8sRouteConfig{Context: "test", Namespace: "default", Service: "missing", Port: 80}
+	tun := NewTunnel("app.localhost", cfg, fake.NewSimpleClientset(), &rest.Config{}, ":8989", false)
+
+	var reported []error
+	tun.SetHealthObserver(func(err error) { reported = append(reported, err) })
+
+	if err := tun.Start(context.Background()); err == nil {
+		t.Fatal("expected the start to fail, the service doesn't exist")
+	}
+	if len(reported) != 1 || reported[0] == nil {
+		t.Fatalf("expected one failure reported, got %v", reported)
+	}
+
+	// the caller going away isn't the route's fault
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = tun.Start(ctx)
+	if len(reported) != 1 {
+		t.Errorf("canceled start was reported: %v", reported)
+	}
+}
+
+func TestTunnel_ReportsStateChanges(t *testing.T) {
+	cfg := config.K8sRouteConfig{Context: "test", Namespace: "default", Service: "missing", Port: 80}
+	tun := NewTunnel("app.localhost", cfg, fake.NewSimpleClientset(), &rest.Config{}, ":8989", false)
+
+	var states []State
+	var lastErr error
+	tun.SetStateObserver(func(to State, err error) {
+		states = append(states, to)
+		lastErr = err
+	})
+
+	_ = tun.Start(context.Background())
+	if len(states) != 2 || states[0] != StateStarting || states[1] != StateFailed {
+		t.Fatalf("got states %v, want [starting failed]", states)
+	}
+	if lastErr == nil {
+		t.Error("failed state reported without its error")
+	}
+}
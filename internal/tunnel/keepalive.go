@@ -0,0 +1,61 @@
+package tunnel
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/client-go/rest"
+	clientspdy "k8s.io/client-go/transport/spdy"
+)
+
+// keepaliveProbes is how many unanswered TCP keepalive probes mark the API server
+// connection dead, after which the port-forward fails and the tunnel is recreated
+const keepaliveProbes = 3
+
+// roundTripperFor is spdy.RoundTripperFor with our keepalive settings. SPDY pings
+// keep NAT/load balancer entries between us and the API server from expiring while
+// a tunnel sits idle; client-go ignores unanswered pings, so TCP keepalive is what
+// notices a connection that was dropped silently.
+func roundTripperFor(config *rest.Config, keepalive time.Duration) (http.RoundTripper, clientspdy.Upgrader, error) {
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		proxy = config.Proxy
+	}
+
+	upgradeRoundTripper, err := spdy.NewRoundTripperWithConfig(spdy.RoundTripperConfig{
+		TLS:        tlsConfig,
+		Proxier:    proxy,
+		PingPeriod: keepalive,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	upgradeRoundTripper.Dialer = keepaliveDialer(keepalive)
+
+	wrapper, err := rest.HTTPWrappersForConfig(config, upgradeRoundTripper)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wrapper, upgradeRoundTripper, nil
+}
+
+// keepaliveDialer probes idle connections every keepalive; 0 keeps Go's defaults
+func keepaliveDialer(keepalive time.Duration) *net.Dialer {
+	if keepalive <= 0 {
+		return &net.Dialer{}
+	}
+	return &net.Dialer{
+		KeepAliveConfig: net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     keepalive,
+			Interval: keepalive,
+			Count:    keepaliveProbes,
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/client-go/rest"
+)
+
+func TestKeepaliveDialer(t *testing.T) {
+	d := keepaliveDialer(10 * time.Second)
+	ka := d.KeepAliveConfig
+	if !ka.Enable || ka.Idle != 10*time.Second || ka.Interval != 10*time.Second || ka.Count != keepaliveProbes {
+		t.Errorf("keepaliveDialer(10s) = %+v", ka)
+	}
+
+	if d := keepaliveDialer(0); d.KeepAliveConfig.Enable || d.KeepAlive != 0 {
+		t.Errorf("keepaliveDialer(0) should keep Go's defaults, got %+v", d)
+	}
+}
+
+func TestRoundTripperFor_UsesKeepaliveDialer(t *testing.T) {
+	_, upgrader, err := roundTripperFor(&rest.Config{Host: "https://127.0.0.1:6443"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("roundTripperFor() error = %v", err)
+	}
+
+	rt, ok := upgrader.(*spdy.SpdyRoundTripper)
+	if !ok {
+		t.Fatalf("upgrader is %T, want *spdy.SpdyRoundTripper", upgrader)
+	}
+	if rt.Dialer == nil || rt.Dialer.KeepAliveConfig.Idle != 5*time.Second {
+		t.Errorf("expected the keepalive dialer, got %+v", rt.Dialer)
+	}
+}
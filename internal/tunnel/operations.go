@@ -1,6 +1,9 @@
 package tunnel
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 func (t *Tunnel) LocalPort() int {
 	t.mu.RLock()
@@ -8,22 +11,73 @@ func (t *Tunnel) LocalPort() int {
 	return t.localPort
 }
 
+// LocalPortFor returns the local forwarded port for a configured target port
+// (Port or one of ExtraPorts), or 0 if the tunnel is not running
+func (t *Tunnel) LocalPortFor(port int) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.localPorts[port]
+}
+
+// PodName returns the pod the tunnel last forwarded to ("" before the first start)
+func (t *Tunnel) PodName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.pod
+}
+
 func (t *Tunnel) Touch() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.lastAccess = time.Now()
 }
 
+// IdleDuration is how long the tunnel has been unused; 0 while a tracked
+// connection is open
 func (t *Tunnel) IdleDuration() time.Duration {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
+	if t.openConns > 0 {
+		return 0
+	}
 	return time.Since(t.lastAccess)
 }
 
+// TrackConn marks a long-lived connection (e.g. an upgraded WebSocket) as using the
+// tunnel, so idle cleanup leaves it alone until the returned func is called. The
+// idle timer restarts when the last tracked connection closes.
+func (t *Tunnel) TrackConn() (release func()) {
+	t.mu.Lock()
+	t.openConns++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.openConns--
+			t.lastAccess = time.Now()
+		})
+	}
+}
+
+// OpenConns returns the number of tracked connections currently open
+func (t *Tunnel) OpenConns() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.openConns
+}
+
 func (t *Tunnel) Hostname() string {
 	return t.hostname
 }
 
+// TargetPort returns the backend port LocalPort forwards to
+func (t *Tunnel) TargetPort() int {
+	return t.config.Port
+}
+
 func (t *Tunnel) Scheme() string {
 	if t.config.Scheme == "" {
 		return "http"
@@ -36,3 +90,18 @@ func (t *Tunnel) LastError() error {
 	defer t.mu.RUnlock()
 	return t.lastError
 }
+
+// readyTimeout is how long a start may take: the route's ready_timeout or the default
+func (t *Tunnel) readyTimeout() time.Duration {
+	if t.config.ReadyTimeout > 0 {
+		return t.config.ReadyTimeout
+	}
+	return PortForwardReadyTimeout
+}
+
+// SetPodPin makes the tunnel consult pin when choosing a service's pod
+func (t *Tunnel) SetPodPin(pin *PodPin) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pin = pin
+}
@@ -65,6 +65,28 @@ func TestTunnel_Touch_IdleDuration(t *testing.T) {
 	}
 }
 
+func TestTunnel_TrackConn(t *testing.T) {
+	tunnel := &Tunnel{lastAccess: time.Now().Add(-1 * time.Hour)}
+
+	release := tunnel.TrackConn()
+	if idle := tunnel.IdleDuration(); idle != 0 {
+		t.Errorf("IdleDuration() with an open connection = %v, want 0", idle)
+	}
+	if n := tunnel.OpenConns(); n != 1 {
+		t.Errorf("OpenConns() = %d, want 1", n)
+	}
+
+	release()
+	release() // extra calls are no-ops
+	if n := tunnel.OpenConns(); n != 0 {
+		t.Errorf("OpenConns() after release = %d, want 0", n)
+	}
+	// the idle timer restarts when the connection closes
+	if idle := tunnel.IdleDuration(); idle > 100*time.Millisecond {
+		t.Errorf("IdleDuration() after release = %v, expected < 100ms", idle)
+	}
+}
+
 func TestTunnel_LastError(t *testing.T) {
 	tunnel := &Tunnel{}
 
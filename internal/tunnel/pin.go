@@ -0,0 +1,73 @@
+package tunnel
+
+import (
+	"sync"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// PodPin keeps a service route on one pod across tunnel restarts. The tunnel
+// manager holds one per route so it outlives the tunnels that consult it.
+type PodPin struct {
+	mu      sync.RWMutex
+	auto    bool   // pin the first pod a tunnel selects
+	pod     string // pinned pod, "" when not pinned
+	current string // pod the last tunnel forwarded to
+}
+
+// NewPodPin creates a pin from a route's pin_pod setting ("", a pod name or "auto")
+func NewPodPin(pinPod string) *PodPin {
+	if pinPod == config.PinPodAuto {
+		return &PodPin{auto: true}
+	}
+	return &PodPin{pod: pinPod}
+}
+
+// Pod returns the pinned pod, or "" when the route isn't pinned
+func (p *PodPin) Pod() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.pod
+}
+
+// Current returns the pod the route's last tunnel forwarded to
+func (p *PodPin) Current() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current
+}
+
+// Pin pins the route to pod, or to the pod it currently forwards to when pod is ""
+// Returns the pinned pod, "" when there is nothing to pin to yet.
+func (p *PodPin) Pin(pod string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pod == "" {
+		pod = p.current
+	}
+	p.pod = pod
+	return pod
+}
+
+// Unpin lets the route pick any ready pod again. With pin_pod: auto the next
+// pod selected gets pinned.
+func (p *PodPin) Unpin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pod = ""
+}
+
+// selected records the pod a tunnel is forwarding to
+func (p *PodPin) selected(pod string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = pod
+	if p.auto && p.pod == "" {
+		p.pod = pod
+	}
+}
+
+// Auto reports whether the route pins the first pod selected (pin_pod: auto)
+func (p *PodPin) Auto() bool {
+	return p.auto
+}
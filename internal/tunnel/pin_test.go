@@ -0,0 +1,46 @@
+package tunnel
+
+import "testing"
+
+func TestPodPin_Auto(t *testing.T) {
+	pin := NewPodPin("auto")
+	if pin.Pod() != "" {
+		t.Fatalf("Pod() = %q before any pod was selected, want empty", pin.Pod())
+	}
+
+	pin.selected("pod-a")
+	pin.selected("pod-b") // already pinned, only the current pod changes
+	if pin.Pod() != "pod-a" || pin.Current() != "pod-b" {
+		t.Errorf("Pod() = %q, Current() = %q, want pod-a, pod-b", pin.Pod(), pin.Current())
+	}
+
+	// unpinning an auto route pins the next pod selected
+	pin.Unpin()
+	pin.selected("pod-c")
+	if pin.Pod() != "pod-c" {
+		t.Errorf("Pod() = %q after unpin, want pod-c", pin.Pod())
+	}
+}
+
+func TestPodPin_Manual(t *testing.T) {
+	pin := NewPodPin("")
+	if got := pin.Pin(""); got != "" {
+		t.Errorf("Pin(\"\") = %q with no current pod, want empty", got)
+	}
+
+	pin.selected("pod-a")
+	if pin.Pod() != "" {
+		t.Errorf("Pod() = %q, selecting a pod should not pin without auto", pin.Pod())
+	}
+	if got := pin.Pin(""); got != "pod-a" {
+		t.Errorf("Pin(\"\") = %q, want the current pod", got)
+	}
+	if got := pin.Pin("pod-z"); got != "pod-z" || pin.Pod() != "pod-z" {
+		t.Errorf("Pin(pod-z) = %q, Pod() = %q", got, pin.Pod())
+	}
+
+	pin.Unpin()
+	if pin.Pod() != "" {
+		t.Errorf("Pod() = %q after Unpin", pin.Pod())
+	}
+}
@@ -0,0 +1,261 @@
+package tunnel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/portstate"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// standbyRestartInterval limits how often a failed pool member is restarted
+const standbyRestartInterval = 5 * time.Second
+
+// Pool keeps a route's tunnel plus config.Standby warm standby port-forwards open
+// and rotates new connections through the running ones. When one SPDY connection
+// breaks, connections move to the others while it restarts in the background.
+type Pool struct {
+	mu sync.RWMutex
+
+	hostname   string
+	config     config.K8sRouteConfig
+	members    []*Tunnel
+	restarted  []time.Time // last background restart per member
+	lastAccess time.Time
+	openConns  int // tracked long-lived connections, see Tunnel.TrackConn
+	stopped    bool
+
+	observeHealth HealthObserver // see SetHealthObserver
+
+	next atomic.Uint32 // round-robin position
+}
+
+// NewPool creates a pool of 1 + cfg.Standby tunnels for one route
+func NewPool(hostname string, cfg config.K8sRouteConfig, clientset kubernetes.Interface, restConfig *rest.Config, listenAddr string, verbose bool) *Pool {
+	members := make([]*Tunnel, 1+cfg.Standby)
+	for i := range members {
+		members[i] = NewTunnel(hostname, cfg, clientset, restConfig, listenAddr, verbose)
+		cfg.LocalPort = 0 // only the first member can have the fixed port
+	}
+	return &Pool{
+		hostname:   hostname,
+		config:     cfg,
+		members:    members,
+		restarted:  make([]time.Time, len(members)),
+		lastAccess: time.Now(),
+	}
+}
+
+// Start starts every member and returns once one of them is ready. The others
+// keep starting in the background, bounded by their own ready timeout.
+func (p *Pool) Start(ctx context.Context) error {
+	p.mu.Lock()
+	p.stopped = false
+	observe := p.observeHealth
+	p.mu.Unlock()
+
+	err := p.start(ctx)
+	if observe != nil && ctx.Err() == nil {
+		observe(err)
+	}
+	return err
+}
+
+func (p *Pool) start(ctx context.Context) error {
+
+	results := make(chan error, len(p.members))
+	for _, member := range p.members {
+		go func(member *Tunnel) {
+			results <- member.Start(context.WithoutCancel(ctx))
+		}(member)
+	}
+
+	var lastErr error
+	for range p.members {
+		select {
+		case err := <-results:
+			if err == nil {
+				return nil
+			}
+			lastErr = err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
+
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+
+	for _, member := range p.members {
+		member.Stop()
+	}
+}
+
+// IsRunning reports whether any member can take connections
+func (p *Pool) IsRunning() bool {
+	for _, member := range p.members {
+		if member.IsRunning() {
+			return true
+		}
+	}
+	return false
+}
+
+// State is running when any member runs, starting when any starts, else the first member's
+func (p *Pool) State() State {
+	starting := false
+	for _, member := range p.members {
+		switch member.State() {
+		case StateRunning:
+			return StateRunning
+		case StateStarting:
+			starting = true
+		}
+	}
+	if starting {
+		return StateStarting
+	}
+	return p.members[0].State()
+}
+
+func (p *Pool) LocalPort() int {
+	return p.LocalPortFor(p.config.Port)
+}
+
+// LocalPortFor returns the local port of the next running member, restarting
+// members that stopped or failed along the way
+func (p *Pool) LocalPortFor(port int) int {
+	start := int(p.next.Add(1))
+	for i := range p.members {
+		idx := (start + i) % len(p.members)
+		member := p.members[idx]
+		if member.IsRunning() {
+			return member.LocalPortFor(port)
+		}
+		p.restartMember(idx)
+	}
+	return 0
+}
+
+// restartMember restarts a member that is not running, at most every standbyRestartInterval
+func (p *Pool) restartMember(idx int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	member := p.members[idx]
+	if p.stopped || member.State() == StateStarting || time.Since(p.restarted[idx]) < standbyRestartInterval {
+		return
+	}
+	p.restarted[idx] = time.Now()
+	go func() {
+		_ = member.Start(context.Background())
+	}()
+}
+
+func (p *Pool) Touch() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastAccess = time.Now()
+}
+
+func (p *Pool) IdleDuration() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.openConns > 0 {
+		return 0
+	}
+	return time.Since(p.lastAccess)
+}
+
+// TrackConn is Tunnel.TrackConn for the whole pool
+func (p *Pool) TrackConn() (release func()) {
+	p.mu.Lock()
+	p.openConns++
+	p.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.openConns--
+			p.lastAccess = time.Now()
+		})
+	}
+}
+
+func (p *Pool) OpenConns() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.openConns
+}
+
+func (p *Pool) TargetPort() int {
+	return p.config.Port
+}
+
+func (p *Pool) Scheme() string {
+	return p.members[0].Scheme()
+}
+
+// LastError returns the first member error found
+func (p *Pool) LastError() error {
+	for _, member := range p.members {
+		if err := member.LastError(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PodName returns the pod of the first running member
+func (p *Pool) PodName() string {
+	for _, member := range p.members {
+		if member.IsRunning() {
+			return member.PodName()
+		}
+	}
+	return ""
+}
+
+// SetPortStore persists the first member's ports; standbys take any port
+func (p *Pool) SetPortStore(store *portstate.Store, route string) {
+	p.members[0].SetPortStore(store, route)
+}
+
+// SetClientRenewer lets every member renew its credentials
+func (p *Pool) SetClientRenewer(renew ClientRenewer) {
+	for _, member := range p.members {
+		member.SetClientRenewer(renew)
+	}
+}
+
+// SetServiceCache lets every member look the route's service up in cache
+func (p *Pool) SetServiceCache(cache *k8sutil.ServiceCache, contextName string) {
+	for _, member := range p.members {
+		member.SetServiceCache(cache, contextName)
+	}
+}
+
+// SetStartLimiter makes every member's starts share limiter's slots
+func (p *Pool) SetStartLimiter(limiter *StartLimiter) {
+	for _, member := range p.members {
+		member.SetStartLimiter(limiter)
+	}
+}
+
+// SetPodPin pins every member to the route's pod
+func (p *Pool) SetPodPin(pin *PodPin) {
+	for _, member := range p.members {
+		member.SetPodPin(pin)
+	}
+}
@@ -0,0 +1,125 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// unreachableClients returns clients for an API server nothing listens on, so
+// port-forwards fail right away
+func unreachableClients(t *testing.T) (kubernetes.Interface, *rest.Config) {
+	t.Helper()
+	restConfig := &rest.Config{Host: "http://127.0.0.1:1"}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("Failed to create clientset: %v", err)
+	}
+	return clientset, restConfig
+}
+
+func testPool(t *testing.T, standby int) *Pool {
+	t.Helper()
+	clientset, restConfig := unreachableClients(t)
+	cfg := config.K8sRouteConfig{Namespace: "test-ns", Pod: "test-pod", Port: 8080, Standby: standby, ReadyTimeout: time.Second}
+	return NewPool("test.localhost", cfg, clientset, restConfig, "", false)
+}
+
+// setRunning marks a member as running on localPort without a real port-forward
+func setRunning(member *Tunnel, localPort int) {
+	member.mu.Lock()
+	defer member.mu.Unlock()
+	member.state = StateRunning
+	member.localPort = localPort
+	member.localPorts = map[int]int{member.config.Port: localPort}
+}
+
+func TestNewPool_Members(t *testing.T) {
+	p := testPool(t, 2)
+	if len(p.members) != 3 {
+		t.Errorf("Expected 1 + 2 standby members, got %d", len(p.members))
+	}
+}
+
+func TestPool_RotatesThroughRunningMembers(t *testing.T) {
+	p := testPool(t, 2)
+	setRunning(p.members[0], 10001)
+	setRunning(p.members[1], 10002)
+	setRunning(p.members[2], 10003)
+
+	seen := make(map[int]bool)
+	for range 6 {
+		seen[p.LocalPort()] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected connections spread over all 3 members, got ports %v", seen)
+	}
+}
+
+func TestPool_SkipsAndRestartsFailedMember(t *testing.T) {
+	p := testPool(t, 1)
+	setRunning(p.members[0], 10001)
+	p.members[1].setFailed(context.DeadlineExceeded)
+
+	for range 4 {
+		if port := p.LocalPortFor(8080); port != 10001 {
+			t.Fatalf("LocalPortFor() = %d, want the running member's port", port)
+		}
+	}
+
+	p.mu.RLock()
+	restarted := p.restarted[1]
+	p.mu.RUnlock()
+	if restarted.IsZero() {
+		t.Error("Expected the failed member to be restarted in the background")
+	}
+
+	if !p.IsRunning() || p.State() != StateRunning {
+		t.Errorf("Expected pool running while one member runs, got %v", p.State())
+	}
+}
+
+func TestPool_NoRestartAfterStop(t *testing.T) {
+	p := testPool(t, 1)
+	p.members[1].setFailed(context.DeadlineExceeded)
+	p.Stop()
+
+	if port := p.LocalPort(); port != 0 {
+		t.Errorf("LocalPort() = %d after Stop, want 0", port)
+	}
+	if !p.restarted[1].IsZero() {
+		t.Error("Expected no restarts once the pool is stopped")
+	}
+}
+
+func TestPool_Start_AllMembersFail(t *testing.T) {
+	p := testPool(t, 1)
+
+	if err := p.Start(context.Background()); err == nil {
+		t.Fatal("Expected an error when no member can start")
+	}
+	if p.IsRunning() {
+		t.Error("Expected pool not running")
+	}
+	if p.State() != StateFailed {
+		t.Errorf("State() = %v, want failed", p.State())
+	}
+}
+
+func TestPool_TrackConn(t *testing.T) {
+	p := testPool(t, 1)
+	p.lastAccess = time.Now().Add(-time.Hour)
+
+	release := p.TrackConn()
+	if idle := p.IdleDuration(); idle != 0 {
+		t.Errorf("IdleDuration() with an open connection = %v, want 0", idle)
+	}
+	release()
+	if idle := p.IdleDuration(); idle > 100*time.Millisecond || p.OpenConns() != 0 {
+		t.Errorf("After release: IdleDuration() = %v, OpenConns() = %d", idle, p.OpenConns())
+	}
+}
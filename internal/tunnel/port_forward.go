@@ -7,83 +7,192 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/atas/autotunnel/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
 )
 
 func (t *Tunnel) startPortForward(ctx context.Context) error {
-	podName, targetPort, err := t.discoverTargetPod(ctx)
+	targets, err := t.discoverTargetPods(ctx)
 	if err != nil {
+		t.forgetService()
 		return err
 	}
 
-	fw, errChan, err := t.createPortForwarder(podName, targetPort)
-	if err != nil {
-		return err
+	// an evicted or crashlooping pod fails here; move on to the service's next pod
+	localPorts := t.preferredLocalPorts()
+	var lastErr error
+	for i, target := range targets {
+		if i > 0 {
+			log.Printf("[%s] Port forward to pod %s/%s failed (%v), trying pod %s",
+				t.hostname, t.config.Namespace, targets[i-1].pod, lastErr, target.pod)
+		}
+
+		fw, errChan, err := t.createPortForwarder(target.pod, target.ports, localPorts)
+		if err != nil {
+			return err
+		}
+
+		lastErr = t.waitForReady(ctx, fw, errChan)
+		if lastErr != nil && ctx.Err() == nil && isListenError(lastErr) && t.config.LocalPort == 0 {
+			// a persisted port got taken since we checked; any port will do
+			log.Printf("[%s] Previous local port unavailable (%v), using a new one", t.hostname, lastErr)
+			localPorts = make([]int, len(localPorts))
+			if fw, errChan, err = t.createPortForwarder(target.pod, target.ports, localPorts); err != nil {
+				return err
+			}
+			lastErr = t.waitForReady(ctx, fw, errChan)
+		}
+		if lastErr == nil {
+			t.mu.Lock()
+			t.pod = target.pod
+			t.mu.Unlock()
+			if t.pin != nil {
+				t.pin.selected(target.pod)
+			}
+		}
+		if lastErr != nil && ctx.Err() == nil && isListenError(lastErr) {
+			lastErr = t.localPortTaken(lastErr)
+			break // the local port is taken whichever pod we try
+		}
+		if lastErr == nil || ctx.Err() != nil || k8sutil.IsForbidden(lastErr) {
+			break // forbidden is the same for every pod
+		}
 	}
 
-	return t.waitForReady(ctx, fw, errChan)
+	if lastErr != nil && ctx.Err() == nil {
+		t.forgetService()
+		t.setFailed(lastErr)
+	}
+	return lastErr
 }
 
-// discoverTargetPod figures out which pod to connect to.
-// With pod: config, we use it directly. With service: config, we look up the
-// service's selector labels and find a ready pod that matches.
-func (t *Tunnel) discoverTargetPod(ctx context.Context) (podName string, port int, err error) {
-	port = t.config.Port
+// podTarget is a pod to port-forward to and the container ports to forward,
+// one per configured port (Port first, then ExtraPorts)
+type podTarget struct {
+	pod   string
+	ports []int
+}
 
+// discoverTargetPods figures out which pods to connect to, in order of preference.
+// With pod: config, we use it directly. With service: config, we look up the
+// service's selector labels and list the running pods that match, ready ones first,
+// capped at MaxPodAttempts. A pinned route only ever uses its pinned pod.
+func (t *Tunnel) discoverTargetPods(ctx context.Context) ([]podTarget, error) {
 	if t.config.Pod != "" {
+		ports := t.config.TargetPorts()
 		if t.verbose {
-			log.Printf("[%s] Direct pod targeting: %s/%s port %d", t.hostname, t.config.Namespace, t.config.Pod, port)
+			log.Printf("[%s] Direct pod targeting: %s/%s port %s", t.hostname, t.config.Namespace, t.config.Pod, formatPorts(ports))
 		}
-		return t.config.Pod, port, nil
+		return []podTarget{{pod: t.config.Pod, ports: ports}}, nil
 	}
 
-	svc, err := k8sutil.GetService(ctx, t.clientset, t.config.Namespace, t.config.Service)
+	svc, err := t.getService(ctx)
 	if err != nil {
-		t.setFailed(err)
-		return "", 0, fmt.Errorf("failed to get service: %w", err)
+		return nil, t.fail(StepServiceGet, fmt.Errorf("failed to get service: %w", err))
 	}
 
-	// K8s services can map ports (e.g. service:80 -> container:8080),
-	// so we need to resolve to the actual container port
-	port, targetPortName := k8sutil.ResolveServicePort(svc, t.config.Port)
+	if t.pin != nil {
+		if pinned := t.pin.Pod(); pinned != "" {
+			return t.pinnedTarget(ctx, svc, pinned)
+		}
+	}
 
-	targetPod, err := k8sutil.FindReadyPod(ctx, t.clientset, t.config.Namespace, svc.Spec.Selector, t.config.Service)
+	pods, err := k8sutil.RunningPods(ctx, t.clientset, t.config.Namespace, svc.Spec.Selector, t.config.Service)
 	if err != nil {
-		t.setFailed(err)
-		return "", 0, err
+		return nil, t.fail(StepPodList, err)
+	}
+	if len(pods) > MaxPodAttempts {
+		pods = pods[:MaxPodAttempts]
 	}
 
-	if targetPortName != "" {
-		port, err = k8sutil.ResolveNamedPort(targetPod, targetPortName)
+	targets := make([]podTarget, 0, len(pods))
+	var resolveErr error
+	for i := range pods {
+		ports, err := resolveContainerPorts(svc, &pods[i], t.config.TargetPorts())
 		if err != nil {
-			t.setFailed(err)
-			return "", 0, err
+			resolveErr = err // this pod doesn't declare the named port; try the others
+			continue
 		}
+		targets = append(targets, podTarget{pod: pods[i].Name, ports: ports})
+	}
+	if len(targets) == 0 {
+		return nil, t.fail(StepPortResolve, resolveErr)
 	}
 
 	if t.verbose {
-		log.Printf("[%s] Forwarding to pod %s/%s port %d (via service %s)", t.hostname, t.config.Namespace, targetPod.Name, port, t.config.Service)
+		log.Printf("[%s] Forwarding to pod %s/%s port %s (via service %s)", t.hostname, t.config.Namespace, targets[0].pod, formatPorts(targets[0].ports), t.config.Service)
 	}
 
-	return targetPod.Name, port, nil
+	return targets, nil
 }
 
+// pinnedTarget looks up the route's pinned pod; there is no falling back to other
+// pods, the point of pinning is to stay on this one
+func (t *Tunnel) pinnedTarget(ctx context.Context, svc *corev1.Service, podName string) ([]podTarget, error) {
+	pod, err := t.clientset.CoreV1().Pods(t.config.Namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, t.fail(StepPodList, fmt.Errorf("pinned pod %s/%s: %w", t.config.Namespace, podName, err))
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, t.fail(StepPodList, fmt.Errorf("pinned pod %s/%s is %s", t.config.Namespace, podName, pod.Status.Phase))
+	}
 
-func (t *Tunnel) createPortForwarder(podName string, targetPort int) (*portforward.PortForwarder, chan error, error) {
+	ports, err := resolveContainerPorts(svc, pod, t.config.TargetPorts())
+	if err != nil {
+		return nil, t.fail(StepPortResolve, err)
+	}
+
+	if t.verbose {
+		log.Printf("[%s] Forwarding to pinned pod %s/%s port %s (via service %s)", t.hostname, t.config.Namespace, podName, formatPorts(ports), t.config.Service)
+	}
+	return []podTarget{{pod: podName, ports: ports}}, nil
+}
+
+// resolveContainerPorts maps service ports to the pod's container ports.
+// K8s services can map ports (e.g. service:80 -> container:8080), including by name.
+func resolveContainerPorts(svc *corev1.Service, pod *corev1.Pod, servicePorts []int) ([]int, error) {
+	ports := make([]int, 0, len(servicePorts))
+	for _, servicePort := range servicePorts {
+		port, targetPortName := k8sutil.ResolveServicePort(svc, servicePort)
+		if targetPortName != "" {
+			var err error
+			port, err = k8sutil.ResolveNamedPort(pod, targetPortName)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// formatPorts renders ports like "8080" or "8080,5005" for log lines
+func formatPorts(ports []int) string {
+	strs := make([]string, len(ports))
+	for i, port := range ports {
+		strs[i] = strconv.Itoa(port)
+	}
+	return strings.Join(strs, ",")
+}
+
+// createPortForwarder forwards localPorts[i] to targetPorts[i]; a local port of 0
+// lets the OS pick one
+func (t *Tunnel) createPortForwarder(podName string, targetPorts, localPorts []int) (*portforward.PortForwarder, chan error, error) {
 	req := t.clientset.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Namespace(t.config.Namespace).
 		Name(podName).
 		SubResource("portforward")
 
-	transport, upgrader, err := spdy.RoundTripperFor(t.restConfig)
+	transport, upgrader, err := roundTripperFor(t.restConfig, t.config.Keepalive)
 	if err != nil {
-		t.setFailed(err)
-		return nil, nil, fmt.Errorf("failed to create round tripper: %w", err)
+		return nil, nil, t.fail(StepSPDYDial, fmt.Errorf("failed to create round tripper: %w", err))
 	}
 
 	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
@@ -91,8 +200,11 @@ func (t *Tunnel) createPortForwarder(podName string, targetPort int) (*portforwa
 	t.stopChan = make(chan struct{})
 	t.readyChan = make(chan struct{})
 
-	// port 0 = let the OS pick an available port
-	ports := []string{fmt.Sprintf("0:%d", targetPort)}
+	// all ports share one SPDY session
+	ports := make([]string, len(targetPorts))
+	for i, targetPort := range targetPorts {
+		ports[i] = fmt.Sprintf("%d:%d", localPorts[i], targetPort)
+	}
 
 	var out, errOut io.Writer = io.Discard, io.Discard
 	if t.verbose {
@@ -102,8 +214,7 @@ func (t *Tunnel) createPortForwarder(podName string, targetPort int) (*portforwa
 
 	fw, err := portforward.New(dialer, ports, t.stopChan, t.readyChan, out, errOut)
 	if err != nil {
-		t.setFailed(err)
-		return nil, nil, fmt.Errorf("failed to create port forwarder: %w", err)
+		return nil, nil, t.fail(StepSPDYDial, fmt.Errorf("failed to create port forwarder: %w", err))
 	}
 
 	// ForwardPorts blocks, so run it in background and signal errors via channel
@@ -121,41 +232,41 @@ func (t *Tunnel) waitForReady(ctx context.Context, fw *portforward.PortForwarder
 		return t.handleReady(fw, errChan)
 
 	case err := <-errChan:
-		t.setFailed(err)
-		return fmt.Errorf("port forward failed: %w", err)
+		return &StepError{Step: StepSPDYDial, Err: fmt.Errorf("port forward failed: %w", err)}
 
 	case <-ctx.Done():
+		// caller gave up, or the route's ready_timeout ran out (see Start)
 		close(t.stopChan)
 		t.mu.Lock()
-		t.state = StateIdle
+		t.setState(StateIdle)
 		t.mu.Unlock()
 		return ctx.Err()
-
-	case <-time.After(PortForwardReadyTimeout):
-		close(t.stopChan)
-		err := fmt.Errorf("timeout waiting for port forward to be ready")
-		t.setFailed(err)
-		return err
 	}
 }
 
 func (t *Tunnel) handleReady(fw *portforward.PortForwarder, errChan chan error) error {
 	forwardedPorts, err := fw.GetPorts()
 	if err != nil {
-		t.setFailed(err)
-		return fmt.Errorf("failed to get forwarded ports: %w", err)
+		return t.fail(StepSPDYDial, fmt.Errorf("failed to get forwarded ports: %w", err))
 	}
 
-	if len(forwardedPorts) == 0 {
-		err := fmt.Errorf("no ports were forwarded")
-		t.setFailed(err)
-		return err
+	configured := t.config.TargetPorts()
+	if len(forwardedPorts) != len(configured) {
+		return t.fail(StepSPDYDial, fmt.Errorf("expected %d forwarded ports, got %d", len(configured), len(forwardedPorts)))
+	}
+
+	// GetPorts keeps the order of the requested ports
+	localPorts := make(map[int]int, len(configured))
+	for i, port := range configured {
+		localPorts[port] = int(forwardedPorts[i].Local)
 	}
 
 	t.mu.Lock()
 	t.localPort = int(forwardedPorts[0].Local)
-	t.state = StateRunning
+	t.localPorts = localPorts
+	t.setState(StateRunning)
 	t.mu.Unlock()
+	t.recordLocalPorts(localPorts)
 
 	scheme := t.config.Scheme
 	if scheme == "" {
@@ -165,8 +276,8 @@ func (t *Tunnel) handleReady(fw *portforward.PortForwarder, errChan chan error)
 	if t.config.Pod != "" {
 		target = "pod/" + t.config.Pod
 	}
-	log.Printf("Tunnel started: %s://%s%s -> %s/%s:%d",
-		scheme, t.hostname, t.listenAddr, t.config.Namespace, target, t.config.Port)
+	log.Printf("Tunnel started: %s://%s%s -> %s/%s:%s",
+		scheme, t.hostname, t.listenAddr, t.config.Namespace, target, formatPorts(configured))
 
 	// the port-forward can die anytime (pod restart, network issues, etc)
 	go t.monitorErrors(errChan)
@@ -178,6 +289,7 @@ func (t *Tunnel) monitorErrors(errChan chan error) {
 	if err := <-errChan; err != nil {
 		log.Printf("[%s] Port forward error: %v", t.hostname, err)
 		t.setFailed(err)
+		t.reportHealth(err)
 	}
 }
 
@@ -185,5 +297,5 @@ func (t *Tunnel) setFailed(err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.lastError = err
-	t.state = StateFailed
+	t.setState(StateFailed)
 }
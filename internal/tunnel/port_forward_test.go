@@ -2,6 +2,7 @@ package tunnel
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/atas/autotunnel/internal/config"
@@ -228,10 +229,10 @@ func TestFindReadyPod_IgnoresNonMatchingLabels(t *testing.T) {
 }
 
 // ============================================================================
-// discoverTargetPod tests (with fake K8s clientset)
+// discoverTargetPods tests (with fake K8s clientset)
 // ============================================================================
 
-func TestDiscoverTargetPod_DirectPodMode(t *testing.T) {
+func TestDiscoverTargetPods_DirectPodMode(t *testing.T) {
 	ctx := context.Background()
 
 	tunnel := &Tunnel{
@@ -244,19 +245,23 @@ func TestDiscoverTargetPod_DirectPodMode(t *testing.T) {
 		verbose: false,
 	}
 
-	podName, port, err := tunnel.discoverTargetPod(ctx)
+	targets, err := tunnel.discoverTargetPods(ctx)
 	if err != nil {
-		t.Fatalf("discoverTargetPod() error = %v", err)
+		t.Fatalf("discoverTargetPods() error = %v", err)
 	}
+	if len(targets) != 1 {
+		t.Fatalf("discoverTargetPods() = %v, want 1 target", targets)
+	}
+	podName, ports := targets[0].pod, targets[0].ports
 	if podName != "my-pod" {
-		t.Errorf("discoverTargetPod() podName = %q, want %q", podName, "my-pod")
+		t.Errorf("discoverTargetPods() podName = %q, want %q", podName, "my-pod")
 	}
-	if port != 8080 {
-		t.Errorf("discoverTargetPod() port = %d, want %d", port, 8080)
+	if len(ports) != 1 || ports[0] != 8080 {
+		t.Errorf("discoverTargetPods() ports = %v, want [%d]", ports, 8080)
 	}
 }
 
-func TestDiscoverTargetPod_ServiceMode_NumericPort(t *testing.T) {
+func TestDiscoverTargetPods_ServiceMode_NumericPort(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a service with numeric target port
@@ -303,19 +308,23 @@ func TestDiscoverTargetPod_ServiceMode_NumericPort(t *testing.T) {
 		verbose: false,
 	}
 
-	podName, port, err := tunnel.discoverTargetPod(ctx)
+	targets, err := tunnel.discoverTargetPods(ctx)
 	if err != nil {
-		t.Fatalf("discoverTargetPod() error = %v", err)
+		t.Fatalf("discoverTargetPods() error = %v", err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("discoverTargetPods() = %v, want 1 target", targets)
 	}
+	podName, ports := targets[0].pod, targets[0].ports
 	if podName != "test-pod" {
-		t.Errorf("discoverTargetPod() podName = %q, want %q", podName, "test-pod")
+		t.Errorf("discoverTargetPods() podName = %q, want %q", podName, "test-pod")
 	}
-	if port != 8080 {
-		t.Errorf("discoverTargetPod() port = %d, want %d (target port)", port, 8080)
+	if len(ports) != 1 || ports[0] != 8080 {
+		t.Errorf("discoverTargetPods() ports = %v, want [%d] (target port)", ports, 8080)
 	}
 }
 
-func TestDiscoverTargetPod_ServiceMode_NamedPort(t *testing.T) {
+func TestDiscoverTargetPods_ServiceMode_NamedPort(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a service with named target port
@@ -372,19 +381,232 @@ func TestDiscoverTargetPod_ServiceMode_NamedPort(t *testing.T) {
 		verbose: false,
 	}
 
-	podName, port, err := tunnel.discoverTargetPod(ctx)
+	targets, err := tunnel.discoverTargetPods(ctx)
 	if err != nil {
-		t.Fatalf("discoverTargetPod() error = %v", err)
+		t.Fatalf("discoverTargetPods() error = %v", err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("discoverTargetPods() = %v, want 1 target", targets)
 	}
+	podName, ports := targets[0].pod, targets[0].ports
 	if podName != "test-pod" {
-		t.Errorf("discoverTargetPod() podName = %q, want %q", podName, "test-pod")
+		t.Errorf("discoverTargetPods() podName = %q, want %q", podName, "test-pod")
+	}
+	if len(ports) != 1 || ports[0] != 8443 {
+		t.Errorf("discoverTargetPods() ports = %v, want [%d] (resolved from named port)", ports, 8443)
+	}
+}
+
+func TestDiscoverTargetPods_ServiceMode_ExtraPorts(t *testing.T) {
+	ctx := context.Background()
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-svc",
+			Namespace: "test-ns",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "test"},
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt32(8080)},
+				{Port: 5005, TargetPort: intstr.FromString("debug")},
+			},
+		},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "test-ns",
+			Labels:    map[string]string{"app": "test"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name: "main",
+					Ports: []corev1.ContainerPort{
+						{Name: "debug", ContainerPort: 15005},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(svc, pod)
+
+	tunnel := &Tunnel{
+		hostname:  "tcp:18080",
+		clientset: fakeClient,
+		config: config.K8sRouteConfig{
+			Namespace:  "test-ns",
+			Service:    "test-svc",
+			Port:       80,
+			ExtraPorts: []int{5005},
+		},
+		verbose: false,
+	}
+
+	targets, err := tunnel.discoverTargetPods(ctx)
+	if err != nil {
+		t.Fatalf("discoverTargetPods() error = %v", err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("discoverTargetPods() = %v, want 1 target", targets)
+	}
+	ports := targets[0].ports
+	if len(ports) != 2 || ports[0] != 8080 || ports[1] != 15005 {
+		t.Errorf("discoverTargetPods() ports = %v, want [8080 15005]", ports)
+	}
+}
+
+func TestDiscoverTargetPods_ServiceMode_AlternatePods(t *testing.T) {
+	ctx := context.Background()
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-svc",
+			Namespace: "test-ns",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "test"},
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http")},
+			},
+		},
+	}
+
+	newPod := func(name string, containerPort int32, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-ns",
+				Labels:    map[string]string{"app": "test"},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+		container := corev1.Container{Name: "main"}
+		if containerPort != 0 {
+			container.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: containerPort}}
+		}
+		pod.Spec.Containers = []corev1.Container{container}
+		return pod
+	}
+
+	fakeClient := fake.NewSimpleClientset(svc,
+		newPod("pod-a", 8080, true),
+		newPod("pod-b", 0, true), // old revision without the named port
+		newPod("pod-c", 8081, false),
+		newPod("pod-d", 8082, true),
+	)
+
+	tunnel := &Tunnel{
+		hostname:  "test.localhost",
+		clientset: fakeClient,
+		config: config.K8sRouteConfig{
+			Namespace: "test-ns",
+			Service:   "test-svc",
+			Port:      80,
+		},
+	}
+
+	targets, err := tunnel.discoverTargetPods(ctx)
+	if err != nil {
+		t.Fatalf("discoverTargetPods() error = %v", err)
+	}
+
+	// ready pods first, capped at MaxPodAttempts, skipping the pod without the port
+	want := []podTarget{
+		{pod: "pod-a", ports: []int{8080}},
+		{pod: "pod-d", ports: []int{8082}},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("discoverTargetPods() = %v, want %v", targets, want)
+	}
+	for i := range want {
+		if targets[i].pod != want[i].pod || targets[i].ports[0] != want[i].ports[0] {
+			t.Errorf("discoverTargetPods()[%d] = %v, want %v", i, targets[i], want[i])
+		}
+	}
+}
+
+func TestDiscoverTargetPods_ServiceMode_PinnedPod(t *testing.T) {
+	ctx := context.Background()
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-svc",
+			Namespace: "test-ns",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "test"},
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http")},
+			},
+		},
 	}
-	if port != 8443 {
-		t.Errorf("discoverTargetPod() port = %d, want %d (resolved from named port)", port, 8443)
+
+	newPod := func(name string, containerPort int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test-ns",
+				Labels:    map[string]string{"app": "test"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "main",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: containerPort}},
+				}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+
+	fakeClient := fake.NewSimpleClientset(svc, newPod("pod-a", 8080), newPod("pod-b", 9090))
+
+	tunnel := &Tunnel{
+		hostname:  "test.localhost",
+		clientset: fakeClient,
+		config: config.K8sRouteConfig{
+			Namespace: "test-ns",
+			Service:   "test-svc",
+			Port:      80,
+		},
+		pin: NewPodPin("pod-b"),
+	}
+
+	targets, err := tunnel.discoverTargetPods(ctx)
+	if err != nil {
+		t.Fatalf("discoverTargetPods() error = %v", err)
+	}
+	if len(targets) != 1 || targets[0].pod != "pod-b" || targets[0].ports[0] != 9090 {
+		t.Errorf("discoverTargetPods() = %v, want only pod-b:9090", targets)
+	}
+
+	// a pinned pod that went away is an error, not a reason to use another pod
+	tunnel.pin.Pin("pod-gone")
+	if _, err := tunnel.discoverTargetPods(ctx); err == nil || !strings.Contains(err.Error(), "pinned pod test-ns/pod-gone") {
+		t.Errorf("discoverTargetPods() error = %v, want pinned pod error", err)
 	}
 }
 
-func TestDiscoverTargetPod_ServiceNotFound(t *testing.T) {
+func TestDiscoverTargetPods_ServiceNotFound(t *testing.T) {
 	ctx := context.Background()
 
 	fakeClient := fake.NewSimpleClientset() // No services
@@ -400,13 +622,19 @@ func TestDiscoverTargetPod_ServiceNotFound(t *testing.T) {
 		verbose: false,
 	}
 
-	_, _, err := tunnel.discoverTargetPod(ctx)
+	_, err := tunnel.discoverTargetPods(ctx)
 	if err == nil {
-		t.Fatal("discoverTargetPod() expected error for nonexistent service, got nil")
+		t.Fatal("discoverTargetPods() expected error for nonexistent service, got nil")
+	}
+	if step := FailedStep(err); step != StepServiceGet {
+		t.Errorf("FailedStep() = %q, want %q", step, StepServiceGet)
+	}
+	if FailedStep(tunnel.LastError()) != StepServiceGet {
+		t.Errorf("LastError() should carry the failed step, got %v", tunnel.LastError())
 	}
 }
 
-func TestDiscoverTargetPod_NoPodsForService(t *testing.T) {
+func TestDiscoverTargetPods_NoPodsForService(t *testing.T) {
 	ctx := context.Background()
 
 	// Create service but no pods
@@ -439,9 +667,12 @@ func TestDiscoverTargetPod_NoPodsForService(t *testing.T) {
 		verbose: false,
 	}
 
-	_, _, err := tunnel.discoverTargetPod(ctx)
+	_, err := tunnel.discoverTargetPods(ctx)
 	if err == nil {
-		t.Fatal("discoverTargetPod() expected error for no pods, got nil")
+		t.Fatal("discoverTargetPods() expected error for no pods, got nil")
+	}
+	if step := FailedStep(err); step != StepPodList {
+		t.Errorf("FailedStep() = %q, want %q", step, StepPodList)
 	}
 }
 
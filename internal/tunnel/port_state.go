@@ -0,0 +1,88 @@
+package tunnel
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/netutil"
+	"github.com/atas/autotunnel/internal/portstate"
+)
+
+// SetPortStore makes the tunnel ask for the local ports it had last time (persist_ports),
+// recorded in store under route
+func (t *Tunnel) SetPortStore(store *portstate.Store, route string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.portStore = store
+	t.portRoute = route
+}
+
+// preferredLocalPorts returns the local port to request for each configured port,
+// in TargetPorts order: the route's local_port, else the recorded one if it is
+// still free, else 0 (any port)
+func (t *Tunnel) preferredLocalPorts() []int {
+	t.mu.RLock()
+	store, route := t.portStore, t.portRoute
+	t.mu.RUnlock()
+
+	configured := t.config.TargetPorts()
+	local := make([]int, len(configured))
+	for i, port := range configured {
+		switch {
+		case i == 0 && t.config.LocalPort > 0:
+			local[i] = t.config.LocalPort
+		case store != nil:
+			if prev := store.Port(portstate.Key(route, port)); prev > 0 && localPortFree(prev) {
+				local[i] = prev
+			}
+		}
+	}
+	return local
+}
+
+// recordLocalPorts saves the ports a started tunnel got
+func (t *Tunnel) recordLocalPorts(localPorts map[int]int) {
+	t.mu.RLock()
+	store, route := t.portStore, t.portRoute
+	t.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	for port, local := range localPorts {
+		if err := store.SetPort(portstate.Key(route, port), local); err != nil {
+			log.Printf("[%s] Warning: failed to save local port: %v", t.hostname, err)
+			return
+		}
+	}
+}
+
+// localPortFree reports whether port can be bound on localhost. Another process
+// may still take it before the port-forward binds it; see isListenError.
+func localPortFree(port int) bool {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// localPortTaken explains a bind failure of the route's fixed local_port
+func (t *Tunnel) localPortTaken(err error) error {
+	if t.config.LocalPort == 0 {
+		return err
+	}
+	msg := fmt.Sprintf("local_port %d is already in use", t.config.LocalPort)
+	if owner := netutil.DescribePortOwner(t.config.LocalPort); owner != "" {
+		msg += " by " + owner
+	}
+	return &StepError{Step: StepSPDYDial, Err: fmt.Errorf("%s: %w", msg, err)}
+}
+
+// isListenError reports whether the port-forward failed to bind its local ports
+func isListenError(err error) bool {
+	return strings.Contains(err.Error(), "unable to listen on")
+}
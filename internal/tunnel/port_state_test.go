@@ -0,0 +1,121 @@
+package tunnel
+
+import (
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/portstate"
+)
+
+func TestTunnel_PreferredLocalPorts(t *testing.T) {
+	store, err := portstate.Load(filepath.Join(t.TempDir(), "ports.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.K8sRouteConfig{Context: "test", Namespace: "ns", Service: "api", Port: 8080, ExtraPorts: []int{5005}}
+	tun := NewTunnel("api.localhost", cfg, nil, nil, ":8989", false)
+	if got := tun.preferredLocalPorts(); got[0] != 0 || got[1] != 0 {
+		t.Errorf("preferredLocalPorts() = %v without a store, want all 0", got)
+	}
+
+	tun.SetPortStore(store, "api.localhost")
+	if got := tun.preferredLocalPorts(); got[0] != 0 || got[1] != 0 {
+		t.Errorf("preferredLocalPorts() = %v with nothing recorded, want all 0", got)
+	}
+
+	// a free port is reused, a taken one is not
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	free := freePort(t)
+	tun.recordLocalPorts(map[int]int{8080: free, 5005: taken.Addr().(*net.TCPAddr).Port})
+
+	got := tun.preferredLocalPorts()
+	if got[0] != free {
+		t.Errorf("port 8080 -> %d, want the recorded free port %d", got[0], free)
+	}
+	if got[1] != 0 {
+		t.Errorf("port 5005 -> %d, want 0 since the recorded port is taken", got[1])
+	}
+
+	// another tunnel of the same route, e.g. after a restart, sees the same ports
+	next := NewTunnel("api.localhost", cfg, nil, nil, ":8989", false)
+	next.SetPortStore(store, "api.localhost")
+	if got := next.preferredLocalPorts(); got[0] != free {
+		t.Errorf("new tunnel port 8080 -> %d, want %d", got[0], free)
+	}
+}
+
+func TestTunnel_PreferredLocalPorts_FixedLocalPort(t *testing.T) {
+	store, err := portstate.Load(filepath.Join(t.TempDir(), "ports.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPort(portstate.Key("docs.localhost", 80), freePort(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.K8sRouteConfig{Context: "test", Namespace: "ns", Service: "docs", Port: 80, LocalPort: 18080}
+	tun := NewTunnel("docs.localhost", cfg, nil, nil, ":8989", false)
+	tun.SetPortStore(store, "docs.localhost")
+	if got := tun.preferredLocalPorts(); got[0] != 18080 {
+		t.Errorf("preferredLocalPorts() = %v, want local_port 18080 over the recorded port", got)
+	}
+}
+
+func TestNewPool_FixedLocalPortOnFirstMember(t *testing.T) {
+	clientset, restConfig := unreachableClients(t)
+	cfg := config.K8sRouteConfig{Namespace: "ns", Pod: "docs-0", Port: 80, LocalPort: 18080, Standby: 2}
+	pool := NewPool("docs.localhost", cfg, clientset, restConfig, "", false)
+
+	for i, member := range pool.members {
+		want := 0
+		if i == 0 {
+			want = 18080
+		}
+		if got := member.preferredLocalPorts()[0]; got != want {
+			t.Errorf("member %d local port = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestTunnel_LocalPortTaken(t *testing.T) {
+	listenErr := errors.New("port forward failed: unable to listen on any of the requested ports: [{18080 80}]")
+
+	fixed := NewTunnel("docs.localhost", config.K8sRouteConfig{Port: 80, LocalPort: 18080}, nil, nil, "", false)
+	err := fixed.localPortTaken(listenErr)
+	if FailedStep(err) != StepSPDYDial || !strings.Contains(err.Error(), "local_port 18080 is already in use") {
+		t.Errorf("localPortTaken() = %v, want a spdy dial step naming local_port", err)
+	}
+
+	ephemeral := NewTunnel("api.localhost", config.K8sRouteConfig{Port: 80}, nil, nil, "", false)
+	if err := ephemeral.localPortTaken(listenErr); err != listenErr {
+		t.Errorf("localPortTaken() without local_port = %v, want the error unchanged", err)
+	}
+}
+
+func TestIsListenError(t *testing.T) {
+	if !isListenError(errors.New("port forward failed: unable to listen on any of the requested ports: [{41234 8080}]")) {
+		t.Error("Expected the port-forward bind failure to be a listen error")
+	}
+	if isListenError(errors.New("port forward failed: error upgrading connection")) {
+		t.Error("Expected other port-forward errors not to be listen errors")
+	}
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
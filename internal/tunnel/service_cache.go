@@ -0,0 +1,37 @@
+package tunnel
+
+import (
+	"context"
+
+	"github.com/atas/autotunnel/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SetServiceCache makes the tunnel look its service up in cache, where it is kept
+// under contextName, instead of fetching it on every start
+func (t *Tunnel) SetServiceCache(cache *k8sutil.ServiceCache, contextName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.services = cache
+	t.svcContext = contextName
+}
+
+// getService returns the route's service, from the service cache if there is one
+func (t *Tunnel) getService(ctx context.Context) (*corev1.Service, error) {
+	t.mu.RLock()
+	cache, contextName, clientset := t.services, t.svcContext, t.clientset
+	t.mu.RUnlock()
+	return cache.Get(ctx, clientset, contextName, t.config.Namespace, t.config.Service)
+}
+
+// forgetService drops the route's service from the cache after a failed start,
+// in case the service changed (new selector or target port) since it was cached
+func (t *Tunnel) forgetService() {
+	if t.config.Service == "" {
+		return
+	}
+	t.mu.RLock()
+	cache, contextName := t.services, t.svcContext
+	t.mu.RUnlock()
+	cache.Forget(contextName, t.config.Namespace, t.config.Service)
+}
@@ -0,0 +1,62 @@
+package tunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDiscoverTargetPods_ServiceCache(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt32(8080)}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "apps", Labels: map[string]string{"app": "web"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	fakeClient := fake.NewSimpleClientset(svc, pod)
+	gets := 0
+	fakeClient.PrependReactor("get", "services", func(k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+
+	cache := k8sutil.NewServiceCache(time.Minute)
+	cfg := config.K8sRouteConfig{Context: "dev", Namespace: "apps", Service: "web", Port: 80}
+	for range 2 {
+		// a new tunnel per start, as after an idle expiry
+		tun := NewTunnel("web.localhost", cfg, fakeClient, nil, "", false)
+		tun.SetServiceCache(cache, "dev")
+		targets, err := tun.discoverTargetPods(context.Background())
+		if err != nil || len(targets) != 1 || targets[0].ports[0] != 8080 {
+			t.Fatalf("discoverTargetPods() = %v, %v", targets, err)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("expected the second tunnel to reuse the service, got %d service lookups", gets)
+	}
+
+	// a failed start drops the service, the next one fetches it again
+	tun := NewTunnel("web.localhost", cfg, fakeClient, nil, "", false)
+	tun.SetServiceCache(cache, "dev")
+	tun.forgetService()
+	if _, err := tun.discoverTargetPods(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if gets != 2 {
+		t.Errorf("expected a lookup after forgetService, got %d service lookups", gets)
+	}
+}
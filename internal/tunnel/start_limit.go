@@ -0,0 +1,63 @@
+package tunnel
+
+import (
+	"context"
+	"log"
+)
+
+// StartLimiter bounds how many tunnels establish at once across all routes
+// (concurrent_starts), so a browser reopening 20 hostnames after a laptop
+// wakes up doesn't fire all their API calls and SPDY dials together and get
+// throttled by client-go. A nil limiter doesn't limit.
+type StartLimiter struct {
+	slots chan struct{}
+}
+
+// NewStartLimiter returns a limiter allowing n concurrent starts, or nil if n
+// isn't positive
+func NewStartLimiter(n int) *StartLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &StartLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a free slot until ctx is done. release must be called once
+// the start has finished, successfully or not.
+func (l *StartLimiter) acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// SetStartLimiter makes the tunnel's starts share limiter's slots with other tunnels
+func (t *Tunnel) SetStartLimiter(limiter *StartLimiter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.startLimiter = limiter
+}
+
+// waitStartSlot takes one of the start limiter's slots. A start that runs out of
+// time while queued leaves the tunnel idle, like one timing out while dialing.
+func (t *Tunnel) waitStartSlot(ctx context.Context) (release func(), err error) {
+	t.mu.RLock()
+	limiter := t.startLimiter
+	t.mu.RUnlock()
+
+	if release, err = limiter.acquire(ctx); err == nil || limiter == nil {
+		return release, err
+	}
+	if t.verbose {
+		log.Printf("[%s] Gave up waiting for a free tunnel start slot: %v", t.hostname, err)
+	}
+	t.mu.Lock()
+	t.setState(StateIdle)
+	t.mu.Unlock()
+	return nil, err
+}
@@ -0,0 +1,63 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestStartLimiter_Queues(t *testing.T) {
+	limiter := NewStartLimiter(1)
+	release, err := limiter.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if release2, err := limiter.acquire(context.Background()); err == nil {
+			close(acquired)
+			release2()
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second start got a slot while the first held the only one")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second start didn't get the slot once it was released")
+	}
+
+	if NewStartLimiter(0) != nil {
+		t.Error("expected no limiter for 0")
+	}
+}
+
+func TestTunnel_StartTimesOutWaitingForSlot(t *testing.T) {
+	cfg := config.K8sRouteConfig{Context: "test", Namespace: "default", Service: "web", Port: 80, ReadyTimeout: 50 * time.Millisecond}
+	tun := NewTunnel("app.localhost", cfg, fake.NewSimpleClientset(), &rest.Config{}, ":8989", false)
+
+	limiter := NewStartLimiter(1)
+	release, _ := limiter.acquire(context.Background())
+	defer release()
+	tun.SetStartLimiter(limiter)
+
+	err := tun.Start(context.Background())
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != StepReadyTimeout {
+		t.Fatalf("expected a ready timeout while queued, got %v", err)
+	}
+	if state := tun.State(); state != StateIdle {
+		t.Errorf("expected the tunnel idle after giving up, got %v", state)
+	}
+}
@@ -4,6 +4,10 @@ import "time"
 
 // Timeout constants for tunnel operations
 const (
-	// PortForwardReadyTimeout is the timeout for waiting for port forward to be ready
+	// PortForwardReadyTimeout is the default for how long a tunnel start may take,
+	// used when neither the route nor the config sets ready_timeout
 	PortForwardReadyTimeout = 30 * time.Second
+
+	// MaxPodAttempts is how many of a service's pods a tunnel tries before giving up
+	MaxPodAttempts = 3
 )
@@ -2,10 +2,14 @@ package tunnel
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/portstate"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -45,12 +49,26 @@ type Tunnel struct {
 	listenAddr string
 	verbose    bool
 
-	clientset  kubernetes.Interface
-	restConfig *rest.Config
+	clientset   kubernetes.Interface
+	restConfig  *rest.Config
+	renewClient ClientRenewer // nil: a 401 isn't retried
+
+	observeHealth HealthObserver // nil: starts and failures aren't reported
+	observeState  StateObserver  // nil: state changes aren't reported
 
 	state      State
 	localPort  int
+	localPorts map[int]int // configured target port -> local forwarded port
+	pod        string      // pod the running port-forward goes to
 	lastAccess time.Time
+	openConns  int              // long-lived connections (upgrades) holding the tunnel open
+	pin        *PodPin          // nil when the route can't be pinned
+	portStore  *portstate.Store // nil unless persist_ports is on
+	portRoute  string           // key prefix in portStore
+
+	services     *k8sutil.ServiceCache // nil: the service is fetched on every start
+	svcContext   string                // context the service is cached under
+	startLimiter *StartLimiter         // nil: starts aren't limited
 
 	stopChan  chan struct{}
 	readyChan chan struct{}
@@ -71,7 +89,32 @@ func NewTunnel(hostname string, cfg config.K8sRouteConfig, clientset kubernetes.
 	}
 }
 
+// Start opens the port-forward, waiting at most the route's ready timeout
 func (t *Tunnel) Start(ctx context.Context) error {
+	err := t.startWithTimeout(ctx)
+	if ctx.Err() == nil {
+		// a caller giving up says nothing about the route
+		t.reportHealth(err)
+	}
+	return err
+}
+
+func (t *Tunnel) startWithTimeout(ctx context.Context) error {
+	timeout := t.readyTimeout()
+	startCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := t.start(startCtx)
+	if err != nil && startCtx.Err() == nil && t.renewCredentials(err) {
+		err = t.start(startCtx)
+	}
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return &StepError{Step: StepReadyTimeout, Err: fmt.Errorf("tunnel not ready after %v (ready_timeout)", timeout)}
+	}
+	return err
+}
+
+func (t *Tunnel) start(ctx context.Context) error {
 	t.mu.Lock()
 	if t.state == StateRunning {
 		t.mu.Unlock()
@@ -81,8 +124,14 @@ func (t *Tunnel) Start(ctx context.Context) error {
 		t.mu.Unlock()
 		return t.awaitReady(ctx) // Wait for first caller to complete
 	}
-	t.state = StateStarting
+	t.setState(StateStarting)
 	t.mu.Unlock()
+
+	release, err := t.waitStartSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return t.startPortForward(ctx)
 }
 
@@ -107,7 +156,7 @@ func (t *Tunnel) awaitReady(ctx context.Context) error {
 			case StateFailed:
 				return lastErr
 			case StateIdle:
-				return t.Start(ctx) // First caller failed, retry
+				return t.start(ctx) // First caller failed, retry
 				// StateStarting: keep waiting
 			}
 		}
@@ -122,11 +171,11 @@ func (t *Tunnel) Stop() {
 		return
 	}
 
-	t.state = StateStopping
+	t.setState(StateStopping)
 	if t.stopChan != nil {
 		close(t.stopChan)
 	}
-	t.state = StateIdle
+	t.setState(StateIdle)
 }
 
 func (t *Tunnel) IsRunning() bool {
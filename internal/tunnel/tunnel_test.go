@@ -2,10 +2,15 @@ package tunnel
 
 import (
 	"context"
+	"net"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 func TestState_String(t *testing.T) {
@@ -129,6 +134,23 @@ func TestTunnel_Stop_Running(t *testing.T) {
 	}
 }
 
+func TestTunnel_Stop_ForgetsLocalPorts(t *testing.T) {
+	tunnel := &Tunnel{
+		state:      StateRunning,
+		stopChan:   make(chan struct{}),
+		localPorts: map[int]int{80: 41234},
+	}
+	if got := tunnel.LocalPortFor(80); got != 41234 {
+		t.Fatalf("LocalPortFor(80) = %d while running, want 41234", got)
+	}
+
+	tunnel.Stop()
+
+	if got := tunnel.LocalPortFor(80); got != 0 {
+		t.Errorf("LocalPortFor(80) = %d after Stop, want 0", got)
+	}
+}
+
 func TestTunnel_ConcurrentStateAccess(t *testing.T) {
 	tunnel := NewTunnel("test.localhost", config.K8sRouteConfig{}, nil, nil, ":8989", false)
 
@@ -297,3 +319,51 @@ func TestTunnel_InitialStateIsIdle(t *testing.T) {
 		t.Error("New tunnel should not be running")
 	}
 }
+
+func TestTunnel_ReadyTimeout(t *testing.T) {
+	tun := NewTunnel("test.localhost", config.K8sRouteConfig{}, nil, nil, "", false)
+	if got := tun.readyTimeout(); got != PortForwardReadyTimeout {
+		t.Errorf("readyTimeout() = %v, want default %v", got, PortForwardReadyTimeout)
+	}
+
+	tun = NewTunnel("test.localhost", config.K8sRouteConfig{ReadyTimeout: 2 * time.Minute}, nil, nil, "", false)
+	if got := tun.readyTimeout(); got != 2*time.Minute {
+		t.Errorf("readyTimeout() = %v, want 2m", got)
+	}
+}
+
+func TestTunnel_Start_ReadyTimeoutExceeded(t *testing.T) {
+	// An API server that accepts connections but never answers, like one behind a slow VPN
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	restConfig := &rest.Config{Host: "http://" + listener.Addr().String()}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatalf("Failed to create clientset: %v", err)
+	}
+
+	cfg := config.K8sRouteConfig{Namespace: "test-ns", Pod: "test-pod", Port: 8080, ReadyTimeout: 200 * time.Millisecond}
+	tun := NewTunnel("test.localhost", cfg, clientset, restConfig, "", false)
+
+	start := time.Now()
+	err = tun.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not ready after 200ms") {
+		t.Fatalf("Start() error = %v, want ready timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Start() took %v, expected to give up after ready_timeout", elapsed)
+	}
+}
@@ -0,0 +1,149 @@
+package tunnelmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+const (
+	// defaultCompletions is how many completions GET /complete returns without ?limit
+	defaultCompletions = 50
+	// CompletionKindService marks a live service, next to the config.RouteKind* kinds
+	CompletionKindService = "service"
+)
+
+// errContextNotAllowed refuses to list services of a context dynamic_host may not use
+var errContextNotAllowed = errors.New("not a context dynamic_host may use")
+
+// Completion is one GET /complete result
+type Completion struct {
+	Value     string `json:"value"` // route ID, or the service's dynamic hostname ("namespace/service:port" without dynamic_host)
+	Kind      string `json:"kind"`  // a route kind, or "service"
+	Context   string `json:"context,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Target    string `json:"target,omitempty"`
+}
+
+// RegisterCompletionHandlers adds the completion endpoint to the admin API:
+//
+//	GET /complete?prefix=api                  configured routes whose ID starts with prefix
+//	GET /complete?prefix=api&context=dev      plus the services of dev whose name or hostname does
+//
+// context may be repeated, but only with contexts dynamic_host may use, and
+// services are limited to dynamic_host_allow. Listing services is refused with
+// 403 under --locked. limit caps the results (default 50).
+func (m *Manager) RegisterCompletionHandlers(h *admin.Handler) {
+	h.HandleFunc("GET /complete", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit := defaultCompletions
+		if s := query.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				admin.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", s))
+				return
+			}
+			limit = n
+		}
+		completions, err := m.Complete(r.Context(), query.Get("prefix"), query["context"], limit)
+		if errors.Is(err, ErrLocked) || errors.Is(err, errContextNotAllowed) {
+			admin.WriteError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if err != nil {
+			admin.WriteError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, completions)
+	})
+}
+
+// Complete returns the configured routes whose ID starts with prefix, then the
+// services of contexts whose name or dynamic hostname does, up to limit results.
+// Like dynamic_host itself, services are only listed for the contexts of
+// dynamicContexts, within dynamic_host_allow, and not under --locked.
+func (m *Manager) Complete(ctx context.Context, prefix string, contexts []string, limit int) ([]Completion, error) {
+	if len(contexts) > 0 {
+		if m.config.Locked {
+			return nil, fmt.Errorf("listing services: %w", ErrLocked)
+		}
+		allowed := m.dynamicContexts()
+		for _, name := range contexts {
+			if !slices.Contains(allowed, name) {
+				return nil, fmt.Errorf("listing services of %s: %w", name, errContextNotAllowed)
+			}
+		}
+	}
+	prefix = strings.ToLower(prefix)
+	completions := []Completion{}
+	for _, route := range m.config.RouteList() {
+		if len(completions) >= limit {
+			return completions, nil
+		}
+		if strings.HasPrefix(route.ID, prefix) {
+			completions = append(completions, Completion{
+				Value:     route.ID,
+				Kind:      route.Kind,
+				Context:   route.Context,
+				Namespace: route.Namespace,
+				Target:    route.Target,
+			})
+		}
+	}
+
+	for _, contextName := range contexts {
+		if len(completions) >= limit {
+			break
+		}
+		services, err := m.serviceCompletions(ctx, contextName, prefix)
+		if err != nil {
+			return nil, err
+		}
+		completions = append(completions, services[:min(len(services), limit-len(completions))]...)
+	}
+	return completions, nil
+}
+
+// serviceCompletions lists the service ports of contextName matching prefix
+func (m *Manager) serviceCompletions(ctx context.Context, contextName, prefix string) ([]Completion, error) {
+	ctx, cancel := context.WithTimeout(ctx, dynamicExamplesTimeout)
+	defer cancel()
+	services, err := m.serviceList(ctx, contextName, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing services of %s: %w", contextName, err)
+	}
+	sortServices(services)
+
+	dynamicHost := m.config.GetDynamicHost()
+	allow := m.config.HTTP.K8s.DynamicHostAllow
+	var completions []Completion
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			route := config.K8sRouteConfig{Context: contextName, Namespace: svc.Namespace, Service: svc.Name, Port: int(port.Port)}
+			if allow.Allows(route) != nil {
+				continue
+			}
+			c := Completion{
+				Value:     fmt.Sprintf("%s/%s:%d", svc.Namespace, svc.Name, port.Port),
+				Kind:      CompletionKindService,
+				Context:   contextName,
+				Namespace: svc.Namespace,
+				Target:    fmt.Sprintf("%s:%d", svc.Name, port.Port),
+			}
+			if dynamicHost != "" && config.IsValidPodName(contextName) {
+				c.Value = m.dynamicServiceHost(contextName, svc.Namespace, svc.Name, port.Port)
+			}
+			if strings.HasPrefix(svc.Name, prefix) || strings.HasPrefix(c.Value, prefix) {
+				completions = append(completions, c)
+			}
+		}
+	}
+	return completions, nil
+}
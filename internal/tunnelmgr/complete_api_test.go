@@ -0,0 +1,134 @@
+package tunnelmgr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestComplete(t *testing.T) {
+	cfg := testConfigWithTCP(
+		map[string]config.K8sRouteConfig{
+			"api.localhost":   {Context: "dev", Namespace: "default", Service: "api", Port: 80},
+			"admin.localhost": {Context: "dev", Namespace: "default", Service: "admin", Port: 80},
+		},
+		map[int]config.TCPRouteConfig{
+			5432: {Context: "dev", Namespace: "db", Service: "postgres", Port: 5432},
+		},
+	)
+	cfg.HTTP.K8s.DynamicHost = "k8s.localhost"
+	cfg.HTTP.K8s.DynamicHostAllow = config.DynamicHostAllowConfig{Namespaces: []string{"default"}}
+	m := NewManager(cfg)
+	m.serviceList = func(ctx context.Context, contextName, namespace string) ([]corev1.Service, error) {
+		if contextName != "dev" {
+			return nil, errors.New("no such context")
+		}
+		return []corev1.Service{
+			testService("kube-system", "apiserver", corev1.ServicePort{Port: 443}),
+			testService("default", "api", corev1.ServicePort{Port: 80}),
+			testService("default", "web", corev1.ServicePort{Port: 80}),
+		}, nil
+	}
+
+	got, err := m.Complete(context.Background(), "AP", nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Value != "api.localhost" || got[0].Kind != config.RouteKindHTTP {
+		t.Errorf("Complete without contexts = %+v", got)
+	}
+
+	got, err = m.Complete(context.Background(), "ap", []string{"dev"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// kube-system is outside dynamic_host_allow.namespaces
+	want := []string{"api.localhost", "api-80.svc.default.ns.dev.cx.k8s.localhost"}
+	if len(got) != len(want) {
+		t.Fatalf("Complete = %+v, want values %v", got, want)
+	}
+	for i, c := range got {
+		if c.Value != want[i] {
+			t.Errorf("completion %d = %q, want %q", i, c.Value, want[i])
+		}
+	}
+	if got[1].Kind != CompletionKindService || got[1].Context != "dev" || got[1].Target != "api:80" {
+		t.Errorf("Unexpected service completion %+v", got[1])
+	}
+
+	if got, _ := m.Complete(context.Background(), "", []string{"dev"}, 2); len(got) != 2 {
+		t.Errorf("Expected limit 2 to be applied, got %d", len(got))
+	}
+	if _, err := m.Complete(context.Background(), "", []string{"prod"}, 10); !errors.Is(err, errContextNotAllowed) {
+		t.Errorf("Expected a context dynamic_host may not use to be refused, got %v", err)
+	}
+}
+
+func TestCompletionHandlers(t *testing.T) {
+	m := NewManager(testConfigWithTCP(map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "dev", Namespace: "default", Service: "api", Port: 80},
+	}, nil))
+	h := admin.NewHandler()
+	m.RegisterCompletionHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?prefix=api", nil))
+	var got []Completion
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GET /complete: %v (%s)", err, rec.Body)
+	}
+	if len(got) != 1 || got[0].Value != "api.localhost" {
+		t.Errorf("GET /complete = %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?prefix=x", nil))
+	if rec.Body.String() != "[]\n" {
+		t.Errorf("Expected an empty list, got %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /complete?limit=0: status %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?context=prod", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /complete?context=prod: status %d, want 403", rec.Code)
+	}
+}
+
+func TestCompletionHandlers_Locked(t *testing.T) {
+	cfg := testConfigWithTCP(map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "dev", Namespace: "default", Service: "api", Port: 80},
+	}, nil)
+	cfg.Locked = true
+	m := NewManager(cfg)
+	m.serviceList = func(ctx context.Context, contextName, namespace string) ([]corev1.Service, error) {
+		t.Error("Expected no service listing under --locked")
+		return nil, nil
+	}
+	h := admin.NewHandler()
+	m.RegisterCompletionHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?context=dev", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("GET /complete?context=dev: status %d, want 403", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/complete?prefix=api", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /complete without contexts: status %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,26 @@
+package tunnelmgr
+
+import (
+	"github.com/atas/autotunnel/internal/tunnel"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// clientRenewer is implemented by tunnels that can swap a client the API server
+// stopped accepting
+type clientRenewer interface {
+	SetClientRenewer(renew tunnel.ClientRenewer)
+}
+
+// attachClientRenewer lets a newly created tunnel rebuild its context's client
+// when its token expires. The renewed client is cached, so the context's other
+// tunnels and the jump routes pick it up too.
+func (m *Manager) attachClientRenewer(kubeconfigs []string, contextName string, execEnv map[string]string, tun TunnelHandle) {
+	renewer, ok := tun.(clientRenewer)
+	if !ok {
+		return
+	}
+	renewer.SetClientRenewer(func(stale *rest.Config) (kubernetes.Interface, *rest.Config, error) {
+		return m.clientFactory.RenewClientForContext(kubeconfigs, contextName, execEnv, stale)
+	})
+}
@@ -0,0 +1,61 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// AddDiscoveredRoute registers a TCP route found by tcp.k8s.discover on localPort,
+// replacing the one discovered there before. A changed route's running tunnel is
+// stopped so the next connection uses the new target. Configured routes win.
+func (m *Manager) AddDiscoveredRoute(localPort int, route config.TCPRouteConfig) error {
+	m.tcpTunnelsMu.Lock()
+	defer m.tcpTunnelsMu.Unlock()
+
+	if _, ok := m.config.TCP.K8s.Routes[localPort]; ok {
+		return fmt.Errorf("port %d already has a configured TCP route", localPort)
+	}
+	if old, ok := m.discovered[localPort]; ok && !reflect.DeepEqual(old, route) {
+		m.stopTCPTunnel(localPort)
+	}
+	m.discovered[localPort] = route
+	return nil
+}
+
+// RemoveDiscoveredRoute forgets the discovered route on localPort and stops its tunnel
+func (m *Manager) RemoveDiscoveredRoute(localPort int) {
+	m.tcpTunnelsMu.Lock()
+	defer m.tcpTunnelsMu.Unlock()
+
+	if _, ok := m.discovered[localPort]; !ok {
+		return
+	}
+	delete(m.discovered, localPort)
+	m.stopTCPTunnel(localPort)
+}
+
+// tcpRoute returns the configured or discovered TCP route on localPort.
+// Caller must hold m.tcpTunnelsMu.
+func (m *Manager) tcpRoute(localPort int) (config.TCPRouteConfig, bool) {
+	if route, ok := m.config.TCP.K8s.Routes[localPort]; ok {
+		return route, true
+	}
+	route, ok := m.discovered[localPort]
+	return route, ok
+}
+
+// stopTCPTunnel stops and forgets the tunnel on localPort. Caller must hold m.tcpTunnelsMu.
+func (m *Manager) stopTCPTunnel(localPort int) {
+	tun, ok := m.tcpTunnels[localPort]
+	if !ok {
+		return
+	}
+	delete(m.tcpTunnels, localPort)
+	if tun.IsRunning() {
+		log.Printf("Tunnel stopped: tcp://localhost:%d (discovered route changed)", localPort)
+		tun.Stop()
+	}
+}
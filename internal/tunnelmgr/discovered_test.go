@@ -0,0 +1,59 @@
+package tunnelmgr
+
+import (
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestDiscoveredRoutes(t *testing.T) {
+	m := NewManager(testConfigWithTCP(nil, map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432},
+	}))
+	var created []config.K8sRouteConfig
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		created = append(created, cfg)
+		return newMockTunnel(true)
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	if err := m.AddDiscoveredRoute(5432, config.TCPRouteConfig{Context: "test", Namespace: "db", Service: "other", Port: 5432}); err == nil {
+		t.Error("Expected a configured route's port to be refused")
+	}
+
+	route := config.TCPRouteConfig{Context: "test", Namespace: "cache", Service: "redis", Port: 6379}
+	if err := m.AddDiscoveredRoute(16379, route); err != nil {
+		t.Fatalf("AddDiscoveredRoute() error = %v", err)
+	}
+	tun, err := m.GetOrCreateTCPTunnel(16379)
+	if err != nil {
+		t.Fatalf("GetOrCreateTCPTunnel() error = %v", err)
+	}
+	if len(created) != 1 || created[0].Service != "redis" || created[0].Port != 6379 {
+		t.Errorf("Expected a tunnel to redis:6379, got %+v", created)
+	}
+
+	// the same route again keeps the tunnel, a changed one stops it
+	_ = m.AddDiscoveredRoute(16379, route)
+	if tun.(*mockTunnel).wasStopped() {
+		t.Error("Expected an unchanged route to keep its tunnel")
+	}
+	route.Namespace = "cache-v2"
+	_ = m.AddDiscoveredRoute(16379, route)
+	if !tun.(*mockTunnel).wasStopped() {
+		t.Error("Expected a changed route to stop its tunnel")
+	}
+
+	tun, _ = m.GetOrCreateTCPTunnel(16379)
+	m.RemoveDiscoveredRoute(16379)
+	if !tun.(*mockTunnel).wasStopped() {
+		t.Error("Expected a removed route to stop its tunnel")
+	}
+	if _, err := m.GetOrCreateTCPTunnel(16379); err == nil {
+		t.Error("Expected no route after removal")
+	}
+}
@@ -0,0 +1,67 @@
+package tunnelmgr
+
+import (
+	"time"
+)
+
+// defaultDrainGrace is how long a tunnel retired by idle cleanup keeps serving
+// requests that picked it up just before, while new ones start a fresh tunnel
+const defaultDrainGrace = 10 * time.Second
+
+// StateDraining is reported by ListTunnels for tunnels retired but not yet stopped
+const StateDraining = "draining"
+
+// drain stops a tunnel already removed from its map once it has gone a whole grace
+// period unused. A request or stream that raced the cleanup keeps it alive until
+// it finishes. Caller must have removed tun from the tunnel maps.
+func (m *Manager) drain(name string, tun TunnelHandle) {
+	if m.drainGrace <= 0 {
+		tun.Stop()
+		return
+	}
+
+	m.drainingMu.Lock()
+	m.draining[tun] = name
+	m.drainingMu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.drainingMu.Lock()
+			delete(m.draining, tun)
+			m.drainingMu.Unlock()
+		}()
+
+		timer := time.NewTimer(m.drainGrace)
+		defer timer.Stop()
+		for {
+			select {
+			case <-m.ctx.Done():
+				tun.Stop()
+				return
+			case <-timer.C:
+			}
+			// IdleDuration is 0 while tracked connections are open
+			if idle := tun.IdleDuration(); idle < m.drainGrace {
+				timer.Reset(m.drainGrace - idle)
+				continue
+			}
+			tun.Stop()
+			return
+		}
+	}()
+}
+
+// drainingTunnels returns the tunnels being drained by name: the hostname for HTTP
+// routes, "tcp:<port>" and "group:<name>" for TCP ones
+func (m *Manager) drainingTunnels() map[string]TunnelHandle {
+	m.drainingMu.Lock()
+	defer m.drainingMu.Unlock()
+
+	tunnels := make(map[string]TunnelHandle, len(m.draining))
+	for tun, name := range m.draining {
+		tunnels[name] = tun
+	}
+	return tunnels
+}
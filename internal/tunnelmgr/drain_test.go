@@ -0,0 +1,129 @@
+package tunnelmgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func (m *mockTunnel) setIdle(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.idleDuration = d
+}
+
+func waitStopped(t *testing.T, tun *mockTunnel, within time.Duration) bool {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for time.Now().Before(deadline) {
+		if tun.wasStopped() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestCleanupIdleTunnels_Drains(t *testing.T) {
+	cfg := testConfig(map[string]config.K8sRouteConfig{
+		"app.localhost": {Context: "test", Namespace: "default", Service: "app", Port: 80},
+	})
+	cfg.HTTP.IdleTimeout = 30 * time.Minute
+	m := NewManager(cfg)
+	m.drainGrace = 50 * time.Millisecond
+	defer m.Shutdown()
+
+	m.ClientFactory().InjectClient("test", nil, nil)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		return newMockTunnel(false)
+	}
+
+	old := newMockTunnel(true)
+	old.idleDuration = time.Hour
+	m.tunnels["app.localhost"] = old
+
+	m.cleanupIdleTunnels()
+
+	if old.wasStopped() {
+		t.Fatal("Expected the idle tunnel to drain before stopping")
+	}
+	infos := m.ListTunnels()
+	if len(infos) != 1 || infos[0].State != StateDraining {
+		t.Errorf("Expected the tunnel listed as draining, got %+v", infos)
+	}
+
+	// new requests get a fresh tunnel instead of the draining one
+	fresh, err := m.GetOrCreateTunnel("app.localhost", "http")
+	if err != nil {
+		t.Fatalf("GetOrCreateTunnel: %v", err)
+	}
+	if fresh == old {
+		t.Fatal("Expected a fresh tunnel while the old one drains")
+	}
+
+	if !waitStopped(t, old, time.Second) {
+		t.Fatal("Expected the drained tunnel to stop after the grace period")
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(m.drainingTunnels()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(m.drainingTunnels()); n != 0 {
+		t.Errorf("Expected no draining tunnels left, got %d", n)
+	}
+}
+
+func TestDrain_WaitsWhileInUse(t *testing.T) {
+	m := NewManager(testConfig(nil))
+	m.drainGrace = 30 * time.Millisecond
+	defer m.Shutdown()
+
+	// a request that picked up the tunnel just before cleanup keeps using it
+	tun := newMockTunnel(true)
+	m.drain("app.localhost", tun)
+
+	if waitStopped(t, tun, 100*time.Millisecond) {
+		t.Fatal("Expected a tunnel in use to keep draining")
+	}
+	tun.setIdle(time.Hour)
+	if !waitStopped(t, tun, time.Second) {
+		t.Fatal("Expected the tunnel to stop once unused for the grace period")
+	}
+}
+
+func TestDrain_StopsOnShutdown(t *testing.T) {
+	m := NewManager(testConfig(nil))
+	m.drainGrace = time.Hour
+
+	tun := newMockTunnel(true)
+	m.drain("app.localhost", tun)
+	m.Shutdown()
+
+	if !tun.wasStopped() {
+		t.Error("Expected Shutdown to stop draining tunnels")
+	}
+}
+
+func TestIdleCleanupLoop_UsesCleanupInterval(t *testing.T) {
+	cfg := testConfig(nil)
+	cfg.HTTP.IdleTimeout = 30 * time.Minute
+	cfg.CleanupInterval = time.Second // the default would be 30s
+	m := NewManager(cfg)
+	m.drainGrace = 0
+
+	idle := newMockTunnel(true)
+	idle.idleDuration = time.Hour
+	m.tunnels["idle.localhost"] = idle
+
+	m.Start()
+	defer m.Shutdown()
+
+	if !waitStopped(t, idle, 3*time.Second) {
+		t.Error("Expected the idle tunnel to be cleaned up after cleanup_interval")
+	}
+}
@@ -0,0 +1,276 @@
+package tunnelmgr
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// maxDynamicExamples is how many example URLs GET / shows per context
+	maxDynamicExamples = 5
+	// dynamicExamplesTTL is how long a context's examples are reused
+	dynamicExamplesTTL = time.Minute
+	// dynamicExamplesTimeout bounds listing one context's services
+	dynamicExamplesTimeout = 5 * time.Second
+)
+
+// serviceLister lists the services of namespace ("" = all) in a context
+type serviceLister func(ctx context.Context, contextName, namespace string) ([]corev1.Service, error)
+
+// exampleCache keeps the example URLs built per context for GET /
+type exampleCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedExamples
+}
+
+type cachedExamples struct {
+	urls    []string
+	err     error
+	fetched time.Time
+}
+
+// DynamicHostPage is what GET / on the status host shows
+type DynamicHostPage struct {
+	Suffix   string                        // dynamic_host ("" = off)
+	Locked   bool                          // dynamic_host is set but --locked turned it off
+	Patterns []string                      // hostname grammar for services and pods
+	Allow    config.DynamicHostAllowConfig // dynamic_host_allow
+	Contexts []DynamicHostContext
+}
+
+// DynamicHostContext is one context usable in dynamic hostnames, with example
+// URLs for services it has right now
+type DynamicHostContext struct {
+	Name     string
+	Examples []string
+	Error    string
+}
+
+// RegisterDynamicHostHandlers adds GET / to the admin API: a page explaining the
+// dynamic_host hostname grammar, the contexts it may use and example URLs for
+// services they have.
+func (m *Manager) RegisterDynamicHostHandlers(h *admin.Handler) {
+	h.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		page := m.DynamicHostPage(r.Context())
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dynamicHostTemplate.Execute(w, page); err != nil {
+			log.Printf("[admin] Failed to render the dynamic_host page: %v", err)
+		}
+	})
+}
+
+// DynamicHostPage gathers the page for GET /. Contexts are listed in parallel;
+// each is bounded by dynamicExamplesTimeout and cached for dynamicExamplesTTL.
+func (m *Manager) DynamicHostPage(ctx context.Context) DynamicHostPage {
+	k8s := m.config.HTTP.K8s
+	page := DynamicHostPage{
+		Suffix: m.config.GetDynamicHost(),
+		Locked: m.config.Locked && k8s.DynamicHost != "",
+		Allow:  k8s.DynamicHostAllow,
+	}
+	if page.Suffix == "" {
+		return page
+	}
+	page.Patterns = []string{
+		"{service}-{port}.svc.{namespace}.ns.{context}.cx." + page.Suffix,
+		"{pod}-{port}.pod.{namespace}.ns.{context}.cx." + page.Suffix,
+	}
+
+	names := m.dynamicContexts()
+	page.Contexts = make([]DynamicHostContext, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		page.Contexts[i].Name = name
+		if !config.IsValidPodName(name) {
+			page.Contexts[i].Error = "not usable in a hostname, add a contexts: alias for it"
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			urls, err := m.dynamicExamples(ctx, name)
+			page.Contexts[i].Examples = urls
+			if err != nil {
+				page.Contexts[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return page
+}
+
+// dynamicContexts returns dynamic_host_allow.contexts, or else the contexts the
+// config names (aliases and HTTP routes) plus the kubeconfig's current context
+func (m *Manager) dynamicContexts() []string {
+	if allowed := m.config.HTTP.K8s.DynamicHostAllow.Contexts; len(allowed) > 0 {
+		return slices.Sorted(slices.Values(allowed))
+	}
+
+	seen := make(map[string]bool)
+	for name := range m.config.Contexts {
+		seen[name] = true
+	}
+	for _, r := range m.config.HTTP.K8s.Routes {
+		if r.Context != "" {
+			seen[r.Context] = true
+		}
+	}
+	if current, err := k8sutil.CurrentContext(m.config.HTTP.K8s.ResolvedKubeconfigs); err == nil {
+		seen[current] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dynamicExamples returns up to maxDynamicExamples URLs for services of
+// contextName that dynamic_host_allow lets through
+func (m *Manager) dynamicExamples(ctx context.Context, contextName string) ([]string, error) {
+	c := m.examples
+	c.mu.Lock()
+	if e, ok := c.entries[contextName]; ok && time.Since(e.fetched) < dynamicExamplesTTL {
+		c.mu.Unlock()
+		return e.urls, e.err
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, dynamicExamplesTimeout)
+	defer cancel()
+
+	allow := m.config.HTTP.K8s.DynamicHostAllow
+	var urls []string
+	var err error
+	for _, namespace := range exampleNamespaces(allow) {
+		var services []corev1.Service
+		services, err = m.serviceList(ctx, contextName, namespace)
+		if err != nil {
+			break
+		}
+		urls = append(urls, m.exampleURLs(contextName, services, maxDynamicExamples-len(urls))...)
+		if len(urls) >= maxDynamicExamples {
+			break
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("listing services: %w", err)
+	}
+
+	c.mu.Lock()
+	c.entries[contextName] = cachedExamples{urls: urls, err: err, fetched: time.Now()}
+	c.mu.Unlock()
+	return urls, err
+}
+
+// exampleNamespaces lists the namespaces dynamic_host_allow names exactly, or ""
+// (all of them) when it has globs or no namespaces
+func exampleNamespaces(allow config.DynamicHostAllowConfig) []string {
+	if len(allow.Namespaces) == 0 {
+		return []string{""}
+	}
+	for _, ns := range allow.Namespaces {
+		if strings.ContainsAny(ns, `*?[\`) {
+			return []string{""}
+		}
+	}
+	return allow.Namespaces
+}
+
+// exampleURLs builds up to limit dynamic URLs for services, sorted by namespace and name
+func (m *Manager) exampleURLs(contextName string, services []corev1.Service, limit int) []string {
+	sortServices(services)
+
+	k8s := m.config.HTTP.K8s
+	_, listenPort, _ := net.SplitHostPort(m.config.HTTP.ListenAddr)
+	var urls []string
+	for _, svc := range services {
+		for _, port := range svc.Spec.Ports {
+			if len(urls) >= limit {
+				return urls
+			}
+			route := config.K8sRouteConfig{Context: contextName, Namespace: svc.Namespace, Service: svc.Name, Port: int(port.Port)}
+			if k8s.DynamicHostAllow.Allows(route) != nil {
+				continue
+			}
+			scheme := "http"
+			if k8s.DynamicHostTLS == config.TLSModeTerminate || port.Name == "https" || port.Port == 443 {
+				scheme = "https"
+			}
+			host := m.dynamicServiceHost(contextName, svc.Namespace, svc.Name, port.Port)
+			urls = append(urls, scheme+"://"+net.JoinHostPort(host, listenPort)+"/")
+		}
+	}
+	return urls
+}
+
+// dynamicServiceHost is the dynamic_host hostname of a service port
+func (m *Manager) dynamicServiceHost(contextName, namespace, service string, port int32) string {
+	return fmt.Sprintf("%s-%d.svc.%s.ns.%s.cx.%s", service, port, namespace, contextName, m.config.GetDynamicHost())
+}
+
+// sortServices sorts services by namespace and name
+func sortServices(services []corev1.Service) {
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Namespace != services[j].Namespace {
+			return services[i].Namespace < services[j].Namespace
+		}
+		return services[i].Name < services[j].Name
+	})
+}
+
+// listServices is the default serviceLister, through the context's cached client
+func (m *Manager) listServices(ctx context.Context, contextName, namespace string) ([]corev1.Service, error) {
+	clientset, _, err := m.GetClientForContext(m.config.HTTP.K8s.ResolvedKubeconfigs, contextName)
+	if err != nil {
+		return nil, err
+	}
+	list, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{Limit: 500})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+var dynamicHostTemplate = template.Must(template.New("dynamic_host").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>autotunnel</title>
+<style>body{font-family:sans-serif;max-width:60em;margin:2em auto;padding:0 1em}code{background:#f3f3f3;padding:0 .2em}li{margin:.2em 0}.error{color:#a00}</style>
+</head>
+<body>
+<h1>autotunnel</h1>
+<p>The status API is at <a href="/status">/status</a>.</p>
+<h2>Dynamic hostnames</h2>
+{{if .Locked}}<p>dynamic_host is turned off by <code>-locked</code>.</p>
+{{else if not .Suffix}}<p>dynamic_host is not set, so only the routes in the config file are reachable.</p>
+{{else}}<p>Any service or pod is reachable without a route, by a hostname like:</p>
+<ul>{{range .Patterns}}<li><code>{{.}}</code></li>{{end}}</ul>
+{{with .Allow}}{{if .Contexts}}<p>Allowed contexts: {{range .Contexts}}<code>{{.}}</code> {{end}}</p>{{end}}
+{{if .Namespaces}}<p>Allowed namespaces: {{range .Namespaces}}<code>{{.}}</code> {{end}}</p>{{end}}
+{{if .Ports}}<p>Allowed ports: {{range .Ports}}<code>{{.}}</code> {{end}}</p>{{end}}{{end}}
+<h3>Contexts</h3>
+{{range .Contexts}}<h4>{{.Name}}</h4>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Examples}}<ul>{{range .Examples}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul>{{else if not .Error}}<p>No services found.</p>{{end}}
+{{else}}<p>No contexts configured.</p>
+{{end}}{{end}}
+</body>
+</html>
+`))
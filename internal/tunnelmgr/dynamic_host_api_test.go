@@ -0,0 +1,105 @@
+package tunnelmgr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testService(namespace, name string, ports ...corev1.ServicePort) corev1.Service {
+	return corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+func TestDynamicHostPage(t *testing.T) {
+	cfg := &config.Config{
+		Contexts: config.ContextsConfig{"dev": {}, "arn:aws:eks:prod": {}},
+		HTTP: config.HTTPConfig{
+			ListenAddr: ":8989",
+			K8s: config.K8sConfig{
+				DynamicHost:      "k8s.localhost",
+				DynamicHostAllow: config.DynamicHostAllowConfig{Namespaces: []string{"default", "web"}},
+			},
+		},
+	}
+	m := NewManager(cfg)
+	var mu sync.Mutex
+	var listed []string
+	m.serviceList = func(ctx context.Context, contextName, namespace string) ([]corev1.Service, error) {
+		mu.Lock()
+		listed = append(listed, contextName+"/"+namespace)
+		mu.Unlock()
+		switch namespace {
+		case "default":
+			return []corev1.Service{testService("default", "nginx", corev1.ServicePort{Port: 80}, corev1.ServicePort{Name: "https", Port: 8443})}, nil
+		case "web":
+			return []corev1.Service{testService("web", "api", corev1.ServicePort{Port: 8080})}, nil
+		}
+		return nil, errors.New("unexpected namespace")
+	}
+
+	page := m.DynamicHostPage(context.Background())
+	if page.Suffix != "k8s.localhost" || len(page.Patterns) != 2 {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+
+	byName := make(map[string]DynamicHostContext)
+	for _, c := range page.Contexts {
+		byName[c.Name] = c
+	}
+	if !strings.Contains(byName["arn:aws:eks:prod"].Error, "not usable in a hostname") {
+		t.Errorf("Expected a context with colons to be flagged, got %+v", byName["arn:aws:eks:prod"])
+	}
+	want := []string{
+		"http://nginx-80.svc.default.ns.dev.cx.k8s.localhost:8989/",
+		"https://nginx-8443.svc.default.ns.dev.cx.k8s.localhost:8989/",
+		"http://api-8080.svc.web.ns.dev.cx.k8s.localhost:8989/",
+	}
+	if got := byName["dev"].Examples; !slices.Equal(got, want) {
+		t.Errorf("Examples = %v, want %v", got, want)
+	}
+
+	// examples are cached
+	before := len(listed)
+	m.DynamicHostPage(context.Background())
+	if len(listed) != before {
+		t.Errorf("Expected cached examples, services were listed again: %v", listed[before:])
+	}
+}
+
+func TestDynamicHostHandlers(t *testing.T) {
+	cfg := &config.Config{
+		Locked: true,
+		HTTP:   config.HTTPConfig{ListenAddr: ":8989", K8s: config.K8sConfig{DynamicHost: "k8s.localhost"}},
+	}
+	m := NewManager(cfg)
+	h := admin.NewHandler()
+	m.RegisterDynamicHostHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET /: status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), "turned off by <code>-locked</code>") {
+		t.Errorf("Expected the page to say dynamic_host is locked, got %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /other: status %d, want 404", rec.Code)
+	}
+}
@@ -7,6 +7,21 @@ import (
 	"github.com/atas/autotunnel/internal/config"
 )
 
+// DynamicRoute parses hostname under cfg's dynamic_host (none with --locked) and
+// applies dynamic_host_tls and dynamic_host_scheme. scheme is used when
+// dynamic_host_scheme is unset.
+func DynamicRoute(cfg *config.Config, hostname, scheme string) (*config.K8sRouteConfig, bool) {
+	route, ok := ParseDynamicHostname(hostname, cfg.GetDynamicHost(), scheme)
+	if !ok {
+		return nil, false
+	}
+	route.TLS = cfg.HTTP.K8s.DynamicHostTLS
+	if s := cfg.HTTP.K8s.DynamicHostScheme; s != "" {
+		route.Scheme = s
+	}
+	return route, true
+}
+
 // ParseDynamicHostname extracts k8s routing info from specially formatted hostnames.
 // Format: {name}-{port}.{svc|pod}.{namespace}.ns.{context}.cx.{suffix}
 // e.g. argocd-server-443.svc.argocd.ns.microk8s.cx.k8s.localhost
@@ -2,6 +2,8 @@ package tunnelmgr
 
 import (
 	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
 )
 
 func TestParseDynamicHostname(t *testing.T) {
@@ -245,3 +247,30 @@ func TestParseDynamicHostname(t *testing.T) {
 		})
 	}
 }
+
+func TestDynamicRoute(t *testing.T) {
+	cfg := &config.Config{HTTP: config.HTTPConfig{K8s: config.K8sConfig{
+		DynamicHost:       "k8s.localhost",
+		DynamicHostTLS:    config.TLSModeTerminate,
+		DynamicHostScheme: "https",
+	}}}
+	host := "argocd-server-443.svc.argocd.ns.microk8s.cx.k8s.localhost"
+
+	route, ok := DynamicRoute(cfg, host, "http")
+	if !ok {
+		t.Fatal("Expected a dynamic route")
+	}
+	if route.TLS != config.TLSModeTerminate || route.Scheme != "https" {
+		t.Errorf("Expected tls terminate and scheme https, got %q and %q", route.TLS, route.Scheme)
+	}
+
+	cfg.HTTP.K8s.DynamicHostScheme = ""
+	if route, _ := DynamicRoute(cfg, host, "http"); route.Scheme != "http" {
+		t.Errorf("Expected the request scheme without dynamic_host_scheme, got %q", route.Scheme)
+	}
+
+	cfg.Locked = true
+	if _, ok := DynamicRoute(cfg, host, "http"); ok {
+		t.Error("Expected no dynamic route when locked")
+	}
+}
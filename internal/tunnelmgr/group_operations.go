@@ -0,0 +1,100 @@
+package tunnelmgr
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// GroupServicePorts looks up the ports declared by a group's service, sorted
+func (m *Manager) GroupServicePorts(ctx context.Context, name string) ([]int, error) {
+	group, ok := m.config.TCP.K8s.Groups[name]
+	if !ok {
+		return nil, fmt.Errorf("no TCP group configured named %s", name)
+	}
+
+	clientset, _, err := m.clientFactory.GetClientForRoute(m.tcpKubeconfigs(), group.Context, group.ExecEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get k8s client for context %s: %w", group.Context, err)
+	}
+
+	svc, err := k8sutil.GetService(ctx, clientset, group.Namespace, group.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := k8sutil.ServicePorts(svc)
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("service %s/%s declares no ports", group.Namespace, group.Service)
+	}
+	return ports, nil
+}
+
+// GetOrCreateGroupTunnel returns the tunnel that forwards all of a group's service
+// ports over one port-forward session
+func (m *Manager) GetOrCreateGroupTunnel(name string, ports []int) (TunnelHandle, error) {
+	m.tcpTunnelsMu.Lock()
+	defer m.tcpTunnelsMu.Unlock()
+
+	if tun, ok := m.groupTunnels[name]; ok {
+		state := tun.State()
+		if state != tunnel.StateStopping && state != tunnel.StateFailed {
+			tun.Touch()
+			return tun, nil
+		}
+		delete(m.groupTunnels, name)
+	}
+
+	group, ok := m.config.TCP.K8s.Groups[name]
+	if !ok {
+		return nil, fmt.Errorf("no TCP group configured named %s", name)
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("TCP group %s has no ports to forward", name)
+	}
+
+	clientset, restConfig, err := m.clientFactory.GetClientForRoute(m.tcpKubeconfigs(), group.Context, group.ExecEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get k8s client for context %s: %w", group.Context, err)
+	}
+
+	newTunnel := m.tunnelFactory(
+		"group:"+name,
+		m.withGlobals(group.ToK8sRouteConfig(ports)),
+		clientset,
+		restConfig,
+		"", // No listen addr for tunnels - they pick a random port
+		m.config.Verbose,
+	)
+
+	m.attachPin("group:"+name, newTunnel)
+	m.attachPortStore("group:"+name, newTunnel)
+	m.attachClientRenewer(m.tcpKubeconfigs(), group.Context, group.ExecEnv, newTunnel)
+	m.attachServiceCache(group.Context, newTunnel)
+	m.attachStartLimiter(newTunnel)
+	m.groupTunnels[name] = newTunnel
+
+	if m.config.Verbose {
+		log.Printf("[tcp] Created tunnel for group %s -> %s/%s ports %v",
+			name, group.Namespace, group.Service, ports)
+	}
+
+	return newTunnel, nil
+}
+
+// tcpKubeconfigs returns the kubeconfigs for TCP routes, falling back to the HTTP ones
+func (m *Manager) tcpKubeconfigs() []string {
+	if len(m.config.TCP.K8s.ResolvedKubeconfigs) > 0 {
+		return m.config.TCP.K8s.ResolvedKubeconfigs
+	}
+	return m.config.HTTP.K8s.ResolvedKubeconfigs
+}
+
+// groupDisplay describes a group's target for log lines
+func groupDisplay(group config.GroupRouteConfig) string {
+	return fmt.Sprintf("%s/%s", group.Namespace, group.Service)
+}
@@ -0,0 +1,112 @@
+package tunnelmgr
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnel"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func testConfigWithGroup() *config.Config {
+	cfg := testConfigWithTCP(nil, nil)
+	cfg.TCP.K8s.Groups = map[string]config.GroupRouteConfig{
+		"api": {Context: "test", Namespace: "apps", Service: "api"},
+	}
+	return cfg
+}
+
+func TestGetOrCreateGroupTunnel_CreatesSharedTunnel(t *testing.T) {
+	m := NewManager(testConfigWithGroup())
+
+	var gotConfig config.K8sRouteConfig
+	newTunnel := newMockTunnel(false)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		gotConfig = cfg
+		return newTunnel
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	result, err := m.GetOrCreateGroupTunnel("api", []int{80, 443, 5005})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != newTunnel {
+		t.Error("Expected the factory's tunnel")
+	}
+	if gotConfig.Service != "api" || !reflect.DeepEqual(gotConfig.TargetPorts(), []int{80, 443, 5005}) {
+		t.Errorf("Expected one tunnel for all service ports, got %+v", gotConfig)
+	}
+
+	// A second port of the group reuses the same tunnel
+	again, err := m.GetOrCreateGroupTunnel("api", []int{80, 443, 5005})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again != newTunnel {
+		t.Error("Expected the existing group tunnel to be reused")
+	}
+}
+
+func TestGetOrCreateGroupTunnel_RemovesFailedTunnel(t *testing.T) {
+	m := NewManager(testConfigWithGroup())
+
+	failed := newMockTunnel(false)
+	failed.state = tunnel.StateFailed
+	m.groupTunnels["api"] = failed
+
+	newTunnel := newMockTunnel(false)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		return newTunnel
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	result, err := m.GetOrCreateGroupTunnel("api", []int{80})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != newTunnel {
+		t.Error("Expected a new tunnel, not the failed one")
+	}
+}
+
+func TestGetOrCreateGroupTunnel_Errors(t *testing.T) {
+	m := NewManager(testConfigWithGroup())
+
+	if _, err := m.GetOrCreateGroupTunnel("unknown", []int{80}); err == nil || !strings.Contains(err.Error(), "no TCP group configured") {
+		t.Errorf("Expected unknown group error, got %v", err)
+	}
+	if _, err := m.GetOrCreateGroupTunnel("api", nil); err == nil || !strings.Contains(err.Error(), "no ports") {
+		t.Errorf("Expected no ports error, got %v", err)
+	}
+	if _, err := m.GroupServicePorts(context.Background(), "unknown"); err == nil {
+		t.Error("Expected unknown group error from GroupServicePorts")
+	}
+}
+
+func TestCleanupIdleTunnels_Groups(t *testing.T) {
+	cfg := testConfigWithGroup()
+	m := NewManager(cfg)
+	m.drainGrace = 0 // stop right away, see TestCleanupIdleTunnels_Drains
+
+	idle := newMockTunnel(true)
+	idle.idleDuration = cfg.HTTP.IdleTimeout * 2
+	m.groupTunnels["api"] = idle
+
+	m.cleanupIdleTunnels()
+
+	if !idle.stopped {
+		t.Error("Expected idle group tunnel to be stopped")
+	}
+	if _, exists := m.groupTunnels["api"]; exists {
+		t.Error("Expected idle group tunnel to be removed")
+	}
+}
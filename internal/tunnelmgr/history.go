@@ -0,0 +1,102 @@
+package tunnelmgr
+
+import (
+	"sort"
+	"time"
+
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// historySize is how many events are kept per route
+const historySize = 50
+
+// TunnelEvent is a state change of a route's tunnel, or an error starting one
+type TunnelEvent struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+	Error string    `json:"error,omitempty"`
+}
+
+// TunnelHistory is the recent events of one route, oldest first
+type TunnelHistory struct {
+	Route  string        `json:"route"`
+	Events []TunnelEvent `json:"events"`
+}
+
+// eventRing keeps the last historySize events, overwriting the oldest
+type eventRing struct {
+	events []TunnelEvent
+	next   int // where the next event goes once the ring is full
+}
+
+func (r *eventRing) add(e TunnelEvent) {
+	if len(r.events) < historySize {
+		r.events = append(r.events, e)
+		return
+	}
+	r.events[r.next] = e
+	r.next = (r.next + 1) % historySize
+}
+
+// list returns the events oldest first
+func (r *eventRing) list() []TunnelEvent {
+	events := make([]TunnelEvent, 0, len(r.events))
+	events = append(events, r.events[r.next:]...)
+	return append(events, r.events[:r.next]...)
+}
+
+// stateObserver is implemented by tunnels that report their state changes
+type stateObserver interface {
+	SetStateObserver(observe tunnel.StateObserver)
+}
+
+// attachHistory records the state changes of a newly created tunnel under key
+func (m *Manager) attachHistory(key string, tun TunnelHandle) {
+	if observer, ok := tun.(stateObserver); ok {
+		observer.SetStateObserver(func(to tunnel.State, err error) {
+			m.recordEvent(key, to.String(), err)
+		})
+	}
+}
+
+// recordEvent adds an event to key's history
+func (m *Manager) recordEvent(key, state string, err error) {
+	e := TunnelEvent{Time: time.Now(), State: state}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	ring, ok := m.history[key]
+	if !ok {
+		ring = &eventRing{}
+		m.history[key] = ring
+	}
+	ring.add(e)
+}
+
+// History returns the recent events of every route that had a tunnel, by route
+func (m *Manager) History() []TunnelHistory {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	histories := make([]TunnelHistory, 0, len(m.history))
+	for key, ring := range m.history {
+		histories = append(histories, TunnelHistory{Route: key, Events: ring.list()})
+	}
+	sort.Slice(histories, func(i, j int) bool { return histories[i].Route < histories[j].Route })
+	return histories
+}
+
+// RouteHistory returns the recent events of route, false if it has none
+func (m *Manager) RouteHistory(route string) (TunnelHistory, bool) {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	ring, ok := m.history[route]
+	if !ok {
+		return TunnelHistory{}, false
+	}
+	return TunnelHistory{Route: route, Events: ring.list()}, true
+}
@@ -0,0 +1,30 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// RegisterHistoryHandlers adds the tunnel history endpoints to the admin API:
+//
+//	GET /tunnels/history          recent state changes and errors of every route
+//	GET /tunnels/{route}/history  the same for one route
+//
+// Routes are the keys HTTP tunnels are tracked under: hostnames, plus
+// "hostname:scheme" and "hostname:port" for scheme_routes and alpn_ports.
+func (m *Manager) RegisterHistoryHandlers(h *admin.Handler) {
+	h.HandleFunc("GET /tunnels/history", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, m.History())
+	})
+	h.HandleFunc("GET /tunnels/{route}/history", func(w http.ResponseWriter, r *http.Request) {
+		route := r.PathValue("route")
+		history, ok := m.RouteHistory(route)
+		if !ok {
+			admin.WriteError(w, http.StatusNotFound, fmt.Sprintf("no history for route %s", route))
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, history)
+	})
+}
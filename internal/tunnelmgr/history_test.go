@@ -0,0 +1,63 @@
+package tunnelmgr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestEventRing_KeepsLatest(t *testing.T) {
+	var r eventRing
+	for i := range historySize + 5 {
+		r.add(TunnelEvent{State: fmt.Sprint(i)})
+	}
+	events := r.list()
+	if len(events) != historySize {
+		t.Fatalf("got %d events, want %d", len(events), historySize)
+	}
+	if events[0].State != "5" || events[historySize-1].State != fmt.Sprint(historySize+4) {
+		t.Errorf("events not oldest first: first %s, last %s", events[0].State, events[historySize-1].State)
+	}
+}
+
+func TestHistoryHandlers(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{}))
+	m.recordEvent("web.localhost", "starting", nil)
+	m.recordEvent("web.localhost", "failed", errors.New("no ready pods"))
+	m.recordEvent("api.localhost", "running", nil)
+
+	h := admin.NewHandler()
+	m.RegisterHistoryHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tunnels/web.localhost/history", nil))
+	var history TunnelHistory
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET history: status %d, body %s", rec.Code, rec.Body)
+	}
+	if len(history.Events) != 2 || history.Events[1].State != "failed" || history.Events[1].Error != "no ready pods" {
+		t.Errorf("GET history = %+v", history)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tunnels/history", nil))
+	var all []TunnelHistory
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET all history: status %d, body %s", rec.Code, rec.Body)
+	}
+	if len(all) != 2 || all[0].Route != "api.localhost" {
+		t.Errorf("GET all history = %+v", all)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tunnels/other.localhost/history", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET history of unknown route: status %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,224 @@
+package tunnelmgr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeepWarmInfo is a route kept running past its idle timeout, for the admin API
+type KeepWarmInfo struct {
+	Route string    `json:"route"`
+	Until time.Time `json:"until"`
+}
+
+// IdleWarning is logged, and posted to idle_notify.webhook, shortly before a
+// watched route's tunnel idles out
+type IdleWarning struct {
+	Event     string    `json:"event"` // always "idle_expiry"
+	Route     string    `json:"route"`
+	ExpiresAt time.Time `json:"expires_at"`
+	KeptWarm  bool      `json:"kept_warm,omitempty"` // the route's keep-warm time is running out
+}
+
+// idleWebhookTimeout bounds one idle_notify.webhook request
+const idleWebhookTimeout = 5 * time.Second
+
+// KeepWarm keeps route's tunnels from idling out for d from now, replacing any
+// earlier keep-warm time. Routes are named like their tunnels: the hostname,
+// "tcp:<port>", "group:<name>" or "pg:<port>".
+func (m *Manager) KeepWarm(route string, d time.Duration) (KeepWarmInfo, error) {
+	if d <= 0 {
+		return KeepWarmInfo{}, fmt.Errorf("keep-warm duration must be positive, got %v", d)
+	}
+	if err := m.checkRoute(route); err != nil {
+		return KeepWarmInfo{}, err
+	}
+
+	until := time.Now().Add(d)
+	m.warmMu.Lock()
+	m.warm[route] = until
+	for key := range m.idleWarned {
+		if routeName(key) == route {
+			delete(m.idleWarned, key) // warn again before the new time runs out
+		}
+	}
+	m.warmMu.Unlock()
+
+	log.Printf("[%s] Kept warm until %s", route, until.Format(time.Kitchen))
+	return KeepWarmInfo{Route: route, Until: until}, nil
+}
+
+// StopKeepWarm lets route's tunnels idle out normally again
+func (m *Manager) StopKeepWarm(route string) error {
+	m.warmMu.Lock()
+	defer m.warmMu.Unlock()
+	if _, ok := m.warm[route]; !ok {
+		return fmt.Errorf("%s is not kept warm", route)
+	}
+	delete(m.warm, route)
+	return nil
+}
+
+// KeptWarm lists the routes currently kept warm
+func (m *Manager) KeptWarm() []KeepWarmInfo {
+	m.warmMu.Lock()
+	defer m.warmMu.Unlock()
+
+	now := time.Now()
+	infos := make([]KeepWarmInfo, 0, len(m.warm))
+	for route, until := range m.warm {
+		if until.After(now) {
+			infos = append(infos, KeepWarmInfo{Route: route, Until: until})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Route < infos[j].Route })
+	return infos
+}
+
+// checkRoute returns an error unless route names a configured route or, for
+// dynamic hostnames, one with a tunnel
+func (m *Manager) checkRoute(route string) error {
+	switch {
+	case strings.HasPrefix(route, "tcp:"):
+		port, err := strconv.Atoi(strings.TrimPrefix(route, "tcp:"))
+		m.tcpTunnelsMu.RLock()
+		_, ok := m.tcpRoute(port)
+		m.tcpTunnelsMu.RUnlock()
+		if err != nil || !ok {
+			return fmt.Errorf("no TCP route configured for %s", route)
+		}
+	case strings.HasPrefix(route, "group:"):
+		if _, ok := m.config.TCP.K8s.Groups[strings.TrimPrefix(route, "group:")]; !ok {
+			return fmt.Errorf("no TCP group configured for %s", route)
+		}
+	case strings.HasPrefix(route, "pg:"):
+		port, err := strconv.Atoi(strings.TrimPrefix(route, "pg:"))
+		if _, ok := m.config.TCP.K8s.Postgres[port]; err != nil || !ok {
+			return fmt.Errorf("no postgres route configured for %s", route)
+		}
+	default:
+		if _, ok := m.config.HTTP.K8s.Routes[route]; ok {
+			return nil
+		}
+		m.mu.RLock()
+		_, ok := m.tunnels[route]
+		m.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("no route configured for hostname: %s", route)
+		}
+	}
+	return nil
+}
+
+// idleExpired reports whether the tunnel tracked under key has been idle longer
+// than timeout and its route (see routeName) isn't kept warm. Until then it sends
+// the idle_notify warning once the expiry is near.
+func (m *Manager) idleExpired(key string, tun TunnelHandle, timeout time.Duration) bool {
+	route := routeName(key)
+	now := time.Now()
+	expiresAt := now.Add(timeout - tun.IdleDuration())
+
+	m.warmMu.Lock()
+	until, warm := m.warm[route]
+	if warm && !until.After(now) {
+		delete(m.warm, route)
+		warm = false
+	}
+	keptWarm := warm && until.After(expiresAt)
+	if keptWarm {
+		expiresAt = until
+	}
+
+	notify := m.config.IdleNotify
+	watched := warm || slices.Contains(notify.Routes, route)
+	var warning *IdleWarning
+	switch {
+	case !notify.Enabled() || !watched || !now.Before(expiresAt):
+	case expiresAt.Sub(now) > notify.Before:
+		delete(m.idleWarned, key) // in use again: warn next time it gets close
+	case !m.idleWarned[key]:
+		m.idleWarned[key] = true
+		warning = &IdleWarning{Event: "idle_expiry", Route: route, ExpiresAt: expiresAt, KeptWarm: keptWarm}
+	}
+	m.warmMu.Unlock()
+
+	if warning != nil {
+		m.sendIdleWarning(*warning)
+	}
+	if !now.After(expiresAt) {
+		return false
+	}
+	m.warmMu.Lock()
+	delete(m.idleWarned, key)
+	m.warmMu.Unlock()
+	return true
+}
+
+// routeName returns the route a tunnel key belongs to: HTTP tunnels for
+// scheme_routes and alpn_ports ("hostname:https", "hostname:50051") belong to the
+// hostname, postgres tunnels ("pg:<port>/<namespace>") to "pg:<port>"
+func routeName(key string) string {
+	if rest, ok := strings.CutPrefix(key, "pg:"); ok {
+		port, _, _ := strings.Cut(rest, "/")
+		return "pg:" + port
+	}
+	if strings.HasPrefix(key, "tcp:") || strings.HasPrefix(key, "group:") {
+		return key
+	}
+	hostname, _, _ := strings.Cut(key, ":")
+	return hostname
+}
+
+// sendIdleWarning logs w and posts it to idle_notify.webhook in the background
+func (m *Manager) sendIdleWarning(w IdleWarning) {
+	what := "idles out"
+	if w.KeptWarm {
+		what = "keep-warm time runs out"
+	}
+	log.Printf("[%s] Warning: %s in %v, extend it with `autotunnel keep-warm %s <duration>`",
+		w.Route, what, time.Until(w.ExpiresAt).Round(time.Second), w.Route)
+
+	webhook := m.config.IdleNotify.Webhook
+	if webhook == "" {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		if err := postIdleWarning(m.ctx, webhook, w); err != nil {
+			log.Printf("[%s] Warning: idle_notify webhook failed: %v", w.Route, err)
+		}
+	}()
+}
+
+func postIdleWarning(ctx context.Context, webhook string, w IdleWarning) error {
+	body, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, idleWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", webhook, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// RegisterKeepWarmHandlers adds the keep-warm endpoints to the admin API:
+//
+//	GET    /keep-warm                 list routes kept warm
+//	PUT    /keep-warm/{route}?for=4h  keep route's tunnels from idling out for that long
+//	DELETE /keep-warm/{route}         let route idle out normally again
+//
+// Routes are hostnames, "tcp:<port>", "group:<name>" or "pg:<port>".
+func (m *Manager) RegisterKeepWarmHandlers(h *admin.Handler) {
+	h.AddSection("keep_warm", func() any { return m.KeptWarm() })
+
+	h.HandleFunc("GET /keep-warm", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, m.KeptWarm())
+	})
+
+	h.HandleFunc("PUT /keep-warm/{route}", func(w http.ResponseWriter, r *http.Request) {
+		d, err := time.ParseDuration(r.URL.Query().Get("for"))
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid for: %v", err))
+			return
+		}
+		info, err := m.KeepWarm(r.PathValue("route"), d)
+		if err != nil {
+			admin.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, info)
+	})
+
+	h.HandleFunc("DELETE /keep-warm/{route}", func(w http.ResponseWriter, r *http.Request) {
+		if err := m.StopKeepWarm(r.PathValue("route")); err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
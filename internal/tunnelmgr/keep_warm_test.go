@@ -0,0 +1,107 @@
+package tunnelmgr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestKeepWarm_SkipsIdleCleanup(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{
+		"db.localhost": {Context: "test", Namespace: "default", Service: "db", Port: 80},
+	}))
+	m.drainGrace = 0
+	tun := newMockTunnel(true)
+	tun.idleDuration = 2 * time.Hour // past the 60m idle timeout
+	m.tunnels["db.localhost:https"] = tun
+
+	if _, err := m.KeepWarm("db.localhost", 4*time.Hour); err != nil {
+		t.Fatalf("KeepWarm: %v", err)
+	}
+	m.cleanupIdleTunnels()
+	if tun.wasStopped() {
+		t.Fatal("tunnel of a route kept warm was stopped")
+	}
+	if warm := m.KeptWarm(); len(warm) != 1 || warm[0].Route != "db.localhost" {
+		t.Errorf("KeptWarm() = %+v", warm)
+	}
+
+	if err := m.StopKeepWarm("db.localhost"); err != nil {
+		t.Fatalf("StopKeepWarm: %v", err)
+	}
+	m.cleanupIdleTunnels()
+	if !tun.wasStopped() {
+		t.Error("tunnel wasn't stopped after keep-warm ended")
+	}
+}
+
+func TestKeepWarm_UnknownRoute(t *testing.T) {
+	m := NewManager(testConfigWithTCP(nil, map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432},
+	}))
+	if _, err := m.KeepWarm("tcp:5432", time.Hour); err != nil {
+		t.Errorf("KeepWarm(tcp:5432): %v", err)
+	}
+	for _, route := range []string{"tcp:6379", "group:api", "pg:5432", "nope.localhost"} {
+		if _, err := m.KeepWarm(route, time.Hour); err == nil {
+			t.Errorf("KeepWarm(%s) accepted an unknown route", route)
+		}
+	}
+	if _, err := m.KeepWarm("tcp:5432", 0); err == nil {
+		t.Error("KeepWarm accepted a zero duration")
+	}
+}
+
+func TestIdleNotify_WarnsOnce(t *testing.T) {
+	warnings := make(chan IdleWarning, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var warning IdleWarning
+		if err := json.NewDecoder(r.Body).Decode(&warning); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		warnings <- warning
+	}))
+	defer srv.Close()
+
+	cfg := testConfigWithTCP(nil, map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432},
+	})
+	cfg.IdleNotify = config.IdleNotifyConfig{Before: 10 * time.Minute, Webhook: srv.URL, Routes: []string{"tcp:5432"}}
+	m := NewManager(cfg)
+	tun := newMockTunnel(true)
+	tun.idleDuration = 55 * time.Minute // 5m left of the 60m timeout
+	m.tcpTunnels[5432] = tun
+
+	m.cleanupIdleTunnels()
+	m.cleanupIdleTunnels()
+	m.wg.Wait()
+
+	if len(warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(warnings))
+	}
+	if w := <-warnings; w.Route != "tcp:5432" || w.Event != "idle_expiry" || w.KeptWarm {
+		t.Errorf("unexpected warning %+v", w)
+	}
+	if tun.wasStopped() {
+		t.Error("tunnel stopped before its idle timeout")
+	}
+}
+
+func TestRouteName(t *testing.T) {
+	for key, want := range map[string]string{
+		"web.localhost":       "web.localhost",
+		"web.localhost:https": "web.localhost",
+		"web.localhost:50051": "web.localhost",
+		"tcp:5432":            "tcp:5432",
+		"group:api":           "group:api",
+		"pg:5432/preview-42":  "pg:5432",
+	} {
+		if got := routeName(key); got != want {
+			t.Errorf("routeName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package tunnelmgr
+
+import (
+	"log"
+	"slices"
+
+	"github.com/atas/autotunnel/internal/k8sutil"
+)
+
+// warnContextConflicts logs contexts defined differently by several of the
+// kubeconfig files, which would otherwise go to whichever file merges first
+func (m *Manager) warnContextConflicts() {
+	conflicts := k8sutil.ContextConflicts(m.config.HTTP.K8s.ResolvedKubeconfigs)
+	if tcp := m.tcpKubeconfigs(); !slices.Equal(tcp, m.config.HTTP.K8s.ResolvedKubeconfigs) {
+		for _, c := range k8sutil.ContextConflicts(tcp) {
+			if !slices.ContainsFunc(conflicts, func(seen k8sutil.ContextConflict) bool { return seen.Context == c.Context }) {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	for _, c := range conflicts {
+		log.Printf("Warning: %s", c)
+	}
+}
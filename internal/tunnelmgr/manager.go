@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/atas/autotunnel/internal/config"
 	"github.com/atas/autotunnel/internal/k8sutil"
+	"github.com/atas/autotunnel/internal/portstate"
 	"github.com/atas/autotunnel/internal/tunnel"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -21,6 +23,9 @@ type TunnelFactory func(hostname string, cfg config.K8sRouteConfig,
 func defaultTunnelFactory(hostname string, cfg config.K8sRouteConfig,
 	clientset kubernetes.Interface, restConfig *rest.Config,
 	listenAddr string, verbose bool) TunnelHandle {
+	if cfg.Standby > 0 {
+		return tunnel.NewPool(hostname, cfg, clientset, restConfig, listenAddr, verbose)
+	}
 	return tunnel.NewTunnel(hostname, cfg, clientset, restConfig, listenAddr, verbose)
 }
 
@@ -29,13 +34,42 @@ type Manager struct {
 
 	config *config.Config
 
-	tunnels      map[string]TunnelHandle // HTTP: hostname -> tunnel
-	tcpTunnels   map[int]TunnelHandle    // TCP: local port -> tunnel
+	tunnels      map[string]TunnelHandle       // HTTP: hostname -> tunnel
+	tcpTunnels   map[int]TunnelHandle          // TCP: local port -> tunnel
+	groupTunnels map[string]TunnelHandle       // TCP groups: group name -> tunnel (guarded by tcpTunnelsMu)
+	discovered   map[int]config.TCPRouteConfig // tcp.k8s.discover routes by local port (guarded by tcpTunnelsMu)
+	pgTunnels    map[string]TunnelHandle       // postgres routes: "pg:<port>/<namespace>" -> tunnel (guarded by tcpTunnelsMu)
 	tcpTunnelsMu sync.RWMutex
 
+	pins   map[string]*tunnel.PodPin // route -> pod pin, see pin_operations.go
+	pinsMu sync.Mutex
+
+	draining   map[TunnelHandle]string // tunnels retired by idle cleanup, see drain.go
+	drainingMu sync.Mutex
+	drainGrace time.Duration
+
+	portStore *portstate.Store // nil unless persist_ports is on
+
+	contexts   map[string]*ContextStatus // prewarmed contexts by name, see prewarm.go
+	contextsMu sync.Mutex
+
+	health   map[string]*routeHealth // HTTP tunnel key -> starts and failures, see route_health.go
+	healthMu sync.Mutex
+
+	history   map[string]*eventRing // HTTP tunnel key -> recent state changes, see history.go
+	historyMu sync.Mutex
+
+	warm       map[string]time.Time // route -> kept from idling out until, see keep_warm.go
+	idleWarned map[string]bool      // tunnel keys warned about idling out (idle_notify)
+	warmMu     sync.Mutex
+
 	tunnelFactory TunnelFactory
+	serviceList   serviceLister // live services for GET / and /complete; injectable for testing
+	examples      *exampleCache // dynamic_host examples for GET /, see dynamic_host_api.go
 
 	clientFactory *k8sutil.ClientFactory
+	services      *k8sutil.ServiceCache // nil when service_cache_ttl is negative
+	startLimiter  *tunnel.StartLimiter  // nil when concurrent_starts is negative
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -44,15 +78,34 @@ type Manager struct {
 
 func NewManager(cfg *config.Config) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Manager{
+	clientFactory := k8sutil.NewClientFactory(cfg.Verbose)
+	clientFactory.SetContexts(cfg.Contexts)
+	m := &Manager{
 		config:        cfg,
 		tunnels:       make(map[string]TunnelHandle),
 		tcpTunnels:    make(map[int]TunnelHandle),
+		groupTunnels:  make(map[string]TunnelHandle),
+		discovered:    make(map[int]config.TCPRouteConfig),
+		pgTunnels:     make(map[string]TunnelHandle),
+		pins:          make(map[string]*tunnel.PodPin),
+		draining:      make(map[TunnelHandle]string),
+		drainGrace:    defaultDrainGrace,
+		portStore:     loadPortStore(cfg),
+		contexts:      make(map[string]*ContextStatus),
+		health:        make(map[string]*routeHealth),
+		history:       make(map[string]*eventRing),
+		warm:          make(map[string]time.Time),
+		idleWarned:    make(map[string]bool),
 		tunnelFactory: defaultTunnelFactory,
-		clientFactory: k8sutil.NewClientFactory(cfg.Verbose),
+		clientFactory: clientFactory,
+		services:      k8sutil.NewServiceCache(cfg.GetServiceCacheTTL()),
+		startLimiter:  tunnel.NewStartLimiter(cfg.GetConcurrentStarts()),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
+	m.serviceList = m.listServices
+	m.examples = &exampleCache{entries: make(map[string]cachedExamples)}
+	return m
 }
 
 func (m *Manager) Start() {
@@ -64,6 +117,13 @@ func (m *Manager) Start() {
 	if m.config.TCP.IdleTimeout > 0 && m.config.TCP.IdleTimeout != m.config.HTTP.IdleTimeout {
 		fmt.Printf("TCP idle timeout: %v\n", m.config.TCP.IdleTimeout)
 	}
+	m.warnTakenLocalPorts()
+	m.warnContextConflicts()
+	if m.config.Prewarm.Enabled {
+		m.wg.Add(1)
+		go m.prewarmClients()
+	}
+	m.startWarmups()
 }
 
 func (m *Manager) Shutdown() {
@@ -88,6 +148,20 @@ func (m *Manager) Shutdown() {
 		}
 	}
 	m.tcpTunnels = make(map[int]TunnelHandle)
+	for name, tunnel := range m.groupTunnels {
+		if tunnel.IsRunning() {
+			log.Printf("Stopping TCP tunnel for group %s", name)
+			tunnel.Stop()
+		}
+	}
+	m.groupTunnels = make(map[string]TunnelHandle)
+	for id, tunnel := range m.pgTunnels {
+		if tunnel.IsRunning() {
+			log.Printf("Stopping TCP tunnel %s", id)
+			tunnel.Stop()
+		}
+	}
+	m.pgTunnels = make(map[string]TunnelHandle)
 	m.tcpTunnelsMu.Unlock()
 
 	m.clientFactory.Clear()
@@ -101,6 +175,16 @@ func (m *Manager) GetClientForContext(kubeconfigPaths []string, contextName stri
 	return m.clientFactory.GetClientForContext(kubeconfigPaths, contextName)
 }
 
+// GetClientForRoute is GetClientForContext for a route setting exec_env
+func (m *Manager) GetClientForRoute(kubeconfigPaths []string, contextName string, execEnv map[string]string) (*kubernetes.Clientset, *rest.Config, error) {
+	return m.clientFactory.GetClientForRoute(kubeconfigPaths, contextName, execEnv)
+}
+
+// RenewClientForContext rebuilds a client whose credentials the API server rejected
+func (m *Manager) RenewClientForContext(kubeconfigPaths []string, contextName string, execEnv map[string]string, stale *rest.Config) (*kubernetes.Clientset, *rest.Config, error) {
+	return m.clientFactory.RenewClientForContext(kubeconfigPaths, contextName, execEnv, stale)
+}
+
 // ClientFactory returns the client factory (for testing)
 func (m *Manager) ClientFactory() *k8sutil.ClientFactory {
 	return m.clientFactory
@@ -2,6 +2,7 @@ package tunnelmgr
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -59,6 +60,10 @@ func (m *mockTunnel) LocalPort() int {
 	return m.localPort
 }
 
+func (m *mockTunnel) LocalPortFor(port int) int {
+	return m.localPort
+}
+
 func (m *mockTunnel) Scheme() string {
 	return m.scheme
 }
@@ -212,6 +217,28 @@ func TestActiveTunnels_CountsCorrectly(t *testing.T) {
 	}
 }
 
+func TestRunningTunnel_DoesNotTouch(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{}))
+
+	running := newMockTunnel(true)
+	m.tunnels["running.localhost"] = running
+	m.tunnels["stopped.localhost"] = newMockTunnel(false)
+
+	tun, ok := m.RunningTunnel("running.localhost")
+	if !ok || tun != running {
+		t.Fatalf("Expected the running tunnel, got %v, %v", tun, ok)
+	}
+	if running.touched {
+		t.Error("RunningTunnel must not touch the tunnel")
+	}
+	if _, ok := m.RunningTunnel("stopped.localhost"); ok {
+		t.Error("Expected no tunnel for a stopped route")
+	}
+	if _, ok := m.RunningTunnel("missing.localhost"); ok {
+		t.Error("Expected no tunnel for an unknown route")
+	}
+}
+
 func TestListTunnels_ReturnsInfo(t *testing.T) {
 	cfg := testConfig(map[string]config.K8sRouteConfig{})
 	m := NewManager(cfg)
@@ -245,6 +272,7 @@ func TestCleanupIdleTunnels_StopsIdleTunnels(t *testing.T) {
 	cfg := testConfig(routes)
 	cfg.HTTP.IdleTimeout = 30 * time.Minute
 	m := NewManager(cfg)
+	m.drainGrace = 0 // stop right away, see TestCleanupIdleTunnels_Drains
 
 	// Idle tunnel (exceeded timeout)
 	idleTunnel := newMockTunnel(true)
@@ -355,6 +383,160 @@ func TestGetOrCreateTunnel_DynamicRouteResolution(t *testing.T) {
 	}
 }
 
+func TestGetOrCreateTunnel_DynamicHostAllow(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+			K8s: config.K8sConfig{
+				DynamicHost:      "k8s.localhost",
+				DynamicHostAllow: config.DynamicHostAllowConfig{Namespaces: []string{"default"}},
+			},
+		},
+	}
+	m := NewManager(cfg)
+	created := 0
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		created++
+		return newMockTunnel(false)
+	}
+	m.ClientFactory().InjectClient("microk8s", nil, nil)
+
+	if _, err := m.GetOrCreateTunnel("nginx-80.svc.default.ns.microk8s.cx.k8s.localhost", "http"); err != nil {
+		t.Fatalf("Unexpected error for an allowed namespace: %v", err)
+	}
+	_, err := m.GetOrCreateTunnel("vault-8200.svc.vault.ns.microk8s.cx.k8s.localhost", "http")
+	if err == nil || !strings.Contains(err.Error(), "dynamic_host_allow.namespaces") {
+		t.Errorf("Expected the vault namespace to be denied, got %v", err)
+	}
+	if tunnel.FailedStep(err) != tunnel.StepRouteLookup {
+		t.Errorf("Expected a route lookup error, got step %q", tunnel.FailedStep(err))
+	}
+	if created != 1 {
+		t.Errorf("Expected 1 tunnel, got %d", created)
+	}
+}
+
+func TestGetOrCreateTunnel_LockedIgnoresDynamicHost(t *testing.T) {
+	cfg := &config.Config{
+		Locked: true,
+		HTTP: config.HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+			K8s: config.K8sConfig{
+				DynamicHost: "k8s.localhost",
+				Routes:      map[string]config.K8sRouteConfig{},
+			},
+		},
+	}
+	m := NewManager(cfg)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		t.Errorf("Unexpected tunnel for %s", hostname)
+		return newMockTunnel(false)
+	}
+	m.ClientFactory().InjectClient("microk8s", nil, nil)
+
+	_, err := m.GetOrCreateTunnel("nginx-80.svc.default.ns.microk8s.cx.k8s.localhost", "http")
+	if err == nil || !strings.Contains(err.Error(), "no route configured") {
+		t.Errorf("Expected no route for a dynamic hostname when locked, got %v", err)
+	}
+}
+
+func TestGetOrCreateTunnel_HostPatternResolution(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{
+			ListenAddr:  ":8989",
+			IdleTimeout: 60 * time.Minute,
+			K8s: config.K8sConfig{
+				Routes: map[string]config.K8sRouteConfig{
+					"web.dev.localhost": {Context: "dev", Namespace: "static", Service: "static-web", Port: 80},
+				},
+				HostPatterns: []config.HostPatternConfig{
+					{Suffix: ".dev.localhost", K8sRouteConfig: config.K8sRouteConfig{Context: "dev", Namespace: "${prefix}", Service: "web", Port: 8080}},
+				},
+			},
+		},
+	}
+	m := NewManager(cfg)
+
+	captured := make(map[string]config.K8sRouteConfig)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		captured[hostname] = cfg
+		return newMockTunnel(false)
+	}
+	m.ClientFactory().InjectClient("dev", nil, nil)
+
+	for _, hostname := range []string{"alice.dev.localhost", "web.dev.localhost"} {
+		if _, err := m.GetOrCreateTunnel(hostname, "http"); err != nil {
+			t.Fatalf("GetOrCreateTunnel(%s): %v", hostname, err)
+		}
+	}
+	if got := captured["alice.dev.localhost"]; got.Namespace != "alice" || got.Service != "web" || got.Port != 8080 {
+		t.Errorf("Expected pattern route alice/web:8080, got %+v", got)
+	}
+	// static routes take priority over patterns
+	if got := captured["web.dev.localhost"]; got.Namespace != "static" {
+		t.Errorf("Expected static route for web.dev.localhost, got %+v", got)
+	}
+
+	if _, err := m.GetOrCreateTunnel("Bad_NS.dev.localhost", "http"); err == nil {
+		t.Error("Expected no route when the capture isn't a valid namespace")
+	}
+}
+
+func TestGetOrCreateTunnel_SchemeRoutes(t *testing.T) {
+	cfg := testConfig(map[string]config.K8sRouteConfig{
+		"app.localhost": {
+			Context: "test", Namespace: "default", Service: "web", Port: 80,
+			SchemeRoutes: map[string]config.SchemeRouteConfig{
+				"https": {Service: "web-tls", Port: 443},
+			},
+		},
+	})
+	m := NewManager(cfg)
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	captured := make(map[string]config.K8sRouteConfig)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		captured[cfg.Service] = cfg
+		return newMockTunnel(true)
+	}
+
+	httpTun, err := m.GetOrCreateTunnel("app.localhost", "http")
+	if err != nil {
+		t.Fatalf("http: %v", err)
+	}
+	httpsTun, err := m.GetOrCreateTunnel("app.localhost", "https")
+	if err != nil {
+		t.Fatalf("https: %v", err)
+	}
+	if httpTun == httpsTun {
+		t.Fatal("Expected separate tunnels for http and https")
+	}
+	if got := captured["web-tls"]; got.Port != 443 || got.Namespace != "default" {
+		t.Errorf("Expected https tunnel to web-tls:443 in default, got %+v", got)
+	}
+	if got := captured["web"]; got.Port != 80 {
+		t.Errorf("Expected http tunnel to web:80, got %+v", got)
+	}
+	if _, ok := m.tunnels["app.localhost:https"]; !ok {
+		t.Errorf("Expected https tunnel tracked as app.localhost:https, got %v", m.tunnels)
+	}
+
+	// the same scheme reuses its tunnel
+	if again, _ := m.GetOrCreateTunnel("app.localhost", "https"); again != httpsTun {
+		t.Error("Expected the https tunnel to be reused")
+	}
+}
+
 func TestManager_ConcurrentGetOrCreateTunnel(t *testing.T) {
 	routes := map[string]config.K8sRouteConfig{
 		"test1.localhost": {Context: "test", Namespace: "default", Service: "test1", Port: 80},
@@ -419,6 +601,7 @@ func TestManager_CleanupIdleTCPTunnels(t *testing.T) {
 		},
 	}
 	m := NewManager(cfg)
+	m.drainGrace = 0 // stop right away, see TestCleanupIdleTunnels_Drains
 
 	// Add idle TCP tunnel
 	idleTunnel := newMockTunnel(true)
@@ -471,3 +654,58 @@ func TestManager_ActiveTunnels_MixedStates(t *testing.T) {
 	}
 }
 
+
+func TestGetOrCreatePortTunnel(t *testing.T) {
+	cfg := testConfig(map[string]config.K8sRouteConfig{
+		"grpc.localhost": {Context: "ctx", Namespace: "default", Service: "api", Port: 443},
+	})
+	m := NewManager(cfg)
+
+	var capturedPorts []int
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		capturedPorts = append(capturedPorts, cfg.Port)
+		return newMockTunnel(false)
+	}
+	m.ClientFactory().InjectClient("ctx", nil, nil)
+
+	defaultTun, err := m.GetOrCreateTunnel("grpc.localhost", "https")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	h2Tun, err := m.GetOrCreatePortTunnel("grpc.localhost", 8443)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if defaultTun == h2Tun {
+		t.Error("Expected a separate tunnel for the overridden port")
+	}
+
+	again, _ := m.GetOrCreatePortTunnel("grpc.localhost", 8443)
+	if again != h2Tun {
+		t.Error("Expected the port tunnel to be reused")
+	}
+	if len(capturedPorts) != 2 || capturedPorts[0] != 443 || capturedPorts[1] != 8443 {
+		t.Errorf("Expected tunnels for ports [443 8443], got %v", capturedPorts)
+	}
+	if cfg.HTTP.K8s.Routes["grpc.localhost"].Port != 443 {
+		t.Error("Route config must not be modified")
+	}
+
+	if _, err := m.GetOrCreatePortTunnel("unknown.localhost", 8443); err == nil {
+		t.Error("Expected error for unknown hostname")
+	}
+}
+
+func TestDefaultTunnelFactory_Standby(t *testing.T) {
+	single := defaultTunnelFactory("a.localhost", config.K8sRouteConfig{Service: "a", Port: 80}, nil, nil, "", false)
+	if _, ok := single.(*tunnel.Tunnel); !ok {
+		t.Errorf("Expected a plain tunnel without standby, got %T", single)
+	}
+
+	pool := defaultTunnelFactory("a.localhost", config.K8sRouteConfig{Service: "a", Port: 80, Standby: 2}, nil, nil, "", false)
+	if _, ok := pool.(*tunnel.Pool); !ok {
+		t.Errorf("Expected a pool with standby, got %T", pool)
+	}
+}
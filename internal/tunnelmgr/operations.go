@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/atas/autotunnel/internal/config"
 	"github.com/atas/autotunnel/internal/tunnel"
 )
 
@@ -12,46 +13,130 @@ func (m *Manager) GetOrCreateTunnel(hostname string, scheme string) (TunnelHandl
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if tun, ok := m.tunnels[hostname]; ok {
-		state := tun.State()
-		// Preserve tunnels that are idle, starting, or running
-		if state != tunnel.StateStopping && state != tunnel.StateFailed {
-			tun.Touch()
-			return tun, nil
-		}
-		// Only delete stopped/failed tunnels
-		delete(m.tunnels, hostname)
+	// scheme_routes get a tunnel of their own next to the route's default one
+	key := hostname
+	routeConfig, ok := m.config.HTTP.K8s.Routes[hostname]
+	if schemeRoute, hasOverride := routeConfig.ForScheme(scheme); ok && hasOverride {
+		routeConfig = schemeRoute
+		key = hostname + ":" + scheme
 	}
 
-	// static routes take priority, then try dynamic pattern matching
-	routeConfig, ok := m.config.HTTP.K8s.Routes[hostname]
+	if tun, ok := m.existingTunnel(key); ok {
+		return tun, nil
+	}
+
+	// static routes take priority, then dynamic_host, then host_patterns in order
 	if !ok {
-		if parsed, valid := ParseDynamicHostname(hostname, m.config.HTTP.K8s.DynamicHost, scheme); valid {
+		if parsed, valid := DynamicRoute(m.config, hostname, scheme); valid {
+			// one line per resolution, allowed or not, so dynamic_host use can be audited
+			target := fmt.Sprintf("%s/%s:%d (context: %s)", parsed.Namespace, parsed.TargetName(), parsed.Port, parsed.Context)
+			if err := m.config.HTTP.K8s.DynamicHostAllow.Allows(*parsed); err != nil {
+				log.Printf("[dynamic] [%s] Denied %s: %v", hostname, target, err)
+				return nil, &tunnel.StepError{Step: tunnel.StepRouteLookup, Err: fmt.Errorf("%s: %w", hostname, err)}
+			}
 			routeConfig = *parsed
 			ok = true
-			log.Printf("[dynamic] Resolved %s -> %s/%s:%d (context: %s)",
-				hostname, routeConfig.Namespace, routeConfig.Service+routeConfig.Pod, routeConfig.Port, routeConfig.Context)
+			log.Printf("[dynamic] [%s] Resolved -> %s", hostname, target)
+		} else if routeConfig, ok = m.config.HTTP.K8s.MatchHostPattern(hostname); ok {
+			log.Printf("[pattern] Resolved %s -> %s/%s:%d (context: %s)",
+				hostname, routeConfig.Namespace, routeConfig.TargetName(), routeConfig.Port, routeConfig.Context)
 		}
 	}
+	if !ok {
+		return nil, &tunnel.StepError{Step: tunnel.StepRouteLookup, Err: fmt.Errorf("no route configured for hostname: %s", hostname)}
+	}
+
+	return m.createTunnel(key, hostname, routeConfig)
+}
+
+// GetOrCreatePortTunnel returns a tunnel to hostname's static route with the backend
+// port replaced (used for alpn_ports). It is tracked as "hostname:port", next to
+// the route's default tunnel.
+func (m *Manager) GetOrCreatePortTunnel(hostname string, port int) (TunnelHandle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", hostname, port)
+	if tun, ok := m.existingTunnel(key); ok {
+		return tun, nil
+	}
+
+	routeConfig, ok := m.config.HTTP.K8s.Routes[hostname]
 	if !ok {
 		return nil, fmt.Errorf("no route configured for hostname: %s", hostname)
 	}
+	routeConfig.Port = port
+	routeConfig.LocalPort = 0 // belongs to the route's own tunnel
+
+	return m.createTunnel(key, hostname, routeConfig)
+}
+
+// RunningTunnel returns hostname's tunnel if it is running. Unlike GetOrCreateTunnel
+// it neither creates nor touches the tunnel, so callers don't keep it from idling out.
+func (m *Manager) RunningTunnel(hostname string) (TunnelHandle, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	clientset, restConfig, err := m.clientFactory.GetClientForContext(m.config.HTTP.K8s.ResolvedKubeconfigs, routeConfig.Context)
+	tun, ok := m.tunnels[hostname]
+	if !ok || !tun.IsRunning() {
+		return nil, false
+	}
+	return tun, true
+}
+
+// existingTunnel returns a reusable tunnel for key, dropping stopped/failed ones.
+// Caller must hold m.mu.
+func (m *Manager) existingTunnel(key string) (TunnelHandle, bool) {
+	if tun, ok := m.tunnels[key]; ok {
+		state := tun.State()
+		// Preserve tunnels that are idle, starting, or running
+		if state != tunnel.StateStopping && state != tunnel.StateFailed {
+			tun.Touch()
+			return tun, true
+		}
+		// Only delete stopped/failed tunnels
+		delete(m.tunnels, key)
+	}
+	return nil, false
+}
+
+// createTunnel creates and registers a tunnel under key. Caller must hold m.mu.
+func (m *Manager) createTunnel(key, hostname string, routeConfig config.K8sRouteConfig) (TunnelHandle, error) {
+	clientset, restConfig, err := m.clientFactory.GetClientForRoute(m.config.HTTP.K8s.ResolvedKubeconfigs, routeConfig.Context, routeConfig.ExecEnv)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get k8s client for context %s: %w", routeConfig.Context, err)
+		err = &tunnel.StepError{Step: tunnel.StepK8sClient, Err: fmt.Errorf("failed to get k8s client for context %s: %w", routeConfig.Context, err)}
+		m.recordHealth(key, err)
+		m.recordEvent(key, tunnel.StateFailed.String(), err)
+		return nil, err
 	}
 
-	tun := m.tunnelFactory(hostname, routeConfig, clientset, restConfig, m.config.HTTP.ListenAddr, m.config.Verbose)
-	m.tunnels[hostname] = tun
+	tun := m.tunnelFactory(hostname, m.withGlobals(routeConfig), clientset, restConfig, m.config.HTTP.ListenAddr, m.config.Verbose)
+	m.attachPin(hostname, tun)
+	m.attachPortStore(key, tun)
+	m.attachHealth(key, tun)
+	m.attachHistory(key, tun)
+	m.attachClientRenewer(m.config.HTTP.K8s.ResolvedKubeconfigs, routeConfig.Context, routeConfig.ExecEnv, tun)
+	m.attachServiceCache(routeConfig.Context, tun)
+	m.attachStartLimiter(tun)
+	m.tunnels[key] = tun
 
 	return tun, nil
 }
 
+// withGlobals fills in the top-level settings a tunnel needs: keepalive, and
+// ready_timeout unless the route sets its own
+func (m *Manager) withGlobals(route config.K8sRouteConfig) config.K8sRouteConfig {
+	route.Keepalive = m.config.Keepalive
+	if route.ReadyTimeout == 0 {
+		route.ReadyTimeout = m.config.ReadyTimeout
+	}
+	return route
+}
+
 func (m *Manager) idleCleanupLoop() {
 	defer m.wg.Done()
 
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(m.config.GetCleanupInterval())
 	defer ticker.Stop()
 
 	for {
@@ -74,12 +159,12 @@ func (m *Manager) cleanupIdleHTTPTunnels() {
 	defer m.mu.Unlock()
 
 	for hostname, tunnel := range m.tunnels {
-		if tunnel.IsRunning() && tunnel.IdleDuration() > m.config.HTTP.IdleTimeout {
+		if tunnel.IsRunning() && m.idleExpired(hostname, tunnel, m.config.HTTP.IdleTimeout) {
 			idleDur := tunnel.IdleDuration().Round(time.Second)
 			log.Printf("Tunnel stopped: %s://%s%s (idle for %v)",
 				tunnel.Scheme(), hostname, m.config.HTTP.ListenAddr, idleDur)
-			tunnel.Stop()
 			delete(m.tunnels, hostname)
+			m.drain(hostname, tunnel)
 		}
 	}
 }
@@ -95,13 +180,32 @@ func (m *Manager) cleanupIdleTCPTunnels() {
 	}
 
 	for port, tunnel := range m.tcpTunnels {
-		if tunnel.IsRunning() && tunnel.IdleDuration() > tcpIdleTimeout {
-			target := m.config.TCP.K8s.Routes[port]
+		if tunnel.IsRunning() && m.idleExpired(fmt.Sprintf("tcp:%d", port), tunnel, tcpIdleTimeout) {
+			target, _ := m.tcpRoute(port)
 			idleDur := tunnel.IdleDuration().Round(time.Second)
 			log.Printf("Tunnel stopped: tcp://localhost:%d -> %s/%s (idle for %v)",
 				port, target.Namespace, target.TargetName(), idleDur)
-			tunnel.Stop()
 			delete(m.tcpTunnels, port)
+			m.drain(fmt.Sprintf("tcp:%d", port), tunnel)
+		}
+	}
+
+	for name, tunnel := range m.groupTunnels {
+		if tunnel.IsRunning() && m.idleExpired("group:"+name, tunnel, tcpIdleTimeout) {
+			idleDur := tunnel.IdleDuration().Round(time.Second)
+			log.Printf("Tunnel stopped: tcp group %s -> %s (idle for %v)",
+				name, groupDisplay(m.config.TCP.K8s.Groups[name]), idleDur)
+			delete(m.groupTunnels, name)
+			m.drain("group:"+name, tunnel)
+		}
+	}
+
+	for id, tunnel := range m.pgTunnels {
+		if tunnel.IsRunning() && m.idleExpired(id, tunnel, tcpIdleTimeout) {
+			idleDur := tunnel.IdleDuration().Round(time.Second)
+			log.Printf("Tunnel stopped: %s (idle for %v)", id, idleDur)
+			delete(m.pgTunnels, id)
+			m.drain(id, tunnel)
 		}
 	}
 }
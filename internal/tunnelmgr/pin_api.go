@@ -0,0 +1,49 @@
+package tunnelmgr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// RegisterPinHandlers adds the pod pinning endpoints to the admin API:
+//
+//	GET    /pins                   list pins
+//	PUT    /pins/{route}?pod=name  pin route to a pod (its current pod when omitted)
+//	DELETE /pins/{route}           unpin route
+//
+// Routes are hostnames, "tcp:<port>" or "group:<name>".
+func (m *Manager) RegisterPinHandlers(h *admin.Handler) {
+	h.AddSection("pins", func() any { return m.Pins() })
+
+	h.HandleFunc("GET /pins", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, m.Pins())
+	})
+
+	h.HandleFunc("PUT /pins/{route}", func(w http.ResponseWriter, r *http.Request) {
+		route := r.PathValue("route")
+		pod, err := m.PinPod(route, r.URL.Query().Get("pod"))
+		if err != nil {
+			admin.WriteError(w, pinErrorStatus(err), err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, PinInfo{Route: route, Pod: pod})
+	})
+
+	h.HandleFunc("DELETE /pins/{route}", func(w http.ResponseWriter, r *http.Request) {
+		if err := m.UnpinPod(r.PathValue("route")); err != nil {
+			admin.WriteError(w, pinErrorStatus(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// pinErrorStatus is 403 for pins refused by --locked, 400 otherwise
+func pinErrorStatus(err error) int {
+	if errors.Is(err, ErrLocked) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
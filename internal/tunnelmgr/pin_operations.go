@@ -0,0 +1,181 @@
+package tunnelmgr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// podPinner is implemented by tunnels that can be pinned to a pod
+type podPinner interface {
+	SetPodPin(pin *tunnel.PodPin)
+}
+
+// PinInfo describes a pinnable route for the admin API
+type PinInfo struct {
+	Route   string `json:"route"`
+	Pod     string `json:"pod,omitempty"`     // pinned pod
+	Current string `json:"current,omitempty"` // pod the last tunnel forwarded to
+	Auto    bool   `json:"auto,omitempty"`
+}
+
+// attachPin hands route's pod pin to a newly created tunnel. Pins are kept per
+// route, not per tunnel, so they survive tunnels being stopped and recreated.
+func (m *Manager) attachPin(route string, tun TunnelHandle) {
+	pinner, ok := tun.(podPinner)
+	if !ok {
+		return
+	}
+	pin, err := m.podPin(route)
+	if err != nil {
+		return // not pinnable: dynamic or pod route
+	}
+	pinner.SetPodPin(pin)
+}
+
+// podPin returns route's pin, created from its pin_pod setting on first use
+func (m *Manager) podPin(route string) (*tunnel.PodPin, error) {
+	pinPod, err := m.routePinPod(route)
+	if err != nil {
+		return nil, err
+	}
+
+	m.pinsMu.Lock()
+	defer m.pinsMu.Unlock()
+	pin, ok := m.pins[route]
+	if !ok {
+		pin = tunnel.NewPodPin(pinPod)
+		m.pins[route] = pin
+	}
+	return pin, nil
+}
+
+// routePinPod returns the pin_pod setting of a pinnable route. Routes are named
+// like their tunnels: the hostname, "tcp:<port>" or "group:<name>".
+func (m *Manager) routePinPod(route string) (string, error) {
+	var pinPod, pod string
+	switch {
+	case strings.HasPrefix(route, "tcp:"):
+		port, err := strconv.Atoi(strings.TrimPrefix(route, "tcp:"))
+		r, ok := m.config.TCP.K8s.Routes[port]
+		if err != nil || !ok {
+			return "", fmt.Errorf("no TCP route configured for %s", route)
+		}
+		pinPod, pod = r.PinPod, r.Pod
+	case strings.HasPrefix(route, "group:"):
+		g, ok := m.config.TCP.K8s.Groups[strings.TrimPrefix(route, "group:")]
+		if !ok {
+			return "", fmt.Errorf("no TCP group configured for %s", route)
+		}
+		pinPod = g.PinPod
+	default:
+		r, ok := m.config.HTTP.K8s.Routes[route]
+		if !ok {
+			return "", fmt.Errorf("no route configured for hostname: %s", route)
+		}
+		pinPod, pod = r.PinPod, r.Pod
+	}
+	if pod != "" {
+		return "", fmt.Errorf("%s targets pod %s directly and can't be pinned", route, pod)
+	}
+	return pinPod, nil
+}
+
+// ErrLocked is returned for changes --locked doesn't allow at runtime
+var ErrLocked = errors.New("disabled by --locked")
+
+// PinPod pins route to pod, or to the pod it currently forwards to when pod is "".
+// Running tunnels on another pod are stopped so the next connection uses the pin.
+func (m *Manager) PinPod(route, pod string) (string, error) {
+	if m.config.Locked {
+		return "", fmt.Errorf("pinning %s: %w", route, ErrLocked)
+	}
+	pin, err := m.podPin(route)
+	if err != nil {
+		return "", err
+	}
+
+	current := pin.Current()
+	pinned := pin.Pin(pod)
+	if pinned == "" {
+		return "", fmt.Errorf("%s has not forwarded to a pod yet, name the pod to pin", route)
+	}
+	log.Printf("[%s] Pinned to pod %s", route, pinned)
+
+	if current != "" && current != pinned {
+		m.stopRouteTunnels(route)
+	}
+	return pinned, nil
+}
+
+// UnpinPod lets route pick any ready pod again from its next tunnel start
+func (m *Manager) UnpinPod(route string) error {
+	if m.config.Locked {
+		return fmt.Errorf("unpinning %s: %w", route, ErrLocked)
+	}
+	pin, err := m.podPin(route)
+	if err != nil {
+		return err
+	}
+	pin.Unpin()
+	log.Printf("[%s] Unpinned", route)
+	return nil
+}
+
+// Pins lists the routes that have a pin, sorted by route
+func (m *Manager) Pins() []PinInfo {
+	m.pinsMu.Lock()
+	defer m.pinsMu.Unlock()
+
+	infos := make([]PinInfo, 0, len(m.pins))
+	for route, pin := range m.pins {
+		infos = append(infos, PinInfo{
+			Route:   route,
+			Pod:     pin.Pod(),
+			Current: pin.Current(),
+			Auto:    pin.Auto(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Route < infos[j].Route })
+	return infos
+}
+
+// stopRouteTunnels stops route's running tunnels; they restart on the next connection
+func (m *Manager) stopRouteTunnels(route string) {
+	var tunnels []TunnelHandle
+	switch {
+	case strings.HasPrefix(route, "tcp:"):
+		port, _ := strconv.Atoi(strings.TrimPrefix(route, "tcp:"))
+		m.tcpTunnelsMu.RLock()
+		if tun, ok := m.tcpTunnels[port]; ok {
+			tunnels = append(tunnels, tun)
+		}
+		m.tcpTunnelsMu.RUnlock()
+	case strings.HasPrefix(route, "group:"):
+		m.tcpTunnelsMu.RLock()
+		if tun, ok := m.groupTunnels[strings.TrimPrefix(route, "group:")]; ok {
+			tunnels = append(tunnels, tun)
+		}
+		m.tcpTunnelsMu.RUnlock()
+	default:
+		// the route's default tunnel plus its alpn_ports tunnels ("hostname:port")
+		m.mu.RLock()
+		for key, tun := range m.tunnels {
+			if key == route || strings.HasPrefix(key, route+":") {
+				tunnels = append(tunnels, tun)
+			}
+		}
+		m.mu.RUnlock()
+	}
+
+	for _, tun := range tunnels {
+		if tun.IsRunning() {
+			tun.Stop()
+		}
+	}
+}
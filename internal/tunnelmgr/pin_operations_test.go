@@ -0,0 +1,167 @@
+package tunnelmgr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnel"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// pinnableMockTunnel records the pod pin the manager hands it
+type pinnableMockTunnel struct {
+	*mockTunnel
+	pin *tunnel.PodPin
+}
+
+func (m *pinnableMockTunnel) SetPodPin(pin *tunnel.PodPin) {
+	m.pin = pin
+}
+
+func testConfigWithPins() *config.Config {
+	return testConfigWithTCP(
+		map[string]config.K8sRouteConfig{
+			"web.localhost": {Context: "test", Namespace: "default", Service: "web", Port: 80, PinPod: "auto"},
+			"pod.localhost": {Context: "test", Namespace: "default", Pod: "web-0", Port: 80},
+		},
+		map[int]config.TCPRouteConfig{
+			5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432, PinPod: "postgres-1"},
+		},
+	)
+}
+
+func TestAttachPin_SurvivesTunnelRecreation(t *testing.T) {
+	m := NewManager(testConfigWithPins())
+
+	var created []*pinnableMockTunnel
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		tun := &pinnableMockTunnel{mockTunnel: newMockTunnel(false)}
+		created = append(created, tun)
+		return tun
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	if _, err := m.GetOrCreateTCPTunnel(5432); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	created[0].state = tunnel.StateFailed
+	if _, err := m.GetOrCreateTCPTunnel(5432); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(created) != 2 {
+		t.Fatalf("Expected the failed tunnel to be replaced, got %d tunnels", len(created))
+	}
+	if created[0].pin == nil || created[0].pin != created[1].pin {
+		t.Error("Expected both tunnels of the route to share one pin")
+	}
+	if created[1].pin.Pod() != "postgres-1" {
+		t.Errorf("Expected pin_pod from config, got %q", created[1].pin.Pod())
+	}
+
+	// pod routes target their pod directly and get no pin
+	if _, err := m.GetOrCreateTunnel("pod.localhost", "http"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if created[2].pin != nil {
+		t.Error("Expected no pin for a pod route")
+	}
+}
+
+func TestPinPod(t *testing.T) {
+	m := NewManager(testConfigWithPins())
+
+	tests := []struct {
+		route   string
+		pod     string
+		want    string
+		wantErr string
+	}{
+		{route: "web.localhost", pod: "web-abc", want: "web-abc"},
+		{route: "tcp:5432", pod: "postgres-0", want: "postgres-0"},
+		{route: "web.localhost", want: "", wantErr: "has not forwarded to a pod yet"},
+		{route: "pod.localhost", pod: "web-1", wantErr: "can't be pinned"},
+		{route: "tcp:9999", pod: "x", wantErr: "no TCP route configured"},
+		{route: "group:none", pod: "x", wantErr: "no TCP group configured"},
+		{route: "other.localhost", pod: "x", wantErr: "no route configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route+"/"+tt.pod, func(t *testing.T) {
+			got, err := m.PinPod(tt.route, tt.pod)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("PinPod() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PinPod() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PinPod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinHandlers(t *testing.T) {
+	m := NewManager(testConfigWithPins())
+	h := admin.NewHandler()
+	m.RegisterPinHandlers(h)
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/pins/tcp:5432?pod=postgres-2"); rec.Code != http.StatusOK {
+		t.Fatalf("PUT /pins: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/pins/pod.localhost?pod=x"); rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT /pins on a pod route: status %d, want 400", rec.Code)
+	}
+
+	var pins []PinInfo
+	if err := json.Unmarshal(do(http.MethodGet, "/pins").Body.Bytes(), &pins); err != nil {
+		t.Fatalf("GET /pins: %v", err)
+	}
+	if len(pins) != 1 || pins[0].Route != "tcp:5432" || pins[0].Pod != "postgres-2" {
+		t.Errorf("GET /pins = %+v", pins)
+	}
+
+	if rec := do(http.MethodDelete, "/pins/tcp:5432"); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE /pins: status %d", rec.Code)
+	}
+	if pod := m.Pins()[0].Pod; pod != "" {
+		t.Errorf("Expected route to be unpinned, still pinned to %q", pod)
+	}
+}
+
+func TestPinHandlers_Locked(t *testing.T) {
+	cfg := testConfigWithPins()
+	cfg.Locked = true
+	m := NewManager(cfg)
+	h := admin.NewHandler()
+	m.RegisterPinHandlers(h)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, "/pins/tcp:5432?pod=postgres-2", nil))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s /pins when locked: status %d, want 403", method, rec.Code)
+		}
+	}
+	if pins := m.Pins(); len(pins) != 0 {
+		t.Errorf("Expected no pins when locked, got %+v", pins)
+	}
+}
@@ -0,0 +1,61 @@
+package tunnelmgr
+
+import (
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/netutil"
+	"github.com/atas/autotunnel/internal/portstate"
+)
+
+// portPersister is implemented by tunnels that can reuse their previous local ports
+type portPersister interface {
+	SetPortStore(store *portstate.Store, route string)
+}
+
+// loadPortStore opens the persist_ports state file, nil when it is off or unreadable
+func loadPortStore(cfg *config.Config) *portstate.Store {
+	path := cfg.GetPortStateFile()
+	if path == "" {
+		return nil
+	}
+	store, err := portstate.Load(path)
+	if err != nil {
+		log.Printf("Warning: %v, local ports won't be reused", err)
+		return nil
+	}
+	return store
+}
+
+// warnTakenLocalPorts logs HTTP routes whose local_port another process already
+// holds; their tunnels would fail on the first request
+func (m *Manager) warnTakenLocalPorts() {
+	for hostname, route := range m.config.HTTP.K8s.Routes {
+		if route.LocalPort == 0 {
+			continue
+		}
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(route.LocalPort)))
+		if err != nil {
+			owner := netutil.DescribePortOwner(route.LocalPort)
+			if owner == "" {
+				owner = "another process"
+			}
+			log.Printf("[%s] Warning: local_port %d is in use by %s, the tunnel won't start until it is free",
+				hostname, route.LocalPort, owner)
+			continue
+		}
+		l.Close()
+	}
+}
+
+// attachPortStore lets a newly created tunnel reuse the ports its route had before
+func (m *Manager) attachPortStore(route string, tun TunnelHandle) {
+	if m.portStore == nil {
+		return
+	}
+	if persister, ok := tun.(portPersister); ok {
+		persister.SetPortStore(m.portStore, route)
+	}
+}
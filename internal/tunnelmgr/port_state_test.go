@@ -0,0 +1,91 @@
+package tunnelmgr
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/portstate"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// persistingMockTunnel records the port store the manager hands it
+type persistingMockTunnel struct {
+	*mockTunnel
+	store *portstate.Store
+	route string
+}
+
+func (m *persistingMockTunnel) SetPortStore(store *portstate.Store, route string) {
+	m.store, m.route = store, route
+}
+
+func TestAttachPortStore(t *testing.T) {
+	cfg := testConfigWithPins()
+	cfg.PersistPorts = true
+	cfg.PortStateFile = filepath.Join(t.TempDir(), "ports.json")
+
+	m := NewManager(cfg)
+	var created []*persistingMockTunnel
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		tun := &persistingMockTunnel{mockTunnel: newMockTunnel(false)}
+		created = append(created, tun)
+		return tun
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	if _, err := m.GetOrCreateTunnel("web.localhost", "http"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := m.GetOrCreateTCPTunnel(5432); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(created) != 2 {
+		t.Fatalf("Expected 2 tunnels, got %d", len(created))
+	}
+	for i, want := range []string{"web.localhost", "tcp:5432"} {
+		if created[i].store == nil || created[i].route != want {
+			t.Errorf("tunnel %d: store set %v, route %q, want %q", i, created[i].store != nil, created[i].route, want)
+		}
+	}
+}
+
+func TestAttachPortStore_Disabled(t *testing.T) {
+	m := NewManager(testConfigWithPins())
+	if m.portStore != nil {
+		t.Error("Expected no port store without persist_ports")
+	}
+}
+
+func TestWarnTakenLocalPorts(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	port := taken.Addr().(*net.TCPAddr).Port
+
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{
+		"api.localhost": {Context: "test", Namespace: "default", Service: "api", Port: 80, LocalPort: port},
+	}))
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	m.warnTakenLocalPorts()
+
+	want := fmt.Sprintf("[api.localhost] Warning: local_port %d is in use", port)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected log containing %q, got %q", want, buf.String())
+	}
+}
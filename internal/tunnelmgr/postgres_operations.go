@@ -0,0 +1,60 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// PostgresTunnelID names the tunnel of a postgres route to one namespace
+func PostgresTunnelID(localPort int, namespace string) string {
+	return fmt.Sprintf("pg:%d/%s", localPort, namespace)
+}
+
+// GetOrCreatePostgresTunnel returns the tunnel of the postgres route on localPort
+// to the service in namespace. Each namespace gets a tunnel of its own.
+func (m *Manager) GetOrCreatePostgresTunnel(localPort int, route config.TCPRouteConfig) (TunnelHandle, error) {
+	m.tcpTunnelsMu.Lock()
+	defer m.tcpTunnelsMu.Unlock()
+
+	tunnelID := PostgresTunnelID(localPort, route.Namespace)
+	if tun, ok := m.pgTunnels[tunnelID]; ok {
+		state := tun.State()
+		if state != tunnel.StateStopping && state != tunnel.StateFailed {
+			tun.Touch()
+			return tun, nil
+		}
+		delete(m.pgTunnels, tunnelID)
+	}
+
+	if _, ok := m.config.TCP.K8s.Postgres[localPort]; !ok {
+		return nil, fmt.Errorf("no postgres route configured for port %d", localPort)
+	}
+
+	clientset, restConfig, err := m.clientFactory.GetClientForRoute(m.tcpKubeconfigs(), route.Context, route.ExecEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get k8s client for context %s: %w", route.Context, err)
+	}
+
+	newTunnel := m.tunnelFactory(
+		tunnelID,
+		m.withGlobals(route.ToK8sRouteConfig()),
+		clientset,
+		restConfig,
+		"", // No listen addr for tunnels - they pick a random port
+		m.config.Verbose,
+	)
+	m.attachPortStore(tunnelID, newTunnel)
+	m.attachClientRenewer(m.tcpKubeconfigs(), route.Context, route.ExecEnv, newTunnel)
+	m.attachServiceCache(route.Context, newTunnel)
+	m.attachStartLimiter(newTunnel)
+	m.pgTunnels[tunnelID] = newTunnel
+
+	if m.config.Verbose {
+		log.Printf("[tcp] Created tunnel for port %d -> %s/%s:%d (postgres)",
+			localPort, route.Namespace, route.Service, route.Port)
+	}
+	return newTunnel, nil
+}
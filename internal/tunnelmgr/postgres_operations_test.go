@@ -0,0 +1,49 @@
+package tunnelmgr
+
+import (
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestGetOrCreatePostgresTunnel_PerNamespace(t *testing.T) {
+	cfg := testConfigWithTCP(nil, nil)
+	cfg.TCP.K8s.Postgres = map[int]config.PostgresRouteConfig{
+		15432: {Context: "test", Service: "postgres", Port: 5432},
+	}
+	m := NewManager(cfg)
+	var ids []string
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		ids = append(ids, hostname)
+		return newMockTunnel(true)
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	a, err := m.GetOrCreatePostgresTunnel(15432, config.TCPRouteConfig{Context: "test", Namespace: "pr-1", Service: "postgres", Port: 5432})
+	if err != nil {
+		t.Fatalf("GetOrCreatePostgresTunnel() error = %v", err)
+	}
+	again, _ := m.GetOrCreatePostgresTunnel(15432, config.TCPRouteConfig{Context: "test", Namespace: "pr-1", Service: "postgres", Port: 5432})
+	b, _ := m.GetOrCreatePostgresTunnel(15432, config.TCPRouteConfig{Context: "test", Namespace: "pr-2", Service: "postgres", Port: 5432})
+	if a != again || a == b {
+		t.Error("Expected one tunnel per namespace")
+	}
+	if len(ids) != 2 || ids[0] != "pg:15432/pr-1" || ids[1] != "pg:15432/pr-2" {
+		t.Errorf("Created tunnels %v", ids)
+	}
+
+	if _, err := m.RestartTunnel("pg:15432/pr-1"); err != nil {
+		t.Errorf("RestartTunnel() error = %v", err)
+	}
+	if !a.(*mockTunnel).wasStopped() || b.(*mockTunnel).wasStopped() {
+		t.Error("Expected only pr-1's tunnel to be restarted")
+	}
+
+	if _, err := m.GetOrCreatePostgresTunnel(5433, config.TCPRouteConfig{Context: "test", Namespace: "pr-1"}); err == nil {
+		t.Error("Expected an error for a port without a postgres route")
+	}
+}
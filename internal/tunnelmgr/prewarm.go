@@ -0,0 +1,171 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atas/autotunnel/internal/k8sutil"
+)
+
+// contextRetryInterval is how often a context that failed prewarm is tried again
+var contextRetryInterval = 30 * time.Second
+
+// Context states in ContextStatuses
+const (
+	ContextStateOK       = "ok"
+	ContextStateDegraded = "degraded"
+)
+
+// ContextStatus is how resolving a context went at startup (prewarm:). A degraded
+// context's routes fail until it is reachable; every other route keeps working.
+type ContextStatus struct {
+	Context  string    `json:"context"`
+	State    string    `json:"state"`
+	Error    string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"`
+	Attempts int       `json:"attempts,omitempty"` // failed attempts while degraded
+}
+
+// prewarmClients resolves the clients of every configured route's context in the
+// background (prewarm:). A context that fails is marked degraded and retried until
+// it answers; the others and their routes aren't held up by it.
+func (m *Manager) prewarmClients() {
+	defer m.wg.Done()
+	targets := m.prewarmTargets()
+	if len(targets) == 0 {
+		return
+	}
+
+	start := time.Now()
+	errs := m.clientFactory.Prewarm(m.ctx, targets, m.config.Prewarm.GetParallelism(), m.config.Prewarm.GetTimeout())
+	if m.ctx.Err() != nil {
+		return
+	}
+	ready := 0
+	for i, err := range errs {
+		m.setContextStatus(targets[i], err)
+		if err != nil {
+			log.Printf("Warning: context %s is unreachable, its routes are degraded (retrying every %v): %v",
+				targets[i], contextRetryInterval, err)
+			m.wg.Add(1)
+			go m.retryContext(targets[i])
+			continue
+		}
+		ready++
+	}
+	log.Printf("Prewarmed %d/%d contexts in %v", ready, len(targets), time.Since(start).Round(time.Millisecond))
+}
+
+// retryContext resolves a degraded context again until it answers or the manager stops
+func (m *Manager) retryContext(target k8sutil.PrewarmTarget) {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(contextRetryInterval):
+		}
+
+		err := m.clientFactory.Prewarm(m.ctx, []k8sutil.PrewarmTarget{target}, 1, m.config.Prewarm.GetTimeout())[0]
+		if m.ctx.Err() != nil {
+			return
+		}
+		m.setContextStatus(target, err)
+		if err == nil {
+			log.Printf("Context %s is reachable again", target)
+			return
+		}
+		if m.config.Verbose {
+			log.Printf("Context %s still unreachable: %v", target, err)
+		}
+	}
+}
+
+// setContextStatus records the outcome of resolving target
+func (m *Manager) setContextStatus(target k8sutil.PrewarmTarget, err error) {
+	name := target.String()
+	m.contextsMu.Lock()
+	defer m.contextsMu.Unlock()
+
+	status, ok := m.contexts[name]
+	if !ok {
+		status = &ContextStatus{Context: name}
+		m.contexts[name] = status
+	}
+	state := ContextStateOK
+	if err != nil {
+		state = ContextStateDegraded
+		status.Error = err.Error()
+		status.Attempts++
+	} else {
+		status.Error = ""
+		status.Attempts = 0
+	}
+	if status.State != state {
+		status.State = state
+		status.Since = time.Now()
+	}
+}
+
+// ContextStatuses returns the prewarmed contexts by name, degraded ones included
+func (m *Manager) ContextStatuses() []ContextStatus {
+	m.contextsMu.Lock()
+	defer m.contextsMu.Unlock()
+
+	statuses := make([]ContextStatus, 0, len(m.contexts))
+	for _, status := range m.contexts {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Context < statuses[j].Context })
+	return statuses
+}
+
+// prewarmTargets lists the distinct contexts of the named routes. dynamic_host and
+// host_patterns pick their context per request, so they are left out.
+func (m *Manager) prewarmTargets() []k8sutil.PrewarmTarget {
+	seen := make(map[string]bool)
+	var targets []k8sutil.PrewarmTarget
+	add := func(kubeconfigs []string, contextName string, execEnv map[string]string) {
+		if contextName == "" {
+			return
+		}
+		target := k8sutil.PrewarmTarget{Kubeconfigs: kubeconfigs, Context: contextName, ExecEnv: execEnv}
+		key := fmt.Sprint(kubeconfigs, target)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		targets = append(targets, target)
+	}
+
+	httpKubeconfigs := m.config.HTTP.K8s.ResolvedKubeconfigs
+	for _, r := range m.config.HTTP.K8s.Routes {
+		add(httpKubeconfigs, r.Context, r.ExecEnv)
+	}
+	tcpKubeconfigs := m.tcpKubeconfigs()
+	for _, r := range m.config.TCP.K8s.Routes {
+		add(tcpKubeconfigs, r.Context, r.ExecEnv)
+	}
+	for _, g := range m.config.TCP.K8s.Groups {
+		add(tcpKubeconfigs, g.Context, g.ExecEnv)
+	}
+	for _, j := range m.config.TCP.K8s.Jump {
+		add(tcpKubeconfigs, j.Context, j.ExecEnv)
+	}
+	for _, p := range m.config.TCP.K8s.Postgres {
+		add(tcpKubeconfigs, p.Context, p.ExecEnv)
+	}
+	for _, d := range m.config.TCP.K8s.Discover {
+		add(tcpKubeconfigs, d.Context, nil)
+	}
+
+	// map order is random; keep the log lines stable
+	slices.SortFunc(targets, func(a, b k8sutil.PrewarmTarget) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return targets
+}
@@ -0,0 +1,84 @@
+package tunnelmgr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestPrewarmTargets_Distinct(t *testing.T) {
+	cfg := testConfig(map[string]config.K8sRouteConfig{
+		"a.localhost": {Context: "prod", Namespace: "a", Service: "a", Port: 80},
+		"b.localhost": {Context: "prod", Namespace: "b", Service: "b", Port: 80},
+		"c.localhost": {Context: "prod", ExecEnv: map[string]string{"AWS_PROFILE": "ops"}, Namespace: "c", Service: "c", Port: 80},
+	})
+	cfg.TCP.K8s.Routes = map[int]config.TCPRouteConfig{
+		5432: {Context: "staging", Namespace: "db", Service: "pg", Port: 5432},
+	}
+	cfg.TCP.K8s.Discover = []config.TCPDiscoverConfig{{Context: "staging"}}
+	m := NewManager(cfg)
+
+	targets := m.prewarmTargets()
+	if len(targets) != 3 {
+		t.Fatalf("got %d targets, want 3 (prod, prod with exec_env, staging): %+v", len(targets), targets)
+	}
+	if targets[2].Context != "staging" {
+		t.Errorf("targets not sorted by context: %+v", targets)
+	}
+}
+
+func TestPrewarm_DegradedContextRecovers(t *testing.T) {
+	defer func(d time.Duration) { contextRetryInterval = d }(contextRetryInterval)
+	contextRetryInterval = 20 * time.Millisecond
+
+	var up atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unreachable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"29"}`))
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(map[string]config.K8sRouteConfig{
+		"a.localhost": {Context: "prod", Namespace: "a", Service: "a", Port: 80},
+	})
+	cfg.Prewarm.Enabled = true
+	m := NewManager(cfg)
+	restConfig := &rest.Config{Host: srv.URL}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ClientFactory().InjectClient("prod", clientset, restConfig)
+
+	m.Start()
+	defer m.Shutdown()
+
+	waitForState := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if statuses := m.ContextStatuses(); len(statuses) == 1 && statuses[0].State == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("context never became %s: %+v", want, m.ContextStatuses())
+	}
+	waitForState(ContextStateDegraded)
+	if status := m.ContextStatuses()[0]; status.Error == "" || status.Attempts == 0 {
+		t.Errorf("degraded status without error: %+v", status)
+	}
+
+	up.Store(true)
+	waitForState(ContextStateOK)
+}
@@ -0,0 +1,87 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// RestartTunnel stops route's tunnels right away, without draining, and drops the
+// cached client and services of its context, so the next connection starts a fresh tunnel with
+// the kubeconfig read again. It is meant for a tunnel wedged on a deleted pod or an
+// expired token. Routes are named like their tunnels: the hostname (with its
+// alpn_ports and scheme_routes tunnels), "tcp:<port>" or "group:<name>".
+// Returns the number of tunnels stopped.
+func (m *Manager) RestartTunnel(route string) (int, error) {
+	tunnels, contextName, err := m.takeRouteTunnels(route)
+	if err != nil {
+		return 0, err
+	}
+	if len(tunnels) == 0 {
+		return 0, fmt.Errorf("no tunnel running for route %q", route)
+	}
+
+	if contextName != "" {
+		m.clientFactory.Forget(contextName)
+		m.services.ForgetContext(contextName)
+	}
+	for _, tun := range tunnels {
+		tun.Stop()
+	}
+	log.Printf("[%s] Restarted: stopped %d tunnel(s), the next connection starts a new one", route, len(tunnels))
+	return len(tunnels), nil
+}
+
+// takeRouteTunnels removes route's tunnels from the tunnel maps and returns them
+// with the route's context ("" when it isn't a configured route)
+func (m *Manager) takeRouteTunnels(route string) ([]TunnelHandle, string, error) {
+	var tunnels []TunnelHandle
+	switch {
+	case strings.HasPrefix(route, "tcp:"):
+		port, err := strconv.Atoi(strings.TrimPrefix(route, "tcp:"))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid route %q: expected tcp:<port>", route)
+		}
+		m.tcpTunnelsMu.Lock()
+		defer m.tcpTunnelsMu.Unlock()
+		if tun, ok := m.tcpTunnels[port]; ok {
+			tunnels = append(tunnels, tun)
+			delete(m.tcpTunnels, port)
+		}
+		route, _ := m.tcpRoute(port)
+		return tunnels, route.Context, nil
+	case strings.HasPrefix(route, "group:"):
+		name := strings.TrimPrefix(route, "group:")
+		m.tcpTunnelsMu.Lock()
+		defer m.tcpTunnelsMu.Unlock()
+		if tun, ok := m.groupTunnels[name]; ok {
+			tunnels = append(tunnels, tun)
+			delete(m.groupTunnels, name)
+		}
+		return tunnels, m.config.TCP.K8s.Groups[name].Context, nil
+	case strings.HasPrefix(route, "pg:"):
+		port, _, _ := strings.Cut(strings.TrimPrefix(route, "pg:"), "/")
+		localPort, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid route %q: expected pg:<port>/<namespace>", route)
+		}
+		m.tcpTunnelsMu.Lock()
+		defer m.tcpTunnelsMu.Unlock()
+		if tun, ok := m.pgTunnels[route]; ok {
+			tunnels = append(tunnels, tun)
+			delete(m.pgTunnels, route)
+		}
+		return tunnels, m.config.TCP.K8s.Postgres[localPort].Context, nil
+	default:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for key, tun := range m.tunnels {
+			if key == route || strings.HasPrefix(key, route+":") {
+				tunnels = append(tunnels, tun)
+				delete(m.tunnels, key)
+			}
+		}
+		return tunnels, m.config.HTTP.K8s.Routes[route].Context, nil
+	}
+}
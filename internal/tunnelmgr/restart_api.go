@@ -0,0 +1,30 @@
+package tunnelmgr
+
+import (
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// RestartResult is returned by POST /tunnels/{route}/restart
+type RestartResult struct {
+	Route   string `json:"route"`
+	Stopped int    `json:"stopped"`
+}
+
+// RegisterRestartHandlers adds the tunnel restart endpoint to the admin API:
+//
+//	POST /tunnels/{route}/restart  stop route's tunnels; the next connection starts new ones
+//
+// Routes are hostnames, "tcp:<port>" or "group:<name>".
+func (m *Manager) RegisterRestartHandlers(h *admin.Handler) {
+	h.HandleFunc("POST /tunnels/{route}/restart", func(w http.ResponseWriter, r *http.Request) {
+		route := r.PathValue("route")
+		stopped, err := m.RestartTunnel(route)
+		if err != nil {
+			admin.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, RestartResult{Route: route, Stopped: stopped})
+	})
+}
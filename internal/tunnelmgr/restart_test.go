@@ -0,0 +1,107 @@
+package tunnelmgr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestRestartTunnel_HTTPRouteWithSiblings(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{
+		"web.localhost": {Context: "test", Namespace: "default", Service: "web", Port: 80},
+	}))
+	main, alpn, other := newMockTunnel(true), newMockTunnel(true), newMockTunnel(true)
+	m.tunnels["web.localhost"] = main
+	m.tunnels["web.localhost:50051"] = alpn
+	m.tunnels["webapp.localhost"] = other
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	stopped, err := m.RestartTunnel("web.localhost")
+	if err != nil {
+		t.Fatalf("RestartTunnel() error = %v", err)
+	}
+	if stopped != 2 {
+		t.Errorf("RestartTunnel() stopped %d tunnels, want 2", stopped)
+	}
+	if !main.wasStopped() || !alpn.wasStopped() {
+		t.Error("Expected the route's tunnels to be stopped")
+	}
+	if other.wasStopped() {
+		t.Error("Expected a route sharing the name prefix to be left alone")
+	}
+	if _, ok := m.tunnels["web.localhost"]; ok {
+		t.Error("Expected the restarted tunnel to be removed, not drained")
+	}
+	if len(m.drainingTunnels()) != 0 {
+		t.Error("Expected restarted tunnels to stop without draining")
+	}
+
+	// the cached client is dropped so the kubeconfig is read again
+	if _, _, err := m.ClientFactory().GetClientForContext([]string{"/fake/kubeconfig"}, "test"); err == nil {
+		t.Error("Expected the client for the route's context to be rebuilt")
+	}
+}
+
+func TestRestartTunnel_TCPAndGroup(t *testing.T) {
+	cfg := testConfigWithTCP(nil, map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432},
+	})
+	m := NewManager(cfg)
+	tcpTun, groupTun := newMockTunnel(true), newMockTunnel(true)
+	m.tcpTunnels[5432] = tcpTun
+	m.groupTunnels["api"] = groupTun
+
+	for route, tun := range map[string]*mockTunnel{"tcp:5432": tcpTun, "group:api": groupTun} {
+		if _, err := m.RestartTunnel(route); err != nil {
+			t.Fatalf("RestartTunnel(%s) error = %v", route, err)
+		}
+		if !tun.wasStopped() {
+			t.Errorf("Expected %s's tunnel to be stopped", route)
+		}
+	}
+	if len(m.tcpTunnels) != 0 || len(m.groupTunnels) != 0 {
+		t.Error("Expected the restarted tunnels to be removed")
+	}
+}
+
+func TestRestartTunnel_Errors(t *testing.T) {
+	m := NewManager(testConfig(nil))
+
+	for route, wantErr := range map[string]string{
+		"idle.localhost": "no tunnel running",
+		"tcp:5432":       "no tunnel running",
+		"tcp:abc":        "expected tcp:<port>",
+	} {
+		if _, err := m.RestartTunnel(route); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("RestartTunnel(%s) error = %v, want %q", route, err, wantErr)
+		}
+	}
+}
+
+func TestRestartHandlers(t *testing.T) {
+	m := NewManager(testConfig(nil))
+	m.tunnels["web.localhost"] = newMockTunnel(true)
+	h := admin.NewHandler()
+	m.RegisterRestartHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tunnels/web.localhost/restart", nil))
+	var res RestartResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("POST restart: status %d, body %s", rec.Code, rec.Body)
+	}
+	if res.Route != "web.localhost" || res.Stopped != 1 {
+		t.Errorf("POST restart = %+v", res)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/tunnels/web.localhost/restart", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("POST restart with nothing running: status %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package tunnelmgr
+
+import (
+	"time"
+
+	"github.com/atas/autotunnel/internal/tunnel"
+)
+
+// healthReporter is implemented by tunnels that report their starts and failures
+type healthReporter interface {
+	SetHealthObserver(observe tunnel.HealthObserver)
+}
+
+// routeHealth is how a route's tunnels have fared. It is kept per tunnel key
+// ("hostname", "hostname:https", ...), so it outlives tunnels that failed and
+// were replaced.
+type routeHealth struct {
+	lastError   string
+	lastFailure time.Time
+	failures    int
+	lastSuccess time.Time
+}
+
+// unhealthy reports whether the route's last attempt failed
+func (h *routeHealth) unhealthy() bool {
+	return h.lastFailure.After(h.lastSuccess)
+}
+
+// attachHealth records the starts and failures of a newly created tunnel under key
+func (m *Manager) attachHealth(key string, tun TunnelHandle) {
+	if reporter, ok := tun.(healthReporter); ok {
+		reporter.SetHealthObserver(func(err error) { m.recordHealth(key, err) })
+	}
+}
+
+// recordHealth notes a success (nil err) or failure of the route's tunnel
+func (m *Manager) recordHealth(key string, err error) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	h, ok := m.health[key]
+	if !ok {
+		h = &routeHealth{}
+		m.health[key] = h
+	}
+	if err == nil {
+		h.lastSuccess = time.Now()
+		return
+	}
+	h.lastError = err.Error()
+	h.lastFailure = time.Now()
+	h.failures++
+}
+
+// withHealth fills in info's health fields from what was recorded for key
+func (m *Manager) withHealth(key string, info TunnelInfo) TunnelInfo {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	h, ok := m.health[key]
+	if !ok {
+		return info
+	}
+	info.Failures = h.failures
+	info.LastSuccess = h.lastSuccess
+	if h.unhealthy() {
+		info.LastError = h.lastError
+		info.LastFailure = h.lastFailure
+	}
+	return info
+}
+
+// unhealthyWithoutTunnel returns the keys of failing routes that have no tunnel,
+// e.g. because their context's client couldn't be created. Caller must hold m.mu.
+func (m *Manager) unhealthyWithoutTunnel() []string {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+
+	var keys []string
+	for key, h := range m.health {
+		if _, ok := m.tunnels[key]; !ok && h.unhealthy() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
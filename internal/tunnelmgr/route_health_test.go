@@ -0,0 +1,41 @@
+package tunnelmgr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+func TestListTunnels_RouteHealth(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{}))
+	m.tunnels["ok.localhost"] = newMockTunnel(true)
+	m.tunnels["flaky.localhost"] = newMockTunnel(false)
+
+	m.recordHealth("ok.localhost", errors.New("pod restarting"))
+	m.recordHealth("ok.localhost", nil)
+	m.recordHealth("flaky.localhost", errors.New("no ready pods"))
+	m.recordHealth("flaky.localhost", errors.New("no ready pods"))
+	m.recordHealth("noclient.localhost", errors.New("context not found"))
+
+	infos := make(map[string]TunnelInfo)
+	for _, info := range m.ListTunnels() {
+		infos[info.Hostname] = info
+	}
+
+	ok := infos["ok.localhost"]
+	if ok.Failures != 1 || ok.LastError != "" || ok.LastSuccess.IsZero() {
+		t.Errorf("recovered route: %+v", ok)
+	}
+	flaky := infos["flaky.localhost"]
+	if flaky.Failures != 2 || flaky.LastError != "no ready pods" || flaky.LastFailure.IsZero() || !flaky.LastSuccess.IsZero() {
+		t.Errorf("failing route: %+v", flaky)
+	}
+	noClient, found := infos["noclient.localhost"]
+	if !found {
+		t.Fatal("failing route without a tunnel is missing")
+	}
+	if noClient.State != "failed" || noClient.LastError != "context not found" {
+		t.Errorf("route without a tunnel: %+v", noClient)
+	}
+}
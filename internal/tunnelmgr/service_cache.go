@@ -0,0 +1,16 @@
+package tunnelmgr
+
+import "github.com/atas/autotunnel/internal/k8sutil"
+
+// serviceCacher is implemented by tunnels that can reuse their route's service
+type serviceCacher interface {
+	SetServiceCache(cache *k8sutil.ServiceCache, contextName string)
+}
+
+// attachServiceCache lets a newly created tunnel reuse its route's service from
+// earlier tunnels instead of fetching it again (service_cache_ttl)
+func (m *Manager) attachServiceCache(contextName string, tun TunnelHandle) {
+	if cacher, ok := tun.(serviceCacher); ok && m.services != nil {
+		cacher.SetServiceCache(m.services, contextName)
+	}
+}
@@ -0,0 +1,16 @@
+package tunnelmgr
+
+import "github.com/atas/autotunnel/internal/tunnel"
+
+// startLimited is implemented by tunnels whose starts can share a global limit
+type startLimited interface {
+	SetStartLimiter(limiter *tunnel.StartLimiter)
+}
+
+// attachStartLimiter makes a newly created tunnel wait for one of the
+// concurrent_starts slots before it establishes
+func (m *Manager) attachStartLimiter(tun TunnelHandle) {
+	if limited, ok := tun.(startLimited); ok && m.startLimiter != nil {
+		limited.SetStartLimiter(m.startLimiter)
+	}
+}
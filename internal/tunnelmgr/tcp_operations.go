@@ -22,17 +22,12 @@ func (m *Manager) GetOrCreateTCPTunnel(localPort int) (TunnelHandle, error) {
 		delete(m.tcpTunnels, localPort)
 	}
 
-	routeConfig, ok := m.config.TCP.K8s.Routes[localPort]
+	routeConfig, ok := m.tcpRoute(localPort)
 	if !ok {
 		return nil, fmt.Errorf("no TCP route configured for port %d", localPort)
 	}
 
-	kubeconfigs := m.config.TCP.K8s.ResolvedKubeconfigs
-	if len(kubeconfigs) == 0 {
-		kubeconfigs = m.config.HTTP.K8s.ResolvedKubeconfigs
-	}
-
-	clientset, restConfig, err := m.clientFactory.GetClientForContext(kubeconfigs, routeConfig.Context)
+	clientset, restConfig, err := m.clientFactory.GetClientForRoute(m.tcpKubeconfigs(), routeConfig.Context, routeConfig.ExecEnv)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get k8s client for context %s: %w", routeConfig.Context, err)
 	}
@@ -40,13 +35,18 @@ func (m *Manager) GetOrCreateTCPTunnel(localPort int) (TunnelHandle, error) {
 	tunnelID := fmt.Sprintf("tcp:%d", localPort)
 	newTunnel := m.tunnelFactory(
 		tunnelID,
-		routeConfig.ToK8sRouteConfig(),
+		m.withGlobals(routeConfig.ToK8sRouteConfig()),
 		clientset,
 		restConfig,
 		"", // No listen addr for tunnels - they pick a random port
 		m.config.Verbose,
 	)
 
+	m.attachPin(tunnelID, newTunnel)
+	m.attachPortStore(tunnelID, newTunnel)
+	m.attachClientRenewer(m.tcpKubeconfigs(), routeConfig.Context, routeConfig.ExecEnv, newTunnel)
+	m.attachServiceCache(routeConfig.Context, newTunnel)
+	m.attachStartLimiter(newTunnel)
 	m.tcpTunnels[localPort] = newTunnel
 
 	if m.config.Verbose {
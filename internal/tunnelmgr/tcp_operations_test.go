@@ -187,3 +187,36 @@ func TestTCPRouteConfig_TargetName(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrCreateTCPTunnel_PassesGlobals(t *testing.T) {
+	tcpRoutes := map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "default", Service: "postgres", Port: 5432},
+		6379: {Context: "test", Namespace: "default", Service: "redis", Port: 6379, ReadyTimeout: 5 * time.Second},
+	}
+	cfg := testConfigWithTCP(nil, tcpRoutes)
+	cfg.Keepalive = 20 * time.Second
+	cfg.ReadyTimeout = 2 * time.Minute
+	m := NewManager(cfg)
+
+	gotConfigs := make(map[string]config.K8sRouteConfig)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		gotConfigs[hostname] = cfg
+		return newMockTunnel(false)
+	}
+	m.ClientFactory().InjectClient("test", nil, nil)
+
+	for port := range tcpRoutes {
+		if _, err := m.GetOrCreateTCPTunnel(port); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := gotConfigs["tcp:5432"]; got.Keepalive != 20*time.Second || got.ReadyTimeout != 2*time.Minute {
+		t.Errorf("Expected keepalive 20s and the global ready timeout, got %v and %v", got.Keepalive, got.ReadyTimeout)
+	}
+	if got := gotConfigs["tcp:6379"]; got.ReadyTimeout != 5*time.Second {
+		t.Errorf("Expected the route's own ready timeout, got %v", got.ReadyTimeout)
+	}
+}
@@ -12,6 +12,7 @@ type TunnelHandle interface {
 	Start(ctx context.Context) error
 	Stop()
 	LocalPort() int
+	LocalPortFor(port int) int
 	Scheme() string
 	Touch()
 	IdleDuration() time.Duration
@@ -20,10 +21,26 @@ type TunnelHandle interface {
 }
 
 type TunnelInfo struct {
-	Hostname     string
-	LocalPort    int
-	State        string
-	IdleDuration time.Duration
+	Hostname     string        `json:"hostname"`
+	LocalPort    int           `json:"local_port"`            // forwarded port on 127.0.0.1, usable directly while running
+	TargetPort   int           `json:"target_port,omitempty"` // backend port it forwards to
+	State        string        `json:"state"`
+	IdleDuration time.Duration `json:"idle_duration"`
+	OpenConns    int           `json:"open_conns,omitempty"`  // long-lived connections holding the tunnel open
+	LastError    string        `json:"last_error,omitempty"`  // why the last start failed or the port-forward broke, while unresolved
+	LastFailure  time.Time     `json:"last_failure,omitzero"` // when, set together with LastError
+	Failures     int           `json:"failures,omitempty"`    // failed starts and broken port-forwards since the route was first used
+	LastSuccess  time.Time     `json:"last_success,omitzero"` // last time a tunnel for the route started
+}
+
+// connCounter is implemented by tunnels that track long-lived connections
+type connCounter interface {
+	OpenConns() int
+}
+
+// targetPorter is implemented by tunnels that know their backend port
+type targetPorter interface {
+	TargetPort() int
 }
 
 func (m *Manager) ActiveTunnels() int {
@@ -45,11 +62,30 @@ func (m *Manager) ListTunnels() []TunnelInfo {
 
 	infos := make([]TunnelInfo, 0, len(m.tunnels))
 	for hostname, tunnel := range m.tunnels {
-		infos = append(infos, TunnelInfo{
+		info := TunnelInfo{
 			Hostname:     hostname,
 			LocalPort:    tunnel.LocalPort(),
 			State:        tunnel.State().String(),
 			IdleDuration: tunnel.IdleDuration(),
+		}
+		if counter, ok := tunnel.(connCounter); ok {
+			info.OpenConns = counter.OpenConns()
+		}
+		if porter, ok := tunnel.(targetPorter); ok {
+			info.TargetPort = porter.TargetPort()
+		}
+		infos = append(infos, m.withHealth(hostname, info))
+	}
+	// routes failing before a tunnel exists (no client for the context) show up too
+	for _, key := range m.unhealthyWithoutTunnel() {
+		infos = append(infos, m.withHealth(key, TunnelInfo{Hostname: key, State: tunnel.StateFailed.String()}))
+	}
+	for name, tunnel := range m.drainingTunnels() {
+		infos = append(infos, TunnelInfo{
+			Hostname:     name,
+			LocalPort:    tunnel.LocalPort(),
+			State:        StateDraining,
+			IdleDuration: tunnel.IdleDuration(),
 		})
 	}
 	return infos
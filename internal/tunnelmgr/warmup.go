@@ -0,0 +1,72 @@
+package tunnelmgr
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/atas/autotunnel/internal/cron"
+)
+
+// startWarmups starts a goroutine for each route with a warmup_schedule that
+// brings its tunnel up when the schedule fires
+func (m *Manager) startWarmups() {
+	for hostname, route := range m.config.HTTP.K8s.Routes {
+		m.startWarmup(hostname, route.WarmupSchedule, func() (TunnelHandle, error) {
+			return m.GetOrCreateTunnel(hostname, "")
+		})
+	}
+	for port, route := range m.config.TCP.K8s.Routes {
+		m.startWarmup(fmt.Sprintf("tcp:%d", port), route.WarmupSchedule, func() (TunnelHandle, error) {
+			return m.GetOrCreateTCPTunnel(port)
+		})
+	}
+}
+
+func (m *Manager) startWarmup(route, schedule string, get func() (TunnelHandle, error)) {
+	if schedule == "" {
+		return
+	}
+	sched, err := cron.Parse(schedule)
+	if err != nil {
+		log.Printf("[%s] Warning: ignoring warmup_schedule: %v", route, err)
+		return
+	}
+	m.wg.Add(1)
+	go m.warmupLoop(route, sched, get)
+}
+
+// warmupLoop warms route up each time sched fires, until shutdown
+func (m *Manager) warmupLoop(route string, sched *cron.Schedule, get func() (TunnelHandle, error)) {
+	defer m.wg.Done()
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := m.warmUp(route, get); err != nil {
+			log.Printf("[%s] Scheduled warm-up failed: %v", route, err)
+		}
+	}
+}
+
+// warmUp starts the tunnel get returns unless it is already running. It then
+// idles out as usual if nothing uses it.
+func (m *Manager) warmUp(route string, get func() (TunnelHandle, error)) error {
+	tun, err := get()
+	if err != nil {
+		return err
+	}
+	if tun.IsRunning() {
+		return nil
+	}
+	log.Printf("[%s] Warming up on schedule", route)
+	return tun.Start(m.ctx)
+}
@@ -0,0 +1,59 @@
+package tunnelmgr
+
+import (
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestWarmUp_StartsTunnel(t *testing.T) {
+	m := NewManager(testConfigWithTCP(map[string]config.K8sRouteConfig{
+		"dash.localhost": {Context: "test", Namespace: "default", Service: "grafana", Port: 80, WarmupSchedule: "0 9 * * MON-FRI"},
+	}, map[int]config.TCPRouteConfig{
+		5432: {Context: "test", Namespace: "db", Service: "postgres", Port: 5432, WarmupSchedule: "0 9 * * *"},
+	}))
+	defer m.Shutdown()
+	m.ClientFactory().InjectClient("test", nil, nil)
+	m.tunnelFactory = func(hostname string, cfg config.K8sRouteConfig,
+		clientset kubernetes.Interface, restConfig *rest.Config,
+		listenAddr string, verbose bool) TunnelHandle {
+		return newMockTunnel(false)
+	}
+
+	if err := m.warmUp("dash.localhost", func() (TunnelHandle, error) { return m.GetOrCreateTunnel("dash.localhost", "") }); err != nil {
+		t.Fatalf("warmUp(dash.localhost): %v", err)
+	}
+	if tun, ok := m.RunningTunnel("dash.localhost"); !ok || !tun.IsRunning() {
+		t.Error("HTTP route's tunnel isn't running after warm-up")
+	}
+
+	if err := m.warmUp("tcp:5432", func() (TunnelHandle, error) { return m.GetOrCreateTCPTunnel(5432) }); err != nil {
+		t.Fatalf("warmUp(tcp:5432): %v", err)
+	}
+	if tun := m.tcpTunnels[5432]; tun == nil || !tun.IsRunning() {
+		t.Error("TCP route's tunnel isn't running after warm-up")
+	}
+
+	// warming up a running tunnel leaves it alone
+	tun := m.tunnels["dash.localhost"]
+	if err := m.warmUp("dash.localhost", func() (TunnelHandle, error) { return m.GetOrCreateTunnel("dash.localhost", "") }); err != nil {
+		t.Fatalf("second warmUp: %v", err)
+	}
+	if m.tunnels["dash.localhost"] != tun {
+		t.Error("warm-up replaced a running tunnel")
+	}
+}
+
+func TestStartWarmups_StopOnShutdown(t *testing.T) {
+	m := NewManager(testConfig(map[string]config.K8sRouteConfig{
+		"dash.localhost": {Context: "test", Namespace: "default", Service: "grafana", Port: 80, WarmupSchedule: "0 9 * * *"},
+		"api.localhost":  {Context: "test", Namespace: "default", Service: "api", Port: 80},
+	}))
+	m.startWarmups()
+	m.Shutdown() // waits for the warm-up goroutine
+	if len(m.tunnels) != 0 {
+		t.Errorf("tunnels created before the schedule fired: %v", m.tunnels)
+	}
+}
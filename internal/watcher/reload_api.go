@@ -0,0 +1,48 @@
+package watcher
+
+import (
+	"net/http"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// PendingInfo describes a config change held back by reload_mode: confirm
+type PendingInfo struct {
+	Pending bool     `json:"pending"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// RegisterReloadHandlers adds the reload confirmation endpoints to the admin API:
+//
+//	GET    /reload        show the pending change
+//	POST   /reload/apply  apply it (restarts listeners and tunnels)
+//	DELETE /reload        discard it
+func (cw *ConfigWatcher) RegisterReloadHandlers(h *admin.Handler) {
+	h.AddSection("pending_reload", func() any { return cw.pendingInfo() })
+
+	h.HandleFunc("GET /reload", func(w http.ResponseWriter, r *http.Request) {
+		admin.WriteJSON(w, http.StatusOK, cw.pendingInfo())
+	})
+
+	h.HandleFunc("POST /reload/apply", func(w http.ResponseWriter, r *http.Request) {
+		changes, err := cw.ApplyPending()
+		if err != nil {
+			admin.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		admin.WriteJSON(w, http.StatusOK, PendingInfo{Changes: changes})
+	})
+
+	h.HandleFunc("DELETE /reload", func(w http.ResponseWriter, r *http.Request) {
+		if !cw.DiscardPending() {
+			admin.WriteError(w, http.StatusConflict, "no config change is waiting to be applied")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (cw *ConfigWatcher) pendingInfo() PendingInfo {
+	changes, ok := cw.PendingReload()
+	return PendingInfo{Pending: ok, Changes: changes}
+}
@@ -0,0 +1,98 @@
+package watcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atas/autotunnel/internal/admin"
+)
+
+// startConfirmWatcher starts a watcher on a reload_mode: confirm config and saves a change
+// that adds updated.localhost, waiting until it is pending
+func startConfirmWatcher(t *testing.T) *ConfigWatcher {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	watcher := startWatcher(t, configPath, "test.localhost")
+	watcher.GetConfig().ReloadMode = "confirm"
+
+	if err := os.WriteFile(configPath, []byte("reload_mode: confirm\n"+routeConfig("updated.localhost")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := watcher.PendingReload(); ok {
+			return watcher
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Expected a pending config change")
+	return nil
+}
+
+func TestConfigWatcher_ConfirmModeHoldsChanges(t *testing.T) {
+	watcher := startConfirmWatcher(t)
+
+	select {
+	case <-watcher.ReloadChan:
+		t.Fatal("Expected no reload before apply")
+	default:
+	}
+	if _, ok := watcher.GetConfig().HTTP.K8s.Routes["test.localhost"]; !ok {
+		t.Error("Expected the running config to stay current until apply")
+	}
+
+	changes, _ := watcher.PendingReload()
+	if len(changes) != 2 { // + updated.localhost, - test.localhost
+		t.Errorf("Expected 2 changes, got %v", changes)
+	}
+
+	if _, err := watcher.ApplyPending(); err != nil {
+		t.Fatalf("ApplyPending failed: %v", err)
+	}
+	select {
+	case <-watcher.ReloadChan:
+	case <-time.After(time.Second):
+		t.Fatal("Expected reload signal after apply")
+	}
+	if _, ok := watcher.GetConfig().HTTP.K8s.Routes["updated.localhost"]; !ok {
+		t.Error("Expected the applied config to be current")
+	}
+	if _, err := watcher.ApplyPending(); err == nil {
+		t.Error("Expected an error applying twice")
+	}
+}
+
+func TestConfigWatcher_ReloadHandlers(t *testing.T) {
+	watcher := startConfirmWatcher(t)
+	h := admin.NewHandler()
+	watcher.RegisterReloadHandlers(h)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reload", nil))
+	var info PendingInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil || !info.Pending || len(info.Changes) == 0 {
+		t.Fatalf("GET /reload = %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/reload", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE /reload = %d, want 204", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload/apply", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("POST /reload/apply without a pending change = %d, want 409", rec.Code)
+	}
+	select {
+	case <-watcher.ReloadChan:
+		t.Error("Expected no reload after discarding")
+	default:
+	}
+}
@@ -1,7 +1,12 @@
 package watcher
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,14 +14,33 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
+// debounceDelay collapses a burst of events (an editor save, several files
+// changed together) into one reload
+var debounceDelay = 500 * time.Millisecond
+
+// missingGrace is how long a reload waits for a config file that disappeared,
+// e.g. between the unlink and rename of an atomic save on a network filesystem
+var missingGrace = 2 * time.Second
+
 type ConfigWatcher struct {
 	configPath string
 	watcher    *fsnotify.Watcher
 	cliVerbose bool // Preserve CLI --verbose flag across reloads
 
+	// Parent directories are watched rather than the files themselves, so atomic
+	// saves (write temp, rename) and files that are briefly missing keep working.
+	// Events are matched against patterns: files, globs or whole directories.
+	patternsMu sync.RWMutex
+	patterns   []string
+	dirs       map[string]bool // watched directories
+
 	mu            sync.Mutex
 	currentConfig *config.Config
 
+	// With reload_mode: confirm, a validated change waits here for ApplyPending
+	pending        *config.Config
+	pendingChanges []string
+
 	// ReloadChan signals that config has changed and app should restart
 	// Buffered with capacity 1 to avoid blocking
 	ReloadChan chan struct{}
@@ -31,20 +55,83 @@ func NewConfigWatcher(configPath string, initialConfig *config.Config, cliVerbos
 		return nil, err
 	}
 
-	if err := watcher.Add(configPath); err != nil {
-		watcher.Close()
-		return nil, err
-	}
-
-	return &ConfigWatcher{
-		configPath:    configPath,
+	cw := &ConfigWatcher{
+		configPath:    filepath.Clean(configPath),
 		watcher:       watcher,
 		cliVerbose:    cliVerbose,
+		dirs:          make(map[string]bool),
 		currentConfig: initialConfig,
 		ReloadChan:    make(chan struct{}, 1),
 		stopChan:      make(chan struct{}),
 		doneChan:      make(chan struct{}),
-	}, nil
+	}
+
+	if err := cw.Watch(configPath); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	// a symlinked config (dotfiles repo) is edited at its target
+	if resolved, err := filepath.EvalSymlinks(configPath); err == nil && resolved != cw.configPath {
+		if err := cw.Watch(resolved); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	return cw, nil
+}
+
+// Watch adds files that trigger a reload besides the config file: a file path,
+// a glob like "routes.d/*.yaml" (matched within its directory) or a directory,
+// meaning every file directly inside it
+func (cw *ConfigWatcher) Watch(pattern string) error {
+	pattern = filepath.Clean(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid watch pattern %q: %w", pattern, err)
+	}
+
+	dir := filepath.Dir(pattern)
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		dir = pattern
+	}
+
+	cw.patternsMu.Lock()
+	defer cw.patternsMu.Unlock()
+
+	if !cw.dirs[dir] {
+		if err := cw.watcher.Add(dir); err != nil {
+			return err
+		}
+		cw.dirs[dir] = true
+	}
+	cw.patterns = append(cw.patterns, pattern)
+	return nil
+}
+
+// matches reports whether an event on path should trigger a reload
+func (cw *ConfigWatcher) matches(path string) bool {
+	path = filepath.Clean(path)
+	name := filepath.Base(path)
+
+	cw.patternsMu.RLock()
+	defer cw.patternsMu.RUnlock()
+
+	for _, pattern := range cw.patterns {
+		if path == pattern {
+			return true
+		}
+		if cw.dirs[pattern] {
+			// directory pattern: skip editor swap/backup files
+			if filepath.Dir(path) == pattern && !strings.HasPrefix(name, ".") && !strings.HasSuffix(name, "~") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func (cw *ConfigWatcher) Start() {
@@ -78,20 +165,17 @@ func (cw *ConfigWatcher) watchLoop() {
 				return
 			}
 
-			// vim/nano do atomic saves (write temp, rename), so we need to watch for Rename too
-			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Chmod|fsnotify.Rename) != 0 {
-				// after rename, old inode is gone so we need to re-watch the path
-				if event.Op&fsnotify.Rename != 0 {
-					time.Sleep(100 * time.Millisecond) // let new file appear
-					_ = cw.watcher.Remove(cw.configPath)
-					_ = cw.watcher.Add(cw.configPath)
-				}
-
+			// vim/nano do atomic saves (write temp, rename), so the directory sees
+			// Create/Rename/Remove for the config path rather than a Write
+			if !cw.matches(event.Name) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Chmod|fsnotify.Rename|fsnotify.Remove) != 0 {
 				debounceMu.Lock()
 				if debounceTimer != nil {
 					debounceTimer.Stop()
 				}
-				debounceTimer = time.AfterFunc(500*time.Millisecond, func() {
+				debounceTimer = time.AfterFunc(debounceDelay, func() {
 					cw.reloadConfig()
 				})
 				debounceMu.Unlock()
@@ -109,6 +193,11 @@ func (cw *ConfigWatcher) watchLoop() {
 func (cw *ConfigWatcher) reloadConfig() {
 	log.Println("Config file changed, validating...")
 
+	if !cw.waitForConfig() {
+		log.Printf("Config file %s is missing (keeping current config until it is back)", cw.configPath)
+		return
+	}
+
 	newConfig, err := config.LoadConfig(cw.configPath)
 	if err != nil {
 		log.Printf("Failed to load config: %v (keeping current config)", err)
@@ -120,10 +209,32 @@ func (cw *ConfigWatcher) reloadConfig() {
 	}
 
 	cw.mu.Lock()
-	cw.currentConfig = newConfig
+	current := cw.currentConfig
+	if !current.ConfirmReloads() {
+		cw.currentConfig = newConfig
+		cw.mu.Unlock()
+		cw.signalReload()
+		return
+	}
+
+	changes := config.DiffRoutes(current, newConfig)
+	if len(changes) == 0 {
+		cw.pending, cw.pendingChanges = nil, nil
+		cw.mu.Unlock()
+		log.Println("Config file saved without changes, nothing to apply")
+		return
+	}
+	cw.pending, cw.pendingChanges = newConfig, changes
 	cw.mu.Unlock()
 
-	// Signal main.go to restart the application
+	log.Printf("Config change validated, run `autotunnel reload apply` to apply it (%d changes):", len(changes))
+	for _, change := range changes {
+		log.Printf("  %s", change)
+	}
+}
+
+// signalReload tells main.go to restart with the current config
+func (cw *ConfigWatcher) signalReload() {
 	// Non-blocking send (channel has capacity 1)
 	select {
 	case cw.ReloadChan <- struct{}{}:
@@ -133,6 +244,72 @@ func (cw *ConfigWatcher) reloadConfig() {
 	}
 }
 
+// PendingReload returns the changes waiting for ApplyPending (reload_mode: confirm)
+func (cw *ConfigWatcher) PendingReload() ([]string, bool) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.pending == nil {
+		return nil, false
+	}
+	return append([]string(nil), cw.pendingChanges...), true
+}
+
+// ApplyPending makes the pending config current and triggers the restart
+func (cw *ConfigWatcher) ApplyPending() ([]string, error) {
+	cw.mu.Lock()
+	if cw.pending == nil {
+		cw.mu.Unlock()
+		return nil, errors.New("no config change is waiting to be applied")
+	}
+	changes := cw.pendingChanges
+	cw.currentConfig = cw.pending
+	cw.pending, cw.pendingChanges = nil, nil
+	cw.mu.Unlock()
+
+	log.Printf("Applying %d config changes", len(changes))
+	cw.signalReload()
+	return changes, nil
+}
+
+// DiscardPending drops the pending config, reporting whether there was one.
+// The file keeps the edit; the next save is diffed against the running config again.
+func (cw *ConfigWatcher) DiscardPending() bool {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	discarded := cw.pending != nil
+	cw.pending, cw.pendingChanges = nil, nil
+	return discarded
+}
+
+// waitForConfig waits up to missingGrace for the config file to exist again.
+// Its reappearance also triggers a reload on its own.
+func (cw *ConfigWatcher) waitForConfig() bool {
+	deadline := time.Now().Add(missingGrace)
+	for {
+		_, err := os.Stat(cw.configPath)
+		if !errors.Is(err, os.ErrNotExist) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-cw.stopChan:
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Rollback makes cfg current again after the reloaded config failed to start.
+// Any pending change is dropped; the next save of the file is loaded as usual.
+func (cw *ConfigWatcher) Rollback(cfg *config.Config) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.currentConfig = cfg
+	cw.pending, cw.pendingChanges = nil, nil
+}
+
 // GetConfig returns the current validated config
 func (cw *ConfigWatcher) GetConfig() *config.Config {
 	cw.mu.Lock()
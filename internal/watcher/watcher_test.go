@@ -372,3 +372,190 @@ http:
 		t.Error("Expected config to be reloaded with new route")
 	}
 }
+
+func routeConfig(hostname string) string {
+	return `apiVersion: autotunnel/v1
+http:
+  listen: ":8989"
+  idle_timeout: 60m
+  k8s:
+    routes:
+      ` + hostname + `:
+        context: test
+        namespace: default
+        service: test
+        port: 80
+`
+}
+
+// startWatcher writes configPath with a route for hostname and starts a watcher on it
+func startWatcher(t *testing.T, configPath, hostname string) *ConfigWatcher {
+	t.Helper()
+	if err := os.WriteFile(configPath, []byte(routeConfig(hostname)), 0644); err != nil {
+		t.Fatalf("Failed to write initial config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	watcher, err := NewConfigWatcher(configPath, cfg, false)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	watcher.Start()
+	t.Cleanup(watcher.Stop)
+	time.Sleep(100 * time.Millisecond)
+	return watcher
+}
+
+func TestConfigWatcher_Matches(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	routesDir := filepath.Join(tempDir, "routes.d")
+	extraDir := filepath.Join(tempDir, "extra")
+	for _, dir := range []string{routesDir, extraDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	watcher := startWatcher(t, configPath, "test.localhost")
+	if err := watcher.Watch(filepath.Join(routesDir, "*.yaml")); err != nil {
+		t.Fatalf("Watch(glob) failed: %v", err)
+	}
+	if err := watcher.Watch(extraDir); err != nil {
+		t.Fatalf("Watch(dir) failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{configPath, true},
+		{filepath.Join(tempDir, ".config.yaml.swp"), false},
+		{filepath.Join(tempDir, "other.yaml"), false},
+		{filepath.Join(routesDir, "db.yaml"), true},
+		{filepath.Join(routesDir, "db.yaml.bak"), false},
+		{filepath.Join(extraDir, "anything.yml"), true},
+		{filepath.Join(extraDir, ".hidden"), false},
+		{filepath.Join(extraDir, "anything.yml~"), false},
+		{filepath.Join(extraDir, "nested", "file.yaml"), false},
+	}
+	for _, tt := range tests {
+		if got := watcher.matches(tt.path); got != tt.want {
+			t.Errorf("matches(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if err := watcher.Watch(filepath.Join(tempDir, "[")); err == nil {
+		t.Error("Expected an error for a malformed glob")
+	}
+}
+
+// TestConfigWatcher_DebouncesMultiFileEdits verifies edits to several watched files cause one reload
+func TestConfigWatcher_DebouncesMultiFileEdits(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	routesDir := filepath.Join(tempDir, "routes.d")
+	if err := os.Mkdir(routesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	watcher := startWatcher(t, configPath, "test.localhost")
+	if err := watcher.Watch(routesDir); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		if err := os.WriteFile(filepath.Join(routesDir, name), []byte("x: 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(configPath, []byte(routeConfig("updated.localhost")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-watcher.ReloadChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected reload signal not received")
+	}
+	select {
+	case <-watcher.ReloadChan:
+		t.Error("Expected a single reload for one burst of edits")
+	case <-time.After(time.Second):
+	}
+}
+
+// TestConfigWatcher_ConfigTemporarilyMissing verifies a save that removes the file before
+// writing it again still reloads the new content
+func TestConfigWatcher_ConfigTemporarilyMissing(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	watcher := startWatcher(t, configPath, "test.localhost")
+
+	if err := os.Remove(configPath); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(700 * time.Millisecond) // past the debounce: the reload is waiting for the file
+	if err := os.WriteFile(configPath, []byte(routeConfig("updated.localhost")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-watcher.ReloadChan:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected reload signal not received")
+	}
+	if _, ok := watcher.GetConfig().HTTP.K8s.Routes["updated.localhost"]; !ok {
+		t.Error("Expected the rewritten config to be loaded")
+	}
+}
+
+// TestConfigWatcher_FollowsSymlink verifies edits to a symlinked config's target trigger a reload
+func TestConfigWatcher_FollowsSymlink(t *testing.T) {
+	targetPath := filepath.Join(t.TempDir(), "dotfiles-config.yaml")
+	if err := os.WriteFile(targetPath, []byte(routeConfig("test.localhost")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.Symlink(targetPath, configPath); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	watcher, err := NewConfigWatcher(configPath, cfg, false)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	watcher.Start()
+	defer watcher.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	if err := os.WriteFile(targetPath, []byte(routeConfig("updated.localhost")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-watcher.ReloadChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected reload signal not received")
+	}
+}
+
+func TestConfigWatcher_Rollback(t *testing.T) {
+	watcher := startConfirmWatcher(t)
+	previous := watcher.GetConfig()
+
+	if _, err := watcher.ApplyPending(); err != nil {
+		t.Fatalf("ApplyPending failed: %v", err)
+	}
+	<-watcher.ReloadChan
+
+	watcher.Rollback(previous)
+	if watcher.GetConfig() != previous {
+		t.Error("Expected the previous config to be current after rollback")
+	}
+	if _, ok := watcher.PendingReload(); ok {
+		t.Error("Expected no pending change after rollback")
+	}
+}
@@ -7,12 +7,17 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/atas/autotunnel/internal/admin"
+	"github.com/atas/autotunnel/internal/authz"
 	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/diag"
+	"github.com/atas/autotunnel/internal/healthcheck"
 	"github.com/atas/autotunnel/internal/httpserver"
+	"github.com/atas/autotunnel/internal/logsink"
+	"github.com/atas/autotunnel/internal/selfupdate"
 	"github.com/atas/autotunnel/internal/tcpserver"
 	"github.com/atas/autotunnel/internal/tunnelmgr"
 	"github.com/atas/autotunnel/internal/watcher"
@@ -22,6 +27,9 @@ var (
 	version = "dev"
 	commit  = "none"
 	date    = "unknown"
+	// releaseKey is the minisign public key self-update checks releases against,
+	// set at release build time; dev builds have none and refuse to self-update
+	releaseKey = ""
 )
 
 type appComponents struct {
@@ -29,19 +37,41 @@ type appComponents struct {
 	manager    *tunnelmgr.Manager
 	httpServer *httpserver.Server
 	tcpServer  *tcpserver.Server
+	checker    *healthcheck.Checker // nil when no route has a health_check or protocol: grpc
 }
 
 func main() {
+	if runSubcommand(os.Args[1:]) {
+		return
+	}
+
 	var configPath string
 	var verbose bool
 	var showVersion bool
+	var noBanner bool
+	var quiet bool
+	var output string
+	var locked bool
+	var runModeName, pidFile, readyFile string
 
-	home, _ := os.UserHomeDir()
-	defaultConfig := filepath.Join(home, ".autotunnel.yaml")
-
-	flag.StringVar(&configPath, "config", defaultConfig, "Path to configuration file")
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "Path to configuration file")
 	flag.BoolVar(&verbose, "verbose", false, "Enable verbose logging")
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
+	flag.BoolVar(&noBanner, "no-banner", false, "Don't print the banner at startup")
+	flag.BoolVar(&quiet, "quiet", false, "Print a one-line summary instead of the banner and route table")
+	flag.BoolVar(&locked, "locked", false, "Only use what the config file names: no dynamic_host, runtime pins or via.create pods")
+	flag.StringVar(&output, "output", "text", `Startup info format: "text" or "json" (one JSON line per start on stdout, everything else on stderr)`)
+	flag.StringVar(&runModeName, "run-mode", "foreground", `"foreground", or "supervised" for service managers: no banner, exit 78 on config errors and 75 on transient failures, pidfile and ready file`)
+	flag.StringVar(&pidFile, "pid-file", "", "Write the pid here while running (supervised default: ~/.autotunnel/autotunnel.pid)")
+	flag.StringVar(&readyFile, "ready-file", "", "Create this file while the listeners are bound (supervised default: ~/.autotunnel/ready)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: autotunnel [options]\n       autotunnel <command> [flags]\n\nCommands:\n")
+		for _, name := range subcommandNames() {
+			fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", name)
+		}
+		fmt.Fprintf(flag.CommandLine.Output(), "\nOptions:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if showVersion {
@@ -49,17 +79,40 @@ func main() {
 		return
 	}
 
-	printBanner()
+	if output != "text" && output != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid -output %q: must be text or json\n", output)
+		os.Exit(2)
+	}
+	mode, err := newRunMode(runModeName, pidFile, readyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -run-mode %q: %v\n", runModeName, err)
+		os.Exit(2)
+	}
+	if mode.supervised {
+		noBanner = true
+	}
+	// keep stdout for the JSON summary; everything printed for humans goes to stderr
+	summaryOut := os.Stdout
+	if output == "json" {
+		os.Stdout = os.Stderr
+		quiet = true
+	}
+
+	if !noBanner && !quiet {
+		printBanner()
+	}
 
 	log.SetFlags(log.Ldate | log.Ltime)
 	log.SetPrefix("[autotunnel] ")
 
+	defer mode.stop()
+
 	if !config.FileExists(configPath) {
 		fmt.Println("-----------------------------------------------------------------------------")
 		fmt.Printf("Config file not found, creating: %s\n", configPath)
 
 		if err := config.CreateDefaultConfig(configPath); err != nil {
-			log.Fatalf("Failed to create config file: %v", err)
+			mode.fatal(exitConfigError, "Failed to create config file: %v", err)
 		}
 
 		fmt.Printf("Created: %s\n", configPath)
@@ -72,8 +125,20 @@ func main() {
 	// Set up config watcher (persists across restarts)
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config from %s: %v", configPath, err)
+		mode.fatal(exitConfigError, "Failed to load config from %s: %v", configPath, err)
+	}
+
+	// log.output is read once; changing it takes a restart
+	if sink, err := logsink.Open(cfg.Log); err != nil {
+		log.Printf("Warning: %v, logging to stderr", err)
+	} else if sink != nil {
+		defer sink.Close()
+		log.SetOutput(sink)
+		log.SetFlags(0) // the system log timestamps and tags lines itself
+		log.SetPrefix("")
 	}
+	// keep recent lines for `autotunnel debug bundle`
+	diag.CaptureLogs(diag.DefaultLogLines)
 
 	var configWatcher *watcher.ConfigWatcher
 	if cfg.ShouldAutoReload() {
@@ -86,57 +151,92 @@ func main() {
 		}
 	}
 
+	// release checks run for the whole process, not per reload; dev builds skip them
+	var updates *selfupdate.Checker
+	if cfg.ShouldCheckUpdates() && version != "dev" {
+		updates = selfupdate.NewChecker(version, selfupdate.LatestReleaseURL, selfupdate.DefaultCheckInterval)
+		updates.Start()
+		defer updates.Stop()
+	}
+
 	// Main run loop - restart on config changes
+	var lastGood *config.Config // config of the last successful start, for rollback
+	retries := 0                // restarts in a row after server errors (server_retry)
 	for {
-		app, err := initializeApp(configPath, verbose, configWatcher)
+		app, err := initializeApp(configPath, verbose, locked, configWatcher, updates)
 		if err != nil {
-			log.Fatalf("Failed to initialize: %v", err)
+			if !rollbackReload(configWatcher, lastGood, configPath, err) {
+				mode.fatal(exitConfigError, "Failed to initialize: %v", err)
+			}
+			lastGood = nil // a failing rollback is fatal
+			continue
 		}
 
-		printConfigInfo(configPath, app.cfg)
-
-		// Start servers
-		app.manager.Start()
+		printConfigInfo(configPath, app.cfg, quiet)
 
 		serverErrChan := make(chan error, 1)
-		go func() {
-			if err := app.httpServer.Start(); err != nil {
-				serverErrChan <- err
+		if err := startApp(app, serverErrChan); err != nil {
+			if retry := app.cfg.ServerRetry; retries < retry.MaxAttempts {
+				retries++
+				delay := retry.Delay(retries)
+				log.Printf("Error: failed to start: %v (retry %d/%d in %v)", err, retries, retry.MaxAttempts, delay)
+				if !sleepOrSignal(delay, sigChan) {
+					break
+				}
+				continue
 			}
-		}()
+			retries = 0
 
-		if app.tcpServer != nil {
-			if err := app.tcpServer.Start(); err != nil {
-				shutdownApp(app, context.Background())
-				log.Fatalf("Failed to start TCP server: %v", err)
+			if !rollbackReload(configWatcher, lastGood, configPath, err) {
+				mode.fatal(exitTransient, "Failed to start: %v", err)
+			}
+			lastGood = nil // a failing rollback is fatal
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		lastGood = app.cfg
+		startedAt := time.Now()
+		mode.ready()
+		if output == "json" {
+			if err := writeStartupSummary(summaryOut, configPath, app); err != nil {
+				log.Printf("Warning: failed to write startup summary: %v", err)
 			}
 		}
 
 		// Wait for signal or config reload
 		shouldExit := false
+		restartDelay := 100 * time.Millisecond // let ports release
 		select {
 		case sig := <-sigChan:
 			log.Printf("Received signal %v, shutting down...", sig)
 			shouldExit = true
 
 		case err := <-serverErrChan:
-			log.Fatalf("Server error: %v", err)
+			if time.Since(startedAt) > retryResetAfter {
+				retries = 0
+			}
+			retry := app.cfg.ServerRetry
+			if retries >= retry.MaxAttempts {
+				mode.fatal(exitTransient, "Server error: %v", err)
+			}
+			retries++
+			restartDelay = retry.Delay(retries)
+			log.Printf("Server error: %v (restarting, attempt %d/%d in %v)", err, retries, retry.MaxAttempts, restartDelay)
 
 		case <-getReloadChan(configWatcher):
 			log.Println("Config changed, restarting...")
+			retries = 0
 		}
 
 		// Shutdown current instance
+		mode.notReady()
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		shutdownApp(app, ctx)
 		cancel()
 
-		if shouldExit {
+		if shouldExit || !sleepOrSignal(restartDelay, sigChan) {
 			break
 		}
-
-		// Small delay before restart to let ports release
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	log.Println("Shutdown complete")
@@ -156,7 +256,7 @@ On-demand Port Forwarding
 https://github.com/atas/autotunnel`)
 }
 
-func initializeApp(configPath string, cliVerbose bool, configWatcher *watcher.ConfigWatcher) (*appComponents, error) {
+func initializeApp(configPath string, cliVerbose, locked bool, configWatcher *watcher.ConfigWatcher, updates *selfupdate.Checker) (*appComponents, error) {
 	var cfg *config.Config
 	var err error
 
@@ -174,29 +274,126 @@ func initializeApp(configPath string, cliVerbose bool, configWatcher *watcher.Co
 	if cliVerbose || (configWatcher != nil && configWatcher.CLIVerbose()) {
 		cfg.Verbose = true
 	}
+	cfg.Locked = locked
 
 	// systemd/launchd run with minimal PATH, so we add common tool locations
-	config.ExpandExecPath(cfg.ExecPath)
+	cfg.ExecPath.Expand()
 	if cfg.Verbose {
 		log.Printf("PATH expanded for exec credential plugins")
 	}
 
+	policy, err := authz.NewPolicy(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up authorizers: %w", err)
+	}
+
 	manager := tunnelmgr.NewManager(cfg)
 	httpServer := httpserver.NewServer(cfg, manager)
+	httpServer.SetAuthorizer(policy)
 
 	var tcpServer *tcpserver.Server
-	if len(cfg.TCP.K8s.Routes) > 0 || len(cfg.TCP.K8s.Jump) > 0 {
+	if len(cfg.TCP.K8s.Routes) > 0 || len(cfg.TCP.K8s.Jump) > 0 || len(cfg.TCP.K8s.Groups) > 0 || len(cfg.TCP.K8s.Discover) > 0 || len(cfg.TCP.K8s.Postgres) > 0 || cfg.TCP.EchoPort != 0 {
 		tcpServer = tcpserver.NewServer(cfg, manager)
+		tcpServer.SetAuthorizer(policy)
+	}
+
+	checker := healthcheck.NewChecker(cfg, manager)
+
+	adminHandler := admin.NewHandler()
+	adminHandler.AddSection("tunnels", func() any { return manager.ListTunnels() })
+	if cfg.Prewarm.Enabled {
+		adminHandler.AddSection("contexts", func() any { return manager.ContextStatuses() })
 	}
+	adminHandler.AddSection("http_traffic", func() any { return httpServer.Traffic() })
+	manager.RegisterPinHandlers(adminHandler)
+	manager.RegisterRestartHandlers(adminHandler)
+	manager.RegisterHistoryHandlers(adminHandler)
+	manager.RegisterKeepWarmHandlers(adminHandler)
+	manager.RegisterDynamicHostHandlers(adminHandler)
+	manager.RegisterCompletionHandlers(adminHandler)
+	if configWatcher != nil {
+		configWatcher.RegisterReloadHandlers(adminHandler)
+	}
+	if checker != nil {
+		adminHandler.AddSection("health_checks", func() any { return checker.Results() })
+	}
+	if tcpServer != nil {
+		adminHandler.AddSection("tcp_port_remaps", func() any { return tcpServer.RemappedPorts() })
+		adminHandler.AddSection("tcp_groups", func() any { return tcpServer.GroupPorts() })
+		adminHandler.AddSection("tcp_listeners", func() any { return tcpServer.Listeners() })
+		adminHandler.AddSection("jump_pods", func() any { return tcpServer.JumpPods() })
+		adminHandler.AddSection("tcp_discovered", func() any { return tcpServer.DiscoveredRoutes() })
+	}
+	if updates != nil {
+		adminHandler.AddSection("update", func() any { return updates.Status() })
+	}
+	diag.RegisterHandlers(adminHandler)
+	httpServer.SetAdminHandler(adminHandler)
 
 	return &appComponents{
 		cfg:        cfg,
 		manager:    manager,
 		httpServer: httpServer,
 		tcpServer:  tcpServer,
+		checker:    checker,
 	}, nil
 }
 
+// rollbackReload handles a config that failed to initialize or start. After a
+// reload it makes configWatcher go back to lastGood, the config that was running,
+// and returns true; without one to go back to it returns false and the caller exits.
+func rollbackReload(configWatcher *watcher.ConfigWatcher, lastGood *config.Config, configPath string, err error) bool {
+	if lastGood == nil || configWatcher == nil {
+		return false
+	}
+	log.Printf("Error: new config failed to apply: %v", err)
+	log.Printf("Rolling back to the previous config (fix %s and save again)", configPath)
+	configWatcher.Rollback(lastGood)
+	return true
+}
+
+// retryResetAfter is how long an instance must run before server_retry starts
+// counting restarts from zero again
+const retryResetAfter = time.Minute
+
+// sleepOrSignal waits d, returning false if a shutdown signal arrives first
+func sleepOrSignal(d time.Duration, sigChan <-chan os.Signal) bool {
+	select {
+	case sig := <-sigChan:
+		log.Printf("Received signal %v, shutting down...", sig)
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// startApp binds the HTTP and TCP listeners and starts serving. On error everything
+// started so far is shut down again.
+func startApp(app *appComponents, serverErrChan chan<- error) error {
+	app.manager.Start()
+	if app.checker != nil {
+		app.checker.Start()
+	}
+
+	if err := app.httpServer.Listen(); err != nil {
+		shutdownApp(app, context.Background())
+		return fmt.Errorf("HTTP server: %w", err)
+	}
+	go func() {
+		if err := app.httpServer.Serve(); err != nil {
+			serverErrChan <- err
+		}
+	}()
+
+	if app.tcpServer != nil {
+		if err := app.tcpServer.Start(); err != nil {
+			shutdownApp(app, context.Background())
+			return fmt.Errorf("TCP server: %w", err)
+		}
+	}
+	return nil
+}
+
 func shutdownApp(app *appComponents, ctx context.Context) {
 	// HTTP first - stop accepting connections before tearing down tunnels
 	if err := app.httpServer.Shutdown(ctx); err != nil {
@@ -207,19 +404,41 @@ func shutdownApp(app *appComponents, ctx context.Context) {
 		app.tcpServer.Shutdown()
 	}
 
+	if app.checker != nil {
+		app.checker.Stop()
+	}
+
 	app.manager.Shutdown()
 }
 
-func printConfigInfo(configPath string, cfg *config.Config) {
+func printConfigInfo(configPath string, cfg *config.Config, quiet bool) {
+	if quiet {
+		fmt.Printf("Config: %s (%d routes)\n", configPath, cfg.RouteCount())
+		return
+	}
 	fmt.Println("-----------------------------------------------------------------------------")
-	if len(cfg.HTTP.K8s.Routes) == 0 && len(cfg.TCP.K8s.Routes) == 0 && len(cfg.TCP.K8s.Jump) == 0 {
+	if cfg.RouteCount() == 0 && len(cfg.TCP.K8s.Discover) == 0 {
 		fmt.Println("Add/remove routes !!!❗️⚠️🔴")
 	}
 	fmt.Printf("Config: %s\n", configPath)
+	if cfg.Team.Enabled() {
+		fmt.Printf("Team mode: %d users, HTTP routes require a token\n", len(cfg.Team.Users))
+	}
+	if cfg.Locked {
+		fmt.Println("Locked: dynamic_host, runtime pins and via.create are disabled")
+	}
 	fmt.Println("-----------------------------------------------------------------------------")
-	cfg.PrintRoutes()
-	cfg.PrintTCPRoutes()
-	cfg.PrintJumpRoutes()
+	cfg.WriteRouteTable(os.Stdout, config.RouteTableOptions{Color: colorOutput(os.Stdout)})
+}
+
+// colorOutput reports whether f is a terminal that should get ANSI colors
+// (no NO_COLOR, TERM isn't "dumb")
+func colorOutput(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 // getReloadChan returns the reload channel if watcher exists, or a nil channel that never fires
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/atas/autotunnel/internal/config"
+	"github.com/atas/autotunnel/internal/watcher"
+)
+
+func TestRollbackReload_InitializeFailure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := config.CreateDefaultConfig(configPath); err != nil {
+		t.Fatalf("CreateDefaultConfig failed: %v", err)
+	}
+	good, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	// passes Validate, but the authorizer can't be built without its CA
+	bad, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	bad.Authorizers = []config.AuthorizerConfig{{Type: config.AuthorizerClientCert, CAFile: filepath.Join(t.TempDir(), "missing.pem")}}
+	if err := bad.Validate(); err != nil {
+		t.Fatalf("Expected the config to pass validation, got %v", err)
+	}
+
+	configWatcher, err := watcher.NewConfigWatcher(configPath, bad, false)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+	configWatcher.Start()
+	defer configWatcher.Stop()
+
+	_, initErr := initializeApp(configPath, false, false, configWatcher, nil)
+	if initErr == nil {
+		t.Fatal("Expected initializeApp to fail on the unreadable ca_file")
+	}
+
+	if rollbackReload(configWatcher, nil, configPath, initErr) {
+		t.Error("Expected no rollback without a previous config")
+	}
+	if !rollbackReload(configWatcher, good, configPath, initErr) {
+		t.Fatal("Expected a failed reload to roll back")
+	}
+	if configWatcher.GetConfig() != good {
+		t.Error("Expected the watcher to be back on the previous config")
+	}
+	if _, err := initializeApp(configPath, false, false, configWatcher, nil); err != nil {
+		t.Errorf("Expected the previous config to initialize again, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Exit codes of -run-mode supervised, from sysexits.h, so a service manager can
+// tell a config to fix from a failure worth restarting for
+const (
+	exitTransient   = 75 // EX_TEMPFAIL: a listener or the server failed, restarting may work
+	exitConfigError = 78 // EX_CONFIG: the config can't be loaded or used, restarting won't help
+)
+
+// runMode is what -run-mode changes. Supervised mode exits with exitConfigError
+// or exitTransient instead of 1 and keeps a pidfile and a ready file, which are
+// otherwise only written when their flags are set.
+type runMode struct {
+	supervised bool
+	pidFile    string // holds the pid once autotunnel has started
+	readyFile  string // exists while the listeners are bound
+}
+
+func newRunMode(mode, pidFile, readyFile string) (runMode, error) {
+	m := runMode{pidFile: pidFile, readyFile: readyFile}
+	switch mode {
+	case "foreground":
+	case "supervised":
+		m.supervised = true
+		home, _ := os.UserHomeDir()
+		if m.pidFile == "" {
+			m.pidFile = filepath.Join(home, ".autotunnel", "autotunnel.pid")
+		}
+		if m.readyFile == "" {
+			m.readyFile = filepath.Join(home, ".autotunnel", "ready")
+		}
+	default:
+		return runMode{}, fmt.Errorf("must be foreground or supervised")
+	}
+	return m, nil
+}
+
+// ready writes the pidfile and the ready file once the listeners are bound. Not
+// writing them earlier keeps a second instance, which fails to bind, from taking
+// over the files of the one running.
+func (m runMode) ready() {
+	for _, path := range []string{m.pidFile, m.readyFile} {
+		if path == "" {
+			continue
+		}
+		if err := writePIDFile(path); err != nil {
+			log.Printf("Warning: failed to write %s: %v", path, err)
+		}
+	}
+}
+
+// notReady removes the ready file before the listeners are closed
+func (m runMode) notReady() {
+	removePIDFile(m.readyFile)
+}
+
+// stop removes the pidfile and the ready file
+func (m runMode) stop() {
+	removePIDFile(m.readyFile)
+	removePIDFile(m.pidFile)
+}
+
+// fatal logs and exits: with code in supervised mode, with 1 otherwise
+func (m runMode) fatal(code int, format string, args ...any) {
+	log.Printf(format, args...)
+	m.stop()
+	if !m.supervised {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// writePIDFile writes this process's pid to path, through a rename so readers
+// never see a partial file
+func writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removePIDFile removes path if it holds this process's pid
+func removePIDFile(path string) {
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) == strconv.Itoa(os.Getpid()) {
+		_ = os.Remove(path)
+	}
+}
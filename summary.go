@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/atas/autotunnel/internal/config"
+)
+
+// startupSummary is printed as one JSON line per (re)start with -output json
+type startupSummary struct {
+	Event      string             `json:"event"` // "started"
+	Version    string             `json:"version"`
+	Config     string             `json:"config"`
+	HTTPListen string             `json:"http_listen"`
+	StatusHost string             `json:"status_host,omitempty"`
+	TeamMode   bool               `json:"team_mode,omitempty"`
+	Routes     []config.RouteInfo `json:"routes"`
+}
+
+// writeStartupSummary describes the running app, with TCP routes at the ports
+// they were actually bound to (auto_remap_ports)
+func writeStartupSummary(w io.Writer, configPath string, app *appComponents) error {
+	cfg := app.cfg
+	var remapped map[int]int
+	if app.tcpServer != nil {
+		remapped = app.tcpServer.RemappedPorts()
+	}
+
+	routes := cfg.RouteList()
+	for i, r := range routes {
+		if r.LocalPort == 0 {
+			continue
+		}
+		if bound, ok := remapped[r.LocalPort]; ok {
+			routes[i].LocalPort = bound
+		}
+		routes[i].Local = net.JoinHostPort(cfg.TCP.GetListenHost(), strconv.Itoa(routes[i].LocalPort))
+	}
+
+	data, err := json.Marshal(startupSummary{
+		Event:      "started",
+		Version:    version,
+		Config:     configPath,
+		HTTPListen: cfg.HTTP.ListenAddr,
+		StatusHost: cfg.HTTP.StatusHost,
+		TeamMode:   cfg.Team.Enabled(),
+		Routes:     routes,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}